@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/docker/go-units"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/server/backup"
+	"github.com/pterodactyl/wings/server/filesystem"
+)
+
+func newBackupCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "backup",
+		Short: "Manage the local backups stored on this machine.",
+		Long: "Manages the local backups stored on this machine. Backups created and restored using these commands are " +
+			"not reported to the Panel, which makes them useful when migrating servers or responding to an incident.",
+	}
+
+	verify := &cobra.Command{
+		Use:   "verify <backup>",
+		Short: "Check that a local backup can be restored without restoring it.",
+		Long: "Reads through a local backup without restoring it, checking that every file within it can be read " +
+			"and, if a checksum is provided, that the checksum of the archive matches the one stored by the Panel.",
+		Args: cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			initConfig()
+		},
+		Run: backupVerifyCmdRun,
+	}
+	verify.Flags().String("checksum", "", "the expected sha1 checksum of the backup")
+	command.AddCommand(verify)
+
+	create := &cobra.Command{
+		Use:   "create <server>",
+		Short: "Create a local backup of a server.",
+		Long: "Creates a local backup of the files for a server. The server-wide .pteroignore file is used to decide " +
+			"which files to skip unless ignored files are provided.",
+		Args: cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			initConfig()
+		},
+		Run: backupCreateCmdRun,
+	}
+	create.Flags().String("ignore", "", "newline separated list of files to skip, in the format of a .pteroignore file")
+	command.AddCommand(create)
+
+	command.AddCommand(&cobra.Command{
+		Use:   "list <server>",
+		Short: "List the local backups of a server created using this command.",
+		Args:  cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			initConfig()
+		},
+		Run: backupListCmdRun,
+	})
+
+	restore := &cobra.Command{
+		Use:   "restore <server> <backup>",
+		Short: "Restore a local backup to a server.",
+		Long: "Restores a local backup to a server, overwriting any files that exist in the backup. The server must " +
+			"be offline unless --force is used, since files being written by a running server may be corrupted.",
+		Args: cobra.ExactArgs(2),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			initConfig()
+		},
+		Run: backupRestoreCmdRun,
+	}
+	restore.Flags().Bool("truncate", false, "delete all of the existing files for the server before restoring")
+	restore.Flags().Bool("force", false, "restore the backup without checking that the server is offline")
+	command.AddCommand(restore)
+
+	return command
+}
+
+// serverDataDirectory returns the directory that the files for the server are
+// stored in, checking the directory for the primary Panel and every tenant.
+func serverDataDirectory(server string) (string, error) {
+	if _, err := uuid.Parse(server); err != nil {
+		return "", errors.New("the server must be a valid uuid")
+	}
+	sc := config.Get().System
+	tenants := []string{""}
+	for _, t := range config.Get().Tenants {
+		tenants = append(tenants, t.Name)
+	}
+	for _, t := range tenants {
+		p := filepath.Join(sc.TenantDataDirectory(t), server)
+		if st, err := os.Stat(p); err == nil && st.IsDir() {
+			return p, nil
+		}
+	}
+	return "", errServerNotFound
+}
+
+func backupCreateCmdRun(cmd *cobra.Command, args []string) {
+	dir, err := serverDataDirectory(args[0])
+	if err != nil {
+		fmt.Printf("Unable to find the server %s: %s\n", args[0], err)
+		os.Exit(1)
+	}
+
+	ignore, _ := cmd.Flags().GetString("ignore")
+	if ignore == "" {
+		if ignore, err = readServerwideIgnoredFiles(dir); err != nil {
+			fmt.Printf("Unable to read the .pteroignore file for the server: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	b := backup.NewLocal(nil, uuid.New().String(), ignore)
+	fmt.Printf("Creating backup %s of server %s...\n", b.Identifier(), args[0])
+	ad, err := b.Generate(cmd.Context(), dir, ignore)
+	if err != nil {
+		fmt.Printf("Unable to create the backup: %s\n", err)
+		_ = b.Remove()
+		os.Exit(1)
+	}
+	err = b.WriteMetadata(backup.LocalMetadata{
+		Server:    args[0],
+		CreatedAt: time.Now(),
+		Checksum:  ad.Checksum,
+		Size:      ad.Size,
+	})
+	if err != nil {
+		fmt.Printf("Unable to store the details of the backup: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Created backup %s at %s (%s, %s %s).\n", b.Identifier(), b.Path(), units.BytesSize(float64(ad.Size)), ad.ChecksumType, ad.Checksum)
+}
+
+// readServerwideIgnoredFiles returns the contents of the .pteroignore file in the
+// root of the server, using the same limits as backups triggered by the Panel.
+func readServerwideIgnoredFiles(dir string) (string, error) {
+	st, err := os.Lstat(filepath.Join(dir, ".pteroignore"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !st.Mode().IsRegular() || st.Size() > 32*1024 {
+		return "", nil
+	}
+	b, err := os.ReadFile(filepath.Join(dir, ".pteroignore"))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func backupListCmdRun(cmd *cobra.Command, args []string) {
+	backups, err := backup.ListLocalMetadata(args[0])
+	if err != nil {
+		fmt.Printf("Unable to list the backups: %s\n", err)
+		os.Exit(1)
+	}
+	if len(backups) == 0 {
+		fmt.Printf("There are no local backups of server %s created using this command.\n", args[0])
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "UUID\tCREATED\tSIZE\tCHECKSUM")
+	for _, m := range backups {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.Uuid, m.CreatedAt.Local().Format(time.RFC3339), units.BytesSize(float64(m.Size)), m.Checksum)
+	}
+	_ = w.Flush()
+}
+
+func backupRestoreCmdRun(cmd *cobra.Command, args []string) {
+	truncate, _ := cmd.Flags().GetBool("truncate")
+	force, _ := cmd.Flags().GetBool("force")
+
+	dir, err := serverDataDirectory(args[0])
+	if err != nil {
+		fmt.Printf("Unable to find the server %s: %s\n", args[0], err)
+		os.Exit(1)
+	}
+	b, _, err := backup.LocateLocal(nil, args[1])
+	if err != nil {
+		fmt.Printf("Unable to find the backup %s: %s\n", args[1], err)
+		os.Exit(1)
+	}
+	if !force {
+		if err := checkServerOffline(cmd, args[0]); err != nil {
+			fmt.Printf("Unable to restore the backup: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// The disk limit is not enforced since the backup is being restored by
+	// someone with access to the machine.
+	sfs := filesystem.New(dir, 0, nil)
+	if truncate {
+		if err := sfs.TruncateRootDirectory(); err != nil {
+			fmt.Printf("Unable to delete the existing files for the server: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var files int
+	names := filesystem.NewNameResolver()
+	err = b.Restore(cmd.Context(), nil, func(file string, r io.Reader, mode fs.FileMode, atime, mtime time.Time) error {
+		file, ok := names.Resolve(file)
+		if !ok {
+			return nil
+		}
+		files++
+		if err := sfs.Writefile(file, r); err != nil {
+			return err
+		}
+		if err := sfs.Chmod(file, mode); err != nil {
+			return err
+		}
+		return sfs.Chtimes(file, atime, mtime)
+	})
+	if err != nil {
+		fmt.Printf("Unable to restore the backup after %d file(s): %s\n", files, err)
+		os.Exit(1)
+	}
+	for _, c := range names.Conflicts() {
+		fmt.Println(c.String())
+	}
+	fmt.Printf("Restored %d file(s) from backup %s to server %s.\n", files, b.Identifier(), args[0])
+}
+
+// checkServerOffline returns an error if the local Wings instance reports that
+// the server is running. If the Wings API cannot be reached the containers for
+// the server are checked instead, since the server keeps running while Wings
+// is stopped or restarting.
+func checkServerOffline(cmd *cobra.Command, server string) error {
+	lc := newLocalClient()
+	for _, p := range lc.panels {
+		var s struct {
+			State string `json:"state"`
+		}
+		code, err := lc.request(cmd.Context(), p, http.MethodGet, "/api/servers/"+url.PathEscape(server), nil, &s)
+		if err != nil {
+			if code == 0 {
+				return checkContainersOffline(cmd, server)
+			}
+			if code == http.StatusNotFound {
+				continue
+			}
+			return err
+		}
+		if s.State != environment.ProcessOfflineState {
+			return errors.New(fmt.Sprintf("the server is currently %s, stop it first or use --force", s.State))
+		}
+		return nil
+	}
+	return nil
+}
+
+// checkContainersOffline returns an error if any container belonging to the
+// server is running, or if the containers could not be checked.
+func checkContainersOffline(cmd *cobra.Command, server string) error {
+	containers, err := environment.ManagedContainers(cmd.Context())
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to check if the server is running: %s, use --force to restore anyway", err))
+	}
+	for _, c := range containers {
+		if c.Server == server && c.Running {
+			return errors.New("a container for the server is currently running, stop it first or use --force")
+		}
+	}
+	return nil
+}
+
+func backupVerifyCmdRun(cmd *cobra.Command, args []string) {
+	checksum, _ := cmd.Flags().GetString("checksum")
+
+	b, _, err := backup.LocateLocal(nil, args[0])
+	if err != nil {
+		fmt.Printf("Unable to find the backup %s: %s\n", args[0], err)
+		os.Exit(1)
+	}
+	res, err := b.Verify(cmd.Context(), checksum)
+	if err != nil {
+		fmt.Printf("Unable to read the backup at %s: %s\n", b.Path(), err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Read %d file(s) from %s (%d bytes, sha1 %s).\n", res.Files, b.Path(), res.Size, res.Checksum)
+	for _, e := range res.Errors {
+		if e.File != "" {
+			fmt.Printf("ERROR   %s: %s\n", e.File, e.Error)
+		} else {
+			fmt.Printf("ERROR   %s\n", e.Error)
+		}
+	}
+	if res.ChecksumMatches != nil && !*res.ChecksumMatches {
+		fmt.Printf("ERROR   the checksum does not match the expected checksum %s\n", checksum)
+	}
+	if !res.Valid {
+		fmt.Println("\nThe backup is not valid and cannot be fully restored.")
+		os.Exit(1)
+	}
+	fmt.Println("\nThe backup is valid.")
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/docker/go-units"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/server/filesystem"
+)
+
+func newCompressCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "compress [server...]",
+		Short: "Compress the files of stopped servers to reclaim disk space.",
+		Long: "Compresses the files of the given servers using NTFS or Compact OS style compression, which is only " +
+			"supported on Windows. Servers that are running are skipped unless --force is used, since files that are " +
+			"open by a server cannot be compressed.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			initConfig()
+		},
+		Run: compressCmdRun,
+	}
+	command.Flags().String("algorithm", "", "the compression algorithm to use, defaults to the volume_compression configured for the node")
+	command.Flags().Bool("all", false, "compress every server on this machine")
+	command.Flags().Bool("force", false, "compress servers even if they are running")
+
+	return command
+}
+
+func compressCmdRun(cmd *cobra.Command, args []string) {
+	algorithm, _ := cmd.Flags().GetString("algorithm")
+	all, _ := cmd.Flags().GetBool("all")
+	force, _ := cmd.Flags().GetBool("force")
+
+	if algorithm == "" {
+		algorithm = config.Get().System.VolumeCompression
+	}
+	if !config.IsVolumeCompression(algorithm) {
+		fmt.Println("A compression algorithm must be provided using --algorithm, or configured using volume_compression.")
+		os.Exit(1)
+	}
+	if all {
+		args = localServers()
+	}
+	if len(args) == 0 {
+		fmt.Println("At least one server must be provided, or --all used to compress every server.")
+		os.Exit(1)
+	}
+
+	var failed bool
+	for _, server := range args {
+		dir, err := serverDataDirectory(server)
+		if err != nil {
+			fmt.Printf("Unable to find the server %s: %s\n", server, err)
+			failed = true
+			continue
+		}
+		if !force {
+			if err := checkServerOffline(cmd, server); err != nil {
+				fmt.Printf("Skipping server %s: %s\n", server, err)
+				continue
+			}
+		}
+		res, err := filesystem.New(dir, 0, nil).Compress(cmd.Context(), algorithm)
+		if err != nil {
+			fmt.Printf("Unable to compress the files for server %s: %s\n", server, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("Compressed %d file(s) for server %s from %s to %s, %d file(s) skipped.\n",
+			res.Files,
+			server,
+			units.BytesSize(float64(res.Before)),
+			units.BytesSize(float64(res.After)),
+			res.Skipped,
+		)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// localServers returns the uuid of every server with a data directory on this
+// machine, across the primary Panel and every tenant.
+func localServers() []string {
+	sc := config.Get().System
+	tenants := []string{""}
+	for _, t := range config.Get().Tenants {
+		tenants = append(tenants, t.Name)
+	}
+	var servers []string
+	for _, t := range tenants {
+		entries, err := os.ReadDir(sc.TenantDataDirectory(t))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if _, err := uuid.Parse(e.Name()); err == nil && e.IsDir() {
+				servers = append(servers, e.Name())
+			}
+		}
+	}
+	return servers
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+func newConfigCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the configuration file for this Wings instance.",
+	}
+
+	command.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration file for mistakes without starting Wings.",
+		Long: "Loads the configuration file and checks it for mistakes, such as conflicting ports, missing " +
+			"directories, invalid certificates or an unreachable Docker daemon, without starting Wings.",
+		Args: cobra.NoArgs,
+		Run:  configValidateCmdRun,
+	})
+
+	return command
+}
+
+func configValidateCmdRun(cmd *cobra.Command, _ []string) {
+	b, err := os.ReadFile(configPath)
+	if err != nil {
+		fmt.Printf("Unable to read the configuration file at %s: %s\n", configPath, err)
+		os.Exit(1)
+	}
+	if err := config.FromFile(configPath); err != nil {
+		fmt.Printf("Unable to parse the configuration file at %s: %s\n", configPath, err)
+		os.Exit(1)
+	}
+	c := config.Get()
+	issues := c.Validate()
+
+	// Keys that are not recognized are most likely typos, which would otherwise be
+	// silently ignored and have the default value used instead.
+	if err := yaml.UnmarshalStrict(b, &config.Configuration{}); err != nil {
+		issues = append(issues, config.ValidationIssue{Field: "config", Message: err.Error(), Warning: true})
+	}
+
+	for _, a := range []struct {
+		field string
+		host  string
+		port  int
+	}{
+		{"api.port", c.Api.Host, c.Api.Port},
+		{"system.sftp.bind_port", c.System.Sftp.Address, c.System.Sftp.Port},
+	} {
+		l, err := net.Listen("tcp", net.JoinHostPort(a.host, strconv.Itoa(a.port)))
+		if err != nil {
+			issues = append(issues, config.ValidationIssue{
+				Field:   a.field,
+				Message: fmt.Sprintf("port %d is already in use, this is expected if Wings is currently running", a.port),
+				Warning: true,
+			})
+			continue
+		}
+		_ = l.Close()
+	}
+
+	if err := checkDockerConnection(cmd.Context()); err != nil {
+		issues = append(issues, config.ValidationIssue{Field: "docker", Message: fmt.Sprintf("unable to connect to the Docker daemon: %s", err)})
+	}
+
+	var failed int
+	for _, i := range issues {
+		fmt.Println(i.String())
+		if !i.Warning {
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("\nFound %d error(s) in %s, Wings will not start correctly until they are fixed.\n", failed, configPath)
+		os.Exit(1)
+	}
+	fmt.Printf("\nThe configuration file at %s is valid.\n", configPath)
+}
+
+func checkDockerConnection(ctx context.Context) error {
+	client, err := environment.Docker()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	_, err = client.Ping(ctx)
+	return err
+}
@@ -147,11 +147,18 @@ func diagnosticsCmdRun(cmd *cobra.Command, args []string) {
 		}
 		fmt.Fprintln(output, "LoggingDriver:", dockerInfo.LoggingDriver)
 		fmt.Fprintln(output, " CgroupDriver:", dockerInfo.CgroupDriver)
+		caps := environment.CapabilitiesFromInfo(dockerInfo)
+		if caps.CgroupVersion != "" {
+			fmt.Fprintln(output, "CgroupVersion:", caps.CgroupVersion)
+		}
 		if len(dockerInfo.Warnings) > 0 {
 			for _, w := range dockerInfo.Warnings {
 				fmt.Fprintln(output, w)
 			}
 		}
+		for _, w := range caps.Warnings() {
+			fmt.Fprintln(output, "WARNING:", w)
+		}
 	} else {
 		fmt.Fprintln(output, dockerErr.Error())
 	}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/loggers/cli"
+	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/remote/transport"
+	"github.com/pterodactyl/wings/server"
+)
+
+func newImportCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <server> <path>",
+		Short: "Import files into a server from a directory or archive on this machine.",
+		Long: "Import files into a server from a directory or archive on this machine, such as when migrating " +
+			"a server from another panel. The server must already be created in the Panel and must be stopped.",
+		Args: cobra.ExactArgs(2),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			initConfig()
+			log.SetHandler(cli.Default)
+		},
+		Run: importCmdRun,
+	}
+}
+
+func importCmdRun(cmd *cobra.Command, args []string) {
+	if err := config.EnsurePterodactylUser(); err != nil {
+		log.WithField("error", err).Fatal("failed to configure pterodactyl system user")
+	}
+
+	pclient := remote.New(
+		config.Get().PanelLocation,
+		remote.WithCredentials(config.Get().AuthenticationTokenId, config.Get().AuthenticationToken),
+		remote.WithHttpClient(transport.NewClient("")),
+	)
+
+	data, err := pclient.GetServerConfiguration(cmd.Context(), args[0])
+	if err != nil {
+		log.WithField("error", err).Fatal("failed to get server configuration from panel")
+	}
+	s, err := server.NewEmptyManager(pclient).InitServer(data)
+	if err != nil {
+		log.WithField("error", err).Fatal("failed to load server configuration")
+	}
+
+	if running, err := s.Environment.IsRunning(cmd.Context()); err != nil {
+		log.WithField("error", err).Fatal("failed to check if server is running")
+	} else if running {
+		log.Fatal("server must be stopped before files can be imported")
+	}
+
+	if err := s.Import(cmd.Context(), args[1]); err != nil {
+		log.WithField("error", err).Fatal("failed to import files for server")
+	}
+	log.WithField("server", s.ID()).Info("imported files for server successfully")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// socketListener returns a listener for the Unix domain socket that the API
+// should listen on, applying the configured permissions to the socket file. A
+// socket left behind by a previous process is removed first.
+func socketListener(c config.ApiSocketConfiguration) (net.Listener, error) {
+	mode, err := strconv.ParseUint(c.Mode, 8, 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "cmd: invalid mode for api socket")
+	}
+	if st, err := os.Lstat(c.Path); err == nil {
+		if st.Mode()&os.ModeSocket == 0 {
+			return nil, errors.New("cmd: api socket path already exists and is not a socket: " + c.Path)
+		}
+		if err := os.Remove(c.Path); err != nil {
+			return nil, errors.WithStackIf(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+	l, err := net.Listen("unix", c.Path)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+	if err := os.Chmod(c.Path, os.FileMode(mode)); err != nil {
+		_ = l.Close()
+		return nil, errors.WithStackIf(err)
+	}
+	if c.Group != "" {
+		g, err := user.LookupGroup(c.Group)
+		if err != nil {
+			_ = l.Close()
+			return nil, errors.Wrap(err, "cmd: failed to lookup group for api socket")
+		}
+		gid, _ := strconv.Atoi(g.Gid)
+		if err := os.Chown(c.Path, -1, gid); err != nil {
+			_ = l.Close()
+			return nil, errors.WithStackIf(err)
+		}
+	}
+	return l, nil
+}
+
+// dialSocket connects to the Unix domain socket that the API is listening on.
+func dialSocket(ctx context.Context, path string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", path)
+}
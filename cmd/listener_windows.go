@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"context"
+	"net"
+
+	"emperror.dev/errors"
+	"github.com/Microsoft/go-winio"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// socketListener returns a listener for the named pipe that the API should
+// listen on. Access to the pipe is controlled by its security descriptor.
+func socketListener(c config.ApiSocketConfiguration) (net.Listener, error) {
+	l, err := winio.ListenPipe(c.Path, &winio.PipeConfig{SecurityDescriptor: c.SecurityDescriptor})
+	if err != nil {
+		return nil, errors.Wrap(err, "cmd: failed to listen on named pipe for api")
+	}
+	return l, nil
+}
+
+// dialSocket connects to the named pipe that the API is listening on.
+func dialSocket(ctx context.Context, path string) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, path)
+}
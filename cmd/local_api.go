@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/gbrlsnchs/jwt/v3"
+	"github.com/goccy/go-json"
+	"github.com/gorilla/websocket"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/router/tokens"
+)
+
+// errServerNotFound is returned when a server does not exist on the local
+// instance for any of the Panels the node is registered with.
+var errServerNotFound = errors.Sentinel("server does not exist on this instance")
+
+// localPanel is a Panel that the node is registered with, requests for servers
+// belonging to the Panel must be authorized with its token.
+type localPanel struct {
+	Tenant   string
+	Location string
+	Token    string
+}
+
+// localClient makes requests to the API of the Wings instance running on this
+// machine. The local socket is used if one is configured, otherwise requests are
+// made to the API over the loopback interface.
+type localClient struct {
+	http   *http.Client
+	ws     *websocket.Dialer
+	base   string
+	panels []localPanel
+}
+
+func newLocalClient() *localClient {
+	c := config.Get()
+	lc := &localClient{
+		panels: []localPanel{{Location: c.PanelLocation, Token: c.AuthenticationToken}},
+	}
+	for _, t := range c.Tenants {
+		lc.panels = append(lc.panels, localPanel{Tenant: t.Name, Location: t.PanelLocation, Token: t.AuthenticationToken})
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	lc.ws = &websocket.Dialer{HandshakeTimeout: time.Second * 10}
+	if path := c.Api.Socket.Path; path != "" {
+		dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialSocket(ctx, path)
+		}
+		transport.DialContext = dial
+		lc.ws.NetDialContext = dial
+		lc.base = "http://localhost"
+	} else {
+		host := c.Api.Host
+		if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+			host = "127.0.0.1"
+		}
+		scheme := "http"
+		if c.Api.Ssl.Enabled {
+			scheme = "https"
+			// The certificate is issued for the public hostname of the node, which will
+			// never match the loopback address that the request is being made to.
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+			lc.ws.TLSClientConfig = transport.TLSClientConfig
+		}
+		lc.base = scheme + "://" + net.JoinHostPort(host, strconv.Itoa(c.Api.Port))
+	}
+	lc.http = &http.Client{Transport: transport, Timeout: time.Second * 30}
+	return lc
+}
+
+// request makes a request to the local API, decoding the response into v if it
+// is not nil. Responses with an error status return the error from the API.
+func (lc *localClient) request(ctx context.Context, p localPanel, method string, path string, body interface{}, v interface{}) (int, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, lc.base+path, r)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.Token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := lc.http.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not connect to the local Wings API, is Wings running?")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil || e.Error == "" {
+			return res.StatusCode, errors.New(fmt.Sprintf("the API responded with status %d", res.StatusCode))
+		}
+		return res.StatusCode, errors.New(e.Error)
+	}
+	if v != nil && res.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			return res.StatusCode, errors.Wrap(err, "failed to decode API response")
+		}
+	}
+	return res.StatusCode, nil
+}
+
+// panelFor returns the Panel that the server belongs to. Servers belonging to a
+// different Panel than the token used are reported as not existing by the API,
+// so the token for every Panel is tried until one is found.
+func (lc *localClient) panelFor(ctx context.Context, uuid string) (localPanel, error) {
+	for _, p := range lc.panels {
+		code, err := lc.request(ctx, p, http.MethodGet, "/api/servers/"+url.PathEscape(uuid), nil, nil)
+		if err == nil {
+			return p, nil
+		}
+		if code != http.StatusNotFound {
+			return p, err
+		}
+	}
+	return localPanel{}, errServerNotFound
+}
+
+// websocket connects to the websocket for the server and authenticates using a
+// token signed with the secret for the Panel the server belongs to.
+func (lc *localClient) websocket(ctx context.Context, p localPanel, uuid string) (*websocket.Conn, error) {
+	now := time.Now()
+	token, err := jwt.Sign(&tokens.WebsocketPayload{
+		Payload: jwt.Payload{
+			IssuedAt:       jwt.NumericDate(now),
+			ExpirationTime: jwt.NumericDate(now.Add(time.Hour * 24)),
+		},
+		UserID:      "0",
+		ServerUUID:  uuid,
+		Permissions: []string{"*", "admin.websocket.errors", "admin.websocket.install"},
+	}, jwt.NewHS256([]byte(p.Token)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign websocket token")
+	}
+
+	u, _ := url.Parse(lc.base + "/api/servers/" + url.PathEscape(uuid) + "/ws")
+	u.Scheme = map[string]string{"http": "ws", "https": "wss"}[u.Scheme]
+	conn, _, err := lc.ws.DialContext(ctx, u.String(), http.Header{"Origin": []string{p.Location}})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to the server websocket")
+	}
+	if err := conn.WriteJSON(map[string]interface{}{"event": "auth", "args": []string{string(token)}}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
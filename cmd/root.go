@@ -4,14 +4,17 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	log2 "log"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/NYTimes/logrotate"
@@ -22,11 +25,18 @@ import (
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/acme"
 	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/environment/containerd"
+	"github.com/pterodactyl/wings/events/publisher"
+	"github.com/pterodactyl/wings/events/webhook"
 	"github.com/pterodactyl/wings/loggers/cli"
+	"github.com/pterodactyl/wings/loggers/rotate"
 	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/remote/transport"
 	"github.com/pterodactyl/wings/router"
 	"github.com/pterodactyl/wings/server"
 	"github.com/pterodactyl/wings/sftp"
@@ -83,6 +93,11 @@ func init() {
 	rootCommand.AddCommand(versionCommand)
 	rootCommand.AddCommand(configureCmd)
 	rootCommand.AddCommand(newDiagnosticsCommand())
+	rootCommand.AddCommand(newImportCommand())
+	rootCommand.AddCommand(newConfigCommand())
+	rootCommand.AddCommand(newBackupCommand())
+	rootCommand.AddCommand(newServerCommand())
+	rootCommand.AddCommand(newCompressCommand())
 }
 
 func rootCmdRun(cmd *cobra.Command, _ []string) {
@@ -122,20 +137,48 @@ func rootCmdRun(cmd *cobra.Command, _ []string) {
 	pclient := remote.New(
 		config.Get().PanelLocation,
 		remote.WithCredentials(config.Get().AuthenticationTokenId, config.Get().AuthenticationToken),
-		remote.WithHttpClient(&http.Client{
-			Timeout: time.Second * time.Duration(config.Get().RemoteQuery.Timeout),
-		}),
+		remote.WithHttpClient(transport.NewClient("")),
 	)
 
-	manager, err := server.NewManager(cmd.Context(), pclient)
+	if err := publisher.Configure(); err != nil {
+		log.WithField("error", err).Fatal("failed to configure external event bus publisher")
+	}
+	go handleShutdown()
+
+	// Every additional Panel that this node is registered with gets its own client so
+	// that requests are made using the correct credentials for each of them.
+	var opts []server.ManagerOption
+	for _, t := range config.Get().Tenants {
+		if !config.ValidTenantName(t.Name) {
+			log.WithField("tenant", t.Name).Fatal("invalid tenant name, it must be lowercase letters, numbers, dashes and underscores")
+		}
+		log.WithField("tenant", t.Name).WithField("remote", t.PanelLocation).Info("registering additional panel for tenant")
+		opts = append(opts, server.WithTenant(t.Name, remote.New(
+			t.PanelLocation,
+			remote.WithCredentials(t.AuthenticationTokenId, t.AuthenticationToken),
+			remote.WithHttpClient(transport.NewClient(t.Name)),
+		)))
+	}
+
+	manager, err := server.NewManager(cmd.Context(), pclient, opts...)
 	if err != nil {
 		log.WithField("error", err).Fatal("failed to load server configurations")
 	}
 
-	if err := environment.ConfigureDocker(cmd.Context()); err != nil {
+	// Installation containers are always run using Docker, so it must be configured
+	// even when server processes are running in containerd. If the Docker daemon
+	// cannot be reached the node starts in a degraded state until it can be.
+	dockerReady, err := environment.StartDocker(cmd.Context())
+	if err != nil {
 		log.WithField("error", err).Fatal("failed to configure docker environment")
 	}
 
+	if config.Get().Containerd.Enabled {
+		if err := containerd.Configure(cmd.Context()); err != nil {
+			log.WithField("error", err).Fatal("failed to configure containerd environment")
+		}
+	}
+
 	if err := config.WriteToDisk(config.Get()); err != nil {
 		log.WithField("error", err).Fatal("failed to write configuration to disk")
 	}
@@ -145,6 +188,12 @@ func rootCmdRun(cmd *cobra.Command, _ []string) {
 		log.WithField("server", s.ID()).Info("finished loading configuration for server")
 	}
 
+	// Backups that were waiting for the backup window when Wings stopped will never be
+	// generated, so let the Panel know that they failed.
+	if err := manager.FailLostBackups(cmd.Context()); err != nil {
+		log.WithField("error", err).Error("failed to report lost backups to the panel")
+	}
+
 	states, err := manager.ReadStates()
 	if err != nil {
 		log.WithField("error", err).Error("failed to retrieve locally cached server states from disk, assuming all servers in offline state")
@@ -168,86 +217,54 @@ func rootCmdRun(cmd *cobra.Command, _ []string) {
 		}
 	}()
 
-	// Create a new workerpool that limits us to 4 servers being bootstrapped at a time
-	// on Wings. This allows us to ensure the environment exists, write configurations,
-	// and reboot processes without causing a slow-down due to sequential booting.
-	pool := workerpool.New(4)
-	for _, serv := range manager.All() {
-		s := serv
-
-		// For each server we encounter make sure the root data directory exists.
-		if err := s.EnsureDataDirectoryExists(); err != nil {
-			s.Log().Error("could not create root data directory for server: not loading server...")
-			continue
+	// Servers are restored once Docker has been configured. If the Docker daemon is
+	// not available yet this happens in the background once it is, rather than
+	// preventing the API and SFTP servers from starting. Servers running in
+	// containerd do not depend on Docker, so they are always restored straight away.
+	onDockerReady := func(restore bool) {
+		environment.PrePullImages(cmd.Context())
+		environment.StartImageGarbageCollection(cmd.Context(), manager.Images)
+
+		// Handle any containers left behind by servers that no longer exist on this node,
+		// such as servers that were deleted while Wings was not running.
+		if orphans, err := manager.ReconcileContainers(cmd.Context()); err != nil {
+			log.WithField("error", err).Error("failed to check for orphaned server containers")
+		} else if len(orphans) > 0 {
+			log.WithField("containers", len(orphans)).Warn("found containers that do not belong to any server on this node")
 		}
 
-		pool.Submit(func() {
-			s.Log().Info("configuring server environment and restoring to previous state")
-			var st string
-			if state, exists := states[s.ID()]; exists {
-				st = state
-			}
-
-			// Use a timed context here to avoid booting issues where Docker hangs for a
-			// specific container that would cause Wings to be un-bootable until the entire
-			// machine is rebooted. It is much better for us to just have a single failed
-			// server instance than an entire offline node.
-			//
-			// @see https://github.com/pterodactyl/panel/issues/2475
-			// @see https://github.com/pterodactyl/panel/issues/3358
-			ctx, cancel := context.WithTimeout(cmd.Context(), time.Second*30)
-			defer cancel()
-
-			r, err := s.Environment.IsRunning(ctx)
-			// We ignore missing containers because we don't want to actually block booting of wings at this
-			// point. If we didn't do this, and you pruned all the images and then started wings you could
-			// end up waiting a long period of time for all the images to be re-pulled on Wings boot rather
-			// than when the server itself is started.
-			if err != nil && !client.IsErrNotFound(err) {
-				s.Log().WithField("error", err).Error("error checking server environment status")
-			}
-
-			// Check if the server was previously running. If so, attempt to start the server now so that Wings
-			// can pick up where it left off. If the environment does not exist at all, just create it and then allow
-			// the normal flow to execute.
-			//
-			// This does mean that booting wings after a catastrophic machine crash and wiping out the Docker images
-			// as a result will result in a slow boot.
-			if !r && (st == environment.ProcessRunningState || st == environment.ProcessStartingState) {
-				if err := s.HandlePowerAction(server.PowerActionStart); err != nil {
-					s.Log().WithField("error", err).Warn("failed to return server to running state")
-				}
-			} else if r || (!r && s.IsRunning()) {
-				// If the server is currently running on Docker, mark the process as being in that state.
-				// We never want to stop an instance that is currently running external from Wings since
-				// that is a good way of keeping things running even if Wings gets in a very corrupted state.
-				//
-				// This will also validate that a server process is running if the last tracked state we have
-				// is that it was running, but we see that the container process is not currently running.
-				s.Log().Info("detected server is running, re-attaching to process...")
-
-				s.Environment.SetState(environment.ProcessRunningState)
-				if err := s.Environment.Attach(ctx); err != nil {
-					s.Log().WithField("error", err).Warn("failed to attach to running server environment")
-				}
-			} else {
-				// At this point we've determined that the server should indeed be in an offline state, so we'll
-				// make a call to set that state just to ensure we don't ever accidentally end up with some invalid
-				// state being tracked.
-				s.Environment.SetState(environment.ProcessOfflineState)
-			}
-
-			if state := s.Environment.State(); state == environment.ProcessStartingState || state == environment.ProcessRunningState {
-				s.Log().Debug("re-syncing server configuration for already running server")
-				if err := s.Sync(); err != nil {
-					s.Log().WithError(err).Error("failed to re-sync server configuration")
-				}
+		if restore {
+			restoreServers(cmd.Context(), manager, states)
+		}
+		go environment.WatchDocker(cmd.Context(), manager.ReconcileEnvironments)
+	}
+	select {
+	case <-dockerReady:
+		onDockerReady(true)
+	default:
+		restored := config.Get().Containerd.Enabled
+		if restored {
+			restoreServers(cmd.Context(), manager, states)
+		} else {
+			log.Warn("docker is not available, servers will be restored once it is")
+		}
+		go func() {
+			select {
+			case <-dockerReady:
+				onDockerReady(!restored)
+			case <-cmd.Context().Done():
 			}
-		})
+		}()
 	}
 
-	// Wait until all the servers are ready to go before we fire up the SFTP and HTTP servers.
-	pool.StopWait()
+	webhook.Dispatch("", webhook.NodeStartedEvent, map[string]interface{}{
+		"version": system.Version,
+		"servers": len(manager.All()),
+	})
+	manager.StartHeartbeat(cmd.Context())
+	manager.StartMirrors(cmd.Context())
+	manager.StartTasks(cmd.Context())
+	startLogPruning(cmd.Context(), manager)
 	defer func() {
 		// Cancel the context on all the running servers at this point, even though the
 		// program is just shutting down.
@@ -306,6 +323,21 @@ func rootCmdRun(cmd *cobra.Command, _ []string) {
 		TLSConfig: config.DefaultTLSConfig,
 	}
 
+	// The local socket is always served over plain HTTP, access to it is controlled by
+	// the permissions on the socket itself.
+	if api.Socket.Path != "" {
+		l, err := socketListener(api.Socket)
+		if err != nil {
+			log.WithField("error", err).Fatal("failed to configure api socket")
+		}
+		log.WithField("path", api.Socket.Path).Info("webserver is now also listening on a local socket")
+		go func() {
+			if err := (&http.Server{Handler: s.Handler}).Serve(l); err != nil {
+				log.WithField("error", err).Error("failed to serve api socket")
+			}
+		}()
+	}
+
 	profile, _ := cmd.Flags().GetBool("pprof")
 	if profile {
 		if r, _ := cmd.Flags().GetInt("pprof-block-rate"); r > 0 {
@@ -356,11 +388,29 @@ func rootCmdRun(cmd *cobra.Command, _ []string) {
 		return
 	}
 	s.TLSConfig = nil
+	if api.H2C {
+		log.Info("accepting HTTP/2 over plain text connections (h2c)")
+		s.Handler = h2c.NewHandler(s.Handler, &http2.Server{})
+	}
 	if err := s.ListenAndServe(); err != nil {
 		log.WithField("error", err).Fatal("failed to configure HTTP server")
 	}
 }
 
+// handleShutdown waits for Wings to be asked to stop, then sends the events still
+// queued for the external event bus and closes the connection to the broker before
+// exiting. Server processes are left running, the same as when Wings is killed.
+func handleShutdown() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	sig := <-ch
+	log.WithField("signal", sig.String()).Info("received signal, shutting down wings")
+	if err := publisher.Close(); err != nil {
+		log.WithField("error", err).Warn("failed to close external event bus publisher")
+	}
+	os.Exit(0)
+}
+
 // Configures the global logger for Zap so that we can call it from any location
 // in the code without having to pass around a logger instance.
 func initLogging() {
@@ -369,14 +419,160 @@ func initLogging() {
 		log2.Fatalf("cmd/root: failed to create install directory path: %s", err)
 	}
 	p := filepath.Join(dir, "/wings.log")
-	w, err := logrotate.NewFile(p)
-	if err != nil {
-		log2.Fatalf("cmd/root: failed to create wings log: %s", err)
+	var w io.Writer
+	if c := config.Get().System; c.EnableLogRotate && c.LogRotation.Mode() == config.LogRotationBuiltin {
+		f, err := rotate.Open(p, rotate.Options{
+			MaxSize:    int64(c.LogRotation.MaxSize) * 1024 * 1024,
+			MaxAge:     time.Duration(c.LogRotation.MaxAge) * time.Hour * 24,
+			MaxBackups: c.LogRotation.MaxBackups,
+			Compress:   c.LogRotation.Compress,
+		})
+		if err != nil {
+			log2.Fatalf("cmd/root: failed to create wings log: %s", err)
+		}
+		logFile = f
+		w = f
+	} else {
+		f, err := logrotate.NewFile(p)
+		if err != nil {
+			log2.Fatalf("cmd/root: failed to create wings log: %s", err)
+		}
+		w = f.File
 	}
 	log.SetLevel(log.InfoLevel)
 	if config.Get().Debug {
 		log.SetLevel(log.DebugLevel)
 	}
-	log.SetHandler(multi.New(cli.Default, cli.New(w.File, false)))
+	log.SetHandler(multi.New(cli.Default, cli.New(w, false)))
 	log.WithField("path", p).Info("writing log files to disk")
 }
+
+// The log file written to by Wings when it is rotating its own logs.
+var logFile *rotate.File
+
+// startLogPruning removes the logs of previous installations of every server,
+// and the rotated logs of Wings when using the builtin strategy, once they are
+// older than the maximum age. Expired logs are checked for every hour until the
+// context is canceled.
+func startLogPruning(ctx context.Context, m *server.Manager) {
+	c := config.Get().System
+	if !c.EnableLogRotate || c.LogRotation.Mode() == config.LogRotationNone || c.LogRotation.MaxAge <= 0 {
+		return
+	}
+	maxAge := time.Duration(c.LogRotation.MaxAge) * time.Hour * 24
+	prune := func() {
+		if logFile != nil {
+			if err := logFile.Prune(); err != nil {
+				log.WithField("error", err).Warn("failed to remove expired wings logs")
+			}
+		}
+		for _, s := range m.All() {
+			if err := s.PruneExpiredInstallLogs(maxAge); err != nil {
+				s.Log().WithField("error", err).Warn("failed to remove expired installation logs")
+			}
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		prune()
+		for {
+			select {
+			case <-ticker.C:
+				prune()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// restoreServers returns every server to the state it was in when Wings was last
+// stopped, attaching to the processes that are still running.
+func restoreServers(ctx context.Context, manager *server.Manager, states map[string]string) {
+	// Create a new workerpool that limits us to 4 servers being bootstrapped at a time
+	// on Wings. This allows us to ensure the environment exists, write configurations,
+	// and reboot processes without causing a slow-down due to sequential booting.
+	pool := workerpool.New(4)
+	for _, serv := range manager.All() {
+		s := serv
+
+		// For each server we encounter make sure the root data directory exists.
+		if err := s.PrepareDataDirectory(); err != nil {
+			s.Log().Error("could not create root data directory for server: not loading server...")
+			continue
+		}
+
+		pool.Submit(func() {
+			s.Log().Info("configuring server environment and restoring to previous state")
+			var st string
+			if state, exists := states[s.ID()]; exists {
+				st = state
+			}
+
+			// Use a timed context here to avoid booting issues where Docker hangs for a
+			// specific container that would cause Wings to be un-bootable until the entire
+			// machine is rebooted. It is much better for us to just have a single failed
+			// server instance than an entire offline node.
+			//
+			// @see https://github.com/pterodactyl/panel/issues/2475
+			// @see https://github.com/pterodactyl/panel/issues/3358
+			ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+			defer cancel()
+
+			r, err := s.Environment.IsRunning(ctx)
+			// We ignore missing containers because we don't want to actually block booting of wings at this
+			// point. If we didn't do this, and you pruned all the images and then started wings you could
+			// end up waiting a long period of time for all the images to be re-pulled on Wings boot rather
+			// than when the server itself is started.
+			if err != nil && !client.IsErrNotFound(err) {
+				s.Log().WithField("error", err).Error("error checking server environment status")
+			}
+
+			// Check if the server was previously running. If so, attempt to start the server now so that Wings
+			// can pick up where it left off. If the environment does not exist at all, just create it and then allow
+			// the normal flow to execute.
+			//
+			// This does mean that booting wings after a catastrophic machine crash and wiping out the Docker images
+			// as a result will result in a slow boot.
+			if !r && (st == environment.ProcessRunningState || st == environment.ProcessStartingState) {
+				if err := s.HandlePowerAction(server.PowerActionStart); err != nil {
+					s.Log().WithField("error", err).Warn("failed to return server to running state")
+				}
+			} else if r || (!r && s.IsRunning()) {
+				// If the server is currently running on Docker, mark the process as being in that state.
+				// We never want to stop an instance that is currently running external from Wings since
+				// that is a good way of keeping things running even if Wings gets in a very corrupted state.
+				//
+				// This will also validate that a server process is running if the last tracked state we have
+				// is that it was running, but we see that the container process is not currently running.
+				s.Log().Info("detected server is running, re-attaching to process...")
+
+				s.Environment.SetState(environment.ProcessRunningState)
+				if err := s.Environment.Attach(ctx); err != nil {
+					s.Log().WithField("error", err).Warn("failed to attach to running server environment")
+				}
+			} else {
+				// At this point we've determined that the server should indeed be in an offline state, so we'll
+				// make a call to set that state just to ensure we don't ever accidentally end up with some invalid
+				// state being tracked.
+				s.Environment.SetState(environment.ProcessOfflineState)
+			}
+
+			if state := s.Environment.State(); state == environment.ProcessStartingState || state == environment.ProcessRunningState {
+				s.Log().Debug("re-syncing server configuration for already running server")
+				if err := s.Sync(); err != nil {
+					s.Log().WithError(err).Error("failed to re-sync server configuration")
+				} else {
+					s.SyncWriteDenylist()
+				}
+				if err := s.EnforceSuspension(ctx); err != nil {
+					s.Log().WithError(err).Warn("failed to enforce suspension state for running server")
+				}
+			}
+		})
+	}
+
+	// Wait until all the servers are ready to go.
+	pool.StopWait()
+}
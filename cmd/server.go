@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/docker/go-units"
+	"github.com/goccy/go-json"
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+)
+
+func newServerCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "server",
+		Short: "Manage the servers on this machine through the local Wings API.",
+		Long: "Manages the servers on this machine by making requests to the Wings instance running on it, using the " +
+			"local socket if one is configured or the API port otherwise. This can be used when the Panel is unavailable.",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			initConfig()
+		},
+	}
+
+	command.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the servers on this machine and their current state.",
+		Args:  cobra.NoArgs,
+		Run:   serverListCmdRun,
+	})
+	for _, action := range []struct {
+		name  string
+		short string
+	}{
+		{"start", "Start a server."},
+		{"stop", "Stop a server, killing it if it does not stop within the wait time."},
+		{"restart", "Restart a server."},
+		{"kill", "Kill a server immediately."},
+	} {
+		c := &cobra.Command{
+			Use:   action.name + " <server>",
+			Short: action.short,
+			Args:  cobra.ExactArgs(1),
+			Run:   serverPowerCmdRun(action.name),
+		}
+		c.Flags().Int("wait", 30, "the number of seconds to wait for the server to stop")
+		command.AddCommand(c)
+	}
+	command.AddCommand(&cobra.Command{
+		Use:   "console <server>",
+		Short: "Attach to the console of a server.",
+		Long: "Attaches to the console of a server, printing its output and sending each line entered as a command " +
+			"to the server. Press Ctrl+C to detach, which does not stop the server.",
+		Args: cobra.ExactArgs(1),
+		Run:  serverConsoleCmdRun,
+	})
+
+	return command
+}
+
+func serverListCmdRun(cmd *cobra.Command, _ []string) {
+	lc := newLocalClient()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "UUID\tSTATE\tCPU\tMEMORY\tDISK\tUPTIME\tPANEL")
+	for _, p := range lc.panels {
+		var servers []struct {
+			State       string `json:"state"`
+			IsSuspended bool   `json:"is_suspended"`
+			Utilization struct {
+				Memory uint64  `json:"memory_bytes"`
+				Cpu    float64 `json:"cpu_absolute"`
+				Disk   int64   `json:"disk_bytes"`
+				Uptime int64   `json:"uptime"`
+			} `json:"utilization"`
+			Configuration struct {
+				Uuid string `json:"uuid"`
+			} `json:"configuration"`
+		}
+		if _, err := lc.request(cmd.Context(), p, http.MethodGet, "/api/servers", nil, &servers); err != nil {
+			fmt.Printf("Unable to list servers: %s\n", err)
+			os.Exit(1)
+		}
+		panel := p.Tenant
+		if panel == "" {
+			panel = "primary"
+		}
+		for _, s := range servers {
+			state := s.State
+			if s.IsSuspended {
+				state += " (suspended)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%.1f%%\t%s\t%s\t%s\t%s\n",
+				s.Configuration.Uuid,
+				state,
+				s.Utilization.Cpu,
+				units.BytesSize(float64(s.Utilization.Memory)),
+				units.BytesSize(float64(s.Utilization.Disk)),
+				(time.Duration(s.Utilization.Uptime) * time.Millisecond).Round(time.Second),
+				panel,
+			)
+		}
+	}
+	_ = w.Flush()
+}
+
+func serverPowerCmdRun(action string) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		wait, _ := cmd.Flags().GetInt("wait")
+		lc := newLocalClient()
+		p, err := lc.panelFor(cmd.Context(), args[0])
+		if err != nil {
+			fmt.Printf("Unable to find the server %s: %s\n", args[0], err)
+			os.Exit(1)
+		}
+		body := map[string]interface{}{"action": action, "wait_seconds": wait}
+		if _, err := lc.request(cmd.Context(), p, http.MethodPost, "/api/servers/"+url.PathEscape(args[0])+"/power", body, nil); err != nil {
+			fmt.Printf("Unable to %s the server %s: %s\n", action, args[0], err)
+			os.Exit(1)
+		}
+		fmt.Printf("Sent the %s action to server %s.\n", action, args[0])
+	}
+}
+
+func serverConsoleCmdRun(cmd *cobra.Command, args []string) {
+	ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer cancel()
+
+	lc := newLocalClient()
+	p, err := lc.panelFor(ctx, args[0])
+	if err != nil {
+		fmt.Printf("Unable to find the server %s: %s\n", args[0], err)
+		os.Exit(1)
+	}
+	conn, err := lc.websocket(ctx, p, args[0])
+	if err != nil {
+		fmt.Printf("Unable to attach to the console of server %s: %s\n", args[0], err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	// Writes to the websocket cannot happen concurrently.
+	var mu sync.Mutex
+	send := func(event string, args ...string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return conn.WriteJSON(map[string]interface{}{"event": event, "args": args})
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- readConsole(conn, func() {
+			_ = send("send logs")
+		})
+	}()
+	go func() {
+		s := bufio.NewScanner(os.Stdin)
+		for s.Scan() {
+			if err := send("send command", s.Text()); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		fmt.Println()
+	case err := <-errs:
+		if err != nil {
+			fmt.Printf("\nDisconnected from the console: %s\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// readConsole prints the console output sent over the websocket until it is
+// closed. The authenticated function is called once the websocket has accepted
+// the token, at which point the console history can be requested.
+func readConsole(conn *websocket.Conn, authenticated func()) error {
+	for {
+		var m struct {
+			Event string   `json:"event"`
+			Args  []string `json:"args"`
+		}
+		_, b, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return nil
+			}
+			return err
+		}
+		if err := json.Unmarshal(b, &m); err != nil {
+			continue
+		}
+		switch m.Event {
+		case "auth success":
+			authenticated()
+		case "console output", "install output", "daemon message":
+			for _, line := range m.Args {
+				fmt.Println(line)
+			}
+		case "status":
+			if len(m.Args) > 0 {
+				fmt.Printf("[wings] server marked as %s\n", m.Args[0])
+			}
+		case "daemon error":
+			fmt.Printf("[wings] %s\n", strings.Join(m.Args, " "))
+		case "jwt error":
+			return errors.New(strings.Join(m.Args, " "))
+		}
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"emperror.dev/errors"
 	"github.com/apex/log"
@@ -72,13 +74,94 @@ type ApiConfiguration struct {
 		KeyFile         string `json:"key" yaml:"key"`
 	}
 
+	// Determines if HTTP/2 should be accepted over plain text connections (h2c) when SSL is
+	// not enabled. This is intended for deployments where Wings is behind a reverse proxy that
+	// terminates TLS and speaks HTTP/2 to its upstreams, and has no effect when SSL is enabled
+	// since HTTP/2 is always available over TLS.
+	H2C bool `default:"false" json:"h2c" yaml:"h2c"`
+
 	// Determines if functionality for allowing remote download of files into server directories
 	// is enabled on this instance. If set to "true" remote downloads will not be possible for
 	// servers.
 	DisableRemoteDownload bool `json:"disable_remote_download" yaml:"disable_remote_download"`
 
-	// The maximum size for files uploaded through the Panel in MB.
-	UploadLimit int64 `default:"100" json:"upload_limit" yaml:"upload_limit"`
+	// Determines if administrators are able to execute commands inside of server containers
+	// using the node's authentication token. The exec endpoints are only available on this
+	// instance when this is set to "true", and commands always run as the container user.
+	EnableContainerExec bool `json:"enable_container_exec" yaml:"enable_container_exec"`
+
+	// The maximum size for files uploaded through the Panel's file manager in MB, and for
+	// archives uploaded to restore the files of a server. The Panel is able to override
+	// both of these for individual servers.
+	UploadLimit        int64 `default:"100" json:"upload_limit" yaml:"upload_limit"`
+	RestoreUploadLimit int64 `default:"1024" json:"restore_upload_limit" yaml:"restore_upload_limit"`
+
+	// Socket allows the API to additionally listen on a Unix domain socket, or a named
+	// pipe on Windows, so that local reverse proxies and tooling can reach Wings without
+	// another TCP port being opened.
+	Socket ApiSocketConfiguration `json:"socket" yaml:"socket"`
+
+	// The maximum number of entries returned for a single request to list the contents of a
+	// directory. Directories with more entries than this must be paginated through.
+	DirectoryListingLimit int `default:"10000" json:"directory_listing_limit" yaml:"directory_listing_limit"`
+
+	// The maximum number of seconds that a signed download URL generated by Wings is valid
+	// for. Signed URLs can only ever be used once, and expire after this time even if they
+	// were never used.
+	SignedUrlLifetime int `default:"60" json:"signed_url_lifetime" yaml:"signed_url_lifetime"`
+
+	// Requires a confirmation token to be sent with requests for destructive actions, such as
+	// deleting a server, deleting files in its root directory, or restoring a backup. The
+	// token is requested from Wings beforehand and can only be used once, within the number
+	// of seconds set by ConfirmationLifetime.
+	RequireConfirmation  bool `default:"false" json:"require_confirmation" yaml:"require_confirmation"`
+	ConfirmationLifetime int  `default:"60" json:"confirmation_lifetime" yaml:"confirmation_lifetime"`
+
+	// The number of requests to compute the checksums of files that can be made for each
+	// server every minute, and the maximum number of files that can be included in each of
+	// those requests. Computing checksums reads every file in full, so these prevent the
+	// disk from being saturated by a single server.
+	ChecksumRateLimit int `default:"30" json:"checksum_rate_limit" yaml:"checksum_rate_limit"`
+	ChecksumMaxFiles  int `default:"50" json:"checksum_max_files" yaml:"checksum_max_files"`
+
+	// WebDav allows the files of a server to be accessed over WebDAV, such as by mapping
+	// them as a network drive in Explorer, using the same credentials as the SFTP server.
+	WebDav WebDavConfiguration `json:"webdav" yaml:"webdav"`
+}
+
+// WebDavConfiguration defines the WebDAV endpoint that is served by the internal
+// API at "/webdav". Credentials are validated by the Panel in the same way as the
+// SFTP server, and the permissions of the user are applied to every request.
+type WebDavConfiguration struct {
+	// Determines if the WebDAV endpoint is enabled. Explorer only allows basic authentication
+	// over HTTPS by default, so SSL should be enabled when it is used on Windows clients.
+	Enabled bool `default:"false" json:"enabled" yaml:"enabled"`
+
+	// If set to true, no write actions will be allowed through the WebDAV endpoint.
+	ReadOnly bool `default:"false" json:"read_only" yaml:"read_only"`
+
+	// The number of seconds that credentials validated by the Panel are cached for. Clients
+	// make a request for every file they access, so without this every one of them would
+	// have to be validated by the Panel.
+	CredentialCache int `default:"60" json:"credential_cache" yaml:"credential_cache"`
+}
+
+// ApiSocketConfiguration defines the local socket that the internal API can
+// listen on in addition to the TCP port. Requests over the socket are still
+// required to be authorized in the same way as any other request.
+type ApiSocketConfiguration struct {
+	// The path to the socket, such as "/run/wings/wings.sock", or the name of the pipe
+	// on Windows, such as "\\.\pipe\wings". The socket is disabled if this is empty.
+	Path string `json:"path" yaml:"path"`
+
+	// The permissions for the socket file on Linux, as an octal string. Access to the
+	// socket is controlled using these permissions and the group of the file.
+	Mode  string `default:"0660" json:"mode" yaml:"mode"`
+	Group string `json:"group" yaml:"group"`
+
+	// The security descriptor for the named pipe on Windows, in SDDL format. By default
+	// only the SYSTEM account and administrators are able to connect.
+	SecurityDescriptor string `default:"D:P(A;;GA;;;SY)(A;;GA;;;BA)" json:"security_descriptor" yaml:"security_descriptor"`
 }
 
 // RemoteQueryConfiguration defines the configuration settings for remote requests
@@ -89,8 +172,23 @@ type RemoteQueryConfiguration struct {
 	// are taking longer than 30 seconds to complete it is likely a performance issue that
 	// should be resolved on the Panel, and not something that should be resolved by upping this
 	// number.
+	//
+	// This applies to each attempt at making a request, so a request that is retried can take
+	// longer than this in total.
 	Timeout int `default:"30" yaml:"timeout"`
 
+	// The number of times a request to the Panel is retried if it fails because of a network
+	// error or the Panel returning a 5xx error. Retries are made using an exponential backoff
+	// with jitter, starting at the RetryBackoff value in milliseconds.
+	Retries      int `default:"3" yaml:"retries"`
+	RetryBackoff int `default:"500" yaml:"retry_backoff"`
+
+	// The number of consecutive failed requests after which Wings will stop sending requests to
+	// the Panel for CircuitBreakerCooldown seconds, failing them immediately instead. This stops
+	// Wings from piling requests onto a Panel that is already struggling. Set to 0 to disable.
+	CircuitBreakerThreshold int `default:"10" yaml:"circuit_breaker_threshold"`
+	CircuitBreakerCooldown  int `default:"30" yaml:"circuit_breaker_cooldown"`
+
 	// The number of servers to load in a single request to the Panel API when booting the
 	// Wings instance. A single request is initially made to the Panel to get this number
 	// of servers, and then the pagination status is checked and additional requests are
@@ -101,6 +199,27 @@ type RemoteQueryConfiguration struct {
 	// 50 servers is likely just as quick as two for 100 or one for 400, and will certainly
 	// be less likely to cause performance issues on the Panel.
 	BootServersPerPage int `default:"50" yaml:"boot_servers_per_page"`
+
+	// The number of pages of servers requested from the Panel at the same time when booting.
+	// Servers are initialized as soon as the page containing them arrives rather than once
+	// every page has been loaded. A page that fails to load is retried BootPageRetries times
+	// before Wings finishes booting without it, the missing pages are then fetched in the
+	// background every OfflineCacheRetry seconds until all the servers have been loaded.
+	BootConcurrency int `default:"4" yaml:"boot_concurrency"`
+	BootPageRetries int `default:"3" yaml:"boot_page_retries"`
+
+	// Whether the server configurations returned by the Panel when booting should be stored
+	// on the disk, allowing Wings to boot and run the existing servers from them when the
+	// Panel is unreachable. While running from the cache Wings will try to fetch the servers
+	// from the Panel again every OfflineCacheRetry seconds, syncing them once it recovers.
+	// The cache contains the secrets of every server, so it is disabled by default.
+	OfflineCache      bool `default:"false" yaml:"offline_cache"`
+	OfflineCacheRetry int  `default:"60" yaml:"offline_cache_retry"`
+
+	// The number of seconds between each heartbeat sent to the Panel, which reports the
+	// version and uptime of Wings, the number of servers, the resources still available on
+	// the node and the operations in progress. Set to 0 to disable sending heartbeats.
+	HeartbeatInterval int `default:"60" yaml:"heartbeat_interval"`
 }
 
 type CrashDetection struct {
@@ -116,6 +235,102 @@ type CrashDetection struct {
 	// to be automatically restarted, this value is used to prevent servers from
 	// becoming stuck in a boot-loop after multiple consecutive crashes.
 	Timeout int `default:"60" json:"timeout"`
+
+	// CollectReports determines if a crash report is written whenever a server is
+	// detected as having crashed. Reports contain the last lines of console output,
+	// the exit state and the container details, and can be retrieved through the API.
+	CollectReports bool `default:"false" yaml:"collect_reports"`
+
+	// ReportLines is the number of lines of console output included in a report.
+	ReportLines int `default:"100" yaml:"report_lines"`
+
+	// ReportRetention is the number of crash reports kept for each server, the oldest
+	// reports are removed when a new one is written.
+	ReportRetention int `default:"10" yaml:"report_retention"`
+
+	// MaintenanceWindows are the times during which crashed servers on this node are
+	// not automatically restarted, so that intentional downtime such as a map reset
+	// does not fight with the crash handler. Servers may also define their own
+	// windows which apply in addition to these.
+	MaintenanceWindows []MaintenanceWindow `yaml:"maintenance_windows"`
+}
+
+// MaintenanceWindow is a time of the day during which a server is expected to be
+// offline. The times are in the format "15:04" using the timezone of the system,
+// and the window may cross midnight. Days limits the window to certain days of the
+// week, such as "mon" or "sat", based on the day that the window starts. If no
+// days are given the window applies every day.
+type MaintenanceWindow struct {
+	Start string   `json:"start" yaml:"start"`
+	End   string   `json:"end" yaml:"end"`
+	Days  []string `json:"days" yaml:"days"`
+}
+
+// Contains returns true if the time is within the maintenance window.
+func (w MaintenanceWindow) Contains(now time.Time) bool {
+	start, err := parseTimeOfDay(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseTimeOfDay(w.End)
+	if err != nil || start == end {
+		return false
+	}
+	cur := timeOfDay(now)
+	if !withinTimeOfDay(start, end, cur) {
+		return false
+	}
+	// When the window crosses midnight the part after midnight belongs to the day
+	// the window started on.
+	day := now.Weekday()
+	if start > end && cur < end {
+		day = (day + 6) % 7
+	}
+	return w.appliesOn(day)
+}
+
+func (w MaintenanceWindow) appliesOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if v, ok := parseWeekday(d); ok && v == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWeekday parses the name of a day of the week, either in full or as the
+// first three letters.
+func parseWeekday(v string) (time.Weekday, bool) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if len(v) < 3 {
+		return 0, false
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if v == name || v == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// StartupDetection defines the node-wide defaults used when determining if a
+// server has failed to reach a running state after being started. These values
+// are used for any server that does not define its own startup configuration.
+type StartupDetection struct {
+	// Timeout is the number of seconds that a server is allowed to remain in the
+	// starting state before the TimeoutAction is applied to it. Set to 0 to allow
+	// a server to remain in the starting state indefinitely.
+	Timeout int `default:"0" yaml:"timeout"`
+
+	// TimeoutAction determines what happens to a server that does not reach the
+	// running state within the timeout. This should be one of "kill", "restart"
+	// or "fail". The "fail" action terminates the process and reports that it
+	// failed to start, without triggering crash detection.
+	TimeoutAction string `default:"kill" yaml:"timeout_action"`
 }
 
 type Backups struct {
@@ -128,6 +343,143 @@ type Backups struct {
 	//
 	// Defaults to 0 (unlimited)
 	WriteLimit int `default:"0" yaml:"write_limit"`
+
+	// WriteLimitSchedule overrides the WriteLimit during certain hours of the day,
+	// such as removing the limit overnight while keeping it in place when servers are
+	// busiest. The first matching entry is used, and the limit is re-evaluated while
+	// a backup is running.
+	WriteLimitSchedule []BandwidthSchedule `yaml:"write_limit_schedule"`
+
+	// ReinstallBackup determines if a backup of the server files should be created
+	// before a server is reinstalled. If the installation fails the files are restored
+	// from the backup. Only the most recent backup is kept for each server.
+	ReinstallBackup bool `default:"true" yaml:"reinstall_backup"`
+
+	// MaxConcurrent is the maximum number of backups that can be generated at the same
+	// time on this node, any other backups wait until one of them has completed.
+	//
+	// Defaults to 0 (unlimited)
+	MaxConcurrent int `default:"0" yaml:"max_concurrent"`
+
+	// Window restricts the backups requested by the Panel to only start during the
+	// given hours of the day, any backups requested outside of it wait until it opens.
+	Window BackupWindow `yaml:"window"`
+
+	// StreamUploads determines if backups using the S3, Azure or GCS adapters are
+	// uploaded as the archive is created rather than writing the entire archive to
+	// the BackupDirectory first. This allows backups to be made on nodes that do not
+	// have enough free disk space to hold the largest server's archive.
+	//
+	// S3 backups still write a single part of the archive to the disk at a time,
+	// while Azure and GCS backups are uploaded directly from memory.
+	StreamUploads bool `default:"false" yaml:"stream_uploads"`
+
+	// BlockCloning determines if local backups are stored as a snapshot of the server
+	// files when the BackupDirectory is on the same ReFS volume, or XFS filesystem
+	// with reflinks, as the server data. The files are cloned using copy-on-write,
+	// which is almost instant and only uses disk space for files that are changed
+	// afterwards. Restoring a snapshot clones the files back into the server.
+	//
+	// This is disabled by default. Backups fall back to creating an archive when
+	// block cloning is not supported.
+	BlockCloning bool `default:"false" yaml:"block_cloning"`
+
+	// Azure configures the Azure Blob Storage container used for backups created
+	// with the "azure" adapter.
+	Azure AzureBackupConfiguration `yaml:"azure"`
+
+	// Gcs configures the Google Cloud Storage bucket used for backups created with
+	// the "gcs" adapter.
+	Gcs GcsBackupConfiguration `yaml:"gcs"`
+}
+
+// AzureBackupConfiguration defines the Azure Blob Storage container that backups
+// are uploaded to. Unlike S3 backups, the credentials for the container are
+// stored on the node rather than being provided by the Panel.
+type AzureBackupConfiguration struct {
+	// ContainerUrl is the URL of the container including a SAS token that allows
+	// reading, writing and deleting blobs, such as
+	// "https://account.blob.core.windows.net/backups?sv=...&sig=...".
+	ContainerUrl string `yaml:"container_url"`
+
+	// Prefix is prepended to the name of every backup stored in the container.
+	// Backups are stored below the prefix in a directory for the server they
+	// belong to, such as "prefix/<server>/<backup>.tar.gz", with the name of the
+	// tenant before the server for servers that do not belong to the primary Panel.
+	Prefix string `yaml:"prefix"`
+}
+
+// GcsBackupConfiguration defines the Google Cloud Storage bucket that backups are
+// uploaded to.
+type GcsBackupConfiguration struct {
+	Bucket string `yaml:"bucket"`
+
+	// CredentialsFile is the path to the JSON key of a service account that is
+	// allowed to create, read and delete objects in the bucket.
+	CredentialsFile string `yaml:"credentials_file"`
+
+	// Prefix is prepended to the name of every backup stored in the bucket.
+	// Backups are stored below the prefix in a directory for the server they
+	// belong to, such as "prefix/<server>/<backup>.tar.gz", with the name of the
+	// tenant before the server for servers that do not belong to the primary Panel.
+	Prefix string `yaml:"prefix"`
+}
+
+// BackupWindow is the time of day during which backups are allowed to start. The
+// times are in the format "15:04" using the timezone of the system, and the window
+// may cross midnight, such as "22:00" to "06:00". If either time is empty backups
+// may start at any time.
+type BackupWindow struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// Until returns how long until backups are allowed to start, which is zero if
+// the window is currently open or there is no window configured.
+func (w BackupWindow) Until(now time.Time) time.Duration {
+	if w.Start == "" || w.End == "" {
+		return 0
+	}
+	start, err := parseTimeOfDay(w.Start)
+	if err != nil {
+		return 0
+	}
+	end, err := parseTimeOfDay(w.End)
+	if err != nil {
+		return 0
+	}
+	cur := timeOfDay(now)
+	if start == end || withinTimeOfDay(start, end, cur) {
+		return 0
+	}
+	d := start - cur
+	if d < 0 {
+		d += time.Hour * 24
+	}
+	return d
+}
+
+// timeOfDay returns the time since midnight.
+func timeOfDay(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// withinTimeOfDay returns true if the time since midnight is between the start
+// and end times, which may cross midnight.
+func withinTimeOfDay(start, end, cur time.Duration) bool {
+	if start < end {
+		return cur >= start && cur < end
+	}
+	return cur >= start || cur < end
+}
+
+// parseTimeOfDay returns the time since midnight for a time in the format "15:04".
+func parseTimeOfDay(v string) (time.Duration, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
 }
 
 type Transfers struct {
@@ -138,6 +490,247 @@ type Transfers struct {
 	//
 	// Defaults to 0 (unlimited)
 	DownloadLimit int `default:"0" yaml:"download_limit"`
+
+	// DownloadLimitSchedule overrides the DownloadLimit during certain hours of the
+	// day in the same way as the WriteLimitSchedule for backups.
+	DownloadLimitSchedule []BandwidthSchedule `yaml:"download_limit_schedule"`
+
+	// The text files to convert to the line endings used by this system when a server
+	// is transferred to this node, in the same format as a .gitignore file. This allows
+	// configuration files from a Linux node to be edited as expected on a Windows node,
+	// and the other way around. Leave empty to keep the files unchanged.
+	ConvertLineEndings []string `yaml:"convert_line_endings"`
+}
+
+// WriteLimitAt returns the write limit for backups in MiB/s at the given time.
+func (b Backups) WriteLimitAt(now time.Time) int {
+	return scheduledLimit(b.WriteLimitSchedule, b.WriteLimit, now)
+}
+
+// DownloadLimitAt returns the download limit for transfers in MiB/s at the given
+// time.
+func (t Transfers) DownloadLimitAt(now time.Time) int {
+	return scheduledLimit(t.DownloadLimitSchedule, t.DownloadLimit, now)
+}
+
+// BandwidthSchedule overrides a limit between the given times of the day, which
+// are in the format "15:04" using the timezone of the system and may cross
+// midnight. A limit less than 1 is unlimited.
+type BandwidthSchedule struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+	Limit int    `yaml:"limit"`
+}
+
+// scheduledLimit returns the limit from the first schedule that contains the time,
+// or the default limit if there are none.
+func scheduledLimit(schedules []BandwidthSchedule, def int, now time.Time) int {
+	cur := timeOfDay(now)
+	for _, s := range schedules {
+		start, err := parseTimeOfDay(s.Start)
+		if err != nil {
+			continue
+		}
+		end, err := parseTimeOfDay(s.End)
+		if err != nil {
+			continue
+		}
+		if start != end && withinTimeOfDay(start, end, cur) {
+			return s.Limit
+		}
+	}
+	return def
+}
+
+// EventBusConfiguration defines the settings for mirroring internal server events
+// onto an external message broker so that other systems can react to them without
+// polling the Panel.
+type EventBusConfiguration struct {
+	// Enabled determines if events should be published to the external broker.
+	Enabled bool `default:"false" json:"enabled" yaml:"enabled"`
+
+	// Driver is the type of broker being published to. This should be one of "nats",
+	// "redis" or "mqtt".
+	Driver string `default:"nats" json:"driver" yaml:"driver"`
+
+	// Address is the host and port of the broker, e.g. "127.0.0.1:4222".
+	Address string `json:"address" yaml:"address"`
+
+	// Optional credentials used when connecting to the broker.
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+
+	// Prefix is prepended to every subject or topic published. Events are published to
+	// "<prefix>.servers.<uuid>.<event>" using the separator native to the driver.
+	Prefix string `default:"pterodactyl" json:"prefix" yaml:"prefix"`
+
+	// Events is the list of event names that should be published. If left empty the
+	// default set of state, resource, installation, and crash events is published.
+	Events []string `json:"events" yaml:"events"`
+}
+
+// WebhookConfiguration defines a single endpoint that node and server events are
+// delivered to.
+type WebhookConfiguration struct {
+	// URL is the endpoint that the event payload is sent to using a POST request.
+	URL string `json:"url" yaml:"url"`
+
+	// Secret is used to sign the body of each request using HMAC-SHA256, the result
+	// is sent in the "X-Wings-Signature" header. If empty requests are not signed.
+	Secret string `json:"secret" yaml:"secret"`
+
+	// Format controls the shape of the request body. This should be one of "json",
+	// "discord" or "slack".
+	Format string `default:"json" json:"format" yaml:"format"`
+
+	// Events is the list of event names sent to this endpoint. If left empty every
+	// event is sent.
+	Events []string `json:"events" yaml:"events"`
+}
+
+// WebhooksConfiguration defines the webhook endpoints that notifications are sent
+// to when certain events occur on the node, such as a server crashing.
+type WebhooksConfiguration struct {
+	// The number of times delivery of an event is retried before it is discarded.
+	Retries int `default:"3" json:"retries" yaml:"retries"`
+
+	// The number of seconds to wait for an endpoint to respond before the request is
+	// considered failed.
+	Timeout int `default:"10" json:"timeout" yaml:"timeout"`
+
+	Endpoints []WebhookConfiguration `json:"endpoints" yaml:"endpoints"`
+}
+
+// The scopes supported for the permission check performed when a server boots.
+const (
+	CheckPermissionsScopeAll      = "all"
+	CheckPermissionsScopeTopLevel = "top_level"
+)
+
+// The modes supported for counting the disk usage of a server.
+const (
+	DiskUsageModeLogical   = "logical"
+	DiskUsageModeAllocated = "allocated"
+)
+
+// The algorithms supported for compressing the data directories of servers.
+const (
+	VolumeCompressionNTFS      = "ntfs"
+	VolumeCompressionXpress4K  = "xpress4k"
+	VolumeCompressionXpress8K  = "xpress8k"
+	VolumeCompressionXpress16K = "xpress16k"
+	VolumeCompressionLZX       = "lzx"
+)
+
+// IsVolumeCompression returns true if the algorithm is a supported compression
+// algorithm for the data directories of servers.
+func IsVolumeCompression(algorithm string) bool {
+	switch algorithm {
+	case VolumeCompressionNTFS, VolumeCompressionXpress4K, VolumeCompressionXpress8K, VolumeCompressionXpress16K, VolumeCompressionLZX:
+		return true
+	}
+	return false
+}
+
+// The policies supported for files with conflicting names.
+const (
+	NameConflictRename = "rename"
+	NameConflictSkip   = "skip"
+)
+
+// The modes supported for the overcommit guard.
+const (
+	OvercommitModeOff    = "off"
+	OvercommitModeWarn   = "warn"
+	OvercommitModeRefuse = "refuse"
+)
+
+// OvercommitConfiguration defines how far the resources allocated to the servers
+// on the node may exceed the resources the node actually has.
+type OvercommitConfiguration struct {
+	// Mode determines what happens when creating or starting a server would allocate
+	// more resources than allowed. This should be one of "off", "warn" or "refuse".
+	Mode string `default:"off" json:"mode" yaml:"mode"`
+
+	// The ratio of allocated resources to the resources of the node. For example a
+	// memory ratio of 1.5 allows servers to be allocated 150% of the node memory.
+	// Servers without a limit for a resource are not counted towards it.
+	Memory float64 `default:"1" json:"memory" yaml:"memory"`
+	Cpu    float64 `default:"4" json:"cpu" yaml:"cpu"`
+	Disk   float64 `default:"1" json:"disk" yaml:"disk"`
+}
+
+// The strategies supported for rotating the log files written by Wings.
+const (
+	LogRotationLogrotate = "logrotate"
+	LogRotationBuiltin   = "builtin"
+	LogRotationNone      = "none"
+)
+
+// LogRotationConfiguration defines how the log files written by Wings are rotated
+// so that they do not grow without limit.
+type LogRotationConfiguration struct {
+	// Strategy is either "logrotate" to write a configuration file for the system
+	// logrotate, "builtin" to rotate the files within Wings itself, or "none". If empty
+	// "logrotate" is used on Linux and "builtin" is used on Windows.
+	Strategy string `json:"strategy" yaml:"strategy"`
+
+	// The size in MiB at which wings.log, and the log of an installation that is
+	// running, are rotated by the builtin strategy. Only the previous part of an
+	// installation log is kept once it has been rotated.
+	MaxSize int `default:"10" json:"max_size" yaml:"max_size"`
+
+	// The number of days that rotated logs, and the logs of previous installations, are
+	// kept for. The log of the most recent installation of each server is always kept.
+	MaxAge int `default:"7" json:"max_age" yaml:"max_age"`
+
+	// The maximum number of rotated logs that are kept, zero keeps every log that is
+	// not older than the maximum age.
+	MaxBackups int `default:"0" json:"max_backups" yaml:"max_backups"`
+
+	// Whether rotated logs, and the logs of previous installations, are compressed
+	// using gzip by the builtin strategy.
+	Compress bool `default:"true" json:"compress" yaml:"compress"`
+}
+
+// Mode returns the strategy used to rotate logs, using the default strategy for
+// the system when one has not been set.
+func (c LogRotationConfiguration) Mode() string {
+	if c.Strategy == "" {
+		return defaultLogRotationStrategy
+	}
+	return c.Strategy
+}
+
+// ConsoleLogConfiguration defines how the console output of servers is written to
+// log files within the "servers" directory of the log directory, which allows the
+// output to be reviewed after a server's container has been removed or recreated.
+type ConsoleLogConfiguration struct {
+	// Determines if the console output of servers is written to log files.
+	Enabled bool `default:"false" json:"enabled" yaml:"enabled"`
+
+	// The size in MiB at which the console log of a server is rotated.
+	MaxSize int `default:"10" json:"max_size" yaml:"max_size"`
+
+	// The number of rotated console logs that are kept for each server.
+	MaxBackups int `default:"5" json:"max_backups" yaml:"max_backups"`
+
+	// Whether rotated console logs are compressed using gzip.
+	Compress bool `default:"true" json:"compress" yaml:"compress"`
+}
+
+// MirrorConfiguration defines how the mirror jobs of servers are run, which keep
+// a directory of a server in sync with a remote endpoint.
+type MirrorConfiguration struct {
+	// Determines if the mirror jobs defined for servers are run on this node. This
+	// is disabled by default. Remotes within the local network of the node can only
+	// be used if they are in the allowed_internal_networks configuration.
+	Enabled bool `default:"false" json:"enabled" yaml:"enabled"`
+
+	// The maximum number of mirror jobs that are run at the same time across every
+	// server on the node. Jobs that are due while this many are running wait for one
+	// of them to complete.
+	MaxConcurrent int `default:"2" json:"max_concurrent" yaml:"max_concurrent"`
 }
 
 type ConsoleThrottles struct {
@@ -175,28 +768,76 @@ type Configuration struct {
 	// validate against it.
 	AuthenticationToken string `json:"token" yaml:"token"`
 
+	// EncryptToken encrypts the authentication token when the configuration is written
+	// to the disk, so that it is not exposed to anyone able to read the configuration
+	// file. DPAPI is used on Windows, and a key stored in the root directory is used on
+	// Linux. Encrypted tokens are always decrypted when the configuration is loaded.
+	EncryptToken bool `json:"-" yaml:"encrypt_token"`
+
 	Api    ApiConfiguration    `json:"api" yaml:"api"`
 	System SystemConfiguration `json:"system" yaml:"system"`
 	Docker DockerConfiguration `json:"docker" yaml:"docker"`
 
+	// Containerd allows server processes to be run using containerd directly rather
+	// than through the Docker Engine API.
+	Containerd ContainerdConfiguration `json:"containerd" yaml:"containerd"`
+
 	// Defines internal throttling configurations for server processes to prevent
 	// someone from running an endless loop that spams data to logs.
 	Throttles ConsoleThrottles
 
+	// EventBus defines an optional external broker that server events are mirrored to.
+	EventBus EventBusConfiguration `json:"event_bus" yaml:"event_bus"`
+
+	// Webhooks defines endpoints that are notified when certain events occur.
+	Webhooks WebhooksConfiguration `json:"webhooks" yaml:"webhooks"`
+
+	// Overcommit limits the resources that can be allocated to the servers on the
+	// node relative to the resources available.
+	Overcommit OvercommitConfiguration `json:"overcommit" yaml:"overcommit"`
+
+	// Mirrors defines how the mirror jobs configured for servers are run.
+	Mirrors MirrorConfiguration `json:"mirrors" yaml:"mirrors"`
+
+	// Tasks defines the tasks that are run on a schedule for the node itself. These
+	// are managed through the configuration file or the API rather than by the Panel.
+	Tasks TasksConfiguration `json:"-" yaml:"tasks"`
+
 	// The location where the panel is running that this daemon should connect to
 	// to collect data and send events.
 	PanelLocation string                   `json:"remote" yaml:"remote"`
 	RemoteQuery   RemoteQueryConfiguration `json:"remote_query" yaml:"remote_query"`
 
+	// Tenants defines additional Panels that this node is registered with. Each tenant
+	// has its own set of servers that are stored in a separate data directory, and
+	// requests from one tenant are never able to access the servers of another.
+	Tenants []TenantConfiguration `json:"-" yaml:"tenants"`
+
 	// AllowedMounts is a list of allowed host-system mount points.
 	// This is required to have the "Server Mounts" feature work properly.
 	AllowedMounts []string `json:"-" yaml:"allowed_mounts"`
 
+	// AllowedImportPaths is a list of host directories that files can be imported
+	// from through the API. Files can always be imported using the import command.
+	AllowedImportPaths []string `json:"-" yaml:"allowed_import_paths"`
+
+	// AllowedInternalNetworks is a list of networks within the local network of the
+	// node, in CIDR notation, that Wings can connect to when following URLs that are
+	// passed to it, such as backup download links and mirror remotes. Addresses
+	// within the local network are refused otherwise.
+	AllowedInternalNetworks []string `json:"-" yaml:"allowed_internal_networks"`
+
 	// AllowedOrigins is a list of allowed request origins.
 	// The Panel URL is automatically allowed, this is only needed for adding
-	// additional origins.
+	// additional origins. A wildcard can be used for the subdomain, such as
+	// "https://*.example.com" to allow every subdomain of example.com.
 	AllowedOrigins []string `json:"allowed_origins" yaml:"allowed_origins"`
 
+	// CorsOrigins is a list of additional origins that are allowed, along with the
+	// methods and headers that requests from each of them can use. These can also
+	// be changed through the API without restarting Wings.
+	CorsOrigins []CorsOrigin `json:"cors_origins" yaml:"cors_origins"`
+
 	// AllowCORSPrivateNetwork sets the `Access-Control-Request-Private-Network` header which
 	// allows client browsers to make requests to internal IP addresses over HTTP.  This setting
 	// is only required by users running Wings without SSL certificates and using internal IP
@@ -291,6 +932,20 @@ func WriteToDisk(c *Configuration) error {
 	if c.path == "" {
 		return errors.New("cannot write configuration, no path defined in struct")
 	}
+	if ccopy.EncryptToken {
+		t, err := encryptValue(&ccopy, ccopy.AuthenticationToken)
+		if err != nil {
+			return err
+		}
+		ccopy.AuthenticationToken = t
+		// The slice is copied so that the tokens held in memory are left decrypted.
+		ccopy.Tenants = append([]TenantConfiguration(nil), c.Tenants...)
+		for i := range ccopy.Tenants {
+			if ccopy.Tenants[i].AuthenticationToken, err = encryptValue(&ccopy, ccopy.Tenants[i].AuthenticationToken); err != nil {
+				return err
+			}
+		}
+	}
 	b, err := yaml.Marshal(&ccopy)
 	if err != nil {
 		return err
@@ -317,6 +972,17 @@ func FromFile(path string) error {
 		return err
 	}
 
+	// The token is kept decrypted in memory, and is encrypted again when the
+	// configuration is written back to the disk.
+	if c.AuthenticationToken, err = decryptValue(c, c.AuthenticationToken); err != nil {
+		return err
+	}
+	for i := range c.Tenants {
+		if c.Tenants[i].AuthenticationToken, err = decryptValue(c, c.Tenants[i].AuthenticationToken); err != nil {
+			return err
+		}
+	}
+
 	// Store this configuration in the global state.
 	Set(c)
 	return nil
@@ -379,6 +1045,9 @@ func EnableLogRotation() error {
 		log.Info("skipping log rotate configuration, disabled in wings config file")
 		return nil
 	}
+	if _config.System.LogRotation.Mode() != LogRotationLogrotate {
+		return nil
+	}
 
 	if st, err := os.Stat("/etc/logrotate.d"); err != nil && !os.IsNotExist(err) {
 		return err
@@ -422,3 +1091,12 @@ func EnableLogRotation() error {
 func (sc *SystemConfiguration) GetStatesPath() string {
 	return path.Join(sc.RootDirectory, "/states.json")
 }
+
+// GetServerCachePath returns the location of the JSON file that the server
+// configurations returned by the Panel for the tenant are cached in.
+func (sc *SystemConfiguration) GetServerCachePath(tenant string) string {
+	if tenant == "" {
+		return filepath.Join(sc.RootDirectory, "servers.json")
+	}
+	return filepath.Join(sc.RootDirectory, "servers."+tenant+".json")
+}
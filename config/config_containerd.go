@@ -0,0 +1,30 @@
+package config
+
+// ContainerdConfiguration defines the settings used when running server processes
+// with containerd directly instead of the Docker Engine. This support is currently
+// experimental, and installation containers still require the Docker Engine.
+type ContainerdConfiguration struct {
+	// Enabled determines if server containers should be managed using containerd.
+	Enabled bool `default:"false" json:"enabled" yaml:"enabled"`
+
+	// Address is the address of the containerd socket (or named pipe on Windows). If
+	// empty the default address for the platform is used.
+	Address string `json:"address" yaml:"address"`
+
+	// Namespace is the containerd namespace that all server containers and images are
+	// created within.
+	Namespace string `default:"pterodactyl" json:"namespace" yaml:"namespace"`
+
+	// Snapshotter is the snapshotter used when creating container filesystems. If
+	// empty the containerd default for the platform is used.
+	Snapshotter string `json:"snapshotter" yaml:"snapshotter"`
+
+	// Runtime is the runtime used to run containers, for example "io.containerd.runc.v2".
+	// If empty the containerd default for the platform is used.
+	Runtime string `json:"runtime" yaml:"runtime"`
+
+	// NetworkNamespace is the network namespace that server containers are attached to
+	// on Windows, such as one created for the HNS network using CNI. On Linux servers
+	// always use the host network and bind directly to their allocations.
+	NetworkNamespace string `json:"network_namespace" yaml:"network_namespace"`
+}
@@ -8,6 +8,20 @@ import (
 	"github.com/goccy/go-json"
 )
 
+// The container engines that can be used through the Docker Engine API.
+const (
+	EngineDocker = "docker"
+	EnginePodman = "podman"
+)
+
+// The policies for containers created by Wings that do not belong to any server
+// on the node when Wings is started.
+const (
+	OrphanPolicyFlag   = "flag"
+	OrphanPolicyStop   = "stop"
+	OrphanPolicyRemove = "remove"
+)
+
 type dockerNetworkInterfaces struct {
 	V4 struct {
 		Subnet  string `default:"172.18.0.0/16"`
@@ -22,6 +36,15 @@ type dockerNetworkInterfaces struct {
 // DockerConfiguration defines the docker configuration used by the daemon when
 // interacting with containers and networks on the system.
 type DockerConfiguration struct {
+	// Engine is the container engine that Wings communicates with using the Docker
+	// Engine API. This should be either "docker" or "podman".
+	Engine string `default:"docker" json:"engine" yaml:"engine"`
+
+	// Socket is the address of the API socket for the container engine, for example
+	// "unix:///run/user/1000/podman/podman.sock". If empty the DOCKER_HOST environment
+	// variable is used, falling back to the default socket for the engine.
+	Socket string `json:"socket" yaml:"socket"`
+
 	// Network configuration that should be used when creating a new network
 	// for containers run through the daemon.
 	Network DockerNetworkConfiguration `json:"network" yaml:"network"`
@@ -44,19 +67,260 @@ type DockerConfiguration struct {
 	ContainerPidLimit int64 `default:"512" json:"container_pid_limit" yaml:"container_pid_limit"`
 
 	// InstallerLimits defines the limits on the installer containers that prevents a server's
-	// installation process from unintentionally consuming more resources than expected. This
-	// is used in conjunction with the server's defined limits. Whichever value is higher will
-	// take precedence in the installer containers.
+	// installation process from unintentionally consuming more resources than expected. These
+	// are used in place of the server's defined limits, so that servers with low limits are
+	// still able to install and heavy installation processes can be capped. The limits can
+	// be overridden for individual eggs by the Panel. A value of 0 means there is no limit.
 	InstallerLimits struct {
 		Memory int64 `default:"1024" json:"memory" yaml:"memory"`
 		Cpu    int64 `default:"100" json:"cpu" yaml:"cpu"`
+		// Disk is the amount of disk space in megabytes that the installation process can
+		// write to the server files, on top of the files that already existed before it
+		// started. The installer is stopped if this is exceeded.
+		Disk int64 `default:"0" json:"disk" yaml:"disk"`
 	} `json:"installer_limits" yaml:"installer_limits"`
 
+	// InstallerImage is the image used for every installer container in place of the image
+	// defined by the egg. This can be overridden for individual eggs by the Panel.
+	InstallerImage string `json:"installer_image" yaml:"installer_image"`
+
+	// InstallerMaxConcurrent is the maximum number of installation processes that can run
+	// at the same time across every server on the node, including pulling the installer
+	// image. Installations started while this many are running wait for one to complete,
+	// in the order they were started. A value of 0 means there is no limit.
+	InstallerMaxConcurrent int `default:"0" json:"installer_max_concurrent" yaml:"installer_max_concurrent"`
+
+	// Isolation is the isolation mode used for containers on Windows nodes, either "process"
+	// or "hyperv". Process isolation requires images to be built for the same build of Windows
+	// as the node, while Hyper-V isolation can also run images built for older builds. If this
+	// is empty the default isolation mode of Docker is used. This is ignored on Linux nodes.
+	Isolation string `json:"isolation" yaml:"isolation"`
+
 	// Overhead controls the memory overhead given to all containers to circumvent certain
 	// software such as the JVM not staying below the maximum memory limit.
 	Overhead Overhead `json:"overhead" yaml:"overhead"`
 
 	UsePerformantInspect bool `default:"true" json:"use_performant_inspect" yaml:"use_performant_inspect"`
+
+	// PullPolicy determines when images for server and installer containers are pulled
+	// from their registry. This should be one of "always", "if-not-present", or "never".
+	// Nodes that cannot reach a registry should use "never" with pre-loaded images. This
+	// can be overridden for individual servers by the Panel.
+	PullPolicy string `default:"always" json:"pull_policy" yaml:"pull_policy"`
+
+	// PrePullImages is a list of images that are pulled in the background when Wings is
+	// started so that they are already available the first time a server needs them.
+	// Images that already exist are refreshed unless the pull policy prevents it.
+	PrePullImages []string `json:"pre_pull_images" yaml:"pre_pull_images"`
+
+	// RuntimeImages controls the automatic selection of a container image based on the
+	// runtime version a server requires.
+	RuntimeImages RuntimeImages `json:"runtime_images" yaml:"runtime_images"`
+
+	// ImageGarbageCollection controls the periodic removal of images that are no longer
+	// used by any container on the system.
+	ImageGarbageCollection ImageGarbageCollection `json:"image_garbage_collection" yaml:"image_garbage_collection"`
+
+	// CpuPinning controls the automatic assignment of servers to dedicated CPU cores
+	// when the Panel has not assigned any to them. This is only used on Linux.
+	CpuPinning CpuPinning `json:"cpu_pinning" yaml:"cpu_pinning"`
+
+	// SecurityProfiles defines the seccomp and AppArmor profiles applied to server
+	// containers. These are only used on Linux.
+	SecurityProfiles SecurityProfiles `json:"security_profiles" yaml:"security_profiles"`
+
+	// StatsCollector controls how the resource usage of server containers is
+	// collected from Docker.
+	StatsCollector StatsCollector `json:"stats_collector" yaml:"stats_collector"`
+
+	// Gpu controls passing the GPUs of the host through to server containers.
+	Gpu GpuPassthrough `json:"gpu" yaml:"gpu"`
+
+	// Reconnect controls how Wings recovers when the connection to the Docker daemon
+	// is lost, such as when Docker Desktop is restarted on Windows.
+	Reconnect DockerReconnect `json:"reconnect" yaml:"reconnect"`
+
+	// OrphanPolicy determines what happens to containers created by Wings that do not
+	// belong to any server on the node when Wings is started, such as the container of
+	// a server that was deleted while Wings was not running. This should be one of
+	// "flag" to only report them, "stop" to stop them if they are running, or "remove"
+	// to remove them. Orphans are only ever reported if the servers for every Panel
+	// could not be loaded when booting.
+	OrphanPolicy string `default:"flag" json:"orphan_policy" yaml:"orphan_policy"`
+
+	// EntrypointWrappers are the wrappers that eggs are allowed to run in front of the
+	// entrypoint of their image, keyed by the name an egg uses to request one.
+	EntrypointWrappers map[string]EntrypointWrapper `json:"entrypoint_wrappers" yaml:"entrypoint_wrappers"`
+}
+
+// EntrypointWrapper defines an executable or script provided by the node that is
+// run in place of the entrypoint of a server image, allowing it to set up the
+// environment, logging or crash handlers before launching the server process.
+// The entrypoint and command of the image are passed to the wrapper as arguments,
+// and the wrapper is expected to run them once it has finished.
+type EntrypointWrapper struct {
+	// Source is the directory on the host containing the wrapper, which is mounted
+	// read-only into the container at "/Wrapper". A directory is used rather than a
+	// single file since Windows containers are only able to mount directories.
+	Source string `json:"source" yaml:"source"`
+
+	// Command is the command used to run the wrapper within the container, such as
+	// ["/Wrapper/start.sh"] or ["powershell", "-File", "C:\\Wrapper\\start.ps1"].
+	Command []string `json:"command" yaml:"command"`
+
+	// Environment is a set of variables added to the container for the wrapper, in
+	// addition to the variables for the server.
+	Environment map[string]string `json:"environment" yaml:"environment"`
+}
+
+// DockerReconnect defines the settings for detecting that the Docker daemon has
+// been restarted or is no longer reachable. While the daemon cannot be reached
+// the node is marked as degraded, and once it is reachable again Wings attaches
+// to the containers that are still running and starts the servers whose
+// containers were stopped by the daemon going away.
+type DockerReconnect struct {
+	// Enabled controls if the connection to the Docker daemon is monitored.
+	Enabled bool `default:"true" json:"enabled" yaml:"enabled"`
+
+	// Interval is the number of seconds between each check of the connection.
+	Interval int `default:"5" json:"interval" yaml:"interval"`
+
+	// RestartServers controls if servers that were running when the connection was
+	// lost are started again once it is restored. When disabled they are left
+	// offline, but Wings still attaches to any containers that kept running.
+	RestartServers bool `default:"true" json:"restart_servers" yaml:"restart_servers"`
+}
+
+// GpuPassthrough defines the settings for giving server containers access to the
+// GPUs on the host. GPUs are only passed through to the servers that the Panel has
+// enabled it for, and the GPU utilization and video memory used by those servers
+// is included in their resource usage.
+type GpuPassthrough struct {
+	// Enabled controls if GPUs can be passed through to server containers. On Linux
+	// this requires the NVIDIA Container Toolkit to be installed. On Windows the GPUs
+	// are shared with containers using the DirectX device class, which is only
+	// supported by process isolated containers.
+	Enabled bool `default:"false" json:"enabled" yaml:"enabled"`
+
+	// Devices is a list of the GPU indexes or UUIDs to pass through on Linux. When
+	// empty every GPU is passed through. Every GPU is always shared on Windows.
+	Devices []string `json:"devices" yaml:"devices"`
+}
+
+// StatsCollector defines the settings for the shared resource usage collector. Rather
+// than every running server keeping its own stats stream open with Docker, a single
+// collector requests the usage of all running containers at a fixed interval. This
+// greatly reduces the number of connections and goroutines on nodes running a large
+// number of servers.
+type StatsCollector struct {
+	// Enabled controls if the shared collector is used. When disabled every server
+	// streams its own resource usage from Docker.
+	Enabled bool `default:"true" json:"enabled" yaml:"enabled"`
+
+	// Interval is the number of seconds between each collection of resource usage.
+	// When the shared collector is disabled this instead limits how often the usage
+	// streamed from Docker is sent along to the Panel.
+	Interval int `default:"1" json:"interval" yaml:"interval"`
+
+	// OnDemand only collects the resource usage of a server while at least one
+	// websocket client is connected to it. This greatly reduces the work done by
+	// Wings on idle nodes, however the usage reported for a server through the API
+	// is not updated, and memory and CPU alerts are not checked, while nobody is
+	// watching the server. The disk limit of running servers is still enforced.
+	OnDemand bool `default:"false" json:"on_demand" yaml:"on_demand"`
+
+	// Concurrency is the maximum number of containers that have their resource usage
+	// requested from Docker at the same time.
+	Concurrency int `default:"10" json:"concurrency" yaml:"concurrency"`
+}
+
+// CpuPinning defines the settings for automatically pinning servers to CPU cores.
+// Servers are assigned one core for every 100% of CPU limit, and are packed onto
+// the fewest NUMA nodes possible so that a server never spans multiple nodes if
+// it fits within one. Servers without a CPU limit are never pinned.
+type CpuPinning struct {
+	// Enabled controls if servers should be assigned to CPU cores automatically.
+	Enabled bool `default:"false" json:"enabled" yaml:"enabled"`
+
+	// Reserved is a set of CPUs that servers are never assigned to, in the same
+	// format used by the Panel for server threads, for example "0-1,16".
+	Reserved string `json:"reserved" yaml:"reserved"`
+}
+
+// SecurityProfiles defines the seccomp and AppArmor profiles that are applied to
+// server containers, along with the profiles that eggs are allowed to request in
+// place of them.
+type SecurityProfiles struct {
+	// Seccomp is the path to a JSON seccomp profile for server containers. If empty
+	// the default profile bundled with the container engine is used, which blocks
+	// the syscalls that are not safe to use in a container. Setting this to
+	// "unconfined" disables seccomp filtering entirely.
+	Seccomp string `json:"seccomp" yaml:"seccomp"`
+
+	// AppArmor is the name of an AppArmor profile that is loaded on the host. If
+	// empty the default profile bundled with the container engine is used, which is
+	// "docker-default" for Docker.
+	AppArmor string `json:"apparmor" yaml:"apparmor"`
+
+	// SeccompDirectory is the directory that seccomp profiles requested by an egg
+	// are loaded from. An egg requesting the "java" profile will use the file
+	// "java.json" within this directory.
+	SeccompDirectory string `default:"/etc/pterodactyl/seccomp" json:"seccomp_directory" yaml:"seccomp_directory"`
+
+	// AllowedAppArmorProfiles is a list of the AppArmor profiles that eggs are
+	// allowed to request. Eggs are never allowed to run containers unconfined.
+	AllowedAppArmorProfiles []string `json:"allowed_apparmor_profiles" yaml:"allowed_apparmor_profiles"`
+}
+
+// ImageGarbageCollection defines the settings for periodically removing unused server
+// and installer images, along with any dangling image layers, from the system. Images
+// for servers on the node are only removed if no container currently uses them.
+type ImageGarbageCollection struct {
+	// Enabled controls if unused images should be removed automatically.
+	Enabled bool `default:"false" json:"enabled" yaml:"enabled"`
+
+	// Interval is the number of minutes between each run of the garbage collector.
+	Interval int `default:"1440" json:"interval" yaml:"interval"`
+
+	// MinimumAge is the number of hours since an image was created before it can be
+	// removed, so that recently pulled images are not removed before they are used.
+	MinimumAge int `default:"24" json:"minimum_age" yaml:"minimum_age"`
+
+	// Keep is a list of images that should never be removed, even if unused. Entries
+	// may be a full reference ("ghcr.io/pterodactyl/yolks:java_17"), a repository
+	// without a tag to match every tag, or a pattern such as "ghcr.io/pterodactyl/*".
+	// Images defined in the runtime image mappings or pre-pulled at boot, and the
+	// images and installer images of every server on the node, are always kept.
+	Keep []string `json:"keep" yaml:"keep"`
+}
+
+// RuntimeImages defines a mapping between a detected runtime version and the image
+// that should be used to run a server requiring it. When a runtime is detected for
+// a server and an image is mapped for it, that image is used in place of the image
+// assigned to the server by the Panel.
+type RuntimeImages struct {
+	// Enabled controls if images should be swapped based on the detected runtime.
+	Enabled bool `default:"false" json:"enabled" yaml:"enabled"`
+
+	// Images maps a runtime to an image. Keys are in the format of "runtime:version",
+	// for example:
+	//
+	// ```yaml
+	// images:
+	//   java:8: ghcr.io/pterodactyl/yolks:java_8
+	//   java:17: ghcr.io/pterodactyl/yolks:java_17
+	// ```
+	Images map[string]string `json:"images" yaml:"images"`
+}
+
+// ImageFor returns the image mapped to the given runtime key, and a boolean
+// indicating if one was found.
+func (r RuntimeImages) ImageFor(runtime string) (string, bool) {
+	if !r.Enabled || runtime == "" {
+		return "", false
+	}
+	img, ok := r.Images[runtime]
+	return img, ok && img != ""
 }
 
 // RegistryConfiguration defines the authentication credentials for a given
@@ -103,9 +367,26 @@ type Overhead struct {
 	//   4096: 1.10
 	// ```
 	Multipliers map[int]float64 `json:"multipliers" yaml:"multipliers"`
+
+	// Percentage is a flat percentage of memory overhead given to all servers, used in
+	// place of the default multipliers when Override is `false`. Runtimes such as the
+	// JVM or .NET that use memory outside the heap generally need 10-20%. If set to
+	// 0 the default multipliers are used.
+	Percentage float64 `default:"0" json:"percentage" yaml:"percentage"`
+
+	// ReservedMemory is the amount of host memory in megabytes that is kept free for
+	// the system and Wings. No server is given a memory limit greater than the total
+	// memory of the host minus this value, and servers without a memory limit are
+	// limited to it, which prevents a single server from running the node out of
+	// memory.
+	ReservedMemory int64 `default:"0" json:"reserved_memory" yaml:"reserved_memory"`
 }
 
 func (o Overhead) GetMultiplier(memoryLimit int64) float64 {
+	if !o.Override && o.Percentage > 0 {
+		return 1 + o.Percentage/100
+	}
+
 	// Default multiplier values.
 	if !o.Override {
 		if memoryLimit <= 2048 {
@@ -18,6 +18,10 @@ import (
 
 const DefaultLocation = "/etc/pterodactyl/config.yml"
 
+// The strategy used to rotate the log files written by Wings when one has not
+// been configured.
+const defaultLogRotationStrategy = LogRotationLogrotate
+
 // SystemConfiguration defines basic system configuration settings.
 type SystemConfiguration struct {
 	// The root directory where all of the pterodactyl data is stored at.
@@ -52,9 +56,38 @@ type SystemConfiguration struct {
 	// Definitions for the user that gets created to ensure that we can quickly access
 	// this information without constantly having to do a system lookup.
 	User struct {
-		Uid int
-		Gid int
-	}
+		// Rootless controls the settings used when the container daemon is running
+		// as an unprivileged user, such as rootless Docker or rootless Podman.
+		Rootless struct {
+			// Enabled should be set to true when the container daemon is rootless. Wings
+			// will then run as, and give ownership of all files to, the current user.
+			Enabled bool `default:"false" yaml:"enabled"`
+
+			// ContainerUID and ContainerGID are the user and group that processes run
+			// as inside containers. With a rootless daemon the root user inside the
+			// container is mapped to the user running the daemon, so files created
+			// by the server are owned by that user on the host.
+			ContainerUID int `default:"0" yaml:"container_uid"`
+			ContainerGID int `default:"0" yaml:"container_gid"`
+		} `yaml:"rootless"`
+
+		// PerServer assigns each server its own user and group on the host, rather
+		// than every server running as the single system user. A process escaping
+		// a server container is then unable to access the files of other servers.
+		// This is not supported with a rootless container daemon.
+		PerServer struct {
+			// Enabled controls if servers are assigned their own user and group.
+			Enabled bool `default:"false" yaml:"enabled"`
+
+			// BaseID is the first uid and gid assigned to a server. Each server is
+			// assigned the next unused ID, so this should be set to a range that is
+			// not used by any other users or groups on the system.
+			BaseID int `default:"200000" yaml:"base_id"`
+		} `yaml:"per_server"`
+
+		Uid int `yaml:"uid"`
+		Gid int `yaml:"gid"`
+	} `yaml:"user"`
 
 	// The amount of time in seconds that can elapse before a server's disk space calculation is
 	// considered stale and a re-check should occur. DANGER: setting this value too low can seriously
@@ -66,23 +99,80 @@ type SystemConfiguration struct {
 	// disk usage is not a concern.
 	DiskCheckInterval int64 `default:"150" yaml:"disk_check_interval"`
 
+	// Determines how the size of a file is counted towards the disk usage of a server. When
+	// set to "logical" the apparent size of each file is counted, and when set to "allocated"
+	// only the space actually allocated on the disk is counted. Counting the allocated size
+	// avoids servers being charged for sparse files, such as game worlds that preallocate
+	// large regions that are never written to.
+	DiskUsageMode string `default:"logical" yaml:"disk_usage_mode"`
+
+	// The compression to apply to the data directories of servers, this can be overridden
+	// for each server by the Panel. Use "ntfs" for NTFS compression, which is applied to new
+	// files as they are written, or one of "xpress4k", "xpress8k", "xpress16k" or "lzx" for
+	// Compact OS style compression, which gives better ratios but is only applied to existing
+	// files when running "wings compress". Leave empty to disable compression.
+	//
+	// Compression is only supported on Windows volumes and is ignored on Linux.
+	VolumeCompression string `yaml:"volume_compression"`
+
+	// Determines what happens to files that are extracted from an archive, restored from a
+	// backup, or received in a transfer, which have names that only differ by case from
+	// another file or that contain characters that cannot be used on this system. When set
+	// to "rename" the file is written using a new name, and when set to "skip" the file is
+	// not written. Every file that is renamed or skipped is reported.
+	NameConflicts string `default:"rename" yaml:"name_conflicts"`
+
 	// If set to true, file permissions for a server will be checked when the process is
 	// booted. This can cause boot delays if the server has a large amount of files. In most
 	// cases disabling this should not have any major impact unless external processes are
 	// frequently modifying a servers' files.
 	CheckPermissionsOnBoot bool `default:"true" yaml:"check_permissions_on_boot"`
 
-	// If set to false Wings will not attempt to write a log rotate configuration to the disk
-	// when it boots and one is not detected.
+	// The scope of the permission check performed when a server boots. When set to
+	// "all" every file is checked, when set to "top_level" only the server's root
+	// directory and the files directly within it are checked.
+	CheckPermissionsScope string `default:"all" yaml:"check_permissions_scope"`
+
+	// If set to true the permission check is performed in the background once the
+	// server has been started rather than delaying the boot until it is complete.
+	CheckPermissionsAsync bool `default:"false" yaml:"check_permissions_async"`
+
+	// The number of workers used to check the permissions of a server's files. Set
+	// to 0 to use one worker for every CPU core.
+	CheckPermissionsWorkers int `default:"0" yaml:"check_permissions_workers"`
+
+	// If set to false Wings will not rotate its log files, either by writing a log rotate
+	// configuration to the disk when it boots and one is not detected, or by rotating them
+	// itself, depending on the log rotation strategy.
 	EnableLogRotate bool `default:"true" yaml:"enable_log_rotate"`
 
+	// LogRotation controls how the log files written by Wings are rotated.
+	LogRotation LogRotationConfiguration `yaml:"log_rotation"`
+
 	// The number of lines to send when a server connects to the websocket.
 	WebsocketLogCount int `default:"150" yaml:"websocket_log_count"`
 
+	// The number of installation logs to keep for each server. The logs for the oldest
+	// installation attempts are removed when a server is installed again.
+	InstallLogRetention int `default:"10" yaml:"install_log_retention"`
+
+	// ConsoleLogs controls whether the console output of each server is written to a
+	// log file on the node, which is kept when the server's container is recreated.
+	ConsoleLogs ConsoleLogConfiguration `yaml:"console_logs"`
+
+	// A list of patterns, in the same format as a .gitignore file, for files that cannot
+	// be written or made executable through the file manager or SFTP for any server on the
+	// node. Patterns are matched against the path within the server's data directory and
+	// are combined with the write denylist of the server's egg. Negated patterns can be
+	// used to allow files within designated directories, such as "*.exe" and "!/bin/**".
+	WriteDenylist []string `yaml:"write_denylist"`
+
 	Sftp SftpConfiguration `yaml:"sftp"`
 
 	CrashDetection CrashDetection `yaml:"crash_detection"`
 
+	StartupDetection StartupDetection `yaml:"startup_detection"`
+
 	Backups Backups `yaml:"backups"`
 
 	Transfers Transfers `yaml:"transfers"`
@@ -109,6 +199,19 @@ func EnsurePterodactylUser() error {
 		return nil
 	}
 
+	// With a rootless container daemon Wings runs as an unprivileged user, so
+	// there is no way to create a dedicated user. Use the current user instead.
+	if _config.System.User.Rootless.Enabled {
+		u, err := user.Current()
+		if err != nil {
+			return err
+		}
+		_config.System.Username = u.Username
+		_config.System.User.Uid = system.MustInt(u.Uid)
+		_config.System.User.Gid = system.MustInt(u.Gid)
+		return nil
+	}
+
 	u, err := user.Lookup(_config.System.Username)
 	// If an error is returned but it isn't the unknown user error just abort
 	// the process entirely. If we did find a user, return it immediately.
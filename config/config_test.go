@@ -0,0 +1,109 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+)
+
+func TestBackupWindow_Until(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	at := func(hour, min int) time.Time {
+		return time.Date(2022, 4, 1, hour, min, 0, 0, time.UTC)
+	}
+
+	g.Describe("BackupWindow", func() {
+		g.It("is always open when no window is configured", func() {
+			g.Assert(BackupWindow{}.Until(at(12, 0))).Equal(time.Duration(0))
+			g.Assert(BackupWindow{Start: "01:00"}.Until(at(12, 0))).Equal(time.Duration(0))
+		})
+
+		g.It("returns the time until the window opens", func() {
+			w := BackupWindow{Start: "01:00", End: "05:00"}
+			g.Assert(w.Until(at(2, 0))).Equal(time.Duration(0))
+			g.Assert(w.Until(at(0, 30))).Equal(time.Minute * 30)
+			g.Assert(w.Until(at(5, 0))).Equal(time.Hour * 20)
+		})
+
+		g.It("supports a window that crosses midnight", func() {
+			w := BackupWindow{Start: "22:00", End: "06:00"}
+			g.Assert(w.Until(at(23, 0))).Equal(time.Duration(0))
+			g.Assert(w.Until(at(3, 0))).Equal(time.Duration(0))
+			g.Assert(w.Until(at(12, 0))).Equal(time.Hour * 10)
+		})
+	})
+}
+
+func TestBandwidthSchedule(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	at := func(hour, min int) time.Time {
+		return time.Date(2022, 4, 1, hour, min, 0, 0, time.UTC)
+	}
+
+	g.Describe("WriteLimitAt", func() {
+		g.It("returns the write limit when there is no schedule", func() {
+			g.Assert(Backups{WriteLimit: 20}.WriteLimitAt(at(12, 0))).Equal(20)
+		})
+
+		g.It("uses the first matching schedule", func() {
+			b := Backups{
+				WriteLimit: 20,
+				WriteLimitSchedule: []BandwidthSchedule{
+					{Start: "02:00", End: "08:00", Limit: 0},
+					{Start: "00:00", End: "04:00", Limit: 50},
+					{Start: "18:00", End: "23:00", Limit: 5},
+				},
+			}
+			g.Assert(b.WriteLimitAt(at(3, 0))).Equal(0)
+			g.Assert(b.WriteLimitAt(at(1, 0))).Equal(50)
+			g.Assert(b.WriteLimitAt(at(20, 0))).Equal(5)
+			g.Assert(b.WriteLimitAt(at(12, 0))).Equal(20)
+		})
+
+		g.It("supports schedules that cross midnight", func() {
+			tr := Transfers{
+				DownloadLimit:         20,
+				DownloadLimitSchedule: []BandwidthSchedule{{Start: "22:00", End: "06:00", Limit: 0}},
+			}
+			g.Assert(tr.DownloadLimitAt(at(23, 30))).Equal(0)
+			g.Assert(tr.DownloadLimitAt(at(5, 59))).Equal(0)
+			g.Assert(tr.DownloadLimitAt(at(6, 0))).Equal(20)
+		})
+	})
+}
+
+func TestMaintenanceWindow_Contains(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// 2022-04-01 is a Friday.
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2022, 4, day, hour, min, 0, 0, time.UTC)
+	}
+
+	g.Describe("MaintenanceWindow", func() {
+		g.It("applies every day when no days are given", func() {
+			w := MaintenanceWindow{Start: "04:00", End: "05:00"}
+			g.Assert(w.Contains(at(1, 4, 30))).IsTrue()
+			g.Assert(w.Contains(at(2, 4, 30))).IsTrue()
+			g.Assert(w.Contains(at(2, 5, 0))).IsFalse()
+		})
+
+		g.It("only applies on the given days", func() {
+			w := MaintenanceWindow{Start: "04:00", End: "05:00", Days: []string{"fri", "Sunday"}}
+			g.Assert(w.Contains(at(1, 4, 30))).IsTrue()
+			g.Assert(w.Contains(at(2, 4, 30))).IsFalse()
+			g.Assert(w.Contains(at(3, 4, 30))).IsTrue()
+		})
+
+		g.It("uses the start day for windows that cross midnight", func() {
+			w := MaintenanceWindow{Start: "23:00", End: "01:00", Days: []string{"fri"}}
+			g.Assert(w.Contains(at(1, 23, 30))).IsTrue()
+			g.Assert(w.Contains(at(2, 0, 30))).IsTrue()
+			g.Assert(w.Contains(at(2, 23, 30))).IsFalse()
+			g.Assert(w.Contains(at(1, 0, 30))).IsFalse()
+		})
+	})
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// The methods and header names that can be allowed for an origin.
+var (
+	corsMethodRegex = regexp.MustCompile(`^[A-Z]+$`)
+	corsHeaderRegex = regexp.MustCompile(`^[a-zA-Z0-9-]+$`)
+)
+
+// CorsOrigin allows requests from the origins matching a pattern, optionally
+// limiting the methods and headers that the requests are allowed to use.
+type CorsOrigin struct {
+	// The origin to allow, such as "https://panel.example.com". A wildcard can be
+	// used for the subdomain to allow every subdomain of a domain, such as
+	// "https://*.example.com", or "*" can be used to allow every origin.
+	Origin string `json:"origin" yaml:"origin"`
+
+	// The methods that requests from the origin are allowed to use. Every method
+	// used by the API is allowed if this is empty.
+	Methods []string `json:"methods" yaml:"methods"`
+
+	// The headers that requests from the origin are allowed to send. The default
+	// headers are allowed if this is empty, which includes every header used by
+	// the Panel.
+	Headers []string `json:"headers" yaml:"headers"`
+}
+
+// Validate checks that the origin is a valid pattern, and that the methods and
+// headers are valid names.
+func (o CorsOrigin) Validate() error {
+	if err := ValidateOriginPattern(o.Origin); err != nil {
+		return err
+	}
+	for _, m := range o.Methods {
+		if !corsMethodRegex.MatchString(m) {
+			return errors.Errorf("\"%s\" is not a valid method, it must be uppercase such as \"GET\"", m)
+		}
+	}
+	for _, h := range o.Headers {
+		if !corsHeaderRegex.MatchString(h) {
+			return errors.Errorf("\"%s\" is not a valid header name", h)
+		}
+	}
+	return nil
+}
+
+// ValidateOriginPattern checks that the pattern is either "*", or an origin that
+// includes the scheme and optionally uses a wildcard for the subdomain.
+func ValidateOriginPattern(pattern string) error {
+	if pattern == "*" {
+		return nil
+	}
+	u, err := url.Parse(strings.Replace(pattern, "://*.", "://wildcard.", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("\"%s\" is not a valid origin, it must include the scheme such as https://panel.example.com", pattern)
+	}
+	if u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return errors.Errorf("\"%s\" is not a valid origin, it must not include a path", pattern)
+	}
+	if strings.Contains(u.Host, "*") {
+		return errors.Errorf("\"%s\" is not a valid origin, a wildcard can only be used for the start of the domain such as https://*.example.com", pattern)
+	}
+	return nil
+}
+
+// MatchOrigin returns true if the origin sent with a request matches the pattern.
+// A pattern with a wildcard subdomain matches any subdomain of the domain, at any
+// depth, but not the domain itself.
+func MatchOrigin(pattern string, origin string) bool {
+	if pattern == "*" || strings.EqualFold(pattern, origin) {
+		return true
+	}
+	i := strings.Index(pattern, "://*.")
+	if i == -1 || origin == "" {
+		return false
+	}
+	prefix := strings.ToLower(pattern[:i+3])
+	suffix := strings.ToLower(pattern[i+4:])
+	origin = strings.ToLower(origin)
+	if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	sub := origin[len(prefix) : len(origin)-len(suffix)]
+	return sub != "" && !strings.ContainsAny(sub, ":/@")
+}
+
+// CorsOrigin returns the settings for requests from the origin if it is allowed
+// by either the CorsOrigins or AllowedOrigins. The first of the CorsOrigins that
+// matches the origin is used, so more specific patterns should be listed first.
+func (c *Configuration) CorsOrigin(origin string) (CorsOrigin, bool) {
+	for _, o := range c.CorsOrigins {
+		if MatchOrigin(o.Origin, origin) {
+			return o, true
+		}
+	}
+	for _, o := range c.AllowedOrigins {
+		if MatchOrigin(o, origin) {
+			return CorsOrigin{Origin: o}, true
+		}
+	}
+	return CorsOrigin{}, false
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("MatchOrigin", func() {
+		g.It("matches exact origins", func() {
+			g.Assert(MatchOrigin("https://panel.example.com", "https://panel.example.com")).IsTrue()
+			g.Assert(MatchOrigin("https://panel.example.com", "https://Panel.Example.com")).IsTrue()
+			g.Assert(MatchOrigin("https://panel.example.com", "http://panel.example.com")).IsFalse()
+			g.Assert(MatchOrigin("*", "https://anything.test")).IsTrue()
+		})
+
+		g.It("matches wildcard subdomains", func() {
+			g.Assert(MatchOrigin("https://*.example.com", "https://shop.example.com")).IsTrue()
+			g.Assert(MatchOrigin("https://*.example.com", "https://eu.shop.example.com")).IsTrue()
+			g.Assert(MatchOrigin("https://*.example.com", "https://example.com")).IsFalse()
+			g.Assert(MatchOrigin("https://*.example.com", "https://.example.com")).IsFalse()
+			g.Assert(MatchOrigin("https://*.example.com", "http://shop.example.com")).IsFalse()
+			g.Assert(MatchOrigin("https://*.example.com", "https://shop.example.com.evil.test")).IsFalse()
+			g.Assert(MatchOrigin("https://*.example.com", "https://evil.test/.example.com")).IsFalse()
+			g.Assert(MatchOrigin("https://*.example.com", "https://shop.example.com:8443")).IsFalse()
+			g.Assert(MatchOrigin("https://*.example.com:8443", "https://shop.example.com:8443")).IsTrue()
+		})
+	})
+
+	g.Describe("Configuration.CorsOrigin", func() {
+		g.It("returns the first matching origin", func() {
+			c := &Configuration{
+				AllowedOrigins: []string{"https://*.example.com"},
+				CorsOrigins: []CorsOrigin{
+					{Origin: "https://admin.example.com", Methods: []string{"GET"}},
+				},
+			}
+			o, ok := c.CorsOrigin("https://admin.example.com")
+			g.Assert(ok).IsTrue()
+			g.Assert(o.Methods).Equal([]string{"GET"})
+
+			o, ok = c.CorsOrigin("https://shop.example.com")
+			g.Assert(ok).IsTrue()
+			g.Assert(o.Origin).Equal("https://*.example.com")
+			g.Assert(len(o.Methods)).Equal(0)
+
+			_, ok = c.CorsOrigin("https://other.test")
+			g.Assert(ok).IsFalse()
+		})
+	})
+}
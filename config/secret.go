@@ -0,0 +1,42 @@
+package config
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// The prefix for values in the configuration file that have been encrypted.
+const encryptedValuePrefix = "encrypted:"
+
+// encryptValue encrypts a value so that it can be written to the configuration
+// file, using the protection offered by the operating system for secrets stored
+// on the machine.
+func encryptValue(c *Configuration, v string) (string, error) {
+	if v == "" || strings.HasPrefix(v, encryptedValuePrefix) {
+		return v, nil
+	}
+	b, err := protect(c, []byte(v))
+	if err != nil {
+		return "", errors.Wrap(err, "config: failed to encrypt value")
+	}
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(b), nil
+}
+
+// decryptValue decrypts a value read from the configuration file. Values that are
+// not encrypted are returned as-is.
+func decryptValue(c *Configuration, v string) (string, error) {
+	if !strings.HasPrefix(v, encryptedValuePrefix) {
+		return v, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, encryptedValuePrefix))
+	if err != nil {
+		return "", errors.Wrap(err, "config: failed to decode encrypted value")
+	}
+	d, err := unprotect(c, b)
+	if err != nil {
+		return "", errors.Wrap(err, "config: failed to decrypt value")
+	}
+	return string(d), nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+
+	"emperror.dev/errors"
+)
+
+// The name of the file within the root directory that contains the key used to
+// encrypt values in the configuration file.
+const secretKeyFile = ".config.key"
+
+// protect encrypts the data using AES-GCM with a key stored in the root directory,
+// which is only readable by root. This keeps the values out of the configuration
+// file itself, which is often shared or read when debugging a node.
+func protect(c *Configuration, b []byte) ([]byte, error) {
+	gcm, err := secretCipher(c, true)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return gcm.Seal(nonce, nonce, b, nil), nil
+}
+
+// unprotect decrypts data that was encrypted with the key stored on this machine.
+func unprotect(c *Configuration, b []byte) ([]byte, error) {
+	gcm, err := secretCipher(c, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < gcm.NonceSize() {
+		return nil, errors.New("config: encrypted value is too short")
+	}
+	out, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return out, nil
+}
+
+// secretCipher returns the cipher used to encrypt values in the configuration
+// file, creating a new key if one does not exist and create is true.
+func secretCipher(c *Configuration, create bool) (cipher.AEAD, error) {
+	p := filepath.Join(c.System.RootDirectory, secretKeyFile)
+	key, err := os.ReadFile(p)
+	if err != nil {
+		if !os.IsNotExist(err) || !create {
+			return nil, errors.Wrap(err, "config: failed to read encryption key")
+		}
+		key = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := os.MkdirAll(c.System.RootDirectory, 0o700); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := os.WriteFile(p, key, 0o600); err != nil {
+			return nil, errors.Wrap(err, "config: failed to write encryption key")
+		}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "config: invalid encryption key")
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestEncryptValue(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("encryptValue", func() {
+		var c *Configuration
+
+		g.BeforeEach(func() {
+			dir, err := os.MkdirTemp(os.TempDir(), "pterodactyl-config")
+			g.Assert(err).IsNil()
+			c = &Configuration{}
+			c.System.RootDirectory = dir
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(c.System.RootDirectory)
+		})
+
+		g.It("encrypts and decrypts a value", func() {
+			v, err := encryptValue(c, "my-secret-token")
+			g.Assert(err).IsNil()
+			g.Assert(strings.HasPrefix(v, encryptedValuePrefix)).IsTrue()
+			g.Assert(strings.Contains(v, "my-secret-token")).IsFalse()
+
+			d, err := decryptValue(c, v)
+			g.Assert(err).IsNil()
+			g.Assert(d).Equal("my-secret-token")
+		})
+
+		g.It("does not encrypt a value twice", func() {
+			v, err := encryptValue(c, "my-secret-token")
+			g.Assert(err).IsNil()
+			v2, err := encryptValue(c, v)
+			g.Assert(err).IsNil()
+			g.Assert(v2).Equal(v)
+		})
+
+		g.It("returns values that are not encrypted as-is", func() {
+			d, err := decryptValue(c, "plain-token")
+			g.Assert(err).IsNil()
+			g.Assert(d).Equal("plain-token")
+		})
+	})
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// protect encrypts the data using DPAPI. The data is protected for the local
+// machine rather than the current user, since Wings is normally run as a service
+// while the configuration can be written by an administrator.
+func protect(_ *Configuration, b []byte) ([]byte, error) {
+	var out windows.DataBlob
+	in := newDataBlob(b)
+	if err := windows.CryptProtectData(in, nil, nil, 0, nil, windows.CRYPTPROTECT_LOCAL_MACHINE|windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, err
+	}
+	return copyDataBlob(&out), nil
+}
+
+// unprotect decrypts data that was encrypted using DPAPI on this machine.
+func unprotect(_ *Configuration, b []byte) ([]byte, error) {
+	var out windows.DataBlob
+	in := newDataBlob(b)
+	if err := windows.CryptUnprotectData(in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, err
+	}
+	return copyDataBlob(&out), nil
+}
+
+func newDataBlob(b []byte) *windows.DataBlob {
+	if len(b) == 0 {
+		return &windows.DataBlob{}
+	}
+	return &windows.DataBlob{Size: uint32(len(b)), Data: &b[0]}
+}
+
+// copyDataBlob copies the data out of a blob allocated by DPAPI and then frees
+// the memory used by the blob.
+func copyDataBlob(d *windows.DataBlob) []byte {
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(d.Data)))
+	out := make([]byte, d.Size)
+	copy(out, unsafe.Slice(d.Data, d.Size))
+	return out
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/cron"
+)
+
+// The names that can be used for a scheduled task.
+var taskNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// The actions that can be run by a task scheduled on the node.
+const (
+	// Create a local backup of each server, which is not reported to the Panel.
+	TaskActionBackup = "backup"
+	// Remove the unused Docker images on the node.
+	TaskActionPruneImages = "prune_images"
+	// Restart each server that is currently running.
+	TaskActionRestart = "restart"
+	// Recalculate the disk space used by each server.
+	TaskActionRecalculateDisk = "recalculate_disk"
+	// Run a script from the hook directory.
+	TaskActionHook = "hook"
+)
+
+// TasksConfiguration defines the tasks that Wings runs on a schedule for the node
+// itself. These are independent of the schedules that the Panel runs for each
+// server, and are intended for maintenance that is the concern of the node.
+type TasksConfiguration struct {
+	// Determines if the scheduled tasks are run on this node.
+	Enabled bool `default:"true" json:"enabled" yaml:"enabled"`
+
+	// The directory containing the scripts that hook tasks are allowed to run. Hook
+	// tasks cannot be used unless this is set.
+	HookDirectory string `json:"hook_directory" yaml:"hook_directory"`
+
+	Tasks []ScheduledTask `json:"tasks" yaml:"tasks"`
+}
+
+// ScheduledTask is a single action that is run on a schedule.
+type ScheduledTask struct {
+	// The name of the task, which must be unique on the node.
+	Name string `json:"name" yaml:"name"`
+
+	// The cron expression for when the task runs, such as "0 4 * * *" to run at
+	// 04:00 every day, using the timezone of the system.
+	Schedule string `json:"schedule" yaml:"schedule"`
+
+	// One of "backup", "prune_images", "restart", "recalculate_disk" or "hook".
+	Action string `json:"action" yaml:"action"`
+
+	// The servers that the backup, restart and recalculate_disk actions apply to,
+	// every server on the node is used if this is empty.
+	Servers []string `json:"servers" yaml:"servers"`
+
+	// The number of local backups created by the task to keep for each server, the
+	// oldest backups are removed once a new backup is created. Zero keeps them all.
+	Keep int `json:"keep" yaml:"keep"`
+
+	// The script to run and its arguments for the hook action. The script must be
+	// within the hook directory.
+	Command []string `json:"command" yaml:"command"`
+
+	// The number of seconds the hook script may run for before it is killed, zero
+	// is unlimited.
+	Timeout int `json:"timeout" yaml:"timeout"`
+
+	// Disabled tasks are kept in the configuration but never run on their schedule.
+	Disabled bool `json:"disabled" yaml:"disabled"`
+}
+
+// Validate checks that the task can be run, using the hook directory to check
+// the script of hook tasks.
+func (t ScheduledTask) Validate(hookDirectory string) error {
+	if !taskNameRegex.MatchString(t.Name) {
+		return errors.Errorf("\"%s\" is not a valid task name, it must be lowercase letters, numbers, dashes and underscores", t.Name)
+	}
+	if _, err := cron.Parse(t.Schedule); err != nil {
+		return err
+	}
+	if t.Keep < 0 {
+		return errors.New("keep must be 0 or greater")
+	}
+	if t.Timeout < 0 {
+		return errors.New("timeout must be 0 or greater")
+	}
+	switch t.Action {
+	case TaskActionBackup, TaskActionPruneImages, TaskActionRestart, TaskActionRecalculateDisk:
+	case TaskActionHook:
+		if hookDirectory == "" {
+			return errors.New("hook tasks cannot be used unless tasks.hook_directory is set")
+		}
+		if len(t.Command) == 0 || !WithinHookDirectory(hookDirectory, t.Command[0]) {
+			return errors.Errorf("the command for a hook task must be a script within \"%s\"", hookDirectory)
+		}
+	default:
+		return errors.Errorf("\"%s\" is not a valid action", t.Action)
+	}
+	return nil
+}
+
+// WithinHookDirectory returns true if the script is an absolute path within the
+// hook directory.
+func WithinHookDirectory(hookDirectory string, script string) bool {
+	if !filepath.IsAbs(script) || !filepath.IsAbs(hookDirectory) {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(hookDirectory), filepath.Clean(script))
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Task returns the scheduled task with the given name.
+func (c *Configuration) Task(name string) (ScheduledTask, bool) {
+	for _, t := range c.Tasks.Tasks {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return ScheduledTask{}, false
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"crypto/subtle"
+	"path/filepath"
+	"regexp"
+
+	"github.com/gbrlsnchs/jwt/v3"
+)
+
+// The names that can be used for a tenant, which are also used as the name of the
+// directory that the files for the servers belonging to the tenant are stored in.
+var tenantNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// TenantConfiguration defines an additional Panel that this node is registered
+// with. Servers created by the Panel are only accessible using its own token.
+type TenantConfiguration struct {
+	// A unique name for the tenant, such as "reseller-a".
+	Name string `json:"name" yaml:"name"`
+
+	// The location where the Panel for the tenant is running.
+	PanelLocation string `json:"remote" yaml:"remote"`
+
+	// The identifier and token for the node on the tenant's Panel, which work in the
+	// same way as the token_id and token values for the primary Panel.
+	AuthenticationTokenId string `json:"token_id" yaml:"token_id"`
+	AuthenticationToken   string `json:"token" yaml:"token"`
+
+	// The resources that can be allocated to the servers belonging to the tenant in
+	// total. These limit the headroom reported to the tenant's Panel so that it does
+	// not place servers using resources meant for other Panels.
+	Limits TenantLimits `json:"limits" yaml:"limits"`
+}
+
+// TenantLimits are the total resources that can be allocated to the servers of a
+// tenant. Memory and disk are in megabytes, and CPU is a percentage where 100 is
+// a single core. A value of 0 does not limit the resource.
+type TenantLimits struct {
+	Memory int64 `json:"memory" yaml:"memory"`
+	Cpu    int64 `json:"cpu" yaml:"cpu"`
+	Disk   int64 `json:"disk" yaml:"disk"`
+}
+
+// ValidTenantName returns true if the name can be used for a tenant.
+func ValidTenantName(name string) bool {
+	return tenantNameRegex.MatchString(name)
+}
+
+// Tenant returns the configuration for the tenant with the given name.
+func (c *Configuration) Tenant(name string) (TenantConfiguration, bool) {
+	for _, t := range c.Tenants {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return TenantConfiguration{}, false
+}
+
+// TenantForToken returns the name of the tenant that the authentication token
+// belongs to, which is an empty string for the primary Panel. If the token does
+// not belong to any Panel the second return value is false.
+func (c *Configuration) TenantForToken(token string) (string, bool) {
+	if c.AuthenticationToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(c.AuthenticationToken)) == 1 {
+		return "", true
+	}
+	for _, t := range c.Tenants {
+		if t.AuthenticationToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(t.AuthenticationToken)) == 1 {
+			return t.Name, true
+		}
+	}
+	return "", false
+}
+
+// TenantDataDirectory returns the directory that the files for the servers
+// belonging to the tenant are stored in. Servers for the primary Panel are stored
+// directly within the data directory.
+func (sc *SystemConfiguration) TenantDataDirectory(tenant string) string {
+	if tenant == "" {
+		return sc.Data
+	}
+	return filepath.Join(sc.Data, "tenants", tenant)
+}
+
+// GetTenantJwtAlgorithm returns the JWT algorithm used to verify tokens issued by
+// the Panel for the tenant, which is signed using the tenant's authentication
+// token. Nil is returned if the tenant does not exist.
+func GetTenantJwtAlgorithm(tenant string) *jwt.HMACSHA {
+	if tenant == "" {
+		return GetJwtAlgorithm()
+	}
+	t, ok := Get().Tenant(tenant)
+	if !ok || t.AuthenticationToken == "" {
+		return nil
+	}
+	return jwt.NewHS256([]byte(t.AuthenticationToken))
+}
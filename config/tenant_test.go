@@ -0,0 +1,57 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestConfiguration_Tenants(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("TenantForToken", func() {
+		c := &Configuration{
+			AuthenticationToken: "primary",
+			Tenants: []TenantConfiguration{
+				{Name: "reseller", AuthenticationToken: "secondary"},
+				{Name: "empty"},
+			},
+		}
+
+		g.It("returns the primary panel for the node token", func() {
+			tenant, ok := c.TenantForToken("primary")
+			g.Assert(ok).IsTrue()
+			g.Assert(tenant).Equal("")
+		})
+
+		g.It("returns the tenant for its own token", func() {
+			tenant, ok := c.TenantForToken("secondary")
+			g.Assert(ok).IsTrue()
+			g.Assert(tenant).Equal("reseller")
+		})
+
+		g.It("does not match an unknown or empty token", func() {
+			_, ok := c.TenantForToken("unknown")
+			g.Assert(ok).IsFalse()
+			_, ok = c.TenantForToken("")
+			g.Assert(ok).IsFalse()
+		})
+	})
+
+	g.Describe("TenantDataDirectory", func() {
+		sc := &SystemConfiguration{Data: "/var/lib/pterodactyl/volumes"}
+
+		g.It("stores tenant servers in their own directory", func() {
+			g.Assert(sc.TenantDataDirectory("")).Equal(sc.Data)
+			g.Assert(sc.TenantDataDirectory("reseller")).Equal(filepath.Join(sc.Data, "tenants", "reseller"))
+		})
+
+		g.It("only allows safe tenant names", func() {
+			g.Assert(ValidTenantName("reseller-a_1")).IsTrue()
+			g.Assert(ValidTenantName("../reseller")).IsFalse()
+			g.Assert(ValidTenantName("Reseller")).IsFalse()
+			g.Assert(ValidTenantName("")).IsFalse()
+		})
+	})
+}
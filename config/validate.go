@@ -0,0 +1,379 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ValidationIssue is a problem found with the configuration. Warnings will not
+// prevent Wings from booting, but are likely to cause issues once it is running.
+type ValidationIssue struct {
+	// The configuration key the issue is related to, such as "api.port".
+	Field   string
+	Message string
+	Warning bool
+}
+
+func (i ValidationIssue) String() string {
+	level := "ERROR"
+	if i.Warning {
+		level = "WARNING"
+	}
+	return fmt.Sprintf("%-7s %s: %s", level, i.Field, i.Message)
+}
+
+// Validate checks the configuration for values that would prevent Wings from
+// booting or operating correctly, returning every issue that was found. This
+// does not check anything that requires connecting to an external service.
+func (c *Configuration) Validate() []ValidationIssue {
+	var issues []ValidationIssue
+	fail := func(field string, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(field string, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Field: field, Message: fmt.Sprintf(format, args...), Warning: true})
+	}
+
+	if c.Uuid == "" || c.AuthenticationTokenId == "" || c.AuthenticationToken == "" {
+		fail("token", "the node uuid, token_id and token must all be set, run \"wings configure\" or copy the configuration from the Panel")
+	}
+	if u, err := url.Parse(c.PanelLocation); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fail("remote", "\"%s\" is not a valid Panel URL, it must include the scheme such as https://panel.example.com", c.PanelLocation)
+	}
+
+	names := make(map[string]bool, len(c.Tenants))
+	tokens := map[string]bool{c.AuthenticationToken: true}
+	for i, t := range c.Tenants {
+		field := fmt.Sprintf("tenants[%d]", i)
+		if !ValidTenantName(t.Name) {
+			fail(field+".name", "\"%s\" is not a valid tenant name, it must be lowercase letters, numbers, dashes and underscores", t.Name)
+		} else if names[t.Name] {
+			fail(field+".name", "the tenant name \"%s\" is used more than once", t.Name)
+		}
+		names[t.Name] = true
+		if t.AuthenticationTokenId == "" || t.AuthenticationToken == "" {
+			fail(field+".token", "the token_id and token must both be set")
+		} else if tokens[t.AuthenticationToken] {
+			fail(field+".token", "the token is also used by another Panel, every Panel must use a different token")
+		}
+		tokens[t.AuthenticationToken] = true
+		if u, err := url.Parse(t.PanelLocation); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail(field+".remote", "\"%s\" is not a valid Panel URL, it must include the scheme such as https://panel.example.com", t.PanelLocation)
+		}
+		if t.Limits.Memory < 0 || t.Limits.Cpu < 0 || t.Limits.Disk < 0 {
+			fail(field+".limits", "the limits cannot be negative")
+		}
+	}
+
+	if c.Api.Port < 1 || c.Api.Port > 65535 {
+		fail("api.port", "%d is not a valid port", c.Api.Port)
+	}
+	if c.System.Sftp.Port < 1 || c.System.Sftp.Port > 65535 {
+		fail("system.sftp.bind_port", "%d is not a valid port", c.System.Sftp.Port)
+	}
+	if c.Api.Port == c.System.Sftp.Port && addressesOverlap(c.Api.Host, c.System.Sftp.Address) {
+		fail("system.sftp.bind_port", "the SFTP server cannot use port %d since it is used by the API", c.Api.Port)
+	}
+	if net.ParseIP(c.Api.Host) == nil {
+		fail("api.host", "\"%s\" is not a valid IP address to bind to", c.Api.Host)
+	}
+	if net.ParseIP(c.System.Sftp.Address) == nil {
+		fail("system.sftp.bind_address", "\"%s\" is not a valid IP address to bind to", c.System.Sftp.Address)
+	}
+
+	// Signed URLs are only remembered as having been used for an hour, so they cannot be
+	// allowed to live any longer than that.
+	if c.Api.SignedUrlLifetime < 1 || c.Api.SignedUrlLifetime > 3600 {
+		fail("api.signed_url_lifetime", "%d is not valid, it must be between 1 and 3600 seconds", c.Api.SignedUrlLifetime)
+	}
+	if c.Api.ConfirmationLifetime < 1 || c.Api.ConfirmationLifetime > 3600 {
+		fail("api.confirmation_lifetime", "%d is not valid, it must be between 1 and 3600 seconds", c.Api.ConfirmationLifetime)
+	}
+
+	if c.Api.Ssl.Enabled && c.Api.H2C {
+		warn("api.h2c", "h2c has no effect when SSL is enabled, HTTP/2 is already available over TLS")
+	}
+
+	if c.Api.Ssl.Enabled {
+		for _, issue := range validateCertificate(c.Api.Ssl.CertificateFile, c.Api.Ssl.KeyFile) {
+			issue.Field = "api.ssl." + issue.Field
+			issues = append(issues, issue)
+		}
+	}
+
+	if c.System.Timezone != "" {
+		if _, err := time.LoadLocation(c.System.Timezone); err != nil {
+			fail("system.timezone", "\"%s\" is not a valid timezone, use a name such as \"Europe/London\"", c.System.Timezone)
+		}
+	}
+
+	directories := []struct {
+		field string
+		path  string
+	}{
+		{"system.root_directory", c.System.RootDirectory},
+		{"system.data", c.System.Data},
+		{"system.log_directory", c.System.LogDirectory},
+		{"system.archive_directory", c.System.ArchiveDirectory},
+		{"system.backup_directory", c.System.BackupDirectory},
+		{"system.tmp_directory", c.System.TmpDirectory},
+	}
+	for _, d := range directories {
+		if !filepath.IsAbs(d.path) {
+			fail(d.field, "\"%s\" must be an absolute path", d.path)
+			continue
+		}
+		st, err := os.Stat(d.path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				warn(d.field, "\"%s\" does not exist and will be created when Wings is started", d.path)
+			} else {
+				fail(d.field, "unable to access \"%s\": %s", d.path, err)
+			}
+			continue
+		}
+		if !st.IsDir() {
+			fail(d.field, "\"%s\" is not a directory", d.path)
+		}
+	}
+
+	switch c.System.LogRotation.Mode() {
+	case LogRotationLogrotate, LogRotationBuiltin, LogRotationNone:
+	default:
+		fail("system.log_rotation.strategy", "\"%s\" is not a valid strategy, it must be one of \"logrotate\", \"builtin\" or \"none\"", c.System.LogRotation.Strategy)
+	}
+
+	switch c.System.CheckPermissionsScope {
+	case CheckPermissionsScopeAll, CheckPermissionsScopeTopLevel:
+	default:
+		fail("system.check_permissions_scope", "\"%s\" is not valid, it must be either \"all\" or \"top_level\"", c.System.CheckPermissionsScope)
+	}
+	switch c.System.DiskUsageMode {
+	case DiskUsageModeLogical, DiskUsageModeAllocated:
+	default:
+		fail("system.disk_usage_mode", "\"%s\" is not valid, it must be either \"logical\" or \"allocated\"", c.System.DiskUsageMode)
+	}
+	if c.System.VolumeCompression != "" && !IsVolumeCompression(c.System.VolumeCompression) {
+		fail("system.volume_compression", "\"%s\" is not valid, it must be one of \"ntfs\", \"xpress4k\", \"xpress8k\", \"xpress16k\" or \"lzx\"", c.System.VolumeCompression)
+	}
+	switch c.System.NameConflicts {
+	case NameConflictRename, NameConflictSkip:
+	default:
+		fail("system.name_conflicts", "\"%s\" is not valid, it must be either \"rename\" or \"skip\"", c.System.NameConflicts)
+	}
+	if c.System.CheckPermissionsWorkers < 0 {
+		fail("system.check_permissions_workers", "%d is not valid, it must be 0 or greater", c.System.CheckPermissionsWorkers)
+	}
+	if c.System.ConsoleLogs.Enabled && c.System.ConsoleLogs.MaxSize < 1 {
+		fail("system.console_logs.max_size", "%d is not valid, it must be 1 or greater", c.System.ConsoleLogs.MaxSize)
+	}
+
+	if c.System.Backups.MaxConcurrent < 0 {
+		fail("system.backups.max_concurrent", "%d is not valid, it must be 0 or greater", c.System.Backups.MaxConcurrent)
+	}
+	for _, w := range [][2]string{{"start", c.System.Backups.Window.Start}, {"end", c.System.Backups.Window.End}} {
+		if _, err := parseTimeOfDay(w[1]); w[1] != "" && err != nil {
+			fail("system.backups.window."+w[0], "\"%s\" is not a valid time, it must be in the format \"HH:MM\"", w[1])
+		}
+	}
+
+	schedules := []struct {
+		field     string
+		schedules []BandwidthSchedule
+	}{
+		{"system.backups.write_limit_schedule", c.System.Backups.WriteLimitSchedule},
+		{"system.transfers.download_limit_schedule", c.System.Transfers.DownloadLimitSchedule},
+	}
+	for _, s := range schedules {
+		for i, v := range s.schedules {
+			field := fmt.Sprintf("%s[%d]", s.field, i)
+			for _, t := range [][2]string{{"start", v.Start}, {"end", v.End}} {
+				if _, err := parseTimeOfDay(t[1]); err != nil {
+					fail(field+"."+t[0], "\"%s\" is not a valid time, it must be in the format \"HH:MM\"", t[1])
+				}
+			}
+			if v.Start == v.End {
+				warn(field, "the start and end times are the same so this entry is never used")
+			}
+		}
+	}
+
+	for i, w := range c.System.CrashDetection.MaintenanceWindows {
+		field := fmt.Sprintf("system.crash_detection.maintenance_windows[%d]", i)
+		for _, t := range [][2]string{{"start", w.Start}, {"end", w.End}} {
+			if _, err := parseTimeOfDay(t[1]); err != nil {
+				fail(field+"."+t[0], "\"%s\" is not a valid time, it must be in the format \"HH:MM\"", t[1])
+			}
+		}
+		for _, d := range w.Days {
+			if _, ok := parseWeekday(d); !ok {
+				fail(field+".days", "\"%s\" is not a valid day of the week", d)
+			}
+		}
+	}
+
+	if v := c.System.Backups.Azure.ContainerUrl; v != "" {
+		if u, err := url.Parse(v); err != nil || u.Scheme != "https" || u.Host == "" {
+			// The URL is not included in the message since it contains the SAS token.
+			fail("system.backups.azure.container_url", "the container URL is not valid, it must be an https:// URL")
+		} else if u.RawQuery == "" {
+			fail("system.backups.azure.container_url", "the container URL must include a SAS token")
+		}
+	}
+	if c.System.Backups.Gcs.Bucket != "" {
+		if _, err := os.Stat(c.System.Backups.Gcs.CredentialsFile); err != nil {
+			fail("system.backups.gcs.credentials_file", "unable to access \"%s\": %s", c.System.Backups.Gcs.CredentialsFile, err)
+		}
+	}
+
+	for _, v := range []struct {
+		field string
+		value int
+	}{
+		{"remote_query.timeout", c.RemoteQuery.Timeout},
+		{"remote_query.retries", c.RemoteQuery.Retries},
+		{"remote_query.retry_backoff", c.RemoteQuery.RetryBackoff},
+		{"remote_query.circuit_breaker_threshold", c.RemoteQuery.CircuitBreakerThreshold},
+		{"remote_query.circuit_breaker_cooldown", c.RemoteQuery.CircuitBreakerCooldown},
+	} {
+		if v.value < 0 {
+			fail(v.field, "%d is not valid, it must be 0 or greater", v.value)
+		}
+	}
+	if c.RemoteQuery.OfflineCacheRetry < 1 {
+		fail("remote_query.offline_cache_retry", "%d is not valid, it must be 1 or greater", c.RemoteQuery.OfflineCacheRetry)
+	}
+	if c.RemoteQuery.BootConcurrency < 1 {
+		fail("remote_query.boot_concurrency", "%d is not valid, it must be 1 or greater", c.RemoteQuery.BootConcurrency)
+	}
+	if c.RemoteQuery.BootPageRetries < 0 {
+		fail("remote_query.boot_page_retries", "%d is not valid, it must be 0 or greater", c.RemoteQuery.BootPageRetries)
+	}
+	if c.RemoteQuery.HeartbeatInterval < 0 {
+		fail("remote_query.heartbeat_interval", "%d is not valid, it must be 0 or greater", c.RemoteQuery.HeartbeatInterval)
+	}
+
+	switch c.Docker.OrphanPolicy {
+	case OrphanPolicyFlag, OrphanPolicyStop, OrphanPolicyRemove:
+	default:
+		fail("docker.orphan_policy", "\"%s\" is not valid, it must be one of \"flag\", \"stop\" or \"remove\"", c.Docker.OrphanPolicy)
+	}
+	for name, w := range c.Docker.EntrypointWrappers {
+		field := "docker.entrypoint_wrappers." + name
+		if !filepath.IsAbs(w.Source) {
+			fail(field+".source", "\"%s\" must be an absolute path", w.Source)
+		} else if st, err := os.Stat(w.Source); err != nil || !st.IsDir() {
+			warn(field+".source", "\"%s\" is not a directory, servers using this wrapper will fail to start", w.Source)
+		}
+		if len(w.Command) == 0 {
+			fail(field+".command", "a command to run the wrapper must be set")
+		}
+	}
+	if i := c.Docker.Isolation; i != "" && i != "process" && i != "hyperv" {
+		fail("docker.isolation", "\"%s\" is not valid, it must be either \"process\" or \"hyperv\"", i)
+	}
+	for _, l := range []struct {
+		field string
+		value int64
+	}{
+		{"docker.installer_limits.memory", c.Docker.InstallerLimits.Memory},
+		{"docker.installer_limits.cpu", c.Docker.InstallerLimits.Cpu},
+		{"docker.installer_limits.disk", c.Docker.InstallerLimits.Disk},
+	} {
+		if l.value < 0 {
+			fail(l.field, "%d is not valid, it must be 0 or greater", l.value)
+		}
+	}
+	if c.Docker.Overhead.Percentage < 0 {
+		fail("docker.overhead.percentage", "%g is not valid, it must be 0 or greater", c.Docker.Overhead.Percentage)
+	}
+	if c.Docker.Overhead.ReservedMemory < 0 {
+		fail("docker.overhead.reserved_memory", "%d is not valid, it must be 0 or greater", c.Docker.Overhead.ReservedMemory)
+	}
+	if c.Docker.InstallerMaxConcurrent < 0 {
+		fail("docker.installer_max_concurrent", "%d is not valid, it must be 0 or greater", c.Docker.InstallerMaxConcurrent)
+	}
+	if c.Docker.StatsCollector.Interval < 1 {
+		fail("docker.stats_collector.interval", "%d is not valid, it must be 1 or greater", c.Docker.StatsCollector.Interval)
+	}
+	if c.Docker.StatsCollector.Concurrency < 1 {
+		fail("docker.stats_collector.concurrency", "%d is not valid, it must be 1 or greater", c.Docker.StatsCollector.Concurrency)
+	}
+
+	if d := c.Tasks.HookDirectory; d != "" && !filepath.IsAbs(d) {
+		fail("tasks.hook_directory", "\"%s\" must be an absolute path", d)
+	}
+	tasks := make(map[string]bool, len(c.Tasks.Tasks))
+	for i, t := range c.Tasks.Tasks {
+		field := fmt.Sprintf("tasks.tasks[%d]", i)
+		if err := t.Validate(c.Tasks.HookDirectory); err != nil {
+			fail(field, "%s", err)
+		} else if tasks[t.Name] {
+			fail(field+".name", "the task name \"%s\" is used more than once", t.Name)
+		}
+		tasks[t.Name] = true
+	}
+
+	for i, n := range c.AllowedInternalNetworks {
+		if _, _, err := net.ParseCIDR(n); err != nil {
+			fail(fmt.Sprintf("allowed_internal_networks[%d]", i), "\"%s\" is not a valid network, it must be in CIDR notation such as 10.0.0.0/8", n)
+		}
+	}
+	for i, o := range c.AllowedOrigins {
+		if err := ValidateOriginPattern(o); err != nil {
+			fail(fmt.Sprintf("allowed_origins[%d]", i), "%s", err)
+		}
+	}
+	for i, o := range c.CorsOrigins {
+		if err := o.Validate(); err != nil {
+			fail(fmt.Sprintf("cors_origins[%d]", i), "%s", err)
+		}
+	}
+
+	switch c.Overcommit.Mode {
+	case OvercommitModeOff, OvercommitModeWarn, OvercommitModeRefuse:
+	default:
+		fail("overcommit.mode", "\"%s\" is not valid, it must be one of \"off\", \"warn\" or \"refuse\"", c.Overcommit.Mode)
+	}
+
+	return issues
+}
+
+// validateCertificate checks that the certificate and key can be loaded, and
+// that the certificate has not expired.
+func validateCertificate(certFile string, keyFile string) []ValidationIssue {
+	if certFile == "" || keyFile == "" {
+		return []ValidationIssue{{Field: "cert", Message: "SSL is enabled but the certificate or key file is not set"}}
+	}
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return []ValidationIssue{{Field: "cert", Message: fmt.Sprintf("unable to load the certificate and key: %s", err)}}
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return []ValidationIssue{{Field: "cert", Message: fmt.Sprintf("unable to parse the certificate: %s", err)}}
+	}
+	if time.Now().After(cert.NotAfter) {
+		return []ValidationIssue{{Field: "cert", Message: fmt.Sprintf("the certificate expired on %s", cert.NotAfter.Format(time.RFC1123))}}
+	}
+	if time.Until(cert.NotAfter) < time.Hour*24*14 {
+		return []ValidationIssue{{Field: "cert", Message: fmt.Sprintf("the certificate expires on %s", cert.NotAfter.Format(time.RFC1123)), Warning: true}}
+	}
+	return nil
+}
+
+// addressesOverlap returns true if listening on both addresses at the same time
+// would cause a conflict.
+func addressesOverlap(a string, b string) bool {
+	unspecified := func(v string) bool {
+		ip := net.ParseIP(v)
+		return ip == nil || ip.IsUnspecified()
+	}
+	return a == b || unspecified(a) || unspecified(b)
+}
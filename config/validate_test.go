@@ -0,0 +1,162 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestConfiguration_Validate(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("Validate", func() {
+		var c *Configuration
+
+		g.BeforeEach(func() {
+			var err error
+			c, err = NewAtPath("")
+			g.Assert(err).IsNil()
+			dir, err := os.MkdirTemp(os.TempDir(), "pterodactyl-config")
+			g.Assert(err).IsNil()
+			c.Uuid = "uuid"
+			c.AuthenticationTokenId = "id"
+			c.AuthenticationToken = "token"
+			c.PanelLocation = "https://panel.example.com"
+			c.System.Timezone = "UTC"
+			c.System.RootDirectory = dir
+			c.System.Data = dir
+			c.System.LogDirectory = dir
+			c.System.ArchiveDirectory = dir
+			c.System.BackupDirectory = dir
+			c.System.TmpDirectory = dir
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(c.System.RootDirectory)
+		})
+
+		g.It("returns no issues for a valid configuration", func() {
+			g.Assert(len(c.Validate())).Equal(0)
+		})
+
+		g.It("detects the API and SFTP server using the same port", func() {
+			c.System.Sftp.Port = c.Api.Port
+			issues := c.Validate()
+			g.Assert(len(issues)).Equal(1)
+			g.Assert(issues[0].Field).Equal("system.sftp.bind_port")
+			g.Assert(issues[0].Warning).IsFalse()
+		})
+
+		g.It("allows the same port on different addresses", func() {
+			c.Api.Host = "10.0.0.1"
+			c.System.Sftp.Address = "10.0.0.2"
+			c.System.Sftp.Port = c.Api.Port
+			g.Assert(len(c.Validate())).Equal(0)
+		})
+
+		g.It("detects an invalid panel location and timezone", func() {
+			c.PanelLocation = "panel.example.com"
+			c.System.Timezone = "Not/AZone"
+			issues := c.Validate()
+			g.Assert(len(issues)).Equal(2)
+			g.Assert(issues[0].Field).Equal("remote")
+			g.Assert(issues[1].Field).Equal("system.timezone")
+		})
+
+		g.It("detects tenants that reuse a name or token", func() {
+			c.Tenants = []TenantConfiguration{
+				{Name: "reseller", PanelLocation: "https://a.example.com", AuthenticationTokenId: "a", AuthenticationToken: "token"},
+				{Name: "reseller", PanelLocation: "https://b.example.com", AuthenticationTokenId: "b", AuthenticationToken: "other"},
+			}
+			issues := c.Validate()
+			g.Assert(len(issues)).Equal(2)
+			g.Assert(issues[0].Field).Equal("tenants[0].token")
+			g.Assert(issues[1].Field).Equal("tenants[1].name")
+		})
+
+		g.It("detects an invalid permission check scope", func() {
+			c.System.CheckPermissionsScope = "everything"
+			issues := c.Validate()
+			g.Assert(len(issues)).Equal(1)
+			g.Assert(issues[0].Field).Equal("system.check_permissions_scope")
+		})
+
+		g.It("detects an invalid isolation mode", func() {
+			c.Docker.Isolation = "hyper-v"
+			issues := c.Validate()
+			g.Assert(len(issues)).Equal(1)
+			g.Assert(issues[0].Field).Equal("docker.isolation")
+		})
+
+		g.It("detects negative installer limits", func() {
+			c.Docker.InstallerLimits.Disk = -1
+			issues := c.Validate()
+			g.Assert(len(issues)).Equal(1)
+			g.Assert(issues[0].Field).Equal("docker.installer_limits.disk")
+		})
+
+		g.It("detects negative remote query settings", func() {
+			c.RemoteQuery.Retries = -1
+			c.RemoteQuery.CircuitBreakerCooldown = -1
+			issues := c.Validate()
+			g.Assert(len(issues)).Equal(2)
+			g.Assert(issues[0].Field).Equal("remote_query.retries")
+			g.Assert(issues[1].Field).Equal("remote_query.circuit_breaker_cooldown")
+		})
+
+		g.It("detects a negative memory overhead", func() {
+			c.Docker.Overhead.Percentage = -10
+			c.Docker.Overhead.ReservedMemory = -1
+			issues := c.Validate()
+			g.Assert(len(issues)).Equal(2)
+			g.Assert(issues[0].Field).Equal("docker.overhead.percentage")
+			g.Assert(issues[1].Field).Equal("docker.overhead.reserved_memory")
+		})
+
+		g.It("warns about missing directories", func() {
+			c.System.BackupDirectory = c.System.RootDirectory + "/missing"
+			issues := c.Validate()
+			g.Assert(len(issues)).Equal(1)
+			g.Assert(issues[0].Field).Equal("system.backup_directory")
+			g.Assert(issues[0].Warning).IsTrue()
+		})
+
+		g.It("detects invalid scheduled tasks", func() {
+			c.Tasks.HookDirectory = c.System.RootDirectory
+			c.Tasks.Tasks = []ScheduledTask{
+				{Name: "prune", Schedule: "0 4 * * *", Action: TaskActionPruneImages},
+				{Name: "prune", Schedule: "0 5 * * *", Action: TaskActionPruneImages},
+				{Name: "restart", Schedule: "0 25 * * *", Action: TaskActionRestart},
+				{Name: "hook", Schedule: "@daily", Action: TaskActionHook, Command: []string{"/bin/sh"}},
+			}
+			issues := c.Validate()
+			g.Assert(len(issues)).Equal(3)
+			g.Assert(issues[0].Field).Equal("tasks.tasks[1].name")
+			g.Assert(issues[1].Field).Equal("tasks.tasks[2]")
+			g.Assert(issues[2].Field).Equal("tasks.tasks[3]")
+		})
+
+		g.It("detects invalid allowed origins", func() {
+			c.AllowedOrigins = []string{"*", "https://*.example.com", "example.com", "https://shop.*.example.com"}
+			c.CorsOrigins = []CorsOrigin{
+				{Origin: "https://*.example.com:8443", Methods: []string{"GET"}, Headers: []string{"Authorization"}},
+				{Origin: "https://store.example.com/path"},
+				{Origin: "https://store.example.com", Methods: []string{"get"}},
+			}
+			issues := c.Validate()
+			g.Assert(len(issues)).Equal(4)
+			g.Assert(issues[0].Field).Equal("allowed_origins[2]")
+			g.Assert(issues[1].Field).Equal("allowed_origins[3]")
+			g.Assert(issues[2].Field).Equal("cors_origins[1]")
+			g.Assert(issues[3].Field).Equal("cors_origins[2]")
+		})
+
+		g.It("requires a certificate when SSL is enabled", func() {
+			c.Api.Ssl.Enabled = true
+			issues := c.Validate()
+			g.Assert(len(issues)).Equal(1)
+			g.Assert(issues[0].Field).Equal("api.ssl.cert")
+		})
+	})
+}
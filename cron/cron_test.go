@@ -0,0 +1,60 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+)
+
+func TestSchedule(t *testing.T) {
+	g := goblin.Goblin(t)
+	// Wednesday, 15 March 2023.
+	now := time.Date(2023, time.March, 15, 10, 20, 30, 0, time.UTC)
+
+	next := func(expr string) time.Time {
+		s, err := Parse(expr)
+		g.Assert(err).IsNil()
+		return s.Next(now)
+	}
+
+	g.Describe("Parse", func() {
+		g.It("rejects invalid expressions", func() {
+			for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "* * * foo *"} {
+				_, err := Parse(expr)
+				g.Assert(err == nil).IsFalse(expr)
+			}
+		})
+	})
+
+	g.Describe("Next", func() {
+		g.It("returns the next minute for every minute", func() {
+			g.Assert(next("* * * * *")).Equal(time.Date(2023, time.March, 15, 10, 21, 0, 0, time.UTC))
+		})
+
+		g.It("handles steps", func() {
+			g.Assert(next("*/15 * * * *")).Equal(time.Date(2023, time.March, 15, 10, 30, 0, 0, time.UTC))
+		})
+
+		g.It("moves to the next day once the time has passed", func() {
+			g.Assert(next("30 4 * * *")).Equal(time.Date(2023, time.March, 16, 4, 30, 0, 0, time.UTC))
+		})
+
+		g.It("handles days of the week by name", func() {
+			g.Assert(next("0 3 * * sun")).Equal(time.Date(2023, time.March, 19, 3, 0, 0, 0, time.UTC))
+			g.Assert(next("0 3 * * 7")).Equal(time.Date(2023, time.March, 19, 3, 0, 0, 0, time.UTC))
+		})
+
+		g.It("matches either day field when both are restricted", func() {
+			g.Assert(next("0 0 1 * mon")).Equal(time.Date(2023, time.March, 20, 0, 0, 0, 0, time.UTC))
+		})
+
+		g.It("supports macros", func() {
+			g.Assert(next("@monthly")).Equal(time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC))
+		})
+
+		g.It("returns the zero time when nothing matches", func() {
+			g.Assert(next("0 0 31 2 *").IsZero()).IsTrue()
+		})
+	})
+}
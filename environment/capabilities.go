@@ -0,0 +1,125 @@
+package environment
+
+import (
+	"context"
+	"sync"
+
+	"emperror.dev/errors"
+	"github.com/docker/docker/api/types"
+)
+
+// HostCapabilities describes the resource limits that are supported by the host,
+// as reported by the container daemon. Limits that are not supported are left
+// out of the container configuration rather than being silently ignored.
+type HostCapabilities struct {
+	// The version of cgroups in use on the host, either "1" or "2".
+	CgroupVersion string
+
+	// SwapLimit is false if swap accounting is disabled in the kernel, in which
+	// case swap limits cannot be applied to containers.
+	SwapLimit bool
+
+	// OomKillDisable is false if the OOM killer cannot be disabled for containers,
+	// which is always the case with cgroups v2.
+	OomKillDisable bool
+
+	// PidsLimit is false if the pids controller is not available.
+	PidsLimit bool
+
+	// MemoryTotal is the total amount of memory on the host in bytes, or 0 if it is
+	// not known.
+	MemoryTotal int64
+
+	// OSType is the type of containers run by the daemon, either "linux" or
+	// "windows". Docker Desktop on Windows runs Linux containers unless it has been
+	// switched to Windows containers.
+	OSType string
+}
+
+var (
+	capsMu sync.RWMutex
+	// Until the capabilities have been detected assume that all limits are
+	// supported, matching the behavior before detection was added.
+	caps = HostCapabilities{SwapLimit: true, OomKillDisable: true, PidsLimit: true}
+)
+
+// Capabilities returns the resource limiting capabilities of the host.
+func Capabilities() HostCapabilities {
+	capsMu.RLock()
+	defer capsMu.RUnlock()
+	return caps
+}
+
+// CapabilitiesFromInfo returns the resource limiting capabilities of the host from
+// the information reported by the Docker daemon.
+func CapabilitiesFromInfo(info types.Info) HostCapabilities {
+	c := HostCapabilities{
+		CgroupVersion:  info.CgroupVersion,
+		SwapLimit:      info.SwapLimit,
+		OomKillDisable: info.OomKillDisable,
+		PidsLimit:      info.PidsLimit,
+		MemoryTotal:    info.MemTotal,
+		OSType:         info.OSType,
+	}
+	// Older versions of Docker do not report the cgroup version in use.
+	if c.CgroupVersion == "" {
+		c.CgroupVersion = detectCgroupVersion()
+	}
+	// The OOM killer cannot be disabled with cgroups v2, even if the daemon reports
+	// that it can be.
+	if c.CgroupVersion == "2" {
+		c.OomKillDisable = false
+	}
+	return c
+}
+
+// DetectCapabilities detects and stores the resource limiting capabilities of the
+// host using the Docker daemon.
+func DetectCapabilities(ctx context.Context) (HostCapabilities, error) {
+	cli, err := Docker()
+	if err != nil {
+		return HostCapabilities{}, err
+	}
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return HostCapabilities{}, errors.Wrap(err, "environment/docker: failed to get daemon info")
+	}
+	c := CapabilitiesFromInfo(info)
+
+	capsMu.Lock()
+	caps = c
+	capsMu.Unlock()
+
+	return c, nil
+}
+
+// ReadOnlyRootfs returns true if containers can be run with a read-only root
+// filesystem and tmpfs mounts, which Windows containers do not support.
+func (c HostCapabilities) ReadOnlyRootfs() bool {
+	return c.OSType != "windows"
+}
+
+// Warnings returns a warning for each resource limit that servers can be assigned
+// but that is not supported by the host.
+func (c HostCapabilities) Warnings() []string {
+	// Hosts without cgroups, such as Windows, do not support any of these limits
+	// in the first place.
+	if c.CgroupVersion == "" {
+		return nil
+	}
+	var w []string
+	if !c.SwapLimit {
+		w = append(w, "swap accounting is not enabled in the kernel, swap limits for servers will not be applied")
+	}
+	if !c.OomKillDisable {
+		if c.CgroupVersion == "2" {
+			w = append(w, "the OOM killer cannot be disabled for servers when using cgroups v2")
+		} else {
+			w = append(w, "the OOM killer cannot be disabled for servers on this host")
+		}
+	}
+	if !c.PidsLimit {
+		w = append(w, "the pids cgroup controller is not available, process limits for servers will not be applied")
+	}
+	return w
+}
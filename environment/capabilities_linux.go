@@ -0,0 +1,26 @@
+package environment
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// detectCgroupVersion returns "2" if the unified cgroup hierarchy is mounted on
+// the host, otherwise "1".
+func detectCgroupVersion() string {
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err == nil {
+		return "2"
+	}
+	return "1"
+}
+
+// hostMemoryTotal returns the total amount of memory on the host in bytes, or 0
+// if it cannot be determined.
+func hostMemoryTotal() int64 {
+	var info unix.Sysinfo_t
+	if err := unix.Sysinfo(&info); err != nil {
+		return 0
+	}
+	return int64(info.Totalram) * int64(info.Unit)
+}
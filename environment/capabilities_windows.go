@@ -0,0 +1,38 @@
+package environment
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procGlobalMemoryStatusEx = windows.NewLazySystemDLL("kernel32.dll").NewProc("GlobalMemoryStatusEx")
+
+// memoryStatusEx is the MEMORYSTATUSEX structure used by GlobalMemoryStatusEx.
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+// detectCgroupVersion returns an empty string as cgroups do not exist on Windows.
+func detectCgroupVersion() string {
+	return ""
+}
+
+// hostMemoryTotal returns the total amount of physical memory on the host in
+// bytes, or 0 if it cannot be determined.
+func hostMemoryTotal() int64 {
+	m := memoryStatusEx{}
+	m.Length = uint32(unsafe.Sizeof(m))
+	if r, _, _ := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&m))); r == 0 {
+		return 0
+	}
+	return int64(m.TotalPhys)
+}
@@ -17,6 +17,11 @@ type Configuration struct {
 
 	environmentVariables []string
 	settings             Settings
+
+	// Whether references to the data directory used by upstream Linux nodes are
+	// translated in the environment variables, which is only done for servers that
+	// were transferred to this node.
+	translatePaths bool
 }
 
 // Returns a new environment configuration with the given settings and environment variables
@@ -75,3 +80,23 @@ func (c *Configuration) EnvironmentVariables() []string {
 
 	return c.environmentVariables
 }
+
+// Sets if references to the data directory used by upstream Linux nodes should be
+// translated in the environment variables.
+func (c *Configuration) SetTranslatePaths(translate bool) {
+	c.mu.Lock()
+	c.translatePaths = translate
+	c.mu.Unlock()
+}
+
+// Returns the environment variables with TranslateVariables applied to them if
+// the server was transferred to this node, otherwise they are returned as-is.
+func (c *Configuration) TranslateVariables(evs []string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.translatePaths {
+		return evs
+	}
+	return TranslateVariables(evs)
+}
@@ -0,0 +1,84 @@
+package containerd
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/defaults"
+	refdocker "github.com/containerd/containerd/reference/docker"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+var (
+	_conce  sync.Once
+	_client *containerd.Client
+)
+
+// Client returns the containerd client to be used throughout the codebase. Once
+// a client has been created it will be returned for all subsequent calls to this
+// function.
+func Client() (*containerd.Client, error) {
+	var err error
+	_conce.Do(func() {
+		c := config.Get().Containerd
+		address := c.Address
+		if address == "" {
+			address = defaults.DefaultAddress
+		}
+		opts := []containerd.ClientOpt{containerd.WithDefaultNamespace(c.Namespace)}
+		if c.Runtime != "" {
+			opts = append(opts, containerd.WithDefaultRuntime(c.Runtime))
+		}
+		_client, err = containerd.New(address, opts...)
+	})
+	return _client, errors.Wrap(err, "environment/containerd: could not create client")
+}
+
+// Configure ensures that Wings is able to communicate with the containerd daemon
+// on the system.
+func Configure(ctx context.Context) error {
+	cli, err := Client()
+	if err != nil {
+		return err
+	}
+	v, err := cli.Version(ctx)
+	if err != nil {
+		return errors.Wrap(err, "environment/containerd: failed to connect to daemon")
+	}
+	log.WithFields(log.Fields{"version": v.Version, "namespace": config.Get().Containerd.Namespace}).Info("connected to containerd daemon")
+	return nil
+}
+
+// normalizeImage converts an image into the fully qualified reference required by
+// containerd, e.g. "alpine" becomes "docker.io/library/alpine:latest".
+func normalizeImage(image string) (string, error) {
+	ref, err := refdocker.ParseDockerRef(strings.TrimPrefix(image, "~"))
+	if err != nil {
+		return "", errors.Wrapf(err, "environment/containerd: invalid image reference \"%s\"", image)
+	}
+	return ref.String(), nil
+}
+
+// resolver returns a registry resolver that uses the credentials defined in the
+// configuration for the registry the image is hosted on.
+func resolver() remotes.Resolver {
+	registries := config.Get().Docker.Registries
+	authorizer := docker.NewDockerAuthorizer(docker.WithAuthCreds(func(host string) (string, string, error) {
+		for registry, c := range registries {
+			if strings.HasPrefix(host, registry) || strings.HasPrefix(registry, host) {
+				return c.Username, c.Password, nil
+			}
+		}
+		return "", "", nil
+	}))
+	return docker.NewResolver(docker.ResolverOptions{
+		Hosts: docker.ConfigureDefaultRegistries(docker.WithAuthorizer(authorizer)),
+	})
+}
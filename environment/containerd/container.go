@@ -0,0 +1,443 @@
+package containerd
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/containerd/containerd"
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/oci"
+	"github.com/containerd/typeurl"
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/system"
+)
+
+var ErrNotAttached = errors.Sentinel("not attached to instance")
+
+// Attach attaches to the task running in the container, creating the task if
+// it does not yet exist, and ensures that we can pipe data in and out of the
+// process. This should always be called before you have started the task, but
+// after you've ensured the container exists.
+//
+// Calling this function will poll resources for the container in the background
+// until the task exits. The context provided to this function is only used for
+// the purposes of attaching to the container.
+func (e *Environment) Attach(ctx context.Context) error {
+	if e.IsAttached() {
+		return nil
+	}
+
+	c, err := e.client.LoadContainer(ctx, e.Id)
+	if err != nil {
+		return errors.Wrap(err, "environment/containerd: failed to load container")
+	}
+
+	lf, err := e.openLog()
+	if err != nil {
+		return err
+	}
+	stdinR, stdinW := io.Pipe()
+	outR, outW := io.Pipe()
+	cleanup := func() {
+		_ = stdinW.Close()
+		_ = outW.Close()
+		_ = lf.Close()
+	}
+
+	opts := []cio.Opt{cio.WithStreams(stdinR, io.MultiWriter(lf, outW), nil), cio.WithTerminal}
+	task, err := c.Task(ctx, cio.NewAttach(opts...))
+	if err != nil {
+		if !errdefs.IsNotFound(err) {
+			cleanup()
+			return errors.Wrap(err, "environment/containerd: failed to attach to task")
+		}
+		if task, err = c.NewTask(ctx, cio.NewCreator(opts...)); err != nil {
+			cleanup()
+			return errors.Wrap(err, "environment/containerd: failed to create task")
+		}
+	}
+
+	// Wait must be called before the task is started to ensure that the exit of the
+	// process is never missed.
+	exitCh, err := task.Wait(context.Background())
+	if err != nil {
+		cleanup()
+		return errors.Wrap(err, "environment/containerd: failed to wait on task")
+	}
+
+	go e.scanOutput(outR)
+
+	exited := make(chan struct{})
+	e.mu.Lock()
+	e.task = task
+	e.stdin = stdinW
+	e.exited = exited
+	e.startedAt = time.Now()
+	e.mu.Unlock()
+
+	go func() {
+		// Don't use the context provided to the function, that'll cause the polling to
+		// exit unexpectedly.
+		pollCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go func() {
+			if err := e.pollResources(pollCtx); err != nil {
+				if !errors.Is(err, context.Canceled) {
+					e.log().WithField("error", err).Error("error during environment resource polling")
+				} else {
+					e.log().Warn("stopping server resource polling: context canceled")
+				}
+			}
+		}()
+
+		go e.watchOutOfMemory(pollCtx)
+
+		st := <-exitCh
+		code, _, err := st.Result()
+		if err != nil {
+			e.log().WithField("error", err).Warn("error while waiting for container task to exit")
+		}
+
+		// Remove the task so that a new one can be created the next time the server is
+		// started, this also releases the IO for the task.
+		dctx, dcancel := context.WithTimeout(context.Background(), time.Second*10)
+		if _, err := task.Delete(dctx); err != nil && !errdefs.IsNotFound(err) {
+			e.log().WithField("error", err).Warn("failed to delete exited container task")
+		}
+		dcancel()
+		cleanup()
+
+		e.mu.Lock()
+		e.task = nil
+		e.stdin = nil
+		e.exitCode = code
+		e.mu.Unlock()
+		close(exited)
+
+		e.SetState(environment.ProcessOfflineState)
+	}()
+
+	return nil
+}
+
+// watchOutOfMemory listens for out of memory events for the container until the
+// context is canceled. containerd sends these whenever a process in the container
+// is killed for exceeding the memory limit, even if the task keeps running.
+func (e *Environment) watchOutOfMemory(ctx context.Context) {
+	msgs, errs := e.client.Subscribe(ctx, `topic=="/tasks/oom"`)
+	namespace := config.Get().Containerd.Namespace
+	for {
+		select {
+		case env := <-msgs:
+			if env == nil || env.Namespace != namespace {
+				continue
+			}
+			v, err := typeurl.UnmarshalAny(env.Event)
+			if err != nil {
+				continue
+			}
+			if oom, ok := v.(*apievents.TaskOOM); ok && oom.ContainerID == e.Id {
+				e.Events().Publish(environment.OutOfMemoryEvent, "")
+			}
+		case err := <-errs:
+			if err != nil && !errors.Is(err, context.Canceled) {
+				e.log().WithField("error", err).Warn("error while listening for container out of memory events")
+			}
+			return
+		}
+	}
+}
+
+// InSituUpdate performs an in-place update of the task's resource limits without
+// making any changes to the operational state of the container.
+func (e *Environment) InSituUpdate() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	t, err := e.loadTask(ctx)
+	if err != nil {
+		// If there is no task there is nothing to update, the limits will be applied
+		// when the container is next created.
+		if errdefs.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := t.Update(ctx, containerd.WithResources(e.resources())); err != nil {
+		return errors.Wrap(err, "environment/containerd: could not update task")
+	}
+	return nil
+}
+
+// Create creates a new container for the server using all the data that is
+// currently available for it. If the container already exists it will be
+// returned.
+func (e *Environment) Create() error {
+	ctx := context.Background()
+	if _, err := e.client.LoadContainer(ctx, e.Id); err == nil {
+		return nil
+	} else if !errdefs.IsNotFound(err) {
+		return errors.Wrap(err, "environment/containerd: failed to load container")
+	}
+
+	e.mu.RLock()
+	image := e.meta.Image
+	e.mu.RUnlock()
+
+	img, err := e.ensureImageExists(ctx, image)
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	evs, err := environment.RenderVariables(e.Configuration.TranslateVariables(e.Configuration.EnvironmentVariables()), e.Configuration.Allocations())
+	if err != nil {
+		e.log().WithField("error", err).Warn("failed to render templates within environment variables")
+	}
+
+	limits := e.Configuration.Limits()
+	opts := []oci.SpecOpts{
+		oci.WithImageConfig(img),
+		oci.WithHostname(e.Id),
+		oci.WithEnv(evs),
+		oci.WithTTY,
+		oci.WithMounts(e.convertMounts()),
+		oci.WithAnnotations(map[string]string{
+			"Service":       "Pterodactyl",
+			"ContainerType": "server_process",
+		}),
+	}
+	if limit := limits.BoundedMemoryLimit(); limit > 0 {
+		opts = append(opts, oci.WithMemoryLimit(uint64(limit)))
+	}
+	opts = append(opts, platformSpecOpts(e)...)
+
+	snapshotter := config.Get().Containerd.Snapshotter
+	if snapshotter == "" {
+		snapshotter = containerd.DefaultSnapshotter
+	}
+	_, err = e.client.NewContainer(ctx, e.Id,
+		containerd.WithImage(img),
+		containerd.WithSnapshotter(snapshotter),
+		containerd.WithNewSnapshot(e.Id+"-snapshot", img),
+		containerd.WithNewSpec(opts...),
+		containerd.WithContainerLabels(map[string]string{
+			"Service":       "Pterodactyl",
+			"ContainerType": "server_process",
+		}),
+	)
+	if err != nil {
+		return errors.Wrap(err, "environment/containerd: failed to create container")
+	}
+
+	return nil
+}
+
+// Destroy will remove the container from the server. If the task is currently
+// running it will be forcibly killed.
+func (e *Environment) Destroy() error {
+	// We set it to stopping than offline to prevent crash detection from being triggered.
+	e.SetState(environment.ProcessStoppingState)
+
+	err := e.removeContainer(context.Background())
+
+	e.SetState(environment.ProcessOfflineState)
+
+	return err
+}
+
+// removeContainer kills and removes any task for the container, and then removes
+// the container along with its snapshot. If the container does not exist this is
+// a no-op.
+func (e *Environment) removeContainer(ctx context.Context) error {
+	c, err := e.client.LoadContainer(ctx, e.Id)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, "environment/containerd: failed to load container")
+	}
+	if t, err := c.Task(ctx, nil); err == nil {
+		if _, err := t.Delete(ctx, containerd.WithProcessKill); err != nil && !errdefs.IsNotFound(err) {
+			return errors.Wrap(err, "environment/containerd: failed to remove task")
+		}
+	}
+	if err := c.Delete(ctx, containerd.WithSnapshotCleanup); err != nil && !errdefs.IsNotFound(err) {
+		return errors.Wrap(err, "environment/containerd: failed to remove container")
+	}
+	return nil
+}
+
+// loadTask returns the task for the container, or a NotFound error if either the
+// container or task does not exist.
+func (e *Environment) loadTask(ctx context.Context) (containerd.Task, error) {
+	e.mu.RLock()
+	t := e.task
+	e.mu.RUnlock()
+	if t != nil {
+		return t, nil
+	}
+	c, err := e.client.LoadContainer(ctx, e.Id)
+	if err != nil {
+		return nil, err
+	}
+	return c.Task(ctx, nil)
+}
+
+// SendCommand sends the specified command to the stdin of the running task.
+// There is no confirmation that this data is sent successfully, only that it
+// gets pushed into the stdin.
+func (e *Environment) SendCommand(c string) error {
+	if !e.IsAttached() {
+		return errors.Wrap(ErrNotAttached, "environment/containerd: cannot send command to container")
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	// If the command being processed is the same as the process stop command then we
+	// want to mark the server as entering the stopping state otherwise the process will
+	// stop and Wings will think it has crashed and attempt to restart it.
+	if e.meta.Stop.Type == "command" && c == e.meta.Stop.Value {
+		e.SetState(environment.ProcessStoppingState)
+	}
+
+	_, err := e.stdin.Write([]byte(c + "\n"))
+
+	return errors.Wrap(err, "environment/containerd: could not write to container stdin")
+}
+
+// Readlog reads the log file for the server. This does not care if the server
+// is running or not, it will simply try to read the last lines of the file.
+func (e *Environment) Readlog(lines int) ([]string, error) {
+	f, err := os.Open(e.logPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	var out []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		out = append(out, scanner.Text())
+		if len(out) > lines {
+			out = out[1:]
+		}
+	}
+
+	return out, nil
+}
+
+func (e *Environment) scanOutput(reader io.ReadCloser) {
+	defer reader.Close()
+
+	if err := system.ScanReader(reader, func(v []byte) {
+		e.logCallbackMx.Lock()
+		defer e.logCallbackMx.Unlock()
+		e.logCallback(v)
+	}); err != nil && err != io.EOF {
+		e.log().WithField("error", err).Warn("error processing scanner line in console output")
+	}
+}
+
+// ensureImageExists returns the image for the container, pulling it first if
+// required by the pull policy. If the pull fails but the image already exists
+// locally the error is logged and the local image is used.
+func (e *Environment) ensureImageExists(ctx context.Context, image string) (containerd.Image, error) {
+	e.Events().Publish(environment.DockerImagePullStarted, "")
+	defer e.Events().Publish(environment.DockerImagePullCompleted, "")
+
+	ref, err := normalizeImage(image)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	policy := environment.PullPolicy(e.meta.PullPolicy)
+	e.mu.RUnlock()
+
+	// Images prefixed with a ~ are local images that we do not need to try and pull.
+	if strings.HasPrefix(image, "~") {
+		policy = environment.PullPolicyNever
+	}
+
+	snapshotter := config.Get().Containerd.Snapshotter
+	if snapshotter == "" {
+		snapshotter = containerd.DefaultSnapshotter
+	}
+
+	local, lerr := e.client.GetImage(ctx, ref)
+	if lerr != nil && !errdefs.IsNotFound(lerr) {
+		return nil, errors.Wrap(lerr, "environment/containerd: failed to get image")
+	}
+	if lerr == nil && policy != environment.PullPolicyAlways {
+		return local, e.unpack(ctx, local, snapshotter)
+	}
+	if lerr != nil && policy == environment.PullPolicyNever {
+		return nil, errors.Wrapf(environment.ErrImageNotPresent, "environment/containerd: cannot use \"%s\"", ref)
+	}
+
+	// Give it up to 15 minutes to pull the image, matching the Docker environment.
+	pctx, cancel := context.WithTimeout(ctx, time.Minute*15)
+	defer cancel()
+
+	e.log().WithField("image", ref).Debug("pulling image... this could take a bit of time")
+	e.Events().Publish(environment.DockerImagePullStatus, "Pulling "+ref)
+	img, err := e.client.Pull(pctx, ref,
+		containerd.WithPullUnpack,
+		containerd.WithPullSnapshotter(snapshotter),
+		containerd.WithResolver(resolver()),
+	)
+	if err != nil {
+		if lerr == nil {
+			e.log().WithFields(log.Fields{"image": ref, "error": err}).Warn("unable to pull requested image from remote source, however the image exists locally")
+			return local, e.unpack(ctx, local, snapshotter)
+		}
+		return nil, errors.Wrapf(err, "environment/containerd: failed to pull \"%s\" image for server", ref)
+	}
+
+	e.log().WithField("image", ref).Debug("completed image pull")
+	return img, nil
+}
+
+// unpack ensures that the image has been unpacked into the snapshotter being used
+// for server containers.
+func (e *Environment) unpack(ctx context.Context, img containerd.Image, snapshotter string) error {
+	ok, err := img.IsUnpacked(ctx, snapshotter)
+	if err != nil {
+		return errors.Wrap(err, "environment/containerd: failed to check image unpack state")
+	}
+	if ok {
+		return nil
+	}
+	return errors.Wrap(img.Unpack(ctx, snapshotter), "environment/containerd: failed to unpack image")
+}
+
+func (e *Environment) convertMounts() []specs.Mount {
+	var out []specs.Mount
+
+	for _, m := range e.Configuration.Mounts() {
+		out = append(out, specs.Mount{
+			Type:        mountType,
+			Source:      m.Source,
+			Destination: m.Target,
+			Options:     mountOptions(m.ReadOnly),
+		})
+	}
+
+	return out
+}
@@ -0,0 +1,238 @@
+package containerd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/errdefs"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/events"
+	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/system"
+)
+
+type Metadata struct {
+	Image      string
+	PullPolicy string
+	Stop       remote.ProcessStopConfiguration
+}
+
+// Ensure that the containerd environment is always implementing all the methods
+// from the base environment interface.
+var _ environment.ProcessEnvironment = (*Environment)(nil)
+
+type Environment struct {
+	mu sync.RWMutex
+
+	// The public identifier for this environment. This is used as the containerd
+	// container ID for all instances created under it.
+	Id string
+
+	// The environment configuration.
+	Configuration *environment.Configuration
+
+	meta *Metadata
+
+	// The containerd client being used for this instance.
+	client *containerd.Client
+
+	// The task for the running container process, this exists only when we're
+	// attached to the running container instance.
+	task containerd.Task
+
+	// The write side of the pipe connected to the stdin of the task.
+	stdin io.WriteCloser
+
+	// Closed once the currently attached task has exited.
+	exited chan struct{}
+
+	// The exit code of the last task that exited, and the time it was started.
+	exitCode  uint32
+	startedAt time.Time
+
+	emitter *events.Bus
+
+	logCallbackMx sync.Mutex
+	logCallback   func([]byte)
+
+	// Tracks the environment state.
+	st *system.AtomicString
+}
+
+// New creates a new base containerd environment. The ID passed through will be
+// the ID that is used to reference the container from here on out. This should
+// be unique per-server (we use the UUID by default). The container does not need
+// to exist at this point.
+func New(id string, m *Metadata, c *environment.Configuration) (*Environment, error) {
+	cli, err := Client()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Environment{
+		Id:            id,
+		Configuration: c,
+		meta:          m,
+		client:        cli,
+		st:            system.NewAtomicString(environment.ProcessOfflineState),
+		emitter:       events.NewBus(),
+	}
+
+	return e, nil
+}
+
+func (e *Environment) log() *log.Entry {
+	return log.WithField("environment", e.Type()).WithField("container_id", e.Id)
+}
+
+func (e *Environment) Type() string {
+	return "containerd"
+}
+
+// IsAttached determines if this process is currently attached to the task
+// running in the container.
+func (e *Environment) IsAttached() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.task != nil
+}
+
+// Events returns an event bus for the environment.
+func (e *Environment) Events() *events.Bus {
+	return e.emitter
+}
+
+// Exists determines if the container exists in this environment.
+func (e *Environment) Exists() (bool, error) {
+	if _, err := e.client.LoadContainer(context.Background(), e.Id); err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "environment/containerd: failed to load container")
+	}
+	return true, nil
+}
+
+// IsRunning determines if the task for the server container is currently running.
+func (e *Environment) IsRunning(ctx context.Context) (bool, error) {
+	c, err := e.client.LoadContainer(ctx, e.Id)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "environment/containerd: failed to load container")
+	}
+	t, err := c.Task(ctx, nil)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "environment/containerd: failed to load task")
+	}
+	st, err := t.Status(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "environment/containerd: failed to get task status")
+	}
+	return st.Status == containerd.Running, nil
+}
+
+// ExitState returns the exit code of the last task that ran for the container.
+// containerd does not report if the process was killed by the OOM killer in the
+// exit status, so the second value is always false. Out of memory kills are sent
+// as events instead while the task is running.
+func (e *Environment) ExitState() (uint32, bool, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.exitCode, false, nil
+}
+
+// Config returns the environment configuration allowing a process to make
+// modifications of the environment on the fly.
+func (e *Environment) Config() *environment.Configuration {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.Configuration
+}
+
+// SetStopConfiguration sets the stop configuration for the environment.
+func (e *Environment) SetStopConfiguration(c remote.ProcessStopConfiguration) {
+	e.mu.Lock()
+	e.meta.Stop = c
+	e.mu.Unlock()
+}
+
+func (e *Environment) SetImage(i string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.meta.Image = i
+}
+
+// SetPullPolicy sets the policy used to determine if the image for the environment
+// should be pulled before the container is created.
+func (e *Environment) SetPullPolicy(p string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.meta.PullPolicy = p
+}
+
+func (e *Environment) State() string {
+	return e.st.Load()
+}
+
+// SetState sets the state of the environment. This emits an event that server's
+// can hook into to take their own actions and track their own state based on
+// the environment.
+func (e *Environment) SetState(state string) {
+	if state != environment.ProcessOfflineState &&
+		state != environment.ProcessStartingState &&
+		state != environment.ProcessRunningState &&
+		state != environment.ProcessStoppingState {
+		panic(errors.New("attempting to set server to invalid state: " + state))
+	}
+
+	// Emit the event to any listeners that are currently registered.
+	if e.State() != state {
+		// If the state changed make sure we update the internal tracking to note that.
+		e.st.Store(state)
+		e.Events().Publish(environment.StateChangeEvent, state)
+	}
+}
+
+func (e *Environment) SetLogCallback(f func([]byte)) {
+	e.logCallbackMx.Lock()
+	defer e.logCallbackMx.Unlock()
+
+	e.logCallback = f
+}
+
+// logPath returns the path to the file that the console output of the container
+// is written to.
+func (e *Environment) logPath() string {
+	return filepath.Join(config.Get().System.LogDirectory, "containers", e.Id+".log")
+}
+
+// openLog opens the log file for the container for appending, creating it if it
+// does not yet exist.
+func (e *Environment) openLog() (*os.File, error) {
+	p := e.logPath()
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return nil, errors.Wrap(err, "environment/containerd: failed to create log directory")
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, errors.Wrap(err, "environment/containerd: failed to open container log")
+	}
+	return f, nil
+}
@@ -0,0 +1,236 @@
+package containerd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/errdefs"
+
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/remote"
+)
+
+// OnBeforeStart run before the container starts and get the process
+// configuration from the Panel. The container is always removed and created
+// again to ensure that synced data from the Panel is used.
+func (e *Environment) OnBeforeStart(ctx context.Context) error {
+	if err := e.removeContainer(ctx); err != nil {
+		return errors.WrapIf(err, "environment/containerd: failed to remove container during pre-boot")
+	}
+
+	return e.Create()
+}
+
+// Start will start the server environment and begins piping output to the event
+// listeners for the console. If a container does not exist, or needs to be
+// rebuilt that will happen in the call to OnBeforeStart().
+func (e *Environment) Start(ctx context.Context) error {
+	sawError := false
+
+	// If sawError is set to true there was an error somewhere in the pipeline that
+	// got passed up, but we also want to ensure we set the server to be offline at
+	// that point.
+	defer func() {
+		if sawError {
+			// If we don't set it to stopping first, you'll trigger crash detection which
+			// we don't want to do at this point since it'll just immediately try to do the
+			// exact same action that lead to it crashing in the first place...
+			e.SetState(environment.ProcessStoppingState)
+			e.SetState(environment.ProcessOfflineState)
+		}
+	}()
+
+	running, err := e.IsRunning(ctx)
+	if err != nil {
+		return err
+	}
+	// If the server is running update our internal state and continue on with the attach.
+	if running {
+		e.SetState(environment.ProcessRunningState)
+
+		return e.Attach(ctx)
+	}
+
+	// Truncate the log file, so we don't end up outputting a bunch of useless log
+	// information to the websocket from the previous run.
+	if _, err := os.Stat(e.logPath()); err == nil {
+		if err := os.Truncate(e.logPath(), 0); err != nil {
+			return errors.Wrap(err, "environment/containerd: failed to truncate instance logs")
+		}
+	}
+
+	e.SetState(environment.ProcessStartingState)
+
+	// Set this to true for now, we will set it to false once we reach the
+	// end of this chain.
+	sawError = true
+
+	if err := e.OnBeforeStart(ctx); err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	// If we cannot start & attach to the container in 30 seconds something has gone
+	// quite sideways and we should stop trying to avoid a hanging situation.
+	actx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	// Attaching creates the task for the container, it must happen before the task
+	// is started so that no output or exit events are missed.
+	if err := e.Attach(actx); err != nil {
+		return err
+	}
+
+	e.mu.RLock()
+	t := e.task
+	e.mu.RUnlock()
+	if t == nil {
+		return errors.New("environment/containerd: task exited before it could be started")
+	}
+	if err := t.Start(actx); err != nil {
+		return errors.WrapIf(err, "environment/containerd: failed to start task")
+	}
+
+	// No errors, good to continue through.
+	sawError = false
+	return nil
+}
+
+// Stop stops the container that the server is running in. If the stop
+// configuration is a command it is sent to the process, otherwise the process
+// is sent the configured signal.
+//
+// You most likely want to be using WaitForStop() rather than this function,
+// since this will return as soon as the command is sent, rather than waiting
+// for the process to be completed stopped.
+func (e *Environment) Stop(ctx context.Context) error {
+	e.mu.RLock()
+	s := e.meta.Stop
+	e.mu.RUnlock()
+
+	if s.Type == "" || s.Type == remote.ProcessStopSignal {
+		if s.Type == "" {
+			e.log().Warn("no stop configuration detected for environment, using termination procedure")
+		}
+
+		signal := os.Kill
+		switch strings.ToUpper(s.Value) {
+		case "SIGABRT":
+			signal = syscall.SIGABRT
+		case "SIGINT":
+			signal = syscall.SIGINT
+		case "SIGTERM":
+			signal = syscall.SIGTERM
+		}
+		return e.Terminate(ctx, signal)
+	}
+
+	// If the process is already offline don't switch it back to stopping. Just leave it how
+	// it is and continue through to the stop handling for the process.
+	if e.st.Load() != environment.ProcessOfflineState {
+		e.SetState(environment.ProcessStoppingState)
+	}
+
+	if e.IsAttached() && s.Type == remote.ProcessStopCommand {
+		return e.SendCommand(s.Value)
+	}
+
+	t, err := e.loadTask(ctx)
+	if err != nil {
+		// If the task does not exist just mark the process as stopped and return
+		// without an error.
+		if errdefs.IsNotFound(err) {
+			e.SetState(environment.ProcessOfflineState)
+			return nil
+		}
+		return errors.Wrap(err, "environment/containerd: cannot stop container")
+	}
+	if err := t.Kill(ctx, syscall.SIGTERM, containerd.WithKillAll); err != nil && !errdefs.IsNotFound(err) {
+		return errors.Wrap(err, "environment/containerd: cannot stop container")
+	}
+
+	return nil
+}
+
+// WaitForStop attempts to gracefully stop a server using the defined stop
+// command. If the server does not stop after seconds have passed, an error will
+// be returned, or the instance will be terminated forcefully depending on the
+// value of the second argument.
+func (e *Environment) WaitForStop(ctx context.Context, duration time.Duration, terminate bool) error {
+	tctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	doTermination := func(s string) error {
+		e.log().WithField("step", s).WithField("duration", duration).Warn("container stop did not complete in time, terminating process...")
+		return e.Terminate(ctx, os.Kill)
+	}
+
+	if err := e.Stop(tctx); err != nil {
+		if terminate && errors.Is(err, context.DeadlineExceeded) {
+			return doTermination("stop")
+		}
+		return err
+	}
+
+	e.mu.RLock()
+	exited := e.exited
+	attached := e.task != nil
+	e.mu.RUnlock()
+	// Without an attached task there is nothing to wait on, Stop has already marked
+	// the process as offline if the task did not exist.
+	if !attached {
+		return nil
+	}
+
+	select {
+	case <-exited:
+	case <-tctx.Done():
+		if terminate {
+			return doTermination("wait")
+		}
+		return errors.WrapIf(tctx.Err(), "environment/containerd: error waiting on container to stop")
+	}
+
+	return nil
+}
+
+// Terminate forcefully terminates the container using the signal provided.
+func (e *Environment) Terminate(ctx context.Context, signal os.Signal) error {
+	running, err := e.IsRunning(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !running {
+		// If the container is not running, but we're not already in a stopped state go ahead
+		// and update things to indicate we should be completely stopped now. Set to stopping
+		// first so crash detection is not triggered.
+		if e.st.Load() != environment.ProcessOfflineState {
+			e.SetState(environment.ProcessStoppingState)
+			e.SetState(environment.ProcessOfflineState)
+		}
+
+		return nil
+	}
+
+	// We set it to stopping than offline to prevent crash detection from being triggered.
+	e.SetState(environment.ProcessStoppingState)
+	sig, ok := signal.(syscall.Signal)
+	if !ok {
+		sig = syscall.SIGKILL
+	}
+	t, err := e.loadTask(ctx)
+	if err == nil {
+		err = t.Kill(ctx, sig, containerd.WithKillAll)
+	}
+	if err != nil && !errdefs.IsNotFound(err) {
+		return errors.WithStack(err)
+	}
+	e.SetState(environment.ProcessOfflineState)
+
+	return nil
+}
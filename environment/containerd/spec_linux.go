@@ -0,0 +1,88 @@
+package containerd
+
+import (
+	"strconv"
+
+	"github.com/containerd/containerd/oci"
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// Mounts are created as recursive bind mounts of the server directories.
+const mountType = "bind"
+
+// getContainerUser gets the user for the container
+func getContainerUser() string {
+	return strconv.Itoa(config.Get().System.User.Uid) + ":" + strconv.Itoa(config.Get().System.User.Gid)
+}
+
+func mountOptions(readOnly bool) []string {
+	if readOnly {
+		return []string{"rbind", "ro"}
+	}
+	return []string{"rbind", "rw"}
+}
+
+// platformSpecOpts returns the Linux specific options for the container spec.
+// There is no CNI support at this time, so containers share the network of the
+// host and bind directly to their allocations.
+func platformSpecOpts(e *Environment) []oci.SpecOpts {
+	l := e.Configuration.Limits()
+
+	opts := []oci.SpecOpts{
+		oci.WithHostNamespace(specs.NetworkNamespace),
+		oci.WithHostHostsFile,
+		oci.WithHostResolvconf,
+		oci.WithNoNewPrivileges,
+		oci.WithUser(getContainerUser()),
+		oci.WithCPUShares(1024),
+		oci.WithPidsLimit(l.ProcessLimit()),
+	}
+	if l.CpuLimit > 0 {
+		opts = append(opts, oci.WithCPUCFS(l.ConvertedCpuLimit(), 100_000))
+	}
+	if l.Threads != "" {
+		opts = append(opts, oci.WithCPUs(l.Threads))
+	}
+
+	return opts
+}
+
+func (e *Environment) resources() *specs.LinuxResources {
+	l := e.Configuration.Limits()
+	pids := l.ProcessLimit()
+	quota := l.ConvertedCpuLimit()
+	period := uint64(100_000)
+	shares := uint64(1024)
+
+	r := &specs.LinuxResources{
+		CPU: &specs.LinuxCPU{
+			Shares: &shares,
+			Quota:  &quota,
+			Period: &period,
+			Cpus:   l.Threads,
+		},
+		Pids: &specs.LinuxPids{Limit: pids},
+	}
+	// Servers without a memory limit are still limited when the node reserves memory
+	// for the host, in which case the reservation is never more than the limit.
+	if limit := l.BoundedMemoryLimit(); limit > 0 {
+		swap := l.ConvertedSwap()
+		reservation := l.MemoryLimit * 1_000_000
+		if reservation > limit {
+			reservation = limit
+		}
+		r.Memory = &specs.LinuxMemory{
+			Limit:            &limit,
+			Reservation:      &reservation,
+			Swap:             &swap,
+			DisableOOMKiller: &l.OOMDisabled,
+		}
+	}
+	if l.IoWeight > 0 {
+		r.BlockIO = &specs.LinuxBlockIO{Weight: &l.IoWeight}
+	}
+
+	return r
+}
@@ -0,0 +1,87 @@
+package containerd
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/oci"
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// Windows does not support bind mounts in the same way Linux does, the mount
+// type is left empty and the directory is mapped into the container.
+const mountType = ""
+
+// getContainerUser gets the user for the container
+func getContainerUser() string {
+	return config.Get().System.Username
+}
+
+func mountOptions(readOnly bool) []string {
+	if readOnly {
+		return []string{"ro"}
+	}
+	return nil
+}
+
+// platformSpecOpts returns the Windows specific options for the container spec.
+func platformSpecOpts(e *Environment) []oci.SpecOpts {
+	opts := []oci.SpecOpts{
+		oci.WithUsername(getContainerUser()),
+		withCPUMaximum(e.cpuMaximum()),
+	}
+	if ns := config.Get().Containerd.NetworkNamespace; ns != "" {
+		opts = append(opts, oci.WithWindowsNetworkNamespace(ns))
+	}
+	return opts
+}
+
+// cpuMaximum converts the CPU limit for the server, where 100 is a single core,
+// into the portion of the total processor cycles that the container can use in
+// the range 1 to 10000. Zero is returned if there is no limit.
+func (e *Environment) cpuMaximum() uint16 {
+	l := e.Configuration.Limits()
+	if l.CpuLimit <= 0 {
+		return 0
+	}
+	max := l.CpuLimit * 100 / int64(runtime.NumCPU())
+	if max < 1 {
+		max = 1
+	} else if max > 10000 {
+		max = 10000
+	}
+	return uint16(max)
+}
+
+func withCPUMaximum(max uint16) oci.SpecOpts {
+	return func(_ context.Context, _ oci.Client, _ *containers.Container, s *oci.Spec) error {
+		if max == 0 {
+			return nil
+		}
+		if s.Windows == nil {
+			s.Windows = &specs.Windows{}
+		}
+		if s.Windows.Resources == nil {
+			s.Windows.Resources = &specs.WindowsResources{}
+		}
+		s.Windows.Resources.CPU = &specs.WindowsCPUResources{Maximum: &max}
+		return nil
+	}
+}
+
+func (e *Environment) resources() *specs.WindowsResources {
+	l := e.Configuration.Limits()
+
+	r := &specs.WindowsResources{}
+	if limit := uint64(l.BoundedMemoryLimit()); limit > 0 {
+		r.Memory = &specs.WindowsMemoryResources{Limit: &limit}
+	}
+	if max := e.cpuMaximum(); max > 0 {
+		r.CPU = &specs.WindowsCPUResources{Maximum: &max}
+	}
+
+	return r
+}
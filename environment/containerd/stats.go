@@ -0,0 +1,112 @@
+package containerd
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/containerd/typeurl"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+// How often the resource usage of the running task is collected.
+const pollInterval = time.Second
+
+// Uptime returns the current uptime of the container in milliseconds. If the
+// container is not currently running this will return 0.
+func (e *Environment) Uptime(ctx context.Context) (int64, error) {
+	running, err := e.IsRunning(ctx)
+	if err != nil || !running {
+		return 0, err
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.startedAt.IsZero() {
+		return 0, nil
+	}
+	return time.Since(e.startedAt).Milliseconds(), nil
+}
+
+// metrics contains the values we care about from the platform specific metrics
+// returned by the task.
+type metrics struct {
+	// Memory usage and limit, in bytes.
+	memory      uint64
+	memoryLimit uint64
+	// Total CPU time used by the task, in nanoseconds.
+	cpu     uint64
+	network environment.NetworkStats
+}
+
+// Polls the resource usage of the attached task and emits an event whenever it
+// has been collected, until the context is canceled.
+func (e *Environment) pollResources(ctx context.Context) error {
+	if e.st.Load() == environment.ProcessOfflineState {
+		return errors.New("cannot enable resource polling on a stopped server")
+	}
+
+	e.log().Info("starting resource polling for container")
+	defer e.log().Debug("stopped resource polling for container")
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var prev metrics
+	var prevRead time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			// Disable collection if the server is in an offline state and this process is still running.
+			if e.st.Load() == environment.ProcessOfflineState {
+				e.log().Debug("process in offline state while resource polling is still active; stopping poll")
+				return nil
+			}
+
+			e.mu.RLock()
+			t := e.task
+			started := e.startedAt
+			e.mu.RUnlock()
+			if t == nil {
+				return nil
+			}
+
+			m, err := t.Metrics(ctx)
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					return nil
+				}
+				e.log().WithField("error", err).Warn("error while collecting metrics for container task")
+				continue
+			}
+			data, err := typeurl.UnmarshalAny(m.Data)
+			if err != nil {
+				e.log().WithField("error", err).Warn("error while decoding metrics for container task")
+				continue
+			}
+			v, ok := parseMetrics(data)
+			if !ok {
+				e.log().Warn("unsupported metrics type returned for container task")
+				continue
+			}
+
+			cpu := 0.0
+			now := time.Now()
+			if !prevRead.IsZero() && v.cpu > prev.cpu {
+				cpu = float64(v.cpu-prev.cpu) / float64(now.Sub(prevRead).Nanoseconds()) * 100.0
+			}
+			prev, prevRead = v, now
+
+			e.Events().Publish(environment.ResourceEvent, environment.Stats{
+				Uptime:      time.Since(started).Milliseconds(),
+				Memory:      v.memory,
+				MemoryLimit: v.memoryLimit,
+				CpuAbsolute: math.Round(cpu*1000) / 1000,
+				Network:     v.network,
+			})
+		}
+	}
+}
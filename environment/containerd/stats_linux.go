@@ -0,0 +1,44 @@
+package containerd
+
+import (
+	v1 "github.com/containerd/cgroups/stats/v1"
+	v2 "github.com/containerd/cgroups/v2/stats"
+)
+
+// parseMetrics converts the cgroup v1 or v2 metrics returned by the task. The
+// inactive file cache is excluded from the memory usage to match the value
+// reported by the Docker environment.
+func parseMetrics(data interface{}) (metrics, bool) {
+	var m metrics
+	switch v := data.(type) {
+	case *v1.Metrics:
+		if v.Memory != nil && v.Memory.Usage != nil {
+			m.memory = v.Memory.Usage.Usage
+			m.memoryLimit = v.Memory.Usage.Limit
+			if v.Memory.TotalInactiveFile < m.memory {
+				m.memory -= v.Memory.TotalInactiveFile
+			}
+		}
+		if v.CPU != nil && v.CPU.Usage != nil {
+			m.cpu = v.CPU.Usage.Total
+		}
+		for _, n := range v.Network {
+			m.network.RxBytes += n.RxBytes
+			m.network.TxBytes += n.TxBytes
+		}
+	case *v2.Metrics:
+		if v.Memory != nil {
+			m.memory = v.Memory.Usage
+			m.memoryLimit = v.Memory.UsageLimit
+			if v.Memory.InactiveFile < m.memory {
+				m.memory -= v.Memory.InactiveFile
+			}
+		}
+		if v.CPU != nil {
+			m.cpu = v.CPU.UsageUsec * 1000
+		}
+	default:
+		return m, false
+	}
+	return m, true
+}
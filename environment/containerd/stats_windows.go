@@ -0,0 +1,23 @@
+package containerd
+
+import (
+	wstats "github.com/Microsoft/hcsshim/cmd/containerd-shim-runhcs-v1/stats"
+)
+
+// parseMetrics converts the statistics returned by the Windows shim for the task.
+func parseMetrics(data interface{}) (metrics, bool) {
+	var m metrics
+	v, ok := data.(*wstats.Statistics)
+	if !ok || v.GetWindows() == nil {
+		return m, false
+	}
+	w := v.GetWindows()
+	if w.Memory != nil {
+		m.memory = w.Memory.MemoryUsagePrivateWorkingSetBytes
+		m.memoryLimit = w.Memory.MemoryUsageCommitBytes
+	}
+	if w.Processor != nil {
+		m.cpu = w.Processor.TotalRuntimeNS
+	}
+	return m, true
+}
@@ -0,0 +1,96 @@
+package environment
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// The types of containers that Wings creates, set using the "ContainerType" label.
+const (
+	ContainerTypeServer    = "server_process"
+	ContainerTypeInstaller = "server_installer"
+	ContainerTypeSidecar   = "server_sidecar"
+)
+
+// ManagedContainer is a container created by Wings for a server.
+type ManagedContainer struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Server  string `json:"server"`
+	Image   string `json:"image"`
+	Running bool   `json:"running"`
+}
+
+// ManagedContainers returns every container that was created by Wings, along
+// with the server that each container belongs to.
+func ManagedContainers(ctx context.Context) ([]ManagedContainer, error) {
+	cli, err := Docker()
+	if err != nil {
+		return nil, err
+	}
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "Service=Pterodactyl")),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "environment/docker: failed to list containers")
+	}
+	out := make([]ManagedContainer, 0, len(containers))
+	for _, c := range containers {
+		var name string
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		out = append(out, ManagedContainer{
+			ID:      c.ID,
+			Name:    name,
+			Type:    c.Labels["ContainerType"],
+			Server:  containerServer(name, c.Labels),
+			Image:   c.Image,
+			Running: c.State == "running",
+		})
+	}
+	return out, nil
+}
+
+// containerServer returns the UUID of the server that a container belongs to.
+// Sidecars are labeled with the server they belong to, while the containers for
+// the server process and installer are named after the server.
+func containerServer(name string, labels map[string]string) string {
+	if uuid := labels["ServerUuid"]; uuid != "" {
+		return uuid
+	}
+	return strings.TrimSuffix(name, "_installer")
+}
+
+// StopContainer stops the container, killing it if it does not stop within the
+// timeout.
+func StopContainer(ctx context.Context, id string, timeout time.Duration) error {
+	cli, err := Docker()
+	if err != nil {
+		return err
+	}
+	if err := cli.ContainerStop(ctx, id, &timeout); err != nil && !client.IsErrNotFound(err) {
+		return errors.Wrap(err, "environment/docker: failed to stop container")
+	}
+	return nil
+}
+
+// RemoveContainer forcibly removes the container, killing it if it is running.
+func RemoveContainer(ctx context.Context, id string) error {
+	cli, err := Docker()
+	if err != nil {
+		return err
+	}
+	if err := cli.ContainerRemove(ctx, id, types.ContainerRemoveOptions{RemoveVolumes: true, Force: true}); err != nil && !client.IsErrNotFound(err) {
+		return errors.Wrap(err, "environment/docker: failed to remove container")
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestContainerServer(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("containerServer", func() {
+		g.It("returns the server a container belongs to", func() {
+			g.Assert(containerServer("abc", nil)).Equal("abc")
+			g.Assert(containerServer("abc_installer", nil)).Equal("abc")
+			g.Assert(containerServer("abc_db", map[string]string{"ServerUuid": "abc"})).Equal("abc")
+		})
+	})
+}
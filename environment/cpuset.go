@@ -0,0 +1,250 @@
+package environment
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// NUMANode is a NUMA node on the host and the CPUs that belong to it.
+type NUMANode struct {
+	ID   int
+	CPUs []int
+}
+
+// CPUAssignment is the set of CPUs and NUMA nodes that a server is pinned to, in
+// the format used by cpusets.
+type CPUAssignment struct {
+	Cpus string
+	Mems string
+}
+
+// cpuAllocator tracks the CPUs assigned to each server so that new servers can
+// be packed onto the cores that are the least used.
+type cpuAllocator struct {
+	mu       sync.Mutex
+	nodes    []NUMANode
+	used     map[int]int
+	assigned map[string][]int
+}
+
+var (
+	_cpuOnce  sync.Once
+	_cpuAlloc *cpuAllocator
+)
+
+func allocator() *cpuAllocator {
+	_cpuOnce.Do(func() {
+		nodes := detectNUMANodes()
+		reserved, err := ParseCPUSet(config.Get().Docker.CpuPinning.Reserved)
+		if err != nil {
+			log.WithField("error", err).Warn("invalid reserved cpu set for cpu pinning, ignoring")
+		}
+		_cpuAlloc = newCPUAllocator(nodes, reserved)
+	})
+	return _cpuAlloc
+}
+
+func newCPUAllocator(nodes []NUMANode, reserved []int) *cpuAllocator {
+	skip := make(map[int]bool, len(reserved))
+	for _, c := range reserved {
+		skip[c] = true
+	}
+	a := &cpuAllocator{used: make(map[int]int), assigned: make(map[string][]int)}
+	for _, n := range nodes {
+		var cpus []int
+		for _, c := range n.CPUs {
+			if !skip[c] {
+				cpus = append(cpus, c)
+			}
+		}
+		if len(cpus) > 0 {
+			a.nodes = append(a.nodes, NUMANode{ID: n.ID, CPUs: cpus})
+		}
+	}
+	return a
+}
+
+// AssignCPUs pins a server to a set of CPUs based on its CPU limit, where 100 is a
+// single core. If CPU pinning is disabled, or the server does not have a CPU limit,
+// false is returned and the server should not be pinned.
+func AssignCPUs(id string, cpuLimit int64) (CPUAssignment, bool) {
+	if !config.Get().Docker.CpuPinning.Enabled || cpuLimit <= 0 {
+		return CPUAssignment{}, false
+	}
+	return allocator().assign(id, int((cpuLimit+99)/100))
+}
+
+// RegisterCPUs records the CPUs that a server is already pinned to, such as for a
+// container that was left running when Wings was restarted.
+func RegisterCPUs(id string, cpus string) {
+	if !config.Get().Docker.CpuPinning.Enabled || cpus == "" {
+		return
+	}
+	set, err := ParseCPUSet(cpus)
+	if err != nil {
+		return
+	}
+	allocator().register(id, set)
+}
+
+// ReleaseCPUs releases the CPUs assigned to a server so that they can be used by
+// other servers.
+func ReleaseCPUs(id string) {
+	if !config.Get().Docker.CpuPinning.Enabled {
+		return
+	}
+	allocator().release(id)
+}
+
+func (a *cpuAllocator) assign(id string, count int) (CPUAssignment, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.releaseLocked(id)
+	if len(a.nodes) == 0 || count <= 0 {
+		return CPUAssignment{}, false
+	}
+
+	// Use the node with the fewest free cores that can still fit the server entirely,
+	// this keeps larger blocks of free cores available for larger servers.
+	var picked []NUMANode
+	best := -1
+	for i, n := range a.nodes {
+		free := a.free(n)
+		if free >= count && (best == -1 || free < a.free(a.nodes[best])) {
+			best = i
+		}
+	}
+	if best != -1 {
+		picked = []NUMANode{a.nodes[best]}
+	} else {
+		// No node has enough free cores, so use the node with the most free cores if
+		// the server fits within its size, otherwise the server must span every node.
+		for i, n := range a.nodes {
+			if len(n.CPUs) >= count && (best == -1 || a.free(n) > a.free(a.nodes[best])) {
+				best = i
+			}
+		}
+		if best != -1 {
+			picked = []NUMANode{a.nodes[best]}
+		} else {
+			picked = a.nodes
+		}
+	}
+
+	var cpus []int
+	var mems []int
+	for _, n := range picked {
+		cpus = append(cpus, n.CPUs...)
+		mems = append(mems, n.ID)
+	}
+	// Prefer the least used cores, using the lowest numbered core when tied.
+	sort.SliceStable(cpus, func(i, j int) bool {
+		if a.used[cpus[i]] != a.used[cpus[j]] {
+			return a.used[cpus[i]] < a.used[cpus[j]]
+		}
+		return cpus[i] < cpus[j]
+	})
+	if len(cpus) > count {
+		cpus = cpus[:count]
+	}
+	sort.Ints(cpus)
+
+	a.assigned[id] = cpus
+	for _, c := range cpus {
+		a.used[c]++
+	}
+
+	return CPUAssignment{Cpus: FormatCPUSet(cpus), Mems: FormatCPUSet(mems)}, true
+}
+
+func (a *cpuAllocator) register(id string, cpus []int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.releaseLocked(id)
+	a.assigned[id] = cpus
+	for _, c := range cpus {
+		a.used[c]++
+	}
+}
+
+func (a *cpuAllocator) release(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.releaseLocked(id)
+}
+
+func (a *cpuAllocator) releaseLocked(id string) {
+	for _, c := range a.assigned[id] {
+		if a.used[c]--; a.used[c] <= 0 {
+			delete(a.used, c)
+		}
+	}
+	delete(a.assigned, id)
+}
+
+// free returns the number of cores on a node that are not assigned to any server.
+func (a *cpuAllocator) free(n NUMANode) int {
+	var free int
+	for _, c := range n.CPUs {
+		if a.used[c] == 0 {
+			free++
+		}
+	}
+	return free
+}
+
+// ParseCPUSet parses a set of CPUs in the format "0-3,8,10-11".
+func ParseCPUSet(s string) ([]int, error) {
+	var out []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		start, end := part, part
+		if i := strings.Index(part, "-"); i != -1 {
+			start, end = part[:i], part[i+1:]
+		}
+		a, err := strconv.Atoi(start)
+		if err != nil {
+			return nil, errors.Errorf("environment: invalid cpu set \"%s\"", s)
+		}
+		b, err := strconv.Atoi(end)
+		if err != nil || b < a {
+			return nil, errors.Errorf("environment: invalid cpu set \"%s\"", s)
+		}
+		for c := a; c <= b; c++ {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// FormatCPUSet formats a sorted list of CPUs into a set, collapsing consecutive
+// CPUs into ranges.
+func FormatCPUSet(cpus []int) string {
+	var parts []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(cpus[i]))
+		} else {
+			parts = append(parts, strconv.Itoa(cpus[i])+"-"+strconv.Itoa(cpus[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
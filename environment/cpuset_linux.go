@@ -0,0 +1,43 @@
+package environment
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// detectNUMANodes returns the NUMA nodes on the host using sysfs. If the host
+// does not expose its NUMA topology all the CPUs are returned as a single node.
+func detectNUMANodes() []NUMANode {
+	var nodes []NUMANode
+	matches, _ := filepath.Glob("/sys/devices/system/node/node[0-9]*")
+	for _, m := range matches {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(m), "node"))
+		if err != nil {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(m, "cpulist"))
+		if err != nil {
+			continue
+		}
+		cpus, err := ParseCPUSet(strings.TrimSpace(string(b)))
+		if err != nil || len(cpus) == 0 {
+			continue
+		}
+		nodes = append(nodes, NUMANode{ID: id, CPUs: cpus})
+	}
+	if len(nodes) == 0 {
+		cpus := make([]int, runtime.NumCPU())
+		for i := range cpus {
+			cpus[i] = i
+		}
+		return []NUMANode{{ID: 0, CPUs: cpus}}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID < nodes[j].ID
+	})
+	return nodes
+}
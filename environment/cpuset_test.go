@@ -0,0 +1,71 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestCPUSet(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("ParseCPUSet", func() {
+		g.It("parses ranges and single CPUs", func() {
+			cpus, err := ParseCPUSet("0-3,8, 10-11")
+			g.Assert(err).IsNil()
+			g.Assert(cpus).Equal([]int{0, 1, 2, 3, 8, 10, 11})
+		})
+
+		g.It("returns an error for an invalid set", func() {
+			_, err := ParseCPUSet("3-1")
+			g.Assert(err == nil).IsFalse()
+			_, err = ParseCPUSet("a")
+			g.Assert(err == nil).IsFalse()
+		})
+
+		g.It("formats CPUs back into ranges", func() {
+			g.Assert(FormatCPUSet([]int{0, 1, 2, 3, 8, 10, 11})).Equal("0-3,8,10-11")
+		})
+	})
+
+	g.Describe("cpuAllocator", func() {
+		nodes := []NUMANode{
+			{ID: 0, CPUs: []int{0, 1, 2, 3}},
+			{ID: 1, CPUs: []int{4, 5, 6, 7}},
+		}
+
+		g.It("packs servers onto the fullest node that fits them", func() {
+			a := newCPUAllocator(nodes, []int{0})
+
+			first, ok := a.assign("a", 2)
+			g.Assert(ok).IsTrue()
+			g.Assert(first).Equal(CPUAssignment{Cpus: "1-2", Mems: "0"})
+
+			second, ok := a.assign("b", 2)
+			g.Assert(ok).IsTrue()
+			g.Assert(second).Equal(CPUAssignment{Cpus: "4-5", Mems: "1"})
+
+			third, ok := a.assign("c", 1)
+			g.Assert(ok).IsTrue()
+			g.Assert(third).Equal(CPUAssignment{Cpus: "3", Mems: "0"})
+		})
+
+		g.It("spans nodes when a server does not fit in one", func() {
+			a := newCPUAllocator(nodes, nil)
+
+			cpus, ok := a.assign("a", 6)
+			g.Assert(ok).IsTrue()
+			g.Assert(cpus).Equal(CPUAssignment{Cpus: "0-5", Mems: "0-1"})
+		})
+
+		g.It("reuses cores once they are released", func() {
+			a := newCPUAllocator(nodes[:1], nil)
+
+			_, _ = a.assign("a", 4)
+			a.release("a")
+			cpus, ok := a.assign("b", 2)
+			g.Assert(ok).IsTrue()
+			g.Assert(cpus.Cpus).Equal("0-1")
+		})
+	})
+}
@@ -0,0 +1,7 @@
+package environment
+
+// detectNUMANodes returns no nodes on Windows since Docker does not support
+// pinning containers to specific CPUs, which leaves CPU pinning disabled.
+func detectNUMANodes() []NUMANode {
+	return nil
+}
@@ -0,0 +1,190 @@
+package environment
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/events/webhook"
+)
+
+// The maximum amount of time to wait for the Docker daemon to respond when
+// checking if it is available.
+const daemonPingTimeout = time.Second * 5
+
+var daemon = &daemonStatus{}
+
+// daemonStatus tracks if the Docker daemon is currently reachable. The node is
+// considered degraded while it is not, since no server containers can be managed.
+type daemonStatus struct {
+	mu       sync.RWMutex
+	degraded bool
+	since    time.Time
+}
+
+// DockerDegraded returns true if the Docker daemon is currently unreachable,
+// along with the time it was first detected as being unreachable.
+func DockerDegraded() (bool, time.Time) {
+	daemon.mu.RLock()
+	defer daemon.mu.RUnlock()
+	return daemon.degraded, daemon.since
+}
+
+// DockerAvailable returns true if the Docker daemon responds to a ping. If it
+// does not respond the node is marked as degraded immediately.
+func DockerAvailable(ctx context.Context) bool {
+	if err := pingDocker(ctx); err != nil {
+		daemon.markDegraded(err)
+		return false
+	}
+	return true
+}
+
+// StartDocker configures the Docker environment. If the Docker daemon cannot be
+// reached the node is marked as degraded rather than failing to boot, and the
+// configuration is retried in the background until it succeeds or the context is
+// canceled. The returned channel is closed once Docker has been configured. An
+// error is only returned if the daemon is reachable but could not be configured.
+func StartDocker(ctx context.Context) (<-chan struct{}, error) {
+	ready := make(chan struct{})
+	err := ConfigureDocker(ctx)
+	if err == nil {
+		close(ready)
+		return ready, nil
+	}
+	if pingDocker(ctx) == nil {
+		return nil, err
+	}
+
+	daemon.markDegraded(err)
+	go func() {
+		ticker := time.NewTicker(reconnectInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if pingDocker(ctx) != nil {
+				continue
+			}
+			// The API version could not be negotiated while the daemon was unreachable.
+			if cli, err := Docker(); err == nil {
+				cli.NegotiateAPIVersion(ctx)
+			}
+			if err := ConfigureDocker(ctx); err != nil {
+				log.WithField("error", err).Debug("failed to configure docker environment, retrying")
+				continue
+			}
+			daemon.markRecovered()
+			close(ready)
+			return
+		}
+	}()
+	return ready, nil
+}
+
+// WatchDocker checks that the Docker daemon is reachable every interval until
+// the context is canceled. While the daemon is unreachable the node is marked
+// as degraded, and once the daemon is reachable again the API version is
+// negotiated again, since the daemon may have been upgraded, and the callback
+// is run so that servers can be returned to the state they were in.
+func WatchDocker(ctx context.Context, onRecover func(ctx context.Context)) {
+	if !config.Get().Docker.Reconnect.Enabled {
+		return
+	}
+
+	ticker := time.NewTicker(reconnectInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := pingDocker(ctx)
+		if err != nil {
+			daemon.markDegraded(err)
+			continue
+		}
+		if daemon.markRecovered() {
+			if cli, err := Docker(); err == nil {
+				cli.NegotiateAPIVersion(ctx)
+			}
+			if onRecover != nil {
+				onRecover(ctx)
+			}
+		}
+	}
+}
+
+// reconnectInterval returns the amount of time between each attempt to reach the
+// Docker daemon.
+func reconnectInterval() time.Duration {
+	interval := time.Duration(config.Get().Docker.Reconnect.Interval) * time.Second
+	if interval < time.Second {
+		return time.Second
+	}
+	return interval
+}
+
+func pingDocker(ctx context.Context) error {
+	cli, err := Docker()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, daemonPingTimeout)
+	defer cancel()
+	if _, err := cli.Ping(ctx); err != nil {
+		return errors.Wrap(err, "environment/docker: daemon did not respond")
+	}
+	return nil
+}
+
+// markDegraded marks the node as degraded if it is not already, sending the
+// degraded event to any webhook endpoints subscribed to it.
+func (d *daemonStatus) markDegraded(err error) {
+	d.mu.Lock()
+	if d.degraded {
+		d.mu.Unlock()
+		return
+	}
+	d.degraded = true
+	d.since = time.Now()
+	d.mu.Unlock()
+
+	log.WithField("error", err).Error("lost connection to the Docker daemon, node is now running in a degraded state")
+	webhook.Dispatch("", webhook.NodeDegradedEvent, map[string]interface{}{
+		"error": err.Error(),
+	})
+}
+
+// markRecovered clears the degraded state of the node, returning true if the
+// node was degraded. Anything cached about the daemon is cleared, since Docker
+// may have been upgraded or reconfigured while it was unavailable.
+func (d *daemonStatus) markRecovered() bool {
+	d.mu.Lock()
+	if !d.degraded {
+		d.mu.Unlock()
+		return false
+	}
+	since := d.since
+	d.degraded = false
+	d.since = time.Time{}
+	d.mu.Unlock()
+
+	resetNodePlatform()
+
+	downtime := time.Since(since)
+	log.WithField("downtime", downtime.Round(time.Second).String()).Info("reconnected to the Docker daemon, restoring server states")
+	webhook.Dispatch("", webhook.NodeRecoveredEvent, map[string]interface{}{
+		"downtime_ms": downtime.Milliseconds(),
+	})
+	return true
+}
@@ -0,0 +1,86 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestDaemonStatus(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("daemonStatus", func() {
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token"})
+		})
+
+		g.It("only reports recovery once after being degraded", func() {
+			d := &daemonStatus{}
+			g.Assert(d.markRecovered()).IsFalse()
+
+			d.markDegraded(errors.New("pipe closed"))
+			since := d.since
+			g.Assert(d.degraded).IsTrue()
+
+			d.markDegraded(errors.New("pipe closed"))
+			g.Assert(d.since).Equal(since)
+
+			g.Assert(d.markRecovered()).IsTrue()
+			g.Assert(d.degraded).IsFalse()
+			g.Assert(d.markRecovered()).IsFalse()
+		})
+
+		g.It("clears the cached node platform when recovering", func() {
+			nodeCache.platform = &Platform{OS: "windows", Arch: "amd64", Build: 17763}
+			nodeCache.isolation = IsolationProcess
+
+			d := &daemonStatus{}
+			g.Assert(d.markRecovered()).IsFalse()
+			g.Assert(nodeCache.platform != nil).IsTrue()
+
+			d.markDegraded(errors.New("pipe closed"))
+			g.Assert(d.markRecovered()).IsTrue()
+			g.Assert(nodeCache.platform == nil).IsTrue()
+			g.Assert(nodeCache.isolation).Equal("")
+		})
+	})
+
+	g.Describe("StartDocker", func() {
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token"})
+			config.Update(func(c *config.Configuration) {
+				c.Docker.Socket = "unix:///nonexistent/docker.sock"
+			})
+			_conce = sync.Once{}
+			_client = nil
+			daemon = &daemonStatus{}
+		})
+
+		g.AfterEach(func() {
+			_conce = sync.Once{}
+			_client = nil
+			daemon = &daemonStatus{}
+		})
+
+		g.It("starts degraded when the daemon cannot be reached", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			ready, err := StartDocker(ctx)
+			g.Assert(err).IsNil()
+			degraded, _ := DockerDegraded()
+			g.Assert(degraded).IsTrue()
+
+			select {
+			case <-ready:
+				g.Fail("docker should not be ready")
+			default:
+			}
+		})
+	})
+}
@@ -2,6 +2,7 @@ package environment
 
 import (
 	"context"
+	"os"
 	"sync"
 
 	"emperror.dev/errors"
@@ -23,11 +24,31 @@ var (
 func Docker() (*client.Client, error) {
 	var err error
 	_conce.Do(func() {
-		_client, err = client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		_client, err = client.NewClientWithOpts(clientOpts()...)
 	})
 	return _client, errors.Wrap(err, "environment/docker: could not create client")
 }
 
+// clientOpts returns the options used to create the Docker client. The socket
+// defined in the configuration takes priority, followed by the DOCKER_HOST
+// environment variable, and then the default socket for the configured engine.
+func clientOpts() []client.Opt {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	host := config.Get().Docker.Socket
+	if host == "" && os.Getenv("DOCKER_HOST") == "" {
+		host = defaultDockerHost()
+	}
+	if host != "" {
+		opts = append(opts, client.WithHost(host))
+	}
+	return opts
+}
+
+// IsPodman returns true if the container engine being used is Podman.
+func IsPodman() bool {
+	return config.Get().Docker.Engine == config.EnginePodman
+}
+
 // ConfigureDocker configures the required network for the docker environment.
 func ConfigureDocker(ctx context.Context) error {
 	// Ensure the required docker network exists on the system.
@@ -36,6 +57,14 @@ func ConfigureDocker(ctx context.Context) error {
 		return err
 	}
 
+	c, err := DetectCapabilities(ctx)
+	if err != nil {
+		return err
+	}
+	for _, w := range c.Warnings() {
+		log.WithField("cgroup_version", c.CgroupVersion).Warn(w)
+	}
+
 	nw := config.Get().Docker.Network
 	resource, err := cli.NetworkInspect(ctx, nw.Name, types.NetworkInspectOptions{})
 	if err != nil {
@@ -0,0 +1,198 @@
+package docker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+// The maximum amount of time to wait for Docker to return the resource usage of
+// a single container before giving up until the next collection.
+const collectorTimeout = time.Second * 10
+
+var (
+	_collectorOnce sync.Once
+	_collector     *statsCollector
+)
+
+// statsCollector collects the resource usage of every running server container
+// using a single routine, rather than each server keeping its own stats stream
+// open with Docker. Usage is requested for all of the containers at a fixed
+// interval, with only a limited number of requests being made at once.
+type statsCollector struct {
+	mu      sync.Mutex
+	entries map[string]*collectorEntry
+}
+
+// collectorEntry tracks a single container being collected. The previous sample
+// is kept since one-shot stats from Docker do not include it, and it is needed to
+// calculate the CPU usage of the container.
+type collectorEntry struct {
+	ctx  context.Context
+	env  *Environment
+	done chan struct{}
+	once sync.Once
+	// Set while the usage of the container is being requested so that a slow
+	// response from Docker does not cause multiple requests to pile up.
+	busy bool
+
+	uptime int64
+	prev   *types.StatsJSON
+}
+
+// collector returns the shared stats collector, starting it the first time it
+// is used.
+func collector() *statsCollector {
+	_collectorOnce.Do(func() {
+		_collector = &statsCollector{entries: make(map[string]*collectorEntry)}
+		go _collector.run()
+	})
+	return _collector
+}
+
+// watch adds the environment to the collector and blocks until the context is
+// canceled or the container is no longer running.
+func (c *statsCollector) watch(ctx context.Context, e *Environment, uptime int64) error {
+	ent := &collectorEntry{ctx: ctx, env: e, done: make(chan struct{}), uptime: uptime}
+
+	c.mu.Lock()
+	if existing, ok := c.entries[e.Id]; ok {
+		existing.stop()
+	}
+	c.entries[e.Id] = ent
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		if c.entries[e.Id] == ent {
+			delete(c.entries, e.Id)
+		}
+		c.mu.Unlock()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ent.done:
+		return nil
+	}
+}
+
+// run collects the resource usage of the containers at the configured interval
+// for the lifetime of the process.
+func (c *statsCollector) run() {
+	cfg := config.Get().Docker.StatsCollector
+	interval := time.Duration(cfg.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Second
+	}
+	concurrency := cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		c.mu.Lock()
+		pending := make([]*collectorEntry, 0, len(c.entries))
+		for _, ent := range c.entries {
+			if !ent.busy && ent.env.resourcesWanted() {
+				ent.busy = true
+				pending = append(pending, ent)
+			}
+		}
+		c.mu.Unlock()
+
+		for _, ent := range pending {
+			sem <- struct{}{}
+			go func(ent *collectorEntry) {
+				defer func() {
+					c.mu.Lock()
+					ent.busy = false
+					c.mu.Unlock()
+					<-sem
+				}()
+				ent.collect()
+			}(ent)
+		}
+	}
+}
+
+// collect requests the current resource usage of the container from Docker and
+// publishes it to the environment.
+func (ent *collectorEntry) collect() {
+	e := ent.env
+	if ent.ctx.Err() != nil {
+		return
+	}
+	if e.st.Load() == environment.ProcessOfflineState {
+		e.log().Debug("process in offline state while resource polling is still active; stopping poll")
+		ent.stop()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ent.ctx, collectorTimeout)
+	defer cancel()
+	res, err := e.client.ContainerStatsOneShot(ctx, e.Id)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			ent.stop()
+		} else if !errors.Is(err, context.Canceled) {
+			e.log().WithField("error", err).Warn("failed to collect Docker stats for container")
+		}
+		return
+	}
+	defer res.Body.Close()
+
+	var v types.StatsJSON
+	if err := json.NewDecoder(res.Body).Decode(&v); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			e.log().WithField("error", err).Warn("error while processing Docker stats output for container")
+		}
+		return
+	}
+
+	// Usage requested after the container has stopped is empty, so do not publish
+	// it over the top of the final reading.
+	if e.st.Load() == environment.ProcessOfflineState {
+		ent.stop()
+		return
+	}
+
+	e.Events().Publish(environment.ResourceEvent, e.withSidecarUsage(e.withGpuUsage(ctx, ent.next(v))))
+}
+
+// next returns the usage of the container from the sample, using the previous
+// sample to calculate the CPU usage and uptime of the container.
+func (ent *collectorEntry) next(v types.StatsJSON) environment.Stats {
+	// Without a previous sample there is nothing to compare the CPU usage against,
+	// so it is reported as zero until the next collection.
+	prev := v
+	if ent.prev != nil {
+		prev = *ent.prev
+		ent.uptime += v.Read.Sub(prev.Read).Milliseconds()
+	}
+	cur := v
+	ent.prev = &cur
+	v.PreCPUStats = prev.CPUStats
+	v.PreRead = prev.Read
+	st := statsFromDocker(v, ent.uptime)
+	st.DiskIO.SetRates(calculateDockerDiskIO(prev), v.Read.Sub(prev.Read))
+	return st
+}
+
+func (ent *collectorEntry) stop() {
+	ent.once.Do(func() {
+		close(ent.done)
+	})
+}
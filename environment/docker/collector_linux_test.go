@@ -0,0 +1,34 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/franela/goblin"
+)
+
+func TestCollectorEntry_DiskIO(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("collectorEntry#next", func() {
+		g.It("calculates disk operations per second from the previous sample", func() {
+			now := time.Now()
+			ent := &collectorEntry{}
+			first := types.StatsJSON{}
+			first.Read = now
+			first.BlkioStats.IoServicedRecursive = []types.BlkioStatEntry{{Op: "read", Value: 10}, {Op: "write", Value: 100}}
+			ent.next(first)
+
+			second := types.StatsJSON{}
+			second.Read = now.Add(time.Second * 2)
+			second.BlkioStats.IoServicedRecursive = []types.BlkioStatEntry{{Op: "Read", Value: 30}, {Op: "Write", Value: 100}}
+			second.BlkioStats.IoServiceBytesRecursive = []types.BlkioStatEntry{{Op: "read", Value: 4096}}
+			st := ent.next(second)
+			g.Assert(st.DiskIO.ReadOps).Equal(uint64(30))
+			g.Assert(st.DiskIO.ReadBytes).Equal(uint64(4096))
+			g.Assert(st.DiskIO.ReadIops).Equal(10.0)
+			g.Assert(st.DiskIO.WriteIops).Equal(0.0)
+		})
+	})
+}
@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/franela/goblin"
+)
+
+func TestCollectorEntry_Next(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("collectorEntry#next", func() {
+		sample := func(read time.Time, total uint64, system uint64) types.StatsJSON {
+			var v types.StatsJSON
+			v.Read = read
+			v.NumProcs = 2
+			v.CPUStats.OnlineCPUs = 2
+			v.CPUStats.CPUUsage.TotalUsage = total
+			v.CPUStats.SystemUsage = system
+			return v
+		}
+
+		g.It("reports no CPU usage for the first sample", func() {
+			ent := &collectorEntry{uptime: 1000}
+			st := ent.next(sample(time.Now(), 5000, 10000))
+			g.Assert(st.CpuAbsolute).Equal(0.0)
+			g.Assert(st.Uptime).Equal(int64(1000))
+		})
+
+		g.It("uses the previous sample for CPU usage and uptime", func() {
+			now := time.Now()
+			ent := &collectorEntry{uptime: 1000}
+			ent.next(sample(now, 5000, 10000))
+			st := ent.next(sample(now.Add(time.Second), 10000, 20000))
+			g.Assert(st.CpuAbsolute > 0).IsTrue()
+			g.Assert(st.Uptime).Equal(int64(2000))
+			g.Assert(ent.prev.CPUStats.CPUUsage.TotalUsage).Equal(uint64(10000))
+		})
+	})
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/buger/jsonparser"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
 
@@ -44,6 +45,9 @@ func (nw noopWriter) Write(b []byte) (int, error) {
 // until the container is stopped. The context provided to this function is used
 // for the purposes of attaching to the container, a seecond context is created
 // within the function for managing polling.
+//
+// If the server is already running, such as when Wings is restarted, the sidecars
+// for the server are started or re-attached to as well.
 func (e *Environment) Attach(ctx context.Context) error {
 	if e.IsAttached() {
 		return nil
@@ -67,6 +71,10 @@ func (e *Environment) Attach(ctx context.Context) error {
 		e.SetStream(&st)
 	}
 
+	if e.State() == environment.ProcessRunningState {
+		e.startSidecars(ctx)
+	}
+
 	go func() {
 		// Don't use the context provided to the function, that'll cause the polling to
 		// exit unexpectedly. We want a custom context for this, the one passed to the
@@ -78,6 +86,8 @@ func (e *Environment) Attach(ctx context.Context) error {
 			e.SetState(environment.ProcessOfflineState)
 			e.SetStream(nil)
 		}()
+		defer e.removeSidecarsOnExit()
+		defer environment.ReleaseCPUs(e.Id)
 
 		go func() {
 			if err := e.pollResources(pollCtx); err != nil {
@@ -89,6 +99,8 @@ func (e *Environment) Attach(ctx context.Context) error {
 			}
 		}()
 
+		go e.watchOutOfMemory(pollCtx)
+
 		// Block the completion of this routine until the container is no longer running. This allows
 		// the pollResources function to run until it needs to be stopped. Because the container
 		// can be polled for resource usage, even when stopped, we need to have this logic present
@@ -104,11 +116,43 @@ func (e *Environment) Attach(ctx context.Context) error {
 		if _, err := io.Copy(c, e.stream.Reader); err != nil {
 			e.log().WithField("error", err).Error("could not copy from environment stream to noop writer")
 		}
+
+		// The stream also ends when the Docker daemon is stopped or restarted, in which
+		// case the container was not necessarily stopped by the server process. Keep
+		// track of that so that the server can be restored once the daemon is back.
+		if !environment.DockerAvailable(context.Background()) {
+			e.log().Warn("detached from container after losing connection to the Docker daemon")
+			e.interrupted.Store(true)
+		}
 	}()
 
 	return nil
 }
 
+// watchOutOfMemory listens for out of memory events for the container until the
+// context is canceled. Docker sends these whenever a process in the container is
+// killed for exceeding the memory limit, even if the container keeps running.
+func (e *Environment) watchOutOfMemory(ctx context.Context) {
+	msgs, errs := e.client.Events(ctx, types.EventsOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", "container"),
+			filters.Arg("container", e.Id),
+			filters.Arg("event", "oom"),
+		),
+	})
+	for {
+		select {
+		case <-msgs:
+			e.Events().Publish(environment.OutOfMemoryEvent, "")
+		case err := <-errs:
+			if err != nil && err != io.EOF && !errors.Is(err, context.Canceled) {
+				e.log().WithField("error", err).Warn("error while listening for container out of memory events")
+			}
+			return
+		}
+	}
+}
+
 // InSituUpdate performs an in-place update of the Docker container's resource
 // limits without actually making any changes to the operational state of the
 // container. This allows memory, cpu, and IO limitations to be adjusted on the
@@ -135,7 +179,7 @@ func (e *Environment) InSituUpdate() error {
 	//
 	// @see https://github.com/moby/moby/issues/41946
 	if _, err := e.client.ContainerUpdate(ctx, e.Id, container.UpdateConfig{
-		Resources: e.Configuration.Limits().AsContainerResources(),
+		Resources: e.resources(),
 	}); err != nil {
 		return errors.Wrap(err, "environment/docker: could not update container")
 	}
@@ -159,6 +203,11 @@ func (e *Environment) Create() error {
 	if err := e.ensureImageExists(e.meta.Image); err != nil {
 		return errors.WithStackIf(err)
 	}
+	// Make sure the image can run on the node, otherwise Docker returns an error that
+	// does not explain what is wrong with it once the container is started.
+	if err := environment.CheckImageCompatibility(context.Background(), e.client, strings.TrimPrefix(e.meta.Image, "~")); err != nil {
+		return err
+	}
 
 	a := e.Configuration.Allocations()
 
@@ -170,11 +219,15 @@ func (e *Environment) Create() error {
 			evs[i] = "SERVER_IP=" + config.Get().Docker.Network.Interface
 		}
 	}
+	evs, err := environment.RenderVariables(e.Configuration.TranslateVariables(evs), a)
+	if err != nil {
+		e.log().WithField("error", err).Warn("failed to render templates within environment variables")
+	}
 
 	conf := &container.Config{
 		Hostname:     e.Id,
 		Domainname:   config.Get().Docker.Domainname,
-		User:         getContainerUser(),
+		User:         e.containerUser(),
 		AttachStdin:  true,
 		AttachStdout: true,
 		AttachStderr: true,
@@ -182,16 +235,33 @@ func (e *Environment) Create() error {
 		Tty:          true,
 		ExposedPorts: a.Exposed(),
 		Image:        strings.TrimPrefix(e.meta.Image, "~"),
-		Env:          e.Configuration.EnvironmentVariables(),
+		Env:          evs,
 		Labels: map[string]string{
 			"Service":       "Pterodactyl",
 			"ContainerType": "server_process",
 		},
 	}
 
-	hostConf := getContainerHostConfig(e, a)
+	hostConf, err := getContainerHostConfig(e, a)
+	if err != nil {
+		return err
+	}
+
+	if err := e.applyEntrypointWrapper(context.Background(), conf, hostConf); err != nil {
+		return err
+	}
+
+	// If the Panel has not assigned any threads to the server, pin it to the least
+	// used cores on the system when CPU pinning is enabled.
+	if l := e.Configuration.Limits(); l.Threads == "" {
+		if cpus, ok := environment.AssignCPUs(e.Id, l.CpuLimit); ok {
+			hostConf.Resources.CpusetCpus = cpus.Cpus
+			hostConf.Resources.CpusetMems = cpus.Mems
+		}
+	}
 
 	if _, err := e.client.ContainerCreate(context.Background(), conf, hostConf, nil, nil, e.Id); err != nil {
+		environment.ReleaseCPUs(e.Id)
 		return errors.Wrap(err, "environment/docker: failed to create container")
 	}
 
@@ -204,11 +274,16 @@ func (e *Environment) Destroy() error {
 	// We set it to stopping than offline to prevent crash detection from being triggered.
 	e.SetState(environment.ProcessStoppingState)
 
+	if err := e.removeSidecars(context.Background()); err != nil {
+		e.log().WithField("error", err).Warn("failed to remove sidecar containers for server")
+	}
+
 	err := e.client.ContainerRemove(context.Background(), e.Id, types.ContainerRemoveOptions{
 		RemoveVolumes: true,
 		RemoveLinks:   false,
 		Force:         true,
 	})
+	environment.ReleaseCPUs(e.Id)
 
 	e.SetState(environment.ProcessOfflineState)
 
@@ -223,6 +298,20 @@ func (e *Environment) Destroy() error {
 	return err
 }
 
+// CrashDetails returns the details of the container included in a crash report
+// for the server. The environment variables are removed since they may contain
+// secrets.
+func (e *Environment) CrashDetails(ctx context.Context) (interface{}, error) {
+	c, err := e.ContainerInspect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.Config != nil {
+		c.Config.Env = nil
+	}
+	return c, nil
+}
+
 // SendCommand sends the specified command to the stdin of the running container
 // instance. There is no confirmation that this data is sent successfully, only
 // that it gets pushed into the stdin.
@@ -345,6 +434,16 @@ func (e *Environment) ensureImageExists(image string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*15)
 	defer cancel()
 
+	e.mu.RLock()
+	policy := e.meta.PullPolicy
+	e.mu.RUnlock()
+	if pull, err := environment.ShouldPullImage(ctx, e.client, image, policy); err != nil || !pull {
+		if err == nil {
+			log.WithField("image", image).Debug("image exists locally, skipping pull due to pull policy")
+		}
+		return err
+	}
+
 	// Get a registry auth configuration from the config.
 	var registryAuth *config.RegistryConfiguration
 	for registry, c := range config.Get().Docker.Registries {
@@ -369,6 +468,14 @@ func (e *Environment) ensureImageExists(image string) error {
 		imagePullOptions.RegistryAuth = b64
 	}
 
+	// Pull the manifest for the platform of the node when the image is published
+	// for several platforms.
+	platform, err := environment.SelectPlatform(ctx, e.client, image, imagePullOptions.RegistryAuth)
+	if err != nil {
+		return err
+	}
+	imagePullOptions.Platform = platform
+
 	out, err := e.client.ImagePull(ctx, image, imagePullOptions)
 	if err != nil {
 		images, ierr := e.client.ImageList(ctx, types.ImageListOptions{})
@@ -423,6 +530,36 @@ func (e *Environment) ensureImageExists(image string) error {
 	return nil
 }
 
+// resources returns the resource limits for the server container, including the
+// disk throttling limits for the device storing the server data. If the device
+// cannot be determined the server is left without disk throttling.
+func (e *Environment) resources() container.Resources {
+	l := e.Configuration.Limits()
+	r := l.AsContainerResources()
+
+	for _, m := range e.Configuration.Mounts() {
+		if !m.Default {
+			continue
+		}
+		if err := l.ApplyDiskThrottle(&r, m.Source); err != nil {
+			e.log().WithField("error", err).Warn("failed to apply disk throttling limits to server container")
+		}
+	}
+
+	return r
+}
+
+// containerResources returns the resources for a new container. This includes the
+// devices passed through to the container, which cannot be changed by updating the
+// resources of an existing container.
+func (e *Environment) containerResources() container.Resources {
+	r := e.resources()
+	if e.Configuration.Limits().GpuPassthrough() {
+		applyGpuPassthrough(&r)
+	}
+	return r
+}
+
 func (e *Environment) convertMounts() []mount.Mount {
 	var out []mount.Mount
 
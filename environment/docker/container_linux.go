@@ -1,23 +1,70 @@
 package docker
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
+	"emperror.dev/errors"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/daemon/logger/local"
+	"github.com/goccy/go-json"
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
 )
 
-// getContainerUser gets the user for the container
+// defaultExecShell is the command executed inside the container when an exec
+// request does not specify one.
+var defaultExecShell = []string{"/bin/sh"}
+
+// getContainerUser gets the user for the container. With a rootless daemon the
+// configured container user is used, since the host user is mapped into the
+// container's user namespace as that user.
 func getContainerUser() string {
-	return strconv.Itoa(config.Get().System.User.Uid) + ":" + strconv.Itoa(config.Get().System.User.Gid)
+	u := config.Get().System.User
+	if u.Rootless.Enabled {
+		return strconv.Itoa(u.Rootless.ContainerUID) + ":" + strconv.Itoa(u.Rootless.ContainerGID)
+	}
+	return strconv.Itoa(u.Uid) + ":" + strconv.Itoa(u.Gid)
 }
 
-func getContainerHostConfig(e *Environment, a environment.Allocations) *container.HostConfig {
+// getLogConfig returns the logging configuration for the container. Podman does
+// not support the "local" log driver, so the "json-file" driver is used instead
+// which is mapped to its own file based driver.
+func getLogConfig() container.LogConfig {
+	if environment.IsPodman() {
+		return container.LogConfig{
+			Type: "json-file",
+			Config: map[string]string{
+				"max-size": "5m",
+			},
+		}
+	}
+	return container.LogConfig{
+		Type: local.Name,
+		Config: map[string]string{
+			"max-size": "5m",
+			"max-file": "1",
+			"compress": "false",
+			"mode":     "non-blocking",
+		},
+	}
+}
+
+func getContainerHostConfig(e *Environment, a environment.Allocations) (*container.HostConfig, error) {
 	tmpfsSize := strconv.Itoa(int(config.Get().Docker.TmpfsSize))
 
-	return &container.HostConfig{
+	e.mu.RLock()
+	profile := e.meta.Security
+	e.mu.RUnlock()
+	securityOpts, err := getSecurityOpts(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &container.HostConfig{
 		PortBindings: a.DockerBindings(),
 
 		// Configure the mounts for this container. First mount the server data directory
@@ -32,7 +79,7 @@ func getContainerHostConfig(e *Environment, a environment.Allocations) *containe
 
 		// Define resource limits for the container based on the data passed through
 		// from the Panel.
-		Resources: e.Configuration.Limits().AsContainerResources(),
+		Resources: e.containerResources(),
 
 		DNS: config.Get().Docker.Network.Dns,
 
@@ -40,40 +87,82 @@ func getContainerHostConfig(e *Environment, a environment.Allocations) *containe
 		// the server output. Ensure that we don't use too much space on the host machine
 		// since we only need it for the last few hundred lines of output and don't care
 		// about anything else in it.
-		LogConfig: container.LogConfig{
-			Type: local.Name,
-			Config: map[string]string{
-				"max-size": "5m",
-				"max-file": "1",
-				"compress": "false",
-				"mode":     "non-blocking",
-			},
-		},
+		LogConfig: getLogConfig(),
 
-		SecurityOpt:    []string{"no-new-privileges"},
-		ReadonlyRootfs: true,
+		SecurityOpt: securityOpts,
 		CapDrop: []string{
 			"setpcap", "mknod", "audit_write", "net_raw", "dac_override",
 			"fowner", "fsetid", "net_bind_service", "sys_chroot", "setfcap",
 		},
 		NetworkMode: container.NetworkMode(config.Get().Docker.Network.Mode),
 	}
+	// The root filesystem is read-only by default, with the server files and /tmp
+	// being the only writable locations within the container.
+	e.applyRootFilesystem(h, true)
+	return h, nil
 }
 
-func (e *Environment) resources() container.Resources {
-	l := e.Configuration.Limits()
-	pids := l.ProcessLimit()
-
-	return container.Resources{
-		Memory:            l.BoundedMemoryLimit(),
-		MemoryReservation: l.MemoryLimit * 1_000_000,
-		MemorySwap:        l.ConvertedSwap(),
-		CPUQuota:          l.ConvertedCpuLimit(),
-		CPUPeriod:         100_000,
-		CPUShares:         1024,
-		BlkioWeight:       l.IoWeight,
-		OomKillDisable:    &l.OOMDisabled,
-		CpusetCpus:        l.Threads,
-		PidsLimit:         &pids,
+// getSecurityOpts returns the security options for the container, applying the
+// seccomp and AppArmor profiles requested by the egg or configured for the node.
+// Eggs may only use seccomp profiles from the seccomp directory for the node and
+// AppArmor profiles that the node explicitly allows.
+func getSecurityOpts(p environment.SecurityProfile) ([]string, error) {
+	c := config.Get().Docker.SecurityProfiles
+	opts := []string{"no-new-privileges"}
+
+	seccomp := c.Seccomp
+	if p.Seccomp != "" {
+		if p.Seccomp != filepath.Base(p.Seccomp) || strings.HasPrefix(p.Seccomp, ".") {
+			return nil, errors.Errorf("environment/docker: invalid seccomp profile name \"%s\"", p.Seccomp)
+		}
+		seccomp = filepath.Join(c.SeccompDirectory, p.Seccomp+".json")
+	}
+	if seccomp == "unconfined" {
+		opts = append(opts, "seccomp=unconfined")
+	} else if seccomp != "" {
+		// The Docker API expects the contents of the profile rather than a path to it.
+		b, err := os.ReadFile(seccomp)
+		if err != nil {
+			return nil, errors.Wrap(err, "environment/docker: failed to read seccomp profile")
+		}
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, b); err != nil {
+			return nil, errors.Wrapf(err, "environment/docker: invalid seccomp profile \"%s\"", seccomp)
+		}
+		opts = append(opts, "seccomp="+buf.String())
+	}
+
+	apparmor := c.AppArmor
+	if p.AppArmor != "" {
+		allowed := false
+		for _, name := range c.AllowedAppArmorProfiles {
+			if name == p.AppArmor {
+				allowed = true
+				break
+			}
+		}
+		if !allowed || p.AppArmor == "unconfined" {
+			return nil, errors.Errorf("environment/docker: apparmor profile \"%s\" is not allowed on this node", p.AppArmor)
+		}
+		apparmor = p.AppArmor
+	}
+	if apparmor != "" {
+		opts = append(opts, "apparmor="+apparmor)
+	}
+
+	return opts, nil
+}
+
+// applyGpuPassthrough requests the GPUs configured for the node from the NVIDIA
+// container runtime.
+func applyGpuPassthrough(r *container.Resources) {
+	req := container.DeviceRequest{
+		Driver:       "nvidia",
+		Capabilities: [][]string{{"gpu"}},
+		DeviceIDs:    config.Get().Docker.Gpu.Devices,
+	}
+	if len(req.DeviceIDs) == 0 {
+		req.Count = -1
 	}
+	r.DeviceRequests = append(r.DeviceRequests, req)
 }
@@ -10,11 +10,28 @@ import (
 	"github.com/pterodactyl/wings/environment"
 )
 
+// defaultExecShell is the command executed inside the container when an exec
+// request does not specify one.
+var defaultExecShell = []string{"cmd.exe"}
+
 // getContainerUser gets the user for the container
 func getContainerUser() string {
 	return config.Get().System.Username
 }
 
+// getLogConfig returns the logging configuration for the container.
+func getLogConfig() container.LogConfig {
+	return container.LogConfig{
+		Type: local.Name,
+		Config: map[string]string{
+			"max-size": "5m",
+			"max-file": "1",
+			"compress": "false",
+			"mode":     "non-blocking",
+		},
+	}
+}
+
 // getDockerBindingsForWindows As Windows does not support the IP being set on NAT bindings, we will remap the mappings
 // so the IP is not included
 func getDockerBindingsForWindows(a environment.Allocations) nat.PortMap {
@@ -32,10 +49,10 @@ func getDockerBindingsForWindows(a environment.Allocations) nat.PortMap {
 	return portBindings
 }
 
-func getContainerHostConfig(e *Environment, a environment.Allocations) *container.HostConfig {
+func getContainerHostConfig(e *Environment, a environment.Allocations) (*container.HostConfig, error) {
 	tmpfsSize := strconv.Itoa(int(config.Get().Docker.TmpfsSize))
 
-	return &container.HostConfig{
+	h := &container.HostConfig{
 		PortBindings: getDockerBindingsForWindows(a),
 
 		// Configure the mounts for this container. First mount the server data directory
@@ -50,44 +67,43 @@ func getContainerHostConfig(e *Environment, a environment.Allocations) *containe
 
 		// Define resource limits for the container based on the data passed through
 		// from the Panel.
-		Resources: e.Configuration.Limits().AsContainerResources(),
+		Resources: e.containerResources(),
 
 		DNS: config.Get().Docker.Network.Dns,
 
+		// Use the isolation mode configured for the node, or the default of Docker if
+		// one has not been configured.
+		Isolation: container.Isolation(config.Get().Docker.Isolation),
+
 		// Configure logging for the container to make it easier on the Daemon to grab
 		// the server output. Ensure that we don't use too much space on the host machine
 		// since we only need it for the last few hundred lines of output and don't care
 		// about anything else in it.
-		LogConfig: container.LogConfig{
-			Type: local.Name,
-			Config: map[string]string{
-				"max-size": "5m",
-				"max-file": "1",
-				"compress": "false",
-				"mode":     "non-blocking",
-			},
-		},
+		LogConfig: getLogConfig(),
 
 		// This security opt `no-new-privileges` is not supported on Windows
 		// I cannot find something simalar for Windows, also Windows doesn't have Sudo,
 		// so maybe this can be safely ignored
 		// SecurityOpt:    []string{"no-new-privileges"},
-		ReadonlyRootfs: false,
 		CapDrop: []string{
 			"setpcap", "mknod", "audit_write", "net_raw", "dac_override",
 			"fowner", "fsetid", "net_bind_service", "sys_chroot", "setfcap",
 		},
 		NetworkMode: container.NetworkMode(config.Get().Docker.Network.Mode),
 	}
+	// The root filesystem is writable by default on Windows since Windows containers
+	// do not support making it read-only, but it can be enabled for a server that
+	// runs a Linux container through Docker Desktop.
+	e.applyRootFilesystem(h, false)
+	return h, nil
 }
 
-func (e *Environment) resources() container.Resources {
-	l := e.Configuration.Limits()
+// The device interface class for GPUs that support DirectX, passing it through to a
+// container shares every GPU on the host with it.
+// @see https://learn.microsoft.com/en-us/virtualization/windowscontainers/deploy-containers/gpu-acceleration
+const gpuDeviceClass = "class/5B45201D-F2F2-4F3B-85BB-30FF1F953599"
 
-	return container.Resources{
-		Memory:     l.BoundedMemoryLimit(),
-		CPUQuota:   l.ConvertedCpuLimit(),
-		CPUShares:  1024,
-		CpusetCpus: l.Threads,
-	}
+// applyGpuPassthrough shares the GPUs on the host with the container.
+func applyGpuPassthrough(r *container.Resources) {
+	r.Devices = append(r.Devices, container.DeviceMapping{PathOnHost: gpuDeviceClass})
 }
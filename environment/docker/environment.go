@@ -17,8 +17,18 @@ import (
 )
 
 type Metadata struct {
-	Image string
-	Stop  remote.ProcessStopConfiguration
+	Image      string
+	PullPolicy string
+	Stop       remote.ProcessStopConfiguration
+	StopChain  []environment.StopStep
+	Sidecars   []environment.Sidecar
+	Security   environment.SecurityProfile
+	User       string
+	Wrapper    string
+
+	// Overrides the default for the platform when set.
+	ReadOnlyRoot  *bool
+	WritablePaths []string
 }
 
 // Ensure that the Docker environment is always implementing all the methods
@@ -49,11 +59,25 @@ type Environment struct {
 
 	emitter *events.Bus
 
+	// Tracks the latest resource usage of each running sidecar container, and
+	// cancels the routines following them once the server stops.
+	sidecarMu     sync.Mutex
+	sidecarCancel context.CancelFunc
+	sidecarStats  map[string]environment.Stats
+
 	logCallbackMx sync.Mutex
 	logCallback   func([]byte)
 
 	// Tracks the environment state.
 	st *system.AtomicString
+
+	// Tracks if anything is currently watching the resource usage of the server,
+	// only used when resource usage is collected on demand.
+	resourceDemand *system.AtomicBool
+
+	// Set when the stream attached to the container ended because the Docker daemon
+	// stopped responding, rather than because the container stopped.
+	interrupted *system.AtomicBool
 }
 
 // New creates a new base Docker environment. The ID passed through will be the
@@ -67,12 +91,14 @@ func New(id string, m *Metadata, c *environment.Configuration) (*Environment, er
 	}
 
 	e := &Environment{
-		Id:            id,
-		Configuration: c,
-		meta:          m,
-		client:        cli,
-		st:            system.NewAtomicString(environment.ProcessOfflineState),
-		emitter:       events.NewBus(),
+		Id:             id,
+		Configuration:  c,
+		meta:           m,
+		client:         cli,
+		st:             system.NewAtomicString(environment.ProcessOfflineState),
+		emitter:        events.NewBus(),
+		resourceDemand: system.NewAtomicBool(false),
+		interrupted:    system.NewAtomicBool(false),
 	}
 
 	return e, nil
@@ -103,6 +129,13 @@ func (e *Environment) IsAttached() bool {
 	return e.stream != nil
 }
 
+// Interrupted returns true if the environment was detached from the container
+// because the Docker daemon stopped responding while the server was running.
+// The flag is cleared once it has been read.
+func (e *Environment) Interrupted() bool {
+	return e.interrupted.SwapIf(false)
+}
+
 // Events returns an event bus for the environment.
 func (e *Environment) Events() *events.Bus {
 	return e.emitter
@@ -188,6 +221,44 @@ func (e *Environment) SetImage(i string) {
 	e.meta.Image = i
 }
 
+// SetPullPolicy sets the policy used to determine if the image for the environment
+// should be pulled before the container is created.
+func (e *Environment) SetPullPolicy(p string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.meta.PullPolicy = p
+}
+
+// SetUser sets the user that the process runs as within the container, in place
+// of the system user. This is applied when the container is next created.
+func (e *Environment) SetUser(u string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.meta.User = u
+}
+
+// containerUser returns the user that the process runs as within the container.
+func (e *Environment) containerUser() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.meta.User != "" {
+		return e.meta.User
+	}
+	return getContainerUser()
+}
+
+// SetSecurityProfile sets the seccomp and AppArmor profiles requested for the
+// environment, these are applied when the container is next created.
+func (e *Environment) SetSecurityProfile(p environment.SecurityProfile) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.meta.Security = p
+}
+
 func (e *Environment) State() string {
 	return e.st.Load()
 }
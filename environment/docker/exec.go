@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/docker/docker/api/types"
+)
+
+// ErrNotRunning is returned when attempting to execute a command inside of a
+// container that is not currently running.
+var ErrNotRunning = errors.Sentinel("container is not running")
+
+// ErrExecUserNotAllowed is returned when attempting to execute a command as a
+// user other than the one the server container runs as.
+var ErrExecUserNotAllowed = errors.Sentinel("commands can only be executed as the container user")
+
+// ExecOptions defines a command to be executed inside the running container for
+// a server.
+type ExecOptions struct {
+	// The command and arguments to execute. If empty the default shell for the
+	// container platform is used.
+	Cmd []string
+
+	// The user to run the command as, which must be the user the container is
+	// running as. If empty the container user is used.
+	User string
+
+	// Additional environment variables in the format of "KEY=value".
+	Env []string
+
+	// Whether a TTY should be allocated for the command.
+	Tty bool
+}
+
+// Exec is a running command inside the server container. Output from the command
+// is read from, and input written to, the hijacked connection.
+type Exec struct {
+	types.HijackedResponse
+
+	id string
+	e  *Environment
+}
+
+// execUser returns the user to execute a command as, which is always the user
+// the server process runs as within the container. An error is returned if a
+// different user was requested.
+func (e *Environment) execUser(requested string) (string, error) {
+	user := e.containerUser()
+	if requested != "" && requested != user {
+		return "", errors.Wrap(ErrExecUserNotAllowed, "environment/docker: cannot exec in container")
+	}
+	return user, nil
+}
+
+// Exec creates a new exec instance inside the running container and attaches to
+// it. The caller is responsible for closing the returned instance once finished.
+func (e *Environment) Exec(ctx context.Context, opts ExecOptions) (*Exec, error) {
+	if ok, err := e.IsRunning(ctx); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.Wrap(ErrNotRunning, "environment/docker: cannot exec in container")
+	}
+
+	user, err := e.execUser(opts.User)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := opts.Cmd
+	if len(cmd) == 0 {
+		cmd = defaultExecShell
+	}
+	res, err := e.client.ContainerExecCreate(ctx, e.Id, types.ExecConfig{
+		User:         user,
+		Tty:          opts.Tty,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          opts.Env,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "environment/docker: failed to create exec instance")
+	}
+
+	stream, err := e.client.ContainerExecAttach(ctx, res.ID, types.ExecStartCheck{Tty: opts.Tty})
+	if err != nil {
+		return nil, errors.Wrap(err, "environment/docker: failed to attach to exec instance")
+	}
+
+	e.log().WithField("exec_id", res.ID).WithField("cmd", cmd).Info("executing command inside container")
+	return &Exec{HijackedResponse: stream, id: res.ID, e: e}, nil
+}
+
+// ID returns the Docker identifier for the exec instance.
+func (ex *Exec) ID() string {
+	return ex.id
+}
+
+// Resize changes the dimensions of the TTY allocated to the exec instance.
+func (ex *Exec) Resize(ctx context.Context, height uint, width uint) error {
+	err := ex.e.client.ContainerExecResize(ctx, ex.id, types.ResizeOptions{Height: height, Width: width})
+	return errors.Wrap(err, "environment/docker: failed to resize exec instance")
+}
+
+// ExitCode returns the exit code of the command, and whether it is still running.
+func (ex *Exec) ExitCode(ctx context.Context) (int, bool, error) {
+	res, err := ex.e.client.ContainerExecInspect(ctx, ex.id)
+	if err != nil {
+		return 0, false, errors.Wrap(err, "environment/docker: failed to inspect exec instance")
+	}
+	return res.ExitCode, res.Running, nil
+}
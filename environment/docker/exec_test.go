@@ -0,0 +1,33 @@
+package docker
+
+import (
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/franela/goblin"
+)
+
+func TestExecUser(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("execUser", func() {
+		g.It("uses the user assigned to the server", func() {
+			e := &Environment{meta: &Metadata{User: "1005:1005"}}
+
+			u, err := e.execUser("")
+			g.Assert(err).IsNil()
+			g.Assert(u).Equal("1005:1005")
+
+			u, err = e.execUser("1005:1005")
+			g.Assert(err).IsNil()
+			g.Assert(u).Equal("1005:1005")
+		})
+
+		g.It("refuses any other user", func() {
+			e := &Environment{meta: &Metadata{User: "1005:1005"}}
+
+			_, err := e.execUser("0:0")
+			g.Assert(errors.Is(err, ErrExecUserNotAllowed)).IsTrue()
+		})
+	})
+}
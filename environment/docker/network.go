@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"context"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/docker/docker/client"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// SetNetworkIsolated disconnects the running container from the network it was
+// created on, which removes all of its port bindings so that nothing is able to
+// connect to the server. Passing false reconnects the container to the network.
+// Containers are always created on the network, so an isolated server is also
+// reconnected the next time it is started.
+func (e *Environment) SetNetworkIsolated(ctx context.Context, isolated bool) error {
+	network := config.Get().Docker.Network.Mode
+	if network == "host" || network == "none" || strings.HasPrefix(network, "container:") {
+		return errors.Errorf("environment/docker: cannot isolate containers using the \"%s\" network mode", network)
+	}
+
+	c, err := e.ContainerInspect(ctx)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return nil
+		}
+		return errors.WrapIf(err, "environment/docker: failed to inspect container")
+	}
+	var connected bool
+	if c.NetworkSettings != nil {
+		_, connected = c.NetworkSettings.Networks[network]
+	}
+
+	if isolated && connected {
+		if err := e.client.NetworkDisconnect(ctx, network, e.Id, true); err != nil {
+			return errors.WrapIf(err, "environment/docker: failed to disconnect container from network")
+		}
+		e.log().WithField("network", network).Info("disconnected container from network")
+	} else if !isolated && !connected && c.State != nil && c.State.Running {
+		if err := e.client.NetworkConnect(ctx, network, e.Id, nil); err != nil {
+			return errors.WrapIf(err, "environment/docker: failed to connect container to network")
+		}
+		e.log().WithField("network", network).Info("reconnected container to network")
+	}
+	return nil
+}
@@ -32,6 +32,9 @@ func (e *Environment) OnBeforeStart(ctx context.Context) error {
 			return errors.WrapIf(err, "environment/docker: failed to remove container during pre-boot")
 		}
 	}
+	if err := e.removeSidecars(ctx); err != nil {
+		return errors.WrapIf(err, "environment/docker: failed to remove sidecar containers during pre-boot")
+	}
 
 	// The Create() function will check if the container exists in the first place, and if
 	// so just silently return without an error. Otherwise, it will try to create the necessary
@@ -79,6 +82,11 @@ func (e *Environment) Start(ctx context.Context) error {
 		// If the server is running update our internal state and continue on with the attach.
 		if c.State.Running {
 			e.SetState(environment.ProcessRunningState)
+			// Track the cores the container was pinned to before Wings was restarted so
+			// that they are accounted for when assigning cores to other servers.
+			if c.HostConfig != nil && e.Configuration.Limits().Threads == "" {
+				environment.RegisterCPUs(e.Id, c.HostConfig.CpusetCpus)
+			}
 
 			return e.Attach(ctx)
 		}
@@ -126,6 +134,10 @@ func (e *Environment) Start(ctx context.Context) error {
 		return errors.WrapIf(err, "environment/docker: failed to start container")
 	}
 
+	// Sidecars join the network namespace of the server container, so they can only
+	// be started once it is running.
+	e.startSidecars(actx)
+
 	// No errors, good to continue through.
 	sawError = false
 	return nil
@@ -157,8 +169,14 @@ func (e *Environment) Stop(ctx context.Context) error {
 		switch strings.ToUpper(s.Value) {
 		case "SIGABRT":
 			signal = syscall.SIGABRT
-		case "SIGINT":
-			signal = syscall.SIGINT
+		case "SIGINT", "CTRL_C":
+			// Interrupt the process rather than terminating the container so that the
+			// server has a chance to save before it exits, which is the only way to
+			// gracefully stop many Windows servers.
+			if e.st.Load() != environment.ProcessOfflineState {
+				e.SetState(environment.ProcessStoppingState)
+			}
+			return e.interrupt(ctx)
 		case "SIGTERM":
 			signal = syscall.SIGTERM
 		}
@@ -223,6 +241,24 @@ func (e *Environment) WaitForStop(ctx context.Context, duration time.Duration, t
 		return e.Terminate(ctx, os.Kill)
 	}
 
+	// If the egg defines a chain of steps to stop the server use that instead of the
+	// single stop configuration, only terminating the process if every step fails.
+	e.mu.RLock()
+	chain := e.meta.StopChain
+	e.mu.RUnlock()
+	if len(chain) > 0 {
+		if err := e.runStopChain(tctx, chain); err != nil {
+			if terminate {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					e.log().WithField("error", err).Warn("stop chain did not stop the container; terminating process")
+				}
+				return doTermination("chain")
+			}
+			return err
+		}
+		return nil
+	}
+
 	// We pass through the timed context for this stop action so that if one of the
 	// internal docker calls fails to ever finish before we've exhausted the time limit
 	// the resources get cleaned up, and the exection is stopped.
@@ -264,6 +300,108 @@ func (e *Environment) WaitForStop(ctx context.Context, duration time.Duration, t
 	return nil
 }
 
+// SetStopChain sets the steps used to stop the server, replacing the stop
+// configuration from the egg. Changes are applied the next time the server is
+// stopped.
+func (e *Environment) SetStopChain(chain []environment.StopStep) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.meta.StopChain = chain
+}
+
+// runStopChain executes each step in the chain, waiting for up to the timeout of
+// the step for the container to stop before moving on to the next one. An error
+// is returned if the container is still running once every step has been tried.
+func (e *Environment) runStopChain(ctx context.Context, chain []environment.StopStep) error {
+	if e.st.Load() != environment.ProcessOfflineState {
+		e.SetState(environment.ProcessStoppingState)
+	}
+
+	for i, step := range chain {
+		l := e.log().WithField("step", i+1).WithField("type", step.Type)
+		if err := step.Validate(); err != nil {
+			l.WithField("error", err).Warn("skipping invalid stop step")
+			continue
+		}
+
+		l.Debug("executing stop step for container")
+		switch step.Type {
+		case environment.StopStepCommand:
+			// Commands can only be sent while attached to the container.
+			if !e.IsAttached() {
+				l.Debug("not attached to container, skipping stop step")
+				continue
+			}
+			if err := e.SendCommand(step.Value); err != nil {
+				l.WithField("error", err).Warn("failed to send stop command to container")
+				continue
+			}
+		case environment.StopStepRcon:
+			if step.RconAddress == "" {
+				l.Warn("no rcon address for stop step, skipping")
+				continue
+			}
+			rctx, cancel := context.WithTimeout(ctx, time.Second*10)
+			_, err := environment.SendRcon(rctx, step.RconAddress, step.RconPassword, step.Value)
+			cancel()
+			if err != nil {
+				l.WithField("error", err).Warn("failed to send stop command over rcon")
+				continue
+			}
+		case environment.StopStepSignal:
+			send := func() error { return e.client.ContainerKill(ctx, e.Id, step.Signal()) }
+			if step.Signal() == "SIGINT" {
+				send = func() error { return e.interrupt(ctx) }
+			}
+			if err := send(); err != nil {
+				if client.IsErrNotFound(err) {
+					e.SetState(environment.ProcessOfflineState)
+					return nil
+				}
+				l.WithField("error", err).Warn("failed to send stop signal to container")
+				continue
+			}
+		case environment.StopStepKill:
+			return e.Terminate(ctx, os.Kill)
+		}
+
+		stopped, err := e.waitForNotRunning(ctx, step.Duration())
+		if err != nil {
+			return err
+		}
+		if stopped {
+			return nil
+		}
+		l.WithField("timeout", step.Duration()).Info("container did not stop after stop step, escalating")
+	}
+
+	return errors.New("environment/docker: container is still running after every stop step")
+}
+
+// waitForNotRunning waits for up to the duration for the container to stop,
+// returning false if it is still running.
+func (e *Environment) waitForNotRunning(ctx context.Context, d time.Duration) (bool, error) {
+	wctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	ok, errChan := e.client.ContainerWait(wctx, e.Id, container.WaitConditionNotRunning)
+	select {
+	case <-ok:
+		return true, nil
+	case err := <-errChan:
+		if err == nil || client.IsErrNotFound(err) {
+			return true, nil
+		}
+		// Only the timeout for this step has been reached, move on to the next step
+		// unless the overall context has been canceled.
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return false, nil
+		}
+		return false, errors.WrapIf(err, "environment/docker: error waiting on container to enter \"not-running\" state")
+	}
+}
+
 // Terminate forcefully terminates the container using the signal provided.
 func (e *Environment) Terminate(ctx context.Context, signal os.Signal) error {
 	c, err := e.ContainerInspect(ctx)
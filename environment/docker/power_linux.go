@@ -0,0 +1,16 @@
+package docker
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/docker/docker/client"
+)
+
+// interrupt sends SIGINT to the process running in the container.
+func (e *Environment) interrupt(ctx context.Context) error {
+	if err := e.client.ContainerKill(ctx, e.Id, "SIGINT"); err != nil && !client.IsErrNotFound(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
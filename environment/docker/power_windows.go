@@ -0,0 +1,37 @@
+package docker
+
+import (
+	"context"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/docker/docker/client"
+)
+
+// The character written to the console of the container to interrupt it.
+const ctrlC = 0x03
+
+// interrupt sends CTRL+C to the process running in the container. Windows does
+// not support signals, and Docker shuts down the entire container for any signal
+// other than SIGKILL, so the character is written to the console of the container
+// instead. The console host delivers it to every process attached to the console
+// as a CTRL_C_EVENT, exactly as if it had been pressed in a terminal.
+//
+// If we are not attached to the console the container is stopped instead, which
+// sends a CTRL_SHUTDOWN_EVENT to the processes before they are terminated.
+func (e *Environment) interrupt(ctx context.Context) error {
+	if !e.IsAttached() {
+		e.log().Debug("not attached to container, stopping container instead of sending CTRL+C")
+		t := time.Duration(-1)
+		if err := e.client.ContainerStop(ctx, e.Id, &t); err != nil && !client.IsErrNotFound(err) {
+			return errors.Wrap(err, "environment/docker: cannot stop container")
+		}
+		return nil
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	_, err := e.stream.Conn.Write([]byte{ctrlC})
+
+	return errors.Wrap(err, "environment/docker: could not write to container stream")
+}
@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// Process is a single process running inside the server container.
+type Process struct {
+	Pid  int    `json:"pid"`
+	Name string `json:"name"`
+	// The CPU usage of the process as a percentage of a single core. This is only
+	// reported on Linux, Windows only reports the total CPU time used.
+	Cpu float64 `json:"cpu"`
+	// The total CPU time used by the process, in milliseconds.
+	CpuTime int64 `json:"cpu_time"`
+	// The memory used by the process in bytes. This is the resident set size on
+	// Linux and the private working set on Windows.
+	Memory uint64 `json:"memory_bytes"`
+}
+
+// Processes returns the processes running inside the server container.
+func (e *Environment) Processes(ctx context.Context) ([]Process, error) {
+	if ok, err := e.IsRunning(ctx); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.Wrap(ErrNotRunning, "environment/docker: cannot list processes in container")
+	}
+
+	top, err := e.client.ContainerTop(ctx, e.Id, topArguments)
+	if err != nil {
+		return nil, errors.Wrap(err, "environment/docker: failed to list processes in container")
+	}
+	cols := make(map[string]int, len(top.Titles))
+	for i, t := range top.Titles {
+		cols[strings.ToUpper(t)] = i
+	}
+	out := make([]Process, 0, len(top.Processes))
+	for _, row := range top.Processes {
+		if p, ok := parseTopProcess(cols, row); ok {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// parseCPUTime parses the CPU time of a process in the format "[DD-]HH:MM:SS"
+// with optional fractional seconds, returning it in milliseconds.
+func parseCPUTime(v string) (int64, error) {
+	var days int64
+	if i := strings.Index(v, "-"); i >= 0 {
+		d, err := strconv.ParseInt(v[:i], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		days, v = d, v[i+1:]
+	}
+	var total float64
+	for _, part := range strings.Split(v, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, errors.Errorf("environment/docker: invalid cpu time \"%s\"", v)
+		}
+		total = total*60 + n
+	}
+	return days*86400000 + int64(total*1000), nil
+}
@@ -0,0 +1,30 @@
+package docker
+
+import (
+	"strconv"
+)
+
+// The arguments passed to ps when listing the processes in a container.
+var topArguments = []string{"-eo", "pid,pcpu,time,rss,comm"}
+
+// parseTopProcess parses a single row of the ps output for a container.
+func parseTopProcess(cols map[string]int, row []string) (Process, bool) {
+	get := func(name string) string {
+		if i, ok := cols[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+	pid, err := strconv.Atoi(get("PID"))
+	if err != nil {
+		return Process{}, false
+	}
+	p := Process{Pid: pid, Name: get("COMMAND")}
+	p.Cpu, _ = strconv.ParseFloat(get("%CPU"), 64)
+	p.CpuTime, _ = parseCPUTime(get("TIME"))
+	// The resident set size is reported in kilobytes.
+	if rss, err := strconv.ParseUint(get("RSS"), 10, 64); err == nil {
+		p.Memory = rss * 1024
+	}
+	return p, true
+}
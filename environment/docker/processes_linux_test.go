@@ -0,0 +1,46 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestParseTopProcess(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("parseCPUTime", func() {
+		g.It("parses the time with and without days", func() {
+			v, err := parseCPUTime("00:01:05")
+			g.Assert(err).IsNil()
+			g.Assert(v).Equal(int64(65000))
+
+			v, err = parseCPUTime("2-01:00:00")
+			g.Assert(err).IsNil()
+			g.Assert(v).Equal(int64(2*86400000 + 3600000))
+
+			v, err = parseCPUTime("00:00:01.250")
+			g.Assert(err).IsNil()
+			g.Assert(v).Equal(int64(1250))
+		})
+
+		g.It("returns an error for an invalid time", func() {
+			_, err := parseCPUTime("soon")
+			g.Assert(err).IsNotNil()
+		})
+	})
+
+	g.Describe("parseTopProcess", func() {
+		g.It("parses a row of ps output", func() {
+			cols := map[string]int{"PID": 0, "%CPU": 1, "TIME": 2, "RSS": 3, "COMMAND": 4}
+			p, ok := parseTopProcess(cols, []string{"1234", "12.5", "00:00:10", "2048", "java"})
+			g.Assert(ok).IsTrue()
+			g.Assert(p).Equal(Process{Pid: 1234, Name: "java", Cpu: 12.5, CpuTime: 10000, Memory: 2048 * 1024})
+		})
+
+		g.It("skips rows without a PID", func() {
+			_, ok := parseTopProcess(map[string]int{"PID": 0}, []string{"-"})
+			g.Assert(ok).IsFalse()
+		})
+	})
+}
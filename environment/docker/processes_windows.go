@@ -0,0 +1,31 @@
+package docker
+
+import (
+	"strconv"
+
+	"github.com/docker/go-units"
+)
+
+// Arguments cannot be passed when listing the processes in a Windows container.
+var topArguments []string
+
+// parseTopProcess parses a single row of the processes reported by the Host
+// Compute Service for a container.
+func parseTopProcess(cols map[string]int, row []string) (Process, bool) {
+	get := func(name string) string {
+		if i, ok := cols[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+	pid, err := strconv.Atoi(get("PID"))
+	if err != nil {
+		return Process{}, false
+	}
+	p := Process{Pid: pid, Name: get("NAME")}
+	p.CpuTime, _ = parseCPUTime(get("CPU"))
+	if mem, err := units.FromHumanSize(get("PRIVATE WORKING SET")); err == nil {
+		p.Memory = uint64(mem)
+	}
+	return p, true
+}
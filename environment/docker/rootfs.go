@@ -0,0 +1,75 @@
+package docker
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+// SetRootFilesystem sets if the root filesystem of the container is read-only,
+// and the paths within it that remain writable. A nil value uses the default
+// for the platform. These are applied when the container is next created.
+func (e *Environment) SetRootFilesystem(readOnly *bool, writable []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.meta.ReadOnlyRoot = readOnly
+	e.meta.WritablePaths = writable
+}
+
+// applyRootFilesystem makes the root filesystem of the container read-only if
+// it is enabled for the server, or by default for the platform, mounting a tmpfs
+// at each of the writable paths for the server. If the daemon runs Windows
+// containers, which cannot have a read-only root, the root is left writable.
+func (e *Environment) applyRootFilesystem(h *container.HostConfig, def bool) {
+	e.mu.RLock()
+	readOnly := def
+	if e.meta.ReadOnlyRoot != nil {
+		readOnly = *e.meta.ReadOnlyRoot
+	}
+	writable := e.meta.WritablePaths
+	e.mu.RUnlock()
+
+	h.ReadonlyRootfs = false
+	if !readOnly {
+		return
+	}
+	if !environment.Capabilities().ReadOnlyRootfs() {
+		e.log().Warn("read-only root filesystems are not supported for windows containers, leaving root filesystem writable")
+		return
+	}
+
+	h.ReadonlyRootfs = true
+	if h.Tmpfs == nil {
+		h.Tmpfs = make(map[string]string)
+	}
+	opts := "rw,exec,nosuid,size=" + strconv.Itoa(int(config.Get().Docker.TmpfsSize)) + "M"
+	for _, p := range writable {
+		p, ok := writablePath(p)
+		if !ok {
+			e.log().WithField("path", p).Warn("ignoring invalid writable path for container")
+			continue
+		}
+		if _, ok := h.Tmpfs[p]; !ok {
+			h.Tmpfs[p] = opts
+		}
+	}
+}
+
+// writablePath cleans the path, returning false if it is not absolute or would
+// hide the root or data directory of the container.
+func writablePath(p string) (string, bool) {
+	p = path.Clean(strings.ReplaceAll(p, "\\", "/"))
+	if !path.IsAbs(p) || p == "/" {
+		return p, false
+	}
+	if p == environment.ContainerDataDirectory || strings.HasPrefix(p, environment.ContainerDataDirectory+"/") {
+		return p, false
+	}
+	return p, true
+}
@@ -0,0 +1,30 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestWritablePath(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("writablePath", func() {
+		g.It("cleans absolute paths", func() {
+			p, ok := writablePath("/var/cache/../run/")
+			g.Assert(ok).IsTrue()
+			g.Assert(p).Equal("/var/run")
+
+			p, ok = writablePath("\\Users\\ContainerUser\\AppData")
+			g.Assert(ok).IsTrue()
+			g.Assert(p).Equal("/Users/ContainerUser/AppData")
+		})
+
+		g.It("rejects the root and data directories", func() {
+			for _, p := range []string{"relative/path", "/", "/Container", "/Container/logs"} {
+				_, ok := writablePath(p)
+				g.Assert(ok).IsFalse()
+			}
+		})
+	})
+}
@@ -0,0 +1,297 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/system"
+)
+
+// SetSidecars sets the sidecar containers that should be started alongside the
+// server process. Changes are applied the next time the server is started.
+func (e *Environment) SetSidecars(s []environment.Sidecar) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.meta.Sidecars = s
+}
+
+// sidecarName returns the name of the container for a sidecar of the server.
+func (e *Environment) sidecarName(name string) string {
+	return e.Id + "_" + name
+}
+
+// startSidecars creates and starts each of the sidecars for the server, joining
+// them to the network namespace of the server container. If a sidecar is already
+// running, such as after Wings has been restarted, it is only attached to. An
+// error for one sidecar does not prevent the others from being started, the name
+// of each sidecar that failed is published as a SidecarFailedEvent.
+func (e *Environment) startSidecars(ctx context.Context) {
+	e.mu.RLock()
+	sidecars := e.meta.Sidecars
+	e.mu.RUnlock()
+	if len(sidecars) == 0 {
+		return
+	}
+
+	sctx, cancel := context.WithCancel(context.Background())
+	e.sidecarMu.Lock()
+	if e.sidecarCancel != nil {
+		e.sidecarCancel()
+	}
+	e.sidecarCancel = cancel
+	e.sidecarStats = make(map[string]environment.Stats, len(sidecars))
+	e.sidecarMu.Unlock()
+
+	seen := make(map[string]bool, len(sidecars))
+	for _, s := range sidecars {
+		l := e.log().WithField("sidecar", s.Name)
+		err := s.Validate()
+		if err == nil && seen[s.Name] {
+			err = errors.Errorf("environment/docker: sidecar name \"%s\" is used more than once", s.Name)
+		}
+		seen[s.Name] = true
+		if err == nil {
+			err = e.startSidecar(ctx, s)
+		}
+		if err != nil {
+			l.WithField("error", err).Error("failed to start sidecar container for server")
+			e.Events().Publish(environment.SidecarFailedEvent, s.Name)
+			continue
+		}
+		if s.Console {
+			go e.followSidecarOutput(sctx, s.Name)
+		}
+		go func(s environment.Sidecar) {
+			if err := e.pollSidecarResources(sctx, s); err != nil && !errors.Is(err, context.Canceled) {
+				l.WithField("error", err).Warn("error during sidecar resource polling")
+			}
+		}(s)
+	}
+}
+
+func (e *Environment) startSidecar(ctx context.Context, s environment.Sidecar) error {
+	name := e.sidecarName(s.Name)
+	if c, err := e.client.ContainerInspect(ctx, name); err == nil {
+		if c.State.Running {
+			return nil
+		}
+		if err := e.client.ContainerRemove(ctx, name, types.ContainerRemoveOptions{Force: true}); err != nil && !client.IsErrNotFound(err) {
+			return errors.Wrap(err, "environment/docker: failed to remove stopped sidecar container")
+		}
+	} else if !client.IsErrNotFound(err) {
+		return errors.Wrap(err, "environment/docker: failed to inspect sidecar container")
+	}
+
+	if err := e.ensureImageExists(s.Image); err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	evs := e.Configuration.EnvironmentVariables()
+	for k, v := range s.Environment {
+		evs = append(evs, k+"="+v)
+	}
+
+	conf := &container.Config{
+		User:  e.containerUser(),
+		Tty:   true,
+		Image: strings.TrimPrefix(s.Image, "~"),
+		Env:   evs,
+		Labels: map[string]string{
+			"Service":       "Pterodactyl",
+			"ContainerType": "server_sidecar",
+			"ServerUuid":    e.Id,
+		},
+	}
+	if len(s.Command) > 0 {
+		conf.Cmd = s.Command
+	}
+
+	var mounts []mount.Mount
+	if s.DataMount != "" {
+		for _, m := range e.Configuration.Mounts() {
+			if m.Default {
+				mounts = append(mounts, mount.Mount{
+					Type:     mount.TypeBind,
+					Source:   m.Source,
+					Target:   s.DataMount,
+					ReadOnly: true,
+				})
+			}
+		}
+	}
+
+	resources := container.Resources{}
+	if s.MemoryLimit > 0 {
+		resources.Memory = s.MemoryLimit * 1_000_000
+	}
+	if s.CpuLimit > 0 {
+		resources.CPUQuota = s.CpuLimit * 1_000
+		resources.CPUPeriod = 100_000
+	}
+
+	hostConf := &container.HostConfig{
+		// Joining the network namespace of the server container allows the sidecar to
+		// communicate with the server process over localhost, and to use the ports
+		// allocated to the server.
+		NetworkMode: container.NetworkMode("container:" + e.Id),
+		Mounts:      mounts,
+		Resources:   resources,
+		Tmpfs: map[string]string{
+			"/tmp": "rw,exec,nosuid,size=" + strconv.Itoa(int(config.Get().Docker.TmpfsSize)) + "M",
+		},
+		LogConfig: getLogConfig(),
+		CapDrop: []string{
+			"setpcap", "mknod", "audit_write", "net_raw", "dac_override",
+			"fowner", "fsetid", "net_bind_service", "sys_chroot", "setfcap",
+		},
+	}
+
+	if _, err := e.client.ContainerCreate(ctx, conf, hostConf, nil, nil, name); err != nil {
+		return errors.Wrap(err, "environment/docker: failed to create sidecar container")
+	}
+	if err := e.client.ContainerStart(ctx, name, types.ContainerStartOptions{}); err != nil {
+		return errors.Wrap(err, "environment/docker: failed to start sidecar container")
+	}
+	return nil
+}
+
+// removeSidecars stops resource polling and console output for the sidecars and
+// removes all the sidecar containers that exist for the server.
+func (e *Environment) removeSidecars(ctx context.Context) error {
+	e.sidecarMu.Lock()
+	if e.sidecarCancel != nil {
+		e.sidecarCancel()
+		e.sidecarCancel = nil
+	}
+	e.sidecarStats = nil
+	e.sidecarMu.Unlock()
+
+	containers, err := e.client.ContainerList(ctx, types.ContainerListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", "ContainerType=server_sidecar"),
+			filters.Arg("label", "ServerUuid="+e.Id),
+		),
+	})
+	if err != nil {
+		return errors.Wrap(err, "environment/docker: failed to list sidecar containers")
+	}
+	for _, c := range containers {
+		if err := e.client.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{RemoveVolumes: true, Force: true}); err != nil && !client.IsErrNotFound(err) {
+			return errors.Wrap(err, "environment/docker: failed to remove sidecar container")
+		}
+	}
+	return nil
+}
+
+// followSidecarOutput sends the output of a sidecar to the console for the server,
+// prefixing each line with the name of the sidecar.
+func (e *Environment) followSidecarOutput(ctx context.Context, name string) {
+	reader, err := e.client.ContainerLogs(ctx, e.sidecarName(name), types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		e.log().WithField("sidecar", name).WithField("error", err).Warn("failed to follow sidecar container output")
+		return
+	}
+	defer reader.Close()
+
+	prefix := []byte("[" + name + "] ")
+	if err := system.ScanReader(reader, func(v []byte) {
+		e.logCallbackMx.Lock()
+		defer e.logCallbackMx.Unlock()
+		e.logCallback(append(append([]byte{}, prefix...), v...))
+	}); err != nil && err != io.EOF && !errors.Is(err, context.Canceled) {
+		e.log().WithField("sidecar", name).WithField("error", err).Warn("error processing scanner line in sidecar output")
+	}
+}
+
+// pollSidecarResources tracks the resource usage of a sidecar so that it can be
+// included in the resource usage for the server. The usage is removed once the
+// sidecar stops so that it is no longer counted.
+func (e *Environment) pollSidecarResources(ctx context.Context, s environment.Sidecar) error {
+	stats, err := e.client.ContainerStats(ctx, e.sidecarName(s.Name), true)
+	if err != nil {
+		return err
+	}
+	defer stats.Body.Close()
+	defer func() {
+		e.sidecarMu.Lock()
+		delete(e.sidecarStats, s.Name)
+		e.sidecarMu.Unlock()
+	}()
+
+	// Only the limit configured for the sidecar is counted, Docker reports the memory
+	// of the host as the limit for containers without one.
+	var limit uint64
+	if s.MemoryLimit > 0 {
+		limit = uint64(s.MemoryLimit) * 1_000_000
+	}
+
+	dec := json.NewDecoder(stats.Body)
+	for {
+		var v types.StatsJSON
+		if err := dec.Decode(&v); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		e.sidecarMu.Lock()
+		if e.sidecarStats != nil {
+			e.sidecarStats[s.Name] = environment.Stats{
+				Memory:      calculateDockerMemory(v.MemoryStats),
+				MemoryLimit: limit,
+				CpuAbsolute: calculateDockerAbsoluteCpu(v),
+			}
+		}
+		e.sidecarMu.Unlock()
+	}
+}
+
+// withSidecarUsage adds the memory and CPU usage of the sidecars to the resource
+// usage of the server process, along with their memory limits if the server has
+// one. Network usage is not added since the sidecars share the network namespace
+// of the server container.
+func (e *Environment) withSidecarUsage(st environment.Stats) environment.Stats {
+	limited := e.Configuration.Limits().MemoryLimit > 0
+
+	e.sidecarMu.Lock()
+	defer e.sidecarMu.Unlock()
+
+	for _, s := range e.sidecarStats {
+		st.Memory += s.Memory
+		st.CpuAbsolute += s.CpuAbsolute
+		if limited {
+			st.MemoryLimit += s.MemoryLimit
+		}
+	}
+	return st
+}
+
+// removeSidecarsOnExit removes the sidecars once the server process has stopped,
+// they cannot continue running without the network namespace of the server.
+func (e *Environment) removeSidecarsOnExit() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	if err := e.removeSidecars(ctx); err != nil {
+		e.log().WithField("error", err).Warn("failed to remove sidecar containers for stopped server")
+	}
+}
@@ -3,12 +3,15 @@ package docker
 import (
 	"context"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"emperror.dev/errors"
 	"github.com/docker/docker/api/types"
 	"github.com/goccy/go-json"
 
+	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
 )
 
@@ -29,8 +32,22 @@ func (e *Environment) Uptime(ctx context.Context) (int64, error) {
 	return time.Since(started).Milliseconds(), nil
 }
 
+// SetResourceDemand sets if anything is currently watching the resource usage of
+// the server. When resource usage is collected on demand it is only collected
+// while this is true.
+func (e *Environment) SetResourceDemand(wanted bool) {
+	e.resourceDemand.Store(wanted)
+}
+
+// resourcesWanted returns true if the resource usage of the server should be
+// collected right now.
+func (e *Environment) resourcesWanted() bool {
+	return !config.Get().Docker.StatsCollector.OnDemand || e.resourceDemand.Load()
+}
+
 // Attach to the instance and then automatically emit an event whenever the resource usage for the
-// server process changes.
+// server process changes. When the shared collector is enabled the usage is collected along with
+// every other running container, otherwise a stats stream is opened for this container alone.
 func (e *Environment) pollResources(ctx context.Context) error {
 	if e.st.Load() == environment.ProcessOfflineState {
 		return errors.New("cannot enable resource polling on a stopped server")
@@ -39,17 +56,29 @@ func (e *Environment) pollResources(ctx context.Context) error {
 	e.log().Info("starting resource polling for container")
 	defer e.log().Debug("stopped resource polling for container")
 
-	stats, err := e.client.ContainerStats(ctx, e.Id, true)
+	uptime, err := e.Uptime(ctx)
 	if err != nil {
-		return err
+		e.log().WithField("error", err).Warn("failed to calculate container uptime")
 	}
-	defer stats.Body.Close()
 
-	uptime, err := e.Uptime(ctx)
+	if config.Get().Docker.StatsCollector.Enabled {
+		return collector().watch(ctx, e, uptime)
+	}
+	return e.streamResources(ctx, uptime)
+}
+
+// streamResources opens a stats stream with Docker for the container and emits
+// the resource usage each time Docker sends it.
+func (e *Environment) streamResources(ctx context.Context, uptime int64) error {
+	stats, err := e.client.ContainerStats(ctx, e.Id, true)
 	if err != nil {
-		e.log().WithField("error", err).Warn("failed to calculate container uptime")
+		return err
 	}
+	defer stats.Body.Close()
 
+	interval := time.Duration(config.Get().Docker.StatsCollector.Interval) * time.Second
+	var published time.Time
+	var disk environment.DiskIOStats
 	dec := json.NewDecoder(stats.Body)
 	for {
 		select {
@@ -76,20 +105,84 @@ func (e *Environment) pollResources(ctx context.Context) error {
 				uptime = uptime + v.Read.Sub(v.PreRead).Milliseconds()
 			}
 
-			st := environment.Stats{
-				Uptime:      uptime,
-				Memory:      calculateDockerMemory(v.MemoryStats),
-				MemoryLimit: v.MemoryStats.Limit,
-				CpuAbsolute: calculateDockerAbsoluteCpu(v),
-				Network:     environment.NetworkStats{},
+			// Docker sends the usage roughly every second, anything more frequent than
+			// the configured interval is discarded. Half a second of leeway is allowed
+			// since the time between each reading is never exact.
+			if !e.resourcesWanted() || v.Read.Sub(published) < interval-time.Second/2 {
+				continue
 			}
-
-			for _, nw := range v.Networks {
-				st.Network.RxBytes += nw.RxBytes
-				st.Network.TxBytes += nw.TxBytes
+			st := statsFromDocker(v, uptime)
+			if !published.IsZero() {
+				st.DiskIO.SetRates(disk, v.Read.Sub(published))
 			}
+			published, disk = v.Read, st.DiskIO
+			e.Events().Publish(environment.ResourceEvent, e.withSidecarUsage(e.withGpuUsage(ctx, st)))
+		}
+	}
+}
+
+// statsFromDocker converts the stats returned by Docker into the resource usage
+// of the server.
+func statsFromDocker(v types.StatsJSON, uptime int64) environment.Stats {
+	st := environment.Stats{
+		Uptime:      uptime,
+		Memory:      calculateDockerMemory(v.MemoryStats),
+		MemoryLimit: v.MemoryStats.Limit,
+		CpuAbsolute: calculateDockerAbsoluteCpu(v),
+		Network:     environment.NetworkStats{},
+		DiskIO:      calculateDockerDiskIO(v),
+	}
+	for _, nw := range v.Networks {
+		st.Network.RxBytes += nw.RxBytes
+		st.Network.TxBytes += nw.TxBytes
+	}
+	return st
+}
 
-			e.Events().Publish(environment.ResourceEvent, st)
+// withGpuUsage adds the GPU usage of the processes running in the container to
+// the resource usage, if GPUs are passed through to it.
+func (e *Environment) withGpuUsage(ctx context.Context, st environment.Stats) environment.Stats {
+	if !e.Configuration.Limits().GpuPassthrough() {
+		return st
+	}
+	usage, err := environment.GpuProcessUsage(ctx)
+	if err != nil {
+		e.log().WithField("error", err).Debug("failed to collect GPU usage for container")
+		return st
+	}
+	pids, err := e.containerPids(ctx)
+	if err != nil {
+		e.log().WithField("error", err).Debug("failed to list processes in container")
+		return st
+	}
+	gpu := environment.GpuUsageFor(usage, pids)
+	st.Gpu = &gpu
+	return st
+}
+
+// containerPids returns the PIDs on the host of the processes running within the
+// container.
+func (e *Environment) containerPids(ctx context.Context) ([]int, error) {
+	top, err := e.client.ContainerTop(ctx, e.Id, nil)
+	if err != nil {
+		return nil, err
+	}
+	col := -1
+	for i, t := range top.Titles {
+		if strings.EqualFold(t, "pid") {
+			col = i
+		}
+	}
+	if col < 0 {
+		return nil, errors.New("environment/docker: container process list does not include a PID")
+	}
+	pids := make([]int, 0, len(top.Processes))
+	for _, p := range top.Processes {
+		if col < len(p) {
+			if pid, err := strconv.Atoi(p[col]); err == nil {
+				pids = append(pids, pid)
+			}
 		}
 	}
+	return pids, nil
 }
@@ -2,8 +2,11 @@ package docker
 
 import (
 	"math"
+	"strings"
 
 	"github.com/docker/docker/api/types"
+
+	"github.com/pterodactyl/wings/environment"
 )
 
 // The "docker stats" CLI call does not return the same value as the types.MemoryStats.Usage
@@ -26,6 +29,30 @@ func calculateDockerMemory(stats types.MemoryStats) uint64 {
 	return stats.Usage
 }
 
+// calculateDockerDiskIO returns the disk activity of the container from the blkio
+// statistics of the cgroup. The operation names are capitalized with cgroups v1
+// and lowercase with cgroups v2.
+func calculateDockerDiskIO(v types.StatsJSON) environment.DiskIOStats {
+	var d environment.DiskIOStats
+	for _, e := range v.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			d.ReadBytes += e.Value
+		case "write":
+			d.WriteBytes += e.Value
+		}
+	}
+	for _, e := range v.BlkioStats.IoServicedRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			d.ReadOps += e.Value
+		case "write":
+			d.WriteOps += e.Value
+		}
+	}
+	return d
+}
+
 // Calculates the absolute CPU usage used by the server process on the system, not constrained
 // by the defined CPU limits on the container.
 //
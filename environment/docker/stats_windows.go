@@ -2,6 +2,8 @@ package docker
 
 import (
 	"github.com/docker/docker/api/types"
+
+	"github.com/pterodactyl/wings/environment"
 )
 
 // The "docker stats" CLI call does not return the same value as the types.MemoryStats.Usage
@@ -16,6 +18,17 @@ func calculateDockerMemory(stats types.MemoryStats) uint64 {
 	return stats.PrivateWorkingSet
 }
 
+// calculateDockerDiskIO returns the disk activity of the container from the storage
+// statistics reported by the Host Compute Service.
+func calculateDockerDiskIO(v types.StatsJSON) environment.DiskIOStats {
+	return environment.DiskIOStats{
+		ReadBytes:  v.StorageStats.ReadSizeBytes,
+		WriteBytes: v.StorageStats.WriteSizeBytes,
+		ReadOps:    v.StorageStats.ReadCountNormalized,
+		WriteOps:   v.StorageStats.WriteCountNormalized,
+	}
+}
+
 // Calculates the absolute CPU usage used by the server process on the system, not constrained
 // by the defined CPU limits on the container.
 //
@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/strslice"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+// SetEntrypointWrapper sets the name of the entrypoint wrapper requested by the
+// egg, this is applied when the container is next created.
+func (e *Environment) SetEntrypointWrapper(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.meta.Wrapper = name
+}
+
+// applyEntrypointWrapper runs the entrypoint wrapper requested by the egg in
+// place of the entrypoint of the image. The wrapper is mounted read-only into
+// the container and receives the entrypoint and command of the image as its
+// arguments. Eggs may only use the wrappers that are configured for the node.
+func (e *Environment) applyEntrypointWrapper(ctx context.Context, conf *container.Config, hostConf *container.HostConfig) error {
+	e.mu.RLock()
+	name := e.meta.Wrapper
+	e.mu.RUnlock()
+	if name == "" {
+		return nil
+	}
+	w, ok := config.Get().Docker.EntrypointWrappers[name]
+	if !ok || len(w.Command) == 0 {
+		return errors.Errorf("environment/docker: entrypoint wrapper \"%s\" is not configured for this node", name)
+	}
+
+	img, _, err := e.client.ImageInspectWithRaw(ctx, conf.Image)
+	if err != nil {
+		return errors.Wrap(err, "environment/docker: failed to inspect image for entrypoint wrapper")
+	}
+	var args []string
+	if img.Config != nil {
+		args = append(args, img.Config.Entrypoint...)
+		args = append(args, img.Config.Cmd...)
+	}
+
+	conf.Entrypoint = strslice.StrSlice(w.Command)
+	conf.Cmd = strslice.StrSlice(args)
+	conf.Env = append(conf.Env, wrapperVariables(w.Environment)...)
+	hostConf.Mounts = append(hostConf.Mounts, mount.Mount{
+		Type:     mount.TypeBind,
+		Source:   w.Source,
+		Target:   environment.ContainerWrapperDirectory,
+		ReadOnly: true,
+	})
+	return nil
+}
+
+// wrapperVariables returns the variables for the wrapper in the "KEY=value"
+// format, sorted so that the container configuration is always the same.
+func wrapperVariables(env map[string]string) []string {
+	out := make([]string, 0, len(env)+1)
+	for k, v := range env {
+		if k == "" || strings.Contains(k, "=") {
+			continue
+		}
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out)
+	return append(out, "WRAPPER_DIRECTORY="+environment.ContainerWrapperDirectory)
+}
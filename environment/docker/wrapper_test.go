@@ -0,0 +1,18 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestWrapperVariables(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("wrapperVariables", func() {
+		g.It("returns the variables sorted with the wrapper directory last", func() {
+			out := wrapperVariables(map[string]string{"B": "2", "A": "1", "BAD=KEY": "x"})
+			g.Assert(out).Equal([]string{"A=1", "B=2", "WRAPPER_DIRECTORY=/Wrapper"})
+		})
+	})
+}
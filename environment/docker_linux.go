@@ -2,6 +2,8 @@ package environment
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/docker/docker/api/types"
@@ -10,6 +12,27 @@ import (
 	"github.com/pterodactyl/wings/config"
 )
 
+// defaultDockerHost returns the default socket for the configured engine when
+// it differs from the default Docker socket. Rootless daemons listen on a socket
+// in the runtime directory of the user running them.
+func defaultDockerHost() string {
+	rootless := config.Get().System.User.Rootless.Enabled
+	if !rootless && !IsPodman() {
+		return ""
+	}
+	if !rootless {
+		return "unix:///run/podman/podman.sock"
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = "/run/user/" + strconv.Itoa(os.Getuid())
+	}
+	if IsPodman() {
+		return "unix://" + filepath.Join(dir, "podman", "podman.sock")
+	}
+	return "unix://" + filepath.Join(dir, "docker.sock")
+}
+
 // Creates a new network on the machine if one does not exist already.
 func createDockerNetwork(ctx context.Context, cli *client.Client) error {
 	nw := config.Get().Docker.Network
@@ -26,19 +49,20 @@ func createDockerNetwork(ctx context.Context, cli *client.Client) error {
 				Gateway: nw.Interfaces.V6.Gateway,
 			}},
 		},
-		Options: map[string]string{
-			"encryption": "false",
-			"com.docker.network.bridge.default_bridge":       "false",
-			"com.docker.network.bridge.enable_icc":           strconv.FormatBool(nw.EnableICC),
-			"com.docker.network.bridge.enable_ip_masquerade": "true",
-			"com.docker.network.bridge.host_binding_ipv4":    "0.0.0.0",
-			"com.docker.network.bridge.name":                 "pterodactyl0",
-			"com.docker.network.driver.mtu":                  "1500",
-		},
+		Options: networkOptions(nw),
 	})
 	if err != nil {
 		return err
 	}
+	// With a rootless daemon the network exists inside of the daemon's own network
+	// namespace, so the gateway is not reachable from the host. Ports published
+	// by containers are forwarded to the loopback interface of the host instead.
+	if config.Get().System.User.Rootless.Enabled {
+		config.Update(func(c *config.Configuration) {
+			c.Docker.Network.Interface = "127.0.0.1"
+		})
+		return nil
+	}
 	if nw.Driver != "host" && nw.Driver != "overlay" && nw.Driver != "weavemesh" {
 		config.Update(func(c *config.Configuration) {
 			c.Docker.Network.Interface = c.Docker.Network.Interfaces.V4.Gateway
@@ -46,3 +70,24 @@ func createDockerNetwork(ctx context.Context, cli *client.Client) error {
 	}
 	return nil
 }
+
+// networkOptions returns the driver options used when creating the network. Podman
+// only supports a subset of the bridge options that Docker does, and rejects the
+// network if any others are provided.
+func networkOptions(nw config.DockerNetworkConfiguration) map[string]string {
+	if IsPodman() {
+		return map[string]string{
+			"com.docker.network.bridge.name": "pterodactyl0",
+			"com.docker.network.driver.mtu":  "1500",
+		}
+	}
+	return map[string]string{
+		"encryption": "false",
+		"com.docker.network.bridge.default_bridge":       "false",
+		"com.docker.network.bridge.enable_icc":           strconv.FormatBool(nw.EnableICC),
+		"com.docker.network.bridge.enable_ip_masquerade": "true",
+		"com.docker.network.bridge.host_binding_ipv4":    "0.0.0.0",
+		"com.docker.network.bridge.name":                 "pterodactyl0",
+		"com.docker.network.driver.mtu":                  "1500",
+	}
+}
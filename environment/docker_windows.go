@@ -9,6 +9,12 @@ import (
 	"github.com/pterodactyl/wings/config"
 )
 
+// defaultDockerHost returns the default socket for the configured engine. Only
+// Docker is supported on Windows, so the default Docker named pipe is always used.
+func defaultDockerHost() string {
+	return ""
+}
+
 // Creates a new network on the machine if one does not exist already.
 func createDockerNetwork(ctx context.Context, cli *client.Client) error {
 	nw := config.Get().Docker.Network
@@ -11,6 +11,8 @@ import (
 const (
 	StateChangeEvent         = "state change"
 	ResourceEvent            = "resources"
+	OutOfMemoryEvent         = "out of memory"
+	SidecarFailedEvent       = "sidecar failed"
 	DockerImagePullStarted   = "docker image pull started"
 	DockerImagePullStatus    = "docker image pull status"
 	DockerImagePullCompleted = "docker image pull completed"
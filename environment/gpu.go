@@ -0,0 +1,55 @@
+package environment
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// GpuStats is the GPU usage of a server, or of a single process.
+type GpuStats struct {
+	// The percentage of time the GPU spent running work for the server. This can
+	// exceed 100 when the server is using multiple GPUs.
+	Utilization float64 `json:"utilization"`
+
+	// The amount of video memory, in bytes, used by the server.
+	MemoryBytes uint64 `json:"memory_bytes"`
+}
+
+// The amount of time the GPU usage of the system is cached for. This is needed
+// since the usage is requested for every running server at the same time, and
+// sampling it takes a while.
+const gpuSampleLifetime = time.Second
+
+var gpuSample struct {
+	mu    sync.Mutex
+	at    time.Time
+	usage map[int]GpuStats
+	err   error
+}
+
+// GpuProcessUsage returns the GPU usage of every process on the system that is
+// using a GPU, keyed by the PID of the process on the host.
+func GpuProcessUsage(ctx context.Context) (map[int]GpuStats, error) {
+	gpuSample.mu.Lock()
+	defer gpuSample.mu.Unlock()
+
+	if time.Since(gpuSample.at) < gpuSampleLifetime {
+		return gpuSample.usage, gpuSample.err
+	}
+	gpuSample.usage, gpuSample.err = sampleGpuUsage(ctx)
+	gpuSample.at = time.Now()
+	return gpuSample.usage, gpuSample.err
+}
+
+// GpuUsageFor returns the combined GPU usage of the given processes.
+func GpuUsageFor(usage map[int]GpuStats, pids []int) GpuStats {
+	var st GpuStats
+	for _, pid := range pids {
+		if u, ok := usage[pid]; ok {
+			st.Utilization += u.Utilization
+			st.MemoryBytes += u.MemoryBytes
+		}
+	}
+	return st
+}
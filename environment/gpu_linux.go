@@ -0,0 +1,68 @@
+package environment
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// sampleGpuUsage returns the usage of every process using an NVIDIA GPU, using
+// the process monitor of nvidia-smi.
+func sampleGpuUsage(ctx context.Context) (map[int]GpuStats, error) {
+	out, err := exec.CommandContext(ctx, "nvidia-smi", "pmon", "-c", "1", "-s", "um").Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "environment: failed to run nvidia-smi")
+	}
+	return parsePmon(out), nil
+}
+
+// parsePmon parses the output of "nvidia-smi pmon". The columns are found using
+// the header since they differ between driver versions. The usage of a process
+// using more than one GPU is combined.
+func parsePmon(out []byte) map[int]GpuStats {
+	usage := make(map[int]GpuStats)
+	cols := map[string]int{}
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "#" {
+			// The first header line has the column names, the second has the units.
+			if len(cols) == 0 {
+				for i, f := range fields[1:] {
+					cols[f] = i
+				}
+			}
+			continue
+		}
+		value := func(name string) float64 {
+			i, ok := cols[name]
+			if !ok || i >= len(fields) {
+				return 0
+			}
+			v, _ := strconv.ParseFloat(fields[i], 64)
+			return v
+		}
+		i, ok := cols["pid"]
+		if !ok || i >= len(fields) {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[i])
+		if err != nil {
+			continue
+		}
+		u := usage[pid]
+		u.Utilization += value("sm")
+		// The framebuffer memory is reported in megabytes.
+		u.MemoryBytes += uint64(value("fb")) * 1024 * 1024
+		usage[pid] = u
+	}
+	return usage
+}
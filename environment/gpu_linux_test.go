@@ -0,0 +1,26 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestParsePmon(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("parsePmon", func() {
+		g.It("parses the usage of each process", func() {
+			out := []byte(`# gpu         pid  type    fb    sm   mem   enc   dec   command
+# Idx           #   C/G    MB     %     %     %     %   name
+    0       1234     C   512    40    10     -     -   java
+    1       1234     C   256    20     5     -     -   java
+    0       5678     G     -     -     -     -     -   Xorg
+`)
+			usage := parsePmon(out)
+			g.Assert(usage[1234].Utilization).Equal(60.0)
+			g.Assert(usage[1234].MemoryBytes).Equal(uint64(768 * 1024 * 1024))
+			g.Assert(usage[5678].Utilization).Equal(0.0)
+		})
+	})
+}
@@ -0,0 +1,24 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestGpuUsageFor(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("GpuUsageFor", func() {
+		g.It("combines the usage of the processes", func() {
+			usage := map[int]GpuStats{
+				10: {Utilization: 20, MemoryBytes: 1024},
+				11: {Utilization: 5, MemoryBytes: 2048},
+				12: {Utilization: 50, MemoryBytes: 4096},
+			}
+			st := GpuUsageFor(usage, []int{10, 11, 13})
+			g.Assert(st.Utilization).Equal(25.0)
+			g.Assert(st.MemoryBytes).Equal(uint64(3072))
+		})
+	})
+}
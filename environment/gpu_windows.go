@@ -0,0 +1,73 @@
+package environment
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+var gpuCounterPid = regexp.MustCompile(`\(pid_(\d+)_`)
+
+// sampleGpuUsage returns the usage of every process using a GPU from the GPU
+// performance counters that Windows provides for every DXGI adapter, no matter
+// the vendor of the GPU.
+func sampleGpuUsage(ctx context.Context) (map[int]GpuStats, error) {
+	out, err := exec.CommandContext(ctx, "typeperf", "-sc", "1",
+		`\GPU Engine(*)\Utilization Percentage`,
+		`\GPU Process Memory(*)\Dedicated Usage`,
+	).Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "environment: failed to read GPU performance counters")
+	}
+	return parseTypeperf(out), nil
+}
+
+// parseTypeperf parses the CSV output of typeperf. Every GPU engine used by a
+// process has its own counter, the busiest engine is used as the utilization of
+// the process in the same way as the task manager.
+func parseTypeperf(out []byte) map[int]GpuStats {
+	usage := make(map[int]GpuStats)
+	var rows [][]string
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte(`"`)) {
+			continue
+		}
+		row, err := csv.NewReader(bytes.NewReader(line)).Read()
+		if err != nil {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) < 2 {
+		return usage
+	}
+	header, values := rows[0], rows[len(rows)-1]
+	for i := 1; i < len(header) && i < len(values); i++ {
+		m := gpuCounterPid.FindStringSubmatch(header[i])
+		if m == nil {
+			continue
+		}
+		pid, _ := strconv.Atoi(m[1])
+		v, err := strconv.ParseFloat(strings.TrimSpace(values[i]), 64)
+		if err != nil {
+			continue
+		}
+		u := usage[pid]
+		if strings.HasSuffix(header[i], `\Utilization Percentage`) {
+			if v > u.Utilization {
+				u.Utilization = v
+			}
+		} else {
+			u.MemoryBytes += uint64(v)
+		}
+		usage[pid] = u
+	}
+	return usage
+}
@@ -0,0 +1,204 @@
+package environment
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// The policies that control when an image is pulled from its registry.
+const (
+	// Always pull the image, falling back to a local copy if the pull fails.
+	PullPolicyAlways = "always"
+	// Only pull the image if it does not already exist on the system.
+	PullPolicyIfNotPresent = "if-not-present"
+	// Never pull the image, it must already exist on the system.
+	PullPolicyNever = "never"
+)
+
+// ErrImageNotPresent is returned when an image does not exist locally and the
+// pull policy does not allow it to be pulled.
+var ErrImageNotPresent = errors.Sentinel("image is not present and pull policy is never")
+
+// PullPolicy returns the pull policy to use given the policy defined for a server.
+// If the policy is empty or unknown the default policy for the node is returned.
+func PullPolicy(p string) string {
+	switch p {
+	case PullPolicyAlways, PullPolicyIfNotPresent, PullPolicyNever:
+		return p
+	}
+	switch p = config.Get().Docker.PullPolicy; p {
+	case PullPolicyIfNotPresent, PullPolicyNever:
+		return p
+	}
+	return PullPolicyAlways
+}
+
+// ShouldPullImage determines if the image should be pulled according to the pull
+// policy. If the policy is "never" and the image does not exist locally an error
+// is returned.
+func ShouldPullImage(ctx context.Context, cli *client.Client, image string, policy string) (bool, error) {
+	policy = PullPolicy(policy)
+	if policy == PullPolicyAlways {
+		return true, nil
+	}
+	if _, _, err := cli.ImageInspectWithRaw(ctx, image); err == nil {
+		return false, nil
+	} else if !client.IsErrNotFound(err) {
+		return false, errors.Wrap(err, "environment/docker: failed to inspect image")
+	}
+	if policy == PullPolicyNever {
+		return false, errors.Wrapf(ErrImageNotPresent, "environment/docker: cannot use \"%s\"", image)
+	}
+	return true, nil
+}
+
+// ImagePruneReport contains the results of a single image garbage collection run.
+type ImagePruneReport struct {
+	Removed        []string `json:"removed"`
+	SpaceReclaimed uint64   `json:"space_reclaimed"`
+}
+
+// StartImageGarbageCollection periodically removes unused images from the system
+// until the provided context is canceled. The images function is called before
+// each run and returns the images referenced by the servers on the node, which
+// are always kept. If garbage collection is disabled in the configuration this
+// function returns immediately.
+func StartImageGarbageCollection(ctx context.Context, images func() []string) {
+	c := config.Get().Docker.ImageGarbageCollection
+	if !c.Enabled || c.Interval <= 0 {
+		return
+	}
+
+	log.WithField("interval", c.Interval).Info("starting docker image garbage collection")
+	ticker := time.NewTicker(time.Minute * time.Duration(c.Interval))
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r, err := PruneImages(ctx, images())
+				if err != nil {
+					log.WithField("error", err).Warn("failed to remove unused docker images")
+					continue
+				}
+				log.WithFields(log.Fields{"removed": len(r.Removed), "space_reclaimed": r.SpaceReclaimed}).Info("removed unused docker images")
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// PruneImages removes every image that is not used by a container on the system,
+// is older than the configured minimum age, and is not in the keep list or the
+// provided list of images. Dangling image layers are removed once this is complete.
+//
+// Servers that are stopped or have not been installed yet do not have a container
+// using their image, so the images for every server on the node must be passed
+// in to prevent them from being removed.
+func PruneImages(ctx context.Context, images []string) (ImagePruneReport, error) {
+	var report ImagePruneReport
+	cli, err := Docker()
+	if err != nil {
+		return report, err
+	}
+
+	c := config.Get().Docker
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{All: true})
+	if err != nil {
+		return report, errors.Wrap(err, "environment/docker: failed to list containers")
+	}
+	used := make(map[string]bool, len(containers))
+	for _, ct := range containers {
+		used[ct.ImageID] = true
+	}
+
+	keep := keepList(c, images)
+
+	list, err := cli.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return report, errors.Wrap(err, "environment/docker: failed to list images")
+	}
+	cutoff := time.Now().Add(-time.Hour * time.Duration(c.ImageGarbageCollection.MinimumAge))
+	for _, img := range list {
+		if used[img.ID] || time.Unix(img.Created, 0).After(cutoff) || keepImage(img.RepoTags, keep) {
+			continue
+		}
+		if _, err := cli.ImageRemove(ctx, img.ID, types.ImageRemoveOptions{PruneChildren: true}); err != nil {
+			// The image may have been removed already as the child of another image,
+			// or started being used by a container since it was listed.
+			if !client.IsErrNotFound(err) {
+				log.WithFields(log.Fields{"image": img.ID, "tags": img.RepoTags, "error": err}).Warn("failed to remove unused docker image")
+			}
+			continue
+		}
+		log.WithFields(log.Fields{"image": img.ID, "tags": img.RepoTags}).Debug("removed unused docker image")
+		report.Removed = append(report.Removed, img.ID)
+		report.SpaceReclaimed += uint64(img.Size)
+	}
+
+	res, err := cli.ImagesPrune(ctx, filters.NewArgs(filters.Arg("dangling", "true")))
+	if err != nil {
+		return report, errors.Wrap(err, "environment/docker: failed to prune dangling images")
+	}
+	for _, d := range res.ImagesDeleted {
+		if d.Deleted != "" {
+			report.Removed = append(report.Removed, d.Deleted)
+		}
+	}
+	report.SpaceReclaimed += res.SpaceReclaimed
+
+	return report, nil
+}
+
+// keepList returns the images that must never be removed, made up of the keep list
+// in the configuration, the images selected for detected runtimes, the images that
+// are pre-pulled at boot, and the provided images referenced by servers.
+func keepList(c config.DockerConfiguration, images []string) []string {
+	// Copy the keep list rather than appending to it directly, which would write the
+	// other images into the backing array of the configuration.
+	keep := make([]string, 0, len(c.ImageGarbageCollection.Keep)+len(c.RuntimeImages.Images)+len(c.PrePullImages)+len(images))
+	keep = append(keep, c.ImageGarbageCollection.Keep...)
+	for _, img := range c.RuntimeImages.Images {
+		keep = append(keep, img)
+	}
+	// Pre-pulled images are not used by any container until a server is installed
+	// with them, so they would otherwise be removed once they reach the minimum age.
+	keep = append(keep, c.PrePullImages...)
+	for _, img := range images {
+		if img != "" {
+			keep = append(keep, img)
+		}
+	}
+	return keep
+}
+
+// keepImage determines if any of the tags for an image match an entry in the
+// keep list. Entries without a tag match every tag of the repository.
+func keepImage(tags []string, keep []string) bool {
+	for _, tag := range tags {
+		repo := tag
+		if i := strings.LastIndex(tag, ":"); i > strings.LastIndex(tag, "/") {
+			repo = tag[:i]
+		}
+		for _, k := range keep {
+			if k == tag || k == repo {
+				return true
+			}
+			if ok, _ := path.Match(k, tag); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
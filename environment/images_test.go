@@ -0,0 +1,53 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestImages(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("keepList", func() {
+		g.It("includes the configured, runtime and server images", func() {
+			var c config.DockerConfiguration
+			c.ImageGarbageCollection.Keep = []string{"ghcr.io/pterodactyl/*"}
+			c.RuntimeImages.Images = map[string]string{"java:17": "ghcr.io/pterodactyl/yolks:java_17"}
+
+			keep := keepList(c, []string{"ghcr.io/example/game:latest", "", "ghcr.io/example/installer:debian"})
+			g.Assert(keep).Equal([]string{
+				"ghcr.io/pterodactyl/*",
+				"ghcr.io/pterodactyl/yolks:java_17",
+				"ghcr.io/example/game:latest",
+				"ghcr.io/example/installer:debian",
+			})
+		})
+
+		g.It("includes the pre-pulled images", func() {
+			var c config.DockerConfiguration
+			c.PrePullImages = []string{"mcr.microsoft.com/windows/servercore:ltsc2022"}
+
+			g.Assert(keepImage([]string{"mcr.microsoft.com/windows/servercore:ltsc2022"}, keepList(c, nil))).IsTrue()
+		})
+
+		g.It("does not write into the configured keep list", func() {
+			var c config.DockerConfiguration
+			c.ImageGarbageCollection.Keep = make([]string, 1, 4)
+			c.ImageGarbageCollection.Keep[0] = "a"
+
+			keepList(c, []string{"b", "c"})
+			g.Assert(c.ImageGarbageCollection.Keep[:3]).Equal([]string{"a", "", ""})
+		})
+	})
+
+	g.Describe("keepImage", func() {
+		g.It("matches images used by a stopped server", func() {
+			keep := keepList(config.DockerConfiguration{}, []string{"ghcr.io/example/game:latest"})
+			g.Assert(keepImage([]string{"ghcr.io/example/game:latest"}, keep)).IsTrue()
+			g.Assert(keepImage([]string{"ghcr.io/example/game:old"}, keep)).IsFalse()
+		})
+	})
+}
@@ -0,0 +1,269 @@
+package environment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/docker/docker/client"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// The isolation modes that Windows containers can be run with.
+const (
+	IsolationProcess = "process"
+	IsolationHyperV  = "hyperv"
+)
+
+// ImageCompatibilityError is returned when an image is not able to run on the
+// node, such as a Linux image on a node running Windows containers, so that the
+// reason can be shown to the user rather than the error from Docker.
+type ImageCompatibilityError struct {
+	Image  string
+	Reason string
+}
+
+func (e *ImageCompatibilityError) Error() string {
+	return fmt.Sprintf("environment: image \"%s\" cannot run on this node: %s", e.Image, e.Reason)
+}
+
+// IsImageCompatibilityError returns true if the error is, or wraps, an
+// ImageCompatibilityError.
+func IsImageCompatibilityError(err error) bool {
+	var ierr *ImageCompatibilityError
+	return errors.As(err, &ierr)
+}
+
+// Platform is the operating system and architecture that an image is built for,
+// or that the node runs containers on.
+type Platform struct {
+	OS      string `json:"os"`
+	Arch    string `json:"architecture"`
+	Variant string `json:"variant,omitempty"`
+	// The build number of Windows, such as 17763 for Windows Server 2019. This is
+	// zero for Linux, or if the build is not known.
+	Build int `json:"build,omitempty"`
+}
+
+func (p Platform) String() string {
+	if p.Build > 0 {
+		return fmt.Sprintf("%s (build %d)", p.pullPlatform(), p.Build)
+	}
+	return p.pullPlatform()
+}
+
+// pullPlatform returns the platform in the format used by Docker when pulling an
+// image, such as "linux/arm64/v8".
+func (p Platform) pullPlatform() string {
+	if p.Variant != "" {
+		return p.OS + "/" + p.Arch + "/" + p.Variant
+	}
+	return p.OS + "/" + p.Arch
+}
+
+// nodeCache holds the platform of the node once it has been retrieved from
+// Docker, since it cannot change without Docker being restarted. It is cleared
+// when Wings reconnects to Docker, since it may have been upgraded or had its
+// default isolation mode changed while it was unavailable.
+var nodeCache struct {
+	mu        sync.Mutex
+	platform  *Platform
+	isolation string
+}
+
+// resetNodePlatform clears the cached platform of the node so that it is
+// retrieved from Docker again the next time it is needed.
+func resetNodePlatform() {
+	nodeCache.mu.Lock()
+	defer nodeCache.mu.Unlock()
+	nodeCache.platform = nil
+	nodeCache.isolation = ""
+}
+
+// NodePlatform returns the operating system, architecture and Windows build that
+// the node runs containers on.
+func NodePlatform(ctx context.Context, cli *client.Client) (Platform, error) {
+	p, _, err := nodePlatform(ctx, cli)
+	return p, err
+}
+
+// nodePlatform returns the platform of the node along with the isolation mode
+// used for Windows containers, which is the one set in the configuration or the
+// default used by Docker.
+func nodePlatform(ctx context.Context, cli *client.Client) (Platform, string, error) {
+	nodeCache.mu.Lock()
+	defer nodeCache.mu.Unlock()
+	if nodeCache.platform == nil {
+		info, err := cli.Info(ctx)
+		if err != nil {
+			return Platform{}, "", errors.Wrap(err, "environment: failed to get docker information")
+		}
+		nodeCache.platform = &Platform{OS: info.OSType, Arch: NormalizeArch(info.Architecture), Build: kernelBuild(info.KernelVersion)}
+		nodeCache.isolation = string(info.Isolation)
+	}
+	isolation := config.Get().Docker.Isolation
+	if isolation == "" {
+		isolation = nodeCache.isolation
+	}
+	return *nodeCache.platform, isolation, nil
+}
+
+// CheckImageCompatibility checks that the image, which must already exist on the
+// node, was built for the operating system and architecture that the node runs
+// containers on. For Windows images the build must also be able to run with the
+// isolation mode used by the node. An ImageCompatibilityError is returned if it
+// cannot run on the node.
+func CheckImageCompatibility(ctx context.Context, cli *client.Client, image string) error {
+	img, _, err := cli.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		// Leave it to Docker to report an image that does not exist.
+		if client.IsErrNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, "environment: failed to inspect image")
+	}
+	node, isolation, err := nodePlatform(ctx, cli)
+	if err != nil {
+		return err
+	}
+	target := Platform{OS: img.Os, Arch: NormalizeArch(img.Architecture), Build: windowsBuild(img.OsVersion)}
+	if reason := checkPlatform(target, node, isolation); reason != "" {
+		return &ImageCompatibilityError{Image: image, Reason: reason}
+	}
+	return nil
+}
+
+// SelectPlatform returns the platform to request when pulling the image, such as
+// "linux/arm64", so that the manifest matching the node is pulled from an image
+// that is published for several platforms. Docker picks the manifest for the
+// Windows build of the node itself, so for Windows images this only checks that
+// one of the published builds is able to run on the node. An
+// ImageCompatibilityError is returned if none of the platforms can run on the
+// node. If the platform of the node cannot be retrieved, or the registry cannot
+// be queried for the platforms, an empty string is returned and the choice is
+// left to Docker.
+func SelectPlatform(ctx context.Context, cli *client.Client, image string, registryAuth string) (string, error) {
+	node, isolation, err := nodePlatform(ctx, cli)
+	if err != nil {
+		log.WithField("image", image).WithField("error", err).Debug("failed to get node platform, leaving platform selection to docker")
+		return "", nil
+	}
+	inspect, err := cli.DistributionInspect(ctx, image, registryAuth)
+	if err != nil {
+		log.WithField("image", image).WithField("error", err).Debug("failed to inspect image manifest, leaving platform selection to docker")
+		return "", nil
+	}
+	var available []Platform
+	for _, p := range inspect.Platforms {
+		// Skip the entries used for attestations, which do not contain an image.
+		if p.OS == "" || p.OS == "unknown" {
+			continue
+		}
+		available = append(available, Platform{OS: p.OS, Arch: NormalizeArch(p.Architecture), Variant: p.Variant, Build: windowsBuild(p.OSVersion)})
+	}
+	if len(available) == 0 {
+		return "", nil
+	}
+	p, reason := selectPlatform(available, node, isolation)
+	if reason != "" {
+		return "", &ImageCompatibilityError{Image: image, Reason: reason}
+	}
+	log.WithFields(log.Fields{"image": image, "platform": p.String()}).Debug("selected image manifest for node platform")
+	return Platform{OS: p.OS, Arch: p.Arch, Variant: p.Variant}.pullPlatform(), nil
+}
+
+// selectPlatform returns the platform from those available that is the best match
+// for the node, preferring the same Windows build as the node and then the newest
+// build that can run on it. If none can run on the node the reason is returned.
+func selectPlatform(available []Platform, node Platform, isolation string) (Platform, string) {
+	var compatible []Platform
+	for _, p := range available {
+		if checkPlatform(p, node, isolation) == "" {
+			compatible = append(compatible, p)
+		}
+	}
+	if len(compatible) == 0 {
+		if len(available) == 1 {
+			return Platform{}, checkPlatform(available[0], node, isolation)
+		}
+		names := make([]string, len(available))
+		for i, p := range available {
+			names[i] = p.String()
+		}
+		return Platform{}, fmt.Sprintf("the image is published for %s but the node is %s", strings.Join(names, ", "), node.String())
+	}
+	sort.SliceStable(compatible, func(i, j int) bool {
+		if (compatible[i].Build == node.Build) != (compatible[j].Build == node.Build) {
+			return compatible[i].Build == node.Build
+		}
+		return compatible[i].Build > compatible[j].Build
+	})
+	return compatible[0], ""
+}
+
+// checkPlatform returns the reason that an image built for the target platform
+// cannot run on the node, or an empty string if it can.
+func checkPlatform(target, node Platform, isolation string) string {
+	if target.OS != "" && node.OS != "" && target.OS != node.OS {
+		return fmt.Sprintf("the image is built for %s containers but the node runs %s containers", target.OS, node.OS)
+	}
+	if target.Arch != "" && node.Arch != "" && target.Arch != node.Arch {
+		return fmt.Sprintf("the image is built for %s but the node is %s", target.Arch, node.Arch)
+	}
+	if node.OS != "windows" || target.Build == 0 || node.Build == 0 {
+		return ""
+	}
+	// Hyper-V isolation runs each container in its own virtual machine, which allows
+	// images for older builds of Windows to be run, but never newer ones.
+	if isolation == IsolationHyperV {
+		if target.Build > node.Build {
+			return fmt.Sprintf("the image requires Windows build %d or newer but the node is running build %d", target.Build, node.Build)
+		}
+		return ""
+	}
+	if target.Build != node.Build {
+		return fmt.Sprintf("the image is built for Windows build %d but the node is running build %d, which must match when using process isolation", target.Build, node.Build)
+	}
+	return ""
+}
+
+// NormalizeArch returns the name used by images for the architecture, since
+// Docker reports the architecture of the node using the name from the kernel.
+func NormalizeArch(arch string) string {
+	switch strings.ToLower(arch) {
+	case "x86_64", "x86-64", "amd64":
+		return "amd64"
+	case "aarch64", "arm64":
+		return "arm64"
+	}
+	return strings.ToLower(arch)
+}
+
+// windowsBuild returns the build number from the version of Windows an image is
+// built for, such as 17763 from "10.0.17763.2686".
+func windowsBuild(version string) int {
+	parts := strings.Split(version, ".")
+	if len(parts) < 3 {
+		return 0
+	}
+	build, _ := strconv.Atoi(parts[2])
+	return build
+}
+
+// kernelBuild returns the build number of Windows from the kernel version that
+// Docker reports for a Windows node, such as 17763 from
+// "10.0 17763 (17763.1.amd64fre.rs5_release.180914-1434)".
+func kernelBuild(version string) int {
+	fields := strings.Fields(version)
+	if len(fields) < 2 {
+		return 0
+	}
+	build, _ := strconv.Atoi(fields[1])
+	return build
+}
@@ -0,0 +1,89 @@
+package environment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestPlatform(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	windows := Platform{OS: "windows", Arch: "amd64", Build: kernelBuild("10.0 17763 (17763.1.amd64fre.rs5_release.180914-1434)")}
+
+	g.Describe("checkPlatform", func() {
+		g.It("allows an image for the same platform", func() {
+			linux := Platform{OS: "linux", Arch: NormalizeArch("x86_64")}
+			g.Assert(checkPlatform(Platform{OS: "linux", Arch: "amd64"}, linux, "")).Equal("")
+			g.Assert(checkPlatform(Platform{OS: "windows", Arch: "amd64", Build: windowsBuild("10.0.17763.2686")}, windows, IsolationProcess)).Equal("")
+		})
+
+		g.It("rejects an image for a different operating system or architecture", func() {
+			g.Assert(checkPlatform(Platform{OS: "linux", Arch: "amd64"}, windows, "")).Equal("the image is built for linux containers but the node runs windows containers")
+			g.Assert(checkPlatform(Platform{OS: "linux", Arch: "arm64"}, Platform{OS: "linux", Arch: "amd64"}, "")).Equal("the image is built for arm64 but the node is amd64")
+		})
+
+		g.It("requires the Windows build to match when using process isolation", func() {
+			g.Assert(checkPlatform(Platform{OS: "windows", Arch: "amd64", Build: 14393}, windows, IsolationProcess)).IsNotZero()
+			g.Assert(checkPlatform(Platform{OS: "windows", Arch: "amd64", Build: 20348}, windows, IsolationProcess)).IsNotZero()
+		})
+
+		g.It("allows older Windows builds when using Hyper-V isolation", func() {
+			g.Assert(checkPlatform(Platform{OS: "windows", Arch: "amd64", Build: 14393}, windows, IsolationHyperV)).Equal("")
+			g.Assert(checkPlatform(Platform{OS: "windows", Arch: "amd64", Build: 20348}, windows, IsolationHyperV)).Equal("the image requires Windows build 20348 or newer but the node is running build 17763")
+		})
+	})
+
+	g.Describe("selectPlatform", func() {
+		arm := Platform{OS: "linux", Arch: NormalizeArch("aarch64")}
+		available := []Platform{
+			{OS: "linux", Arch: "amd64"},
+			{OS: "linux", Arch: "arm64", Variant: "v8"},
+			{OS: "windows", Arch: "amd64", Build: 20348},
+			{OS: "windows", Arch: "amd64", Build: 17763},
+			{OS: "windows", Arch: "amd64", Build: 14393},
+		}
+
+		g.It("selects the manifest for the architecture of the node", func() {
+			p, reason := selectPlatform(available, arm, "")
+			g.Assert(reason).Equal("")
+			g.Assert(p.pullPlatform()).Equal("linux/arm64/v8")
+		})
+
+		g.It("selects the manifest for the Windows build of the node", func() {
+			p, reason := selectPlatform(available, windows, IsolationProcess)
+			g.Assert(reason).Equal("")
+			g.Assert(p.Build).Equal(17763)
+
+			p, reason = selectPlatform(available[2:3], Platform{OS: "windows", Arch: "amd64", Build: 22621}, IsolationHyperV)
+			g.Assert(reason).Equal("")
+			g.Assert(p.Build).Equal(20348)
+		})
+
+		g.It("returns the reason when no manifest can run on the node", func() {
+			_, reason := selectPlatform(available[:1], arm, "")
+			g.Assert(reason).Equal("the image is built for amd64 but the node is arm64")
+
+			_, reason = selectPlatform(available[2:], Platform{OS: "windows", Arch: "amd64", Build: 19042}, IsolationProcess)
+			g.Assert(reason).Equal("the image is published for windows/amd64 (build 20348), windows/amd64 (build 17763), windows/amd64 (build 14393) but the node is windows/amd64 (build 19042)")
+		})
+	})
+
+	g.Describe("SelectPlatform", func() {
+		g.It("leaves the choice to docker when the node platform is not known", func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token"})
+			resetNodePlatform()
+			cli, err := client.NewClientWithOpts(client.WithHost("tcp://127.0.0.1:1"), client.WithVersion("1.41"))
+			g.Assert(err).IsNil()
+			defer cli.Close()
+
+			p, err := SelectPlatform(context.Background(), cli, "ghcr.io/pterodactyl/yolks:java_17", "")
+			g.Assert(err).IsNil()
+			g.Assert(p).Equal("")
+		})
+	})
+}
@@ -0,0 +1,124 @@
+package environment
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/buger/jsonparser"
+	"github.com/docker/docker/api/types"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// How often progress is logged while an image is being pre-pulled.
+const prePullLogInterval = time.Second * 10
+
+// PrePullImages pulls each of the images configured to be pre-pulled in the
+// background, one at a time, so that they are available the first time a server
+// needs them. Failures are logged and do not stop the remaining images from
+// being pulled.
+func PrePullImages(ctx context.Context) {
+	images := config.Get().Docker.PrePullImages
+	if len(images) == 0 {
+		return
+	}
+
+	go func() {
+		for _, image := range images {
+			if ctx.Err() != nil {
+				return
+			}
+			image = strings.TrimSpace(image)
+			if image == "" || strings.HasPrefix(image, "~") {
+				continue
+			}
+			l := log.WithField("image", image)
+			start := time.Now()
+			if err := prePullImage(ctx, image, l); err != nil {
+				l.WithField("error", err).Warn("failed to pre-pull docker image")
+				continue
+			}
+			l.WithField("duration", time.Since(start).Round(time.Second)).Info("completed pre-pull of docker image")
+		}
+	}()
+}
+
+func prePullImage(ctx context.Context, image string, l *log.Entry) error {
+	cli, err := Docker()
+	if err != nil {
+		return err
+	}
+
+	if pull, err := ShouldPullImage(ctx, cli, image, ""); err != nil || !pull {
+		if err == nil {
+			l.Debug("image exists locally, skipping pre-pull due to pull policy")
+		}
+		return err
+	}
+
+	opts := types.ImagePullOptions{}
+	for registry, c := range config.Get().Docker.Registries {
+		if !strings.HasPrefix(image, registry) {
+			continue
+		}
+		b64, err := c.Base64()
+		if err != nil {
+			l.WithField("error", err).Error("failed to get registry auth credentials")
+		}
+		opts.RegistryAuth = b64
+		break
+	}
+
+	platform, err := SelectPlatform(ctx, cli, image, opts.RegistryAuth)
+	if err != nil {
+		return err
+	}
+	opts.Platform = platform
+
+	l.Info("pre-pulling docker image in the background, this could take some time")
+	out, err := cli.ImagePull(ctx, image, opts)
+	if err != nil {
+		return errors.Wrap(err, "environment/docker: failed to pull image")
+	}
+	defer out.Close()
+
+	// Track the download progress of each layer so that we can log the overall
+	// progress of the pull periodically.
+	type layer struct{ current, total int64 }
+	layers := make(map[string]*layer)
+	last := time.Now()
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		b := scanner.Bytes()
+		if msg, _ := jsonparser.GetString(b, "error"); msg != "" {
+			return errors.New(msg)
+		}
+		id, _ := jsonparser.GetString(b, "id")
+		status, _ := jsonparser.GetString(b, "status")
+		if id != "" && status == "Downloading" {
+			current, _ := jsonparser.GetInt(b, "progressDetail", "current")
+			total, _ := jsonparser.GetInt(b, "progressDetail", "total")
+			layers[id] = &layer{current: current, total: total}
+		} else if id != "" && status == "Download complete" {
+			if ly, ok := layers[id]; ok {
+				ly.current = ly.total
+			}
+		}
+
+		if time.Since(last) < prePullLogInterval {
+			continue
+		}
+		last = time.Now()
+		var current, total int64
+		for _, ly := range layers {
+			current += ly.current
+			total += ly.total
+		}
+		l.WithFields(log.Fields{"layers": len(layers), "downloaded": current, "total": total}).Info("pre-pulling docker image...")
+	}
+	return errors.WithStack(scanner.Err())
+}
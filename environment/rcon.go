@@ -0,0 +1,113 @@
+package environment
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	"emperror.dev/errors"
+)
+
+// The packet types used by the Source RCON protocol, which is also used by
+// Minecraft and most other games that support RCON.
+const (
+	rconTypeAuth         = 3
+	rconTypeAuthResponse = 2
+	rconTypeCommand      = 2
+	rconTypeResponse     = 0
+)
+
+// The maximum size of a packet sent by the server.
+const rconMaxPacketSize = 4096 + 10
+
+// ErrRconAuthFailed is returned when the RCON server rejects the password.
+var ErrRconAuthFailed = errors.Sentinel("environment: rcon authentication failed")
+
+type rconPacket struct {
+	ID   int32
+	Type int32
+	Body string
+}
+
+// SendRcon connects to the RCON server at the address, authenticates using the
+// password and then executes the command, returning the response from the
+// server.
+func SendRcon(ctx context.Context, address string, password string, command string) (string, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", errors.WrapIf(err, "environment: failed to connect to rcon server")
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(time.Second * 10))
+	}
+
+	r := bufio.NewReader(conn)
+	if err := writeRconPacket(conn, rconPacket{ID: 1, Type: rconTypeAuth, Body: password}); err != nil {
+		return "", err
+	}
+	// Some servers send an empty response value packet before the result of the
+	// authentication, so skip over anything that is not the auth response.
+	for {
+		p, err := readRconPacket(r)
+		if err != nil {
+			return "", err
+		}
+		if p.Type != rconTypeAuthResponse {
+			continue
+		}
+		if p.ID == -1 {
+			return "", ErrRconAuthFailed
+		}
+		break
+	}
+
+	if err := writeRconPacket(conn, rconPacket{ID: 2, Type: rconTypeCommand, Body: command}); err != nil {
+		return "", err
+	}
+	p, err := readRconPacket(r)
+	if err != nil {
+		// The server may close the connection without responding when the command
+		// shuts it down, which is exactly what a stop command is expected to do.
+		if errors.Is(err, io.EOF) {
+			return "", nil
+		}
+		return "", err
+	}
+	return p.Body, nil
+}
+
+func writeRconPacket(w io.Writer, p rconPacket) error {
+	b := make([]byte, len(p.Body)+14)
+	binary.LittleEndian.PutUint32(b[0:4], uint32(len(p.Body)+10))
+	binary.LittleEndian.PutUint32(b[4:8], uint32(p.ID))
+	binary.LittleEndian.PutUint32(b[8:12], uint32(p.Type))
+	copy(b[12:], p.Body)
+	_, err := w.Write(b)
+	return errors.WrapIf(err, "environment: failed to write rcon packet")
+}
+
+func readRconPacket(r io.Reader) (rconPacket, error) {
+	var size int32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return rconPacket{}, err
+	}
+	if size < 10 || size > rconMaxPacketSize {
+		return rconPacket{}, errors.Errorf("environment: invalid rcon packet size %d", size)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return rconPacket{}, err
+	}
+	return rconPacket{
+		ID:   int32(binary.LittleEndian.Uint32(b[0:4])),
+		Type: int32(binary.LittleEndian.Uint32(b[4:8])),
+		Body: string(b[8 : size-2]),
+	}, nil
+}
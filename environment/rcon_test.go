@@ -0,0 +1,89 @@
+package environment
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+// serveRcon accepts a single connection and responds to it like an RCON server
+// using the given password, sending every command received to the channel.
+func serveRcon(l net.Listener, password string, commands chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		p, err := readRconPacket(r)
+		if err != nil {
+			return
+		}
+		switch p.Type {
+		case rconTypeAuth:
+			id := p.ID
+			if p.Body != password {
+				id = -1
+			}
+			_ = writeRconPacket(conn, rconPacket{ID: p.ID, Type: rconTypeResponse})
+			_ = writeRconPacket(conn, rconPacket{ID: id, Type: rconTypeAuthResponse})
+		default:
+			commands <- p.Body
+			_ = writeRconPacket(conn, rconPacket{ID: p.ID, Type: rconTypeResponse, Body: "Stopping the server"})
+		}
+	}
+}
+
+func TestSendRcon(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("SendRcon", func() {
+		var l net.Listener
+		var commands chan string
+
+		g.BeforeEach(func() {
+			var err error
+			l, err = net.Listen("tcp", "127.0.0.1:0")
+			g.Assert(err).IsNil()
+			commands = make(chan string, 1)
+			go serveRcon(l, "secret", commands)
+		})
+
+		g.AfterEach(func() {
+			_ = l.Close()
+		})
+
+		g.It("authenticates and sends the command", func() {
+			res, err := SendRcon(context.Background(), l.Addr().String(), "secret", "stop")
+			g.Assert(err).IsNil()
+			g.Assert(res).Equal("Stopping the server")
+			g.Assert(<-commands).Equal("stop")
+		})
+
+		g.It("returns an error if the password is wrong", func() {
+			_, err := SendRcon(context.Background(), l.Addr().String(), "wrong", "stop")
+			g.Assert(err).Equal(ErrRconAuthFailed)
+		})
+	})
+
+	g.Describe("StopStep", func() {
+		g.It("validates the step", func() {
+			g.Assert(StopStep{Type: StopStepCommand, Value: "stop"}.Validate()).IsNil()
+			g.Assert(StopStep{Type: StopStepCommand}.Validate() == nil).IsFalse()
+			g.Assert(StopStep{Type: StopStepRcon, Value: "stop"}.Validate() == nil).IsFalse()
+			g.Assert(StopStep{Type: StopStepSignal, Value: "sigterm"}.Validate()).IsNil()
+			g.Assert(StopStep{Type: StopStepSignal, Value: "SIGFOO"}.Validate() == nil).IsFalse()
+			g.Assert(StopStep{Type: StopStepKill}.Validate()).IsNil()
+			g.Assert(StopStep{Type: "reboot"}.Validate() == nil).IsFalse()
+		})
+
+		g.It("defaults the timeout", func() {
+			g.Assert(StopStep{}.Duration()).Equal(defaultStopStepTimeout)
+			g.Assert(StopStep{Timeout: 5}.Duration().Seconds()).Equal(float64(5))
+		})
+	})
+}
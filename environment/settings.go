@@ -3,20 +3,54 @@ package environment
 import (
 	"fmt"
 	"math"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/apex/log"
 
 	"github.com/pterodactyl/wings/config"
 )
 
+// The directory that the server files are mounted to within the container.
+const ContainerDataDirectory = "/Container"
+
+// The directory that the entrypoint wrapper requested by an egg is mounted to
+// within the container.
+const ContainerWrapperDirectory = "/Wrapper"
+
+// legacyDataDirectory matches the directory that server files are mounted to on
+// upstream Linux nodes, which is still referenced by the mounts and startup
+// commands of servers that have been transferred from one of those nodes.
+var legacyDataDirectory = regexp.MustCompile(`/home/container\b`)
+
+// TranslateDataDirectory replaces any reference to the data directory used by
+// upstream Linux nodes with the data directory used by this node.
+func TranslateDataDirectory(v string) string {
+	return legacyDataDirectory.ReplaceAllString(v, ContainerDataDirectory)
+}
+
+// TranslateVariables returns the environment variables, which are in the
+// "KEY=value" format, with TranslateDataDirectory applied to each value so that
+// startup commands written for upstream Linux nodes work on this node.
+func TranslateVariables(evs []string) []string {
+	out := make([]string, len(evs))
+	for i, v := range evs {
+		out[i] = v
+		if eq := strings.Index(v, "="); eq != -1 {
+			out[i] = v[:eq+1] + TranslateDataDirectory(v[eq+1:])
+		}
+	}
+	return out
+}
+
 type Mount struct {
 	// In Docker environments this makes no difference, however in a non-Docker environment you
 	// should treat the "Default" mount as the root directory for the server. All other mounts
 	// are just in addition to that one, and generally things like shared maps or timezone data.
 	Default bool `json:"-"`
 
-	// The target path on the system. This is "/home/container" for all server's Default mount
+	// The target path on the system. This is "/Container" for all server's Default mount
 	// but in non-container environments you can likely ignore the target and just work with the
 	// source.
 	Target string `json:"target"`
@@ -30,6 +64,16 @@ type Mount struct {
 	ReadOnly bool `json:"read_only"`
 }
 
+// SecurityProfile defines the seccomp and AppArmor profiles requested by an egg
+// for its server containers. If empty the profiles for the node are used.
+type SecurityProfile struct {
+	// The name of a seccomp profile within the seccomp directory for the node.
+	Seccomp string `json:"seccomp"`
+
+	// The name of an AppArmor profile that the node allows eggs to use.
+	AppArmor string `json:"apparmor"`
+}
+
 // Limits is the build settings for a given server that impact docker container
 // creation and resource limits for a server instance.
 type Limits struct {
@@ -44,6 +88,16 @@ type Limits struct {
 	// containers on the system and should be a value between 10 and 1000.
 	IoWeight uint16 `json:"io_weight"`
 
+	// The maximum rate in megabytes per second that a server can read from and write
+	// to the disk storing its data. A value of 0 means there is no limit.
+	IoReadBandwidth  int64 `json:"io_read_bandwidth"`
+	IoWriteBandwidth int64 `json:"io_write_bandwidth"`
+
+	// The maximum number of read and write operations per second that a server can
+	// perform on the disk storing its data. A value of 0 means there is no limit.
+	IoReadIops  int64 `json:"io_read_iops"`
+	IoWriteIops int64 `json:"io_write_iops"`
+
 	// The percentage of CPU that this instance is allowed to consume relative to
 	// the host. A value of 200% represents complete utilization of two cores. This
 	// should be a value between 1 and THREAD_COUNT * 100.
@@ -55,7 +109,21 @@ type Limits struct {
 	// Sets which CPU threads can be used by the docker instance.
 	Threads string `json:"threads"`
 
+	// Sets which NUMA memory nodes can be used by the docker instance. This should
+	// match the nodes of the threads assigned to the server.
+	Mems string `json:"mems"`
+
 	OOMDisabled bool `json:"oom_disabled"`
+
+	// Gpu controls if the GPUs on the host are passed through to the server. This
+	// has no effect unless GPU passthrough is also enabled for the node.
+	Gpu bool `json:"gpu"`
+}
+
+// GpuPassthrough returns true if the GPUs on the host should be passed through
+// to the server, which requires both the Panel and the node to allow it.
+func (l Limits) GpuPassthrough() bool {
+	return l.Gpu && config.Get().Docker.Gpu.Enabled
 }
 
 // ConvertedCpuLimit converts the CPU limit for a server build into a number
@@ -77,8 +145,30 @@ func (l Limits) MemoryOverheadMultiplier() float64 {
 	return config.Get().Docker.Overhead.GetMultiplier(l.MemoryLimit)
 }
 
+// BoundedMemoryLimit returns the hard memory limit for the server in bytes,
+// including the memory overhead. If the node reserves memory for the host the
+// limit is capped so that the reserved memory is always available, servers
+// without a memory limit are given the capped limit.
 func (l Limits) BoundedMemoryLimit() int64 {
-	return int64(math.Round(float64(l.MemoryLimit) * l.MemoryOverheadMultiplier() * 1_000_000))
+	limit := int64(math.Round(float64(l.MemoryLimit) * l.MemoryOverheadMultiplier() * 1_000_000))
+
+	reserved := config.Get().Docker.Overhead.ReservedMemory
+	if reserved <= 0 {
+		return limit
+	}
+	// The total is only reported by Docker, so it is read from the host for servers
+	// using containerd on a node where Docker is not available.
+	total := Capabilities().MemoryTotal
+	if total <= 0 {
+		total = hostMemoryTotal()
+	}
+	if total > 0 {
+		if max := total - reserved*1_000_000; max > 0 && (limit == 0 || limit > max) {
+			return max
+		}
+	}
+
+	return limit
 }
 
 // ConvertedSwap returns the amount of swap available as a total in bytes. This
@@ -92,6 +182,12 @@ func (l Limits) ConvertedSwap() int64 {
 	return (l.Swap * 1_000_000) + l.BoundedMemoryLimit()
 }
 
+// HasDiskThrottle returns true if any disk bandwidth or IOPS limits are set for
+// the server.
+func (l Limits) HasDiskThrottle() bool {
+	return l.IoReadBandwidth > 0 || l.IoWriteBandwidth > 0 || l.IoReadIops > 0 || l.IoWriteIops > 0
+}
+
 // ProcessLimit returns the process limit for a container. This is currently
 // defined at a system level and not on a per-server basis.
 func (l Limits) ProcessLimit() int64 {
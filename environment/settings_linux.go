@@ -1,22 +1,91 @@
 package environment
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"emperror.dev/errors"
+	"github.com/docker/docker/api/types/blkiodev"
 	"github.com/docker/docker/api/types/container"
+	"golang.org/x/sys/unix"
 )
 
+// AsContainerResources returns the resource limits for a container. Limits that
+// are not supported by the host are left unset, rather than being sent to the
+// daemon which would discard them.
 func (l Limits) AsContainerResources() container.Resources {
+	c := Capabilities()
 	pids := l.ProcessLimit()
 
-	return container.Resources{
+	r := container.Resources{
 		Memory:            l.BoundedMemoryLimit(),
 		MemoryReservation: l.MemoryLimit * 1_000_000,
-		MemorySwap:        l.ConvertedSwap(),
 		CPUQuota:          l.ConvertedCpuLimit(),
 		CPUPeriod:         100_000,
 		CPUShares:         1024,
 		BlkioWeight:       l.IoWeight,
-		OomKillDisable:    &l.OOMDisabled,
 		CpusetCpus:        l.Threads,
-		PidsLimit:         &pids,
+		CpusetMems:        l.Mems,
+	}
+	// The reservation cannot be more than the limit, which happens when the limit is
+	// capped by the memory reserved for the host.
+	if r.Memory > 0 && r.MemoryReservation > r.Memory {
+		r.MemoryReservation = r.Memory
+	}
+	if c.SwapLimit {
+		r.MemorySwap = l.ConvertedSwap()
+	}
+	if c.OomKillDisable {
+		r.OomKillDisable = &l.OOMDisabled
+	}
+	if c.PidsLimit {
+		r.PidsLimit = &pids
+	}
+
+	return r
+}
+
+// ApplyDiskThrottle sets the disk bandwidth and IOPS limits on the resources for
+// the block device that stores the given path. Throttling only applies to direct
+// IO on cgroup v1 hosts, on cgroup v2 buffered writes are throttled as well.
+func (l Limits) ApplyDiskThrottle(r *container.Resources, path string) error {
+	if !l.HasDiskThrottle() {
+		return nil
+	}
+	dev, err := blockDevice(path)
+	if err != nil {
+		return err
+	}
+
+	throttle := func(rate int64) []*blkiodev.ThrottleDevice {
+		if rate <= 0 {
+			return nil
+		}
+		return []*blkiodev.ThrottleDevice{{Path: dev, Rate: uint64(rate)}}
+	}
+	r.BlkioDeviceReadBps = throttle(l.IoReadBandwidth * 1_000_000)
+	r.BlkioDeviceWriteBps = throttle(l.IoWriteBandwidth * 1_000_000)
+	r.BlkioDeviceReadIOps = throttle(l.IoReadIops)
+	r.BlkioDeviceWriteIOps = throttle(l.IoWriteIops)
+
+	return nil
+}
+
+// blockDevice returns the path to the disk that stores the given path. If the
+// path is stored on a partition the disk containing the partition is returned,
+// since throttling limits can only be applied to whole disks.
+func blockDevice(path string) (string, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return "", errors.Wrap(err, "environment: failed to stat path for disk throttling")
+	}
+	sys, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", unix.Major(uint64(st.Dev)), unix.Minor(uint64(st.Dev))))
+	if err != nil {
+		return "", errors.Errorf("environment: %s is not stored on a block device", path)
+	}
+	if _, err := os.Stat(filepath.Join(sys, "partition")); err == nil {
+		sys = filepath.Dir(sys)
 	}
+	return "/dev/" + filepath.Base(sys), nil
 }
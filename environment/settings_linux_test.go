@@ -0,0 +1,86 @@
+package environment
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestApplyDiskThrottle(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("Limits.ApplyDiskThrottle", func() {
+		g.It("leaves the resources unchanged when there are no limits", func() {
+			var r container.Resources
+			g.Assert(Limits{}.ApplyDiskThrottle(&r, "/path/that/does/not/exist")).IsNil()
+			g.Assert(r.BlkioDeviceReadBps == nil).IsTrue()
+			g.Assert(r.BlkioDeviceWriteIOps == nil).IsTrue()
+		})
+
+		g.It("returns an error when the path does not exist", func() {
+			var r container.Resources
+			err := Limits{IoReadBandwidth: 50}.ApplyDiskThrottle(&r, "/path/that/does/not/exist")
+			g.Assert(err != nil).IsTrue()
+			g.Assert(r.BlkioDeviceReadBps == nil).IsTrue()
+		})
+
+		g.It("throttles the device storing the path", func() {
+			dir, err := os.MkdirTemp("", "wings-throttle")
+			g.Assert(err).IsNil()
+			defer os.RemoveAll(dir)
+			dev, err := blockDevice(dir)
+			// Nothing can be throttled when the temporary directory is not stored on
+			// a block device, such as when it is on a tmpfs.
+			if err != nil {
+				return
+			}
+			g.Assert(strings.HasPrefix(dev, "/dev/")).IsTrue()
+
+			var r container.Resources
+			l := Limits{IoReadBandwidth: 50, IoWriteIops: 200}
+			g.Assert(l.ApplyDiskThrottle(&r, dir)).IsNil()
+
+			g.Assert(len(r.BlkioDeviceReadBps)).Equal(1)
+			g.Assert(r.BlkioDeviceReadBps[0].Path).Equal(dev)
+			g.Assert(r.BlkioDeviceReadBps[0].Rate).Equal(uint64(50_000_000))
+			g.Assert(len(r.BlkioDeviceWriteIOps)).Equal(1)
+			g.Assert(r.BlkioDeviceWriteIOps[0].Rate).Equal(uint64(200))
+			g.Assert(r.BlkioDeviceWriteBps == nil).IsTrue()
+			g.Assert(r.BlkioDeviceReadIOps == nil).IsTrue()
+		})
+	})
+}
+
+func TestAsContainerResources(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("Limits.AsContainerResources", func() {
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token"})
+		})
+
+		g.It("reserves the memory limit without the overhead", func() {
+			r := Limits{MemoryLimit: 1000}.AsContainerResources()
+			g.Assert(r.MemoryReservation).Equal(int64(1_000_000_000))
+			g.Assert(r.Memory > r.MemoryReservation).IsTrue()
+		})
+
+		g.It("never reserves more than the capped limit", func() {
+			total := hostMemoryTotal()
+			if total <= 0 {
+				return
+			}
+			config.Update(func(c *config.Configuration) {
+				c.Docker.Overhead.ReservedMemory = 512
+			})
+			r := Limits{MemoryLimit: total / 1_000_000}.AsContainerResources()
+			g.Assert(r.Memory < total).IsTrue()
+			g.Assert(r.MemoryReservation).Equal(r.Memory)
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestTranslateVariables(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("TranslateVariables", func() {
+		g.It("replaces the upstream data directory in each value", func() {
+			out := TranslateVariables([]string{
+				"STARTUP=cd /home/container && ./run.sh",
+				"CONFIG=/home/container/config.yml",
+				"OTHER=/home/containers",
+				"INVALID",
+			})
+			g.Assert(out).Equal([]string{
+				"STARTUP=cd " + ContainerDataDirectory + " && ./run.sh",
+				"CONFIG=" + ContainerDataDirectory + "/config.yml",
+				"OTHER=/home/containers",
+				"INVALID",
+			})
+		})
+
+		g.It("only translates the variables of transferred servers", func() {
+			evs := []string{"CONFIG=/home/container/config.yml"}
+			c := NewConfiguration(Settings{}, evs)
+			g.Assert(c.TranslateVariables(evs)).Equal(evs)
+
+			c.SetTranslatePaths(true)
+			g.Assert(c.TranslateVariables(evs)).Equal([]string{"CONFIG=" + ContainerDataDirectory + "/config.yml"})
+		})
+	})
+}
+
+func TestLimits(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("Limits.HasDiskThrottle", func() {
+		g.It("is only true when a disk limit is set", func() {
+			g.Assert(Limits{}.HasDiskThrottle()).IsFalse()
+			g.Assert(Limits{IoWeight: 500}.HasDiskThrottle()).IsFalse()
+			g.Assert(Limits{IoReadBandwidth: 50}.HasDiskThrottle()).IsTrue()
+			g.Assert(Limits{IoWriteBandwidth: 50}.HasDiskThrottle()).IsTrue()
+			g.Assert(Limits{IoReadIops: 100}.HasDiskThrottle()).IsTrue()
+			g.Assert(Limits{IoWriteIops: 100}.HasDiskThrottle()).IsTrue()
+		})
+	})
+
+	g.Describe("Limits.BoundedMemoryLimit", func() {
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token"})
+			config.Update(func(c *config.Configuration) {
+				c.Docker.Overhead.Percentage = 10
+			})
+		})
+
+		g.It("applies the overhead when no memory is reserved", func() {
+			g.Assert(Limits{MemoryLimit: 1000}.BoundedMemoryLimit()).Equal(int64(1_100_000_000))
+			g.Assert(Limits{}.BoundedMemoryLimit()).Equal(int64(0))
+		})
+
+		g.It("keeps the reserved memory available to the host", func() {
+			total := Capabilities().MemoryTotal
+			if total <= 0 {
+				total = hostMemoryTotal()
+			}
+			// The cap cannot be checked when the total memory of the host is unknown.
+			if total <= 0 {
+				return
+			}
+			config.Update(func(c *config.Configuration) {
+				c.Docker.Overhead.ReservedMemory = 512
+			})
+			max := total - 512_000_000
+			g.Assert(Limits{}.BoundedMemoryLimit()).Equal(max)
+			g.Assert(Limits{MemoryLimit: total / 1_000_000}.BoundedMemoryLimit()).Equal(max)
+			g.Assert(Limits{MemoryLimit: 100}.BoundedMemoryLimit()).Equal(int64(110_000_000))
+		})
+	})
+
+	g.Describe("Limits.GpuPassthrough", func() {
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token"})
+		})
+
+		g.It("requires the server and the node to allow it", func() {
+			g.Assert(Limits{Gpu: true}.GpuPassthrough()).IsFalse()
+
+			config.Update(func(c *config.Configuration) {
+				c.Docker.Gpu.Enabled = true
+			})
+			g.Assert(Limits{}.GpuPassthrough()).IsFalse()
+			g.Assert(Limits{Gpu: true}.GpuPassthrough()).IsTrue()
+		})
+	})
+}
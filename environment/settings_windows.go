@@ -6,9 +6,30 @@ import (
 
 func (l Limits) AsContainerResources() container.Resources {
 	return container.Resources{
-		Memory:     l.BoundedMemoryLimit(),
-		CPUQuota:   l.ConvertedCpuLimit(),
-		CPUShares:  1024,
-		CpusetCpus: l.Threads,
+		Memory:             l.BoundedMemoryLimit(),
+		CPUQuota:           l.ConvertedCpuLimit(),
+		CPUShares:          1024,
+		CpusetCpus:         l.Threads,
+		IOMaximumBandwidth: uint64(maxLimit(l.IoReadBandwidth, l.IoWriteBandwidth) * 1_000_000),
+		IOMaximumIOps:      uint64(maxLimit(l.IoReadIops, l.IoWriteIops)),
 	}
 }
+
+// ApplyDiskThrottle is a no-op on Windows, the disk limits do not depend on the
+// device storing the server data and are set by AsContainerResources.
+func (l Limits) ApplyDiskThrottle(r *container.Resources, path string) error {
+	return nil
+}
+
+// maxLimit returns the larger of a read and write limit, since Windows only
+// supports a single limit for both. A limit of 0 or less is no limit.
+func maxLimit(read, write int64) int64 {
+	m := read
+	if write > m {
+		m = write
+	}
+	if m < 0 {
+		return 0
+	}
+	return m
+}
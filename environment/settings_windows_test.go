@@ -0,0 +1,25 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestAsContainerResources(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("Limits.AsContainerResources", func() {
+		g.It("uses the larger of the read and write disk limits", func() {
+			r := Limits{IoReadBandwidth: 20, IoWriteBandwidth: 50, IoReadIops: 300, IoWriteIops: 100}.AsContainerResources()
+			g.Assert(r.IOMaximumBandwidth).Equal(uint64(50_000_000))
+			g.Assert(r.IOMaximumIOps).Equal(uint64(300))
+		})
+
+		g.It("does not limit the disk when no limits are set", func() {
+			r := Limits{IoReadBandwidth: -1}.AsContainerResources()
+			g.Assert(r.IOMaximumBandwidth).Equal(uint64(0))
+			g.Assert(r.IOMaximumIOps).Equal(uint64(0))
+		})
+	})
+}
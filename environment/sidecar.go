@@ -0,0 +1,53 @@
+package environment
+
+import (
+	"regexp"
+
+	"emperror.dev/errors"
+)
+
+var sidecarNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,62}$`)
+
+// Sidecar defines an auxiliary container declared by an egg, such as a proxy, a
+// database, or a metrics exporter. Sidecars are started after the server process
+// and share its network namespace, so they can reach the server (and each other)
+// on localhost. They are removed whenever the server process stops.
+type Sidecar struct {
+	// The name of the sidecar, this must be unique for the server.
+	Name string `json:"name"`
+
+	// The image used to run the sidecar.
+	Image string `json:"image"`
+
+	// The command to run in the sidecar container. If empty the default command
+	// for the image is used.
+	Command []string `json:"command"`
+
+	// Additional environment variables for the sidecar. The environment variables
+	// for the server are always passed through.
+	Environment map[string]string `json:"environment"`
+
+	// The memory limit for the sidecar in megabytes, and the CPU limit where 100
+	// is a single core. A value of zero means there is no limit.
+	MemoryLimit int64 `json:"memory_limit"`
+	CpuLimit    int64 `json:"cpu_limit"`
+
+	// The path in the sidecar that the server data directory is mounted to as
+	// read-only. If empty the server data is not mounted into the sidecar.
+	DataMount string `json:"data_mount"`
+
+	// If true the output of the sidecar is included in the console for the server,
+	// with each line being prefixed with the name of the sidecar.
+	Console bool `json:"console"`
+}
+
+// Validate checks that the sidecar definition can be used to create a container.
+func (s Sidecar) Validate() error {
+	if !sidecarNameRegex.MatchString(s.Name) {
+		return errors.Errorf("environment: invalid sidecar name \"%s\"", s.Name)
+	}
+	if s.Image == "" {
+		return errors.Errorf("environment: no image defined for sidecar \"%s\"", s.Name)
+	}
+	return nil
+}
@@ -1,5 +1,10 @@
 package environment
 
+import (
+	"math"
+	"time"
+)
+
 // Stats defines the current resource usage for a given server instance.
 type Stats struct {
 	// The total amount of memory, in bytes, that this server instance is consuming. This is
@@ -22,9 +27,40 @@ type Stats struct {
 
 	// The current uptime of the container, in milliseconds.
 	Uptime int64 `json:"uptime"`
+
+	// The disk activity of the container since it was started. This is only reported
+	// by environments that are able to measure it.
+	DiskIO DiskIOStats `json:"disk_io"`
+
+	// The GPU usage of the container, only set when GPUs are passed through to it.
+	Gpu *GpuStats `json:"gpu,omitempty"`
 }
 
 type NetworkStats struct {
 	RxBytes uint64 `json:"rx_bytes"`
 	TxBytes uint64 `json:"tx_bytes"`
 }
+
+// DiskIOStats is the amount of data read and written to the disk by a container,
+// along with the number of operations performed per second since the previous
+// reading.
+type DiskIOStats struct {
+	ReadBytes  uint64  `json:"read_bytes"`
+	WriteBytes uint64  `json:"write_bytes"`
+	ReadOps    uint64  `json:"read_ops"`
+	WriteOps   uint64  `json:"write_ops"`
+	ReadIops   float64 `json:"read_iops"`
+	WriteIops  float64 `json:"write_iops"`
+}
+
+// SetRates calculates the operations per second from the previous reading, which
+// was taken the given amount of time ago. The rates are left as zero if the
+// counters went backwards, which happens when the container is restarted.
+func (d *DiskIOStats) SetRates(prev DiskIOStats, elapsed time.Duration) {
+	d.ReadIops, d.WriteIops = 0, 0
+	if elapsed <= 0 || d.ReadOps < prev.ReadOps || d.WriteOps < prev.WriteOps {
+		return
+	}
+	d.ReadIops = math.Round(float64(d.ReadOps-prev.ReadOps)/elapsed.Seconds()*100) / 100
+	d.WriteIops = math.Round(float64(d.WriteOps-prev.WriteOps)/elapsed.Seconds()*100) / 100
+}
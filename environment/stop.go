@@ -0,0 +1,101 @@
+package environment
+
+import (
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+)
+
+// The types of steps that can be used in a stop chain.
+const (
+	StopStepCommand = "command"
+	StopStepRcon    = "rcon"
+	StopStepSignal  = "signal"
+	StopStepKill    = "kill"
+)
+
+// The amount of time to wait for the process to stop after a step if the egg
+// does not define a timeout for it.
+const defaultStopStepTimeout = 30 * time.Second
+
+// StopStep is a single step in the chain used to stop a server, allowing an egg
+// to escalate from a console command to an RCON command, a signal, and finally
+// killing the process when the server ignores the earlier steps. Each step is
+// only executed if the process is still running after the previous step timed
+// out.
+type StopStep struct {
+	// The type of step, one of "command", "rcon", "signal" or "kill".
+	Type string `json:"type"`
+
+	// The command sent to the console or RCON server, or the signal sent to the
+	// process such as "SIGTERM", "SIGINT" or "CTRL_C".
+	Value string `json:"value"`
+
+	// The number of seconds to wait for the process to stop before moving on to
+	// the next step. Defaults to 30 seconds.
+	Timeout int `json:"timeout"`
+
+	// The names of the environment variables holding the port and password of the
+	// RCON server, only used by "rcon" steps.
+	RconPortVariable     string `json:"rcon_port_variable"`
+	RconPasswordVariable string `json:"rcon_password_variable"`
+
+	// The address and password of the RCON server, these are resolved from the
+	// server's allocations and environment variables before the chain is used.
+	RconAddress  string `json:"-"`
+	RconPassword string `json:"-"`
+}
+
+// Duration returns the amount of time to wait for the process to stop after the
+// step has been executed.
+func (s StopStep) Duration() time.Duration {
+	if s.Timeout <= 0 {
+		return defaultStopStepTimeout
+	}
+	return time.Duration(s.Timeout) * time.Second
+}
+
+// Validate checks that the step can be executed.
+func (s StopStep) Validate() error {
+	switch s.Type {
+	case StopStepCommand:
+		if s.Value == "" {
+			return errors.New("environment: no command defined for stop step")
+		}
+	case StopStepRcon:
+		if s.Value == "" {
+			return errors.New("environment: no command defined for rcon stop step")
+		}
+		if s.RconPortVariable == "" {
+			return errors.New("environment: no port variable defined for rcon stop step")
+		}
+	case StopStepSignal:
+		if _, ok := stopSignals[strings.ToUpper(s.Value)]; !ok {
+			return errors.Errorf("environment: unsupported signal \"%s\" for stop step", s.Value)
+		}
+	case StopStepKill:
+	default:
+		return errors.Errorf("environment: invalid stop step type \"%s\"", s.Type)
+	}
+	return nil
+}
+
+// The signals that can be sent to a process by a stop step, mapped to the name
+// used by the container runtime.
+var stopSignals = map[string]string{
+	"SIGINT":  "SIGINT",
+	"SIGTERM": "SIGTERM",
+	"SIGQUIT": "SIGQUIT",
+	"SIGHUP":  "SIGHUP",
+	"SIGABRT": "SIGABRT",
+	"SIGKILL": "SIGKILL",
+	// CTRL+C is sent to the console of Windows processes, which do not support
+	// signals, and as SIGINT to everything else.
+	"CTRL_C": "SIGINT",
+}
+
+// Signal returns the name of the signal sent by a "signal" step.
+func (s StopStep) Signal() string {
+	return stopSignals[strings.ToUpper(s.Value)]
+}
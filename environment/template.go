@@ -0,0 +1,125 @@
+package environment
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"emperror.dev/errors"
+)
+
+// The delimiters used for templates within environment variables. These differ
+// from the "{{VARIABLE}}" syntax replaced by the container entrypoint so that
+// existing startup commands are left unchanged.
+const (
+	templateLeftDelim  = "{{="
+	templateRightDelim = "}}"
+)
+
+// RenderVariables evaluates any templates within the environment variables, which
+// are in the "KEY=value" format. Templates can reference the value of any other
+// variable, such as {{= .SERVER_PORT }}, use conditionals, and call the functions
+// defined by templateFuncs. A new slice is returned, and any variable that fails
+// to render is left unchanged with the first error encountered being returned.
+func RenderVariables(evs []string, a Allocations) ([]string, error) {
+	vars := make(map[string]string, len(evs))
+	for _, v := range evs {
+		if i := strings.Index(v, "="); i != -1 {
+			vars[v[:i]] = v[i+1:]
+		}
+	}
+
+	var rerr error
+	funcs := templateFuncs(a)
+	out := make([]string, len(evs))
+	for i, v := range evs {
+		out[i] = v
+		eq := strings.Index(v, "=")
+		if eq == -1 || !strings.Contains(v[eq+1:], templateLeftDelim) {
+			continue
+		}
+		rendered, err := renderTemplate(v[:eq], v[eq+1:], vars, funcs)
+		if err != nil {
+			if rerr == nil {
+				rerr = err
+			}
+			continue
+		}
+		out[i] = v[:eq+1] + rendered
+	}
+	return out, rerr
+}
+
+func renderTemplate(name string, text string, vars map[string]string, funcs template.FuncMap) (string, error) {
+	t, err := template.New(name).
+		Delims(templateLeftDelim, templateRightDelim).
+		Option("missingkey=zero").
+		Funcs(funcs).
+		Parse(text)
+	if err != nil {
+		return "", errors.Wrap(err, "environment: failed to parse template for variable "+name)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, vars); err != nil {
+		return "", errors.Wrap(err, "environment: failed to render template for variable "+name)
+	}
+	return b.String(), nil
+}
+
+// templateFuncs returns the functions available to templates within environment
+// variables.
+func templateFuncs(a Allocations) template.FuncMap {
+	return template.FuncMap{
+		// default returns the fallback value if the value is empty, and is intended to
+		// be used in a pipeline such as {{= .PORT | default "25565" }}.
+		"default": func(fallback string, v string) string {
+			if v == "" {
+				return fallback
+			}
+			return v
+		},
+		"lower":    strings.ToLower,
+		"upper":    strings.ToUpper,
+		"trim":     strings.TrimSpace,
+		"quote":    strconv.Quote,
+		"contains": func(substr string, s string) bool { return strings.Contains(s, substr) },
+		"replace":  func(old string, new string, s string) string { return strings.ReplaceAll(s, old, new) },
+		// ports returns every port allocated to the server in ascending order.
+		"ports": func() []int { return allocatedPorts(a) },
+		// randomPort returns a random port allocated to the server other than the default
+		// port, or the default port if the server has no other allocations.
+		"randomPort": func() int {
+			var ports []int
+			for _, p := range allocatedPorts(a) {
+				if p != a.DefaultMapping.Port {
+					ports = append(ports, p)
+				}
+			}
+			if len(ports) == 0 {
+				return a.DefaultMapping.Port
+			}
+			return ports[rand.New(rand.NewSource(time.Now().UnixNano())).Intn(len(ports))]
+		},
+	}
+}
+
+// allocatedPorts returns the unique ports allocated to the server across every IP
+// address, in ascending order.
+func allocatedPorts(a Allocations) []int {
+	seen := make(map[int]bool)
+	var ports []int
+	for _, pp := range a.Mappings {
+		for _, p := range pp {
+			if p < 1 || p > 65535 || seen[p] {
+				continue
+			}
+			seen[p] = true
+			ports = append(ports, p)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
@@ -0,0 +1,56 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestRenderVariables(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	var a Allocations
+	a.DefaultMapping.Ip = "127.0.0.1"
+	a.DefaultMapping.Port = 25565
+	a.Mappings = map[string][]int{"127.0.0.1": {25565, 25566}}
+
+	g.Describe("RenderVariables", func() {
+		g.It("leaves variables without templates unchanged", func() {
+			evs := []string{"STARTUP=java -Xmx{{SERVER_MEMORY}}M -jar server.jar", "SERVER_MEMORY=1024"}
+			out, err := RenderVariables(evs, a)
+			g.Assert(err).IsNil()
+			g.Assert(out).Equal(evs)
+		})
+
+		g.It("references other variables and applies functions", func() {
+			out, err := RenderVariables([]string{
+				"STARTUP=./server --name {{= .NAME | upper | quote }} --level {{= .LEVEL | default \"world\" }}",
+				"NAME=my server",
+			}, a)
+			g.Assert(err).IsNil()
+			g.Assert(out[0]).Equal(`STARTUP=./server --name "MY SERVER" --level world`)
+		})
+
+		g.It("evaluates conditionals", func() {
+			out, err := RenderVariables([]string{
+				"STARTUP=./server{{= if eq .MODE \"hardcore\" }} --hardcore{{= end }}",
+				"MODE=hardcore",
+			}, a)
+			g.Assert(err).IsNil()
+			g.Assert(out[0]).Equal("STARTUP=./server --hardcore")
+		})
+
+		g.It("picks a random port other than the default", func() {
+			out, err := RenderVariables([]string{"QUERY_PORT={{= randomPort }}"}, a)
+			g.Assert(err).IsNil()
+			g.Assert(out[0]).Equal("QUERY_PORT=25566")
+		})
+
+		g.It("leaves invalid templates unchanged and returns an error", func() {
+			evs := []string{"STARTUP=./server {{= .NAME | missing }}"}
+			out, err := RenderVariables(evs, a)
+			g.Assert(err == nil).IsFalse()
+			g.Assert(out).Equal(evs)
+		})
+	})
+}
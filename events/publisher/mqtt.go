@@ -0,0 +1,88 @@
+package publisher
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// mqttDriver publishes messages to an MQTT 3.1.1 broker using QoS 0.
+//
+// @see http://docs.oasis-open.org/mqtt/mqtt/v3.1.1/mqtt-v3.1.1.html
+type mqttDriver struct {
+	*conn
+}
+
+func newMqtt(c config.EventBusConfiguration) *mqttDriver {
+	d := &mqttDriver{conn: &conn{address: c.Address}}
+	d.handshake = func(nc net.Conn) error {
+		// Protocol name, level 4 (3.1.1), connect flags, and a keep alive of 0 which
+		// disables the keep alive mechanism on the broker.
+		var flags byte = 0x02 // clean session
+		payload := mqttString("wings-" + config.Get().Uuid)
+		if c.Username != "" {
+			flags |= 0x80
+			payload = append(payload, mqttString(c.Username)...)
+			if c.Password != "" {
+				flags |= 0x40
+				payload = append(payload, mqttString(c.Password)...)
+			}
+		}
+		vh := append(mqttString("MQTT"), 0x04, flags, 0x00, 0x00)
+		if _, err := nc.Write(mqttPacket(0x10, append(vh, payload...))); err != nil {
+			return err
+		}
+
+		_ = nc.SetReadDeadline(time.Now().Add(time.Second * 5))
+		ack := make([]byte, 4)
+		if _, err := io.ReadFull(nc, ack); err != nil {
+			return err
+		}
+		if ack[0] != 0x20 || ack[3] != 0x00 {
+			return errors.Errorf("publisher: mqtt broker refused connection (code %d)", ack[3])
+		}
+		_ = nc.SetReadDeadline(time.Time{})
+
+		go func() {
+			_, _ = io.Copy(io.Discard, nc)
+		}()
+		return nil
+	}
+	return d
+}
+
+func (d *mqttDriver) Publish(subject string, payload []byte) error {
+	return d.write(mqttPacket(0x30, append(mqttString(subject), payload...)))
+}
+
+func (d *mqttDriver) Separator() string {
+	return "/"
+}
+
+// mqttString encodes a string with the two byte length prefix used by MQTT.
+func mqttString(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s))}, s...)
+}
+
+// mqttPacket returns a packet with the given fixed header type and body, using
+// the variable length encoding for the remaining length.
+func mqttPacket(t byte, body []byte) []byte {
+	b := []byte{t}
+	l := len(body)
+	for {
+		d := byte(l % 128)
+		l /= 128
+		if l > 0 {
+			d |= 0x80
+		}
+		b = append(b, d)
+		if l == 0 {
+			break
+		}
+	}
+	return append(b, body...)
+}
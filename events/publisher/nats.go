@@ -0,0 +1,81 @@
+package publisher
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// natsDriver publishes messages using the NATS text protocol.
+//
+// @see https://docs.nats.io/reference/reference-protocols/nats-protocol
+type natsDriver struct {
+	*conn
+}
+
+func newNats(c config.EventBusConfiguration) *natsDriver {
+	d := &natsDriver{conn: &conn{address: c.Address}}
+	d.handshake = func(nc net.Conn) error {
+		_ = nc.SetReadDeadline(time.Now().Add(time.Second * 5))
+		r := bufio.NewReader(nc)
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, "INFO") {
+			return errors.New("publisher: unexpected response from nats server: " + strings.TrimSpace(line))
+		}
+		_ = nc.SetReadDeadline(time.Time{})
+
+		b, err := json.Marshal(map[string]interface{}{
+			"verbose":  false,
+			"pedantic": false,
+			"name":     "wings",
+			"user":     c.Username,
+			"pass":     c.Password,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := nc.Write([]byte("CONNECT " + string(b) + "\r\n")); err != nil {
+			return err
+		}
+
+		// The server will periodically send a PING to the client, and close the connection
+		// if it does not receive a PONG in response.
+		go func() {
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if strings.HasPrefix(line, "PING") {
+					if _, err := nc.Write([]byte("PONG\r\n")); err != nil {
+						return
+					}
+				}
+			}
+		}()
+		return nil
+	}
+	return d
+}
+
+func (d *natsDriver) Publish(subject string, payload []byte) error {
+	b := make([]byte, 0, len(subject)+len(payload)+16)
+	b = append(b, "PUB "+subject+" "+strconv.Itoa(len(payload))+"\r\n"...)
+	b = append(b, payload...)
+	b = append(b, "\r\n"...)
+	return d.write(b)
+}
+
+func (d *natsDriver) Separator() string {
+	return "."
+}
@@ -0,0 +1,268 @@
+// Package publisher mirrors internal Wings events onto an external message broker
+// such as NATS, Redis pub/sub, or an MQTT broker. Only publishing is supported,
+// Wings never consumes messages from the broker.
+package publisher
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// ErrUnknownDriver is returned when the configured broker driver is not one that
+// is supported by Wings.
+var ErrUnknownDriver = errors.Sentinel("publisher: unknown driver")
+
+// The maximum number of messages that can be waiting to be sent to the broker.
+// Once this limit is reached additional messages are dropped rather than blocking
+// the event processing of a server.
+const queueSize = 1024
+
+// The maximum amount of time to wait for the queued messages to be sent to the
+// broker when the publisher is closed.
+const closeTimeout = time.Second * 10
+
+// Driver defines a connection to an external broker.
+type Driver interface {
+	// Publish sends the payload to the given subject on the broker.
+	Publish(subject string, payload []byte) error
+
+	// Separator returns the character used by the broker to separate the segments
+	// of a subject or topic.
+	Separator() string
+
+	// Close terminates the connection to the broker.
+	Close() error
+}
+
+// Message is the payload published to the broker for every event.
+type Message struct {
+	Server    string      `json:"server"`
+	Event     string      `json:"event"`
+	Data      interface{} `json:"data"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+type queued struct {
+	subject []string
+	message Message
+}
+
+// Publisher queues events and sends them to the broker in a background routine.
+type Publisher struct {
+	driver Driver
+	prefix string
+	events map[string]bool
+	queue  chan queued
+	done   chan struct{}
+
+	// mu guards closed, which prevents messages from being queued once the queue
+	// has been closed.
+	mu     sync.RWMutex
+	closed bool
+}
+
+var (
+	mu       sync.RWMutex
+	instance *Publisher
+)
+
+// DefaultEvents is the list of events published when no events are configured.
+var DefaultEvents = []string{
+	"status",
+	"stats",
+	"install completed",
+	"install rolled back",
+	"crash detected",
+	"out of memory",
+}
+
+// New returns a new publisher for the given configuration.
+func New(c config.EventBusConfiguration) (*Publisher, error) {
+	var d Driver
+	switch c.Driver {
+	case "nats":
+		d = newNats(c)
+	case "redis":
+		d = newRedis(c)
+	case "mqtt":
+		d = newMqtt(c)
+	default:
+		return nil, errors.Wrap(ErrUnknownDriver, c.Driver)
+	}
+
+	evts := c.Events
+	if len(evts) == 0 {
+		evts = DefaultEvents
+	}
+	p := &Publisher{
+		driver: d,
+		prefix: c.Prefix,
+		events: make(map[string]bool, len(evts)),
+		queue:  make(chan queued, queueSize),
+		done:   make(chan struct{}),
+	}
+	for _, e := range evts {
+		p.events[e] = true
+	}
+	go p.run()
+	return p, nil
+}
+
+// Configure creates the global publisher instance using the current configuration.
+// If publishing is disabled this is a no-op.
+func Configure() error {
+	c := config.Get().EventBus
+	if !c.Enabled {
+		return nil
+	}
+	p, err := New(c)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	instance = p
+	mu.Unlock()
+	log.WithFields(log.Fields{"driver": c.Driver, "address": c.Address}).Info("publishing server events to external event bus")
+	return nil
+}
+
+// Get returns the global publisher instance, or nil if one is not configured.
+func Get() *Publisher {
+	mu.RLock()
+	defer mu.RUnlock()
+	return instance
+}
+
+// Close closes the global publisher instance, if one is configured, sending any
+// events that are still queued to the broker first.
+func Close() error {
+	mu.Lock()
+	p := instance
+	instance = nil
+	mu.Unlock()
+	if p == nil {
+		return nil
+	}
+	return p.Close()
+}
+
+// Wants determines if the publisher is configured to publish the given event.
+func (p *Publisher) Wants(event string) bool {
+	return p != nil && p.events[event]
+}
+
+// Publish queues an event for a server to be sent to the broker. If the queue is
+// full, or the publisher has been closed, the event is dropped.
+func (p *Publisher) Publish(server string, event string, data interface{}) {
+	if !p.Wants(event) {
+		return
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+	q := queued{
+		subject: []string{p.prefix, "servers", server, strings.ReplaceAll(event, " ", "_")},
+		message: Message{Server: server, Event: event, Data: data, Timestamp: time.Now().UTC()},
+	}
+	select {
+	case p.queue <- q:
+	default:
+		log.WithField("event", event).Warn("event bus publish queue is full, dropping event")
+	}
+}
+
+// Close stops accepting new events and waits for the events that are already
+// queued to be sent to the broker, for up to closeTimeout, before closing the
+// connection to the broker.
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-time.After(closeTimeout):
+		log.WithField("queued", len(p.queue)).Warn("timed out waiting for queued events to be sent to event bus")
+	}
+	return p.driver.Close()
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+	for q := range p.queue {
+		b, err := json.Marshal(q.message)
+		if err != nil {
+			log.WithField("error", err).Warn("failed to marshal event for event bus")
+			continue
+		}
+		var segments []string
+		for _, s := range q.subject {
+			if s != "" {
+				segments = append(segments, s)
+			}
+		}
+		if err := p.driver.Publish(strings.Join(segments, p.driver.Separator()), b); err != nil {
+			log.WithFields(log.Fields{"event": q.message.Event, "error": err}).Warn("failed to publish event to event bus")
+		}
+	}
+}
+
+// conn is a lazily established connection to a broker that is re-established
+// whenever a write to it fails.
+type conn struct {
+	mu        sync.Mutex
+	address   string
+	c         net.Conn
+	handshake func(c net.Conn) error
+}
+
+// write sends the data to the broker, connecting first if there is no active
+// connection.
+func (c *conn) write(b []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.c == nil {
+		nc, err := net.DialTimeout("tcp", c.address, time.Second*5)
+		if err != nil {
+			return errors.Wrap(err, "publisher: failed to connect to broker")
+		}
+		if c.handshake != nil {
+			if err := c.handshake(nc); err != nil {
+				_ = nc.Close()
+				return errors.Wrap(err, "publisher: failed to complete broker handshake")
+			}
+		}
+		c.c = nc
+	}
+	_ = c.c.SetWriteDeadline(time.Now().Add(time.Second * 5))
+	if _, err := c.c.Write(b); err != nil {
+		_ = c.c.Close()
+		c.c = nil
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (c *conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.c == nil {
+		return nil
+	}
+	err := c.c.Close()
+	c.c = nil
+	return err
+}
@@ -0,0 +1,282 @@
+package publisher
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// broker runs fn against the broker side of a pipe in the background and returns
+// the client side of it, along with a channel that receives the error returned
+// by fn once it has finished.
+func broker(fn func(c net.Conn) error) (net.Conn, <-chan error) {
+	client, server := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(server)
+	}()
+	return client, done
+}
+
+// expect reads the given bytes from the connection, returning an error if the
+// bytes read do not match.
+func expect(c net.Conn, want []byte) error {
+	b := make([]byte, len(want))
+	if _, err := io.ReadFull(c, b); err != nil {
+		return err
+	}
+	if string(b) != string(want) {
+		return &mismatch{got: b, want: want}
+	}
+	return nil
+}
+
+type mismatch struct {
+	got, want []byte
+}
+
+func (m *mismatch) Error() string {
+	return "unexpected bytes: got " + strings.TrimSpace(string(m.got)) + ", want " + strings.TrimSpace(string(m.want))
+}
+
+func TestDrivers(t *testing.T) {
+	g := Goblin(t)
+
+	g.Describe("Drivers", func() {
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{AuthenticationToken: "test", Uuid: "node"})
+		})
+
+		g.Describe("NATS", func() {
+			g.It("sends CONNECT after INFO and replies to PING", func() {
+				d := newNats(config.EventBusConfiguration{Username: "user", Password: "pass"})
+				client, done := broker(func(c net.Conn) error {
+					if _, err := c.Write([]byte("INFO {\"server_id\":\"test\"}\r\n")); err != nil {
+						return err
+					}
+					line, err := bufio.NewReader(c).ReadString('\n')
+					if err != nil {
+						return err
+					}
+					if !strings.HasPrefix(line, "CONNECT {") || !strings.Contains(line, `"user":"user"`) || !strings.HasSuffix(line, "}\r\n") {
+						return &mismatch{got: []byte(line), want: []byte("CONNECT {...}")}
+					}
+					if _, err := c.Write([]byte("PING\r\n")); err != nil {
+						return err
+					}
+					return expect(c, []byte("PONG\r\n"))
+				})
+				defer client.Close()
+
+				g.Assert(d.handshake(client)).IsNil()
+				g.Assert(<-done).IsNil()
+			})
+
+			g.It("rejects a server that does not send INFO", func() {
+				d := newNats(config.EventBusConfiguration{})
+				client, _ := broker(func(c net.Conn) error {
+					_, err := c.Write([]byte("-ERR 'Unknown Protocol Operation'\r\n"))
+					return err
+				})
+				defer client.Close()
+
+				g.Assert(d.handshake(client) != nil).IsTrue()
+			})
+
+			g.It("frames published messages", func() {
+				d := newNats(config.EventBusConfiguration{})
+				client, done := broker(func(c net.Conn) error {
+					return expect(c, []byte("PUB wings.servers.abc.status 7\r\nrunning\r\n"))
+				})
+				defer client.Close()
+				d.c = client
+
+				g.Assert(d.Publish("wings.servers.abc.status", []byte("running"))).IsNil()
+				g.Assert(<-done).IsNil()
+			})
+		})
+
+		g.Describe("Redis", func() {
+			g.It("authenticates before publishing", func() {
+				d := newRedis(config.EventBusConfiguration{Username: "user", Password: "pass"})
+				client, done := broker(func(c net.Conn) error {
+					if err := expect(c, []byte("*3\r\n$4\r\nAUTH\r\n$4\r\nuser\r\n$4\r\npass\r\n")); err != nil {
+						return err
+					}
+					_, err := c.Write([]byte("+OK\r\n"))
+					return err
+				})
+				defer client.Close()
+
+				g.Assert(d.handshake(client)).IsNil()
+				g.Assert(<-done).IsNil()
+			})
+
+			g.It("returns an error when authentication fails", func() {
+				d := newRedis(config.EventBusConfiguration{Password: "pass"})
+				client, _ := broker(func(c net.Conn) error {
+					if err := expect(c, []byte("*2\r\n$4\r\nAUTH\r\n$4\r\npass\r\n")); err != nil {
+						return err
+					}
+					_, err := c.Write([]byte("-WRONGPASS invalid password\r\n"))
+					return err
+				})
+				defer client.Close()
+
+				g.Assert(d.handshake(client) != nil).IsTrue()
+			})
+
+			g.It("encodes commands as an array of bulk strings", func() {
+				g.Assert(string(respCommand("PUBLISH", "a:b", ""))).Equal("*3\r\n$7\r\nPUBLISH\r\n$3\r\na:b\r\n$0\r\n\r\n")
+			})
+
+			g.It("writes published messages to the connection", func() {
+				d := newRedis(config.EventBusConfiguration{})
+				client, done := broker(func(c net.Conn) error {
+					return expect(c, []byte("*3\r\n$7\r\nPUBLISH\r\n$3\r\na:b\r\n$7\r\nrunning\r\n"))
+				})
+				defer client.Close()
+				d.c = client
+
+				g.Assert(d.Publish("a:b", []byte("running"))).IsNil()
+				g.Assert(<-done).IsNil()
+			})
+		})
+
+		g.Describe("MQTT", func() {
+			g.It("sends CONNECT and waits for CONNACK", func() {
+				d := newMqtt(config.EventBusConfiguration{Username: "user", Password: "pass"})
+				client, done := broker(func(c net.Conn) error {
+					connect := []byte{0x10, 0x22, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0xc2, 0x00, 0x00}
+					connect = append(connect, 0x00, 0x0a)
+					connect = append(connect, "wings-node"...)
+					connect = append(connect, 0x00, 0x04)
+					connect = append(connect, "user"...)
+					connect = append(connect, 0x00, 0x04)
+					connect = append(connect, "pass"...)
+					if err := expect(c, connect); err != nil {
+						return err
+					}
+					_, err := c.Write([]byte{0x20, 0x02, 0x00, 0x00})
+					return err
+				})
+				defer client.Close()
+
+				g.Assert(d.handshake(client)).IsNil()
+				g.Assert(<-done).IsNil()
+			})
+
+			g.It("returns an error when the broker refuses the connection", func() {
+				d := newMqtt(config.EventBusConfiguration{})
+				client, _ := broker(func(c net.Conn) error {
+					if _, err := io.ReadFull(c, make([]byte, 24)); err != nil {
+						return err
+					}
+					_, err := c.Write([]byte{0x20, 0x02, 0x00, 0x05})
+					return err
+				})
+				defer client.Close()
+
+				err := d.handshake(client)
+				g.Assert(err != nil).IsTrue()
+				g.Assert(strings.Contains(err.Error(), "code 5")).IsTrue()
+			})
+
+			g.It("frames published messages", func() {
+				d := newMqtt(config.EventBusConfiguration{})
+				client, done := broker(func(c net.Conn) error {
+					return expect(c, append([]byte{0x30, 0x0c, 0x00, 0x03, 'a', '/', 'b'}, "running"...))
+				})
+				defer client.Close()
+				d.c = client
+
+				g.Assert(d.Publish("a/b", []byte("running"))).IsNil()
+				g.Assert(<-done).IsNil()
+			})
+
+			g.It("uses multiple bytes for long remaining lengths", func() {
+				b := mqttPacket(0x30, make([]byte, 200))
+				g.Assert(b[:3]).Equal([]byte{0x30, 0xc8, 0x01})
+				g.Assert(len(b)).Equal(203)
+			})
+		})
+	})
+}
+
+// recorder is a driver that records the subjects of the messages published to it.
+type recorder struct {
+	mu       sync.Mutex
+	subjects []string
+	closed   bool
+}
+
+func (r *recorder) Publish(subject string, _ []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subjects = append(r.subjects, subject)
+	return nil
+}
+
+func (r *recorder) Separator() string {
+	return "."
+}
+
+func (r *recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+func TestPublisher(t *testing.T) {
+	g := Goblin(t)
+
+	g.Describe("Publisher", func() {
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{AuthenticationToken: "test", Uuid: "node"})
+		})
+
+		newPublisher := func(d Driver) *Publisher {
+			p := &Publisher{
+				driver: d,
+				prefix: "wings",
+				events: map[string]bool{"status": true},
+				queue:  make(chan queued, queueSize),
+				done:   make(chan struct{}),
+			}
+			go p.run()
+			return p
+		}
+
+		g.It("sends every queued event before closing the connection", func() {
+			d := &recorder{}
+			p := newPublisher(d)
+			for i := 0; i < 100; i++ {
+				p.Publish("abc", "status", "running")
+			}
+			p.Publish("abc", "stats", nil)
+
+			g.Assert(p.Close()).IsNil()
+			g.Assert(len(d.subjects)).Equal(100)
+			g.Assert(d.subjects[0]).Equal("wings.servers.abc.status")
+			g.Assert(d.closed).IsTrue()
+		})
+
+		g.It("drops events published after it has been closed", func() {
+			d := &recorder{}
+			p := newPublisher(d)
+			g.Assert(p.Close()).IsNil()
+			g.Assert(p.Close()).IsNil()
+
+			p.Publish("abc", "status", "running")
+			g.Assert(len(d.subjects)).Equal(0)
+		})
+	})
+}
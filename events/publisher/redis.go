@@ -0,0 +1,73 @@
+package publisher
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// redisDriver publishes messages to Redis pub/sub channels using the RESP
+// protocol.
+//
+// @see https://redis.io/docs/reference/protocol-spec/
+type redisDriver struct {
+	*conn
+}
+
+func newRedis(c config.EventBusConfiguration) *redisDriver {
+	d := &redisDriver{conn: &conn{address: c.Address}}
+	d.handshake = func(nc net.Conn) error {
+		if c.Password != "" {
+			args := []string{"AUTH", c.Password}
+			if c.Username != "" {
+				args = []string{"AUTH", c.Username, c.Password}
+			}
+			if _, err := nc.Write(respCommand(args...)); err != nil {
+				return err
+			}
+			_ = nc.SetReadDeadline(time.Now().Add(time.Second * 5))
+			line, err := bufio.NewReader(nc).ReadString('\n')
+			if err != nil {
+				return err
+			}
+			if !strings.HasPrefix(line, "+") {
+				return errors.New("publisher: redis authentication failed: " + strings.TrimSpace(line))
+			}
+			_ = nc.SetReadDeadline(time.Time{})
+		}
+
+		// Replies to the publish commands are not needed, but they must be read so that
+		// the connection buffers do not fill up.
+		go func() {
+			_, _ = io.Copy(io.Discard, nc)
+		}()
+		return nil
+	}
+	return d
+}
+
+func (d *redisDriver) Publish(subject string, payload []byte) error {
+	return d.write(respCommand("PUBLISH", subject, string(payload)))
+}
+
+func (d *redisDriver) Separator() string {
+	return ":"
+}
+
+// respCommand encodes the arguments as a RESP array of bulk strings.
+func respCommand(args ...string) []byte {
+	b := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		b = append(b, "$"+strconv.Itoa(len(a))+"\r\n"...)
+		b = append(b, a...)
+		b = append(b, "\r\n"...)
+	}
+	return b
+}
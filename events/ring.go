@@ -0,0 +1,148 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Ring is a fixed size buffer of lines that is shared by every subscriber,
+// rather than a copy of each line being sent to every subscriber like with a
+// Bus. Each subscriber has its own cursor into the ring, and subscribers that
+// fall more than the size of the ring behind skip the lines they missed rather
+// than slowing down the writer or any of the other subscribers.
+//
+// Pushing a line does not take any locks, it only notifies the subscribers that
+// there is something new to read.
+type Ring struct {
+	// The sequence number of the last entry pushed into the ring. This must be the
+	// first field in the struct so that it is aligned for atomic operations on
+	// 32-bit platforms.
+	head  uint64
+	slots []atomic.Value
+
+	mu   sync.Mutex
+	subs atomic.Value
+}
+
+// RingEntry is a single line in a Ring.
+type RingEntry struct {
+	Seq uint64
+	// The time that the line was pushed into the ring.
+	Time time.Time
+	Data []byte
+
+	once    sync.Once
+	encoded []byte
+}
+
+// Encoded returns the data of the entry encoded using fn. The data is only
+// encoded once, and the result is shared with every subscriber that reads the
+// entry, so fn must return the same result no matter which subscriber calls it.
+func (e *RingEntry) Encoded(fn func(data []byte) []byte) []byte {
+	e.once.Do(func() {
+		e.encoded = fn(e.Data)
+	})
+	return e.encoded
+}
+
+// NewRing returns a ring that holds the given number of lines.
+func NewRing(size int) *Ring {
+	if size < 1 {
+		size = 1
+	}
+	r := &Ring{slots: make([]atomic.Value, size)}
+	r.subs.Store([]*RingCursor(nil))
+	return r
+}
+
+// Push adds a copy of the data to the ring, replacing the oldest line once the
+// ring is full, and notifies every subscriber.
+func (r *Ring) Push(data []byte) {
+	seq := atomic.AddUint64(&r.head, 1)
+	r.slots[seq%uint64(len(r.slots))].Store(&RingEntry{Seq: seq, Time: time.Now(), Data: append([]byte(nil), data...)})
+	for _, c := range r.subs.Load().([]*RingCursor) {
+		select {
+		case c.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a new cursor that reads the lines pushed into the ring from
+// this point onward. Unsubscribe must be called once the cursor is no longer
+// being used.
+func (r *Ring) Subscribe() *RingCursor {
+	c := &RingCursor{
+		ring:   r,
+		next:   atomic.LoadUint64(&r.head) + 1,
+		notify: make(chan struct{}, 1),
+	}
+	r.mu.Lock()
+	subs := r.subs.Load().([]*RingCursor)
+	r.subs.Store(append(append([]*RingCursor{}, subs...), c))
+	r.mu.Unlock()
+	return c
+}
+
+// Unsubscribe stops the cursor from being notified about new lines.
+func (r *Ring) Unsubscribe(c *RingCursor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subs := r.subs.Load().([]*RingCursor)
+	out := make([]*RingCursor, 0, len(subs))
+	for _, s := range subs {
+		if s != c {
+			out = append(out, s)
+		}
+	}
+	r.subs.Store(out)
+}
+
+// Subscribers returns the number of cursors subscribed to the ring.
+func (r *Ring) Subscribers() int {
+	return len(r.subs.Load().([]*RingCursor))
+}
+
+// RingCursor is the position of a single subscriber in a Ring. A cursor must
+// only be used by a single goroutine.
+type RingCursor struct {
+	ring   *Ring
+	next   uint64
+	notify chan struct{}
+}
+
+// C returns a channel that receives a value when there are new lines to read.
+// Multiple lines being pushed may only result in a single value being sent.
+func (c *RingCursor) C() <-chan struct{} {
+	return c.notify
+}
+
+// Read appends the lines that have been pushed since the last read to buf and
+// returns it, along with the number of lines that were skipped because the
+// cursor fell too far behind.
+func (c *RingCursor) Read(buf []*RingEntry) ([]*RingEntry, uint64) {
+	size := uint64(len(c.ring.slots))
+	head := atomic.LoadUint64(&c.ring.head)
+	var skipped uint64
+	if head >= c.next+size {
+		skipped = head - size + 1 - c.next
+		c.next = head - size + 1
+	}
+	for c.next <= head {
+		e, _ := c.ring.slots[c.next%size].Load().(*RingEntry)
+		// The entry is still being written by another call to Push, the cursor
+		// will be notified once it has been.
+		if e == nil || e.Seq < c.next {
+			break
+		}
+		// The entry was replaced by a newer one after the head was loaded.
+		if e.Seq > c.next {
+			skipped++
+		} else {
+			buf = append(buf, e)
+		}
+		c.next++
+	}
+	return buf, skipped
+}
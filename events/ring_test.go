@@ -0,0 +1,83 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestRing(t *testing.T) {
+	g := Goblin(t)
+
+	g.Describe("Ring", func() {
+		var r *Ring
+		g.BeforeEach(func() {
+			r = NewRing(4)
+		})
+
+		g.It("only reads lines pushed after subscribing", func() {
+			r.Push([]byte("before"))
+			c := r.Subscribe()
+			r.Push([]byte("after"))
+
+			entries, skipped := c.Read(nil)
+			g.Assert(skipped).Equal(uint64(0))
+			g.Assert(len(entries)).Equal(1)
+			g.Assert(string(entries[0].Data)).Equal("after")
+
+			entries, _ = c.Read(nil)
+			g.Assert(len(entries)).Equal(0)
+		})
+
+		g.It("shares each line between subscribers", func() {
+			a, b := r.Subscribe(), r.Subscribe()
+			r.Push([]byte("line"))
+			ea, _ := a.Read(nil)
+			eb, _ := b.Read(nil)
+			g.Assert(ea[0] == eb[0]).IsTrue()
+
+			calls := 0
+			encode := func(data []byte) []byte {
+				calls++
+				return append([]byte("encoded "), data...)
+			}
+			g.Assert(string(ea[0].Encoded(encode))).Equal("encoded line")
+			g.Assert(string(eb[0].Encoded(encode))).Equal("encoded line")
+			g.Assert(calls).Equal(1)
+		})
+
+		g.It("skips lines once a subscriber falls behind", func() {
+			c := r.Subscribe()
+			for i := 0; i < 10; i++ {
+				r.Push([]byte(fmt.Sprint(i)))
+			}
+			entries, skipped := c.Read(nil)
+			g.Assert(skipped).Equal(uint64(6))
+			g.Assert(len(entries)).Equal(4)
+			g.Assert(string(entries[0].Data)).Equal("6")
+			g.Assert(string(entries[3].Data)).Equal("9")
+		})
+
+		g.It("notifies subscribers without blocking", func() {
+			c := r.Subscribe()
+			r.Push([]byte("a"))
+			r.Push([]byte("b"))
+			<-c.C()
+			select {
+			case <-c.C():
+				g.Fail("expected a single notification")
+			default:
+			}
+
+			r.Unsubscribe(c)
+			g.Assert(r.Subscribers()).Equal(0)
+			r.Push([]byte("c"))
+			select {
+			case <-c.C():
+				g.Fail("expected no notification after unsubscribing")
+			default:
+			}
+		})
+	})
+}
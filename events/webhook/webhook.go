@@ -0,0 +1,182 @@
+// Package webhook delivers notifications about node and server events to HTTP
+// endpoints configured by the node administrator, such as a Discord or Slack
+// incoming webhook.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/system"
+)
+
+// The events that are sent to webhook endpoints.
+const (
+	CrashDetectedEvent     = "crash detected"
+	OutOfMemoryEvent       = "out of memory"
+	InstallRolledBackEvent = "install rolled back"
+	BackupFailedEvent      = "backup failed"
+	DiskLimitExceededEvent = "disk limit exceeded"
+	TransferCompletedEvent = "transfer completed"
+	TransferIntegrityEvent = "transfer integrity"
+	ResourceAlertEvent     = "resource alert"
+	MirrorFailedEvent      = "mirror failed"
+	NodeDegradedEvent      = "node degraded"
+	NodeRecoveredEvent     = "node recovered"
+	OrphanedContainerEvent = "orphaned container"
+	ImageIncompatibleEvent = "image incompatible"
+	TaskFailedEvent        = "task failed"
+	NodeStartedEvent       = "node started"
+)
+
+// The body formats supported for webhook endpoints.
+const (
+	FormatJSON    = "json"
+	FormatDiscord = "discord"
+	FormatSlack   = "slack"
+)
+
+// retryDelay is the base amount of time to wait between delivery attempts, it is
+// doubled after every failed attempt.
+var retryDelay = time.Second
+
+// Payload is the body sent to endpoints using the "json" format.
+type Payload struct {
+	Event     string      `json:"event"`
+	Server    string      `json:"server,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// Dispatch sends the event to every configured endpoint that is subscribed to it.
+// Delivery happens in the background, so this function never blocks the caller.
+func Dispatch(server string, event string, data interface{}) {
+	c := config.Get().Webhooks
+	p := Payload{Event: event, Server: server, Data: data, Timestamp: time.Now().UTC()}
+	for _, e := range c.Endpoints {
+		if !wants(e, event) {
+			continue
+		}
+		go func(e config.WebhookConfiguration) {
+			if err := deliver(e, c.Retries, time.Duration(c.Timeout)*time.Second, p); err != nil {
+				log.WithFields(log.Fields{"event": event, "url": e.URL, "error": err}).Warn("failed to deliver webhook notification")
+			}
+		}(e)
+	}
+}
+
+// wants determines if the endpoint is subscribed to the given event.
+func wants(e config.WebhookConfiguration, event string) bool {
+	if e.URL == "" {
+		return false
+	}
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, v := range e.Events {
+		if v == event {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver sends the payload to the endpoint, retrying with an exponential backoff
+// when the request fails or the endpoint responds with a retryable status.
+func deliver(e config.WebhookConfiguration, retries int, timeout time.Duration, p Payload) error {
+	b, err := body(e.Format, p)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: timeout}
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		retry, err := send(client, e, p, b)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= retries {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// send performs a single delivery attempt, returning whether the request should
+// be attempted again if it failed.
+func send(client *http.Client, e config.WebhookConfiguration, p Payload, b []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, e.URL, bytes.NewReader(b))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("Pterodactyl Wings/v%s (id:%s)", system.Version, config.Get().AuthenticationTokenId))
+	req.Header.Set("X-Wings-Event", p.Event)
+	req.Header.Set("X-Wings-Timestamp", strconv.FormatInt(p.Timestamp.Unix(), 10))
+	if e.Secret != "" {
+		req.Header.Set("X-Wings-Signature", "sha256="+Sign(e.Secret, b))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return true, errors.WithStack(err)
+	}
+	res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+	err = errors.Errorf("webhook: endpoint responded with unexpected status code %d", res.StatusCode)
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500, err
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of the body using the secret.
+func Sign(secret string, b []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// body returns the encoded request body for the payload in the given format.
+func body(format string, p Payload) ([]byte, error) {
+	var v interface{}
+	switch format {
+	case FormatDiscord:
+		v = map[string]string{"content": message(p)}
+	case FormatSlack:
+		v = map[string]string{"text": message(p)}
+	case FormatJSON, "":
+		v = p
+	default:
+		return nil, errors.Errorf("webhook: unknown body format \"%s\"", format)
+	}
+	b, err := json.Marshal(v)
+	return b, errors.WithStack(err)
+}
+
+// message returns a human-readable description of the payload for use with chat
+// services.
+func message(p Payload) string {
+	m := fmt.Sprintf("Event \"%s\" occurred", p.Event)
+	if p.Server != "" {
+		m += fmt.Sprintf(" for server %s", p.Server)
+	}
+	if p.Data != nil {
+		if b, err := json.Marshal(p.Data); err == nil {
+			m += fmt.Sprintf(": %s", b)
+		}
+	}
+	return m
+}
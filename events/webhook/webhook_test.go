@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestDeliver(t *testing.T) {
+	g := Goblin(t)
+
+	g.Describe("Webhook", func() {
+		retryDelay = time.Millisecond
+		config.Set(&config.Configuration{AuthenticationToken: "test"})
+
+		p := Payload{Event: CrashDetectedEvent, Server: "abc", Timestamp: time.Now()}
+
+		g.It("signs the request body", func() {
+			var signature string
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				signature = r.Header.Get("X-Wings-Signature")
+				body, _ = io.ReadAll(r.Body)
+			}))
+			defer srv.Close()
+
+			err := deliver(config.WebhookConfiguration{URL: srv.URL, Secret: "secret"}, 0, time.Second, p)
+			g.Assert(err).IsNil()
+			g.Assert(signature).Equal("sha256=" + Sign("secret", body))
+		})
+
+		g.It("retries failed deliveries", func() {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) < 3 {
+					w.WriteHeader(http.StatusBadGateway)
+				}
+			}))
+			defer srv.Close()
+
+			err := deliver(config.WebhookConfiguration{URL: srv.URL}, 3, time.Second, p)
+			g.Assert(err).IsNil()
+			g.Assert(atomic.LoadInt32(&calls)).Equal(int32(3))
+		})
+
+		g.It("does not retry client errors", func() {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			defer srv.Close()
+
+			err := deliver(config.WebhookConfiguration{URL: srv.URL}, 3, time.Second, p)
+			g.Assert(err).IsNotNil()
+			g.Assert(atomic.LoadInt32(&calls)).Equal(int32(1))
+		})
+
+		g.It("only sends subscribed events", func() {
+			e := config.WebhookConfiguration{URL: "http://localhost", Events: []string{BackupFailedEvent}}
+			g.Assert(wants(e, BackupFailedEvent)).IsTrue()
+			g.Assert(wants(e, CrashDetectedEvent)).IsFalse()
+		})
+	})
+}
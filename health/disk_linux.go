@@ -0,0 +1,15 @@
+package health
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// diskSpace returns the free and total space in bytes of the filesystem containing
+// the given path. The free space is the space available to unprivileged users.
+func diskSpace(path string) (uint64, uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), st.Blocks * uint64(st.Bsize), nil
+}
@@ -0,0 +1,19 @@
+package health
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// diskSpace returns the free and total space in bytes of the volume containing the
+// given path. The free space is the space available to the user Wings runs as.
+func diskSpace(path string) (uint64, uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree); err != nil {
+		return 0, 0, err
+	}
+	return free, total, nil
+}
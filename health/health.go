@@ -0,0 +1,267 @@
+// Package health checks the dependencies Wings needs to operate, such as the
+// Docker daemon and the Panel, for use by load balancers and uptime monitors.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+// The status of a single check, or of the node as a whole.
+const (
+	StatusOk      = "ok"
+	StatusWarning = "warning"
+	StatusError   = "error"
+)
+
+// The maximum amount of time each check is allowed to take.
+const checkTimeout = time.Second * 5
+
+// The amount of clock skew from the Panel that results in a warning, and the
+// amount that results in an error since tokens issued by the Panel will be
+// rejected as expired or not yet valid.
+const (
+	clockSkewWarning = time.Second * 30
+	clockSkewError   = time.Minute * 5
+)
+
+// The percentage of free disk space below which a directory results in a warning
+// or an error.
+const (
+	diskFreeWarning = 10
+	diskFreeError   = 2
+)
+
+// Check is the result of checking a single dependency.
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	// The time taken to perform the check, in milliseconds.
+	Latency int64 `json:"latency_ms"`
+	// The tenant the check is for, only set for checks of a tenant's Panel.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// Report is the result of every check, the status of the report is the worst
+// status of any individual check.
+type Report struct {
+	Status string  `json:"status"`
+	Checks []Check `json:"checks"`
+}
+
+// Healthy returns false if any check resulted in an error.
+func (r Report) Healthy() bool {
+	return r.Status != StatusError
+}
+
+type checkFunc func(ctx context.Context) (string, string)
+
+// The amount of time the result returned by Cached is reused for before the
+// checks are performed again.
+const cacheDuration = time.Second * 10
+
+// runChecks performs the checks for Cached, this is replaced in tests.
+var runChecks = Run
+
+var cache struct {
+	mu     sync.Mutex
+	report Report
+	at     time.Time
+}
+
+// Cached returns the result of the last time every check was performed, running
+// them again if that was more than 10 seconds ago. Only one run is performed at
+// a time, so that unauthenticated requests cannot be used to flood Docker and
+// the Panel with checks.
+func Cached() Report {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.at.IsZero() || time.Since(cache.at) >= cacheDuration {
+		// The checks are not tied to the request that happened to trigger them, since
+		// a cancelled request would otherwise cache a failing report.
+		cache.report = runChecks(context.Background())
+		cache.at = time.Now()
+	}
+	report := cache.report
+	report.Checks = append([]Check(nil), cache.report.Checks...)
+	return report
+}
+
+// Run performs every check at the same time and returns the results.
+func Run(ctx context.Context) Report {
+	cfg := config.Get()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	report := Report{Status: StatusOk}
+	run := func(name string, tenant string, fn checkFunc) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			start := time.Now()
+			status, message := fn(ctx)
+			c := Check{Name: name, Status: status, Message: message, Latency: time.Since(start).Milliseconds(), Tenant: tenant}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks = append(report.Checks, c)
+			report.Status = worst(report.Status, status)
+		}()
+	}
+
+	run("docker", "", checkDocker)
+	run("panel", "", func(ctx context.Context) (string, string) {
+		status, message, skew := checkPanel(ctx, cfg.PanelLocation)
+		if status == StatusOk {
+			run("clock", "", func(context.Context) (string, string) {
+				return checkClockSkew(skew)
+			})
+		}
+		return status, message
+	})
+	for _, t := range cfg.Tenants {
+		location := t.PanelLocation
+		run("panel", t.Name, func(ctx context.Context) (string, string) {
+			status, message, _ := checkPanel(ctx, location)
+			return status, message
+		})
+	}
+	run("sftp", "", func(ctx context.Context) (string, string) {
+		return checkSftp(ctx, cfg.System.Sftp)
+	})
+	seen := make(map[string]bool)
+	for _, dir := range []string{
+		cfg.System.RootDirectory,
+		cfg.System.Data,
+		cfg.System.LogDirectory,
+		cfg.System.ArchiveDirectory,
+		cfg.System.BackupDirectory,
+		cfg.System.TmpDirectory,
+	} {
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		dir := dir
+		run("disk:"+dir, "", func(context.Context) (string, string) {
+			return checkDisk(dir)
+		})
+	}
+
+	wg.Wait()
+	sort.SliceStable(report.Checks, func(i, j int) bool {
+		if report.Checks[i].Name != report.Checks[j].Name {
+			return report.Checks[i].Name < report.Checks[j].Name
+		}
+		return report.Checks[i].Tenant < report.Checks[j].Tenant
+	})
+	return report
+}
+
+// worst returns the more severe of two statuses.
+func worst(a string, b string) string {
+	rank := map[string]int{StatusOk: 0, StatusWarning: 1, StatusError: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+func checkDocker(ctx context.Context) (string, string) {
+	cli, err := environment.Docker()
+	if err != nil {
+		return StatusError, err.Error()
+	}
+	if _, err := cli.Ping(ctx); err != nil {
+		return StatusError, errors.Wrap(err, "could not connect to the Docker daemon").Error()
+	}
+	return StatusOk, ""
+}
+
+// checkPanel makes a request to the Panel, any response means it is reachable.
+// The date returned by the Panel is used to determine the clock skew between
+// the node and the Panel.
+func checkPanel(ctx context.Context, location string) (string, string, time.Duration) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return StatusError, err.Error(), 0
+	}
+	start := time.Now()
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return StatusError, errors.Wrap(err, "could not connect to the Panel").Error(), 0
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusInternalServerError {
+		return StatusError, "the Panel responded with status " + strconv.Itoa(res.StatusCode), 0
+	}
+	date, err := http.ParseTime(res.Header.Get("Date"))
+	if err != nil {
+		return StatusOk, "", 0
+	}
+	// The Panel set the date roughly halfway through the request.
+	return StatusOk, "", start.Add(time.Since(start) / 2).Sub(date)
+}
+
+func checkClockSkew(skew time.Duration) (string, string) {
+	if skew < 0 {
+		skew = -skew
+	}
+	// The date sent by the Panel only has a resolution of one second.
+	message := fmt.Sprintf("the clock differs from the Panel by %s", skew.Round(time.Second))
+	switch {
+	case skew >= clockSkewError:
+		return StatusError, message
+	case skew >= clockSkewWarning:
+		return StatusWarning, message
+	}
+	return StatusOk, ""
+}
+
+// checkSftp connects to the SFTP server to confirm that it is listening.
+func checkSftp(ctx context.Context, sftp config.SftpConfiguration) (string, string) {
+	host := sftp.Address
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(sftp.Port)))
+	if err != nil {
+		return StatusError, errors.Wrap(err, "the SFTP server is not accepting connections").Error()
+	}
+	_ = conn.Close()
+	return StatusOk, ""
+}
+
+func checkDisk(dir string) (string, string) {
+	free, total, err := diskSpace(dir)
+	if err != nil {
+		return StatusError, errors.Wrap(err, "could not determine the free disk space").Error()
+	}
+	if total == 0 {
+		return StatusOk, ""
+	}
+	percent := float64(free) / float64(total) * 100
+	message := fmt.Sprintf("%.1f%% of the disk is free", percent)
+	switch {
+	case percent < diskFreeError:
+		return StatusError, message
+	case percent < diskFreeWarning:
+		return StatusWarning, message
+	}
+	return StatusOk, ""
+}
@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestHealth(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("worst", func() {
+		g.It("returns the more severe status", func() {
+			g.Assert(worst(StatusOk, StatusWarning)).Equal(StatusWarning)
+			g.Assert(worst(StatusError, StatusWarning)).Equal(StatusError)
+			g.Assert(worst(StatusOk, StatusOk)).Equal(StatusOk)
+		})
+	})
+
+	g.Describe("checkClockSkew", func() {
+		g.It("warns about and fails on large amounts of skew", func() {
+			s, _ := checkClockSkew(time.Second * 2)
+			g.Assert(s).Equal(StatusOk)
+			s, _ = checkClockSkew(-time.Minute)
+			g.Assert(s).Equal(StatusWarning)
+			s, m := checkClockSkew(time.Minute * 10)
+			g.Assert(s).Equal(StatusError)
+			g.Assert(m).Equal("the clock differs from the Panel by 10m0s")
+		})
+	})
+
+	g.Describe("checkSftp", func() {
+		g.It("connects to the SFTP server", func() {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			g.Assert(err).IsNil()
+			port := l.Addr().(*net.TCPAddr).Port
+
+			s, _ := checkSftp(context.Background(), config.SftpConfiguration{Address: "0.0.0.0", Port: port})
+			g.Assert(s).Equal(StatusOk)
+
+			_ = l.Close()
+			s, m := checkSftp(context.Background(), config.SftpConfiguration{Address: "127.0.0.1", Port: port})
+			g.Assert(s).Equal(StatusError)
+			g.Assert(m != "").IsTrue()
+		})
+	})
+
+	g.Describe("checkDisk", func() {
+		g.It("fails for a directory that does not exist", func() {
+			s, _ := checkDisk("/this/does/not/exist")
+			g.Assert(s).Equal(StatusError)
+		})
+	})
+	g.Describe("Cached", func() {
+		g.It("reuses the result of recent checks", func() {
+			var runs int
+			runChecks = func(context.Context) Report {
+				runs++
+				return Report{Status: StatusOk, Checks: []Check{{Name: "docker", Status: StatusOk}}}
+			}
+			defer func() { runChecks = Run }()
+
+			r := Cached()
+			g.Assert(r.Status).Equal(StatusOk)
+			r.Checks[0].Status = StatusError
+			g.Assert(Cached().Checks[0].Status).Equal(StatusOk)
+			g.Assert(runs).Equal(1)
+
+			cache.mu.Lock()
+			cache.at = time.Now().Add(-cacheDuration)
+			cache.mu.Unlock()
+			Cached()
+			g.Assert(runs).Equal(2)
+		})
+	})
+}
@@ -23,11 +23,24 @@ type ServerDetails struct {
 // have been passed along in the request. This should be manually run before
 // calling Execute().
 func New(ctx context.Context, manager *server.Manager, details ServerDetails) (*Installer, error) {
+	return NewForTenant(ctx, manager, "", details)
+}
+
+// NewForTenant works in the same way as New, but fetches the configuration for
+// the server from the Panel that the tenant belongs to and stores the server in
+// the tenant's data directory.
+func NewForTenant(ctx context.Context, manager *server.Manager, tenant string, details ServerDetails) (*Installer, error) {
 	if !govalidator.IsUUIDv4(details.UUID) {
 		return nil, NewValidationError("uuid provided was not in a valid format")
 	}
 
-	c, err := manager.Client().GetServerConfiguration(ctx, details.UUID)
+	// Never allow a Panel to take over a server that belongs to a different Panel
+	// registered with this node.
+	if _, ok := manager.Get(details.UUID); ok && manager.ServerTenant(details.UUID) != tenant {
+		return nil, NewValidationError("uuid provided is already in use by another panel")
+	}
+
+	c, err := manager.TenantClient(tenant).GetServerConfiguration(ctx, details.UUID)
 	if err != nil {
 		if !remote.IsRequestError(err) {
 			return nil, errors.WithStackIf(err)
@@ -37,7 +50,7 @@ func New(ctx context.Context, manager *server.Manager, details ServerDetails) (*
 
 	// Create a new server instance using the configuration we wrote to the disk
 	// so that everything gets instantiated correctly on the struct.
-	s, err := manager.InitServer(c)
+	s, err := manager.InitTenantServer(tenant, c)
 	if err != nil {
 		return nil, errors.WrapIf(err, "installer: could not init server instance")
 	}
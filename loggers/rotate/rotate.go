@@ -0,0 +1,232 @@
+// Package rotate implements rotation of log files within Wings itself, for
+// systems where logrotate is not available such as Windows. The active file is
+// rotated once it reaches a maximum size, and rotated files are compressed and
+// removed once they are older than the maximum age.
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+)
+
+// The format of the timestamp added to the name of rotated files.
+const timeFormat = "20060102T150405"
+
+// Options controls when a file is rotated and how long rotated files are kept.
+type Options struct {
+	// The size in bytes at which the file is rotated, zero disables rotation.
+	MaxSize int64
+	// The maximum age of rotated files, and the maximum number of rotated files
+	// that are kept. Zero keeps rotated files forever.
+	MaxAge     time.Duration
+	MaxBackups int
+	// Whether rotated files are compressed using gzip.
+	Compress bool
+}
+
+// File is a log file that is rotated once it reaches the maximum size. It is
+// safe to write to a File from multiple goroutines.
+type File struct {
+	mu   sync.Mutex
+	path string
+	opts Options
+	f    *os.File
+	size int64
+}
+
+var _ io.WriteCloser = (*File)(nil)
+
+// Open opens the log file at the path for appending, creating it if it does
+// not exist.
+func Open(path string, opts Options) (*File, error) {
+	f := &File{path: path, opts: opts}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "rotate: failed to open log file")
+	}
+	st, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return errors.Wrap(err, "rotate: failed to stat log file")
+	}
+	f.f = file
+	f.size = st.Size()
+	return nil
+}
+
+// Write writes to the log file, rotating it first if the write would cause it
+// to exceed the maximum size.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.opts.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.opts.MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.f.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate moves the current file aside and starts a new one.
+func (f *File) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rotate()
+}
+
+// rotate closes the active file before renaming it, since an open file cannot
+// be renamed on Windows, and then opens a new file in its place. Rotated files
+// are compressed and pruned in the background.
+func (f *File) rotate() error {
+	if err := f.f.Close(); err != nil {
+		return errors.Wrap(err, "rotate: failed to close log file")
+	}
+	rotated := f.rotatedName(time.Now())
+	if err := os.Rename(f.path, rotated); err != nil {
+		// Keep writing to the existing file if it could not be moved.
+		if oerr := f.open(); oerr != nil {
+			return oerr
+		}
+		return errors.Wrap(err, "rotate: failed to rename log file")
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	go func() {
+		if f.opts.Compress {
+			_ = Compress(rotated)
+		}
+		_ = f.Prune()
+	}()
+	return nil
+}
+
+// rotatedName returns the path that the active file is moved to when it is
+// rotated at the given time. The timestamp only has a resolution of one second,
+// so a counter is added when the file has already been rotated within the same
+// second, rather than overwriting the previously rotated file.
+func (f *File) rotatedName(at time.Time) string {
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext) + "-" + at.Format(timeFormat)
+	for i := 0; ; i++ {
+		p := base + ext
+		if i > 0 {
+			p = base + "-" + strconv.Itoa(i) + ext
+		}
+		if !exists(p) && !exists(p+".gz") {
+			return p
+		}
+	}
+}
+
+// Prune removes the rotated files that are older than the maximum age, or that
+// are beyond the maximum number of rotated files.
+func (f *File) Prune() error {
+	ext := filepath.Ext(f.path)
+	prefix := filepath.Base(strings.TrimSuffix(f.path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return errors.Wrap(err, "rotate: failed to read log directory")
+	}
+
+	type backup struct {
+		name string
+		at   time.Time
+		seq  int
+	}
+	var backups []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		var seq int
+		if i := strings.IndexByte(ts, '-'); i >= 0 {
+			if seq, err = strconv.Atoi(ts[i+1:]); err != nil {
+				continue
+			}
+			ts = ts[:i]
+		}
+		at, err := time.ParseInLocation(timeFormat, ts, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{name: name, at: at, seq: seq})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].at.Equal(backups[j].at) {
+			return backups[i].seq > backups[j].seq
+		}
+		return backups[i].at.After(backups[j].at)
+	})
+
+	for i, b := range backups {
+		expired := f.opts.MaxAge > 0 && time.Since(b.at) > f.opts.MaxAge
+		if expired || (f.opts.MaxBackups > 0 && i >= f.opts.MaxBackups) {
+			if err := os.Remove(filepath.Join(filepath.Dir(f.path), b.name)); err != nil && !os.IsNotExist(err) {
+				return errors.Wrap(err, "rotate: failed to remove rotated log file")
+			}
+		}
+	}
+	return nil
+}
+
+// Close closes the active file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.f.Close()
+}
+
+// Compress writes a gzip compressed copy of the file and removes the original.
+func Compress(p string) error {
+	in, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(p+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	gw := gzip.NewWriter(out)
+	if _, err := io.Copy(gw, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(out.Name())
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(out.Name())
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	_ = in.Close()
+	return os.Remove(p)
+}
+
+func exists(p string) bool {
+	_, err := os.Lstat(p)
+	return err == nil
+}
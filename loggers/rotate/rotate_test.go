@@ -0,0 +1,102 @@
+package rotate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+)
+
+func TestFile(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("File", func() {
+		var dir string
+		g.BeforeEach(func() {
+			dir = t.TempDir()
+		})
+
+		g.It("rotates the file once it reaches the maximum size", func() {
+			f, err := Open(filepath.Join(dir, "wings.log"), Options{MaxSize: 10})
+			g.Assert(err).IsNil()
+			defer f.Close()
+
+			_, err = f.Write([]byte("12345678\n"))
+			g.Assert(err).IsNil()
+			_, err = f.Write([]byte("abc\n"))
+			g.Assert(err).IsNil()
+
+			b, err := os.ReadFile(filepath.Join(dir, "wings.log"))
+			g.Assert(err).IsNil()
+			g.Assert(string(b)).Equal("abc\n")
+
+			matches, _ := filepath.Glob(filepath.Join(dir, "wings-*.log"))
+			g.Assert(len(matches)).Equal(1)
+		})
+
+		g.It("does not overwrite files rotated within the same second", func() {
+			f, err := Open(filepath.Join(dir, "wings.log"), Options{})
+			g.Assert(err).IsNil()
+			defer f.Close()
+
+			for _, line := range []string{"first\n", "second\n", "third\n"} {
+				_, err = f.Write([]byte(line))
+				g.Assert(err).IsNil()
+				g.Assert(f.Rotate()).IsNil()
+			}
+
+			matches, _ := filepath.Glob(filepath.Join(dir, "wings-*.log"))
+			g.Assert(len(matches)).Equal(3)
+		})
+
+		g.It("keeps the most recent files rotated within the same second", func() {
+			ts := time.Now().Add(-time.Hour).Format(timeFormat)
+			for _, name := range []string{"wings-" + ts + ".log", "wings-" + ts + "-1.log.gz", "wings-" + ts + "-2.log"} {
+				g.Assert(os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o644)).IsNil()
+			}
+
+			f, err := Open(filepath.Join(dir, "wings.log"), Options{MaxBackups: 2})
+			g.Assert(err).IsNil()
+			defer f.Close()
+			g.Assert(f.Prune()).IsNil()
+
+			_, err = os.Stat(filepath.Join(dir, "wings-"+ts+".log"))
+			g.Assert(os.IsNotExist(err)).IsTrue()
+			matches, _ := filepath.Glob(filepath.Join(dir, "wings-*"))
+			g.Assert(len(matches)).Equal(2)
+		})
+
+		g.It("removes rotated files beyond the limits", func() {
+			now := time.Now()
+			for _, at := range []time.Time{now.Add(-time.Hour), now.Add(-time.Hour * 2), now.Add(-time.Hour * 72)} {
+				name := filepath.Join(dir, "wings-"+at.Format(timeFormat)+".log.gz")
+				g.Assert(os.WriteFile(name, []byte("x"), 0o644)).IsNil()
+			}
+
+			f, err := Open(filepath.Join(dir, "wings.log"), Options{MaxAge: time.Hour * 48, MaxBackups: 1})
+			g.Assert(err).IsNil()
+			defer f.Close()
+			g.Assert(f.Prune()).IsNil()
+
+			matches, _ := filepath.Glob(filepath.Join(dir, "wings-*"))
+			g.Assert(len(matches)).Equal(1)
+			g.Assert(strings.Contains(matches[0], now.Add(-time.Hour).Format(timeFormat))).IsTrue()
+		})
+	})
+
+	g.Describe("Compress", func() {
+		g.It("replaces the file with a compressed copy", func() {
+			p := filepath.Join(t.TempDir(), "wings-20220101T000000.log")
+			g.Assert(os.WriteFile(p, []byte("hello"), 0o644)).IsNil()
+			g.Assert(Compress(p)).IsNil()
+
+			_, err := os.Stat(p)
+			g.Assert(os.IsNotExist(err)).IsTrue()
+			_, err = os.Stat(p + ".gz")
+			g.Assert(err).IsNil()
+		})
+	})
+}
@@ -0,0 +1,109 @@
+// Package netguard provides an HTTP client for requests made to URLs that are
+// passed to Wings, such as backup download links and mirror remotes, which will
+// not connect to addresses within the local network of the node.
+package netguard
+
+import (
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// ErrInternalAddress is returned when connecting to an address within the local
+// network of the node that is not in the allowed_internal_networks configuration.
+var ErrInternalAddress = errors.Sentinel("netguard: destination resolves to an internal network address")
+
+// The IP ranges that are considered to be within the local network of the node.
+var internalRanges = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("10.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("127.0.0.0/8"),
+	mustParseCIDR("169.254.0.0/16"),
+	mustParseCIDR("172.16.0.0/12"),
+	mustParseCIDR("192.168.0.0/16"),
+	mustParseCIDR("::/128"),
+	mustParseCIDR("::1/128"),
+	mustParseCIDR("fc00::/7"),
+	mustParseCIDR("fe80::/10"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, block, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return block
+}
+
+// IsInternal returns true if the IP address is within the local network, such
+// as a loopback, link-local or private address.
+func IsInternal(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, block := range internalRanges {
+		if block.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed returns true if the IP address is not within the local network, or is
+// within one of the networks that Wings is configured to allow.
+func allowed(ip net.IP) bool {
+	if !IsInternal(ip) {
+		return true
+	}
+	for _, n := range config.Get().AllowedInternalNetworks {
+		if _, block, err := net.ParseCIDR(n); err == nil && block.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dialer returns a dialer that refuses to connect to internal addresses. The
+// address is checked after it has been resolved, immediately before connecting,
+// so that a hostname cannot resolve to a different address once it is checked.
+func Dialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !allowed(ip) {
+				return errors.WithStack(ErrInternalAddress)
+			}
+			return nil
+		},
+	}
+}
+
+// Client returns an HTTP client that refuses to connect to internal addresses.
+// Proxies configured in the environment are not used, since the address of the
+// destination would not be checked when connecting through them. A timeout of
+// zero means requests do not time out.
+func Client(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           Dialer().DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package netguard
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"emperror.dev/errors"
+	. "github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestNetguard(t *testing.T) {
+	g := Goblin(t)
+
+	g.Describe("IsInternal", func() {
+		g.It("returns true for internal addresses", func() {
+			for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "::1", "fe80::1", "fd00::1", "0.0.0.0"} {
+				g.Assert(IsInternal(net.ParseIP(ip))).IsTrue(ip)
+			}
+		})
+
+		g.It("returns false for public addresses", func() {
+			for _, ip := range []string{"1.1.1.1", "172.32.0.1", "2606:4700:4700::1111"} {
+				g.Assert(IsInternal(net.ParseIP(ip))).IsFalse(ip)
+			}
+		})
+	})
+
+	g.Describe("Client", func() {
+		var srv *httptest.Server
+
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token"})
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+		})
+
+		g.AfterEach(func() {
+			srv.Close()
+		})
+
+		g.It("refuses to connect to an internal address", func() {
+			_, err := Client(0).Get(srv.URL)
+			g.Assert(err).IsNotNil()
+			g.Assert(errors.Is(err, ErrInternalAddress)).IsTrue()
+		})
+
+		g.It("connects to an internal address in an allowed network", func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token", AllowedInternalNetworks: []string{"127.0.0.0/8"}})
+
+			res, err := Client(0).Get(srv.URL)
+			g.Assert(err).IsNil()
+			_ = res.Body.Close()
+			g.Assert(res.StatusCode).Equal(http.StatusNoContent)
+		})
+	})
+}
@@ -0,0 +1,56 @@
+package remote
+
+import (
+	"context"
+)
+
+// Heartbeat is the status of the node that is periodically reported to the
+// Panel, allowing it to show the health of the node.
+type Heartbeat struct {
+	Version string `json:"version"`
+	// The number of seconds that Wings has been running for.
+	Uptime int64 `json:"uptime"`
+	// The number of servers belonging to the Panel on the node, and the number of
+	// them that are currently running.
+	Servers        int `json:"servers"`
+	RunningServers int `json:"running_servers"`
+	// The resources of the node that can still be allocated to servers.
+	Headroom HeartbeatHeadroom `json:"headroom"`
+	// The operations belonging to the Panel that are in progress on the node.
+	Pending HeartbeatOperations `json:"pending"`
+}
+
+// HeartbeatHeadroom is the amount of each resource that can still be allocated
+// to servers. Memory and disk are in megabytes, and CPU is a percentage where
+// 100 is a single core. The values are negative if the node is overcommitted.
+type HeartbeatHeadroom struct {
+	Memory int64 `json:"memory"`
+	Cpu    int64 `json:"cpu"`
+	Disk   int64 `json:"disk"`
+}
+
+// HeartbeatOperations is the number of each type of operation in progress.
+type HeartbeatOperations struct {
+	Installs  int `json:"installs"`
+	Restores  int `json:"restores"`
+	Transfers int `json:"transfers"`
+	Backups   int `json:"backups"`
+}
+
+// HeartbeatClient is implemented by clients that are able to report the status
+// of the node to the Panel.
+type HeartbeatClient interface {
+	SendHeartbeat(ctx context.Context, h Heartbeat) error
+}
+
+var _ HeartbeatClient = (*client)(nil)
+
+// SendHeartbeat reports the current status of the node to the Panel.
+func (c *client) SendHeartbeat(ctx context.Context, h Heartbeat) error {
+	resp, err := c.Post(ctx, "/heartbeat", h)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
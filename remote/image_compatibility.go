@@ -0,0 +1,36 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+)
+
+// IncompatibleImageReport is sent to the Panel when the image for a server
+// cannot run on the node, such as a Linux image on a node running Windows
+// containers.
+type IncompatibleImageReport struct {
+	Image  string `json:"image"`
+	Reason string `json:"reason"`
+	// The action that failed because of the image, either "start" or "install".
+	Action string `json:"action"`
+}
+
+// IncompatibleImageClient is implemented by clients that are able to report an
+// incompatible image to the Panel, so that the reason can be shown rather than
+// a generic start or installation failure.
+type IncompatibleImageClient interface {
+	ReportIncompatibleImage(ctx context.Context, uuid string, r IncompatibleImageReport) error
+}
+
+var _ IncompatibleImageClient = (*client)(nil)
+
+// ReportIncompatibleImage notifies the Panel that the image for the server cannot
+// run on the node.
+func (c *client) ReportIncompatibleImage(ctx context.Context, uuid string, r IncompatibleImageReport) error {
+	resp, err := c.Post(ctx, fmt.Sprintf("/servers/%s/image-incompatible", uuid), r)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,37 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+)
+
+// InstallationStatus is the result of an installation process for a server.
+type InstallationStatus struct {
+	Successful bool `json:"successful"`
+	// Whether the server files were restored to the state they were in before
+	// the installation started, after it failed.
+	RolledBack bool `json:"rolled_back"`
+	// The exit code of the installation script if it exited with a non-zero code.
+	ExitCode *int64 `json:"exit_code,omitempty"`
+}
+
+// InstallationStatusClient is implemented by clients that are able to report
+// the full result of an installation to the Panel, rather than only whether it
+// was successful.
+type InstallationStatusClient interface {
+	SendInstallationStatus(ctx context.Context, uuid string, status InstallationStatus) error
+}
+
+var _ InstallationStatusClient = (*client)(nil)
+
+// SendInstallationStatus notifies the Panel that the installation process for
+// the server has completed, including whether the server files were rolled back
+// after it failed.
+func (c *client) SendInstallationStatus(ctx context.Context, uuid string, status InstallationStatus) error {
+	resp, err := c.Post(ctx, fmt.Sprintf("/servers/%s/install", uuid), status)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,34 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+)
+
+// OutOfMemoryReport is sent to the Panel when a process within a server container
+// is killed for exceeding the memory limit of the server.
+type OutOfMemoryReport struct {
+	// The memory limit of the server in megabytes.
+	MemoryLimit int64 `json:"memory_limit"`
+	// The number of times the server has run out of memory since it was started.
+	Count int `json:"count"`
+}
+
+// OutOfMemoryClient is implemented by clients that are able to report out of
+// memory kills to the Panel, so that they can be shown separately from crashes.
+type OutOfMemoryClient interface {
+	ReportOutOfMemory(ctx context.Context, uuid string, r OutOfMemoryReport) error
+}
+
+var _ OutOfMemoryClient = (*client)(nil)
+
+// ReportOutOfMemory notifies the Panel that a process for the server was killed
+// for exceeding the memory limit.
+func (c *client) ReportOutOfMemory(ctx context.Context, uuid string, r OutOfMemoryReport) error {
+	resp, err := c.Post(ctx, fmt.Sprintf("/servers/%s/out-of-memory", uuid), r)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
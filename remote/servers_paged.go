@@ -0,0 +1,12 @@
+package remote
+
+import (
+	"context"
+)
+
+// GetServersPaged returns a single page of the servers belonging to the node,
+// along with the pagination details, so that the servers on each page can be
+// loaded while the remaining pages are being fetched.
+func (c *client) GetServersPaged(ctx context.Context, page, limit int) ([]RawServerData, Pagination, error) {
+	return c.getServersPaged(ctx, page, limit)
+}
@@ -0,0 +1,141 @@
+package transport
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	uuidSegmentRegex    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	numericSegmentRegex = regexp.MustCompile(`^\d+$`)
+)
+
+var (
+	registryMu sync.Mutex
+	registry   []*Transport
+)
+
+// PanelMetrics is a snapshot of the metrics for the requests made to a single
+// Panel.
+type PanelMetrics struct {
+	// The name of the tenant the Panel belongs to, empty for the primary Panel.
+	Name      string            `json:"name"`
+	Circuit   string            `json:"circuit"`
+	Endpoints []EndpointMetrics `json:"endpoints"`
+}
+
+// EndpointMetrics is a snapshot of the metrics for the requests made to a
+// single endpoint on the Panel. Latencies are in milliseconds, and every
+// attempt at making a request is counted separately.
+type EndpointMetrics struct {
+	Endpoint       string  `json:"endpoint"`
+	Requests       uint64  `json:"requests"`
+	Errors         uint64  `json:"errors"`
+	AverageLatency float64 `json:"average_latency"`
+	MaxLatency     float64 `json:"max_latency"`
+	LastError      string  `json:"last_error,omitempty"`
+}
+
+// Metrics records the latency and errors for every endpoint called on a
+// Panel.
+type Metrics struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpoint
+}
+
+type endpoint struct {
+	requests  uint64
+	errors    uint64
+	total     time.Duration
+	max       time.Duration
+	lastError string
+}
+
+func newMetrics() *Metrics {
+	return &Metrics{endpoints: make(map[string]*endpoint)}
+}
+
+// register tracks the transport so that its metrics are returned by Snapshot.
+func register(t *Transport) {
+	registryMu.Lock()
+	registry = append(registry, t)
+	registryMu.Unlock()
+}
+
+// Snapshot returns the current metrics for every Panel that a transport has
+// been created for.
+func Snapshot() []PanelMetrics {
+	registryMu.Lock()
+	transports := append([]*Transport{}, registry...)
+	registryMu.Unlock()
+
+	out := make([]PanelMetrics, 0, len(transports))
+	for _, t := range transports {
+		out = append(out, PanelMetrics{
+			Name:      t.name,
+			Circuit:   t.breaker.state(),
+			Endpoints: t.metrics.snapshot(),
+		})
+	}
+	return out
+}
+
+// record tracks the result of a single attempt at making the request.
+func (m *Metrics) record(req *http.Request, latency time.Duration, err error) {
+	key := endpointName(req)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.endpoints[key]
+	if !ok {
+		e = &endpoint{}
+		m.endpoints[key] = e
+	}
+	e.requests++
+	e.total += latency
+	if latency > e.max {
+		e.max = latency
+	}
+	if err != nil {
+		e.errors++
+		e.lastError = err.Error()
+	}
+}
+
+func (m *Metrics) snapshot() []EndpointMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]EndpointMetrics, 0, len(m.endpoints))
+	for k, e := range m.endpoints {
+		out = append(out, EndpointMetrics{
+			Endpoint:       k,
+			Requests:       e.requests,
+			Errors:         e.errors,
+			AverageLatency: float64(e.total.Microseconds()) / float64(e.requests) / 1000,
+			MaxLatency:     float64(e.max.Microseconds()) / 1000,
+			LastError:      e.lastError,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Endpoint < out[j].Endpoint
+	})
+	return out
+}
+
+// endpointName returns the name the metrics for the request are grouped under,
+// which is the method and path with any identifiers replaced so that requests
+// for different servers are grouped together.
+func endpointName(req *http.Request) string {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i, s := range segments {
+		if uuidSegmentRegex.MatchString(s) {
+			segments[i] = ":uuid"
+		} else if numericSegmentRegex.MatchString(s) {
+			segments[i] = ":id"
+		}
+	}
+	return req.Method + " /" + strings.Join(segments, "/")
+}
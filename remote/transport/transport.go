@@ -0,0 +1,275 @@
+// Package transport provides the HTTP transport used for requests made to the
+// Panel, which retries failed requests, stops sending requests while the Panel
+// is unavailable, and records metrics for every endpoint that is called.
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// ErrCircuitOpen is returned for requests that are not sent to the Panel since
+// too many of the previous requests to it have failed.
+var ErrCircuitOpen = errors.Sentinel("transport: circuit breaker is open, the panel is unavailable")
+
+// Transport is an http.RoundTripper that wraps another round tripper with
+// retries, a circuit breaker, and per-endpoint metrics.
+type Transport struct {
+	name    string
+	base    http.RoundTripper
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+	breaker *breaker
+	metrics *Metrics
+}
+
+// New returns a new transport for the Panel with the given name, which is the
+// name of the tenant the Panel belongs to or an empty string for the primary
+// Panel. The transport is registered so that its metrics can be retrieved
+// using Snapshot.
+func New(name string, base http.RoundTripper, c config.RemoteQueryConfiguration) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{
+		name:    name,
+		base:    base,
+		timeout: time.Second * time.Duration(c.Timeout),
+		retries: c.Retries,
+		backoff: time.Millisecond * time.Duration(c.RetryBackoff),
+		breaker: newBreaker(c.CircuitBreakerThreshold, time.Second*time.Duration(c.CircuitBreakerCooldown)),
+		metrics: newMetrics(),
+	}
+	register(t)
+	return t
+}
+
+// NewClient returns an HTTP client for making requests to the Panel with the
+// given name, using the remote query settings from the configuration. The
+// timeout is applied to each attempt by the transport rather than the client
+// so that retries are not cut short.
+func NewClient(name string) *http.Client {
+	return &http.Client{Transport: New(name, nil, config.Get().RemoteQuery)}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of the context with the ID of the request to
+// Wings that it belongs to. Requests made to the Panel using the context include
+// the ID in the X-Request-Id header, so that they can be matched up with the
+// logs for the request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request to Wings that the context belongs to,
+// or an empty string if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RoundTrip sends the request to the Panel, retrying it if it fails because of
+// a network error or a 5xx response. Requests with a body are only retried if
+// the body can be read again.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.allow() {
+		t.metrics.record(req, 0, ErrCircuitOpen)
+		return nil, ErrCircuitOpen
+	}
+	if id := RequestID(req.Context()); id != "" && req.Header.Get("X-Request-Id") == "" {
+		// A round tripper must not modify the request it is given.
+		req = req.Clone(req.Context())
+		req.Header.Set("X-Request-Id", id)
+	}
+
+	// Requests with a body that cannot be read again are never retried.
+	retries := t.retries
+	if retries < 0 || req.Body != nil && req.GetBody == nil {
+		retries = 0
+	}
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = t.backoff
+	b.Multiplier = 2
+	b.MaxElapsedTime = 0
+
+	var attempt int
+	var res *http.Response
+	err := backoff.Retry(func() error {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return backoff.Permanent(errors.WithStackIf(err))
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		if attempt > 0 && !t.breaker.allow() {
+			return backoff.Permanent(ErrCircuitOpen)
+		}
+		attempt++
+
+		var err error
+		res, err = t.attempt(r)
+		if err != nil && errors.Is(err, context.Canceled) {
+			// The request was canceled by the caller, which says nothing about the
+			// health of the Panel.
+			t.breaker.release()
+			return backoff.Permanent(err)
+		}
+		t.breaker.done(err == nil && res.StatusCode < http.StatusInternalServerError)
+		if err != nil {
+			return err
+		}
+		if res.StatusCode >= http.StatusInternalServerError || res.StatusCode == http.StatusTooManyRequests {
+			// The response is returned as-is once there are no retries remaining so
+			// that the caller is able to handle the error returned by the Panel.
+			if attempt > retries {
+				return nil
+			}
+			// Discard the body so that the connection can be reused for the next attempt.
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+			res = nil
+			return errors.New("transport: panel returned an error response")
+		}
+		return nil
+	}, backoff.WithContext(backoff.WithMaxRetries(b, uint64(retries)), req.Context()))
+	if err != nil {
+		if res != nil {
+			_ = res.Body.Close()
+		}
+		return nil, err
+	}
+	if attempt > 1 {
+		log.WithField("panel", t.name).WithField("attempts", attempt).Debugf("request to %s succeeded after retrying", req.URL.Path)
+	}
+	return res, nil
+}
+
+// attempt makes a single attempt at sending the request, applying the timeout
+// until the body of the response has been closed.
+func (t *Transport) attempt(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	cancel := context.CancelFunc(func() {})
+	if t.timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(req.Context(), t.timeout)
+		req = req.WithContext(ctx)
+	}
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		cancel()
+		t.metrics.record(req, time.Since(start), err)
+		return nil, err
+	}
+	t.metrics.record(req, time.Since(start), statusError(res.StatusCode))
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// statusError returns an error for responses that indicate the request failed,
+// including those where the Panel is rate limiting requests.
+func statusError(code int) error {
+	if code >= http.StatusInternalServerError || code == http.StatusTooManyRequests {
+		return errors.New(http.StatusText(code))
+	}
+	return nil
+}
+
+// cancelBody cancels the context for the request once the response body has
+// been closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// The possible states for the circuit breaker.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// breaker stops requests from being sent to the Panel once the threshold of
+// consecutive failures is reached. Once the cooldown has passed a single
+// request is allowed through, which closes the circuit again if it succeeds.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow returns true if a request can be sent to the Panel.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// done records the result of a request sent to the Panel.
+func (b *breaker) done(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// release allows another request through after a request was canceled before
+// the Panel responded to it.
+func (b *breaker) release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// state returns the current state of the circuit.
+func (b *breaker) state() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return CircuitClosed
+	}
+	if b.probing || time.Since(b.openedAt) >= b.cooldown {
+		return CircuitHalfOpen
+	}
+	return CircuitOpen
+}
@@ -0,0 +1,106 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestTransport(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("RoundTrip", func() {
+		var calls int32
+		var failures int32
+		var requestId atomic.Value
+		var srv *httptest.Server
+		var client *http.Client
+		var tr *Transport
+
+		g.BeforeEach(func() {
+			atomic.StoreInt32(&calls, 0)
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestId.Store(r.Header.Get("X-Request-Id"))
+				if atomic.AddInt32(&calls, 1) <= atomic.LoadInt32(&failures) {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			tr = New("", nil, config.RemoteQueryConfiguration{
+				Timeout:                 5,
+				Retries:                 2,
+				RetryBackoff:            1,
+				CircuitBreakerThreshold: 3,
+				CircuitBreakerCooldown:  60,
+			})
+			client = &http.Client{Transport: tr}
+		})
+
+		g.AfterEach(func() {
+			srv.Close()
+		})
+
+		g.It("retries requests that fail with a 5xx error", func() {
+			atomic.StoreInt32(&failures, 2)
+			res, err := client.Get(srv.URL + "/api/remote/servers/8d1d2f2c-7f39-4d4f-9f4e-4b6a3c5e8a10")
+			g.Assert(err).IsNil()
+			g.Assert(res.StatusCode).Equal(http.StatusOK)
+			_ = res.Body.Close()
+			g.Assert(atomic.LoadInt32(&calls)).Equal(int32(3))
+
+			m := tr.metrics.snapshot()
+			g.Assert(len(m)).Equal(1)
+			g.Assert(m[0].Endpoint).Equal("GET /api/remote/servers/:uuid")
+			g.Assert(m[0].Requests).Equal(uint64(3))
+			g.Assert(m[0].Errors).Equal(uint64(2))
+		})
+
+		g.It("returns the error response once out of retries", func() {
+			atomic.StoreInt32(&failures, 10)
+			res, err := client.Get(srv.URL)
+			g.Assert(err).IsNil()
+			g.Assert(res.StatusCode).Equal(http.StatusBadGateway)
+			_ = res.Body.Close()
+			g.Assert(atomic.LoadInt32(&calls)).Equal(int32(3))
+		})
+
+		g.It("retries without waiting when there is no backoff", func() {
+			atomic.StoreInt32(&failures, 1)
+			tr.backoff = 0
+			res, err := client.Get(srv.URL)
+			g.Assert(err).IsNil()
+			g.Assert(res.StatusCode).Equal(http.StatusOK)
+			_ = res.Body.Close()
+			g.Assert(atomic.LoadInt32(&calls)).Equal(int32(2))
+		})
+
+		g.It("stops sending requests once the circuit is open", func() {
+			atomic.StoreInt32(&failures, 10)
+			res, err := client.Get(srv.URL)
+			g.Assert(err).IsNil()
+			_ = res.Body.Close()
+			g.Assert(tr.breaker.state()).Equal(CircuitOpen)
+
+			_, err = client.Get(srv.URL)
+			g.Assert(err).IsNotNil()
+			g.Assert(atomic.LoadInt32(&calls)).Equal(int32(3))
+		})
+
+		g.It("sends the request ID from the context", func() {
+			atomic.StoreInt32(&failures, 0)
+			req, _ := http.NewRequestWithContext(WithRequestID(context.Background(), "5b8c1e0a-request"), http.MethodGet, srv.URL, nil)
+			res, err := client.Do(req)
+			g.Assert(err).IsNil()
+			_ = res.Body.Close()
+			g.Assert(requestId.Load()).Equal("5b8c1e0a-request")
+			g.Assert(req.Header.Get("X-Request-Id")).Equal("")
+		})
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/pterodactyl/wings/router/middleware"
 	"github.com/pterodactyl/wings/server"
 	"github.com/pterodactyl/wings/server/filesystem"
 )
@@ -78,39 +79,38 @@ func (e *RequestError) AbortWithStatus(status int, c *gin.Context) {
 	// If this error is because the resource does not exist, we likely do not need to log
 	// the error anywhere, just return a 404 and move on with our lives.
 	if errors.Is(e.err, os.ErrNotExist) {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"error": "The requested resource was not found on the system.",
-		})
+		middleware.AbortWithError(c, http.StatusNotFound, middleware.ErrCodeNotFound, "The requested resource was not found on the system.")
 		return
 	}
 
 	if strings.HasPrefix(e.err.Error(), "invalid URL escape") {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-			"error": "Some of the data provided in the request appears to be escaped improperly.",
-		})
+		middleware.AbortWithError(c, http.StatusBadRequest, middleware.ErrCodeBadFormat, "Some of the data provided in the request appears to be escaped improperly.")
 		return
 	}
 
 	// If this is a Filesystem error just return it without all of the tracking code nonsense
 	// since we don't need to be logging it into the logs or anything, its just a normal error
 	// that the user can solve on their end.
-	if st, msg := e.getAsFilesystemError(); st != 0 {
-		c.AbortWithStatusJSON(st, gin.H{"error": msg})
+	if st, code, msg := e.getAsFilesystemError(); st != 0 {
+		middleware.AbortWithError(c, st, code, msg)
 		return
 	}
 
 	// Otherwise, log the error to zap, and then report the error back to the user.
+	logger := e.logger().WithField("request_id", middleware.RequestID(c))
 	if status >= 500 {
-		e.logger().Error("unexpected error while handling HTTP request")
+		logger.Error("unexpected error while handling HTTP request")
 	} else {
-		e.logger().Debug("non-server error encountered while handling HTTP request")
+		logger.Debug("non-server error encountered while handling HTTP request")
 	}
 
 	if e.message == "" {
 		e.message = "An unexpected error was encountered while processing this request."
 	}
 
-	c.AbortWithStatusJSON(status, gin.H{"error": e.message, "error_id": e.uuid})
+	body := middleware.ErrorBody(c, middleware.StatusErrorCode(status), e.message)
+	body["error_id"] = e.uuid
+	c.AbortWithStatusJSON(status, body)
 }
 
 // Helper function to just abort with an internal server error. This is generally the response
@@ -121,29 +121,35 @@ func (e *RequestError) Abort(c *gin.Context) {
 
 // Looks at the given RequestError and determines if it is a specific filesystem error that
 // we can process and return differently for the user.
-func (e *RequestError) getAsFilesystemError() (int, string) {
+func (e *RequestError) getAsFilesystemError() (int, middleware.ErrorCode, string) {
 	// Some external things end up calling fmt.Errorf() on our filesystem errors
 	// which ends up just unleashing chaos on the system. For the sake of this
 	// fallback to using text checks...
 	if filesystem.IsErrorCode(e.err, filesystem.ErrCodeDenylistFile) || strings.Contains(e.err.Error(), "filesystem: file access prohibited") {
-		return http.StatusForbidden, "This file cannot be modified: present in egg denylist."
+		return http.StatusForbidden, middleware.ErrorCode(filesystem.ErrCodeDenylistFile), "This file cannot be modified: present in egg denylist."
+	}
+	if filesystem.IsErrorCode(e.err, filesystem.ErrCodeWriteDenied) || strings.Contains(e.err.Error(), "filesystem: write prohibited") {
+		return http.StatusForbidden, middleware.ErrorCode(filesystem.ErrCodeWriteDenied), "This file cannot be modified: present in the write denylist."
 	}
 	if filesystem.IsErrorCode(e.err, filesystem.ErrCodePathResolution) || strings.Contains(e.err.Error(), "resolves to a location outside the server root") {
-		return http.StatusNotFound, "The requested resource was not found on the system."
+		return http.StatusNotFound, middleware.ErrorCode(filesystem.ErrCodePathResolution), "The requested resource was not found on the system."
 	}
 	if filesystem.IsErrorCode(e.err, filesystem.ErrCodeIsDirectory) || strings.Contains(e.err.Error(), "filesystem: is a directory") {
-		return http.StatusBadRequest, "Cannot perform that action: file is a directory."
+		return http.StatusBadRequest, middleware.ErrorCode(filesystem.ErrCodeIsDirectory), "Cannot perform that action: file is a directory."
 	}
 	if filesystem.IsErrorCode(e.err, filesystem.ErrCodeDiskSpace) || strings.Contains(e.err.Error(), "filesystem: not enough disk space") {
-		return http.StatusBadRequest, "Cannot perform that action: not enough disk space available."
+		return http.StatusBadRequest, middleware.ErrorCode(filesystem.ErrCodeDiskSpace), "Cannot perform that action: not enough disk space available."
+	}
+	if filesystem.IsErrorCode(e.err, filesystem.ErrCodeNotSupported) {
+		return http.StatusBadRequest, middleware.ErrorCode(filesystem.ErrCodeNotSupported), "Cannot perform that action: it is not supported on this system."
 	}
 	if strings.HasSuffix(e.err.Error(), "file name too long") {
-		return http.StatusBadRequest, "Cannot perform that action: file name is too long."
+		return http.StatusBadRequest, middleware.ErrCodeNameTooLong, "Cannot perform that action: file name is too long."
 	}
 	if e, ok := e.err.(*os.SyscallError); ok && e.Syscall == "readdirent" {
-		return http.StatusNotFound, "The requested directory does not exist."
+		return http.StatusNotFound, middleware.ErrCodeNotFound, "The requested directory does not exist."
 	}
-	return 0, ""
+	return 0, "", ""
 }
 
 // Handle specific filesystem errors for a server.
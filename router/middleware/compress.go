@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Responses smaller than this are sent uncompressed since the overhead of the
+// compression outweighs any savings. Up to this many bytes are held back until it
+// is known if the response is large enough to be compressed.
+const minCompressSize = 1024
+
+// compressWriter compresses the response written by a handler as it is written,
+// rather than holding the entire response in memory. The decision to reply with
+// 304 Not Modified is made when the handler starts writing the body, since the
+// status and headers are known by then.
+type compressWriter struct {
+	gin.ResponseWriter
+	accept      string
+	ifNoneMatch string
+	started     bool
+	discard     bool
+	decided     bool
+	pending     []byte
+	enc         io.WriteCloser
+}
+
+// start is called before anything is written to the client.
+func (w *compressWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	if w.Status() != http.StatusOK {
+		w.decided = true
+		return
+	}
+
+	h := w.Header()
+	if etag := h.Get("ETag"); etag != "" && etagMatches(w.ifNoneMatch, etag) {
+		h.Del("Content-Length")
+		h.Del("Content-Type")
+		w.discard = true
+		w.decided = true
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	if n, err := strconv.Atoi(h.Get("Content-Length")); err == nil {
+		w.decide(n >= minCompressSize)
+	}
+}
+
+// decide sets up the encoder if the response should be compressed, and writes
+// anything that was held back while waiting to decide.
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+	if compress {
+		encoding := acceptedEncoding(w.accept)
+		switch encoding {
+		case "zstd":
+			w.enc, _ = zstd.NewWriter(w.ResponseWriter, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+		case "gzip":
+			w.enc, _ = gzip.NewWriterLevel(w.ResponseWriter, gzip.BestSpeed)
+		}
+		if w.enc != nil {
+			w.Header().Set("Content-Encoding", encoding)
+			w.Header().Del("Content-Length")
+		}
+	}
+	if len(w.pending) > 0 {
+		_, _ = w.write(w.pending)
+		w.pending = nil
+	}
+}
+
+func (w *compressWriter) write(b []byte) (int, error) {
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	w.start()
+	if w.decided && !w.discard {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	w.start()
+	switch {
+	case w.discard:
+		return len(b), nil
+	case !w.decided:
+		w.pending = append(w.pending, b...)
+		if len(w.pending) >= minCompressSize {
+			w.decide(true)
+		}
+		return len(b), nil
+	}
+	return w.write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends everything written so far to the client. The response is always
+// compressed once it has been flushed, since more is expected to follow.
+func (w *compressWriter) Flush() {
+	w.start()
+	if w.discard {
+		return
+	}
+	if !w.decided {
+		w.decide(true)
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// close writes anything still held back and completes the compressed stream once
+// the handler has returned.
+func (w *compressWriter) close() {
+	if w.started && !w.decided {
+		w.decide(false)
+	}
+	if w.enc != nil {
+		_ = w.enc.Close()
+	}
+}
+
+// CompressAndCache compresses the response from the handler using zstd or gzip
+// if the client supports it, writing it through to the client as the handler
+// writes it. If the handler sets an ETag header before writing the body and it
+// matches the If-None-Match header sent by the client, 304 Not Modified is sent
+// in its place so that the client can skip downloading a response it already has.
+func CompressAndCache() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			accept:         c.GetHeader("Accept-Encoding"),
+			ifNoneMatch:    c.GetHeader("If-None-Match"),
+		}
+		c.Header("Vary", "Accept-Encoding")
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// ETag returns a strong entity tag derived from the sum of the hash, for handlers
+// that compute a hash over the data in their response.
+func ETag(h hash.Hash) string {
+	sum := h.Sum(nil)
+	if len(sum) > 16 {
+		sum = sum[:16]
+	}
+	return `"` + hex.EncodeToString(sum) + `"`
+}
+
+// etagMatches returns true if the If-None-Match header contains the ETag.
+func etagMatches(header string, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedEncoding returns the preferred compression supported by the client,
+// or an empty string if the response should not be compressed.
+func acceptedEncoding(header string) string {
+	var gz bool
+	for _, v := range strings.Split(header, ",") {
+		parts := strings.Split(v, ";")
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		// Encodings with a quality of zero have been explicitly disabled by the client.
+		if len(parts) > 1 && strings.ReplaceAll(strings.TrimSpace(parts[1]), " ", "") == "q=0" {
+			continue
+		}
+		switch name {
+		case "zstd":
+			return name
+		case "gzip":
+			gz = true
+		}
+	}
+	if gz {
+		return "gzip"
+	}
+	return ""
+}
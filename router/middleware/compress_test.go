@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompressAndCache(t *testing.T) {
+	g := goblin.Goblin(t)
+	gin.SetMode(gin.TestMode)
+
+	body := strings.Repeat("pterodactyl ", 500)
+	h := sha256.New()
+	h.Write([]byte(body))
+	etag := ETag(h)
+
+	var flushed int
+	r := gin.New()
+	r.GET("/", CompressAndCache(), func(c *gin.Context) {
+		c.Header("ETag", etag)
+		c.String(http.StatusOK, body)
+	})
+	r.GET("/small", CompressAndCache(), func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/plain", []byte("small"))
+	})
+	r.GET("/stream", CompressAndCache(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString(body)
+		c.Writer.Flush()
+		flushed = c.Writer.Size()
+		_, _ = c.Writer.WriteString(body)
+	})
+	r.GET("/missing", CompressAndCache(), func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "not found"})
+	})
+
+	request := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	g.Describe("CompressAndCache", func() {
+		g.It("compresses the response using gzip", func() {
+			w := request("/", map[string]string{"Accept-Encoding": "gzip, deflate"})
+			g.Assert(w.Code).Equal(http.StatusOK)
+			g.Assert(w.Header().Get("Content-Encoding")).Equal("gzip")
+
+			gr, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+			g.Assert(err).IsNil()
+			b, err := io.ReadAll(gr)
+			g.Assert(err).IsNil()
+			g.Assert(string(b)).Equal(body)
+		})
+
+		g.It("prefers zstd when supported", func() {
+			w := request("/", map[string]string{"Accept-Encoding": "gzip, zstd"})
+			g.Assert(w.Header().Get("Content-Encoding")).Equal("zstd")
+
+			d, err := zstd.NewReader(bytes.NewReader(w.Body.Bytes()))
+			g.Assert(err).IsNil()
+			defer d.Close()
+			b, err := io.ReadAll(d)
+			g.Assert(err).IsNil()
+			g.Assert(string(b)).Equal(body)
+		})
+
+		g.It("does not compress when not supported", func() {
+			w := request("/", nil)
+			g.Assert(w.Header().Get("Content-Encoding")).Equal("")
+			g.Assert(w.Body.String()).Equal(body)
+		})
+
+		g.It("does not compress small responses with a known length", func() {
+			w := request("/small", map[string]string{"Accept-Encoding": "gzip"})
+			g.Assert(w.Header().Get("Content-Encoding")).Equal("")
+			g.Assert(w.Body.String()).Equal("small")
+		})
+
+		g.It("writes the response through to the client as it is written", func() {
+			flushed = 0
+			w := request("/stream", map[string]string{"Accept-Encoding": "gzip"})
+			g.Assert(flushed > 0).IsTrue()
+
+			gr, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+			g.Assert(err).IsNil()
+			b, err := io.ReadAll(gr)
+			g.Assert(err).IsNil()
+			g.Assert(string(b)).Equal(body + body)
+		})
+
+		g.It("returns not modified for a matching etag", func() {
+			g.Assert(request("/", nil).Header().Get("ETag")).Equal(etag)
+
+			w := request("/", map[string]string{"If-None-Match": etag, "Accept-Encoding": "gzip"})
+			g.Assert(w.Code).Equal(http.StatusNotModified)
+			g.Assert(w.Body.Len()).Equal(0)
+		})
+
+		g.It("passes through error responses", func() {
+			w := request("/missing", map[string]string{"Accept-Encoding": "gzip"})
+			g.Assert(w.Code).Equal(http.StatusNotFound)
+			g.Assert(w.Header().Get("Content-Encoding")).Equal("")
+			g.Assert(strings.Contains(w.Body.String(), "not found")).IsTrue()
+		})
+	})
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/server/filesystem"
+)
+
+// ErrorCode is a machine-readable code returned in the body of error responses,
+// in the same format as the codes used for filesystem errors. The codes for
+// filesystem errors are returned as-is.
+type ErrorCode string
+
+const (
+	ErrCodeBadRequest   ErrorCode = "E_BADREQUEST"
+	ErrCodeBadFormat    ErrorCode = "E_BADFORMAT"
+	ErrCodeUnauthorized ErrorCode = "E_UNAUTHORIZED"
+	ErrCodeForbidden    ErrorCode = "E_FORBIDDEN"
+	ErrCodeNotFound     ErrorCode = "E_NOTFOUND"
+	ErrCodeConflict     ErrorCode = "E_CONFLICT"
+	ErrCodeInvalid      ErrorCode = "E_INVALID"
+	ErrCodeRateLimited  ErrorCode = "E_RATELIMITED"
+	ErrCodeDisabled     ErrorCode = "E_DISABLED"
+	ErrCodeConfirmation ErrorCode = "E_CONFIRMATION"
+	ErrCodeNameTooLong  ErrorCode = "E_NAMETOOLONG"
+	ErrCodeAborted      ErrorCode = "E_ABORTED"
+	ErrCodeTimeout      ErrorCode = "E_TIMEOUT"
+	ErrCodeUnavailable  ErrorCode = "E_UNAVAILABLE"
+	ErrCodeInternal     ErrorCode = "E_INTERNAL"
+)
+
+// Request IDs sent by the client are only used if they match this format, so
+// that nothing unexpected ends up in the logs.
+var requestIdRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]{8,64}$`)
+
+// StatusErrorCode returns the error code used for a response with the status
+// when the handler did not provide a more specific code.
+func StatusErrorCode(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusUnprocessableEntity:
+		return ErrCodeInvalid
+	case http.StatusTooManyRequests:
+		return ErrCodeRateLimited
+	case http.StatusPreconditionRequired:
+		return ErrCodeConfirmation
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return ErrCodeUnavailable
+	case http.StatusGatewayTimeout:
+		return ErrCodeTimeout
+	}
+	if status >= 500 {
+		return ErrCodeInternal
+	}
+	return ErrCodeBadRequest
+}
+
+// RequestID returns the unique ID of the request, or an empty string if the
+// AttachRequestID middleware has not been used.
+func RequestID(c *gin.Context) string {
+	return c.GetString("request_id")
+}
+
+// ErrorBody returns the body for an error response, which includes the message
+// for the user, the error code and the ID of the request so that the error can
+// be found in the logs.
+func ErrorBody(c *gin.Context, code ErrorCode, msg string) gin.H {
+	return gin.H{"error": msg, "code": code, "request_id": RequestID(c)}
+}
+
+// AbortWithError aborts the request with an error response.
+func AbortWithError(c *gin.Context, status int, code ErrorCode, msg string) {
+	c.AbortWithStatusJSON(status, ErrorBody(c, code, msg))
+}
+
+// filesystemErrorCode returns the code for the filesystem error.
+func filesystemErrorCode(code filesystem.ErrorCode) ErrorCode {
+	return ErrorCode(code)
+}
+
+// errorWriter adds the error code and request ID to the JSON error responses
+// written by handlers that do not include them.
+type errorWriter struct {
+	gin.ResponseWriter
+	id string
+}
+
+func (w *errorWriter) Write(b []byte) (int, error) {
+	if w.Status() < 400 || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return w.ResponseWriter.Write(b)
+	}
+	out, ok := structuredError(b, w.Status(), w.id)
+	if !ok {
+		return w.ResponseWriter.Write(b)
+	}
+	if _, err := w.ResponseWriter.Write(out); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// StructuredErrors makes sure that every JSON error response includes an error
+// code and the ID of the request, using a code based on the status for handlers
+// that do not provide one. This must be used after AttachRequestID.
+func StructuredErrors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer = &errorWriter{ResponseWriter: c.Writer, id: RequestID(c)}
+		c.Next()
+	}
+}
+
+// structuredError adds the error code and request ID to the body of an error
+// response. False is returned if the body is not an object with an error.
+func structuredError(b []byte, status int, id string) ([]byte, bool) {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(b, &body); err != nil {
+		return nil, false
+	}
+	if _, ok := body["error"]; !ok {
+		return nil, false
+	}
+	_, hasCode := body["code"]
+	_, hasId := body["request_id"]
+	if hasCode && (hasId || id == "") {
+		return nil, false
+	}
+	if !hasCode {
+		body["code"], _ = json.Marshal(StatusErrorCode(status))
+	}
+	if !hasId && id != "" {
+		body["request_id"], _ = json.Marshal(id)
+	}
+	out, err := json.Marshal(body)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
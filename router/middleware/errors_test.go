@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+func TestStructuredErrors(t *testing.T) {
+	g := goblin.Goblin(t)
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("request_id", "c8a4f9e2-request")
+		c.Next()
+	}, StructuredErrors())
+	r.GET("/missing", func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "not found"})
+	})
+	r.GET("/denied", func(c *gin.Context) {
+		AbortWithError(c, http.StatusForbidden, ErrorCode("E_DENYLIST"), "denied")
+	})
+	r.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"error": "not an error"})
+	})
+
+	request := func(path string) map[string]string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]string
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		return body
+	}
+
+	g.Describe("StructuredErrors", func() {
+		g.It("adds the code and request ID to error responses", func() {
+			body := request("/missing")
+			g.Assert(body["error"]).Equal("not found")
+			g.Assert(body["code"]).Equal(string(ErrCodeNotFound))
+			g.Assert(body["request_id"]).Equal("c8a4f9e2-request")
+		})
+
+		g.It("keeps the code set by the handler", func() {
+			body := request("/denied")
+			g.Assert(body["code"]).Equal("E_DENYLIST")
+			g.Assert(body["request_id"]).Equal("c8a4f9e2-request")
+		})
+
+		g.It("does not change successful responses", func() {
+			body := request("/ok")
+			g.Assert(len(body)).Equal(1)
+		})
+	})
+
+	g.Describe("StatusErrorCode", func() {
+		g.It("returns a code for the status", func() {
+			g.Assert(StatusErrorCode(http.StatusConflict)).Equal(ErrCodeConflict)
+			g.Assert(StatusErrorCode(http.StatusGatewayTimeout)).Equal(ErrCodeTimeout)
+			g.Assert(StatusErrorCode(http.StatusInsufficientStorage)).Equal(ErrCodeInternal)
+			g.Assert(StatusErrorCode(http.StatusMethodNotAllowed)).Equal(ErrCodeBadRequest)
+		})
+	})
+}
@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"context"
-	"crypto/subtle"
 	"io"
 	"net/http"
 	"os"
@@ -15,6 +14,7 @@ import (
 
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/remote/transport"
 	"github.com/pterodactyl/wings/server"
 	"github.com/pterodactyl/wings/server/filesystem"
 )
@@ -24,6 +24,7 @@ import (
 type RequestError struct {
 	err    error
 	status int
+	code   ErrorCode
 	msg    string
 }
 
@@ -43,6 +44,12 @@ func (re *RequestError) SetMessage(m string) {
 	re.msg = m
 }
 
+// SetCode sets the machine-readable code for the error response. By default the
+// code is based on the HTTP status of the response.
+func (re *RequestError) SetCode(code ErrorCode) {
+	re.code = code
+}
+
 // SetStatus sets the HTTP status code for the error response. By default this
 // is a HTTP-500 error.
 func (re *RequestError) SetStatus(s int) {
@@ -53,19 +60,14 @@ func (re *RequestError) SetStatus(s int) {
 // logs the event into the logs. The error that is output will include the unique
 // request ID if it is present.
 func (re *RequestError) Abort(c *gin.Context, status int) {
-	reqId := c.Writer.Header().Get("X-Request-Id")
-
-	// Generate the base logger instance, attaching the unique request ID and
-	// the URL that was requested.
-	event := log.WithField("request_id", reqId).WithField("url", c.Request.URL.String())
-	// If there is a server present in the gin.Context stack go ahead and pull it
-	// and attach that server UUID to the logs as well so that we can see what specific
-	// server triggered this error.
-	if s, ok := c.Get("server"); ok {
-		if s, ok := s.(*server.Server); ok {
-			event = event.WithField("server_id", s.ID())
-		}
+	// Use the logger for the request, which already has the unique request ID and
+	// the server or tenant the request is for attached, along with the URL that was
+	// requested.
+	event := log.WithField("request_id", RequestID(c))
+	if l, ok := c.Get("logger"); ok {
+		event = l.(*log.Entry)
 	}
+	event = event.WithField("url", c.Request.URL.String())
 
 	if c.Writer.Status() == 200 {
 		// Handle context deadlines being exceeded a little differently since we want
@@ -74,9 +76,11 @@ func (re *RequestError) Abort(c *gin.Context, status int) {
 		// logic is finished running.
 		if errors.Is(re.err, context.DeadlineExceeded) {
 			re.SetStatus(http.StatusGatewayTimeout)
+			re.SetCode(ErrCodeTimeout)
 			re.SetMessage("The server could not process this request in time, please try again.")
 		} else if strings.Contains(re.Cause().Error(), "context canceled") {
 			re.SetStatus(http.StatusBadRequest)
+			re.SetCode(ErrCodeAborted)
 			re.SetMessage("Request aborted by client.")
 		}
 	}
@@ -92,10 +96,13 @@ func (re *RequestError) Abort(c *gin.Context, status int) {
 	if re.msg == "" {
 		re.msg = "An unexpected error was encountered while processing this request"
 	}
+	if re.code == "" {
+		re.code = StatusErrorCode(status)
+	}
 	// Now abort the request with the error message and include the unique request
 	// ID that was present to make things super easy on people who don't know how
 	// or cannot view the response headers (where X-Request-Id would be present).
-	c.AbortWithStatusJSON(status, gin.H{"error": re.msg, "request_id": reqId})
+	AbortWithError(c, status, re.code, re.msg)
 }
 
 // Cause returns the underlying error.
@@ -117,43 +124,56 @@ func (re *RequestError) Error() string {
 //
 // If the error passed into this call is nil or does not match empty values will
 // be returned to the caller.
-func (re *RequestError) asFilesystemError() (int, string) {
+func (re *RequestError) asFilesystemError() (int, ErrorCode, string) {
 	err := re.Cause()
 	if err == nil {
-		return 0, ""
+		return 0, "", ""
 	}
 	if filesystem.IsErrorCode(err, filesystem.ErrCodeDenylistFile) || strings.Contains(err.Error(), "filesystem: file access prohibited") {
-		return http.StatusForbidden, "This file cannot be modified: present in egg denylist."
+		return http.StatusForbidden, filesystemErrorCode(filesystem.ErrCodeDenylistFile), "This file cannot be modified: present in egg denylist."
+	}
+	if filesystem.IsErrorCode(err, filesystem.ErrCodeWriteDenied) || strings.Contains(err.Error(), "filesystem: write prohibited") {
+		return http.StatusForbidden, filesystemErrorCode(filesystem.ErrCodeWriteDenied), "This file cannot be modified: present in the write denylist."
 	}
 	if filesystem.IsErrorCode(err, filesystem.ErrCodePathResolution) || strings.Contains(err.Error(), "resolves to a location outside the server root") {
-		return http.StatusNotFound, "The requested resource was not found on the system."
+		return http.StatusNotFound, filesystemErrorCode(filesystem.ErrCodePathResolution), "The requested resource was not found on the system."
 	}
 	if filesystem.IsErrorCode(err, filesystem.ErrCodeIsDirectory) || strings.Contains(err.Error(), "filesystem: is a directory") {
-		return http.StatusBadRequest, "Cannot perform that action: file is a directory."
+		return http.StatusBadRequest, filesystemErrorCode(filesystem.ErrCodeIsDirectory), "Cannot perform that action: file is a directory."
 	}
 	if filesystem.IsErrorCode(err, filesystem.ErrCodeDiskSpace) || strings.Contains(err.Error(), "filesystem: not enough disk space") {
-		return http.StatusBadRequest, "There is not enough disk space available to perform that action."
+		return http.StatusBadRequest, filesystemErrorCode(filesystem.ErrCodeDiskSpace), "There is not enough disk space available to perform that action."
+	}
+	if filesystem.IsErrorCode(err, filesystem.ErrCodeNotSupported) {
+		return http.StatusBadRequest, filesystemErrorCode(filesystem.ErrCodeNotSupported), "Cannot perform that action: it is not supported on this system."
 	}
 	if strings.HasSuffix(err.Error(), "file name too long") {
-		return http.StatusBadRequest, "Cannot perform that action: file name is too long."
+		return http.StatusBadRequest, ErrCodeNameTooLong, "Cannot perform that action: file name is too long."
 	}
 	if e, ok := err.(*os.SyscallError); ok && e.Syscall == "readdirent" {
-		return http.StatusNotFound, "The requested directory does not exist."
+		return http.StatusNotFound, ErrCodeNotFound, "The requested directory does not exist."
 	}
-	return 0, ""
+	return 0, "", ""
 }
 
 // AttachRequestID attaches a unique ID to the incoming HTTP request so that any
 // errors that are generated or returned to the client will include this reference
 // allowing for an easier time identifying the specific request that failed for
-// the user.
+// the user. If the Panel sent its own ID for the request in the X-Request-Id header
+// that is used instead, so that the request can be followed across both of them.
+// The ID is also sent to the Panel with any requests made using the context of
+// the request.
 //
 // If you are using a tool such as Sentry or Bugsnag for error reporting this is
 // a great location to also attach this request ID to your error handling logic
 // so that you can easily cross-reference the errors.
 func AttachRequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id := uuid.New().String()
+		id := c.GetHeader("X-Request-Id")
+		if !requestIdRegex.MatchString(id) {
+			id = uuid.New().String()
+		}
+		c.Request = c.Request.WithContext(transport.WithRequestID(c.Request.Context(), id))
 		c.Set("request_id", id)
 		c.Set("logger", log.WithField("request_id", id))
 		c.Header("X-Request-Id", id)
@@ -203,35 +223,42 @@ func CaptureErrors() gin.HandlerFunc {
 			status = c.Writer.Status()
 		}
 		if err.Error() == io.EOF.Error() {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The data passed in the request was not in a parsable format. Please try again."})
+			AbortWithError(c, http.StatusBadRequest, ErrCodeBadFormat, "The data passed in the request was not in a parsable format. Please try again.")
 			return
 		}
 		captured := NewError(err.Err)
-		if status, msg := captured.asFilesystemError(); msg != "" {
-			c.AbortWithStatusJSON(status, gin.H{"error": msg, "request_id": c.Writer.Header().Get("X-Request-Id")})
+		if status, code, msg := captured.asFilesystemError(); msg != "" {
+			AbortWithError(c, status, code, msg)
 			return
 		}
 		captured.Abort(c, status)
 	}
 }
 
+// The methods and headers allowed for origins that do not limit them.
+const (
+	corsMethods = "GET, POST, PATCH, PUT, DELETE, OPTIONS"
+	corsHeaders = "Accept, Accept-Encoding, Authorization, Cache-Control, Content-Type, Content-Length, Origin, X-Real-IP, X-CSRF-Token"
+)
+
 // SetAccessControlHeaders sets the access request control headers on all of
-// the requests.
+// the requests. The allowed origins are read from the configuration for each
+// request so that changes to them apply without restarting Wings.
 func SetAccessControlHeaders() gin.HandlerFunc {
-	cfg := config.Get()
-	origins := cfg.AllowedOrigins
-	location := cfg.PanelLocation
-	allowPrivateNetwork := cfg.AllowCORSPrivateNetwork
-
 	return func(c *gin.Context) {
+		cfg := config.Get()
+		location := cfg.PanelLocation
 		c.Header("Access-Control-Allow-Origin", location)
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PATCH, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Accept, Accept-Encoding, Authorization, Cache-Control, Content-Type, Content-Length, Origin, X-Real-IP, X-CSRF-Token")
+		c.Header("Access-Control-Allow-Methods", corsMethods)
+		c.Header("Access-Control-Allow-Headers", corsHeaders)
+		// The headers depend on the origin of the request, so they cannot be cached
+		// for requests from a different origin.
+		c.Writer.Header().Add("Vary", "Origin")
 
 		// CORS for Private Networks (RFC1918)
 		// @see https://developer.chrome.com/blog/private-network-access-update/?utm_source=devtools
-		if allowPrivateNetwork {
+		if cfg.AllowCORSPrivateNetwork {
 			c.Header("Access-Control-Request-Private-Network", "true")
 		}
 
@@ -244,18 +271,27 @@ func SetAccessControlHeaders() gin.HandlerFunc {
 		// Validate that the request origin is coming from an allowed origin. Because you
 		// cannot set multiple values here we need to see if the origin is one of the ones
 		// that we allow, and if so return it explicitly. Otherwise, just return the default
-		// origin which is the same URL that the Panel is located at.
+		// origin which is the same URL that the Panel is located at. The Panels for every
+		// tenant are allowed in the same way as the primary Panel.
 		origin := c.GetHeader("Origin")
-		if origin != location {
-			for _, o := range origins {
-				if o != "*" && o != origin {
-					continue
+		if origin != "" && origin != location {
+			if o, ok := corsOrigin(cfg, origin); ok {
+				if o.Origin == "*" {
+					c.Header("Access-Control-Allow-Origin", "*")
+				} else {
+					c.Header("Access-Control-Allow-Origin", origin)
+				}
+				if len(o.Methods) > 0 {
+					c.Header("Access-Control-Allow-Methods", strings.Join(o.Methods, ", "))
+				}
+				if len(o.Headers) > 0 {
+					c.Header("Access-Control-Allow-Headers", strings.Join(o.Headers, ", "))
 				}
-				c.Header("Access-Control-Allow-Origin", o)
-				break
 			}
 		}
-		if c.Request.Method == http.MethodOptions {
+		// WebDAV clients use OPTIONS requests to discover the capabilities of the endpoint,
+		// so those requests are passed through to it rather than treated as preflights.
+		if c.Request.Method == http.MethodOptions && !isWebDavRequest(c) {
 			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
@@ -263,6 +299,24 @@ func SetAccessControlHeaders() gin.HandlerFunc {
 	}
 }
 
+// corsOrigin returns the settings for requests from the origin, if it is the
+// Panel for a tenant or is allowed by the configuration.
+func corsOrigin(cfg *config.Configuration, origin string) (config.CorsOrigin, bool) {
+	for _, t := range cfg.Tenants {
+		if t.PanelLocation == origin {
+			return config.CorsOrigin{Origin: origin}, true
+		}
+	}
+	return cfg.CorsOrigin(origin)
+}
+
+// isWebDavRequest returns true if the request is for the WebDAV endpoint and the
+// endpoint is enabled.
+func isWebDavRequest(c *gin.Context) bool {
+	p := c.Request.URL.Path
+	return config.Get().Api.WebDav.Enabled && (p == "/webdav" || strings.HasPrefix(p, "/webdav/"))
+}
+
 // ServerExists will ensure that the requested server exists in this setup.
 // Returns a 404 if we cannot locate it. If the server is found it is set into
 // the request context, and the logger for the context is also updated to include
@@ -275,9 +329,15 @@ func ServerExists() gin.HandlerFunc {
 			s = manager.Find(func(s *server.Server) bool {
 				return c.Param("server") == s.ID()
 			})
+			// Servers belonging to a different Panel are treated as if they do not exist
+			// at all. Routes that are not authorized using the node's token have no tenant
+			// set, and must check the tenant of the server themselves.
+			if tenant, ok := c.Get("tenant"); ok && s != nil && tenant.(string) != manager.ServerTenant(s.ID()) {
+				s = nil
+			}
 		}
 		if s == nil {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "The requested resource does not exist on this instance."})
+			AbortWithError(c, http.StatusNotFound, ErrCodeNotFound, "The requested resource does not exist on this instance.")
 			return
 		}
 		c.Set("logger", ExtractLogger(c).WithField("server_id", s.ID()))
@@ -292,24 +352,32 @@ func ServerExists() gin.HandlerFunc {
 // request is using a properly signed global token.
 func RequireAuthorization() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// We don't put this value outside this function since the node's authentication
-		// token can be changed on the fly and the config.Get() call returns a copy, so
-		// if it is rotated this value will never properly get updated.
-		token := config.Get().AuthenticationToken
 		auth := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
 		if len(auth) != 2 || auth[0] != "Bearer" {
 			c.Header("WWW-Authenticate", "Bearer")
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "The required authorization heads were not present in the request."})
+			AbortWithError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "The required authorization heads were not present in the request.")
 			return
 		}
 
 		// All requests to Wings must be authorized with the authentication token present in
 		// the Wings configuration file. Remeber, all requests to Wings come from the Panel
 		// backend, or using a signed JWT for temporary authentication.
-		if subtle.ConstantTimeCompare([]byte(auth[1]), []byte(token)) != 1 {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You are not authorized to access this endpoint."})
+		//
+		// We don't load the configuration outside this function since the node's authentication
+		// token can be changed on the fly and the config.Get() call returns a copy, so if it is
+		// rotated the value would never properly get updated.
+		tenant, ok := config.Get().TenantForToken(auth[1])
+		if !ok {
+			AbortWithError(c, http.StatusForbidden, ErrCodeForbidden, "You are not authorized to access this endpoint.")
 			return
 		}
+		// Requests from a tenant must be handled using the API client for their own Panel,
+		// including any callbacks made while processing the request.
+		c.Set("tenant", tenant)
+		if tenant != "" {
+			c.Set("logger", ExtractLogger(c).WithField("tenant", tenant))
+			c.Set("api_client", ExtractManager(c).TenantClient(tenant))
+		}
 		c.Next()
 	}
 }
@@ -320,7 +388,20 @@ func RemoteDownloadEnabled() gin.HandlerFunc {
 	disabled := config.Get().Api.DisableRemoteDownload
 	return func(c *gin.Context) {
 		if disabled {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "This functionality is not currently enabled on this instance."})
+			AbortWithError(c, http.StatusBadRequest, ErrCodeDisabled, "This functionality is not currently enabled on this instance.")
+			return
+		}
+		c.Next()
+	}
+}
+
+// ContainerExecEnabled checks if executing commands inside server containers is
+// enabled for this instance and if not aborts the request.
+func ContainerExecEnabled() gin.HandlerFunc {
+	enabled := config.Get().Api.EnableContainerExec
+	return func(c *gin.Context) {
+		if !enabled {
+			AbortWithError(c, http.StatusBadRequest, ErrCodeDisabled, "This functionality is not currently enabled on this instance.")
 			return
 		}
 		c.Next()
@@ -356,6 +437,12 @@ func ExtractApiClient(c *gin.Context) remote.Client {
 	panic("middleware/middlware: cannot extract api clinet: not present in context")
 }
 
+// ExtractTenant returns the name of the tenant that the request was authorized
+// for, which is an empty string for the primary Panel.
+func ExtractTenant(c *gin.Context) string {
+	return c.GetString("tenant")
+}
+
 // ExtractManager returns the server manager instance set on the request context.
 func ExtractManager(c *gin.Context) *server.Manager {
 	if v, ok := c.Get("manager"); ok {
@@ -1,11 +1,15 @@
 package router
 
 import (
+	"time"
+
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
 
+	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/remote"
 	"github.com/pterodactyl/wings/router/middleware"
+	"github.com/pterodactyl/wings/router/webdav"
 	wserver "github.com/pterodactyl/wings/server"
 )
 
@@ -15,7 +19,7 @@ func Configure(m *wserver.Manager, client remote.Client) *gin.Engine {
 
 	router := gin.New()
 	router.Use(gin.Recovery())
-	router.Use(middleware.AttachRequestID(), middleware.CaptureErrors(), middleware.SetAccessControlHeaders())
+	router.Use(middleware.AttachRequestID(), middleware.StructuredErrors(), middleware.CaptureErrors(), middleware.SetAccessControlHeaders())
 	router.Use(middleware.AttachServerManager(m), middleware.AttachApiClient(client))
 	// @todo log this into a different file so you can setup IP blocking for abusive requests and such.
 	// This should still dump requests in debug mode since it does help with understanding the request
@@ -48,11 +52,35 @@ func Configure(m *wserver.Manager, client remote.Client) *gin.Engine {
 	// and requests are authenticated through a JWT the panel issues to the other daemon.
 	router.GET("/api/servers/:server/archive", middleware.ServerExists(), getServerArchive)
 
+	// The health of the node can be checked by load balancers and uptime monitors without
+	// any authorization, although the details of each check are only included when the
+	// request is authorized.
+	router.GET("/api/system/health", getSystemHealth)
+
+	// WebDAV clients authenticate using the SFTP credentials of a user, which are validated
+	// by the Panel, rather than the Authorization header used by the rest of the API.
+	if cfg := config.Get().Api.WebDav; cfg.Enabled {
+		auth := webdav.NewAuthenticator(m, time.Duration(cfg.CredentialCache)*time.Second)
+		for _, method := range webDavMethods {
+			router.Handle(method, "/webdav", handleWebDav(auth))
+			router.Handle(method, "/webdav/*path", handleWebDav(auth))
+		}
+	}
+
 	// All of the routes beyond this mount will use an authorization middleware
 	// and will not be accessible without the correct Authorization header provided.
 	protected := router.Use(middleware.RequireAuthorization())
 	protected.POST("/api/update", postUpdateConfiguration)
 	protected.GET("/api/system", getSystemInformation)
+	protected.GET("/api/system/resources", getSystemResources)
+	protected.GET("/api/system/metrics", getSystemMetrics)
+	protected.GET("/api/system/operations", getSystemOperations)
+	protected.GET("/api/system/cors", getSystemCors)
+	protected.PUT("/api/system/cors", putSystemCors)
+	protected.GET("/api/system/tasks", getSystemTasks)
+	protected.PUT("/api/system/tasks/:task", putSystemTask)
+	protected.DELETE("/api/system/tasks/:task", deleteSystemTask)
+	protected.POST("/api/system/tasks/:task/run", postSystemTaskRun)
 	protected.GET("/api/servers", getAllServers)
 	protected.POST("/api/servers", postCreateServer)
 	protected.POST("/api/transfer", postTransfer)
@@ -64,31 +92,54 @@ func Configure(m *wserver.Manager, client remote.Client) *gin.Engine {
 	{
 		server.GET("", getServer)
 		server.DELETE("", deleteServer)
-
-		server.GET("/logs", getServerLogs)
+		server.POST("/confirmations", postServerConfirmation)
+
+		server.GET("/logs", middleware.CompressAndCache(), getServerLogs)
+		server.GET("/install-logs", middleware.CompressAndCache(), getServerInstallLogs)
+		server.GET("/install-logs/:log", getServerInstallLog)
+		server.GET("/crash-reports", getServerCrashReports)
+		server.GET("/crash-reports/:report", getServerCrashReport)
+		server.GET("/maintenance", getServerMaintenance)
+		server.GET("/processes", getServerProcesses)
 		server.POST("/power", postServerPower)
 		server.POST("/commands", postServerCommands)
+		server.GET("/commands/history", getServerCommandHistory)
+		server.GET("/macros", getServerMacros)
+		server.PUT("/macros/:macro", putServerMacro)
+		server.DELETE("/macros/:macro", deleteServerMacro)
+		server.POST("/macros/:macro/run", postServerMacroRun)
 		server.POST("/install", postServerInstall)
 		server.POST("/reinstall", postServerReinstall)
+		server.POST("/clone", postServerClone)
+		server.POST("/import", postServerImport)
 		server.POST("/sync", postServerSync)
 		server.POST("/ws/deny", postServerDenyWSTokens)
+		server.POST("/exec", middleware.ContainerExecEnabled(), postServerExec)
+		server.GET("/exec/ws", middleware.ContainerExecEnabled(), getServerExecWebsocket)
 
 		// This archive request causes the archive to start being created
 		// this should only be triggered by the panel.
 		server.POST("/archive", postServerArchive)
+		server.POST("/archive/cancel", postServerArchiveCancel)
+		server.POST("/transfer/cancel", postServerTransferCancel)
 
 		files := server.Group("/files")
 		{
 			files.GET("/contents", getServerFileContents)
-			files.GET("/list-directory", getServerListDirectory)
+			files.GET("/list-directory", middleware.CompressAndCache(), getServerListDirectory)
 			files.PUT("/rename", putServerRenameFiles)
 			files.POST("/copy", postServerCopyFile)
+			files.POST("/copy-to", postServerCopyFiles)
+			files.POST("/move", postServerMoveFiles)
 			files.POST("/write", postServerWriteFile)
 			files.POST("/create-directory", postServerCreateDirectory)
 			files.POST("/delete", postServerDeleteFiles)
 			files.POST("/compress", postServerCompressFiles)
 			files.POST("/decompress", postServerDecompressFiles)
 			files.POST("/chmod", postServerChmodFile)
+			files.POST("/attributes", postServerFileAttributes)
+			files.POST("/checksum", postServerFileChecksums)
+			files.POST("/download-url", postServerFileDownloadUrl)
 
 			files.GET("/pull", middleware.RemoteDownloadEnabled(), getServerPullingFiles)
 			files.POST("/pull", middleware.RemoteDownloadEnabled(), postServerPullRemoteFile)
@@ -99,7 +150,10 @@ func Configure(m *wserver.Manager, client remote.Client) *gin.Engine {
 		{
 			backup.POST("", postServerBackup)
 			backup.POST("/:backup/restore", postServerRestoreBackup)
+			backup.POST("/:backup/verify", postServerVerifyBackup)
+			backup.POST("/:backup/cancel", postServerBackupCancel)
 			backup.DELETE("/:backup", deleteServerBackup)
+			backup.POST("/:backup/download-url", postServerBackupDownloadUrl)
 		}
 	}
 
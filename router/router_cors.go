@@ -0,0 +1,70 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/router/middleware"
+)
+
+// corsSettings are the origins allowed to make requests to Wings, in addition to
+// the Panel itself.
+type corsSettings struct {
+	AllowedOrigins []string            `json:"allowed_origins"`
+	CorsOrigins    []config.CorsOrigin `json:"cors_origins"`
+}
+
+// getSystemCors returns the origins that are allowed to make requests to Wings.
+func getSystemCors(c *gin.Context) {
+	if !primaryPanelOnly(c) {
+		return
+	}
+	cfg := config.Get()
+	c.JSON(http.StatusOK, corsSettings{
+		AllowedOrigins: append([]string{}, cfg.AllowedOrigins...),
+		CorsOrigins:    append([]config.CorsOrigin{}, cfg.CorsOrigins...),
+	})
+}
+
+// putSystemCors replaces the origins that are allowed to make requests to Wings,
+// saving them to the configuration file. The new origins apply to requests made
+// straight away, so a Panel can add the domain for a new storefront without Wings
+// being restarted.
+func putSystemCors(c *gin.Context) {
+	if !primaryPanelOnly(c) {
+		return
+	}
+	var data corsSettings
+	if err := c.BindJSON(&data); err != nil {
+		return
+	}
+	for i, o := range data.AllowedOrigins {
+		if err := config.ValidateOriginPattern(o); err != nil {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+				"error": fmt.Sprintf("The allowed origin at index %d could not be validated: %s", i, err),
+			})
+			return
+		}
+	}
+	for i, o := range data.CorsOrigins {
+		if err := o.Validate(); err != nil {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+				"error": fmt.Sprintf("The CORS origin at index %d could not be validated: %s", i, err),
+			})
+			return
+		}
+	}
+
+	err := updateConfig(func(cfg *config.Configuration) {
+		cfg.AllowedOrigins = data.AllowedOrigins
+		cfg.CorsOrigins = data.CorsOrigins
+	})
+	if err != nil {
+		middleware.CaptureAndAbort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
@@ -4,36 +4,44 @@ import (
 	"bufio"
 	"errors"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/router/middleware"
 	"github.com/pterodactyl/wings/router/tokens"
 	"github.com/pterodactyl/wings/server/backup"
+	"github.com/pterodactyl/wings/server/filesystem"
 )
 
 // Handle a download request for a server backup.
 func getDownloadBackup(c *gin.Context) {
-	client := middleware.ExtractApiClient(c)
 	manager := middleware.ExtractManager(c)
 
 	token := tokens.BackupPayload{}
-	if err := tokens.ParseToken([]byte(c.Query("token")), &token); err != nil {
+	tenant, err := tokens.ParseAnyToken([]byte(c.Query("token")), &token)
+	if err != nil {
 		NewTrackedError(err).Abort(c)
 		return
 	}
 
 	s, ok := manager.Get(token.ServerUuid)
-	if !ok || !token.IsUniqueRequest() {
+	if !ok || manager.ServerTenant(s.ID()) != tenant || !token.IsUniqueRequest() {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 			"error": "The requested resource was not found on this server.",
 		})
 		return
 	}
 
-	b, st, err := backup.LocateLocal(client, token.BackupUuid)
+	b, st, err := backup.LocateLocal(manager.TenantClient(tenant), token.BackupUuid)
+	if err == nil && !localBackupOwnedBy(b, s.ID(), tenant) {
+		err = os.ErrNotExist
+	}
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
@@ -46,6 +54,18 @@ func getDownloadBackup(c *gin.Context) {
 		return
 	}
 
+	// Snapshots are sent as an archive that is created as it is downloaded, so the
+	// size is not known ahead of time.
+	if st.IsDir() {
+		c.Header("Content-Disposition", "attachment; filename="+strconv.Quote(b.Identifier()+".tar.gz"))
+		c.Header("Content-Type", "application/octet-stream")
+		a := &filesystem.Archive{BasePath: b.SnapshotPath()}
+		if err := a.Stream(c.Request.Context(), c.Writer); err != nil {
+			s.Log().WithField("error", err).WithField("backup", b.Identifier()).Warn("failed to stream snapshot for backup download")
+		}
+		return
+	}
+
 	f, err := os.Open(b.Path())
 	if err != nil {
 		NewServerError(err, s).Abort(c)
@@ -60,17 +80,29 @@ func getDownloadBackup(c *gin.Context) {
 	bufio.NewReader(f).WriteTo(c.Writer)
 }
 
+// localBackupOwnedBy returns true if the local backup was created for the server.
+// Backups created before their owner was recorded are only available to the
+// primary Panel, since no other Panel could have created them.
+func localBackupOwnedBy(b *backup.LocalBackup, server string, tenant string) bool {
+	owner, ok := b.Owner()
+	if !ok {
+		return tenant == ""
+	}
+	return owner == server
+}
+
 // Handles downloading a specific file for a server.
 func getDownloadFile(c *gin.Context) {
 	manager := middleware.ExtractManager(c)
 	token := tokens.FilePayload{}
-	if err := tokens.ParseToken([]byte(c.Query("token")), &token); err != nil {
+	tenant, err := tokens.ParseAnyToken([]byte(c.Query("token")), &token)
+	if err != nil {
 		NewTrackedError(err).Abort(c)
 		return
 	}
 
 	s, ok := manager.Get(token.ServerUuid)
-	if !ok || !token.IsUniqueRequest() {
+	if !ok || manager.ServerTenant(s.ID()) != tenant || !token.IsUniqueRequest() {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 			"error": "The requested resource was not found on this server.",
 		})
@@ -103,3 +135,90 @@ func getDownloadFile(c *gin.Context) {
 
 	bufio.NewReader(f).WriteTo(c.Writer)
 }
+
+// signedUrlLifetime returns the lifetime for a signed download URL, using the
+// requested number of seconds if it is within the configured maximum.
+func signedUrlLifetime(requested int) time.Duration {
+	max := config.Get().Api.SignedUrlLifetime
+	if requested > 0 && requested < max {
+		return time.Second * time.Duration(requested)
+	}
+	return time.Second * time.Duration(max)
+}
+
+// Returns a short-lived URL that allows a file on the server to be downloaded
+// exactly once, without needing a token issued by the Panel.
+func postServerFileDownloadUrl(c *gin.Context) {
+	s := ExtractServer(c)
+
+	var data struct {
+		File      string `json:"file"`
+		ExpiresIn int    `json:"expires_in"`
+	}
+	if err := c.BindJSON(&data); err != nil {
+		return
+	}
+
+	p := "/" + strings.TrimLeft(data.File, "/")
+	st, err := s.Filesystem().Stat(p)
+	if err != nil {
+		middleware.CaptureAndAbort(c, err)
+		return
+	}
+	if st.IsDir() {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "Cannot generate a download URL for a directory.",
+		})
+		return
+	}
+
+	token, expires, err := tokens.NewFileDownloadToken(middleware.ExtractTenant(c), s.ID(), p, signedUrlLifetime(data.ExpiresIn))
+	if err != nil {
+		middleware.CaptureAndAbort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"url":        "/download/file?token=" + url.QueryEscape(token),
+		"expires_at": expires,
+	})
+}
+
+// Returns a short-lived URL that allows a local backup of the server to be
+// downloaded exactly once, without needing a token issued by the Panel.
+func postServerBackupDownloadUrl(c *gin.Context) {
+	s := ExtractServer(c)
+
+	var data struct {
+		ExpiresIn int `json:"expires_in"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&data); err != nil {
+			return
+		}
+	}
+
+	b, _, err := backup.LocateLocal(middleware.ExtractApiClient(c), c.Param("backup"))
+	if err == nil && !localBackupOwnedBy(b, s.ID(), middleware.ExtractTenant(c)) {
+		err = os.ErrNotExist
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "The requested backup was not found on this server.",
+			})
+			return
+		}
+		middleware.CaptureAndAbort(c, err)
+		return
+	}
+
+	token, expires, err := tokens.NewBackupDownloadToken(middleware.ExtractTenant(c), s.ID(), c.Param("backup"), signedUrlLifetime(data.ExpiresIn))
+	if err != nil {
+		middleware.CaptureAndAbort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"url":        "/download/backup?token=" + url.QueryEscape(token),
+		"expires_at": expires,
+	})
+}
@@ -2,13 +2,21 @@ package router
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
+	"time"
 
 	"emperror.dev/errors"
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/installer"
 	"github.com/pterodactyl/wings/router/downloader"
 	"github.com/pterodactyl/wings/router/middleware"
 	"github.com/pterodactyl/wings/router/tokens"
@@ -37,9 +45,86 @@ func getServerLogs(c *gin.Context) {
 		return
 	}
 
+	out = s.RedactSecretLines(out)
+	h := sha256.New()
+	for _, line := range out {
+		_, _ = io.WriteString(h, line+"\n")
+	}
+	c.Header("ETag", middleware.ETag(h))
 	c.JSON(http.StatusOK, gin.H{"data": out})
 }
 
+// Returns the installation logs for previous installation attempts of a server.
+func getServerInstallLogs(c *gin.Context) {
+	s := ExtractServer(c)
+
+	logs, err := s.InstallLogs()
+	if err != nil {
+		NewServerError(err, s).Abort(c)
+		return
+	}
+
+	h := sha256.New()
+	for _, l := range logs {
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", l.ID, l.Size, l.CreatedAt.UnixNano())
+	}
+	c.Header("ETag", middleware.ETag(h))
+	c.JSON(http.StatusOK, gin.H{"data": logs})
+}
+
+// Returns the contents of the installation log for a single installation attempt.
+func getServerInstallLog(c *gin.Context) {
+	s := ExtractServer(c)
+
+	r, err := s.OpenInstallLog(c.Param("log"))
+	if err != nil {
+		NewServerError(err, s).Abort(c)
+		return
+	}
+	defer r.Close()
+
+	c.DataFromReader(http.StatusOK, -1, "text/plain; charset=utf-8", r, nil)
+}
+
+// Returns the crash reports collected for the server.
+func getServerCrashReports(c *gin.Context) {
+	s := ExtractServer(c)
+
+	reports, err := s.CrashReports()
+	if err != nil {
+		NewServerError(err, s).Abort(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": reports})
+}
+
+// Downloads a single crash report for the server.
+func getServerCrashReport(c *gin.Context) {
+	s := ExtractServer(c)
+
+	p, err := s.CrashReportPath(c.Param("report"))
+	if err != nil {
+		NewServerError(err, s).Abort(c)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+strconv.Quote(filepath.Base(p)))
+	c.Header("Content-Type", "application/json")
+	c.File(p)
+}
+
+// Returns the maintenance windows for the server and if one is currently active,
+// allowing the Panel to skip scheduled tasks while the server is under maintenance.
+func getServerMaintenance(c *gin.Context) {
+	s := ExtractServer(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"active":  s.InMaintenance(time.Now()),
+		"windows": s.MaintenanceWindows(),
+	})
+}
+
 // Handles a request to control the power state of a server. If the action being passed
 // through is invalid a 404 is returned. Otherwise, a HTTP/202 Accepted response is returned
 // and the actual power action is run asynchronously so that we don't have to block the
@@ -118,14 +203,18 @@ func postServerCommands(c *gin.Context) {
 
 	var data struct {
 		Commands []string `json:"commands"`
+		// The user or system sending the commands, which is recorded in the command
+		// history of the server.
+		Actor string `json:"actor"`
 	}
 	// BindJSON sends 400 if the request fails, all we need to do is return
 	if err := c.BindJSON(&data); err != nil {
 		return
 	}
 
+	origin := server.CommandOrigin{Source: server.CommandSourceApi, Actor: data.Actor}
 	for _, command := range data.Commands {
-		if err := s.Environment.SendCommand(command); err != nil {
+		if err := s.SendCommand(command, origin); err != nil {
 			s.Log().WithFields(log.Fields{"command": command, "error": err}).Warn("failed to send command to server instance")
 		}
 	}
@@ -142,9 +231,16 @@ func postServerSync(c *gin.Context) {
 
 	if err := s.Sync(); err != nil {
 		WithError(c, err)
-	} else {
-		c.Status(http.StatusNoContent)
+		return
+	}
+	s.SyncWriteDenylist()
+
+	// The Panel syncs the server when it is suspended or unsuspended, so apply that
+	// state to the server if it is currently running.
+	if err := s.EnforceSuspension(c.Request.Context()); err != nil {
+		s.Log().WithField("error", err).Warn("failed to enforce suspension state for server")
 	}
+	c.Status(http.StatusNoContent)
 }
 
 // Performs a server installation in a background thread.
@@ -171,8 +267,15 @@ func postServerReinstall(c *gin.Context) {
 		return
 	}
 
+	var opts server.ReinstallOptions
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&opts); err != nil {
+			return
+		}
+	}
+
 	go func(s *server.Server) {
-		if err := s.Reinstall(); err != nil {
+		if err := s.Reinstall(opts); err != nil {
 			s.Log().WithField("error", err).Error("failed to complete server re-install process")
 		}
 	}(s)
@@ -180,9 +283,179 @@ func postServerReinstall(c *gin.Context) {
 	c.Status(http.StatusAccepted)
 }
 
+// Clones the files of a server into a new server created by the Panel, this is
+// done in a background thread once the new server has been configured.
+func postServerClone(c *gin.Context) {
+	s := ExtractServer(c)
+	manager := middleware.ExtractManager(c)
+
+	var data struct {
+		Uuid              string   `json:"uuid"`
+		Ignore            []string `json:"ignore"`
+		StartOnCompletion bool     `json:"start_on_completion"`
+	}
+	if err := c.BindJSON(&data); err != nil {
+		return
+	}
+
+	if data.Uuid == s.ID() {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "A server cannot be cloned into itself.",
+		})
+		return
+	}
+	if s.IsInstalling() || s.IsTransferring() || s.IsRestoring() {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error": "A server cannot be cloned while it is being installed, transferred or restored.",
+		})
+		return
+	}
+	if _, ok := manager.Get(data.Uuid); ok {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error": "The target server already exists on this node.",
+		})
+		return
+	}
+
+	install, err := installer.NewForTenant(c.Request.Context(), manager, middleware.ExtractTenant(c), installer.ServerDetails{
+		UUID:              data.Uuid,
+		StartOnCompletion: data.StartOnCompletion,
+	})
+	if err != nil {
+		if installer.IsValidationError(err) {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "The data provided in the request could not be validated.",
+			})
+			return
+		}
+
+		middleware.CaptureAndAbort(c, err)
+		return
+	}
+
+	if err := manager.CheckOvercommit(install.Server()); err != nil {
+		_ = server.ReleaseServerUser(install.Server().ID())
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error": "The node does not have enough resources available to create this server.",
+		})
+		return
+	}
+	manager.Add(install.Server())
+
+	go func(src *server.Server, i *installer.Installer) {
+		if err := i.Server().CreateEnvironment(); err != nil {
+			i.Server().Log().WithField("error", err).Error("failed to create server environment during clone process")
+			// The Panel is waiting for the clone to finish, so let it know that the server
+			// could not be created.
+			if err := i.Server().SyncInstallState(false); err != nil {
+				i.Server().Log().WithField("error", err).Warn("failed to notify panel of server install state")
+			}
+			return
+		}
+
+		if err := i.Server().CloneFrom(src, data.Ignore); err != nil {
+			i.Server().Log().WithField("error", err).Error("failed to clone files from source server")
+			return
+		}
+
+		if i.StartOnCompletion {
+			if err := i.Server().HandlePowerAction(server.PowerActionStart, 30); err != nil {
+				i.Server().Log().WithFields(log.Fields{"action": "start", "error": err}).Error("encountered error processing a server power action in the background")
+			}
+		}
+	}(s, install)
+
+	c.Status(http.StatusAccepted)
+}
+
+// Imports files into a server from a directory or archive on the host, or from an
+// archive uploaded as the "file" field of a multipart form. Host paths must be
+// within one of the allowed import paths for the node. The import is performed in
+// a background thread once the source has been validated.
+func postServerImport(c *gin.Context) {
+	s := ExtractServer(c)
+
+	if s.Environment.State() != environment.ProcessOfflineState {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error": "Cannot import files into a server that is running.",
+		})
+		return
+	}
+
+	var source string
+	var cleanup bool
+	if c.ContentType() == "multipart/form-data" {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "No archive was found on the request body.",
+			})
+			return
+		}
+		if limit := s.RestoreUploadLimit(); header.Size > limit*1024*1024 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "The archive is larger than the maximum upload size of " + strconv.FormatInt(limit, 10) + " MB.",
+			})
+			return
+		}
+		if err := os.MkdirAll(config.Get().System.TmpDirectory, 0o700); err != nil {
+			NewServerError(err, s).Abort(c)
+			return
+		}
+		// Keep the name of the uploaded file so that the archive format can be determined
+		// from its extension.
+		f, err := os.CreateTemp(config.Get().System.TmpDirectory, "import-*-"+filepath.Base(header.Filename))
+		if err != nil {
+			NewServerError(err, s).Abort(c)
+			return
+		}
+		_ = f.Close()
+		if err := c.SaveUploadedFile(header, f.Name()); err != nil {
+			_ = os.Remove(f.Name())
+			NewServerError(err, s).Abort(c)
+			return
+		}
+		source, cleanup = f.Name(), true
+	} else {
+		var data struct {
+			Path string `json:"path"`
+		}
+		if err := c.BindJSON(&data); err != nil {
+			return
+		}
+		// Resolve any symlinks before checking the path so that a link within an allowed
+		// directory cannot be used to import files from elsewhere on the host.
+		p, err := filepath.EvalSymlinks(data.Path)
+		if err != nil || !server.IsImportPathAllowed(p) {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "The path provided is not in the list of allowed import paths for this node.",
+			})
+			return
+		}
+		source = p
+	}
+
+	go func(s *server.Server) {
+		if cleanup {
+			defer os.Remove(source)
+		}
+		if err := s.Import(s.Context(), source); err != nil {
+			s.Log().WithField("error", err).Error("failed to import files for server")
+			s.PublishConsoleOutputFromDaemon("Failed to import server files.")
+			return
+		}
+		s.PublishConsoleOutputFromDaemon("Finished importing server files.")
+	}(s)
+
+	c.Status(http.StatusAccepted)
+}
+
 // Deletes a server from the wings daemon and dissociate it's objects.
 func deleteServer(c *gin.Context) {
 	s := middleware.ExtractServer(c)
+	if !requireConfirmation(c, s, tokens.ConfirmDeleteServer) {
+		return
+	}
 
 	// Immediately suspend the server to prevent a user from attempting
 	// to start it while this process is running.
@@ -202,17 +475,52 @@ func deleteServer(c *gin.Context) {
 		return
 	}
 
+	// Remove the backup of the server files created when it was last reinstalled.
+	if err := s.RemoveReinstallBackup(); err != nil {
+		s.Log().WithField("error", err).Warn("failed to remove reinstall backup during deletion process")
+	}
+
+	// Remove the logs for every installation attempt of the server.
+	if err := s.RemoveInstallLogs(); err != nil {
+		s.Log().WithField("error", err).Warn("failed to remove installation logs during deletion process")
+	}
+
+	// Remove the console logs written for the server.
+	if err := s.RemoveConsoleLogs(); err != nil {
+		s.Log().WithField("error", err).Warn("failed to remove console logs during deletion process")
+	}
+
+	// Remove the crash reports collected for the server.
+	if err := s.RemoveCrashReports(); err != nil {
+		s.Log().WithField("error", err).Warn("failed to remove crash reports during deletion process")
+	}
+
+	// Remove the command macros and history of the server.
+	if err := s.RemoveCommandMacros(); err != nil {
+		s.Log().WithField("error", err).Warn("failed to remove command macros during deletion process")
+	}
+	s.ClearCommandHistory()
+
 	// Once the environment is terminated, remove the server files from the system. This is
 	// done in a separate process since failure is not the end of the world and can be
 	// manually cleaned up after the fact.
 	//
 	// In addition, servers with large amounts of files can take some time to finish deleting
 	// so we don't want to block the HTTP call while waiting on this.
-	go func(p string) {
+	go func(p string, uuid string) {
 		if err := os.RemoveAll(p); err != nil {
 			log.WithFields(log.Fields{"path": p, "error": err}).Warn("failed to remove server files during deletion process")
+			return
+		}
+		// Only release the user assigned to the server once its files are removed,
+		// otherwise a new server could be given access to them.
+		if err := server.ReleaseServerUser(uuid); err != nil {
+			log.WithFields(log.Fields{"server": uuid, "error": err}).Warn("failed to release user assigned to deleted server")
+		}
+		if err := server.ReleaseTransferred(uuid); err != nil {
+			log.WithFields(log.Fields{"server": uuid, "error": err}).Warn("failed to remove transfer record for deleted server")
 		}
-	}(s.Filesystem().Path())
+	}(s.Filesystem().Path(), s.ID())
 
 	middleware.ExtractManager(c).Remove(func(server *server.Server) bool {
 		return server.ID() == s.ID()
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"io"
 	"net/http"
 	"os"
 	"strings"
@@ -9,7 +10,9 @@ import (
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
 
+	"github.com/pterodactyl/wings/netguard"
 	"github.com/pterodactyl/wings/router/middleware"
+	"github.com/pterodactyl/wings/router/tokens"
 	"github.com/pterodactyl/wings/server"
 	"github.com/pterodactyl/wings/server/backup"
 )
@@ -29,16 +32,12 @@ func postServerBackup(c *gin.Context) {
 		return
 	}
 
-	var adapter backup.BackupInterface
-	switch data.Adapter {
-	case backup.LocalBackupAdapter:
-		adapter = backup.NewLocal(client, data.Uuid, data.Ignore)
-	case backup.S3BackupAdapter:
-		adapter = backup.NewS3(client, data.Uuid, data.Ignore)
-	default:
-		middleware.CaptureAndAbort(c, errors.New("router/backups: provided adapter is not valid: "+string(data.Adapter)))
+	adapter, err := backup.New(data.Adapter, client, data.Uuid, data.Ignore)
+	if err != nil {
+		middleware.CaptureAndAbort(c, err)
 		return
 	}
+	adapter.SetOwner(middleware.ExtractTenant(c), s.ID())
 
 	// Attach the server ID and the request ID to the adapter log context for easier
 	// parsing in the logs.
@@ -48,6 +47,10 @@ func postServerBackup(c *gin.Context) {
 	})
 
 	go func(b backup.BackupInterface, s *server.Server, logger *log.Entry) {
+		if err := s.WaitForBackupWindow(b.Identifier()); err != nil {
+			logger.WithField("error", err).Warn("router: backup was cancelled while waiting for the backup window")
+			return
+		}
 		if err := s.Backup(b); err != nil {
 			logger.WithField("error", errors.WithStackIf(err)).Error("router: failed to generate server backup")
 		}
@@ -71,7 +74,7 @@ func postServerRestoreBackup(c *gin.Context) {
 	logger := middleware.ExtractLogger(c)
 
 	var data struct {
-		Adapter           backup.AdapterType `binding:"required,oneof=wings s3" json:"adapter"`
+		Adapter           backup.AdapterType `binding:"required,oneof=wings s3 azure gcs" json:"adapter"`
 		TruncateDirectory bool               `json:"truncate_directory"`
 		// A UUID is always required for this endpoint, however the download URL
 		// is only present when the given adapter type is s3.
@@ -84,6 +87,20 @@ func postServerRestoreBackup(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The download_url field is required when the backup adapter is set to S3."})
 		return
 	}
+	// A restore overwrites files in the server even when the directory is not
+	// truncated first, so it always needs to be confirmed.
+	if !requireConfirmation(c, s, tokens.ConfirmRestoreBackup) {
+		return
+	}
+	// Check that a local backup belongs to this server before anything is truncated.
+	if data.Adapter == backup.LocalBackupAdapter {
+		if b, _, err := backup.LocateLocal(client, c.Param("backup")); err == nil && !localBackupOwnedBy(b, s.ID(), middleware.ExtractTenant(c)) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "The requested backup was not found on this server.",
+			})
+			return
+		}
+	}
 
 	s.SetRestoring(true)
 	hasError := true
@@ -127,6 +144,39 @@ func postServerRestoreBackup(c *gin.Context) {
 		return
 	}
 
+	// Backups stored in Azure or Google Cloud Storage are read directly from the
+	// storage configured on this node.
+	if data.Adapter == backup.AzureBackupAdapter || data.Adapter == backup.GcsBackupAdapter {
+		b, err := backup.New(data.Adapter, client, c.Param("backup"), "")
+		if err != nil {
+			middleware.CaptureAndAbort(c, err)
+			return
+		}
+		b.SetOwner(middleware.ExtractTenant(c), s.ID())
+		rb := b.(backup.RemoteBackup)
+		// The server context is used rather than the request context since the
+		// restoration continues after the request has been completed.
+		rc, err := rb.Open(s.Context())
+		if err != nil {
+			middleware.CaptureAndAbort(c, err)
+			return
+		}
+		go func(s *server.Server, b backup.BackupInterface, rc io.ReadCloser, logger *log.Entry) {
+			defer rc.Close()
+			logger.WithField("adapter", data.Adapter).Info("starting restoration process for server backup")
+			if err := s.RestoreBackup(b, rc); err != nil {
+				logger.WithField("error", errors.WithStack(err)).Error("failed to restore remote backup to server")
+			}
+			s.Events().Publish(server.DaemonMessageEvent, "Completed server restoration from remote backup.")
+			s.Events().Publish(server.BackupRestoreCompletedEvent, "")
+			logger.Info("completed server restoration from remote backup")
+			s.SetRestoring(false)
+		}(s, b, rc, logger)
+		hasError = false
+		c.Status(http.StatusAccepted)
+		return
+	}
+
 	// Since this is not a local backup we need to stream the archive and then
 	// parse over the contents as we go in order to restore it to the server.
 	httpClient := http.Client{}
@@ -171,12 +221,133 @@ func postServerRestoreBackup(c *gin.Context) {
 	c.Status(http.StatusAccepted)
 }
 
+// postServerVerifyBackup reads through a backup without restoring it to check
+// that every file within it can be read, and that its checksum matches the one
+// stored by the Panel. Local backups are read from the disk, Azure and GCS
+// backups from the storage configured on the node, while S3 backups are
+// streamed from the provided download URL, which cannot be an address within the
+// local network of the node.
+//
+// This endpoint blocks until the entire backup has been read.
+func postServerVerifyBackup(c *gin.Context) {
+	var data struct {
+		Adapter     backup.AdapterType `binding:"required,oneof=wings s3 azure gcs" json:"adapter"`
+		Checksum    string             `json:"checksum"`
+		DownloadUrl string             `json:"download_url"`
+	}
+	if err := c.BindJSON(&data); err != nil {
+		return
+	}
+	if data.Adapter == backup.S3BackupAdapter && data.DownloadUrl == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The download_url field is required when the backup adapter is set to S3."})
+		return
+	}
+
+	var res *backup.VerifyResult
+	if data.Adapter == backup.LocalBackupAdapter {
+		b, _, err := backup.LocateLocal(middleware.ExtractApiClient(c), c.Param("backup"))
+		if err == nil && !localBackupOwnedBy(b, middleware.ExtractServer(c).ID(), middleware.ExtractTenant(c)) {
+			err = os.ErrNotExist
+		}
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+					"error": "The requested backup was not found on this server.",
+				})
+				return
+			}
+			middleware.CaptureAndAbort(c, err)
+			return
+		}
+		if res, err = b.Verify(c.Request.Context(), data.Checksum); err != nil {
+			middleware.CaptureAndAbort(c, err)
+			return
+		}
+	} else if data.Adapter == backup.AzureBackupAdapter || data.Adapter == backup.GcsBackupAdapter {
+		b, err := backup.New(data.Adapter, middleware.ExtractApiClient(c), c.Param("backup"), "")
+		if err != nil {
+			middleware.CaptureAndAbort(c, err)
+			return
+		}
+		b.SetOwner(middleware.ExtractTenant(c), middleware.ExtractServer(c).ID())
+		rc, err := b.(backup.RemoteBackup).Open(c.Request.Context())
+		if err != nil {
+			middleware.CaptureAndAbort(c, err)
+			return
+		}
+		defer rc.Close()
+		if res, err = backup.Verify(c.Request.Context(), rc, data.Checksum); err != nil {
+			middleware.CaptureAndAbort(c, err)
+			return
+		}
+	} else {
+		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, data.DownloadUrl, nil)
+		if err != nil {
+			middleware.CaptureAndAbort(c, err)
+			return
+		}
+		// The download URL is provided by the Panel, so make sure that it cannot be used
+		// to make requests to services on the local network of the node.
+		r, err := netguard.Client(0).Do(req)
+		if err != nil {
+			if errors.Is(err, netguard.ErrInternalAddress) {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"error": "The provided download link resolves to an internal network address.",
+				})
+				return
+			}
+			middleware.CaptureAndAbort(c, err)
+			return
+		}
+		defer r.Body.Close()
+		if r.StatusCode != http.StatusOK {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "The backup could not be downloaded from the provided link, the remote responded with \"" + r.Status + "\".",
+			})
+			return
+		}
+		if res, err = backup.Verify(c.Request.Context(), r.Body, data.Checksum); err != nil {
+			middleware.CaptureAndAbort(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
 // deleteServerBackup deletes a local backup of a server. If the backup is not
 // found on the machine just return a 404 error. The service calling this
 // endpoint can make its own decisions as to how it wants to handle that
 // response.
+//
+// Backups stored in Azure or Google Cloud Storage are deleted from there when
+// the adapter is passed in the query string.
 func deleteServerBackup(c *gin.Context) {
+	if adapter := backup.AdapterType(c.Query("adapter")); adapter == backup.AzureBackupAdapter || adapter == backup.GcsBackupAdapter {
+		b, err := backup.New(adapter, middleware.ExtractApiClient(c), c.Param("backup"), "")
+		if err != nil {
+			middleware.CaptureAndAbort(c, err)
+			return
+		}
+		b.SetOwner(middleware.ExtractTenant(c), middleware.ExtractServer(c).ID())
+		if err := b.Remove(); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+					"error": "The requested backup was not found on this server.",
+				})
+				return
+			}
+			middleware.CaptureAndAbort(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+		return
+	}
+
 	b, _, err := backup.LocateLocal(middleware.ExtractApiClient(c), c.Param("backup"))
+	if err == nil && !localBackupOwnedBy(b, middleware.ExtractServer(c).ID(), middleware.ExtractTenant(c)) {
+		err = os.ErrNotExist
+	}
 	if err != nil {
 		// Just return from the function at this point if the backup was not located.
 		if errors.Is(err, os.ErrNotExist) {
@@ -0,0 +1,116 @@
+package router
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+
+	"github.com/pterodactyl/wings/server"
+)
+
+// Returns the recent commands sent to the server, with the most recent first.
+func getServerCommandHistory(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": ExtractServer(c).CommandHistory()})
+}
+
+// Returns the command macros defined for the server.
+func getServerMacros(c *gin.Context) {
+	s := ExtractServer(c)
+
+	macros, err := s.CommandMacros()
+	if err != nil {
+		NewServerError(err, s).Abort(c)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": macros})
+}
+
+// Creates or replaces the command macro with the name in the URL.
+func putServerMacro(c *gin.Context) {
+	s := ExtractServer(c)
+
+	var m server.CommandMacro
+	if err := c.BindJSON(&m); err != nil {
+		return
+	}
+	m.Name = c.Param("macro")
+	if err := m.Validate(); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "The macro could not be validated: " + err.Error(),
+		})
+		return
+	}
+	if err := s.SaveCommandMacro(m); err != nil {
+		NewServerError(err, s).Abort(c)
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+// Removes a command macro from the server.
+func deleteServerMacro(c *gin.Context) {
+	s := ExtractServer(c)
+
+	if err := s.DeleteCommandMacro(c.Param("macro")); err != nil {
+		if errors.Is(err, server.ErrMacroNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "The requested macro does not exist for this server.",
+			})
+			return
+		}
+		NewServerError(err, s).Abort(c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Sends the commands in a macro to the server. The commands are sent in the
+// background since a macro can wait between each command, so this returns as
+// soon as the macro has been started.
+func postServerMacroRun(c *gin.Context) {
+	s := ExtractServer(c)
+
+	// The body is optional, and only used to record who ran the macro.
+	var data struct {
+		Actor string `json:"actor"`
+	}
+	if err := c.ShouldBindJSON(&data); err != nil && !errors.Is(err, io.EOF) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "The data passed in the request was not in a parsable format. Please try again.",
+		})
+		return
+	}
+
+	m, err := s.CommandMacro(c.Param("macro"))
+	if err != nil {
+		if errors.Is(err, server.ErrMacroNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "The requested macro does not exist for this server.",
+			})
+			return
+		}
+		NewServerError(err, s).Abort(c)
+		return
+	}
+
+	if running, err := s.Environment.IsRunning(c.Request.Context()); err != nil {
+		NewServerError(err, s).Abort(c)
+		return
+	} else if !running {
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+			"error": "Cannot send commands to a stopped server instance.",
+		})
+		return
+	}
+
+	go func(s *server.Server, m server.CommandMacro, actor string) {
+		if err := s.RunCommandMacro(context.Background(), m, actor); err != nil {
+			s.Log().WithField("macro", m.Name).WithField("error", err).Warn("failed to run command macro for server")
+		}
+	}(s, m, data.Actor)
+
+	c.Status(http.StatusAccepted)
+}
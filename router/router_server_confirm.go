@@ -0,0 +1,75 @@
+package router
+
+import (
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/router/middleware"
+	"github.com/pterodactyl/wings/router/tokens"
+	"github.com/pterodactyl/wings/server"
+)
+
+// Issues a token confirming a destructive action on the server, which must be
+// sent in the X-Confirmation-Token header of the request for the action when
+// confirmations are required. This allows the Panel to ask the user to confirm
+// the action before it is requested, knowing that the node enforces it.
+func postServerConfirmation(c *gin.Context) {
+	s := ExtractServer(c)
+
+	var data struct {
+		Action string `json:"action"`
+	}
+	if err := c.BindJSON(&data); err != nil {
+		return
+	}
+	if !tokens.IsConfirmableAction(data.Action) {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "The action must be one of \"" + tokens.ConfirmDeleteServer + "\", \"" + tokens.ConfirmDeleteFiles + "\" or \"" + tokens.ConfirmRestoreBackup + "\".",
+		})
+		return
+	}
+
+	cfg := config.Get().Api
+	token, expires, err := tokens.NewConfirmationToken(s.ID(), data.Action, time.Duration(cfg.ConfirmationLifetime)*time.Second)
+	if err != nil {
+		NewServerError(err, s).Abort(c)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"action":     data.Action,
+		"expires_at": expires,
+		"required":   cfg.RequireConfirmation,
+	})
+}
+
+// requireConfirmation checks that the request includes a valid confirmation
+// token for the action when confirmations are required, aborting the request if
+// it does not. The token is used up even if the action then fails.
+func requireConfirmation(c *gin.Context, s *server.Server, action string) bool {
+	if !config.Get().Api.RequireConfirmation {
+		return true
+	}
+	if tokens.UseConfirmationToken(c.GetHeader("X-Confirmation-Token"), s.ID(), action) {
+		return true
+	}
+	middleware.AbortWithError(c, http.StatusPreconditionRequired, middleware.ErrCodeConfirmation, "This action must be confirmed, a valid confirmation token for it was not provided.")
+	return false
+}
+
+// deletesFromRoot returns true if any of the files being deleted are in the root
+// directory of the server. Every delete at the root requires confirmation, rather
+// than only one that removes every file, since otherwise the check could be avoided
+// by leaving out a single file or by splitting the delete across requests.
+func deletesFromRoot(root string, files []string) bool {
+	for _, f := range files {
+		if path.Dir(path.Join("/", root, f)) == "/" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestDeletesFromRoot(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("deletesFromRoot", func() {
+		g.It("is true when any file is in the root directory", func() {
+			g.Assert(deletesFromRoot("/", []string{"server.jar"})).IsTrue()
+			g.Assert(deletesFromRoot("", []string{"logs/latest.log", "world"})).IsTrue()
+			g.Assert(deletesFromRoot("/", []string{"."})).IsTrue()
+		})
+
+		g.It("resolves the root and file names before checking them", func() {
+			g.Assert(deletesFromRoot("/logs", []string{"../server.jar"})).IsTrue()
+			g.Assert(deletesFromRoot("/logs/../", []string{"world"})).IsTrue()
+			g.Assert(deletesFromRoot("/logs", []string{"old/../latest.log"})).IsFalse()
+		})
+
+		g.It("is false for files in a sub-directory", func() {
+			g.Assert(deletesFromRoot("/logs", []string{"latest.log", "debug.log"})).IsFalse()
+			g.Assert(deletesFromRoot("/", []string{"world/region/r.0.0.mca"})).IsFalse()
+		})
+	})
+}
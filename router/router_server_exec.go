@@ -0,0 +1,229 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	ws "github.com/gorilla/websocket"
+
+	"github.com/pterodactyl/wings/environment/docker"
+	"github.com/pterodactyl/wings/router/websocket"
+	"github.com/pterodactyl/wings/server"
+)
+
+// The events that can be sent as text messages over an exec websocket. Binary
+// messages are written directly to the stdin of the command.
+const (
+	// Writes the first argument to the stdin of the command.
+	execStdinEvent = "stdin"
+	// Resizes the TTY, the arguments are the number of columns and rows.
+	execResizeEvent = "resize"
+)
+
+// The maximum amount of output returned when executing a command using the HTTP
+// endpoint, anything beyond this is discarded.
+const execOutputLimit = 1024 * 1024
+
+// Returns the docker environment for the server, aborting the request if the
+// server is not running in a docker environment.
+func execEnvironment(c *gin.Context, s *server.Server) (*docker.Environment, bool) {
+	env, ok := s.Environment.(*docker.Environment)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "This server's environment does not support executing commands.",
+		})
+	}
+	return env, ok
+}
+
+// Aborts the request with an appropriate response for an error returned when
+// creating an exec instance.
+func abortExecError(c *gin.Context, s *server.Server, err error) {
+	if errors.Is(err, docker.ErrNotRunning) {
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+			"error": "Cannot execute commands in a stopped server instance.",
+		})
+		return
+	}
+	if errors.Is(err, docker.ErrExecUserNotAllowed) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "Commands can only be executed as the user the server container runs as.",
+		})
+		return
+	}
+	NewServerError(err, s).Abort(c)
+}
+
+// postServerExec executes a command inside the server container and returns the
+// output and exit code once it completes. This is only accessible using the node
+// authentication token of the primary Panel and is intended for administrators
+// debugging a server.
+func postServerExec(c *gin.Context) {
+	if !primaryPanelOnly(c) {
+		return
+	}
+	s := ExtractServer(c)
+	env, ok := execEnvironment(c, s)
+	if !ok {
+		return
+	}
+
+	var data struct {
+		Command []string `json:"command" binding:"required,min=1"`
+		User    string   `json:"user"`
+		Timeout int      `json:"timeout"`
+	}
+	if err := c.BindJSON(&data); err != nil {
+		return
+	}
+	if data.Timeout <= 0 || data.Timeout > 300 {
+		data.Timeout = 30
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Second*time.Duration(data.Timeout))
+	defer cancel()
+
+	ex, err := env.Exec(ctx, docker.ExecOptions{Cmd: data.Command, User: data.User, Tty: true})
+	if err != nil {
+		abortExecError(c, s, err)
+		return
+	}
+	defer ex.Close()
+
+	// Closing the connection unblocks the read below if the command runs for longer
+	// than the timeout allows.
+	go func() {
+		<-ctx.Done()
+		ex.Close()
+	}()
+
+	var out bytes.Buffer
+	_, _ = io.Copy(&out, io.LimitReader(ex.Reader, execOutputLimit))
+
+	code, running, err := ex.ExitCode(context.Background())
+	if err != nil {
+		NewServerError(err, s).Abort(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"exit_code": code,
+		"running":   running,
+		"output":    out.String(),
+	})
+}
+
+// getServerExecWebsocket upgrades the request to a websocket and attaches it to
+// an interactive command running inside the server container with a TTY. Output
+// from the command is sent as binary messages, and binary messages received are
+// written to the command's stdin. Once the command exits the socket is closed with
+// the exit code as the reason. Like postServerExec this is only accessible to the
+// primary Panel.
+func getServerExecWebsocket(c *gin.Context) {
+	if !primaryPanelOnly(c) {
+		return
+	}
+	s := ExtractServer(c)
+	env, ok := execEnvironment(c, s)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	ex, err := env.Exec(ctx, docker.ExecOptions{Cmd: c.QueryArray("cmd"), User: c.Query("user"), Tty: true})
+	if err != nil {
+		abortExecError(c, s, err)
+		return
+	}
+	defer ex.Close()
+
+	upgrader := ws.Upgrader{}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	logger := s.Log().WithField("exec_id", ex.ID())
+	logger.Info("opening exec connection to server container")
+	defer logger.Info("closing exec connection to server container")
+
+	// If the server is deleted or the client disconnects, close the exec connection
+	// which will cause the output loop below to exit.
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.Context().Done():
+		}
+		ex.Close()
+	}()
+
+	go func() {
+		defer cancel()
+		for {
+			t, p, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if t == ws.BinaryMessage {
+				if _, err := ex.Conn.Write(p); err != nil {
+					return
+				}
+				continue
+			}
+
+			var m websocket.Message
+			if err := json.Unmarshal(p, &m); err != nil {
+				continue
+			}
+			switch m.Event {
+			case execStdinEvent:
+				if len(m.Args) > 0 {
+					if _, err := ex.Conn.Write([]byte(m.Args[0])); err != nil {
+						return
+					}
+				}
+			case execResizeEvent:
+				if len(m.Args) != 2 {
+					continue
+				}
+				w, _ := strconv.ParseUint(m.Args[0], 10, 16)
+				h, _ := strconv.ParseUint(m.Args[1], 10, 16)
+				if w > 0 && h > 0 {
+					if err := ex.Resize(ctx, uint(h), uint(w)); err != nil {
+						logger.WithField("error", err).Debug("failed to resize exec tty")
+					}
+				}
+			}
+		}
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := ex.Reader.Read(buf)
+		if n > 0 {
+			if werr := conn.WriteMessage(ws.BinaryMessage, buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	reason := "exec closed"
+	if code, running, err := ex.ExitCode(context.Background()); err == nil && !running {
+		reason = fmt.Sprintf("exit code: %d", code)
+	}
+	_ = conn.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(ws.CloseNormalClosure, reason), time.Now().Add(time.Second*5))
+}
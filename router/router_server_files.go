@@ -3,6 +3,8 @@ package router
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -12,12 +14,15 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pterodactyl/wings/config"
 
 	"emperror.dev/errors"
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/pterodactyl/wings/router/downloader"
@@ -25,6 +30,7 @@ import (
 	"github.com/pterodactyl/wings/router/tokens"
 	"github.com/pterodactyl/wings/server"
 	"github.com/pterodactyl/wings/server/filesystem"
+	"github.com/pterodactyl/wings/system"
 )
 
 // getServerFileContents returns the contents of a file on the server.
@@ -68,12 +74,79 @@ func getServerFileContents(c *gin.Context) {
 // Returns the contents of a directory for a server.
 func getServerListDirectory(c *gin.Context) {
 	s := ExtractServer(c)
-	dir := c.Query("directory")
-	if stats, err := s.Filesystem().ListDirectory(dir); err != nil {
+
+	limit := config.Get().Api.DirectoryListingLimit
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", strconv.Itoa(limit)))
+	if perPage <= 0 || perPage > limit {
+		perPage = limit
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	opts := filesystem.ListOptions{
+		Offset:     (page - 1) * perPage,
+		Limit:      perPage,
+		Sort:       c.DefaultQuery("sort", filesystem.SortByName),
+		Descending: c.Query("order") == "desc",
+	}
+	if opts.Sort != filesystem.SortByName && opts.Sort != filesystem.SortBySize && opts.Sort != filesystem.SortByModified {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "The sort field must be one of \"name\", \"size\" or \"modified\".",
+		})
+		return
+	}
+
+	listing, err := s.Filesystem().ListDirectoryPage(c.Query("directory"), opts)
+	if err != nil {
 		WithError(c, err)
-	} else {
-		c.JSON(http.StatusOK, stats)
+		return
+	}
+
+	// The response body remains an array of files so that existing clients continue to
+	// work, with the pagination details returned in the headers.
+	c.Header("X-Pagination-Total", strconv.Itoa(listing.Total))
+	c.Header("X-Pagination-Page", strconv.Itoa(page))
+	c.Header("X-Pagination-Per-Page", strconv.Itoa(perPage))
+	c.Header("ETag", listingETag(listing))
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	// Each entry is encoded and written as it is reached rather than marshaling the
+	// entire listing into memory at once.
+	w := bufio.NewWriter(c.Writer)
+	_ = w.WriteByte('[')
+	var written int
+	for i := range listing.Files {
+		b, err := listing.Files[i].MarshalJSON()
+		if err != nil {
+			s.Log().WithField("error", err).Warn("failed to marshal file in directory listing")
+			continue
+		}
+		if written > 0 {
+			_ = w.WriteByte(',')
+		}
+		_, _ = w.Write(b)
+		written++
+	}
+	_ = w.WriteByte(']')
+	_ = w.Flush()
+}
+
+// listingETag returns an ETag for a page of a directory listing, derived from the
+// details of each entry on the page rather than the encoded response so that it
+// is known before anything is written.
+func listingETag(listing *filesystem.DirectoryPage) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n", listing.Total)
+	for _, f := range listing.Files {
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00%d\x00%s\x00%s\x00", f.Name(), f.Size(), f.ModTime().UnixNano(), f.CTime().UnixNano(), f.Mode(), f.Mimetype)
+		if f.Attributes != nil {
+			fmt.Fprintf(h, "%+v", *f.Attributes)
+		}
+		h.Write([]byte{'\n'})
 	}
+	return middleware.ETag(h)
 }
 
 type renameFile struct {
@@ -146,6 +219,100 @@ func putServerRenameFiles(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// The data sent with the progress and completion events of a copy or move. The
+// error is only set for a completed operation that failed.
+type fileOperation struct {
+	Identifier string `json:"identifier"`
+	Operation  string `json:"operation"`
+	Bytes      int64  `json:"bytes"`
+	Total      int64  `json:"total"`
+	Error      string `json:"error,omitempty"`
+}
+
+// postServerCopyFiles copies files or directories to a new location on the
+// server in the background.
+func postServerCopyFiles(c *gin.Context) {
+	runFileOperation(c, "copy")
+}
+
+// postServerMoveFiles moves files or directories to a new location on the
+// server in the background.
+func postServerMoveFiles(c *gin.Context) {
+	runFileOperation(c, "move")
+}
+
+// runFileOperation copies or moves each of the files in the request in turn,
+// returning an identifier for the operation straight away. Progress events are
+// published for the server while the files are processed, followed by a single
+// completion event. The operation stops at the first file that fails.
+func runFileOperation(c *gin.Context, op string) {
+	s := ExtractServer(c)
+
+	var data struct {
+		Root  string       `json:"root"`
+		Files []renameFile `json:"files"`
+	}
+	if err := c.BindJSON(&data); err != nil {
+		return
+	}
+
+	if len(data.Files) == 0 {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "No files to " + op + " were provided.",
+		})
+		return
+	}
+
+	id := uuid.New().String()
+	go func() {
+		progress := &filesystem.Progress{}
+		event := func() fileOperation {
+			return fileOperation{Identifier: id, Operation: op, Bytes: progress.Done(), Total: progress.Total()}
+		}
+		done := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(2 * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					s.Events().Publish(server.FileOperationProgressEvent, event())
+				}
+			}
+		}()
+
+		var err error
+		for _, f := range data.Files {
+			from, to := path.Join(data.Root, f.From), path.Join(data.Root, f.To)
+			if op == "copy" {
+				_, err = s.Filesystem().CopyTo(s.Context(), from, to, progress)
+			} else {
+				err = s.Filesystem().MoveTo(s.Context(), from, to, progress)
+			}
+			if err != nil {
+				break
+			}
+		}
+		close(done)
+
+		e := event()
+		if err != nil {
+			s.Log().WithField("operation", op).WithField("error", err).Warn("failed to " + op + " server files")
+			e.Error = "An unexpected error was encountered while processing this request."
+			if errors.Is(err, os.ErrExist) {
+				e.Error = "Cannot " + op + " file, destination already exists."
+			} else if _, _, msg := NewServerError(err, s).getAsFilesystemError(); msg != "" {
+				e.Error = msg
+			}
+		}
+		s.Events().Publish(server.FileOperationCompletedEvent, e)
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{"identifier": id})
+}
+
 // Copies a server file.
 func postServerCopyFile(c *gin.Context) {
 	s := ExtractServer(c)
@@ -189,6 +356,9 @@ func postServerDeleteFiles(c *gin.Context) {
 		})
 		return
 	}
+	if deletesFromRoot(data.Root, data.Files) && !requireConfirmation(c, s, tokens.ConfirmDeleteFiles) {
+		return
+	}
 
 	g, ctx := errgroup.WithContext(context.Background())
 
@@ -439,7 +609,8 @@ func postServerDecompressFiles(c *gin.Context) {
 	}
 
 	lg.Info("starting file decompression")
-	if err := s.Filesystem().DecompressFile(data.RootPath, data.File); err != nil {
+	conflicts, err := s.Filesystem().DecompressFile(data.RootPath, data.File)
+	if err != nil {
 		// If the file is busy for some reason just return a nicer error to the user since there is not
 		// much we specifically can do. They'll need to stop the running server process in order to overwrite
 		// a file like this.
@@ -453,6 +624,13 @@ func postServerDecompressFiles(c *gin.Context) {
 		middleware.CaptureAndAbort(c, err)
 		return
 	}
+	// Return the files that had to be renamed or skipped so that they can be shown to
+	// the user, rather than them silently going missing.
+	if len(conflicts) > 0 {
+		lg.WithField("conflicts", len(conflicts)).Warn("renamed or skipped files with conflicting names when decompressing archive")
+		c.JSON(http.StatusOK, gin.H{"conflicts": conflicts})
+		return
+	}
 	c.Status(http.StatusNoContent)
 }
 
@@ -527,17 +705,122 @@ func postServerChmodFile(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// postServerFileAttributes changes the Windows attributes of the given files,
+// any attribute that is not provided is left as-is.
+func postServerFileAttributes(c *gin.Context) {
+	s := ExtractServer(c)
+
+	var data struct {
+		filesystem.AttributeChanges
+		Root  string   `json:"root"`
+		Files []string `json:"files"`
+	}
+	if err := c.BindJSON(&data); err != nil {
+		return
+	}
+
+	if len(data.Files) == 0 {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "No files to change the attributes of were provided.",
+		})
+		return
+	}
+
+	for _, f := range data.Files {
+		if err := s.Filesystem().SetAttributes(path.Join(data.Root, f), data.AttributeChanges); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			NewServerError(err, s).AbortFilesystemError(c)
+			return
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// checksumLimits tracks the number of checksum requests made for each server.
+var checksumLimits sync.Map
+
+// postServerFileChecksums computes the checksums of one or more files on the
+// server, allowing installed content to be verified without downloading it. The
+// SHA-256 checksum is returned if no algorithms are provided. Files that do not
+// exist are returned with a null checksum rather than failing the request.
+func postServerFileChecksums(c *gin.Context) {
+	s := ExtractServer(c)
+
+	var data struct {
+		Root       string   `json:"root"`
+		Files      []string `json:"files"`
+		Algorithms []string `json:"algorithms"`
+	}
+	if err := c.BindJSON(&data); err != nil {
+		return
+	}
+
+	if len(data.Files) == 0 {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "No files to compute the checksums of were provided.",
+		})
+		return
+	}
+	if max := config.Get().Api.ChecksumMaxFiles; max > 0 && len(data.Files) > max {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "The checksums of at most " + strconv.Itoa(max) + " files can be computed in a single request.",
+		})
+		return
+	}
+	if len(data.Algorithms) == 0 {
+		data.Algorithms = []string{filesystem.ChecksumSHA256}
+	}
+	for _, alg := range data.Algorithms {
+		if !filesystem.IsChecksumAlgorithm(alg) {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "The checksum algorithm \"" + alg + "\" is not supported.",
+			})
+			return
+		}
+	}
+
+	if limit := config.Get().Api.ChecksumRateLimit; limit > 0 {
+		r, _ := checksumLimits.LoadOrStore(s.ID(), system.NewRate(uint64(limit), time.Minute))
+		if !r.(*system.Rate).Try() {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "Too many checksum requests have been made for this server, please try again later.",
+			})
+			return
+		}
+	}
+
+	type fileChecksums struct {
+		File      string            `json:"file"`
+		Checksums map[string]string `json:"checksums"`
+	}
+	out := make([]fileChecksums, 0, len(data.Files))
+	for _, f := range data.Files {
+		sums, err := s.Filesystem().Checksums(path.Join(data.Root, f), data.Algorithms)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			NewServerError(err, s).AbortFilesystemError(c)
+			return
+		}
+		out = append(out, fileChecksums{File: f, Checksums: sums})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"files": out})
+}
+
 func postServerUploadFiles(c *gin.Context) {
 	manager := middleware.ExtractManager(c)
 
 	token := tokens.UploadPayload{}
-	if err := tokens.ParseToken([]byte(c.Query("token")), &token); err != nil {
+	tenant, err := tokens.ParseAnyToken([]byte(c.Query("token")), &token)
+	if err != nil {
 		NewTrackedError(err).Abort(c)
 		return
 	}
 
 	s, ok := manager.Get(token.ServerUuid)
-	if !ok || !token.IsUniqueRequest() {
+	if !ok || manager.ServerTenant(s.ID()) != tenant || !token.IsUniqueRequest() {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 			"error": "The requested resource was not found on this server.",
 		})
@@ -562,7 +845,7 @@ func postServerUploadFiles(c *gin.Context) {
 
 	directory := c.Query("directory")
 
-	maxFileSize := config.Get().Api.UploadLimit
+	maxFileSize := s.UploadLimit()
 	maxFileSizeBytes := maxFileSize * 1024 * 1024
 	var totalSize int64
 	for _, header := range headers {
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"net/http"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+
+	"github.com/pterodactyl/wings/router/middleware"
+	"github.com/pterodactyl/wings/server"
+)
+
+// postServerBackupCancel cancels a backup that is being generated for the server,
+// including one that is waiting for other backups to complete. The partial backup
+// is removed and the Panel is told that the backup failed.
+func postServerBackupCancel(c *gin.Context) {
+	cancelServerOperation(c, server.OperationBackup, c.Param("backup"), "There is no backup with that UUID in progress for this server.")
+}
+
+// postServerArchiveCancel cancels the creation of the transfer archive for the
+// server. The partial archive is removed and the Panel is told that the archive
+// could not be created.
+func postServerArchiveCancel(c *gin.Context) {
+	cancelServerOperation(c, server.OperationArchive, "", "There is no transfer archive being created for this server.")
+}
+
+// postServerTransferCancel cancels an incoming transfer of the server while its
+// archive is being downloaded from the source node. The partial archive is removed
+// and the Panel is told that the transfer failed. A transfer cannot be canceled
+// once its archive is being extracted.
+func postServerTransferCancel(c *gin.Context) {
+	cancelServerOperation(c, server.OperationTransfer, "", "There is no transfer in progress that can be canceled for this server.")
+}
+
+// cancelServerOperation cancels the operation on the server, responding with a
+// 404 error and the message if the operation is not in progress. The operation
+// stops and cleans up after itself in the background.
+func cancelServerOperation(c *gin.Context, kind, id, message string) {
+	s := middleware.ExtractServer(c)
+	if err := s.CancelOperation(kind, id); err != nil {
+		if errors.Is(err, server.ErrOperationNotFound) {
+			middleware.AbortWithError(c, http.StatusNotFound, middleware.ErrCodeNotFound, message)
+			return
+		}
+		middleware.CaptureAndAbort(c, err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
@@ -0,0 +1,37 @@
+package router
+
+import (
+	"net/http"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+
+	"github.com/pterodactyl/wings/environment/docker"
+)
+
+// getServerProcesses returns the processes running inside the server container so
+// that users can see what their server has spawned.
+func getServerProcesses(c *gin.Context) {
+	s := ExtractServer(c)
+	env, ok := s.Environment.(*docker.Environment)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "This server's environment does not support listing processes.",
+		})
+		return
+	}
+
+	procs, err := env.Processes(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, docker.ErrNotRunning) {
+			c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+				"error": "Cannot list the processes of a stopped server instance.",
+			})
+			return
+		}
+		NewServerError(err, s).Abort(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": procs})
+}
@@ -32,7 +32,7 @@ func getServerWebsocket(c *gin.Context) {
 	ctx, cancel := context.WithCancel(c.Request.Context())
 	defer cancel()
 
-	handler, err := websocket.GetHandler(s, c.Writer, c.Request)
+	handler, err := websocket.GetHandler(s, manager.ServerTenant(s.ID()), c.Writer, c.Request)
 	if err != nil {
 		NewServerError(err, s).Abort(c)
 		return
@@ -44,6 +44,10 @@ func getServerWebsocket(c *gin.Context) {
 	s.Websockets().Push(handler.Uuid(), &cancel)
 	handler.Logger().Debug("opening connection to server websocket")
 
+	// Resource usage may only be collected while someone is connected to the server.
+	release := s.WatchResources()
+	defer release()
+
 	defer func() {
 		s.Websockets().Remove(handler.Uuid())
 		handler.Logger().Debug("closing connection to server websocket")
@@ -4,20 +4,45 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
 
 	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/health"
 	"github.com/pterodactyl/wings/installer"
+	"github.com/pterodactyl/wings/remote/transport"
 	"github.com/pterodactyl/wings/router/middleware"
 	"github.com/pterodactyl/wings/server"
 	"github.com/pterodactyl/wings/system"
 )
 
-// Returns information about the system that wings is running on.
+// systemInformation is the information about the system returned to the Panel
+// along with the platform that server containers run on, so that the Panel can
+// tell which images are able to run on the node.
+type systemInformation struct {
+	*system.Information
+	Platform *environment.Platform `json:"container_platform,omitempty"`
+}
+
+// Returns information about the system that wings is running on. Tenants only
+// receive the version of Wings and the platform that server containers run on,
+// since the rest describes the entire node.
 func getSystemInformation(c *gin.Context) {
+	if middleware.ExtractTenant(c) != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"version":            system.Version,
+			"container_platform": containerPlatform(c.Request.Context()),
+		})
+		return
+	}
+
 	i, err := system.GetSystemInformation()
 	if err != nil {
 		NewTrackedError(err).Abort(c)
@@ -25,16 +50,137 @@ func getSystemInformation(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, i)
+	c.JSON(http.StatusOK, systemInformation{Information: i, Platform: containerPlatform(c.Request.Context())})
+}
+
+// containerPlatform returns the platform that server containers run on, or nil if
+// it could not be determined.
+func containerPlatform(ctx context.Context) *environment.Platform {
+	if config.Get().Containerd.Enabled {
+		return &environment.Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	}
+	cli, err := environment.Docker()
+	if err != nil {
+		return nil
+	}
+	p, err := environment.NodePlatform(ctx, cli)
+	if err != nil {
+		log.WithField("error", err).Warn("failed to get the platform of the node from docker")
+		return nil
+	}
+	return &p
+}
+
+// Returns the resources of the node and the amount allocated to servers, so that
+// the Panel can determine the headroom available for new servers. Tenants only
+// receive the resources allocated to their own servers.
+func getSystemResources(c *gin.Context) {
+	c.JSON(http.StatusOK, middleware.ExtractManager(c).TenantResources(middleware.ExtractTenant(c)))
+}
+
+// Returns the metrics for the requests this node has made to the Panel. Tenants
+// are only able to see the metrics for their own Panel.
+func getSystemMetrics(c *gin.Context) {
+	tenant := middleware.ExtractTenant(c)
+	panels := make([]transport.PanelMetrics, 0)
+	for _, p := range transport.Snapshot() {
+		if tenant == "" || p.Name == tenant {
+			panels = append(panels, p)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"panels": panels})
+}
+
+// Returns the long-running operations in progress on the node, such as installs,
+// backups and transfers, along with their progress. Tenants are only able to see
+// the operations for their own servers.
+func getSystemOperations(c *gin.Context) {
+	manager := middleware.ExtractManager(c)
+	tenant := middleware.ExtractTenant(c)
+	out := make([]*server.Operation, 0)
+	for _, op := range server.Operations() {
+		if tenant == "" || manager.ServerTenant(op.Server) == tenant {
+			out = append(out, op)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"operations": out})
+}
+
+// Returns the health of the node and the dependencies it needs to operate. A 503
+// response is returned if any check fails so that this can be used directly by
+// load balancers. Requests authorized with the node token receive the result of
+// every check performed at the time of the request, everyone else receives a
+// cached result so that the checks cannot be triggered by unauthenticated
+// requests, with tenants only receiving the checks relevant to them.
+func getSystemHealth(c *gin.Context) {
+	tenant, ok := "", false
+	if auth := strings.SplitN(c.GetHeader("Authorization"), " ", 2); len(auth) == 2 && auth[0] == "Bearer" {
+		tenant, ok = config.Get().TenantForToken(auth[1])
+	}
+
+	var report health.Report
+	if ok && tenant == "" {
+		report = health.Run(c.Request.Context())
+	} else {
+		report = health.Cached()
+	}
+	code := http.StatusOK
+	if !report.Healthy() {
+		code = http.StatusServiceUnavailable
+	}
+
+	if !ok {
+		c.JSON(code, gin.H{"status": report.Status})
+		return
+	}
+	if tenant != "" {
+		checks := make([]health.Check, 0, len(report.Checks))
+		for _, check := range report.Checks {
+			if check.Tenant == tenant || (check.Name != "panel" && check.Name != "clock") {
+				checks = append(checks, check)
+			}
+		}
+		report.Checks = checks
+	}
+	c.JSON(code, report)
 }
 
 // Returns all of the servers that are registered and configured correctly on
-// this wings instance.
+// this wings instance for the Panel making the request. The servers can be
+// filtered by their state using "state", "suspended" and "installing", limited
+// to certain fields using "fields", and split into pages using "page" and
+// "per_page", with the pagination details returned in the headers.
 func getAllServers(c *gin.Context) {
-	servers := middleware.ExtractManager(c).All()
-	out := make([]server.APIResponse, len(servers), len(servers))
-	for i, v := range servers {
-		out[i] = v.ToAPIResponse()
+	listing, err := parseServerListing(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	manager := middleware.ExtractManager(c)
+	tenant := middleware.ExtractTenant(c)
+	servers := manager.Filter(func(s *server.Server) bool {
+		return manager.ServerTenant(s.ID()) == tenant && listing.matches(s)
+	})
+	// Sort the servers so that each page is the same between requests.
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].ID() < servers[j].ID()
+	})
+	if listing.perPage > 0 {
+		c.Header("X-Pagination-Total", strconv.Itoa(len(servers)))
+		c.Header("X-Pagination-Page", strconv.Itoa(listing.page))
+		c.Header("X-Pagination-Per-Page", strconv.Itoa(listing.perPage))
+	}
+
+	servers = listing.paginate(servers)
+	out := make([]json.RawMessage, 0, len(servers))
+	for _, s := range servers {
+		b, err := listing.encode(s)
+		if err != nil {
+			s.Log().WithField("error", err).Warn("failed to marshal server in servers listing")
+			continue
+		}
+		out = append(out, b)
 	}
 	c.JSON(http.StatusOK, out)
 }
@@ -49,7 +195,7 @@ func postCreateServer(c *gin.Context) {
 		return
 	}
 
-	install, err := installer.New(c.Request.Context(), manager, details)
+	install, err := installer.NewForTenant(c.Request.Context(), manager, middleware.ExtractTenant(c), details)
 	if err != nil {
 		if installer.IsValidationError(err) {
 			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
@@ -62,6 +208,16 @@ func postCreateServer(c *gin.Context) {
 		return
 	}
 
+	// Refuse to create the server if it would overcommit the node, the server has not
+	// been added to the manager yet so only the user assigned to it needs cleaning up.
+	if err := manager.CheckOvercommit(install.Server()); err != nil {
+		_ = server.ReleaseServerUser(install.Server().ID())
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error": "The node does not have enough resources available to create this server.",
+		})
+		return
+	}
+
 	// Plop that server instance onto the request so that it can be referenced in
 	// requests from here-on out.
 	manager.Add(install.Server())
@@ -97,8 +253,13 @@ func postCreateServer(c *gin.Context) {
 	c.Status(http.StatusAccepted)
 }
 
-// Updates the running configuration for this Wings instance.
+// Updates the running configuration for this Wings instance. The configuration
+// includes the credentials used for the primary Panel, so it cannot be changed by
+// a tenant.
 func postUpdateConfiguration(c *gin.Context) {
+	if !primaryPanelOnly(c) {
+		return
+	}
 	cfg := config.Get()
 	if err := c.BindJSON(&cfg); err != nil {
 		return
@@ -0,0 +1,152 @@
+package router
+
+import (
+	"net/http"
+	"sync"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/router/middleware"
+	"github.com/pterodactyl/wings/server"
+)
+
+// configMu prevents the configuration from being changed by two requests at the
+// same time, since the entire configuration is written to the disk for each change.
+var configMu sync.Mutex
+
+// primaryPanelOnly aborts the request unless it was made by the primary Panel.
+// Settings such as the tasks for the node act on the servers of every tenant, so
+// they cannot be managed by any other Panel.
+func primaryPanelOnly(c *gin.Context) bool {
+	if middleware.ExtractTenant(c) != "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "This can only be managed by the primary Panel for the node.",
+		})
+		return false
+	}
+	return true
+}
+
+// getSystemTasks returns every task scheduled on the node, along with when it
+// next runs and the outcome of its last run.
+func getSystemTasks(c *gin.Context) {
+	if !primaryPanelOnly(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": middleware.ExtractManager(c).Tasks()})
+}
+
+// putSystemTask creates or replaces the task with the name in the URL, saving it
+// to the configuration file.
+func putSystemTask(c *gin.Context) {
+	if !primaryPanelOnly(c) {
+		return
+	}
+	var t config.ScheduledTask
+	if err := c.BindJSON(&t); err != nil {
+		return
+	}
+	t.Name = c.Param("task")
+	if err := t.Validate(config.Get().Tasks.HookDirectory); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "The task could not be validated: " + err.Error(),
+		})
+		return
+	}
+
+	err := updateTasks(func(tasks []config.ScheduledTask) []config.ScheduledTask {
+		for i := range tasks {
+			if tasks[i].Name == t.Name {
+				tasks[i] = t
+				return tasks
+			}
+		}
+		return append(tasks, t)
+	})
+	if err != nil {
+		middleware.CaptureAndAbort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// deleteSystemTask removes the task from the configuration file. A run of the
+// task that is in progress is allowed to complete.
+func deleteSystemTask(c *gin.Context) {
+	if !primaryPanelOnly(c) {
+		return
+	}
+	name := c.Param("task")
+	if _, ok := config.Get().Task(name); !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"error": "The requested task does not exist on this node.",
+		})
+		return
+	}
+
+	err := updateTasks(func(tasks []config.ScheduledTask) []config.ScheduledTask {
+		out := tasks[:0]
+		for _, t := range tasks {
+			if t.Name != name {
+				out = append(out, t)
+			}
+		}
+		return out
+	})
+	if err != nil {
+		middleware.CaptureAndAbort(c, err)
+		return
+	}
+	middleware.ExtractManager(c).ForgetTask(name)
+	c.Status(http.StatusNoContent)
+}
+
+// postSystemTaskRun runs the task in the background straight away.
+func postSystemTaskRun(c *gin.Context) {
+	if !primaryPanelOnly(c) {
+		return
+	}
+	if err := middleware.ExtractManager(c).RunTask(c.Param("task")); err != nil {
+		switch {
+		case errors.Is(err, server.ErrTaskNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "The requested task does not exist on this node.",
+			})
+		case errors.Is(err, server.ErrTaskRunning):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"error": "The requested task is already running.",
+			})
+		default:
+			middleware.CaptureAndAbort(c, err)
+		}
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// updateTasks writes the tasks returned by fn to the configuration file, and
+// then updates the running configuration so that the scheduler picks them up.
+func updateTasks(fn func(tasks []config.ScheduledTask) []config.ScheduledTask) error {
+	return updateConfig(func(c *config.Configuration) {
+		c.Tasks.Tasks = fn(append([]config.ScheduledTask{}, c.Tasks.Tasks...))
+	})
+}
+
+// updateConfig applies fn to a copy of the configuration and writes it to the
+// configuration file, and then applies the same change to the running
+// configuration once it has been saved. The fn must not modify any slices or
+// maps in place, since they are shared with the running configuration.
+func updateConfig(fn func(c *config.Configuration)) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	cfg := config.Get()
+	fn(cfg)
+	if err := config.WriteToDisk(cfg); err != nil {
+		return err
+	}
+	config.Update(fn)
+	return nil
+}
@@ -3,9 +3,9 @@ package router
 import (
 	"bufio"
 	"context"
-	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
@@ -19,11 +19,10 @@ import (
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/juju/ratelimit"
-	"github.com/mholt/archiver/v3"
 	"github.com/mitchellh/colorstring"
 
 	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/events/webhook"
 	"github.com/pterodactyl/wings/installer"
 	"github.com/pterodactyl/wings/remote"
 	"github.com/pterodactyl/wings/router/middleware"
@@ -31,6 +30,7 @@ import (
 	"github.com/pterodactyl/wings/server"
 	"github.com/pterodactyl/wings/server/filesystem"
 	"github.com/pterodactyl/wings/system"
+	"github.com/pterodactyl/wings/throttle"
 )
 
 // Number of ticks in the progress bar
@@ -44,12 +44,34 @@ type downloadProgress struct {
 	progress int64
 }
 
+// Data sent with a transfer progress event, the node is either "source" or
+// "target" depending on which side of the transfer sent it.
+type transferProgress struct {
+	Node  string `json:"node"`
+	Bytes int64  `json:"bytes"`
+	Total int64  `json:"total"`
+}
+
+// Data sent with a transfer integrity event. The source node sends the checksum of
+// the archive once it has been created, and the target node sends the result of
+// verifying the archive it received.
+type transferIntegrity struct {
+	Node         string `json:"node"`
+	ChecksumType string `json:"checksum_type"`
+	Checksum     string `json:"checksum"`
+	Computed     string `json:"computed,omitempty"`
+	Valid        *bool  `json:"valid,omitempty"`
+}
+
 // Data passed over to initiate a server transfer.
 type serverTransferRequest struct {
 	ServerID string                  `binding:"required" json:"server_id"`
 	URL      string                  `binding:"required" json:"url"`
 	Token    string                  `binding:"required" json:"token"`
 	Server   installer.ServerDetails `json:"server"`
+	// ChecksumType is the checksum algorithm the Panel allows the archive to be
+	// verified with. SHA-256 is always used unless the Panel opts in to xxh64.
+	ChecksumType string `json:"checksum_type"`
 }
 
 func getArchivePath(sID string) string {
@@ -68,13 +90,13 @@ func getServerArchive(c *gin.Context) {
 		return
 	}
 
+	s := ExtractServer(c)
 	token := tokens.TransferPayload{}
-	if err := tokens.ParseToken([]byte(auth[1]), &token); err != nil {
+	if err := tokens.ParseTenantToken([]byte(auth[1]), &token, middleware.ExtractManager(c).ServerTenant(s.ID())); err != nil {
 		NewTrackedError(err).Abort(c)
 		return
 	}
 
-	s := ExtractServer(c)
 	if token.Subject != s.ID() {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
 			"error": "Missing required token subject, or subject is not valid for the requested server.",
@@ -95,38 +117,49 @@ func getServerArchive(c *gin.Context) {
 		return
 	}
 
-	// Compute sha1 checksum.
-	h := sha256.New()
-	f, err := os.Open(archivePath)
+	sums, err := readArchiveChecksums(s.ID())
 	if err != nil {
-		return
-	}
-	if _, err := io.Copy(h, bufio.NewReader(f)); err != nil {
-		_ = f.Close()
 		_ = WithError(c, err)
 		return
 	}
-	if err := f.Close(); err != nil {
-		_ = WithError(c, err)
-		return
-	}
-	checksum := hex.EncodeToString(h.Sum(nil))
+	typ := negotiateChecksum(c.GetHeader(checksumAcceptHeader))
 
 	// Stream the file to the client.
-	f, err = os.Open(archivePath)
+	f, err := os.Open(archivePath)
 	if err != nil {
 		_ = WithError(c, err)
 		return
 	}
 	defer f.Close()
 
-	c.Header("X-Checksum", checksum)
+	c.Header("X-Checksum", sums.Get(typ))
+	c.Header(checksumTypeHeader, typ)
 	c.Header("X-Mime-Type", "application/tar+gzip")
 	c.Header("Content-Length", strconv.Itoa(int(st.Size())))
 	c.Header("Content-Disposition", "attachment; filename="+strconv.Quote(s.ID()+".tar.gz"))
 	c.Header("Content-Type", "application/octet-stream")
 
-	_, _ = bufio.NewReader(f).WriteTo(c.Writer)
+	progress := &downloadProgress{size: st.Size()}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(3 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.Events().Publish(server.TransferProgressEvent, progress.event("source"))
+			}
+		}
+	}()
+
+	if _, err := bufio.NewReader(f).WriteTo(io.MultiWriter(c.Writer, progress)); err != nil {
+		s.Log().WithField("error", err).Warn("failed to send transfer archive to target node")
+		return
+	}
+	s.Events().Publish(server.TransferProgressEvent, progress.event("source"))
 }
 
 func postServerArchive(c *gin.Context) {
@@ -146,6 +179,11 @@ func postServerArchive(c *gin.Context) {
 		s.Events().Publish(server.TransferStatusEvent, "starting")
 		sendTransferLog("Attempting to archive server...")
 
+		ctx, op, done := s.StartOperation(s.Context(), server.OperationArchive, "")
+		defer done()
+		op.SetTotal(s.Filesystem().CachedUsage())
+		ctx = filesystem.WithArchiveProgress(ctx, op)
+
 		hasError := true
 		defer func() {
 			if !hasError {
@@ -157,7 +195,7 @@ func postServerArchive(c *gin.Context) {
 			s.Events().Publish(server.TransferStatusEvent, "failure")
 
 			sendTransferLog("Attempting to notify panel of archive failure..")
-			if err := manager.Client().SetArchiveStatus(s.Context(), s.ID(), false); err != nil {
+			if err := manager.ServerClient(s.ID()).SetArchiveStatus(s.Context(), s.ID(), false); err != nil {
 				if !remote.IsRequestError(err) {
 					sendTransferLog("Failed to notify panel of archive failure: " + err.Error())
 					l.WithField("error", err).Error("failed to notify panel of failed archive status")
@@ -178,7 +216,7 @@ func postServerArchive(c *gin.Context) {
 
 		// Ensure the server is offline. Sometimes a "No such container" error gets through
 		// which means the server is already stopped. We can ignore that.
-		if err := s.Environment.WaitForStop(s.Context(), time.Minute, false); err != nil && !strings.Contains(strings.ToLower(err.Error()), "no such container") {
+		if err := s.Environment.WaitForStop(ctx, time.Minute, false); err != nil && !strings.Contains(strings.ToLower(err.Error()), "no such container") {
 			sendTransferLog("Failed to stop server, aborting transfer..")
 			l.WithField("error", err).Error("failed to stop server")
 			return
@@ -189,17 +227,30 @@ func postServerArchive(c *gin.Context) {
 			BasePath: s.Filesystem().Path(),
 		}
 
-		// Attempt to get an archive of the server.
-		if err := a.Create(getArchivePath(s.ID())); err != nil {
+		// Attempt to get an archive of the server, computing the checksums of it as it
+		// is written so that the archive does not need to be read again when it is
+		// requested by the target node.
+		sums, err := createTransferArchive(ctx, s.ID(), a)
+		if err != nil {
+			if ctx.Err() != nil && s.Context().Err() == nil {
+				sendTransferLog("Archiving the server was canceled.")
+				l.Info("transfer archive for server was canceled")
+				return
+			}
 			sendTransferLog("An error occurred while archiving the server: " + err.Error())
 			l.WithField("error", err).Error("failed to get transfer archive for server")
 			return
 		}
+		s.Events().Publish(server.TransferIntegrityEvent, transferIntegrity{
+			Node:         "source",
+			ChecksumType: ChecksumSHA256,
+			Checksum:     sums.SHA256,
+		})
 
-		sendTransferLog("Successfully created archive, attempting to notify panel..")
+		sendTransferLog("Successfully created archive (sha256: " + sums.SHA256 + "), attempting to notify panel..")
 		l.Info("successfully created server transfer archive, notifying panel..")
 
-		if err := manager.Client().SetArchiveStatus(s.Context(), s.ID(), true); err != nil {
+		if err := manager.ServerClient(s.ID()).SetArchiveStatus(s.Context(), s.ID(), true); err != nil {
 			if !remote.IsRequestError(err) {
 				sendTransferLog("Failed to notify panel of archive success: " + err.Error())
 				l.WithField("error", err).Error("failed to notify panel of successful archive status")
@@ -223,26 +274,63 @@ func postServerArchive(c *gin.Context) {
 	c.Status(http.StatusAccepted)
 }
 
+// Creates the transfer archive for the server and stores the checksums of it next
+// to the archive. Any checksums left over from a previous archive are removed first
+// so that they can never be sent alongside the wrong archive, and the partially
+// written archive is removed if it could not be created.
+func createTransferArchive(ctx context.Context, sID string, a *filesystem.Archive) (archiveChecksums, error) {
+	if err := os.Remove(getArchiveChecksumPath(sID)); err != nil && !os.IsNotExist(err) {
+		return archiveChecksums{}, err
+	}
+
+	f, err := os.OpenFile(getArchivePath(sID), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return archiveChecksums{}, err
+	}
+
+	w := newChecksumWriter()
+	err = a.Stream(ctx, io.MultiWriter(f, w))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(getArchivePath(sID))
+		return archiveChecksums{}, err
+	}
+	sums := w.Sums()
+	if err := writeArchiveChecksums(sID, sums); err != nil {
+		return archiveChecksums{}, err
+	}
+	return sums, nil
+}
+
 func (w *downloadProgress) Write(v []byte) (int, error) {
 	n := len(v)
 	atomic.AddInt64(&w.progress, int64(n))
 	return n, nil
 }
 
+// Returns the current progress as the data for a transfer progress event.
+func (w *downloadProgress) event(node string) transferProgress {
+	return transferProgress{Node: node, Bytes: atomic.LoadInt64(&w.progress), Total: w.size}
+}
+
 // Log helper function to attach all errors and info output to a consistently formatted
 // log string for easier querying.
 func (str serverTransferRequest) log() *log.Entry {
 	return log.WithField("subsystem", "transfers").WithField("server_id", str.ServerID)
 }
 
-// Downloads an archive from the machine that the server currently lives on.
-func (str serverTransferRequest) downloadArchive() (*http.Response, error) {
+// Downloads an archive from the machine that the server currently lives on. The
+// download stops when the context is canceled.
+func (str serverTransferRequest) downloadArchive(ctx context.Context) (*http.Response, error) {
 	client := http.Client{Timeout: 0}
-	req, err := http.NewRequest(http.MethodGet, str.URL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, str.URL, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Authorization", str.Token)
+	req.Header.Set(checksumAcceptHeader, strings.Join(acceptedChecksums(str.ChecksumType), ", "))
 	res, err := client.Do(req) // lgtm [go/request-forgery]
 	if err != nil {
 		return nil, err
@@ -281,21 +369,11 @@ func (str serverTransferRequest) removeArchivePath() {
 	str.log().Debug("deleted temporary transfer archive successfully")
 }
 
-// Verifies that the SHA-256 checksum of the file on the local filesystem matches the
-// expected value from the transfer request. The string value returned is the computed
-// checksum on the system.
-func (str serverTransferRequest) verifyChecksum(matches string) (bool, string, error) {
-	f, err := os.Open(str.path())
-	if err != nil {
-		return false, "", err
-	}
-	defer f.Close()
-	h := sha256.New()
-	if _, err := io.Copy(h, bufio.NewReader(f)); err != nil {
-		return false, "", err
-	}
+// Verifies that the checksum computed while the archive was downloaded matches the
+// one sent by the source node. The string value returned is the computed checksum.
+func (str serverTransferRequest) verifyChecksum(h hash.Hash, matches string) (bool, string) {
 	checksum := hex.EncodeToString(h.Sum(nil))
-	return checksum == matches, checksum, nil
+	return matches != "" && strings.EqualFold(checksum, matches), checksum
 }
 
 // Sends a notification to the Panel letting it know what the status of this transfer is.
@@ -329,15 +407,18 @@ func postTransfer(c *gin.Context) {
 	// the str.path() function to return a location not within the server archive directory.
 	data.ServerID = u.String()
 
+	tenant := middleware.ExtractTenant(c)
+	client := manager.TenantClient(tenant)
+
 	data.log().Info("handling incoming server transfer request")
 	go func(data *serverTransferRequest) {
 		hasError := true
 
 		// Create a new server installer. This will only configure the environment and not
 		// run the installer scripts.
-		i, err := installer.New(context.Background(), manager, data.Server)
+		i, err := installer.NewForTenant(context.Background(), manager, tenant, data.Server)
 		if err != nil {
-			_ = data.sendTransferStatus(manager.Client(), false)
+			_ = data.sendTransferStatus(client, false)
 			data.log().WithField("error", err).Error("failed to validate received server data")
 			return
 		}
@@ -353,10 +434,15 @@ func postTransfer(c *gin.Context) {
 		// then push the server into the global server collection for this instance.
 		i.Server().SetTransferring(true)
 		manager.Add(i.Server())
+
+		// The transfer can be canceled up until the archive starts being extracted.
+		ctx, op, done := i.Server().StartOperation(i.Server().Context(), server.OperationTransfer, "")
+		defer done()
+
 		defer func(s *server.Server) {
 			// In the event that this transfer call fails, remove the server from the global
 			// server tracking so that we don't have a dangling instance.
-			if err := data.sendTransferStatus(manager.Client(), !hasError); hasError || err != nil {
+			if err := data.sendTransferStatus(client, !hasError); hasError || err != nil {
 				sendTransferLog("Server transfer failed, check Wings logs for additional information.")
 				s.Events().Publish(server.TransferStatusEvent, "failure")
 				manager.Remove(func(match *server.Server) bool {
@@ -373,13 +459,14 @@ func postTransfer(c *gin.Context) {
 			} else {
 				s.SetTransferring(false)
 				s.Events().Publish(server.TransferStatusEvent, "success")
+				webhook.Dispatch(s.ID(), webhook.TransferCompletedEvent, nil)
 				sendTransferLog("Transfer completed.")
 			}
 		}(i.Server())
 
 		data.log().Info("downloading server archive from current server node")
 		sendTransferLog("Received incoming transfer from Panel, attempting to download archive from source node...")
-		res, err := data.downloadArchive()
+		res, err := data.downloadArchive(ctx)
 		if err != nil {
 			sendTransferLog("Failed to retrieve server archive from remote node: " + err.Error())
 			data.log().WithField("error", err).Error("failed to download archive for server transfer")
@@ -404,11 +491,16 @@ func postTransfer(c *gin.Context) {
 			return
 		}
 
+		// Whenever the transfer fails or succeeds, delete the temporary transfer archive that
+		// was created on the disk, including when it was only partially downloaded.
+		defer data.removeArchivePath()
+
 		sendTransferLog("Writing archive to disk...")
 		data.log().Info("writing transfer archive to disk...")
 
 		// Copy the file.
 		progress := &downloadProgress{size: size}
+		op.SetTotal(size)
 		ticker := time.NewTicker(3 * time.Second)
 		go func(progress *downloadProgress, t *time.Ticker) {
 			for range ticker.C {
@@ -424,23 +516,34 @@ func postTransfer(c *gin.Context) {
 				width := ((float64(p) / float64(size)) * 100) / tickPercentage
 				bar := strings.Repeat("=", int(width)) + strings.Repeat(" ", ticks-int(width))
 				sendTransferLog("Downloading [" + bar + "] " + system.FormatBytes(p) + " / " + system.FormatBytes(progress.size))
+				i.Server().Events().Publish(server.TransferProgressEvent, progress.event("target"))
 			}
 		}(progress, ticker)
 
-		var reader io.Reader
-		downloadLimit := float64(config.Get().System.Transfers.DownloadLimit) * 1024 * 1024
-		if downloadLimit > 0 {
-			// Wrap the body with a reader that is limited to the defined download limit speed.
-			reader = ratelimit.Reader(res.Body, ratelimit.NewBucketWithRate(downloadLimit, int64(downloadLimit)))
-		} else {
-			reader = res.Body
+		// Wrap the body with a reader that is limited to the defined download limit speed,
+		// which may change during the download if a schedule is configured.
+		reader := throttle.Reader(res.Body, func(now time.Time) int {
+			return config.Get().System.Transfers.DownloadLimitAt(now)
+		})
+
+		// The checksum of the archive is computed as it is downloaded rather than by
+		// reading it back from the disk afterwards.
+		checksumType := res.Header.Get(checksumTypeHeader)
+		if checksumType == "" {
+			checksumType = ChecksumSHA256
 		}
+		h := newChecksumHash(checksumType)
 
 		buf := make([]byte, 1024*4)
-		if _, err := io.CopyBuffer(file, io.TeeReader(reader, progress), buf); err != nil {
+		if _, err := io.CopyBuffer(file, io.TeeReader(reader, io.MultiWriter(progress, h, op)), buf); err != nil {
 			ticker.Stop()
 			_ = file.Close()
 
+			if ctx.Err() != nil {
+				sendTransferLog("Server transfer was canceled.")
+				data.log().Info("server transfer was canceled while downloading archive")
+				return
+			}
 			sendTransferLog("Failed while writing archive file to disk: " + err.Error())
 			data.log().WithField("error", err).Error("failed to copy archive file to disk")
 			return
@@ -450,6 +553,7 @@ func postTransfer(c *gin.Context) {
 		// Show 100% completion.
 		humanSize := system.FormatBytes(progress.size)
 		sendTransferLog("Downloading [" + strings.Repeat("=", ticks) + "] " + humanSize + " / " + humanSize)
+		i.Server().Events().Publish(server.TransferProgressEvent, progress.event("target"))
 
 		if err := file.Close(); err != nil {
 			data.log().WithField("error", err).Error("unable to close archive file on local filesystem")
@@ -458,23 +562,42 @@ func postTransfer(c *gin.Context) {
 		data.log().Info("finished writing transfer archive to disk")
 		sendTransferLog("Successfully wrote archive to disk.")
 
-		// Whenever the transfer fails or succeeds, delete the temporary transfer archive that
-		// was created on the disk.
-		defer data.removeArchivePath()
-
-		sendTransferLog("Verifying checksum of downloaded archive...")
-		data.log().Info("computing checksum of downloaded archive file")
+		sendTransferLog("Verifying " + checksumType + " checksum of downloaded archive...")
+		data.log().WithField("checksum_type", checksumType).Info("verifying checksum of downloaded archive file")
 		expected := res.Header.Get("X-Checksum")
-		if matches, computed, err := data.verifyChecksum(expected); err != nil {
-			data.log().WithField("error", err).Error("encountered an error while calculating local filesystem archive checksum")
-			return
-		} else if !matches {
+		matches, computed := data.verifyChecksum(h, expected)
+		integrity := transferIntegrity{
+			Node:         "target",
+			ChecksumType: checksumType,
+			Checksum:     expected,
+			Computed:     computed,
+			Valid:        &matches,
+		}
+		i.Server().Events().Publish(server.TransferIntegrityEvent, integrity)
+		if !matches {
+			webhook.Dispatch(i.Server().ID(), webhook.TransferIntegrityEvent, integrity)
 			sendTransferLog("@@@@@ CHECKSUM VERIFICATION FAILED @@@@@")
 			sendTransferLog("  -   Source Checksum: " + expected)
 			sendTransferLog("  - Computed Checksum: " + computed)
 			data.log().WithField("expected_sum", expected).WithField("computed_checksum", computed).Error("checksum mismatch when verifying integrity of local archive")
 			return
 		}
+		sendTransferLog("Checksum verified successfully.")
+
+		// Stop here if the transfer was canceled after the archive finished downloading
+		// since it cannot be canceled once the archive is being extracted.
+		op.DisableCancel()
+		if ctx.Err() != nil {
+			sendTransferLog("Server transfer was canceled.")
+			data.log().Info("server transfer was canceled before extracting archive")
+			return
+		}
+
+		// The startup variables of a server transferred from an upstream Linux node may
+		// reference the data directory used by that node, so these are translated.
+		if err := i.Server().MarkTransferred(); err != nil {
+			data.log().WithField("error", err).Warn("failed to record server as transferred")
+		}
 
 		// Create the server's environment.
 		sendTransferLog("Creating server environment, this could take a while..")
@@ -486,7 +609,14 @@ func postTransfer(c *gin.Context) {
 
 		sendTransferLog("Server environment has been created, extracting transfer archive..")
 		data.log().Info("server environment configured, extracting transfer archive")
-		if err := archiver.NewTarGz().Unarchive(data.path(), i.Server().Filesystem().Path()); err != nil {
+		// Create the data directory first so that the extracted files are compressed if
+		// compression is enabled for the server.
+		if err := i.Server().PrepareDataDirectory(); err != nil {
+			data.log().WithField("error", err).Error("failed to create server data directory")
+			return
+		}
+		conflicts, err := i.Server().Filesystem().ExtractTransferArchive(data.path())
+		if err != nil {
 			// Un-archiving failed, delete the server's data directory.
 			if err := os.RemoveAll(i.Server().Filesystem().Path()); err != nil && !os.IsNotExist(err) {
 				data.log().WithField("error", err).Warn("failed to delete local server files directory")
@@ -502,6 +632,18 @@ func postTransfer(c *gin.Context) {
 		// hiccup or the fix of whatever error causing the success request to fail.
 		hasError = false
 
+		for _, c := range conflicts {
+			sendTransferLog(c.String())
+		}
+		if patterns := config.Get().System.Transfers.ConvertLineEndings; len(patterns) > 0 {
+			if n, err := i.Server().Filesystem().ConvertLineEndings(patterns); err != nil {
+				data.log().WithField("error", err).Warn("failed to convert line endings of transferred files")
+				sendTransferLog("Failed to convert the line endings of text files: " + err.Error())
+			} else if n > 0 {
+				sendTransferLog(fmt.Sprintf("Converted the line endings of %d text file(s).", n))
+			}
+		}
+
 		data.log().Info("archive extracted successfully, notifying Panel of status")
 		sendTransferLog("Archive extracted successfully.")
 	}(&data)
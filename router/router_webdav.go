@@ -0,0 +1,68 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	xwebdav "golang.org/x/net/webdav"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/router/middleware"
+	"github.com/pterodactyl/wings/router/webdav"
+)
+
+// The methods that are handled by the WebDAV endpoint.
+var webDavMethods = []string{
+	http.MethodOptions, http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete,
+	"MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK", "PROPFIND", "PROPPATCH",
+}
+
+// Handles a WebDAV request for the files of a server. Clients authenticate using
+// basic authentication with their SFTP credentials, which determine the server
+// whose files are mounted at the root of the endpoint.
+func handleWebDav(auth *webdav.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := config.Get()
+		user, pass, ok := c.Request.BasicAuth()
+		if !ok {
+			c.Header("WWW-Authenticate", "Basic realm="+strconv.Quote(cfg.AppName))
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		sess, err := auth.Authenticate(c.Request.Context(), user, pass, c.ClientIP())
+		if err != nil {
+			if !errors.Is(err, webdav.ErrInvalidCredentials) {
+				middleware.ExtractLogger(c).WithField("error", err).Error("failed to validate webdav credentials")
+			}
+			c.Header("WWW-Authenticate", "Basic realm="+strconv.Quote(cfg.AppName))
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		// The request is canceled if the server is suspended while it is in progress.
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		release, err := sess.Server.TrackFileSession(cancel)
+		if err != nil {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		defer release()
+
+		logger := middleware.ExtractLogger(c).WithField("server", sess.Server.ID())
+		h := &xwebdav.Handler{
+			Prefix:     "/webdav",
+			FileSystem: webdav.NewFileSystem(sess.Server.Filesystem(), sess.Permissions, cfg.Api.WebDav.ReadOnly),
+			LockSystem: auth.LockSystem(sess.Server),
+			Logger: func(r *http.Request, err error) {
+				if err != nil {
+					logger.WithField("error", err).Debugf("webdav: %s %s", r.Method, r.URL.Path)
+				}
+			},
+		}
+		h.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+	}
+}
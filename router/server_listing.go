@@ -0,0 +1,168 @@
+package router
+
+import (
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/server"
+)
+
+// The maximum number of servers returned in a single page of the servers listing.
+const maxServersPerPage = 1000
+
+// serverListing holds the pagination, filters and fields requested for the
+// servers listing.
+type serverListing struct {
+	page, perPage int
+	states        map[string]bool
+	suspended     *bool
+	installing    *bool
+	// The fields to include for each server, a field within an object is given
+	// using dots such as "configuration.uuid". Every field is included if empty.
+	fields [][]string
+}
+
+// parseServerListing reads the query parameters for the servers listing. Every
+// server is returned on a single page unless the page or per_page parameter is
+// used, so that existing clients continue to work.
+func parseServerListing(c *gin.Context) (serverListing, error) {
+	var l serverListing
+	if c.Query("page") != "" || c.Query("per_page") != "" {
+		l.perPage, _ = strconv.Atoi(c.DefaultQuery("per_page", "50"))
+		if l.perPage <= 0 || l.perPage > maxServersPerPage {
+			l.perPage = maxServersPerPage
+		}
+		l.page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+		if l.page < 1 {
+			l.page = 1
+		}
+	}
+
+	if v := c.Query("state"); v != "" {
+		l.states = make(map[string]bool)
+		for _, s := range strings.Split(v, ",") {
+			switch s = strings.TrimSpace(s); s {
+			case environment.ProcessOfflineState, environment.ProcessStartingState, environment.ProcessRunningState, environment.ProcessStoppingState:
+				l.states[s] = true
+			default:
+				return l, errors.Errorf("\"%s\" is not a valid state, it must be one of \"offline\", \"starting\", \"running\" or \"stopping\"", s)
+			}
+		}
+	}
+	var err error
+	if l.suspended, err = boolQuery(c, "suspended"); err != nil {
+		return l, err
+	}
+	if l.installing, err = boolQuery(c, "installing"); err != nil {
+		return l, err
+	}
+
+	if v := c.Query("fields"); v != "" {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				l.fields = append(l.fields, strings.Split(f, "."))
+			}
+		}
+	}
+	return l, nil
+}
+
+// boolQuery returns the value of a boolean query parameter, or nil if it is not
+// present.
+func boolQuery(c *gin.Context, key string) (*bool, error) {
+	v, ok := c.GetQuery(key)
+	if !ok || v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, errors.Errorf("\"%s\" is not a valid value for %s, it must be true or false", v, key)
+	}
+	return &b, nil
+}
+
+// matches returns true if the server matches every filter of the listing.
+func (l serverListing) matches(s *server.Server) bool {
+	if l.states != nil && !l.states[s.Environment.State()] {
+		return false
+	}
+	if l.suspended != nil && s.IsSuspended() != *l.suspended {
+		return false
+	}
+	if l.installing != nil && s.IsInstalling() != *l.installing {
+		return false
+	}
+	return true
+}
+
+// paginate returns the servers on the requested page.
+func (l serverListing) paginate(servers []*server.Server) []*server.Server {
+	if l.perPage == 0 {
+		return servers
+	}
+	start := (l.page - 1) * l.perPage
+	if start >= len(servers) {
+		return []*server.Server{}
+	}
+	end := start + l.perPage
+	if end > len(servers) {
+		end = len(servers)
+	}
+	return servers[start:end]
+}
+
+// encode returns the API response for the server, with only the requested
+// fields included.
+func (l serverListing) encode(s *server.Server) (json.RawMessage, error) {
+	b, err := json.Marshal(s.ToAPIResponse())
+	if err != nil || len(l.fields) == 0 {
+		return b, err
+	}
+	return selectFields(b, l.fields)
+}
+
+// selectFields returns the JSON object with only the given fields, which are
+// each a path of keys into the object. Fields that do not exist are skipped.
+func selectFields(b json.RawMessage, fields [][]string) (json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, err
+	}
+	// Group the fields by their first key so that nested fields of the same object
+	// are selected together. Selecting the whole value overrides any nested fields.
+	whole := make(map[string]bool)
+	nested := make(map[string][][]string)
+	var order []string
+	for _, f := range fields {
+		if _, ok := obj[f[0]]; !ok {
+			continue
+		}
+		if !whole[f[0]] && nested[f[0]] == nil {
+			order = append(order, f[0])
+		}
+		if len(f) == 1 {
+			whole[f[0]] = true
+		} else {
+			nested[f[0]] = append(nested[f[0]], f[1:])
+		}
+	}
+
+	out := make(map[string]json.RawMessage, len(order))
+	for _, k := range order {
+		v := obj[k]
+		if !whole[k] {
+			var err error
+			if v, err = selectFields(v, nested[k]); err != nil {
+				// The value is not an object, so there is nothing within it to select.
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,39 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestServerListing(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("selectFields", func() {
+		b := []byte(`{"state":"running","is_suspended":false,"configuration":{"uuid":"abc","meta":{"name":"test"}}}`)
+
+		g.It("keeps only the requested fields", func() {
+			out, err := selectFields(b, [][]string{{"state"}, {"missing"}})
+			g.Assert(err).IsNil()
+			g.Assert(string(out)).Equal(`{"state":"running"}`)
+		})
+
+		g.It("selects fields within objects", func() {
+			out, err := selectFields(b, [][]string{{"configuration", "uuid"}, {"is_suspended"}})
+			g.Assert(err).IsNil()
+			g.Assert(string(out)).Equal(`{"configuration":{"uuid":"abc"},"is_suspended":false}`)
+		})
+
+		g.It("keeps the whole object when it is requested", func() {
+			out, err := selectFields(b, [][]string{{"configuration", "uuid"}, {"configuration"}})
+			g.Assert(err).IsNil()
+			g.Assert(string(out)).Equal(`{"configuration":{"uuid":"abc","meta":{"name":"test"}}}`)
+		})
+
+		g.It("skips nested fields of values that are not objects", func() {
+			out, err := selectFields(b, [][]string{{"state", "value"}})
+			g.Assert(err).IsNil()
+			g.Assert(string(out)).Equal(`{}`)
+		})
+	})
+}
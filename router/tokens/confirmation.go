@@ -0,0 +1,74 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/patrickmn/go-cache"
+)
+
+// The destructive actions that can require a confirmation token.
+const (
+	ConfirmDeleteServer  = "delete_server"
+	ConfirmDeleteFiles   = "delete_files"
+	ConfirmRestoreBackup = "restore_backup"
+)
+
+// IsConfirmableAction returns true if the action can be confirmed with a token.
+func IsConfirmableAction(action string) bool {
+	switch action {
+	case ConfirmDeleteServer, ConfirmDeleteFiles, ConfirmRestoreBackup:
+		return true
+	}
+	return false
+}
+
+// confirmation is the server and action that a confirmation token was issued for.
+type confirmation struct {
+	server string
+	action string
+}
+
+// confirmations holds the confirmation tokens that have been issued and not yet
+// used, until they expire.
+var confirmations = struct {
+	sync.Mutex
+	cache *cache.Cache
+}{cache: cache.New(time.Minute*5, time.Minute)}
+
+// NewConfirmationToken returns a random token that confirms the action for the
+// server. The token can only be used once, and expires after the lifetime.
+func NewConfirmationToken(server string, action string, lifetime time.Duration) (string, time.Time, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, errors.Wrap(err, "tokens: failed to generate confirmation token")
+	}
+	token := hex.EncodeToString(b)
+	confirmations.Lock()
+	confirmations.cache.Set(token, confirmation{server: server, action: action}, lifetime)
+	confirmations.Unlock()
+	return token, time.Now().Add(lifetime), nil
+}
+
+// UseConfirmationToken returns true if the token was issued for the action on
+// the server and has not expired. The token cannot be used again once it has
+// been used to confirm the action.
+func UseConfirmationToken(token string, server string, action string) bool {
+	if token == "" {
+		return false
+	}
+	confirmations.Lock()
+	defer confirmations.Unlock()
+	v, ok := confirmations.cache.Get(token)
+	if !ok {
+		return false
+	}
+	if c := v.(confirmation); c.server != server || c.action != action {
+		return false
+	}
+	confirmations.cache.Delete(token)
+	return true
+}
@@ -0,0 +1,37 @@
+package tokens
+
+import (
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+)
+
+func TestConfirmationToken(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("UseConfirmationToken", func() {
+		g.It("can only be used once", func() {
+			token, _, err := NewConfirmationToken("server", ConfirmDeleteServer, time.Minute)
+			g.Assert(err).IsNil()
+			g.Assert(UseConfirmationToken(token, "server", ConfirmDeleteServer)).IsTrue()
+			g.Assert(UseConfirmationToken(token, "server", ConfirmDeleteServer)).IsFalse()
+		})
+
+		g.It("is only valid for the server and action it was issued for", func() {
+			token, _, err := NewConfirmationToken("server", ConfirmDeleteFiles, time.Minute)
+			g.Assert(err).IsNil()
+			g.Assert(UseConfirmationToken(token, "other", ConfirmDeleteFiles)).IsFalse()
+			g.Assert(UseConfirmationToken(token, "server", ConfirmDeleteServer)).IsFalse()
+			g.Assert(UseConfirmationToken(token, "server", ConfirmDeleteFiles)).IsTrue()
+		})
+
+		g.It("expires after the lifetime", func() {
+			token, _, err := NewConfirmationToken("server", ConfirmRestoreBackup, time.Millisecond)
+			g.Assert(err).IsNil()
+			time.Sleep(time.Millisecond * 5)
+			g.Assert(UseConfirmationToken(token, "server", ConfirmRestoreBackup)).IsFalse()
+			g.Assert(UseConfirmationToken("", "server", ConfirmRestoreBackup)).IsFalse()
+		})
+	})
+}
@@ -3,6 +3,7 @@ package tokens
 import (
 	"time"
 
+	"emperror.dev/errors"
 	"github.com/gbrlsnchs/jwt/v3"
 
 	"github.com/pterodactyl/wings/config"
@@ -18,12 +19,41 @@ type TokenData interface {
 //
 // This simply returns a parsed token.
 func ParseToken(token []byte, data TokenData) error {
+	return ParseTenantToken(token, data, "")
+}
+
+// ParseTenantToken works in the same way as ParseToken, but validates the JWT
+// against the secret for the Panel that the tenant belongs to. An empty tenant
+// name is the primary Panel.
+func ParseTenantToken(token []byte, data TokenData, tenant string) error {
+	alg := config.GetTenantJwtAlgorithm(tenant)
+	if alg == nil {
+		return jwt.ErrHMACVerification
+	}
+
 	verifyOptions := jwt.ValidatePayload(
 		data.GetPayload(),
 		jwt.ExpirationTimeValidator(time.Now()),
 	)
 
-	_, err := jwt.Verify(token, config.GetJwtAlgorithm(), &data, verifyOptions)
+	_, err := jwt.Verify(token, alg, &data, verifyOptions)
 
 	return err
 }
+
+// ParseAnyToken validates the provided JWT against the secret for every Panel
+// that this node is registered with, returning the name of the tenant that the
+// token was signed for. The caller MUST ensure that the resource being accessed
+// belongs to the returned tenant.
+func ParseAnyToken(token []byte, data TokenData) (string, error) {
+	err := ParseTenantToken(token, data, "")
+	if err == nil || !errors.Is(err, jwt.ErrHMACVerification) {
+		return "", err
+	}
+	for _, t := range config.Get().Tenants {
+		if terr := ParseTenantToken(token, data, t.Name); terr == nil || !errors.Is(terr, jwt.ErrHMACVerification) {
+			return t.Name, terr
+		}
+	}
+	return "", err
+}
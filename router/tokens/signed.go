@@ -0,0 +1,46 @@
+package tokens
+
+import (
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/gbrlsnchs/jwt/v3"
+	"github.com/google/uuid"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// NewFileDownloadToken returns a token for downloading a file from the server
+// that is signed by this node rather than the Panel. The token expires after
+// the given lifetime and can only be used once, so URLs containing it can be
+// handed directly to a browser or CDN.
+func NewFileDownloadToken(tenant string, server string, path string, lifetime time.Duration) (string, time.Time, error) {
+	p := FilePayload{ServerUuid: server, FilePath: path, UniqueId: uuid.NewString()}
+	return sign(&p, tenant, lifetime)
+}
+
+// NewBackupDownloadToken returns a single-use token for downloading a backup of
+// the server that is signed by this node.
+func NewBackupDownloadToken(tenant string, server string, backup string, lifetime time.Duration) (string, time.Time, error) {
+	p := BackupPayload{ServerUuid: server, BackupUuid: backup, UniqueId: uuid.NewString()}
+	return sign(&p, tenant, lifetime)
+}
+
+// sign signs the token using the same secret as the Panel the tenant belongs to,
+// so that it is accepted by the existing download endpoints.
+func sign(data TokenData, tenant string, lifetime time.Duration) (string, time.Time, error) {
+	alg := config.GetTenantJwtAlgorithm(tenant)
+	if alg == nil {
+		return "", time.Time{}, errors.New("tokens: no signing secret for tenant " + tenant)
+	}
+	now := time.Now()
+	p := data.GetPayload()
+	p.IssuedAt = jwt.NumericDate(now)
+	p.ExpirationTime = jwt.NumericDate(now.Add(lifetime))
+	p.JWTID = uuid.NewString()
+	b, err := jwt.Sign(data, alg)
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "tokens: failed to sign token")
+	}
+	return string(b), p.ExpirationTime.Time, nil
+}
@@ -0,0 +1,45 @@
+package tokens
+
+import (
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestSignedTokens(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("NewFileDownloadToken", func() {
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{AuthenticationToken: "node-token"})
+		})
+
+		g.It("creates a token that can only be used once", func() {
+			token, _, err := NewFileDownloadToken("", "server", "/test.txt", time.Minute)
+			g.Assert(err).IsNil()
+
+			var p FilePayload
+			g.Assert(ParseToken([]byte(token), &p)).IsNil()
+			g.Assert(p.ServerUuid).Equal("server")
+			g.Assert(p.FilePath).Equal("/test.txt")
+			g.Assert(p.IsUniqueRequest()).IsTrue()
+			g.Assert(p.IsUniqueRequest()).IsFalse()
+		})
+
+		g.It("creates a token that expires", func() {
+			token, _, err := NewFileDownloadToken("", "server", "/test.txt", -time.Second)
+			g.Assert(err).IsNil()
+
+			var p FilePayload
+			g.Assert(ParseToken([]byte(token), &p)).IsNotNil()
+		})
+
+		g.It("does not sign tokens for an unknown tenant", func() {
+			_, _, err := NewBackupDownloadToken("unknown", "server", "backup", time.Minute)
+			g.Assert(err).IsNotNil()
+		})
+	})
+}
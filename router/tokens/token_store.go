@@ -27,8 +27,13 @@ func getTokenStore() *TokenStore {
 	return _tokens
 }
 
-// Checks if a token is valid or not.
+// Checks if a token is valid or not. Tokens without a unique ID are never
+// valid, otherwise they could be replayed until they expire.
 func (t *TokenStore) IsValidToken(token string) bool {
+	if token == "" {
+		return false
+	}
+
 	t.Lock()
 	defer t.Unlock()
 
@@ -0,0 +1,146 @@
+package router
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/cespare/xxhash/v2"
+	"github.com/goccy/go-json"
+)
+
+// The checksum algorithms that can be used to verify a transfer archive. SHA-256
+// is always supported since it is the only checksum older nodes understand.
+const (
+	ChecksumSHA256 = "sha256"
+	ChecksumXXH64  = "xxh64"
+)
+
+// The header sent by the target node listing the checksum algorithms it supports
+// in order of preference, and the header sent back by the source node with the
+// algorithm that was picked.
+const (
+	checksumAcceptHeader = "X-Checksum-Accept"
+	checksumTypeHeader   = "X-Checksum-Type"
+)
+
+// supportedChecksums are the checksum algorithms supported by this node.
+var supportedChecksums = []string{ChecksumSHA256, ChecksumXXH64}
+
+// acceptedChecksums returns the algorithms sent to the source node when downloading
+// an archive, in order of preference. The much faster xxh64 does not protect against
+// the archive being tampered with, which matters when nodes are not connected over
+// TLS, so it is only offered when the Panel has opted in to it for the transfer.
+func acceptedChecksums(requested string) []string {
+	if strings.ToLower(requested) == ChecksumXXH64 {
+		return []string{ChecksumXXH64, ChecksumSHA256}
+	}
+	return []string{ChecksumSHA256}
+}
+
+// archiveChecksums are the checksums of a transfer archive, computed while the
+// archive is being written and stored next to it on the disk.
+type archiveChecksums struct {
+	SHA256 string `json:"sha256"`
+	XXH64  string `json:"xxh64"`
+}
+
+// Get returns the checksum for the given algorithm.
+func (ac archiveChecksums) Get(typ string) string {
+	if typ == ChecksumXXH64 {
+		return ac.XXH64
+	}
+	return ac.SHA256
+}
+
+// checksumWriter computes every supported checksum of the data written to it.
+type checksumWriter struct {
+	sha256 hash.Hash
+	xxh64  *xxhash.Digest
+}
+
+func newChecksumWriter() *checksumWriter {
+	return &checksumWriter{sha256: sha256.New(), xxh64: xxhash.New()}
+}
+
+func (w *checksumWriter) Write(p []byte) (int, error) {
+	_, _ = w.sha256.Write(p)
+	_, _ = w.xxh64.Write(p)
+	return len(p), nil
+}
+
+// Sums returns the checksums of everything written so far.
+func (w *checksumWriter) Sums() archiveChecksums {
+	return archiveChecksums{
+		SHA256: hex.EncodeToString(w.sha256.Sum(nil)),
+		XXH64:  hex.EncodeToString(w.xxh64.Sum(nil)),
+	}
+}
+
+// newChecksumHash returns the hash for the algorithm, defaulting to SHA-256 when
+// the source node did not say which one it used.
+func newChecksumHash(typ string) hash.Hash {
+	if typ == ChecksumXXH64 {
+		return xxhash.New()
+	}
+	return sha256.New()
+}
+
+// negotiateChecksum returns the first algorithm in the header sent by the target
+// node that is supported, or SHA-256 if the header is missing.
+func negotiateChecksum(header string) string {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		for _, s := range supportedChecksums {
+			if v == s {
+				return v
+			}
+		}
+	}
+	return ChecksumSHA256
+}
+
+func getArchiveChecksumPath(sID string) string {
+	return getArchivePath(sID) + ".checksum"
+}
+
+// writeArchiveChecksums stores the checksums of the archive for the server so
+// that they do not need to be computed again when the archive is requested.
+func writeArchiveChecksums(sID string, sums archiveChecksums) error {
+	b, err := json.Marshal(sums)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(getArchiveChecksumPath(sID), b, 0o600)
+}
+
+// readArchiveChecksums returns the checksums of the archive for the server. If
+// the archive was created without them being stored they are computed from the
+// archive on the disk instead.
+func readArchiveChecksums(sID string) (archiveChecksums, error) {
+	var sums archiveChecksums
+	b, err := os.ReadFile(getArchiveChecksumPath(sID))
+	if err == nil {
+		if err := json.Unmarshal(b, &sums); err == nil && sums.SHA256 != "" && sums.XXH64 != "" {
+			return sums, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return sums, err
+	}
+
+	f, err := os.Open(getArchivePath(sID))
+	if err != nil {
+		return sums, err
+	}
+	defer f.Close()
+	w := newChecksumWriter()
+	if _, err := io.Copy(w, bufio.NewReader(f)); err != nil {
+		return sums, err
+	}
+	return w.Sums(), nil
+}
@@ -0,0 +1,62 @@
+package router
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestTransferChecksum(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("negotiateChecksum", func() {
+		g.It("picks the first supported algorithm", func() {
+			g.Assert(negotiateChecksum("md5, XXH64, sha256")).Equal(ChecksumXXH64)
+			g.Assert(negotiateChecksum("sha256,xxh64")).Equal(ChecksumSHA256)
+		})
+
+		g.It("defaults to sha256 for older nodes", func() {
+			g.Assert(negotiateChecksum("")).Equal(ChecksumSHA256)
+			g.Assert(negotiateChecksum("md5")).Equal(ChecksumSHA256)
+		})
+	})
+
+	g.Describe("acceptedChecksums", func() {
+		g.It("only offers sha256 unless the panel opts in to xxh64", func() {
+			g.Assert(acceptedChecksums("")).Equal([]string{ChecksumSHA256})
+			g.Assert(acceptedChecksums("md5")).Equal([]string{ChecksumSHA256})
+			g.Assert(acceptedChecksums("xxh64")).Equal([]string{ChecksumXXH64, ChecksumSHA256})
+		})
+
+		g.It("results in sha256 being used by the source node by default", func() {
+			g.Assert(negotiateChecksum(strings.Join(acceptedChecksums(""), ", "))).Equal(ChecksumSHA256)
+		})
+	})
+
+	g.Describe("checksumWriter", func() {
+		g.It("computes the same checksums as the target node", func() {
+			w := newChecksumWriter()
+			_, _ = w.Write([]byte("hello "))
+			_, _ = w.Write([]byte("world"))
+			sums := w.Sums()
+
+			for _, typ := range supportedChecksums {
+				h := newChecksumHash(typ)
+				_, _ = h.Write([]byte("hello world"))
+				g.Assert(sums.Get(typ)).Equal(hex.EncodeToString(h.Sum(nil)))
+			}
+			g.Assert(sums.SHA256).Equal("b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9")
+		})
+	})
+
+	g.Describe("verifyChecksum", func() {
+		g.It("never matches a missing checksum", func() {
+			h := newChecksumHash(ChecksumXXH64)
+			ok, computed := serverTransferRequest{}.verifyChecksum(h, "")
+			g.Assert(ok).IsFalse()
+			g.Assert(computed).Equal(hex.EncodeToString(newChecksumHash(ChecksumXXH64).Sum(nil)))
+		})
+	})
+}
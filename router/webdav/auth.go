@@ -0,0 +1,128 @@
+package webdav
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/patrickmn/go-cache"
+	"golang.org/x/net/webdav"
+
+	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/server"
+)
+
+// ErrInvalidCredentials is returned when the username or password provided by a
+// client are not valid for any server on this node.
+var ErrInvalidCredentials = errors.Sentinel("webdav: invalid credentials")
+
+// Session is a user that has been authenticated for a server.
+type Session struct {
+	Server      *server.Server
+	Permissions []string
+}
+
+// Authenticator validates the credentials provided by WebDAV clients with the
+// Panel in the same way as the SFTP server does. Clients make a request for each
+// file they access, so credentials are cached once they have been validated.
+type Authenticator struct {
+	manager *server.Manager
+	ttl     time.Duration
+	cache   *cache.Cache
+
+	mu    sync.Mutex
+	locks map[string]webdav.LockSystem
+}
+
+// NewAuthenticator returns an Authenticator for the servers of the manager that
+// caches validated credentials for the given duration.
+func NewAuthenticator(m *server.Manager, ttl time.Duration) *Authenticator {
+	return &Authenticator{
+		manager: m,
+		ttl:     ttl,
+		cache:   cache.New(ttl, time.Minute*5),
+		locks:   make(map[string]webdav.LockSystem),
+	}
+}
+
+// Authenticate returns the session for the username and password. Usernames are
+// in the same "user.server" format used by the SFTP server, where the server is
+// the short identifier of the server being accessed.
+func (a *Authenticator) Authenticate(ctx context.Context, username, password, ip string) (*Session, error) {
+	s := a.findServer(username)
+	if s == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	key := a.key(username, password)
+	if v, ok := a.cache.Get(key); ok {
+		return &Session{Server: s, Permissions: v.([]string)}, nil
+	}
+
+	resp, err := a.manager.ServerClient(s.ID()).ValidateSftpCredentials(ctx, remote.SftpAuthRequest{
+		Type: remote.SftpAuthPassword,
+		User: username,
+		Pass: password,
+		IP:   ip,
+	})
+	if err != nil {
+		var ice *remote.SftpInvalidCredentialsError
+		if errors.As(err, &ice) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if resp.Server != s.ID() {
+		return nil, ErrInvalidCredentials
+	}
+	if a.ttl > 0 {
+		a.cache.Set(key, resp.Permissions, a.ttl)
+	}
+	return &Session{Server: s, Permissions: resp.Permissions}, nil
+}
+
+// LockSystem returns the lock system for the server. Locks must be shared by all
+// of the requests for a server for them to have any effect.
+func (a *Authenticator) LockSystem(s *server.Server) webdav.LockSystem {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ls, ok := a.locks[s.ID()]
+	if !ok {
+		ls = webdav.NewMemLS()
+		a.locks[s.ID()] = ls
+	}
+	return ls
+}
+
+// findServer returns the server that the username belongs to, or nil if there
+// is no matching server on this node.
+func (a *Authenticator) findServer(username string) *server.Server {
+	id := serverIdentifier(username)
+	if id == "" {
+		return nil
+	}
+	return a.manager.Find(func(s *server.Server) bool {
+		return strings.HasPrefix(s.ID(), id)
+	})
+}
+
+// key returns the cache key for the credentials. The password is hashed so that
+// it is never kept in memory for longer than the request.
+func (a *Authenticator) key(username, password string) string {
+	sum := sha256.Sum256([]byte(username + "\x00" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// serverIdentifier returns the short identifier of the server from a username
+// in the "user.server" format, or an empty string if it is not in that format.
+func serverIdentifier(username string) string {
+	i := strings.LastIndex(username, ".")
+	if i == -1 || len(username)-i-1 != 8 {
+		return ""
+	}
+	return strings.ToLower(username[i+1:])
+}
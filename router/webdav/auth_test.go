@@ -0,0 +1,39 @@
+package webdav
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestAuthenticator(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("serverIdentifier", func() {
+		g.It("returns the short identifier of the server", func() {
+			g.Assert(serverIdentifier("admin.1a2b3c4d")).Equal("1a2b3c4d")
+			g.Assert(serverIdentifier("first.last.1A2B3C4D")).Equal("1a2b3c4d")
+		})
+
+		g.It("rejects usernames without a server", func() {
+			g.Assert(serverIdentifier("admin")).Equal("")
+			g.Assert(serverIdentifier("admin.")).Equal("")
+			g.Assert(serverIdentifier("admin.1a2b")).Equal("")
+		})
+	})
+
+	g.Describe("FileSystem", func() {
+		g.It("grants permissions from the Panel", func() {
+			fs := NewFileSystem(nil, []string{PermissionFileRead, PermissionFileCreate}, false)
+			g.Assert(fs.can(PermissionFileRead)).IsTrue()
+			g.Assert(fs.can(PermissionFileDelete)).IsFalse()
+			g.Assert(fs.canWrite(PermissionFileCreate)).IsNil()
+		})
+
+		g.It("denies every write when read only", func() {
+			fs := NewFileSystem(nil, []string{"*"}, true)
+			g.Assert(fs.can(PermissionFileDelete)).IsTrue()
+			g.Assert(fs.canWrite(PermissionFileCreate) != nil).IsTrue()
+		})
+	})
+}
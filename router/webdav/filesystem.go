@@ -0,0 +1,160 @@
+package webdav
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+
+	"golang.org/x/net/webdav"
+
+	"github.com/pterodactyl/wings/server/filesystem"
+)
+
+// The permissions granted to users by the Panel that control what they are able
+// to do with the files of a server. These are the same permissions that are used
+// by the SFTP server.
+const (
+	PermissionFileRead        = "file.read"
+	PermissionFileReadContent = "file.read-content"
+	PermissionFileCreate      = "file.create"
+	PermissionFileUpdate      = "file.update"
+	PermissionFileDelete      = "file.delete"
+)
+
+var _ webdav.FileSystem = (*FileSystem)(nil)
+
+// FileSystem exposes the files of a server over WebDAV. Every path is resolved
+// through the server filesystem so that the same checks that prevent escaping
+// the data directory, and the same denylists, apply as for the rest of the API.
+type FileSystem struct {
+	fs          *filesystem.Filesystem
+	permissions []string
+	readOnly    bool
+}
+
+// NewFileSystem returns a FileSystem for the server filesystem that only allows
+// the actions granted by the given permissions. No changes can be made to the
+// files when readOnly is true.
+func NewFileSystem(fs *filesystem.Filesystem, permissions []string, readOnly bool) *FileSystem {
+	return &FileSystem{fs: fs, permissions: permissions, readOnly: readOnly}
+}
+
+// can returns true if the user has the given permission.
+func (f *FileSystem) can(permission string) bool {
+	for _, p := range f.permissions {
+		if p == permission || p == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// canWrite returns an error if the user does not have the given permission, or
+// if no changes can be made to the files.
+func (f *FileSystem) canWrite(permission string) error {
+	if f.readOnly || !f.can(permission) {
+		return os.ErrPermission
+	}
+	return nil
+}
+
+// Mkdir creates a single directory, the parent directory must already exist.
+func (f *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if err := f.canWrite(PermissionFileCreate); err != nil {
+		return err
+	}
+	cleaned, err := f.fs.SafePath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(cleaned); err == nil {
+		return os.ErrExist
+	}
+	if _, err := os.Stat(filepath.Dir(cleaned)); err != nil {
+		return err
+	}
+	return f.fs.CreateDirectory(path.Base(name), path.Dir(name))
+}
+
+// OpenFile opens the file with the given flags. Files that are opened for writing
+// are created, along with any missing directories, if they do not exist.
+func (f *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	cleaned, err := f.fs.SafePath(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.fs.IsIgnored(cleaned); err != nil {
+		return nil, os.ErrPermission
+	}
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		st, err := os.Stat(cleaned)
+		if err != nil {
+			return nil, err
+		}
+		if !f.can(PermissionFileRead) || (!st.IsDir() && !f.can(PermissionFileReadContent)) {
+			return nil, os.ErrPermission
+		}
+		return os.Open(cleaned)
+	}
+
+	permission := PermissionFileCreate
+	if _, err := os.Stat(cleaned); err == nil {
+		permission = PermissionFileUpdate
+	}
+	if err := f.canWrite(permission); err != nil {
+		return nil, err
+	}
+	if err := f.fs.HasSpaceErr(true); err != nil {
+		return nil, err
+	}
+	return f.fs.Touch(cleaned, flag)
+}
+
+// RemoveAll removes the file or directory and everything within it.
+func (f *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	if err := f.canWrite(PermissionFileDelete); err != nil {
+		return err
+	}
+	if err := f.fs.IsIgnored(name); err != nil {
+		return os.ErrPermission
+	}
+	return f.fs.Delete(name)
+}
+
+// Rename moves the file or directory to the new name, which must not exist.
+func (f *FileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	if err := f.canWrite(PermissionFileUpdate); err != nil {
+		return err
+	}
+	if err := f.fs.IsIgnored(oldName, newName); err != nil {
+		return os.ErrPermission
+	}
+	return f.fs.Rename(oldName, newName)
+}
+
+// Stat returns the stat information for the file or directory.
+func (f *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if !f.can(PermissionFileRead) {
+		return nil, os.ErrPermission
+	}
+	st, err := f.fs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{st}, nil
+}
+
+// fileInfo uses the MIME type that was detected when the file was stat'd as its
+// content type, rather than the file being opened again to detect it.
+type fileInfo struct {
+	filesystem.Stat
+}
+
+// ContentType implements webdav.ContentTyper.
+func (fi *fileInfo) ContentType(ctx context.Context) (string, error) {
+	if fi.IsDir() {
+		return "", webdav.ErrNotImplemented
+	}
+	return fi.Mimetype, nil
+}
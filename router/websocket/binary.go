@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/pterodactyl/wings/events"
+)
+
+// BinaryConsoleProtocol is the subprotocol that a client can request when opening
+// the websocket to receive console output as binary frames rather than as a JSON
+// message for each line. Every other event is still sent as JSON, and messages
+// sent by the client are always JSON.
+//
+// Each binary frame starts with a single byte for the type of the frame, which
+// is followed by one or more records. Each record is the time the line was
+// output as milliseconds since the Unix epoch (8 bytes), the length of the line
+// (4 bytes) and then the line itself. All integers are unsigned and big-endian.
+const BinaryConsoleProtocol = "pterodactyl.console.v1.binary"
+
+// The types of binary frames.
+const (
+	binaryFrameConsoleOutput byte = 0x01
+)
+
+// The size of the header for each record in a binary frame.
+const binaryRecordHeaderSize = 12
+
+// The maximum size of a binary frame. Lines are split across multiple frames once
+// this is reached, although a single line larger than this is still sent as one
+// frame.
+const maxBinaryFrameSize = 64 * 1024
+
+// appendConsoleRecord appends a record for the line of console output to the
+// binary frame.
+func appendConsoleRecord(frame []byte, t time.Time, line []byte) []byte {
+	var header [binaryRecordHeaderSize]byte
+	binary.BigEndian.PutUint64(header[0:8], uint64(t.UnixNano()/int64(time.Millisecond)))
+	binary.BigEndian.PutUint32(header[8:12], uint32(len(line)))
+	frame = append(frame, header[:]...)
+	return append(frame, line...)
+}
+
+// sendConsoleFrames sends the lines of console output as binary frames, batching
+// as many lines into each frame as will fit. The frame buffer is returned so that
+// it can be reused for the next call.
+func (h *Handler) sendConsoleFrames(frame []byte, entries []*events.RingEntry) ([]byte, error) {
+	if err := h.TokenValid(); err != nil {
+		_ = h.unsafeSendJson(Message{
+			Event: JwtErrorEvent,
+			Args:  []string{err.Error()},
+		})
+		return frame, nil
+	}
+
+	frame = append(frame[:0], binaryFrameConsoleOutput)
+	for i, e := range entries {
+		frame = appendConsoleRecord(frame, e.Time, e.Data)
+		// Send the frame once it is full, or there are no lines remaining to add to it.
+		if i < len(entries)-1 && len(frame)+binaryRecordHeaderSize+len(entries[i+1].Data) <= maxBinaryFrameSize {
+			continue
+		}
+		if err := h.writeMessage(websocket.BinaryMessage, frame); err != nil {
+			return frame, err
+		}
+		frame = append(frame[:0], binaryFrameConsoleOutput)
+	}
+	return frame, nil
+}
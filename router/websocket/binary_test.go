@@ -0,0 +1,29 @@
+package websocket
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+)
+
+func TestAppendConsoleRecord(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("appendConsoleRecord", func() {
+		g.It("appends the timestamp, length and line", func() {
+			ts := time.Date(2021, 6, 1, 12, 0, 0, int(250*time.Millisecond), time.UTC)
+			frame := appendConsoleRecord([]byte{binaryFrameConsoleOutput}, ts, []byte("hello"))
+			frame = appendConsoleRecord(frame, ts.Add(time.Second), []byte(""))
+
+			g.Assert(frame[0]).Equal(binaryFrameConsoleOutput)
+			g.Assert(len(frame)).Equal(1 + binaryRecordHeaderSize*2 + 5)
+			g.Assert(binary.BigEndian.Uint64(frame[1:9])).Equal(uint64(1622548800250))
+			g.Assert(binary.BigEndian.Uint32(frame[9:13])).Equal(uint32(5))
+			g.Assert(string(frame[13:18])).Equal("hello")
+			g.Assert(binary.BigEndian.Uint64(frame[18:26])).Equal(uint64(1622548801250))
+			g.Assert(binary.BigEndian.Uint32(frame[26:30])).Equal(uint32(0))
+		})
+	})
+}
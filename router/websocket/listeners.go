@@ -71,12 +71,23 @@ var e = []string{
 	server.ConsoleOutputEvent,
 	server.InstallOutputEvent,
 	server.InstallStartedEvent,
+	server.InstallQueuedEvent,
 	server.InstallCompletedEvent,
+	server.InstallRolledBackEvent,
 	server.DaemonMessageEvent,
 	server.BackupCompletedEvent,
 	server.BackupRestoreCompletedEvent,
 	server.TransferLogsEvent,
 	server.TransferStatusEvent,
+	server.TransferProgressEvent,
+	server.TransferIntegrityEvent,
+	server.StartupFailedEvent,
+	server.FeatureMatchedEvent,
+	server.CrashDetectedEvent,
+	server.OutOfMemoryEvent,
+	server.ResourceAlertEvent,
+	server.FileOperationProgressEvent,
+	server.FileOperationCompletedEvent,
 }
 
 // ListenForServerEvents will listen for different events happening on a server
@@ -90,11 +101,16 @@ func (h *Handler) listenForServerEvents(ctx context.Context) error {
 	defer cancel()
 
 	eventChan := make(chan []byte)
-	logOutput := make(chan []byte, 8)
 	installOutput := make(chan []byte, 4)
 
+	// Console output is read from the ring shared by every connection to the server,
+	// rather than each connection being sent its own copy of every line.
+	ring := h.server.ConsoleRing()
+	cursor := ring.Subscribe()
+	var entries []*events.RingEntry
+	var frame []byte
+
 	h.server.Events().On(eventChan) // TODO: make a sinky
-	h.server.Sink(system.LogSink).On(logOutput)
 	h.server.Sink(system.InstallSink).On(installOutput)
 
 	onError := func(evt string, err2 error) {
@@ -112,8 +128,22 @@ func (h *Handler) listenForServerEvents(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			break
-		case b := <-logOutput:
-			sendErr := h.SendJson(Message{Event: server.ConsoleOutputEvent, Args: []string{string(b)}})
+		case <-cursor.C():
+			var skipped uint64
+			entries, skipped = cursor.Read(entries[:0])
+			if skipped > 0 {
+				h.Logger().WithField("lines", skipped).Debug("websocket connection fell behind the console output, skipping lines")
+			}
+			var sendErr error
+			if h.binary {
+				frame, sendErr = h.sendConsoleFrames(frame, entries)
+			} else {
+				for _, e := range entries {
+					if sendErr = h.sendConsoleOutput(e); sendErr != nil {
+						break
+					}
+				}
+			}
 			if sendErr == nil {
 				continue
 			}
@@ -155,7 +185,7 @@ func (h *Handler) listenForServerEvents(ctx context.Context) error {
 
 	// These functions will automatically close the channel if it hasn't been already.
 	h.server.Events().Off(eventChan)
-	h.server.Sink(system.LogSink).Off(logOutput)
+	ring.Unsubscribe(cursor)
 	h.server.Sink(system.InstallSink).Off(installOutput)
 
 	// If the internal context is stopped it is either because the parent context
@@ -19,6 +19,7 @@ import (
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
 	"github.com/pterodactyl/wings/environment/docker"
+	"github.com/pterodactyl/wings/events"
 	"github.com/pterodactyl/wings/router/tokens"
 	"github.com/pterodactyl/wings/server"
 )
@@ -40,7 +41,10 @@ type Handler struct {
 	Connection   *websocket.Conn `json:"-"`
 	jwt          *tokens.WebsocketPayload
 	server       *server.Server
+	tenant       string
 	uuid         uuid.UUID
+	// Set when the client requested the binary console protocol when connecting.
+	binary bool
 }
 
 var (
@@ -58,10 +62,11 @@ func IsJwtError(err error) bool {
 		errors.Is(err, jwt.ErrExpValidation)
 }
 
-// NewTokenPayload parses a JWT into a websocket token payload.
-func NewTokenPayload(token []byte) (*tokens.WebsocketPayload, error) {
+// NewTokenPayload parses a JWT into a websocket token payload. The token must
+// have been issued by the Panel that the tenant belongs to.
+func NewTokenPayload(token []byte, tenant string) (*tokens.WebsocketPayload, error) {
 	var payload tokens.WebsocketPayload
-	if err := tokens.ParseToken(token, &payload); err != nil {
+	if err := tokens.ParseTenantToken(token, &payload, tenant); err != nil {
 		return nil, err
 	}
 
@@ -76,9 +81,13 @@ func NewTokenPayload(token []byte) (*tokens.WebsocketPayload, error) {
 	return &payload, nil
 }
 
-// GetHandler returns a new websocket handler using the context provided.
-func GetHandler(s *server.Server, w http.ResponseWriter, r *http.Request) (*Handler, error) {
+// GetHandler returns a new websocket handler using the context provided. The
+// tenant is the name of the tenant that the server belongs to.
+func GetHandler(s *server.Server, tenant string, w http.ResponseWriter, r *http.Request) (*Handler, error) {
 	upgrader := websocket.Upgrader{
+		// Clients can request the binary protocol for console output, otherwise every
+		// message is sent as JSON.
+		Subprotocols: []string{BinaryConsoleProtocol},
 		// Ensure that the websocket request is originating from the Panel itself,
 		// and not some other location.
 		CheckOrigin: func(r *http.Request) bool {
@@ -86,12 +95,11 @@ func GetHandler(s *server.Server, w http.ResponseWriter, r *http.Request) (*Hand
 			if o == config.Get().PanelLocation {
 				return true
 			}
-			for _, origin := range config.Get().AllowedOrigins {
-				if origin == "*" || origin == o {
-					return true
-				}
+			if t, ok := config.Get().Tenant(tenant); ok && o == t.PanelLocation {
+				return true
 			}
-			return false
+			_, ok := config.Get().CorsOrigin(o)
+			return ok
 		},
 	}
 
@@ -109,7 +117,9 @@ func GetHandler(s *server.Server, w http.ResponseWriter, r *http.Request) (*Hand
 		Connection: conn,
 		jwt:        nil,
 		server:     s,
+		tenant:     tenant,
 		uuid:       u,
+		binary:     conn.Subprotocol() == BinaryConsoleProtocol,
 	}, nil
 }
 
@@ -151,7 +161,7 @@ func (h *Handler) SendJson(v Message) error {
 		}
 
 		// If we are sending transfer output, only send it to the user if they have the required permissions.
-		if v.Event == server.TransferLogsEvent {
+		if v.Event == server.TransferLogsEvent || v.Event == server.TransferProgressEvent || v.Event == server.TransferIntegrityEvent {
 			if !j.HasPermission(PermissionReceiveTransfer) {
 				return nil
 			}
@@ -177,6 +187,37 @@ func (h *Handler) SendJson(v Message) error {
 	return nil
 }
 
+// sendConsoleOutput sends a line of console output read from the console ring of
+// the server. The message for the line is only encoded once, and is then shared
+// by every connection that sends it.
+func (h *Handler) sendConsoleOutput(e *events.RingEntry) error {
+	if err := h.TokenValid(); err != nil {
+		_ = h.unsafeSendJson(Message{
+			Event: JwtErrorEvent,
+			Args:  []string{err.Error()},
+		})
+		return nil
+	}
+
+	b := e.Encoded(func(data []byte) []byte {
+		b, _ := json.Marshal(Message{Event: server.ConsoleOutputEvent, Args: []string{string(data)}})
+		return b
+	})
+	return h.writeMessage(websocket.TextMessage, b)
+}
+
+// writeMessage writes an already encoded message to the websocket connection.
+func (h *Handler) writeMessage(messageType int, b []byte) error {
+	h.Lock()
+	err := h.Connection.WriteMessage(messageType, b)
+	h.Unlock()
+	// See SendJson for why this error is ignored.
+	if err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+		return err
+	}
+	return nil
+}
+
 // Sends JSON over the websocket connection, ignoring the authentication state of the
 // socket user. Do not call this directly unless you are positive a response should be
 // sent back to the client!
@@ -283,7 +324,7 @@ func (h *Handler) HandleInbound(ctx context.Context, m Message) error {
 	switch m.Event {
 	case AuthenticationEvent:
 		{
-			token, err := NewTokenPayload([]byte(strings.Join(m.Args, "")))
+			token, err := NewTokenPayload([]byte(strings.Join(m.Args, "")), h.tenant)
 			if err != nil {
 				return err
 			}
@@ -380,7 +421,7 @@ func (h *Handler) HandleInbound(ctx context.Context, m Message) error {
 				return err
 			}
 
-			for _, line := range logs {
+			for _, line := range h.server.RedactSecretLines(logs) {
 				_ = h.SendJson(Message{
 					Event: server.ConsoleOutputEvent,
 					Args:  []string{line},
@@ -421,7 +462,10 @@ func (h *Handler) HandleInbound(ctx context.Context, m Message) error {
 				}
 			}
 
-			return h.server.Environment.SendCommand(strings.Join(m.Args, ""))
+			return h.server.SendCommand(strings.Join(m.Args, ""), server.CommandOrigin{
+				Source: server.CommandSourceWebsocket,
+				Actor:  h.GetJwt().UserID.String(),
+			})
 		}
 	}
 
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/events/webhook"
+)
+
+// The resources that alert rules can be defined for.
+const (
+	AlertMetricMemory = "memory"
+	AlertMetricCpu    = "cpu"
+	AlertMetricDisk   = "disk"
+)
+
+// The actions that can be taken automatically when an alert fires, in addition
+// to the event that is always emitted.
+const (
+	AlertActionNone    = ""
+	AlertActionWarn    = "warn"
+	AlertActionRestart = "restart"
+)
+
+// AlertRule defines a threshold for the resource usage of a server, such as the
+// memory usage being above 95% of the limit for five minutes. An event is emitted
+// when the usage has been above the threshold for the duration, and again once it
+// drops back below the threshold.
+type AlertRule struct {
+	// The resource being checked, one of "memory", "cpu" or "disk".
+	Metric string `json:"metric"`
+
+	// The percentage of the server's limit for the resource that the usage must
+	// reach. Rules for resources without a limit are ignored.
+	Threshold float64 `json:"threshold"`
+
+	// The number of seconds the usage must remain above the threshold before the
+	// alert fires. If zero the alert fires as soon as the threshold is reached.
+	Duration int `json:"duration"`
+
+	// The action taken when the alert fires, either "warn" to send a message to
+	// the console or "restart" to restart the server after the delay. If empty
+	// only the event is emitted.
+	Action string `json:"action"`
+
+	// The message sent to the console by the "warn" and "restart" actions, if not
+	// set a default message describing the alert is used.
+	Message string `json:"message"`
+
+	// The number of seconds to wait before restarting the server for the
+	// "restart" action.
+	Delay int `json:"delay"`
+}
+
+// Validate checks that the rule can be evaluated.
+func (r AlertRule) Validate() error {
+	switch r.Metric {
+	case AlertMetricMemory, AlertMetricCpu, AlertMetricDisk:
+	default:
+		return errors.Errorf("server: invalid alert metric \"%s\"", r.Metric)
+	}
+	if r.Threshold <= 0 {
+		return errors.New("server: alert threshold must be greater than 0")
+	}
+	switch r.Action {
+	case AlertActionNone, AlertActionWarn, AlertActionRestart:
+	default:
+		return errors.Errorf("server: invalid alert action \"%s\"", r.Action)
+	}
+	return nil
+}
+
+// The changes in the state of an alert.
+const (
+	alertUnchanged = ""
+	alertFiring    = "firing"
+	alertResolved  = "resolved"
+)
+
+// alertState tracks how long the usage has been above the threshold for a rule.
+type alertState struct {
+	since  time.Time
+	firing bool
+}
+
+// check updates the state with the latest usage, returning alertFiring when the
+// usage has been above the threshold for the duration, and alertResolved when a
+// firing alert drops back below it.
+func (st *alertState) check(above bool, d time.Duration, now time.Time) string {
+	if !above {
+		st.since = time.Time{}
+		if st.firing {
+			st.firing = false
+			return alertResolved
+		}
+		return alertUnchanged
+	}
+	if st.since.IsZero() {
+		st.since = now
+	}
+	if !st.firing && now.Sub(st.since) >= d {
+		st.firing = true
+		return alertFiring
+	}
+	return alertUnchanged
+}
+
+// alertWatcher evaluates the alert rules for a server against each resource
+// usage event emitted by the environment.
+type alertWatcher struct {
+	mu     sync.Mutex
+	server *Server
+	states map[AlertRule]*alertState
+}
+
+func newAlertWatcher(s *Server) *alertWatcher {
+	return &alertWatcher{server: s, states: make(map[AlertRule]*alertState)}
+}
+
+// Reset clears the state of every alert, this should be called whenever the
+// server process is started or stopped.
+func (aw *alertWatcher) Reset() {
+	aw.mu.Lock()
+	aw.states = make(map[AlertRule]*alertState)
+	aw.mu.Unlock()
+}
+
+// Evaluate checks every alert rule for the server against the latest usage.
+func (aw *alertWatcher) Evaluate(stats environment.Stats) {
+	rules := aw.server.Config().Alerts
+	if len(rules) == 0 {
+		return
+	}
+
+	now := time.Now()
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			continue
+		}
+		usage, ok := aw.server.alertUsage(r.Metric, stats)
+		if !ok {
+			continue
+		}
+		st, ok := aw.states[r]
+		if !ok {
+			st = &alertState{}
+			aw.states[r] = st
+		}
+		if change := st.check(usage >= r.Threshold, time.Duration(r.Duration)*time.Second, now); change != alertUnchanged {
+			go aw.server.onAlert(r, change, usage)
+		}
+	}
+}
+
+// alertUsage returns the usage of the resource as a percentage of the limit for
+// the server, returning false if the server has no limit for the resource.
+func (s *Server) alertUsage(metric string, stats environment.Stats) (float64, bool) {
+	switch metric {
+	case AlertMetricMemory:
+		if limit := s.MemoryLimit(); limit > 0 {
+			return float64(stats.Memory) / float64(limit*1024*1024) * 100, true
+		}
+	case AlertMetricCpu:
+		if limit := s.Config().Build.CpuLimit; limit > 0 {
+			return stats.CpuAbsolute / float64(limit) * 100, true
+		}
+	case AlertMetricDisk:
+		if limit := s.DiskSpace(); limit > 0 {
+			return float64(s.Filesystem().CachedUsage()) / float64(limit) * 100, true
+		}
+	}
+	return 0, false
+}
+
+// onAlert emits the events for an alert that has changed state, and applies the
+// action for the rule when it fires.
+func (s *Server) onAlert(r AlertRule, state string, usage float64) {
+	s.Log().WithFields(log.Fields{"metric": r.Metric, "threshold": r.Threshold, "usage": usage, "state": state}).Info("resource alert for server changed state")
+
+	data := map[string]interface{}{
+		"metric":    r.Metric,
+		"threshold": r.Threshold,
+		"duration":  r.Duration,
+		"usage":     usage,
+		"state":     state,
+	}
+	s.Events().Publish(ResourceAlertEvent, data)
+	webhook.Dispatch(s.ID(), webhook.ResourceAlertEvent, data)
+
+	if state != alertFiring {
+		return
+	}
+	message := r.Message
+	if message == "" {
+		message = fmt.Sprintf("Server %s usage is at %.1f%%, above the alert threshold of %.1f%%.", r.Metric, usage, r.Threshold)
+	}
+	switch r.Action {
+	case AlertActionWarn:
+		s.PublishConsoleOutputFromDaemon(message)
+	case AlertActionRestart:
+		s.PublishConsoleOutputFromDaemon(message)
+		s.PublishConsoleOutputFromDaemon(fmt.Sprintf("Server will be restarted in %d seconds.", r.Delay))
+		select {
+		case <-time.After(time.Duration(r.Delay) * time.Second):
+		case <-s.Context().Done():
+			return
+		}
+		if !s.IsRunning() {
+			return
+		}
+		if err := s.HandlePowerAction(PowerActionRestart, 30); err != nil {
+			s.Log().WithField("error", err).Error("failed to restart server after resource alert")
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+)
+
+func TestAlerts(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("alertState", func() {
+		start := time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+
+		g.It("fires once the usage has been above the threshold for the duration", func() {
+			st := &alertState{}
+			g.Assert(st.check(true, time.Minute, start)).Equal(alertUnchanged)
+			g.Assert(st.check(true, time.Minute, start.Add(30*time.Second))).Equal(alertUnchanged)
+			g.Assert(st.check(true, time.Minute, start.Add(time.Minute))).Equal(alertFiring)
+			g.Assert(st.check(true, time.Minute, start.Add(2*time.Minute))).Equal(alertUnchanged)
+			g.Assert(st.check(false, time.Minute, start.Add(3*time.Minute))).Equal(alertResolved)
+			g.Assert(st.check(false, time.Minute, start.Add(4*time.Minute))).Equal(alertUnchanged)
+		})
+
+		g.It("starts the duration again when the usage drops", func() {
+			st := &alertState{}
+			g.Assert(st.check(true, time.Minute, start)).Equal(alertUnchanged)
+			g.Assert(st.check(false, time.Minute, start.Add(50*time.Second))).Equal(alertUnchanged)
+			g.Assert(st.check(true, time.Minute, start.Add(70*time.Second))).Equal(alertUnchanged)
+			g.Assert(st.check(true, time.Minute, start.Add(130*time.Second))).Equal(alertFiring)
+		})
+
+		g.It("fires immediately without a duration", func() {
+			st := &alertState{}
+			g.Assert(st.check(true, 0, start)).Equal(alertFiring)
+		})
+	})
+
+	g.Describe("AlertRule", func() {
+		g.It("validates the rule", func() {
+			g.Assert(AlertRule{Metric: AlertMetricMemory, Threshold: 95}.Validate()).IsNil()
+			g.Assert(AlertRule{Metric: AlertMetricDisk, Threshold: 90, Action: AlertActionRestart}.Validate()).IsNil()
+			g.Assert(AlertRule{Metric: "swap", Threshold: 90}.Validate() == nil).IsFalse()
+			g.Assert(AlertRule{Metric: AlertMetricCpu}.Validate() == nil).IsFalse()
+			g.Assert(AlertRule{Metric: AlertMetricCpu, Threshold: 90, Action: "stop"}.Validate() == nil).IsFalse()
+		})
+	})
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"io"
 	"io/fs"
 	"os"
@@ -11,8 +12,10 @@ import (
 	"github.com/docker/docker/client"
 
 	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/events/webhook"
 	"github.com/pterodactyl/wings/remote"
 	"github.com/pterodactyl/wings/server/backup"
+	"github.com/pterodactyl/wings/server/filesystem"
 )
 
 // Notifies the panel of a backup's state and returns an error if one is encountered
@@ -68,8 +71,38 @@ func (s *Server) Backup(b backup.BackupInterface) error {
 		}
 	}
 
-	ad, err := b.Generate(s.Context(), s.Filesystem().Path(), ignored)
+	// The backup can be canceled while it is waiting for other backups to complete
+	// as well as while it is being generated.
+	ctx, op, done := s.StartOperation(s.Context(), OperationBackup, b.Identifier())
+	defer done()
+	// The progress is the size of the files added to the backup so far, out of the
+	// disk usage of the server.
+	op.SetTotal(s.Filesystem().CachedUsage())
+	ctx = filesystem.WithArchiveProgress(ctx, op)
+
+	// Wait for any other backups on the node to complete if the maximum number of
+	// backups are already running. A backup that is canceled while it is waiting is
+	// reported to the Panel as failed, unless the server is being removed.
+	release, err := backups.acquire(ctx, s.ID())
+	if err != nil && s.Context().Err() != nil {
+		return errors.WrapIf(err, "backup: error while waiting for other backups to complete")
+	}
+	var ad *backup.ArchiveDetails
+	if err == nil {
+		ad, err = b.Generate(ctx, s.Filesystem().Path(), ignored)
+		release()
+	}
 	if err != nil {
+		// The adapters clean up after most failures themselves, but make sure that
+		// nothing is left behind by a backup that was canceled since it will never
+		// be retried.
+		if ctx.Err() != nil && s.Context().Err() == nil {
+			s.Log().WithField("backup", b.Identifier()).Info("backup was canceled, removing partial backup")
+			if err := b.Remove(); err != nil {
+				s.Log().WithField("backup", b.Identifier()).WithField("error", err).Warn("failed to remove canceled backup")
+			}
+		}
+
 		if err := s.notifyPanelOfBackup(b.Identifier(), &backup.ArchiveDetails{}, false); err != nil {
 			s.Log().WithFields(log.Fields{
 				"backup": b.Identifier(),
@@ -79,6 +112,11 @@ func (s *Server) Backup(b backup.BackupInterface) error {
 			s.Log().WithField("backup", b.Identifier()).Info("notified panel of failed backup state")
 		}
 
+		webhook.Dispatch(s.ID(), webhook.BackupFailedEvent, map[string]interface{}{
+			"uuid":  b.Identifier(),
+			"error": err.Error(),
+		})
+
 		s.Events().Publish(BackupCompletedEvent+":"+b.Identifier(), map[string]interface{}{
 			"uuid":          b.Identifier(),
 			"is_successful": false,
@@ -90,6 +128,14 @@ func (s *Server) Backup(b backup.BackupInterface) error {
 		return errors.WrapIf(err, "backup: error while generating server backup")
 	}
 
+	// Record the server that a local backup belongs to, so that it cannot be
+	// downloaded or restored through another server.
+	if lb, ok := b.(*backup.LocalBackup); ok {
+		if err := lb.WriteOwner(s.ID()); err != nil {
+			s.Log().WithField("backup", b.Identifier()).WithField("error", err).Warn("failed to record the server a local backup belongs to")
+		}
+	}
+
 	// Try to notify the panel about the status of this backup. If for some reason this request
 	// fails, delete the archive from the daemon and return that error up the chain to the caller.
 	if notifyError := s.notifyPanelOfBackup(b.Identifier(), ad, true); notifyError != nil {
@@ -149,10 +195,21 @@ func (s *Server) RestoreBackup(b backup.BackupInterface, reader io.ReadCloser) (
 		}
 	}
 
+	// Snapshots stored on the same volume as the server can be cloned straight back
+	// into the server rather than writing out each file.
+	if sb, ok := b.(snapshotBackup); ok && sb.IsSnapshot() {
+		return s.restoreSnapshot(sb)
+	}
+
 	// Attempt to restore the backup to the server by running through each entry
 	// in the file one at a time and writing them to the disk.
 	s.Log().Debug("starting file writing process for backup restoration")
+	names := filesystem.NewNameResolver()
 	err = b.Restore(s.Context(), reader, func(file string, r io.Reader, mode fs.FileMode, atime, mtime time.Time) error {
+		file, ok := names.Resolve(file)
+		if !ok {
+			return nil
+		}
 		s.Events().Publish(DaemonMessageEvent, "(restoring): "+file)
 		if err := s.Filesystem().Writefile(file, r); err != nil {
 			return err
@@ -162,6 +219,35 @@ func (s *Server) RestoreBackup(b backup.BackupInterface, reader io.ReadCloser) (
 		}
 		return s.Filesystem().Chtimes(file, atime, mtime)
 	})
+	for _, c := range names.Conflicts() {
+		s.Log().WithFields(log.Fields{"name": c.Name, "resolved": c.Resolved, "reason": c.Reason}).Warn("renamed or skipped file with a conflicting name when restoring backup")
+		s.Events().Publish(DaemonMessageEvent, "(restoring): "+c.String())
+	}
 
 	return errors.WithStackIf(err)
 }
+
+// snapshotBackup is a backup that may be stored as a snapshot of the server
+// files, which can be cloned back into the server.
+type snapshotBackup interface {
+	IsSnapshot() bool
+	CloneTo(ctx context.Context, dst *filesystem.Filesystem) (bool, error)
+}
+
+func (s *Server) restoreSnapshot(b snapshotBackup) error {
+	s.Log().Debug("cloning files from snapshot for backup restoration")
+	s.Events().Publish(DaemonMessageEvent, "(restoring): cloning files from snapshot")
+	start := time.Now()
+	cow, err := b.CloneTo(s.Context(), s.Filesystem())
+	if err != nil {
+		return errors.WrapIf(err, "server/backup: restore: failed to clone snapshot")
+	}
+	s.Log().WithFields(log.Fields{
+		"copy_on_write":  cow,
+		"execution_time": time.Since(start),
+	}).Info("restored backup from snapshot")
+
+	// Update the disk usage for the server now that the files have been cloned in.
+	s.Filesystem().HasSpaceAvailable(false)
+	return nil
+}
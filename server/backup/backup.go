@@ -1,6 +1,8 @@
 package backup
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"context"
 	"crypto/sha1"
 	"encoding/hex"
@@ -16,6 +18,8 @@ import (
 
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/server/filesystem"
+	"github.com/pterodactyl/wings/throttle"
 )
 
 type AdapterType string
@@ -23,8 +27,14 @@ type AdapterType string
 const (
 	LocalBackupAdapter AdapterType = "wings"
 	S3BackupAdapter    AdapterType = "s3"
+	AzureBackupAdapter AdapterType = "azure"
+	GcsBackupAdapter   AdapterType = "gcs"
 )
 
+// ErrAdapterNotConfigured is returned when a backup uses an adapter that needs
+// to be configured on the node, but has not been.
+var ErrAdapterNotConfigured = errors.Sentinel("backup: adapter is not configured on this node")
+
 // RestoreCallback is a generic restoration callback that exists for both local
 // and remote backups allowing the files to be restored.
 type RestoreCallback func(file string, r io.Reader, mode fs.FileMode, atime, mtime time.Time) error
@@ -33,6 +43,9 @@ type RestoreCallback func(file string, r io.Reader, mode fs.FileMode, atime, mti
 type BackupInterface interface {
 	// SetClient sets the API request client on the backup interface.
 	SetClient(c remote.Client)
+	// SetOwner sets the server, and the tenant it belongs to, that the backup
+	// belongs to.
+	SetOwner(tenant string, server string)
 	// Identifier returns the UUID of this backup as tracked by the panel
 	// instance.
 	Identifier() string
@@ -62,6 +75,32 @@ type BackupInterface interface {
 	Restore(ctx context.Context, reader io.Reader, callback RestoreCallback) error
 }
 
+// RemoteBackup is a backup that is stored somewhere other than this node, and
+// is read directly from there by Wings rather than through a download link
+// provided by the Panel.
+type RemoteBackup interface {
+	BackupInterface
+	// Open returns a reader for the archive stored remotely.
+	Open(ctx context.Context) (io.ReadCloser, error)
+}
+
+// New returns the backup for the given adapter, or an error if the adapter is
+// not a valid type.
+func New(adapter AdapterType, client remote.Client, uuid string, ignore string) (BackupInterface, error) {
+	switch adapter {
+	case LocalBackupAdapter:
+		return NewLocal(client, uuid, ignore), nil
+	case S3BackupAdapter:
+		return NewS3(client, uuid, ignore), nil
+	case AzureBackupAdapter:
+		return NewAzure(client, uuid, ignore), nil
+	case GcsBackupAdapter:
+		return NewGcs(client, uuid, ignore), nil
+	default:
+		return nil, errors.New("backup: provided adapter is not valid: " + string(adapter))
+	}
+}
+
 type Backup struct {
 	// The UUID of this backup object. This must line up with a backup from
 	// the panel instance.
@@ -73,6 +112,7 @@ type Backup struct {
 
 	client     remote.Client
 	adapter    AdapterType
+	owner      string
 	logContext map[string]interface{}
 }
 
@@ -80,6 +120,26 @@ func (b *Backup) SetClient(c remote.Client) {
 	b.client = c
 }
 
+// SetOwner sets the server that the backup belongs to, along with the tenant
+// that the server belongs to, which is empty for the primary Panel.
+func (b *Backup) SetOwner(tenant string, server string) {
+	b.owner = server
+	if tenant != "" {
+		b.owner = tenant + "/" + server
+	}
+}
+
+// remoteName returns the name of the archive for the backup in a container or
+// bucket, within the given prefix. Archives are stored in a directory for the
+// server they belong to, so that a server is only ever able to read or delete
+// its own backups.
+func (b *Backup) remoteName(prefix string) (string, error) {
+	if b.owner == "" {
+		return "", errors.New("backup: the server that the backup belongs to has not been set")
+	}
+	return prefix + b.owner + "/" + b.Identifier() + ".tar.gz", nil
+}
+
 func (b *Backup) Identifier() string {
 	return b.Uuid
 }
@@ -162,9 +222,10 @@ func (b *Backup) log() *log.Entry {
 }
 
 type ArchiveDetails struct {
-	Checksum     string `json:"checksum"`
-	ChecksumType string `json:"checksum_type"`
-	Size         int64  `json:"size"`
+	Checksum     string              `json:"checksum"`
+	ChecksumType string              `json:"checksum_type"`
+	Size         int64               `json:"size"`
+	Parts        []remote.BackupPart `json:"parts"`
 }
 
 // ToRequest returns a request object.
@@ -174,5 +235,76 @@ func (ad *ArchiveDetails) ToRequest(successful bool) remote.BackupRequest {
 		ChecksumType: ad.ChecksumType,
 		Size:         ad.Size,
 		Successful:   successful,
+		Parts:        ad.Parts,
+	}
+}
+
+// streamArchive creates the archive and passes it to upload as it is written,
+// without it ever being written to the disk. The checksum and size are worked
+// out as the archive is read by upload since the archive cannot be read again
+// once it has been uploaded.
+func streamArchive(ctx context.Context, a *filesystem.Archive, upload func(ctx context.Context, r io.Reader) error) (*ArchiveDetails, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(a.Stream(ctx, pw))
+	}()
+
+	h := sha1.New()
+	cr := &countingReader{r: io.TeeReader(pr, h)}
+	if err := upload(ctx, cr); err != nil {
+		// Stop creating the archive since nothing is reading it anymore.
+		_ = pr.CloseWithError(err)
+		return nil, err
+	}
+	// Make sure that the entire archive was read, otherwise the checksum and size
+	// would not match the archive that was uploaded.
+	if n, err := io.Copy(io.Discard, cr); err != nil {
+		return nil, errors.Wrap(err, "backup: failed to create archive")
+	} else if n > 0 {
+		return nil, errors.New("backup: archive was not completely uploaded")
+	}
+	return &ArchiveDetails{
+		Checksum:     hex.EncodeToString(h.Sum(nil)),
+		ChecksumType: "sha1",
+		Size:         cr.n,
+	}, nil
+}
+
+// restoreArchive reads from the provided reader assuming that it is a gzipped
+// tar reader. When a file is encountered in the archive the callback function
+// will be triggered. If the callback returns an error the entire process is
+// stopped, otherwise this function will run until all files have been written.
+func restoreArchive(ctx context.Context, r io.Reader, callback RestoreCallback) error {
+	// Steal the logic we use for making backups which will be applied when restoring
+	// this specific backup. This allows us to prevent overloading the disk unintentionally.
+	reader := throttle.Reader(r, func(now time.Time) int {
+		return config.Get().System.Backups.WriteLimitAt(now)
+	})
+	gr, err := gzip.NewReader(reader)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+			// Do nothing, fall through to the next block of code in this loop.
+		}
+		header, err := tr.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if header.Typeflag == tar.TypeReg {
+			if err := callback(header.Name, tr, header.FileInfo().Mode(), header.AccessTime, header.ModTime); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
 }
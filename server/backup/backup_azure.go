@@ -0,0 +1,217 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/server/filesystem"
+)
+
+// The version of the Azure Blob Storage REST API used for requests.
+const azureApiVersion = "2020-10-02"
+
+// AzureBackup is a backup stored in an Azure Blob Storage container that is
+// configured on the node. The archive is uploaded as a block blob so that it
+// can be sent in chunks, with each chunk retried separately if it fails.
+type AzureBackup struct {
+	Backup
+}
+
+var _ RemoteBackup = (*AzureBackup)(nil)
+
+func NewAzure(client remote.Client, uuid string, ignore string) *AzureBackup {
+	return &AzureBackup{
+		Backup{
+			client:  client,
+			Uuid:    uuid,
+			Ignore:  ignore,
+			adapter: AzureBackupAdapter,
+		},
+	}
+}
+
+// WithLogContext attaches additional context to the log output for this backup.
+func (a *AzureBackup) WithLogContext(c map[string]interface{}) {
+	a.logContext = c
+}
+
+// Remove deletes the backup from the container.
+func (a *AzureBackup) Remove() error {
+	u, err := a.blobUrl(nil)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-version", azureApiVersion)
+	res, err := uploadClient.Do(req)
+	if err != nil {
+		return errors.Wrap(redact(err), "backup: failed to delete blob")
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusAccepted {
+		return statusError(req, res)
+	}
+	return nil
+}
+
+// Generate creates a new backup and uploads it to the container. Unless uploads
+// are streamed the backup is written to the disk first, and then deleted once
+// it has been uploaded.
+func (a *AzureBackup) Generate(ctx context.Context, basePath, ignore string) (*ArchiveDetails, error) {
+	archive := &filesystem.Archive{
+		BasePath: basePath,
+		Ignore:   ignore,
+	}
+
+	if config.Get().System.Backups.StreamUploads {
+		a.log().Info("creating and uploading backup for server")
+		return streamArchive(ctx, archive, a.upload)
+	}
+
+	defer os.Remove(a.Path())
+
+	a.log().WithField("path", a.Path()).Info("creating backup for server")
+	if err := archive.Create(ctx, a.Path()); err != nil {
+		return nil, err
+	}
+	a.log().Info("created backup successfully")
+
+	f, err := os.Open(a.Path())
+	if err != nil {
+		return nil, errors.Wrap(err, "backup: could not read archive from disk")
+	}
+	defer f.Close()
+
+	if err := a.upload(ctx, f); err != nil {
+		return nil, err
+	}
+	ad, err := a.Details(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "backup: failed to get archive details after upload")
+	}
+	return ad, nil
+}
+
+// Open returns a reader for the blob stored in the container.
+func (a *AzureBackup) Open(ctx context.Context) (io.ReadCloser, error) {
+	u, err := a.blobUrl(nil)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", azureApiVersion)
+	return openRemote(req)
+}
+
+// Restore will read from the provided reader assuming that it is a gzipped
+// tar reader, calling the callback for every file in the archive.
+func (a *AzureBackup) Restore(ctx context.Context, r io.Reader, callback RestoreCallback) error {
+	return restoreArchive(ctx, r, callback)
+}
+
+// upload sends the archive to the container as a series of blocks and then
+// commits the list of blocks, at which point the blob becomes visible.
+func (a *AzureBackup) upload(ctx context.Context, r io.Reader) error {
+	a.log().Info("attempting to upload backup to azure container...")
+
+	var blocks []string
+	_, err := uploadChunks(ctx, r, func(ctx context.Context, chunk []byte, _ int64, _ bool) error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		// Every block ID within a blob must be the same length.
+		id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%010d", len(blocks))))
+		u, err := a.blobUrl(url.Values{"comp": {"block"}, "blockid": {id}})
+		if err != nil {
+			return err
+		}
+		if _, err := sendWithRetry(ctx, func() (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(chunk))
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("x-ms-version", azureApiVersion)
+			return req, nil
+		}, func(status int) bool {
+			return status == http.StatusCreated
+		}); err != nil {
+			return errors.WrapIf(err, "backup: failed to upload block")
+		}
+		blocks = append(blocks, id)
+		a.log().WithField("block", len(blocks)).Debug("uploaded backup block")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?><BlockList>`)
+	for _, id := range blocks {
+		body.WriteString("<Latest>" + id + "</Latest>")
+	}
+	body.WriteString("</BlockList>")
+
+	u, err := a.blobUrl(url.Values{"comp": {"blocklist"}})
+	if err != nil {
+		return err
+	}
+	if _, err := sendWithRetry(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, strings.NewReader(body.String()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("x-ms-version", azureApiVersion)
+		req.Header.Set("x-ms-blob-content-type", "application/x-gzip")
+		return req, nil
+	}, func(status int) bool {
+		return status == http.StatusCreated
+	}); err != nil {
+		return errors.WrapIf(err, "backup: failed to commit block list")
+	}
+
+	a.log().WithField("blocks", len(blocks)).Info("backup has been successfully uploaded")
+	return nil
+}
+
+// blobUrl returns the URL of the blob for this backup, including the SAS token
+// from the configured container URL and any additional query parameters. The
+// blob is stored in a directory for the server the backup belongs to.
+func (a *AzureBackup) blobUrl(query url.Values) (string, error) {
+	cfg := config.Get().System.Backups.Azure
+	if cfg.ContainerUrl == "" {
+		return "", errors.WithStack(ErrAdapterNotConfigured)
+	}
+	u, err := url.Parse(cfg.ContainerUrl)
+	if err != nil {
+		return "", errors.Wrap(err, "backup: failed to parse azure container url")
+	}
+	name, err := a.remoteName(cfg.Prefix)
+	if err != nil {
+		return "", err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + name
+	q := u.Query()
+	for k, v := range query {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
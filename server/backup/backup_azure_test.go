@@ -0,0 +1,98 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestAzureBackup(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("upload", func() {
+		var mu sync.Mutex
+		var requests []string
+		var blockList string
+		var srv *httptest.Server
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			requests = append(requests, r.Method+" "+r.URL.Path+" "+r.URL.Query().Get("comp")+" "+r.URL.Query().Get("sig"))
+			if r.URL.Query().Get("comp") == "blocklist" {
+				b, _ := io.ReadAll(r.Body)
+				blockList = string(b)
+			}
+			if r.Method == http.MethodDelete {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		})
+
+		g.BeforeEach(func() {
+			requests = nil
+			srv = httptest.NewServer(handler)
+			config.Set(&config.Configuration{
+				AuthenticationToken: "token",
+				System: config.SystemConfiguration{
+					Backups: config.Backups{
+						Azure: config.AzureBackupConfiguration{ContainerUrl: srv.URL + "/backups?sig=secret", Prefix: "node/"},
+					},
+				},
+			})
+		})
+
+		g.AfterEach(func() {
+			srv.Close()
+		})
+
+		g.It("uploads the archive in blocks and commits them", func() {
+			b := NewAzure(nil, "uuid", "")
+			b.SetOwner("", "server")
+			err := b.upload(context.Background(), bytes.NewReader(make([]byte, chunkSize+1)))
+			g.Assert(err).IsNil()
+			g.Assert(requests).Equal([]string{
+				"PUT /backups/node/server/uuid.tar.gz block secret",
+				"PUT /backups/node/server/uuid.tar.gz block secret",
+				"PUT /backups/node/server/uuid.tar.gz blocklist secret",
+			})
+			g.Assert(strings.Count(blockList, "<Latest>")).Equal(2)
+		})
+
+		g.It("does not upload an empty block", func() {
+			b := NewAzure(nil, "uuid", "")
+			b.SetOwner("", "server")
+			err := b.upload(context.Background(), bytes.NewReader(make([]byte, chunkSize)))
+			g.Assert(err).IsNil()
+			g.Assert(len(requests)).Equal(2)
+		})
+
+		g.It("stores the archive in a directory for the tenant and server", func() {
+			b := NewAzure(nil, "uuid", "")
+			b.SetOwner("tenant", "server")
+			g.Assert(b.Remove()).IsNil()
+			g.Assert(requests).Equal([]string{"DELETE /backups/node/tenant/server/uuid.tar.gz  secret"})
+		})
+
+		g.It("returns an error when the server the backup belongs to has not been set", func() {
+			err := NewAzure(nil, "uuid", "").upload(context.Background(), bytes.NewReader([]byte("a")))
+			g.Assert(err).IsNotNil()
+			g.Assert(len(requests)).Equal(0)
+		})
+
+		g.It("returns an error when not configured", func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token"})
+			err := NewAzure(nil, "uuid", "").upload(context.Background(), bytes.NewReader([]byte("a")))
+			g.Assert(err).IsNotNil()
+		})
+	})
+}
@@ -0,0 +1,343 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/gbrlsnchs/jwt/v3"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/server/filesystem"
+)
+
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// The endpoints of the Google Cloud Storage JSON API, which are only changed in
+// tests.
+var (
+	gcsUploadEndpoint  = "https://storage.googleapis.com/upload/storage/v1/b/"
+	gcsStorageEndpoint = "https://storage.googleapis.com/storage/v1/b/"
+)
+
+// GcsBackup is a backup stored in a Google Cloud Storage bucket that is
+// configured on the node. The archive is sent using a resumable upload so that
+// it can be sent in chunks, with each chunk retried separately if it fails.
+type GcsBackup struct {
+	Backup
+}
+
+var _ RemoteBackup = (*GcsBackup)(nil)
+
+func NewGcs(client remote.Client, uuid string, ignore string) *GcsBackup {
+	return &GcsBackup{
+		Backup{
+			client:  client,
+			Uuid:    uuid,
+			Ignore:  ignore,
+			adapter: GcsBackupAdapter,
+		},
+	}
+}
+
+// WithLogContext attaches additional context to the log output for this backup.
+func (g *GcsBackup) WithLogContext(c map[string]interface{}) {
+	g.logContext = c
+}
+
+// Remove deletes the backup from the bucket.
+func (g *GcsBackup) Remove() error {
+	u, err := g.objectUrl("")
+	if err != nil {
+		return err
+	}
+	req, err := g.newRequest(context.Background(), http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	res, err := uploadClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "backup: failed to delete object")
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		return statusError(req, res)
+	}
+	return nil
+}
+
+// Generate creates a new backup and uploads it to the bucket. Unless uploads
+// are streamed the backup is written to the disk first, and then deleted once
+// it has been uploaded.
+func (g *GcsBackup) Generate(ctx context.Context, basePath, ignore string) (*ArchiveDetails, error) {
+	archive := &filesystem.Archive{
+		BasePath: basePath,
+		Ignore:   ignore,
+	}
+
+	if config.Get().System.Backups.StreamUploads {
+		g.log().Info("creating and uploading backup for server")
+		return streamArchive(ctx, archive, g.upload)
+	}
+
+	defer os.Remove(g.Path())
+
+	g.log().WithField("path", g.Path()).Info("creating backup for server")
+	if err := archive.Create(ctx, g.Path()); err != nil {
+		return nil, err
+	}
+	g.log().Info("created backup successfully")
+
+	f, err := os.Open(g.Path())
+	if err != nil {
+		return nil, errors.Wrap(err, "backup: could not read archive from disk")
+	}
+	defer f.Close()
+
+	if err := g.upload(ctx, f); err != nil {
+		return nil, err
+	}
+	ad, err := g.Details(ctx)
+	if err != nil {
+		return nil, errors.WrapIf(err, "backup: failed to get archive details after upload")
+	}
+	return ad, nil
+}
+
+// Open returns a reader for the object stored in the bucket.
+func (g *GcsBackup) Open(ctx context.Context) (io.ReadCloser, error) {
+	u, err := g.objectUrl("alt=media")
+	if err != nil {
+		return nil, err
+	}
+	req, err := g.newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return openRemote(req)
+}
+
+// Restore will read from the provided reader assuming that it is a gzipped
+// tar reader, calling the callback for every file in the archive.
+func (g *GcsBackup) Restore(ctx context.Context, r io.Reader, callback RestoreCallback) error {
+	return restoreArchive(ctx, r, callback)
+}
+
+// upload starts a resumable upload session and then sends the archive to it in
+// chunks. The final size of the archive is only sent with the last chunk, so
+// the size does not need to be known in advance.
+func (g *GcsBackup) upload(ctx context.Context, r io.Reader) error {
+	cfg := config.Get().System.Backups.Gcs
+	if cfg.Bucket == "" {
+		return errors.WithStack(ErrAdapterNotConfigured)
+	}
+
+	name, err := g.remoteName(cfg.Prefix)
+	if err != nil {
+		return err
+	}
+
+	g.log().Info("attempting to upload backup to gcs bucket...")
+	u := gcsUploadEndpoint + url.PathEscape(cfg.Bucket) + "/o?uploadType=resumable&name=" + url.QueryEscape(name)
+	res, err := sendWithRetry(ctx, func() (*http.Request, error) {
+		req, err := g.newRequest(ctx, http.MethodPost, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-Upload-Content-Type", "application/x-gzip")
+		return req, nil
+	}, func(status int) bool {
+		return status == http.StatusOK
+	})
+	if err != nil {
+		return errors.WrapIf(err, "backup: failed to start upload session")
+	}
+	session := res.Header.Get("Location")
+	if session == "" {
+		return errors.New("backup: upload session was not returned by gcs")
+	}
+
+	var chunks int
+	_, err = uploadChunks(ctx, r, func(ctx context.Context, chunk []byte, offset int64, last bool) error {
+		// The total size is unknown until the last chunk has been read, and the last
+		// chunk may be empty if the archive is an exact multiple of the chunk size.
+		total := "*"
+		if last {
+			total = fmt.Sprintf("%d", offset+int64(len(chunk)))
+		}
+		contentRange := fmt.Sprintf("bytes %d-%d/%s", offset, offset+int64(len(chunk))-1, total)
+		if len(chunk) == 0 {
+			contentRange = "bytes */" + total
+		}
+		if _, err := sendWithRetry(ctx, func() (*http.Request, error) {
+			req, err := g.newRequest(ctx, http.MethodPut, session, bytes.NewReader(chunk))
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Content-Range", contentRange)
+			return req, nil
+		}, func(status int) bool {
+			// A 308 is returned for every chunk until the upload is complete.
+			if last {
+				return status == http.StatusOK || status == http.StatusCreated
+			}
+			return status == http.StatusPermanentRedirect
+		}); err != nil {
+			return errors.WrapIf(err, "backup: failed to upload chunk")
+		}
+		chunks++
+		g.log().WithField("chunk", chunks).Debug("uploaded backup chunk")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	g.log().WithField("chunks", chunks).Info("backup has been successfully uploaded")
+	return nil
+}
+
+// objectUrl returns the URL of the object in the bucket with the given query.
+// The object is stored in a directory for the server the backup belongs to.
+func (g *GcsBackup) objectUrl(query string) (string, error) {
+	cfg := config.Get().System.Backups.Gcs
+	name, err := g.remoteName(cfg.Prefix)
+	if err != nil {
+		return "", err
+	}
+	u := gcsStorageEndpoint + url.PathEscape(cfg.Bucket) + "/o/" + url.PathEscape(name)
+	if query != "" {
+		u += "?" + query
+	}
+	return u, nil
+}
+
+// newRequest returns a request that is authenticated using the access token
+// for the configured service account.
+func (g *GcsBackup) newRequest(ctx context.Context, method string, u string, body io.Reader) (*http.Request, error) {
+	cfg := config.Get().System.Backups.Gcs
+	if cfg.Bucket == "" {
+		return nil, errors.WithStack(ErrAdapterNotConfigured)
+	}
+	token, err := gcsTokens.token(ctx, cfg.CredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req, nil
+}
+
+// gcsTokens caches the access token for the service account so that a new one
+// is only requested once the previous token is about to expire.
+var gcsTokens = &gcsTokenSource{}
+
+type gcsTokenSource struct {
+	mu      sync.Mutex
+	file    string
+	value   string
+	expires time.Time
+}
+
+type gcsCredentials struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenUri    string `json:"token_uri"`
+}
+
+type gcsClaims struct {
+	jwt.Payload
+	Scope string `json:"scope"`
+}
+
+// token returns an access token for the service account in the given file,
+// exchanging a JWT signed with the account's private key for a new token if
+// there is not one cached.
+func (t *gcsTokenSource) token(ctx context.Context, file string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == file && time.Now().Add(time.Minute).Before(t.expires) {
+		return t.value, nil
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", errors.Wrap(err, "backup: failed to read gcs credentials")
+	}
+	var creds gcsCredentials
+	if err := json.Unmarshal(b, &creds); err != nil {
+		return "", errors.Wrap(err, "backup: failed to parse gcs credentials")
+	}
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", errors.New("backup: gcs credentials do not contain a private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", errors.Wrap(err, "backup: failed to parse gcs private key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("backup: gcs private key is not an RSA key")
+	}
+
+	now := time.Now()
+	assertion, err := jwt.Sign(gcsClaims{
+		Payload: jwt.Payload{
+			Issuer:         creds.ClientEmail,
+			Audience:       jwt.Audience{creds.TokenUri},
+			IssuedAt:       jwt.NumericDate(now),
+			ExpirationTime: jwt.NumericDate(now.Add(time.Hour)),
+		},
+		Scope: gcsScope,
+	}, jwt.NewRS256(jwt.RSAPrivateKey(key)))
+	if err != nil {
+		return "", errors.Wrap(err, "backup: failed to sign gcs token request")
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {string(assertion)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, creds.TokenUri, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := uploadClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "backup: failed to request gcs access token")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", statusError(req, res)
+	}
+	var data struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return "", errors.Wrap(err, "backup: failed to parse gcs access token")
+	}
+
+	t.file = file
+	t.value = data.AccessToken
+	t.expires = now.Add(time.Duration(data.ExpiresIn) * time.Second)
+	return t.value, nil
+}
@@ -0,0 +1,145 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// writeGcsCredentials writes a service account key with a new private key to
+// the directory, which requests access tokens from the given URL.
+func writeGcsCredentials(dir string, tokenUri string) (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(gcsCredentials{
+		ClientEmail: "wings@example.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenUri:    tokenUri,
+	})
+	if err != nil {
+		return "", err
+	}
+	p := filepath.Join(dir, "credentials.json")
+	return p, os.WriteFile(p, b, 0o600)
+}
+
+func TestGcsBackup(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("GcsBackup", func() {
+		var mu sync.Mutex
+		var requests []string
+		var ranges []string
+		var srv *httptest.Server
+		var dir string
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if r.URL.Path == "/token" {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "access", "expires_in": 3600})
+				return
+			}
+			requests = append(requests, r.Method+" "+r.URL.Path+" "+r.URL.Query().Get("name")+" "+r.Header.Get("Authorization"))
+			switch {
+			case r.URL.Query().Get("uploadType") == "resumable":
+				w.Header().Set("Location", srv.URL+"/session")
+				w.WriteHeader(http.StatusOK)
+			case r.URL.Path == "/session":
+				ranges = append(ranges, r.Header.Get("Content-Range"))
+				if len(ranges) == 1 {
+					w.WriteHeader(http.StatusPermanentRedirect)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			case r.Method == http.MethodDelete:
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+
+		g.BeforeEach(func() {
+			requests = nil
+			ranges = nil
+			srv = httptest.NewServer(handler)
+			gcsUploadEndpoint = srv.URL + "/upload/"
+			gcsStorageEndpoint = srv.URL + "/storage/"
+			gcsTokens = &gcsTokenSource{}
+
+			dir, _ = os.MkdirTemp(os.TempDir(), "pterodactyl-gcs")
+			creds, err := writeGcsCredentials(dir, srv.URL+"/token")
+			g.Assert(err).IsNil()
+			config.Set(&config.Configuration{
+				AuthenticationToken: "token",
+				System: config.SystemConfiguration{
+					Backups: config.Backups{
+						Gcs: config.GcsBackupConfiguration{Bucket: "backups", CredentialsFile: creds, Prefix: "node/"},
+					},
+				},
+			})
+		})
+
+		g.AfterEach(func() {
+			srv.Close()
+			_ = os.RemoveAll(dir)
+		})
+
+		g.It("uploads the archive in chunks using a resumable upload", func() {
+			b := NewGcs(nil, "uuid", "")
+			b.SetOwner("", "server")
+			err := b.upload(context.Background(), bytes.NewReader(make([]byte, chunkSize+1)))
+			g.Assert(err).IsNil()
+			g.Assert(requests).Equal([]string{
+				"POST /upload/backups/o node/server/uuid.tar.gz Bearer access",
+				"PUT /session  Bearer access",
+				"PUT /session  Bearer access",
+			})
+			g.Assert(ranges).Equal([]string{
+				"bytes 0-16777215/*",
+				"bytes 16777216-16777216/16777217",
+			})
+		})
+
+		g.It("deletes the object from the directory for the tenant and server", func() {
+			b := NewGcs(nil, "uuid", "")
+			b.SetOwner("tenant", "server")
+			g.Assert(b.Remove()).IsNil()
+			g.Assert(requests).Equal([]string{"DELETE /storage/backups/o/node/tenant/server/uuid.tar.gz  Bearer access"})
+		})
+
+		g.It("returns an error when the server the backup belongs to has not been set", func() {
+			g.Assert(NewGcs(nil, "uuid", "").Remove()).IsNotNil()
+			_, err := NewGcs(nil, "uuid", "").Open(context.Background())
+			g.Assert(err).IsNotNil()
+			g.Assert(len(requests)).Equal(0)
+		})
+
+		g.It("returns an error when not configured", func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token"})
+			b := NewGcs(nil, "uuid", "")
+			b.SetOwner("", "server")
+			err := b.upload(context.Background(), bytes.NewReader([]byte("a")))
+			g.Assert(err).IsNotNil()
+		})
+	})
+}
@@ -4,10 +4,16 @@ import (
 	"context"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"emperror.dev/errors"
+	"github.com/goccy/go-json"
 	"github.com/mholt/archiver/v3"
 
+	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/remote"
 	"github.com/pterodactyl/wings/server/filesystem"
 )
@@ -30,12 +36,20 @@ func NewLocal(client remote.Client, uuid string, ignore string) *LocalBackup {
 }
 
 // LocateLocal finds the backup for a server and returns the local path. This
-// will obviously only work if the backup was created as a local backup.
+// will obviously only work if the backup was created as a local backup. If the
+// backup is stored as a snapshot the returned file info is for its directory.
 func LocateLocal(client remote.Client, uuid string) (*LocalBackup, os.FileInfo, error) {
 	b := NewLocal(client, uuid, "")
 	st, err := os.Stat(b.Path())
 	if err != nil {
-		return nil, nil, err
+		if !errors.Is(err, os.ErrNotExist) || !b.IsSnapshot() {
+			return nil, nil, err
+		}
+		st, err = os.Stat(b.SnapshotPath())
+		if err != nil {
+			return nil, nil, err
+		}
+		return b, st, nil
 	}
 
 	if st.IsDir() {
@@ -45,8 +59,30 @@ func LocateLocal(client remote.Client, uuid string) (*LocalBackup, os.FileInfo,
 	return b, st, nil
 }
 
+// exists returns true if the archive or snapshot for the backup exists.
+func (b *LocalBackup) exists() bool {
+	if _, err := os.Stat(b.Path()); err == nil {
+		return true
+	}
+	return b.IsSnapshot()
+}
+
 // Remove removes a backup from the system.
 func (b *LocalBackup) Remove() error {
+	for _, p := range []string{b.metadataPath(), b.ownerPath()} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if b.IsSnapshot() {
+		if err := os.RemoveAll(b.SnapshotPath()); err != nil {
+			return err
+		}
+		if err := os.Remove(b.Path()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
 	return os.Remove(b.Path())
 }
 
@@ -57,14 +93,39 @@ func (b *LocalBackup) WithLogContext(c map[string]interface{}) {
 
 // Generate generates a backup of the selected files and pushes it to the
 // defined location for this instance.
+//
+// If the server files can be cloned into the backup directory using block
+// cloning a snapshot of the files is created rather than an archive.
 func (b *LocalBackup) Generate(ctx context.Context, basePath, ignore string) (*ArchiveDetails, error) {
+	if useSnapshot(basePath) {
+		if err := b.generateSnapshot(ctx, basePath, ignore); err != nil {
+			return nil, err
+		}
+		// Remove any archive left over from a previous attempt at this backup so that
+		// it is not used instead of the snapshot.
+		if err := os.Remove(b.Path()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		ad, err := b.snapshotDetails(ctx)
+		if err != nil {
+			return nil, errors.WrapIf(err, "backup: failed to get snapshot details for local backup")
+		}
+		return ad, nil
+	}
+
+	// Remove any snapshot left over from a previous attempt at this backup so that
+	// it is not restored instead of the archive.
+	if err := os.RemoveAll(b.SnapshotPath()); err != nil {
+		return nil, err
+	}
+
 	a := &filesystem.Archive{
 		BasePath: basePath,
 		Ignore:   ignore,
 	}
 
 	b.log().WithField("path", b.Path()).Info("creating backup for server")
-	if err := a.Create(b.Path()); err != nil {
+	if err := a.Create(ctx, b.Path()); err != nil {
 		return nil, err
 	}
 	b.log().Info("created backup successfully")
@@ -76,9 +137,21 @@ func (b *LocalBackup) Generate(ctx context.Context, basePath, ignore string) (*A
 	return ad, nil
 }
 
+// Details returns the checksum and size of the archive or snapshot currently
+// stored on the disk.
+func (b *LocalBackup) Details(ctx context.Context) (*ArchiveDetails, error) {
+	if _, err := os.Stat(b.Path()); err != nil && b.IsSnapshot() {
+		return b.snapshotDetails(ctx)
+	}
+	return b.Backup.Details(ctx)
+}
+
 // Restore will walk over the archive and call the callback function for each
 // file encountered.
 func (b *LocalBackup) Restore(ctx context.Context, _ io.Reader, callback RestoreCallback) error {
+	if _, err := os.Stat(b.Path()); err != nil && b.IsSnapshot() {
+		return b.restoreSnapshot(ctx, callback)
+	}
 	return archiver.Walk(b.Path(), func(f archiver.File) error {
 		select {
 		case <-ctx.Done():
@@ -92,3 +165,100 @@ func (b *LocalBackup) Restore(ctx context.Context, _ io.Reader, callback Restore
 		}
 	})
 }
+
+// Verify checks the integrity of the backup stored on the disk, comparing it to
+// the expected checksum if one is provided.
+func (b *LocalBackup) Verify(ctx context.Context, checksum string) (*VerifyResult, error) {
+	if _, err := os.Stat(b.Path()); err != nil && b.IsSnapshot() {
+		return b.verifySnapshot(ctx, checksum)
+	}
+	f, err := os.Open(b.Path())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Verify(ctx, f, checksum)
+}
+
+// LocalMetadata describes a local backup that was created from the command line
+// rather than by the Panel. The Panel keeps track of the backups it creates, but
+// nothing else knows which server these backups belong to, so this is stored in
+// a file next to the backup.
+type LocalMetadata struct {
+	Uuid      string    `json:"uuid"`
+	Server    string    `json:"server"`
+	CreatedAt time.Time `json:"created_at"`
+	Checksum  string    `json:"checksum"`
+	Size      int64     `json:"size"`
+}
+
+func (b *LocalBackup) metadataPath() string {
+	return strings.TrimSuffix(b.Path(), ".tar.gz") + ".json"
+}
+
+// WriteMetadata stores the metadata for the backup next to it on the disk.
+func (b *LocalBackup) WriteMetadata(m LocalMetadata) error {
+	m.Uuid = b.Identifier()
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(b.metadataPath(), data, 0o600)
+}
+
+// ownerPath returns the path of the file that records the server the backup was
+// created for. This is kept separate to the metadata so that backups created by
+// the Panel are not listed as backups created from the command line.
+func (b *LocalBackup) ownerPath() string {
+	return strings.TrimSuffix(b.Path(), ".tar.gz") + ".owner"
+}
+
+// WriteOwner records the server that the backup was created for next to it on
+// the disk.
+func (b *LocalBackup) WriteOwner(server string) error {
+	return os.WriteFile(b.ownerPath(), []byte(server), 0o600)
+}
+
+// Owner returns the server that the backup was created for, or false if this
+// is not known, such as for backups created before the owner was recorded.
+func (b *LocalBackup) Owner() (string, bool) {
+	if data, err := os.ReadFile(b.ownerPath()); err == nil {
+		return strings.TrimSpace(string(data)), true
+	}
+	if data, err := os.ReadFile(b.metadataPath()); err == nil {
+		var m LocalMetadata
+		if err := json.Unmarshal(data, &m); err == nil && m.Server != "" {
+			return m.Server, true
+		}
+	}
+	return "", false
+}
+
+// ListLocalMetadata returns the metadata for every local backup of the server
+// that was created from the command line, with the newest backups first.
+func ListLocalMetadata(server string) ([]LocalMetadata, error) {
+	matches, err := filepath.Glob(filepath.Join(config.Get().System.BackupDirectory, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]LocalMetadata, 0)
+	for _, p := range matches {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var m LocalMetadata
+		if err := json.Unmarshal(data, &m); err != nil || m.Server != server {
+			continue
+		}
+		// Skip metadata left behind for a backup that was removed some other way.
+		if !NewLocal(nil, m.Uuid, "").exists() {
+			continue
+		}
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	return out, nil
+}
@@ -1,25 +1,22 @@
 package backup
 
 import (
-	"archive/tar"
-	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
 	"emperror.dev/errors"
 	"github.com/cenkalti/backoff/v4"
 
-	"github.com/pterodactyl/wings/server/filesystem"
-
-	"github.com/juju/ratelimit"
-
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/server/filesystem"
 )
 
 type S3Backup struct {
@@ -50,17 +47,22 @@ func (s *S3Backup) WithLogContext(c map[string]interface{}) {
 }
 
 // Generate creates a new backup on the disk, moves it into the S3 bucket via
-// the provided presigned URL, and then deletes the backup from the disk.
+// the provided presigned URL, and then deletes the backup from the disk. If
+// uploads are streamed only a single part is written to the disk at a time.
 func (s *S3Backup) Generate(ctx context.Context, basePath, ignore string) (*ArchiveDetails, error) {
-	defer s.Remove()
-
 	a := &filesystem.Archive{
 		BasePath: basePath,
 		Ignore:   ignore,
 	}
 
+	if config.Get().System.Backups.StreamUploads {
+		return s.generateStreamed(ctx, a)
+	}
+
+	defer s.Remove()
+
 	s.log().WithField("path", s.Path()).Info("creating backup for server")
-	if err := a.Create(s.Path()); err != nil {
+	if err := a.Create(ctx, s.Path()); err != nil {
 		return nil, err
 	}
 	s.log().Info("created backup successfully")
@@ -71,79 +73,154 @@ func (s *S3Backup) Generate(ctx context.Context, basePath, ignore string) (*Arch
 	}
 	defer rc.Close()
 
-	if err := s.generateRemoteRequest(ctx, rc); err != nil {
+	parts, err := s.generateRemoteRequest(ctx, rc)
+	if err != nil {
 		return nil, err
 	}
 	ad, err := s.Details(ctx)
 	if err != nil {
 		return nil, errors.WrapIf(err, "backup: failed to get archive details after upload")
 	}
+	ad.Parts = parts
 	return ad, nil
 }
 
-// Restore will read from the provided reader assuming that it is a gzipped
-// tar reader. When a file is encountered in the archive the callback function
-// will be triggered. If the callback returns an error the entire process is
-// stopped, otherwise this function will run until all files have been written.
+// generateStreamed uploads the archive as it is created. The Panel creates the
+// presigned URL for every part up front, which requires knowing the size of the
+// archive before it has been created. The size of the files being archived is
+// used instead since the compressed archive will not be larger than it, and any
+// parts that end up not being needed are not used.
 //
-// This restoration uses a workerpool to use up to the number of CPUs available
-// on the machine when writing files to the disk.
-func (s *S3Backup) Restore(ctx context.Context, r io.Reader, callback RestoreCallback) error {
-	reader := r
-	// Steal the logic we use for making backups which will be applied when restoring
-	// this specific backup. This allows us to prevent overloading the disk unintentionally.
-	if writeLimit := int64(config.Get().System.Backups.WriteLimit * 1024 * 1024); writeLimit > 0 {
-		reader = ratelimit.Reader(r, ratelimit.NewBucketWithRate(float64(writeLimit), writeLimit))
+// Every part must be sent with its length, so each part is written to the disk
+// before it is uploaded and then removed.
+func (s *S3Backup) generateStreamed(ctx context.Context, a *filesystem.Archive) (*ArchiveDetails, error) {
+	size, err := estimateArchiveSize(a.BasePath)
+	if err != nil {
+		return nil, errors.WrapIf(err, "backup: failed to estimate size of archive")
 	}
-	gr, err := gzip.NewReader(reader)
+
+	s.log().WithField("estimated_size", size).Debug("attempting to get S3 upload urls from Panel...")
+	urls, err := s.client.GetBackupRemoteUploadURLs(ctx, s.Backup.Uuid, size)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer gr.Close()
-	tr := tar.NewReader(gr)
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-			// Do nothing, fall through to the next block of code in this loop.
+	s.log().WithField("parts", len(urls.Parts)).Info("creating and uploading backup for server to s3 endpoint...")
+
+	var parts []remote.BackupPart
+	ad, err := streamArchive(ctx, a, func(ctx context.Context, r io.Reader) error {
+		for i, part := range urls.Parts {
+			f, n, err := s.spoolPart(r, urls.PartSize)
+			if err != nil {
+				return err
+			}
+			if n == 0 && i > 0 {
+				_ = f.Close()
+				_ = os.Remove(f.Name())
+				return nil
+			}
+			etag, err := newS3FileUploader(f).uploadPart(ctx, part, n)
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			if err != nil {
+				s.log().WithField("part_id", i+1).WithError(err).Warn("failed to upload part")
+				return err
+			}
+			parts = append(parts, remote.BackupPart{ETag: etag, PartNumber: i + 1})
+			s.log().WithField("part_id", i+1).Info("successfully uploaded backup part")
+			if n < urls.PartSize {
+				return nil
+			}
+		}
+		// Every part has been used, there should not be anything left to read.
+		if n, err := io.CopyN(io.Discard, r, 1); n > 0 || (err != nil && err != io.EOF) {
+			return errors.New("backup: archive is larger than the parts provided by the Panel")
 		}
-		header, err := tr.Next()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ad.Parts = parts
+
+	s.log().WithField("parts", len(parts)).Info("backup has been successfully uploaded")
+	return ad, nil
+}
+
+// spoolPart writes the next part of the archive to a temporary file, returning
+// the file seeked to its start along with the size of the part.
+func (s *S3Backup) spoolPart(r io.Reader, size int64) (*os.File, int64, error) {
+	f, err := os.CreateTemp(config.Get().System.BackupDirectory, s.Identifier()+".part-*")
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "backup: failed to create temporary part")
+	}
+	n, err := io.CopyN(f, r, size)
+	if err == nil || err == io.EOF {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return nil, 0, errors.Wrap(err, "backup: failed to write temporary part")
+	}
+	return f, n, nil
+}
+
+// estimateArchiveSize returns the largest size the archive of the directory
+// could be, which is the size of every file within it along with the space
+// used by the tar headers and the gzip framing.
+func estimateArchiveSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
 			return err
 		}
-		if header.Typeflag == tar.TypeReg {
-			if err := callback(header.Name, tr, header.FileInfo().Mode(), header.AccessTime, header.ModTime); err != nil {
-				return err
+		// Allow for both the header and an extended header for long names.
+		size += 1024
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return nil
 			}
+			// Files are padded to a multiple of the tar block size.
+			size += (info.Size() + 511) / 512 * 512
 		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	return size + size/100 + 1024*1024, nil
+}
+
+// Restore will read from the provided reader assuming that it is a gzipped
+// tar reader. When a file is encountered in the archive the callback function
+// will be triggered. If the callback returns an error the entire process is
+// stopped, otherwise this function will run until all files have been written.
+func (s *S3Backup) Restore(ctx context.Context, r io.Reader, callback RestoreCallback) error {
+	return restoreArchive(ctx, r, callback)
 }
 
 // Generates the remote S3 request and begins the upload.
-func (s *S3Backup) generateRemoteRequest(ctx context.Context, rc io.ReadCloser) error {
+func (s *S3Backup) generateRemoteRequest(ctx context.Context, rc io.ReadCloser) ([]remote.BackupPart, error) {
 	defer rc.Close()
 
 	s.log().Debug("attempting to get size of backup...")
 	size, err := s.Backup.Size()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	s.log().WithField("size", size).Debug("got size of backup")
 
 	s.log().Debug("attempting to get S3 upload urls from Panel...")
 	urls, err := s.client.GetBackupRemoteUploadURLs(context.Background(), s.Backup.Uuid, size)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	s.log().Debug("got S3 upload urls from the Panel")
 	s.log().WithField("parts", len(urls.Parts)).Info("attempting to upload backup to s3 endpoint...")
 
 	uploader := newS3FileUploader(rc)
+	parts := make([]remote.BackupPart, 0, len(urls.Parts))
 	for i, part := range urls.Parts {
 		// Get the size for the current part.
 		var partSize int64
@@ -156,17 +233,19 @@ func (s *S3Backup) generateRemoteRequest(ctx context.Context, rc io.ReadCloser)
 		}
 
 		// Attempt to upload the part.
-		if _, err := uploader.uploadPart(ctx, part, partSize); err != nil {
+		etag, err := uploader.uploadPart(ctx, part, partSize)
+		if err != nil {
 			s.log().WithField("part_id", i+1).WithError(err).Warn("failed to upload part")
-			return err
+			return nil, err
 		}
+		parts = append(parts, remote.BackupPart{ETag: etag, PartNumber: i + 1})
 
 		s.log().WithField("part_id", i+1).Info("successfully uploaded backup part")
 	}
 
 	s.log().WithField("parts", len(urls.Parts)).Info("backup has been successfully uploaded")
 
-	return nil
+	return parts, nil
 }
 
 type s3FileUploader struct {
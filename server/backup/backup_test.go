@@ -0,0 +1,182 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/server/filesystem"
+)
+
+func TestStreamArchive(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("streamArchive", func() {
+		var dir string
+
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token"})
+			dir, _ = os.MkdirTemp(os.TempDir(), "pterodactyl-backup")
+			b := make([]byte, 256*1024)
+			_, _ = rand.Read(b)
+			_ = os.WriteFile(filepath.Join(dir, "random.bin"), b, 0o644)
+			_ = os.WriteFile(filepath.Join(dir, "server.properties"), []byte("motd=hello"), 0o644)
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		g.It("returns the details of the archive that was uploaded", func() {
+			var uploaded bytes.Buffer
+			ad, err := streamArchive(context.Background(), &filesystem.Archive{BasePath: dir}, func(_ context.Context, r io.Reader) error {
+				_, err := io.Copy(&uploaded, r)
+				return err
+			})
+			g.Assert(err).IsNil()
+
+			sum := sha1.Sum(uploaded.Bytes())
+			g.Assert(ad.Checksum).Equal(hex.EncodeToString(sum[:]))
+			g.Assert(ad.Size).Equal(int64(uploaded.Len()))
+
+			res, err := Verify(context.Background(), &uploaded, ad.Checksum)
+			g.Assert(err).IsNil()
+			g.Assert(res.Valid).IsTrue()
+			g.Assert(res.Files).Equal(2)
+
+			estimate, err := estimateArchiveSize(dir)
+			g.Assert(err).IsNil()
+			g.Assert(estimate >= ad.Size).IsTrue()
+		})
+
+		g.It("returns the error from the upload", func() {
+			_, err := streamArchive(context.Background(), &filesystem.Archive{BasePath: dir}, func(_ context.Context, r io.Reader) error {
+				_, _ = io.CopyN(io.Discard, r, 10)
+				return errors.New("upload failed")
+			})
+			g.Assert(err).IsNotNil()
+			g.Assert(err.Error()).Equal("upload failed")
+		})
+	})
+}
+
+func TestLocalMetadata(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("ListLocalMetadata", func() {
+		var dir string
+
+		g.BeforeEach(func() {
+			dir, _ = os.MkdirTemp(os.TempDir(), "pterodactyl-backup")
+			config.Set(&config.Configuration{
+				AuthenticationToken: "token",
+				System:              config.SystemConfiguration{BackupDirectory: dir},
+			})
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		create := func(id string, server string, at time.Time) *LocalBackup {
+			b := NewLocal(nil, id, "")
+			_ = os.WriteFile(b.Path(), []byte("archive"), 0o600)
+			g.Assert(b.WriteMetadata(LocalMetadata{Server: server, CreatedAt: at})).IsNil()
+			return b
+		}
+
+		g.It("lists the backups of the server with the newest first", func() {
+			now := time.Now()
+			create("a", "server-1", now.Add(-time.Hour))
+			create("b", "server-1", now)
+			create("c", "server-2", now)
+
+			backups, err := ListLocalMetadata("server-1")
+			g.Assert(err).IsNil()
+			g.Assert(len(backups)).Equal(2)
+			g.Assert(backups[0].Uuid).Equal("b")
+			g.Assert(backups[1].Uuid).Equal("a")
+		})
+
+		g.It("does not list removed backups", func() {
+			b := create("a", "server-1", time.Now())
+			g.Assert(b.Remove()).IsNil()
+
+			_, err := os.Stat(filepath.Join(dir, "a.json"))
+			g.Assert(errors.Is(err, os.ErrNotExist)).IsTrue()
+			backups, err := ListLocalMetadata("server-1")
+			g.Assert(err).IsNil()
+			g.Assert(len(backups)).Equal(0)
+		})
+
+		g.It("does not list backups created by the Panel", func() {
+			b := NewLocal(nil, "a", "")
+			_ = os.WriteFile(b.Path(), []byte("archive"), 0o600)
+			g.Assert(b.WriteOwner("server-1")).IsNil()
+
+			backups, err := ListLocalMetadata("server-1")
+			g.Assert(err).IsNil()
+			g.Assert(len(backups)).Equal(0)
+		})
+	})
+
+	g.Describe("Owner", func() {
+		var dir string
+
+		g.BeforeEach(func() {
+			dir, _ = os.MkdirTemp(os.TempDir(), "pterodactyl-backup")
+			config.Set(&config.Configuration{
+				AuthenticationToken: "token",
+				System:              config.SystemConfiguration{BackupDirectory: dir},
+			})
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		g.It("returns the server the backup was created for", func() {
+			b := NewLocal(nil, "a", "")
+			g.Assert(b.WriteOwner("server-1")).IsNil()
+
+			owner, ok := b.Owner()
+			g.Assert(ok).IsTrue()
+			g.Assert(owner).Equal("server-1")
+		})
+
+		g.It("uses the server in the metadata of backups created from the command line", func() {
+			b := NewLocal(nil, "a", "")
+			g.Assert(b.WriteMetadata(LocalMetadata{Server: "server-2"})).IsNil()
+
+			owner, ok := b.Owner()
+			g.Assert(ok).IsTrue()
+			g.Assert(owner).Equal("server-2")
+		})
+
+		g.It("returns false when the owner is not known", func() {
+			_, ok := NewLocal(nil, "a", "").Owner()
+			g.Assert(ok).IsFalse()
+		})
+
+		g.It("removes the owner along with the backup", func() {
+			b := NewLocal(nil, "a", "")
+			_ = os.WriteFile(b.Path(), []byte("archive"), 0o600)
+			g.Assert(b.WriteOwner("server-1")).IsNil()
+			g.Assert(b.Remove()).IsNil()
+
+			_, ok := b.Owner()
+			g.Assert(ok).IsFalse()
+		})
+	})
+}
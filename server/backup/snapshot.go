@@ -0,0 +1,206 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/server/filesystem"
+)
+
+// Local backups are stored as a snapshot rather than an archive when the server
+// files can be cloned into the backup directory using copy-on-write, such as on
+// ReFS volumes or XFS filesystems with reflinks. A snapshot is a directory with
+// a copy of the server files, which takes almost no time or extra disk space to
+// create, and can be cloned straight back into the server when restoring.
+
+// SnapshotPath returns the path to the directory holding the snapshot for this
+// backup.
+func (b *LocalBackup) SnapshotPath() string {
+	return filepath.Join(config.Get().System.BackupDirectory, b.Identifier()+".snapshot")
+}
+
+// IsSnapshot returns true if the backup is stored as a snapshot of the server
+// files rather than as an archive.
+func (b *LocalBackup) IsSnapshot() bool {
+	st, err := os.Stat(b.SnapshotPath())
+	return err == nil && st.IsDir()
+}
+
+// useSnapshot returns true if a snapshot should be created for the files in the
+// base path rather than an archive.
+func useSnapshot(basePath string) bool {
+	return config.Get().System.Backups.BlockCloning && filesystem.SupportsCloning(basePath, config.Get().System.BackupDirectory)
+}
+
+// generateSnapshot clones the files in the base path into a temporary directory
+// that replaces the snapshot for the backup once every file has been cloned, so
+// that an incomplete snapshot is never used.
+func (b *LocalBackup) generateSnapshot(ctx context.Context, basePath, ignore string) error {
+	dst := b.SnapshotPath()
+	tmp := dst + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	b.log().WithField("path", dst).Info("creating block cloned snapshot for server")
+	start := time.Now()
+	cow, err := filesystem.CloneDirectory(ctx, basePath, tmp, strings.Split(ignore, "\n"))
+	if err != nil {
+		_ = os.RemoveAll(tmp)
+		return errors.WrapIf(err, "backup: failed to clone server files")
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		_ = os.RemoveAll(tmp)
+		return errors.WithStackIf(err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		_ = os.RemoveAll(tmp)
+		return errors.WithStackIf(err)
+	}
+	b.log().WithFields(log.Fields{
+		"copy_on_write":  cow,
+		"execution_time": time.Since(start),
+	}).Info("created snapshot successfully")
+	return nil
+}
+
+// snapshotFile is a regular file within a snapshot.
+type snapshotFile struct {
+	path string
+	name string
+	info fs.FileInfo
+}
+
+// walkSnapshot calls fn for every regular file in the snapshot directory. The
+// name passed to fn is relative to the directory and always uses forward slashes.
+func walkSnapshot(ctx context.Context, dir string, fn func(f snapshotFile) error) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		return fn(snapshotFile{path: p, name: filepath.ToSlash(rel), info: info})
+	})
+}
+
+// snapshotChecksumType is reported to the Panel as the checksum type of snapshot
+// backups. The checksum is of the manifest of files in the snapshot rather than
+// their contents, so it is named differently to the "sha1" of archive backups.
+const snapshotChecksumType = "sha1-manifest"
+
+// snapshotManifest returns the manifest checksum and total size of the files in
+// the snapshot directory. Reading every file would defeat the point of a
+// snapshot, so the checksum only covers the name, size and modification time of
+// each file, and does not detect changes to their contents.
+func snapshotManifest(ctx context.Context, dir string) (string, int64, error) {
+	var size int64
+	var lines []string
+	err := walkSnapshot(ctx, dir, func(f snapshotFile) error {
+		size += f.info.Size()
+		lines = append(lines, f.name+"\t"+strconv.FormatInt(f.info.Size(), 10)+"\t"+strconv.FormatInt(f.info.ModTime().UnixNano(), 10))
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return manifestChecksum(lines), size, nil
+}
+
+// manifestChecksum returns the SHA1 checksum of the manifest lines, sorted so
+// that the checksum does not depend on the order the files were found in.
+func manifestChecksum(lines []string) string {
+	sort.Strings(lines)
+	h := sha1.New()
+	for _, l := range lines {
+		_, _ = io.WriteString(h, l+"\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// snapshotDetails returns the manifest checksum and size of the snapshot.
+func (b *LocalBackup) snapshotDetails(ctx context.Context) (*ArchiveDetails, error) {
+	checksum, size, err := snapshotManifest(ctx, b.SnapshotPath())
+	if err != nil {
+		return nil, errors.WrapIf(err, "backup: failed to read snapshot")
+	}
+	return &ArchiveDetails{Checksum: checksum, ChecksumType: snapshotChecksumType, Size: size}, nil
+}
+
+// restoreSnapshot calls the callback for every file in the snapshot.
+func (b *LocalBackup) restoreSnapshot(ctx context.Context, callback RestoreCallback) error {
+	return walkSnapshot(ctx, b.SnapshotPath(), func(sf snapshotFile) error {
+		f, err := os.Open(sf.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return callback(sf.name, f, sf.info.Mode(), sf.info.ModTime(), sf.info.ModTime())
+	})
+}
+
+// verifySnapshot reads every file in the snapshot, and compares the checksum of
+// the snapshot to the expected checksum if one is provided.
+func (b *LocalBackup) verifySnapshot(ctx context.Context, checksum string) (*VerifyResult, error) {
+	dir := b.SnapshotPath()
+	res := &VerifyResult{ChecksumType: snapshotChecksumType, Errors: []VerifyError{}}
+	err := walkSnapshot(ctx, dir, func(sf snapshotFile) error {
+		res.Files++
+		f, err := os.Open(sf.path)
+		if err != nil {
+			res.Errors = append(res.Errors, VerifyError{File: sf.name, Error: err.Error()})
+			return nil
+		}
+		defer f.Close()
+		if _, err := io.Copy(io.Discard, f); err != nil {
+			res.Errors = append(res.Errors, VerifyError{File: sf.name, Error: err.Error()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res.Checksum, res.Size, err = snapshotManifest(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	if checksum != "" {
+		matches := strings.EqualFold(checksum, res.Checksum)
+		res.ChecksumMatches = &matches
+	}
+	res.Valid = len(res.Errors) == 0 && (res.ChecksumMatches == nil || *res.ChecksumMatches)
+	return res, nil
+}
+
+// CloneTo clones the files in the snapshot into the root of the filesystem,
+// skipping any files that the server is not allowed to write. The returned
+// boolean indicates if every file was cloned using copy-on-write.
+func (b *LocalBackup) CloneTo(ctx context.Context, dst *filesystem.Filesystem) (bool, error) {
+	return dst.CloneFrom(ctx, b.SnapshotPath())
+}
@@ -0,0 +1,63 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+)
+
+func TestSnapshotManifest(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("snapshotManifest", func() {
+		var dir string
+
+		g.BeforeEach(func() {
+			dir, _ = os.MkdirTemp(os.TempDir(), "pterodactyl-snapshot")
+			_ = os.MkdirAll(filepath.Join(dir, "world"), 0o755)
+			_ = os.WriteFile(filepath.Join(dir, "server.properties"), []byte("motd=hello"), 0o644)
+			_ = os.WriteFile(filepath.Join(dir, "world", "level.dat"), []byte("level"), 0o644)
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		g.It("returns the total size of the files", func() {
+			_, size, err := snapshotManifest(context.Background(), dir)
+			g.Assert(err).IsNil()
+			g.Assert(size).Equal(int64(15))
+		})
+
+		g.It("returns the same checksum for the same files", func() {
+			a, _, err := snapshotManifest(context.Background(), dir)
+			g.Assert(err).IsNil()
+			b, _, err := snapshotManifest(context.Background(), dir)
+			g.Assert(err).IsNil()
+			g.Assert(a).Equal(b)
+		})
+
+		g.It("returns a different checksum when a file is changed", func() {
+			a, _, err := snapshotManifest(context.Background(), dir)
+			g.Assert(err).IsNil()
+
+			mtime := time.Now().Add(time.Hour)
+			_ = os.Chtimes(filepath.Join(dir, "world", "level.dat"), mtime, mtime)
+			b, _, err := snapshotManifest(context.Background(), dir)
+			g.Assert(err).IsNil()
+			g.Assert(a == b).IsFalse()
+		})
+	})
+
+	g.Describe("manifestChecksum", func() {
+		g.It("does not depend on the order of the lines", func() {
+			a := manifestChecksum([]string{"a\t1\t1", "b\t2\t2"})
+			b := manifestChecksum([]string{"b\t2\t2", "a\t1\t1"})
+			g.Assert(a).Equal(b)
+		})
+	})
+}
@@ -0,0 +1,129 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/cenkalti/backoff/v4"
+)
+
+// The size of each chunk uploaded to Azure and Google Cloud Storage. Google
+// requires every chunk other than the last to be a multiple of 256KiB, and
+// Azure allows at most 50,000 blocks per blob which allows for backups up to
+// roughly 780GiB.
+const chunkSize = 16 * 1024 * 1024
+
+// Each request only sends a single chunk, so the timeout can be much lower than
+// the one used for S3 where an entire part is sent at once.
+var uploadClient = &http.Client{Timeout: time.Minute * 10}
+
+// uploadChunks reads the archive in chunks and calls upload for each of them.
+// The last chunk may be empty if the size of the archive is an exact multiple
+// of the chunk size. The total number of bytes uploaded is returned.
+func uploadChunks(ctx context.Context, r io.Reader, upload func(ctx context.Context, chunk []byte, offset int64, last bool) error) (int64, error) {
+	buf := make([]byte, chunkSize)
+	var offset int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return offset, errors.Wrap(err, "backup: failed to read archive")
+		}
+		if err := upload(ctx, buf[:n], offset, last); err != nil {
+			return offset, err
+		}
+		offset += int64(n)
+		if last {
+			return offset, nil
+		}
+	}
+}
+
+// sendWithRetry sends the request returned by newRequest, retrying with an
+// exponential backoff if the request fails or the remote responds with a 5xx
+// or 429 error. The request is created again for every attempt so that its
+// body can be read again. The body of the response is always closed before
+// it is returned.
+func sendWithRetry(ctx context.Context, newRequest func() (*http.Request, error), accept func(status int) bool) (*http.Response, error) {
+	b := backoff.NewExponentialBackOff()
+	b.Multiplier = 2
+	b.MaxElapsedTime = time.Minute
+
+	var res *http.Response
+	err := backoff.Retry(func() error {
+		req, err := newRequest()
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		res, err = uploadClient.Do(req)
+		if err != nil {
+			err = redact(err)
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				return backoff.Permanent(err)
+			}
+			return errors.Wrap(err, "backup: HTTP request failed")
+		}
+		_ = res.Body.Close()
+		if accept(res.StatusCode) {
+			return nil
+		}
+		err = statusError(req, res)
+		if res.StatusCode >= http.StatusInternalServerError || res.StatusCode == http.StatusTooManyRequests {
+			return err
+		}
+		return backoff.Permanent(err)
+	}, backoff.WithContext(b, ctx))
+	if err != nil {
+		if v, ok := err.(*backoff.PermanentError); ok {
+			return nil, v.Unwrap()
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// openRemote sends a GET request for an archive stored remotely and returns
+// the body of the response. If the archive does not exist an error wrapping
+// os.ErrNotExist is returned.
+func openRemote(req *http.Request) (io.ReadCloser, error) {
+	// There is no timeout on this client since the archive is streamed directly
+	// to the server's data directory, which could take a long time.
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(redact(err), "backup: failed to download archive")
+	}
+	if res.StatusCode != http.StatusOK {
+		_ = res.Body.Close()
+		return nil, statusError(req, res)
+	}
+	return res.Body, nil
+}
+
+// statusError returns an error for an unexpected response from the remote
+// storage, wrapping os.ErrNotExist if the archive does not exist.
+func statusError(req *http.Request, res *http.Response) error {
+	err := errors.New(fmt.Sprintf("backup: unexpected response from %s: [HTTP/%d] %s", req.URL.Host, res.StatusCode, res.Status))
+	if res.StatusCode == http.StatusNotFound {
+		return errors.WrapIf(os.ErrNotExist, err.Error())
+	}
+	return err
+}
+
+// redact removes the query from the URL included in a request error, since the
+// query of an Azure URL contains the SAS token used to access the container.
+func redact(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		if u, perr := url.Parse(uerr.URL); perr == nil {
+			u.RawQuery = ""
+			uerr.URL = u.String()
+		}
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+// VerifyResult is the outcome of verifying the integrity of a backup archive.
+type VerifyResult struct {
+	// Valid is true if every file in the archive could be read and the checksum
+	// matched the expected checksum, if one was provided.
+	Valid        bool   `json:"valid"`
+	Checksum     string `json:"checksum"`
+	ChecksumType string `json:"checksum_type"`
+	// ChecksumMatches is nil when there was no expected checksum to compare the
+	// archive against.
+	ChecksumMatches *bool         `json:"checksum_matches"`
+	Size            int64         `json:"size"`
+	Files           int           `json:"files"`
+	Errors          []VerifyError `json:"errors"`
+}
+
+// VerifyError is a problem found with a single file in the archive. The file is
+// empty if the problem is with the archive itself.
+type VerifyError struct {
+	File  string `json:"file,omitempty"`
+	Error string `json:"error"`
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Verify reads through the entire archive without extracting it, checking that
+// every file within it can be read and that the checksum of the archive matches
+// the expected checksum. An error is only returned if the archive could not be
+// read at all, any problems with the archive are returned in the result.
+func Verify(ctx context.Context, r io.Reader, checksum string) (*VerifyResult, error) {
+	h := sha1.New()
+	cr := &countingReader{r: io.TeeReader(r, h)}
+	res := &VerifyResult{ChecksumType: "sha1", Errors: []VerifyError{}}
+
+	if err := verifyArchive(ctx, cr, res); err != nil {
+		return nil, err
+	}
+	// Read anything left over so that the checksum covers the entire archive even
+	// if it stopped being readable part of the way through.
+	if _, err := io.Copy(io.Discard, cr); err != nil {
+		return nil, err
+	}
+
+	res.Size = cr.n
+	res.Checksum = hex.EncodeToString(h.Sum(nil))
+	if checksum != "" {
+		matches := strings.EqualFold(checksum, res.Checksum)
+		res.ChecksumMatches = &matches
+	}
+	res.Valid = len(res.Errors) == 0 && (res.ChecksumMatches == nil || *res.ChecksumMatches)
+	return res, nil
+}
+
+// verifyArchive reads every file in the gzipped tarball. Once an error is found
+// the rest of the archive cannot be read, so it is recorded and the remainder
+// of the archive is skipped.
+func verifyArchive(ctx context.Context, r io.Reader, res *VerifyResult) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		res.Errors = append(res.Errors, VerifyError{Error: "archive is not a valid gzip file: " + err.Error()})
+		return nil
+	}
+	defer gz.Close()
+
+	var last string
+	tr := tar.NewReader(gz)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			res.Errors = append(res.Errors, VerifyError{File: last, Error: "unable to read the next file in the archive: " + err.Error()})
+			return nil
+		}
+		last = header.Name
+		res.Files++
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			res.Errors = append(res.Errors, VerifyError{File: header.Name, Error: err.Error()})
+			return nil
+		}
+	}
+
+	// The gzip checksum is only checked once the end of the stream is reached,
+	// which may be after the end of the tarball.
+	if _, err := io.Copy(io.Discard, gz); err != nil {
+		res.Errors = append(res.Errors, VerifyError{Error: "archive is corrupt: " + err.Error()})
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestVerify(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	archive := func() []byte {
+		var b bytes.Buffer
+		gw := gzip.NewWriter(&b)
+		tw := tar.NewWriter(gw)
+		for _, name := range []string{"a.txt", "b.txt"} {
+			body := bytes.Repeat([]byte(name), 4096)
+			_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body))})
+			_, _ = tw.Write(body)
+		}
+		_ = tw.Close()
+		_ = gw.Close()
+		return b.Bytes()
+	}
+
+	g.Describe("Verify", func() {
+		g.It("reads every file in a valid archive", func() {
+			b := archive()
+			res, err := Verify(context.Background(), bytes.NewReader(b), "")
+			g.Assert(err).IsNil()
+			g.Assert(res.Valid).IsTrue()
+			g.Assert(res.Files).Equal(2)
+			g.Assert(res.Size).Equal(int64(len(b)))
+			g.Assert(res.ChecksumMatches == nil).IsTrue()
+
+			again, err := Verify(context.Background(), bytes.NewReader(b), res.Checksum)
+			g.Assert(err).IsNil()
+			g.Assert(*again.ChecksumMatches).IsTrue()
+		})
+
+		g.It("detects a checksum that does not match", func() {
+			res, err := Verify(context.Background(), bytes.NewReader(archive()), "da39a3ee5e6b4b0d3255bfef95601890afd80709")
+			g.Assert(err).IsNil()
+			g.Assert(*res.ChecksumMatches).IsFalse()
+			g.Assert(res.Valid).IsFalse()
+		})
+
+		g.It("reports a truncated archive", func() {
+			b := archive()
+			res, err := Verify(context.Background(), bytes.NewReader(b[:len(b)/2]), "")
+			g.Assert(err).IsNil()
+			g.Assert(res.Valid).IsFalse()
+			g.Assert(len(res.Errors)).Equal(1)
+		})
+
+		g.It("reports a file that is not an archive", func() {
+			res, err := Verify(context.Background(), bytes.NewReader([]byte("not an archive")), "")
+			g.Assert(err).IsNil()
+			g.Assert(res.Valid).IsFalse()
+			g.Assert(res.Errors[0].File).Equal("")
+		})
+	})
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/server/backup"
+)
+
+// backups limits the number of backups that are generated at the same time
+// across every server on the node.
+var backups = &backupQueue{wake: make(chan struct{}), pending: make(map[string]int)}
+
+type backupQueue struct {
+	mu      sync.Mutex
+	running int
+	// pending is the number of backups either running or waiting for a slot for
+	// each server.
+	pending map[string]int
+	// wake is closed and replaced whenever a backup completes so that every
+	// backup waiting for a slot checks again.
+	wake chan struct{}
+}
+
+// acquire blocks until there are fewer than the configured maximum number of
+// backups running, returning a function that must be called once the backup
+// for the server has completed. The limit is read every time so that changes
+// made to the configuration apply without restarting.
+func (q *backupQueue) acquire(ctx context.Context, id string) (func(), error) {
+	q.mu.Lock()
+	q.pending[id]++
+	q.mu.Unlock()
+	for {
+		q.mu.Lock()
+		if limit := config.Get().System.Backups.MaxConcurrent; limit <= 0 || q.running < limit {
+			q.running++
+			q.mu.Unlock()
+			return func() { q.release(id) }, nil
+		}
+		wake := q.wake
+		q.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			q.mu.Lock()
+			q.done(id)
+			q.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (q *backupQueue) release(id string) {
+	q.mu.Lock()
+	q.running--
+	q.done(id)
+	close(q.wake)
+	q.wake = make(chan struct{})
+	q.mu.Unlock()
+}
+
+// done removes a backup for the server from the pending count, the lock must
+// be held by the caller.
+func (q *backupQueue) done(id string) {
+	if q.pending[id]--; q.pending[id] <= 0 {
+		delete(q.pending, id)
+	}
+}
+
+// count returns the number of backups for the server that are either running
+// or waiting for other backups to complete.
+func (q *backupQueue) count(id string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending[id]
+}
+
+// WaitForBackupWindow blocks until the configured backup window is open, which
+// is immediately if there is no window configured. This should be called before
+// generating any backup requested by the Panel, but not for backups created by
+// Wings itself, such as the backup made before a server is reinstalled. If the
+// backup is canceled while it is waiting the Panel is told that it failed.
+func (s *Server) WaitForBackupWindow(uuid string) error {
+	d := config.Get().System.Backups.Window.Until(time.Now())
+	if d <= 0 {
+		return nil
+	}
+	s.Log().WithField("backup", uuid).WithField("delay", d.Round(time.Second).String()).Info("deferring backup until the backup window opens")
+	if err := addDeferredBackup(uuid, s.ID()); err != nil {
+		s.Log().WithField("backup", uuid).WithField("error", err).Warn("failed to record deferred backup")
+	}
+	defer func() {
+		if err := removeDeferredBackup(uuid); err != nil {
+			s.Log().WithField("backup", uuid).WithField("error", err).Warn("failed to remove deferred backup record")
+		}
+	}()
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-s.Context().Done():
+		// The context for the server is already canceled so it cannot be used to
+		// notify the Panel.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+		defer cancel()
+		if err := s.client.SetBackupStatus(ctx, uuid, (&backup.ArchiveDetails{}).ToRequest(false)); err != nil {
+			s.Log().WithField("backup", uuid).WithField("error", err).Warn("failed to notify panel of canceled backup")
+		}
+		return s.Context().Err()
+	}
+}
+
+// Tracks the backups that are waiting for the backup window to open, and the
+// server that each belongs to. These are persisted to the disk so that backups
+// lost when Wings is restarted can be reported to the Panel as failed, rather
+// than being left as in progress forever.
+var deferredBackups struct {
+	sync.Mutex
+	ids map[string]string
+}
+
+func deferredBackupsPath() string {
+	return filepath.Join(config.Get().System.RootDirectory, "deferred_backups.json")
+}
+
+// loadDeferredBackups reads the deferred backups from the disk if they have not
+// already been loaded. The lock must be held when calling this function.
+func loadDeferredBackups() error {
+	if deferredBackups.ids != nil {
+		return nil
+	}
+	deferredBackups.ids = make(map[string]string)
+	b, err := os.ReadFile(deferredBackupsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "server: failed to read deferred backups")
+	}
+	if err := json.Unmarshal(b, &deferredBackups.ids); err != nil {
+		return errors.Wrap(err, "server: failed to parse deferred backups")
+	}
+	return nil
+}
+
+// saveDeferredBackups writes the deferred backups to the disk. The lock must be
+// held when calling this function.
+func saveDeferredBackups() error {
+	b, err := json.Marshal(deferredBackups.ids)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.WriteFile(deferredBackupsPath(), b, 0o600); err != nil {
+		return errors.Wrap(err, "server: failed to write deferred backups")
+	}
+	return nil
+}
+
+func addDeferredBackup(uuid string, server string) error {
+	deferredBackups.Lock()
+	defer deferredBackups.Unlock()
+	if err := loadDeferredBackups(); err != nil {
+		return err
+	}
+	deferredBackups.ids[uuid] = server
+	return saveDeferredBackups()
+}
+
+func removeDeferredBackup(uuid string) error {
+	deferredBackups.Lock()
+	defer deferredBackups.Unlock()
+	if err := loadDeferredBackups(); err != nil {
+		return err
+	}
+	if _, ok := deferredBackups.ids[uuid]; !ok {
+		return nil
+	}
+	delete(deferredBackups.ids, uuid)
+	return saveDeferredBackups()
+}
+
+// FailLostBackups tells the Panel that any backups which were waiting for the
+// backup window when Wings last stopped have failed, since they will never be
+// generated. Backups for servers that are not loaded are kept so that they are
+// reported once the server is.
+func (m *Manager) FailLostBackups(ctx context.Context) error {
+	deferredBackups.Lock()
+	defer deferredBackups.Unlock()
+	if err := loadDeferredBackups(); err != nil {
+		return err
+	}
+	if len(deferredBackups.ids) == 0 {
+		return nil
+	}
+	for uuid, id := range deferredBackups.ids {
+		s, ok := m.Get(id)
+		if !ok {
+			continue
+		}
+		if err := s.client.SetBackupStatus(ctx, uuid, (&backup.ArchiveDetails{}).ToRequest(false)); err != nil {
+			s.Log().WithField("backup", uuid).WithField("error", err).Warn("failed to notify panel of backup lost while waiting for the backup window")
+			continue
+		}
+		s.Log().WithField("backup", uuid).Info("backup waiting for the backup window was lost when wings stopped, marked as failed")
+		delete(deferredBackups.ids, uuid)
+	}
+	return saveDeferredBackups()
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestDeferredBackups(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("deferredBackups", func() {
+		g.BeforeEach(func() {
+			dir, err := os.MkdirTemp("", "wings-deferred")
+			g.Assert(err).IsNil()
+			c := &config.Configuration{AuthenticationToken: "test"}
+			c.System.RootDirectory = dir
+			config.Set(c)
+			deferredBackups.ids = nil
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(config.Get().System.RootDirectory)
+		})
+
+		g.It("persists deferred backups between loads", func() {
+			g.Assert(addDeferredBackup("backup-a", "server-a")).IsNil()
+			g.Assert(addDeferredBackup("backup-b", "server-b")).IsNil()
+			g.Assert(removeDeferredBackup("backup-a")).IsNil()
+			deferredBackups.ids = nil
+
+			g.Assert(loadDeferredBackups()).IsNil()
+			g.Assert(deferredBackups.ids).Equal(map[string]string{"backup-b": "server-b"})
+		})
+
+		g.It("keeps lost backups for servers that are not loaded", func() {
+			g.Assert(addDeferredBackup("backup-a", "server-a")).IsNil()
+			deferredBackups.ids = nil
+
+			g.Assert(NewEmptyManager(nil).FailLostBackups(context.Background())).IsNil()
+			g.Assert(deferredBackups.ids).Equal(map[string]string{"backup-a": "server-a"})
+		})
+	})
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+// CloneFrom copies the files from another server on the node into this server,
+// skipping any paths that match the ignore patterns, and then notifies the Panel
+// that the server has been installed. This allows servers to be provisioned from
+// a template server without going through a backup. The server is marked as
+// installing until the files have been copied so that it cannot be started.
+func (s *Server) CloneFrom(src *Server, ignored []string) error {
+	s.Events().Publish(InstallStartedEvent, "")
+
+	err := s.whileInstalling(func() error {
+		return s.cloneFrom(src, ignored)
+	})
+
+	s.Log().WithField("was_successful", err == nil).Debug("notifying panel of server install state")
+	if serr := s.SyncInstallState(err == nil); serr != nil {
+		s.Log().WithField("error", serr).Warn("failed to notify panel of server install state")
+	}
+
+	s.Environment.SetState(environment.ProcessOfflineState)
+	s.Events().Publish(InstallCompletedEvent, "")
+
+	return err
+}
+
+func (s *Server) cloneFrom(src *Server, ignored []string) error {
+	if err := s.PrepareDataDirectory(); err != nil {
+		return err
+	}
+
+	s.Log().WithField("source", src.ID()).Info("cloning files from source server")
+	start := time.Now()
+	cow, err := src.Filesystem().CloneTo(s.Context(), s.Filesystem(), ignored)
+	if err != nil {
+		return errors.WrapIf(err, "server: failed to clone files from source server")
+	}
+	s.Log().WithFields(log.Fields{
+		"source":         src.ID(),
+		"copy_on_write":  cow,
+		"execution_time": time.Since(start),
+	}).Info("completed cloning files from source server")
+
+	// Update the disk usage for the server now that the files have been copied in.
+	s.Filesystem().HasSpaceAvailable(false)
+
+	return nil
+}
@@ -0,0 +1,291 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+// The number of commands kept in the history of each server.
+const commandHistorySize = 100
+
+// The limits for the commands run by a macro.
+const (
+	maxMacroCommands = 25
+	maxMacroDelay    = 10000
+)
+
+var ErrMacroNotFound = errors.Sentinel("command macro not found")
+
+// The names that can be used for a command macro.
+var macroNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// The places that a command can be sent to a server from.
+const (
+	CommandSourceApi       = "api"
+	CommandSourceWebsocket = "websocket"
+	CommandSourceMacro     = "macro"
+)
+
+// CommandOrigin identifies who sent a command to a server, and how.
+type CommandOrigin struct {
+	Source string
+	// The user or system that sent the command, as given by the Panel. This is
+	// the ID of the user for commands sent over the websocket.
+	Actor string
+	// The name of the macro that the command was sent by.
+	Macro string
+}
+
+// CommandRecord is a command that was sent to a server.
+type CommandRecord struct {
+	Command string    `json:"command"`
+	Source  string    `json:"source"`
+	Actor   string    `json:"actor,omitempty"`
+	Macro   string    `json:"macro,omitempty"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// commandHistories holds the recent commands sent to each server, keyed by the
+// ID of the server. The history is only kept in memory.
+var commandHistories = struct {
+	sync.Mutex
+	records map[string][]CommandRecord
+}{records: make(map[string][]CommandRecord)}
+
+// SendCommand sends the command to the server process and records it in the
+// command history of the server.
+func (s *Server) SendCommand(command string, origin CommandOrigin) error {
+	if err := s.Environment.SendCommand(command); err != nil {
+		return err
+	}
+	r := CommandRecord{
+		Command: string(s.RedactSecrets([]byte(command))),
+		Source:  origin.Source,
+		Actor:   origin.Actor,
+		Macro:   origin.Macro,
+		SentAt:  time.Now().UTC(),
+	}
+	commandHistories.Lock()
+	commandHistories.records[s.ID()] = appendCommandHistory(commandHistories.records[s.ID()], r)
+	commandHistories.Unlock()
+	return nil
+}
+
+// appendCommandHistory adds the record to the history, removing the oldest
+// records once the history is full.
+func appendCommandHistory(records []CommandRecord, r CommandRecord) []CommandRecord {
+	if len(records) >= commandHistorySize {
+		records = append(records[:0:0], records[len(records)-commandHistorySize+1:]...)
+	}
+	return append(records, r)
+}
+
+// CommandHistory returns the recent commands sent to the server, with the most
+// recent command first.
+func (s *Server) CommandHistory() []CommandRecord {
+	commandHistories.Lock()
+	defer commandHistories.Unlock()
+	records := commandHistories.records[s.ID()]
+	out := make([]CommandRecord, len(records))
+	for i, r := range records {
+		out[len(records)-1-i] = r
+	}
+	return out
+}
+
+// ClearCommandHistory removes the command history of the server.
+func (s *Server) ClearCommandHistory() {
+	commandHistories.Lock()
+	delete(commandHistories.records, s.ID())
+	commandHistories.Unlock()
+}
+
+// CommandMacro is a named list of commands that are sent to a server one after
+// another with a single API call.
+type CommandMacro struct {
+	Name     string   `json:"name"`
+	Commands []string `json:"commands"`
+	// The delay in milliseconds between each command being sent.
+	Delay int `json:"delay"`
+}
+
+// Validate checks that the macro has a valid name and commands.
+func (m CommandMacro) Validate() error {
+	if !macroNameRegex.MatchString(m.Name) {
+		return errors.Errorf("\"%s\" is not a valid name, it must be lowercase letters, numbers, dashes and underscores", m.Name)
+	}
+	if len(m.Commands) == 0 || len(m.Commands) > maxMacroCommands {
+		return errors.Errorf("a macro must have between 1 and %d commands", maxMacroCommands)
+	}
+	for _, c := range m.Commands {
+		if c == "" {
+			return errors.New("a macro cannot contain an empty command")
+		}
+	}
+	if m.Delay < 0 || m.Delay > maxMacroDelay {
+		return errors.Errorf("the delay must be between 0 and %d milliseconds", maxMacroDelay)
+	}
+	return nil
+}
+
+// macrosMu prevents the macros file of a server from being written by two
+// requests at the same time.
+var macrosMu sync.Mutex
+
+// macrosPath returns the path to the file that the command macros of the server
+// are stored in.
+func (s *Server) macrosPath() string {
+	return filepath.Join(config.Get().System.RootDirectory, "macros", s.ID()+".json")
+}
+
+// CommandMacros returns the command macros defined for the server, sorted by
+// their name.
+func (s *Server) CommandMacros() ([]CommandMacro, error) {
+	macrosMu.Lock()
+	defer macrosMu.Unlock()
+	return s.readCommandMacros()
+}
+
+// CommandMacro returns the command macro with the name. An ErrMacroNotFound
+// error is returned if there is no macro with the name.
+func (s *Server) CommandMacro(name string) (CommandMacro, error) {
+	macros, err := s.CommandMacros()
+	if err != nil {
+		return CommandMacro{}, err
+	}
+	for _, m := range macros {
+		if m.Name == name {
+			return m, nil
+		}
+	}
+	return CommandMacro{}, ErrMacroNotFound
+}
+
+// SaveCommandMacro creates the macro, or replaces the existing macro with the
+// same name.
+func (s *Server) SaveCommandMacro(m CommandMacro) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	macrosMu.Lock()
+	defer macrosMu.Unlock()
+	macros, err := s.readCommandMacros()
+	if err != nil {
+		return err
+	}
+	out := []CommandMacro{m}
+	for _, existing := range macros {
+		if existing.Name != m.Name {
+			out = append(out, existing)
+		}
+	}
+	return s.writeCommandMacros(out)
+}
+
+// DeleteCommandMacro removes the macro with the name. An ErrMacroNotFound error
+// is returned if there is no macro with the name.
+func (s *Server) DeleteCommandMacro(name string) error {
+	macrosMu.Lock()
+	defer macrosMu.Unlock()
+	macros, err := s.readCommandMacros()
+	if err != nil {
+		return err
+	}
+	out := make([]CommandMacro, 0, len(macros))
+	for _, m := range macros {
+		if m.Name != name {
+			out = append(out, m)
+		}
+	}
+	if len(out) == len(macros) {
+		return ErrMacroNotFound
+	}
+	return s.writeCommandMacros(out)
+}
+
+// RemoveCommandMacros removes every command macro defined for the server.
+func (s *Server) RemoveCommandMacros() error {
+	macrosMu.Lock()
+	defer macrosMu.Unlock()
+	if err := os.Remove(s.macrosPath()); err != nil && !os.IsNotExist(err) {
+		return errors.WithStackIf(err)
+	}
+	return nil
+}
+
+// RunCommandMacro sends each of the commands in the macro to the server, waiting
+// for the delay of the macro between each of them. This blocks until every
+// command has been sent, or the context is canceled. An ErrIsNotRunning error
+// is returned if the server stops running while the macro is being run.
+func (s *Server) RunCommandMacro(ctx context.Context, m CommandMacro, actor string) error {
+	origin := CommandOrigin{Source: CommandSourceMacro, Actor: actor, Macro: m.Name}
+	for i, c := range m.Commands {
+		if i > 0 && m.Delay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(m.Delay) * time.Millisecond):
+			}
+		}
+		if s.Environment.State() == environment.ProcessOfflineState {
+			return ErrIsNotRunning
+		}
+		if err := s.SendCommand(c, origin); err != nil {
+			return errors.WrapIf(err, "server: failed to send command for macro")
+		}
+	}
+	return nil
+}
+
+// readCommandMacros reads the command macros from the disk. The macrosMu lock
+// must be held by the caller.
+func (s *Server) readCommandMacros() ([]CommandMacro, error) {
+	b, err := os.ReadFile(s.macrosPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []CommandMacro{}, nil
+		}
+		return nil, errors.WithStackIf(err)
+	}
+	var macros []CommandMacro
+	if err := json.Unmarshal(b, &macros); err != nil {
+		return nil, errors.WrapIf(err, "server: failed to parse command macros")
+	}
+	return macros, nil
+}
+
+// writeCommandMacros writes the command macros to the disk, sorted by their name.
+// The macrosMu lock must be held by the caller.
+func (s *Server) writeCommandMacros(macros []CommandMacro) error {
+	if len(macros) == 0 {
+		if err := os.Remove(s.macrosPath()); err != nil && !os.IsNotExist(err) {
+			return errors.WithStackIf(err)
+		}
+		return nil
+	}
+	sort.Slice(macros, func(i, j int) bool {
+		return macros[i].Name < macros[j].Name
+	})
+	b, err := json.MarshalIndent(macros, "", "  ")
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.macrosPath()), 0o700); err != nil {
+		return errors.WithStackIf(err)
+	}
+	if err := os.WriteFile(s.macrosPath(), b, 0o600); err != nil {
+		return errors.WithStackIf(err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestCommandMacros(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("appendCommandHistory", func() {
+		g.It("removes the oldest commands once the history is full", func() {
+			var records []CommandRecord
+			for i := 0; i < commandHistorySize+5; i++ {
+				records = appendCommandHistory(records, CommandRecord{Command: fmt.Sprint(i)})
+			}
+			g.Assert(len(records)).Equal(commandHistorySize)
+			g.Assert(records[0].Command).Equal("5")
+			g.Assert(records[commandHistorySize-1].Command).Equal(fmt.Sprint(commandHistorySize + 4))
+		})
+	})
+
+	g.Describe("CommandMacro.Validate", func() {
+		g.It("accepts a valid macro", func() {
+			m := CommandMacro{Name: "save-and-stop", Commands: []string{"save-all", "stop"}, Delay: 2000}
+			g.Assert(m.Validate()).IsNil()
+		})
+
+		g.It("rejects invalid macros", func() {
+			g.Assert(CommandMacro{Name: "Save", Commands: []string{"save-all"}}.Validate()).IsNotNil()
+			g.Assert(CommandMacro{Name: "save"}.Validate()).IsNotNil()
+			g.Assert(CommandMacro{Name: "save", Commands: []string{""}}.Validate()).IsNotNil()
+			g.Assert(CommandMacro{Name: "save", Commands: []string{"save-all"}, Delay: -1}.Validate()).IsNotNil()
+			g.Assert(CommandMacro{Name: "save", Commands: strings.Split(strings.Repeat("a,", maxMacroCommands), ",")}.Validate()).IsNotNil()
+		})
+	})
+}
@@ -3,7 +3,9 @@ package server
 import (
 	"sync"
 
+	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/server/mirror"
 )
 
 type EggConfiguration struct {
@@ -14,6 +16,89 @@ type EggConfiguration struct {
 	// or basically any type of access on the server by any user. This is NOT the same
 	// as a per-user denylist, this is defined at the Egg level.
 	FileDenylist []string `json:"file_denylist"`
+
+	// A list of files that cannot be written or made executable through the file
+	// manager or SFTP, in addition to the write denylist configured for the node.
+	// Unlike the file denylist these files can still be read.
+	WriteDenylist []string `json:"write_denylist"`
+
+	// The runtime required by servers using this egg, in the format "runtime:version"
+	// (e.g. "java:17"). If not provided, Wings will attempt to detect the runtime from
+	// the server files when the server is started.
+	Runtime string `json:"runtime"`
+
+	// A list of feature hooks enabled for this egg, such as "eula" or "gsl_token".
+	// When console output matches one of the patterns for an enabled feature Wings
+	// will execute the behavior associated with it.
+	Features []string `json:"features"`
+
+	// Allows an egg to override the default patterns used to trigger a feature hook,
+	// keyed by the name of the feature.
+	FeaturePatterns map[string][]string `json:"feature_patterns"`
+
+	// Auxiliary containers that are run alongside the server process, sharing its
+	// network namespace and lifecycle.
+	Sidecars []environment.Sidecar `json:"sidecars"`
+
+	// The seccomp and AppArmor profiles to use for the server container in place of
+	// the profiles configured for the node.
+	Security environment.SecurityProfile `json:"security"`
+
+	// The image and resource limits to use for the installation container in place
+	// of the defaults configured for the node.
+	Installer InstallerConfiguration `json:"installer"`
+
+	// A list of paths that are kept when a server using this egg is reinstalled
+	// with its files removed, in the same format as a .gitignore file.
+	ReinstallPreserve []string `json:"reinstall_preserve"`
+
+	// The steps used to stop the server, in place of the single stop command in
+	// the process configuration. Each step is tried in turn until the server stops.
+	StopChain []environment.StopStep `json:"stop_chain"`
+
+	// The name of an entrypoint wrapper configured for the node that is run in front
+	// of the entrypoint of the server image.
+	EntrypointWrapper string `json:"entrypoint_wrapper"`
+}
+
+// InstallerConfiguration defines the image and resource limits used for the
+// installation container. Memory and disk are in megabytes, and CPU is a
+// percentage where 100 is a single core. Values that are not set use the
+// defaults configured for the node.
+type InstallerConfiguration struct {
+	Image  string `json:"image"`
+	Memory int64  `json:"memory"`
+	Cpu    int64  `json:"cpu"`
+	Disk   int64  `json:"disk"`
+}
+
+// UploadLimits defines the maximum size in megabytes of files uploaded through
+// the file manager, and of archives uploaded to restore the files of a server.
+// Values that are not set use the limits configured for the node.
+type UploadLimits struct {
+	Files   int64 `json:"files"`
+	Restore int64 `json:"restore"`
+}
+
+// StartupConfiguration defines additional rules used to determine when a server
+// has finished starting, or failed to start. These are applied in addition to
+// the "done" lines defined in the egg's process configuration.
+type StartupConfiguration struct {
+	// A list of regular expressions, any of which will mark the server as running
+	// when matched against a line of console output.
+	Done []string `json:"done"`
+
+	// A list of regular expressions, any of which will mark the server as having
+	// failed to start when matched against a line of console output.
+	Failed []string `json:"failed"`
+
+	// The number of seconds the server is allowed to remain in the starting state
+	// before the timeout action is applied. If not set the node default is used.
+	Timeout int `json:"timeout"`
+
+	// The action to take when the timeout is reached, one of "kill", "restart" or
+	// "fail". If not set the node default is used.
+	TimeoutAction string `json:"timeout_action"`
 }
 
 type Configuration struct {
@@ -39,15 +124,52 @@ type Configuration struct {
 	// server process.
 	EnvVars environment.Variables `json:"environment"`
 
+	// The names of the environment variables that have been marked as secret. The
+	// values of these variables are redacted from the console and installation logs.
+	Secrets []string `json:"secrets"`
+
 	Allocations           environment.Allocations `json:"allocations"`
 	Build                 environment.Limits      `json:"build"`
 	CrashDetectionEnabled bool                    `json:"crash_detection_enabled"`
 	Mounts                []Mount                 `json:"mounts"`
 	Egg                   EggConfiguration        `json:"egg,omitempty"`
+	Startup               StartupConfiguration    `json:"startup"`
+
+	// The times during which the server is expected to be offline, in addition to
+	// the maintenance windows configured for the node.
+	MaintenanceWindows []config.MaintenanceWindow `json:"maintenance_windows"`
+
+	// The rules used to alert on the resource usage of the server.
+	Alerts []AlertRule `json:"alerts"`
+
+	// The maximum size of uploads to the server in place of the limits configured
+	// for the node.
+	UploadLimits UploadLimits `json:"upload_limits"`
+
+	// The compression to apply to the data directory of the server in place of the
+	// compression configured for the node.
+	VolumeCompression string `json:"volume_compression"`
+
+	// The jobs that keep a directory of the server in sync with a remote endpoint.
+	Mirrors []mirror.Job `json:"mirrors"`
 
 	Container struct {
 		// Defines the Docker image that will be used for this server
 		Image string `json:"image,omitempty"`
+
+		// Defines when the image for this server should be pulled from the registry,
+		// overriding the default pull policy for the node.
+		PullPolicy string `json:"pull_policy,omitempty"`
+
+		// Determines if the root filesystem of the container is read-only, overriding
+		// the default for the platform. Linux containers are read-only by default, this
+		// is not supported for Windows containers.
+		ReadOnlyRoot *bool `json:"read_only_root,omitempty"`
+
+		// Paths within the container that remain writable when the root filesystem is
+		// read-only. A tmpfs is mounted at each path, so anything written to them is
+		// lost when the server is stopped.
+		WritablePaths []string `json:"writable_paths,omitempty"`
 	} `json:"container,omitempty"`
 }
 
@@ -70,6 +192,83 @@ func (s *Server) MemoryLimit() int64 {
 	return s.cfg.Build.MemoryLimit
 }
 
+// UploadLimit returns the maximum size in megabytes of a file uploaded to the
+// server through the file manager.
+func (s *Server) UploadLimit() int64 {
+	s.cfg.mu.RLock()
+	defer s.cfg.mu.RUnlock()
+	if s.cfg.UploadLimits.Files > 0 {
+		return s.cfg.UploadLimits.Files
+	}
+	return config.Get().Api.UploadLimit
+}
+
+// RestoreUploadLimit returns the maximum size in megabytes of an archive that is
+// uploaded to restore the files of the server.
+func (s *Server) RestoreUploadLimit() int64 {
+	s.cfg.mu.RLock()
+	defer s.cfg.mu.RUnlock()
+	if s.cfg.UploadLimits.Restore > 0 {
+		return s.cfg.UploadLimits.Restore
+	}
+	return config.Get().Api.RestoreUploadLimit
+}
+
+// WriteDenylist returns the patterns for files that cannot be written to the
+// server, combining the rules for the node with the rules for the egg.
+func (s *Server) WriteDenylist() []string {
+	s.cfg.mu.RLock()
+	defer s.cfg.mu.RUnlock()
+	return append(append([]string{}, config.Get().System.WriteDenylist...), s.cfg.Egg.WriteDenylist...)
+}
+
+// SyncWriteDenylist applies the write denylist for the server to its filesystem,
+// this is called whenever the configuration is synced with the Panel so that
+// changes to the egg take effect without restarting Wings.
+func (s *Server) SyncWriteDenylist() {
+	s.fs.SetWriteDenylist(s.WriteDenylist())
+}
+
+// VolumeCompression returns the algorithm used to compress the data directory
+// of the server, or an empty string if it should not be compressed.
+func (s *Server) VolumeCompression() string {
+	s.cfg.mu.RLock()
+	defer s.cfg.mu.RUnlock()
+	if s.cfg.VolumeCompression != "" {
+		return s.cfg.VolumeCompression
+	}
+	return config.Get().System.VolumeCompression
+}
+
+// PrepareDataDirectory creates the data directory for the server if it does not
+// exist and marks it so that new files written to it are compressed, if
+// compression is enabled for the server. This should be used instead of
+// EnsureDataDirectoryExists before any files are written for the server.
+func (s *Server) PrepareDataDirectory() error {
+	if err := s.EnsureDataDirectoryExists(); err != nil {
+		return err
+	}
+	s.enableVolumeCompression()
+	return nil
+}
+
+// enableVolumeCompression marks the data directory of the server so that new
+// files written to it are compressed, if compression is enabled for it. Nothing
+// is done if the data directory does not exist yet.
+func (s *Server) enableVolumeCompression() {
+	c := s.VolumeCompression()
+	if c == "" {
+		return
+	}
+	if !config.IsVolumeCompression(c) {
+		s.Log().WithField("algorithm", c).Warn("ignoring unknown volume compression algorithm for server")
+		return
+	}
+	if err := s.Filesystem().EnableCompression(c); err != nil {
+		s.Log().WithField("error", err).Warn("failed to enable compression for server data directory")
+	}
+}
+
 func (c *Configuration) GetUuid() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -22,6 +22,7 @@ func (s *Server) PublishConsoleOutputFromDaemon(data string) {
 	appNameSync.Do(func() {
 		appName = config.Get().AppName
 	})
+	s.writeConsoleLog(consoleLogSourceDaemon, []byte(data))
 	s.Events().Publish(
 		ConsoleOutputEvent,
 		colorstring.Color(fmt.Sprintf("[yellow][bold][%s Daemon]:[default] %s", appName, data)),
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/loggers/rotate"
+)
+
+// Identifies where a line written to the console log of a server came from.
+const (
+	consoleLogSourceConsole = "console"
+	consoleLogSourceInstall = "install"
+	consoleLogSourceDaemon  = "daemon"
+)
+
+// consoleLogs holds the open console log file of each server, keyed by the ID
+// of the server. Files are opened the first time output is written for them.
+var consoleLogs = struct {
+	sync.Mutex
+	files map[string]*rotate.File
+}{files: make(map[string]*rotate.File)}
+
+// consoleLogDirectory returns the directory that the console output of the
+// server is written to.
+func (s *Server) consoleLogDirectory() string {
+	return filepath.Join(config.Get().System.LogDirectory, "servers", s.ID())
+}
+
+// writeConsoleLog appends the line of output to the console log of the server
+// if console logs are enabled on the node.
+func (s *Server) writeConsoleLog(source string, line []byte) {
+	cfg := config.Get().System.ConsoleLogs
+	if !cfg.Enabled {
+		return
+	}
+	f, err := s.consoleLog(cfg)
+	if err != nil {
+		s.Log().WithField("error", err).Debug("failed to open console log for server")
+		return
+	}
+	if _, err := f.Write(formatConsoleLogLine(time.Now(), source, line)); err != nil {
+		s.Log().WithField("error", err).Debug("failed to write to console log for server")
+	}
+}
+
+// consoleLog returns the open console log file for the server, opening it if
+// it has not been opened yet.
+func (s *Server) consoleLog(cfg config.ConsoleLogConfiguration) (*rotate.File, error) {
+	consoleLogs.Lock()
+	defer consoleLogs.Unlock()
+	if f, ok := consoleLogs.files[s.ID()]; ok {
+		return f, nil
+	}
+	if err := os.MkdirAll(s.consoleLogDirectory(), 0o700); err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+	f, err := rotate.Open(filepath.Join(s.consoleLogDirectory(), "console.log"), rotate.Options{
+		MaxSize:    int64(cfg.MaxSize) * 1024 * 1024,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+	})
+	if err != nil {
+		return nil, err
+	}
+	consoleLogs.files[s.ID()] = f
+	return f, nil
+}
+
+// CloseConsoleLog closes the console log file of the server if it is open.
+func (s *Server) CloseConsoleLog() error {
+	consoleLogs.Lock()
+	defer consoleLogs.Unlock()
+	f, ok := consoleLogs.files[s.ID()]
+	if !ok {
+		return nil
+	}
+	delete(consoleLogs.files, s.ID())
+	return f.Close()
+}
+
+// RemoveConsoleLogs closes the console log file of the server and removes it,
+// along with every rotated console log of the server.
+func (s *Server) RemoveConsoleLogs() error {
+	if err := s.CloseConsoleLog(); err != nil {
+		s.Log().WithField("error", err).Warn("failed to close console log for server")
+	}
+	if err := os.RemoveAll(s.consoleLogDirectory()); err != nil {
+		return errors.WithStackIf(err)
+	}
+	return nil
+}
+
+// formatConsoleLogLine returns the line as it is written to a console log, with
+// the time it was received and where it came from. Colors are removed from the
+// line since the logs are meant to be read outside of a terminal.
+func formatConsoleLogLine(t time.Time, source string, line []byte) []byte {
+	line = bytes.TrimRight(stripAnsiRegex.ReplaceAll(line, nil), "\r\n")
+	b := make([]byte, 0, len(line)+48)
+	b = t.UTC().AppendFormat(b, time.RFC3339)
+	b = append(b, " ["...)
+	b = append(b, source...)
+	b = append(b, "] "...)
+	b = append(b, line...)
+	return append(b, '\n')
+}
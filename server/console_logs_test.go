@@ -0,0 +1,25 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+)
+
+func TestFormatConsoleLogLine(t *testing.T) {
+	g := goblin.Goblin(t)
+	at := time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	g.Describe("formatConsoleLogLine", func() {
+		g.It("prefixes the line with the time and source", func() {
+			b := formatConsoleLogLine(at, consoleLogSourceConsole, []byte("Done (3.21s)!\r\n"))
+			g.Assert(string(b)).Equal("2022-04-01T12:00:00Z [console] Done (3.21s)!\n")
+		})
+
+		g.It("removes colors from the line", func() {
+			b := formatConsoleLogLine(at, consoleLogSourceDaemon, []byte("\x1b[33m\x1b[1m[Daemon]:\x1b[0m Server marked as running"))
+			g.Assert(string(b)).Equal("2022-04-01T12:00:00Z [daemon] [Daemon]: Server marked as running\n")
+		})
+	})
+}
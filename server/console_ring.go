@@ -0,0 +1,39 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/pterodactyl/wings/events"
+)
+
+// The number of lines of console output kept for each server, websocket
+// connections that fall further behind than this skip the lines they missed.
+const consoleRingSize = 512
+
+// consoleRings holds the console output ring of each server, keyed by the ID of
+// the server. Rings are created the first time they are used.
+var consoleRings = struct {
+	sync.Mutex
+	rings map[string]*events.Ring
+}{rings: make(map[string]*events.Ring)}
+
+// ConsoleRing returns the ring that the console output of the server is pushed
+// into. Every websocket connection for the server reads from the same ring, so
+// each line is only stored once no matter how many connections there are.
+func (s *Server) ConsoleRing() *events.Ring {
+	consoleRings.Lock()
+	defer consoleRings.Unlock()
+	r, ok := consoleRings.rings[s.ID()]
+	if !ok {
+		r = events.NewRing(consoleRingSize)
+		consoleRings.rings[s.ID()] = r
+	}
+	return r
+}
+
+// destroyConsoleRing removes the console output ring of the server.
+func (s *Server) destroyConsoleRing() {
+	consoleRings.Lock()
+	delete(consoleRings.rings, s.ID())
+	consoleRings.Unlock()
+}
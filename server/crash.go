@@ -8,13 +8,27 @@ import (
 
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/events/webhook"
+	"github.com/pterodactyl/wings/remote"
 )
 
+// The exit code of a process that was killed with SIGKILL, which is how the OOM
+// killer stops a process.
+const oomExitCode = 137
+
 type CrashHandler struct {
 	mu sync.RWMutex
 
 	// Tracks the time of the last server crash event.
 	lastCrash time.Time
+
+	// Tracks if the server process has run out of memory since it was last started.
+	outOfMemory bool
+
+	// The number of times the server has crashed, and separately the number of times
+	// it has run out of memory, since Wings was started.
+	crashes  int
+	oomKills int
 }
 
 // Returns the time of the last crash for this server instance.
@@ -32,6 +46,87 @@ func (cd *CrashHandler) SetLastCrash(t time.Time) {
 	cd.mu.Unlock()
 }
 
+// RecordOutOfMemory records that the server process ran out of memory, returning
+// the number of times this has occurred since Wings was started.
+func (cd *CrashHandler) RecordOutOfMemory() int {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	cd.outOfMemory = true
+	cd.oomKills++
+	return cd.oomKills
+}
+
+// ResetOutOfMemory clears the out of memory state for the server, this should be
+// called whenever the server process is started.
+func (cd *CrashHandler) ResetOutOfMemory() {
+	cd.mu.Lock()
+	cd.outOfMemory = false
+	cd.mu.Unlock()
+}
+
+// OutOfMemory returns true if the server process has run out of memory since it
+// was last started.
+func (cd *CrashHandler) OutOfMemory() bool {
+	cd.mu.RLock()
+	defer cd.mu.RUnlock()
+
+	return cd.outOfMemory
+}
+
+// recordCrash increments the crash counter for the server, returning the number of
+// crashes and the number of times the server has run out of memory.
+func (cd *CrashHandler) recordCrash() (int, int) {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	cd.crashes++
+	return cd.crashes, cd.oomKills
+}
+
+// onOutOfMemory is called when a process within the server environment is killed
+// for exceeding the memory limit of the server. This can occur without the server
+// process itself being stopped, for example when a child process is killed.
+func (s *Server) onOutOfMemory() {
+	count := s.crasher.RecordOutOfMemory()
+	s.Log().WithField("count", count).Warn("server process ran out of memory")
+	s.PublishConsoleOutputFromDaemon("Server ran out of memory, a process was killed for exceeding the memory limit.")
+
+	data := map[string]interface{}{
+		"memory_limit": s.MemoryLimit(),
+		"count":        count,
+	}
+	s.Events().Publish(OutOfMemoryEvent, data)
+	webhook.Dispatch(s.ID(), webhook.OutOfMemoryEvent, data)
+
+	if c, ok := s.client.(remote.OutOfMemoryClient); ok {
+		go func() {
+			r := remote.OutOfMemoryReport{MemoryLimit: s.MemoryLimit(), Count: count}
+			if err := c.ReportOutOfMemory(s.Context(), s.ID(), r); err != nil {
+				s.Log().WithField("error", err).Warn("failed to notify panel of out of memory kill")
+			}
+		}()
+	}
+}
+
+// MaintenanceWindows returns every maintenance window that applies to the server,
+// which are the windows configured for the node followed by those for the server.
+func (s *Server) MaintenanceWindows() []config.MaintenanceWindow {
+	windows := append([]config.MaintenanceWindow{}, config.Get().System.CrashDetection.MaintenanceWindows...)
+	return append(windows, s.Config().MaintenanceWindows...)
+}
+
+// InMaintenance returns true if the time is within any maintenance window for
+// the server.
+func (s *Server) InMaintenance(now time.Time) bool {
+	for _, w := range s.MaintenanceWindows() {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
 // Looks at the environment exit state to determine if the process exited cleanly or
 // if it was the result of an event that we should try to recover from.
 //
@@ -55,10 +150,29 @@ func (s *Server) handleServerCrash() error {
 		return nil
 	}
 
+	// If the connection to Docker was lost the process was not necessarily stopped, so
+	// the server is returned to its previous state once the connection is back instead.
+	if _, ok := s.Environment.(interruptible); ok {
+		if degraded, _ := environment.DockerDegraded(); degraded {
+			s.Log().Debug("skipping crash detection while the connection to docker is lost")
+			s.PublishConsoleOutputFromDaemon("Lost connection to Docker, the server will be restored once the connection is back.")
+			return nil
+		}
+	}
+
 	exitCode, oomKilled, err := s.Environment.ExitState()
 	if err != nil {
 		return err
 	}
+	// The exit state is not always reliable for detecting out of memory kills, such as
+	// on cgroup v2 hosts, so also check if an out of memory event was received. If an
+	// event was not received the out of memory kill still needs to be recorded.
+	if oomKilled && !s.crasher.OutOfMemory() {
+		s.onOutOfMemory()
+	}
+	// An event is also sent when only a child process is killed, so it is only taken
+	// to mean the server process ran out of memory if the process was killed.
+	oomKilled = oomKilled || (s.crasher.OutOfMemory() && exitCode == oomExitCode)
 
 	// If the system is not configured to detect a clean exit code as a crash, and the
 	// crash is not the result of the program running out of memory, do nothing.
@@ -67,9 +181,38 @@ func (s *Server) handleServerCrash() error {
 		return nil
 	}
 
-	s.PublishConsoleOutputFromDaemon("---------- Detected server process in a crashed state! ----------")
-	s.PublishConsoleOutputFromDaemon(fmt.Sprintf("Exit code: %d", exitCode))
-	s.PublishConsoleOutputFromDaemon(fmt.Sprintf("Out of memory: %t", oomKilled))
+	crashes, oomKills := s.crasher.recordCrash()
+	if oomKilled {
+		s.PublishConsoleOutputFromDaemon("---------- Detected server process was killed after running out of memory! ----------")
+		s.PublishConsoleOutputFromDaemon(fmt.Sprintf("Exit code: %d", exitCode))
+		s.PublishConsoleOutputFromDaemon(fmt.Sprintf("Out of memory kills: %d", oomKills))
+	} else {
+		s.PublishConsoleOutputFromDaemon("---------- Detected server process in a crashed state! ----------")
+		s.PublishConsoleOutputFromDaemon(fmt.Sprintf("Exit code: %d", exitCode))
+	}
+	crash := map[string]interface{}{
+		"exit_code":   exitCode,
+		"oom_killed":  oomKilled,
+		"crash_count": crashes,
+		"oom_count":   oomKills,
+	}
+	if config.Get().System.CrashDetection.CollectReports {
+		if id, err := s.writeCrashReport(exitCode, oomKilled, crashes); err != nil {
+			s.Log().WithField("error", err).Warn("failed to write crash report for server")
+		} else {
+			crash["report"] = id
+		}
+	}
+	s.Events().Publish(CrashDetectedEvent, crash)
+	webhook.Dispatch(s.ID(), webhook.CrashDetectedEvent, crash)
+
+	// Servers are expected to be offline during a maintenance window, so don't fight
+	// whatever is being done to the server by restarting it.
+	if s.InMaintenance(time.Now()) {
+		s.Log().Info("server crashed during a maintenance window, not restarting")
+		s.PublishConsoleOutputFromDaemon("Aborting automatic restart, the server is in a maintenance window.")
+		return nil
+	}
 
 	c := s.crasher.LastCrashTime()
 	timeout := config.Get().System.CrashDetection.Timeout
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// The format used for the ID of a crash report, which is the time that the crash
+// was detected.
+const crashReportFormat = "20060102T150405Z"
+
+var crashReportRegex = regexp.MustCompile(`^\d{8}T\d{6}Z$`)
+
+// CrashReportSummary is returned when listing the crash reports for a server.
+type CrashReportSummary struct {
+	ID        string    `json:"id"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CrashReport contains the details collected when a server crashes.
+type CrashReport struct {
+	ID          string      `json:"id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	ExitCode    uint32      `json:"exit_code"`
+	OomKilled   bool        `json:"oom_killed"`
+	CrashCount  int         `json:"crash_count"`
+	Environment string      `json:"environment"`
+	Console     []string    `json:"console"`
+	Container   interface{} `json:"container,omitempty"`
+	Errors      []string    `json:"errors,omitempty"`
+}
+
+// crashReportDirectory returns the directory that the crash reports for the
+// server are written to.
+func (s *Server) crashReportDirectory() string {
+	return filepath.Join(config.Get().System.LogDirectory, "crashes", s.ID())
+}
+
+// CrashReports returns the crash reports for the server, with the most recent
+// report first.
+func (s *Server) CrashReports() ([]CrashReportSummary, error) {
+	entries, err := os.ReadDir(s.crashReportDirectory())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []CrashReportSummary{}, nil
+		}
+		return nil, errors.WithStackIf(err)
+	}
+
+	reports := make([]CrashReportSummary, 0, len(entries))
+	for _, e := range entries {
+		id := strings.TrimSuffix(e.Name(), ".json")
+		if e.IsDir() || !crashReportRegex.MatchString(id) {
+			continue
+		}
+		created, err := time.Parse(crashReportFormat, id)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		reports = append(reports, CrashReportSummary{ID: id, Size: info.Size(), CreatedAt: created})
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].CreatedAt.After(reports[j].CreatedAt)
+	})
+	return reports, nil
+}
+
+// CrashReportPath returns the path to a crash report for the server. An error
+// wrapping os.ErrNotExist is returned if the report does not exist.
+func (s *Server) CrashReportPath(id string) (string, error) {
+	if !crashReportRegex.MatchString(id) {
+		return "", errors.Wrap(os.ErrNotExist, "server: invalid crash report id")
+	}
+	p := filepath.Join(s.crashReportDirectory(), id+".json")
+	if _, err := os.Stat(p); err != nil {
+		return "", errors.WithStackIf(err)
+	}
+	return p, nil
+}
+
+// writeCrashReport collects the details of a crash and writes them to a new
+// report, removing the oldest reports beyond the retention limit. Any details
+// that cannot be collected are noted in the report rather than failing.
+func (s *Server) writeCrashReport(exitCode uint32, oomKilled bool, crashes int) (string, error) {
+	cfg := config.Get().System.CrashDetection
+	now := time.Now().UTC()
+	report := CrashReport{
+		ID:          now.Format(crashReportFormat),
+		CreatedAt:   now,
+		ExitCode:    exitCode,
+		OomKilled:   oomKilled,
+		CrashCount:  crashes,
+		Environment: s.Environment.Type(),
+		Console:     []string{},
+	}
+
+	if cfg.ReportLines > 0 {
+		lines, err := s.Environment.Readlog(cfg.ReportLines)
+		if err != nil {
+			report.Errors = append(report.Errors, "console: "+err.Error())
+		} else {
+			report.Console = s.RedactSecretLines(lines)
+		}
+	}
+
+	if env, ok := s.Environment.(interface {
+		CrashDetails(context.Context) (interface{}, error)
+	}); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		details, err := env.CrashDetails(ctx)
+		cancel()
+		if err != nil {
+			report.Errors = append(report.Errors, "container: "+err.Error())
+		} else {
+			report.Container = details
+		}
+	}
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", errors.WithStackIf(err)
+	}
+	if err := os.MkdirAll(s.crashReportDirectory(), 0o700); err != nil {
+		return "", errors.WithStackIf(err)
+	}
+	if err := s.pruneCrashReports(cfg.ReportRetention - 1); err != nil {
+		s.Log().WithField("error", err).Warn("failed to remove old crash reports")
+	}
+	if err := os.WriteFile(filepath.Join(s.crashReportDirectory(), report.ID+".json"), b, 0o600); err != nil {
+		return "", errors.WithStackIf(err)
+	}
+	return report.ID, nil
+}
+
+// pruneCrashReports removes all but the most recent crash reports for the server.
+func (s *Server) pruneCrashReports(keep int) error {
+	reports, err := s.CrashReports()
+	if err != nil {
+		return err
+	}
+	if keep < 0 {
+		keep = 0
+	}
+	for i := keep; i < len(reports); i++ {
+		if err := os.Remove(filepath.Join(s.crashReportDirectory(), reports[i].ID+".json")); err != nil && !os.IsNotExist(err) {
+			return errors.WithStackIf(err)
+		}
+	}
+	return nil
+}
+
+// RemoveCrashReports removes every crash report for the server.
+func (s *Server) RemoveCrashReports() error {
+	if err := os.RemoveAll(s.crashReportDirectory()); err != nil {
+		return errors.WithStackIf(err)
+	}
+	return nil
+}
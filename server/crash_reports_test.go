@@ -0,0 +1,94 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestCrashReports(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("CrashReports", func() {
+		var s *Server
+
+		g.BeforeEach(func() {
+			dir, err := os.MkdirTemp("", "wings-crashes")
+			g.Assert(err).IsNil()
+			c := &config.Configuration{AuthenticationToken: "test"}
+			c.System.LogDirectory = dir
+			config.Set(c)
+
+			s = &Server{}
+			s.cfg.Uuid = "abc"
+			g.Assert(os.MkdirAll(s.crashReportDirectory(), 0o700)).IsNil()
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(config.Get().System.LogDirectory)
+		})
+
+		write := func(name string) {
+			g.Assert(os.WriteFile(filepath.Join(s.crashReportDirectory(), name), []byte("{}"), 0o600)).IsNil()
+		}
+
+		g.It("lists the reports with the most recent first", func() {
+			write("20220401T120000Z.json")
+			write("20220403T120000Z.json")
+			write("20220402T120000Z.json")
+			write("notes.txt")
+
+			reports, err := s.CrashReports()
+			g.Assert(err).IsNil()
+			g.Assert(len(reports)).Equal(3)
+			g.Assert(reports[0].ID).Equal("20220403T120000Z")
+			g.Assert(reports[1].ID).Equal("20220402T120000Z")
+			g.Assert(reports[2].ID).Equal("20220401T120000Z")
+			g.Assert(reports[0].Size).Equal(int64(2))
+		})
+
+		g.It("returns no reports for a server that has never crashed", func() {
+			g.Assert(s.RemoveCrashReports()).IsNil()
+
+			reports, err := s.CrashReports()
+			g.Assert(err).IsNil()
+			g.Assert(len(reports)).Equal(0)
+		})
+
+		g.It("only returns the path to reports that exist", func() {
+			write("20220401T120000Z.json")
+
+			p, err := s.CrashReportPath("20220401T120000Z")
+			g.Assert(err).IsNil()
+			g.Assert(p).Equal(filepath.Join(s.crashReportDirectory(), "20220401T120000Z.json"))
+
+			_, err = s.CrashReportPath("20220402T120000Z")
+			g.Assert(errors.Is(err, os.ErrNotExist)).IsTrue()
+
+			_, err = s.CrashReportPath("../../wings")
+			g.Assert(errors.Is(err, os.ErrNotExist)).IsTrue()
+		})
+
+		g.It("removes all but the most recent reports", func() {
+			write("20220401T120000Z.json")
+			write("20220402T120000Z.json")
+			write("20220403T120000Z.json")
+
+			g.Assert(s.pruneCrashReports(2)).IsNil()
+			reports, err := s.CrashReports()
+			g.Assert(err).IsNil()
+			g.Assert(len(reports)).Equal(2)
+			g.Assert(reports[1].ID).Equal("20220402T120000Z")
+
+			g.Assert(s.pruneCrashReports(-1)).IsNil()
+			reports, err = s.CrashReports()
+			g.Assert(err).IsNil()
+			g.Assert(len(reports)).Equal(0)
+		})
+	})
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestCrashHandler(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("CrashHandler", func() {
+		g.It("counts out of memory kills separately from crashes", func() {
+			cd := &CrashHandler{}
+			g.Assert(cd.OutOfMemory()).IsFalse()
+
+			g.Assert(cd.RecordOutOfMemory()).Equal(1)
+			g.Assert(cd.RecordOutOfMemory()).Equal(2)
+			g.Assert(cd.OutOfMemory()).IsTrue()
+
+			crashes, oomKills := cd.recordCrash()
+			g.Assert(crashes).Equal(1)
+			g.Assert(oomKills).Equal(2)
+		})
+
+		g.It("clears the out of memory state when the server is started", func() {
+			cd := &CrashHandler{}
+			cd.RecordOutOfMemory()
+			cd.ResetOutOfMemory()
+			g.Assert(cd.OutOfMemory()).IsFalse()
+
+			_, oomKills := cd.recordCrash()
+			g.Assert(oomKills).Equal(1)
+		})
+	})
+}
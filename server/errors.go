@@ -1,11 +1,14 @@
 package server
 
 import (
+	"fmt"
+
 	"emperror.dev/errors"
 )
 
 var (
 	ErrIsRunning            = errors.New("server is running")
+	ErrIsNotRunning         = errors.New("server is not running")
 	ErrSuspended            = errors.New("server is currently in a suspended state")
 	ErrServerIsInstalling   = errors.New("server is currently installing")
 	ErrServerIsTransferring = errors.New("server is currently being transferred")
@@ -35,3 +38,17 @@ func IsServerDoesNotExistError(err error) bool {
 
 	return ok
 }
+
+// installScriptError is returned when the installation script for a server exits
+// with a non-zero exit code, or is stopped for exceeding the installer disk limit.
+type installScriptError struct {
+	code         int64
+	diskExceeded bool
+}
+
+func (e *installScriptError) Error() string {
+	if e.diskExceeded {
+		return "installation script exceeded the disk space limit for the installer and was stopped"
+	}
+	return fmt.Sprintf("installation script exited with code %d", e.code)
+}
@@ -11,7 +11,9 @@ const (
 	DaemonMessageEvent          = "daemon message"
 	InstallOutputEvent          = "install output"
 	InstallStartedEvent         = "install started"
+	InstallQueuedEvent          = "install queued"
 	InstallCompletedEvent       = "install completed"
+	InstallRolledBackEvent      = "install rolled back"
 	ConsoleOutputEvent          = "console output"
 	StatusEvent                 = "status"
 	StatsEvent                  = "stats"
@@ -19,6 +21,16 @@ const (
 	BackupCompletedEvent        = "backup completed"
 	TransferLogsEvent           = "transfer logs"
 	TransferStatusEvent         = "transfer status"
+	TransferProgressEvent       = "transfer progress"
+	TransferIntegrityEvent      = "transfer integrity"
+	StartupFailedEvent          = "startup failed"
+	FeatureMatchedEvent         = "feature matched"
+	CrashDetectedEvent          = "crash detected"
+	OutOfMemoryEvent            = "out of memory"
+	ResourceAlertEvent          = "resource alert"
+	FileOperationProgressEvent  = "file operation progress"
+	FileOperationCompletedEvent = "file operation completed"
+	MirrorSyncEvent             = "mirror sync"
 )
 
 // Events returns the server's emitter instance.
@@ -52,4 +64,5 @@ func (s *Server) DestroyAllSinks() {
 	for _, sink := range s.sinks {
 		sink.Destroy()
 	}
+	s.destroyConsoleRing()
 }
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bytes"
+	"sync"
+)
+
+// FeatureHook defines a behavior that an egg can opt into by listing the name of
+// the hook in its features. When a line of console output matches one of the
+// hook's patterns the handler is executed for the server.
+type FeatureHook struct {
+	// Patterns are the default regular expressions that trigger this hook. An egg
+	// may override these by defining its own patterns for the feature.
+	Patterns []string
+
+	// Handle is executed with the line of output that matched one of the patterns.
+	Handle func(s *Server, line []byte)
+}
+
+var (
+	featureMu    sync.RWMutex
+	featureHooks = map[string]FeatureHook{
+		"eula": {
+			Patterns: []string{
+				`(?i)you need to agree to the eula in order to run the server`,
+				`(?i)go to eula\.txt for more info`,
+			},
+			Handle: acceptEula,
+		},
+		"gsl_token": {
+			Patterns: []string{
+				`\(gsl token expired\)`,
+				`\(account not found\)`,
+				`(?i)invalid gslt`,
+			},
+			Handle: func(s *Server, _ []byte) {
+				s.PublishConsoleOutputFromDaemon("The Game Server Login Token (GSLT) for this server is invalid or has expired. Please update it in the server's startup variables.")
+			},
+		},
+		"oom": {
+			Patterns: []string{
+				`java\.lang\.OutOfMemoryError`,
+				`(?i)\bout of memory\b`,
+			},
+			Handle: func(s *Server, _ []byte) {
+				s.PublishConsoleOutputFromDaemon("The server process has run out of memory. Consider increasing the memory assigned to this server.")
+			},
+		},
+		"java_version": {
+			Patterns: []string{
+				`(?i)unsupported major\.minor version`,
+				`has been compiled by a more recent version of the Java Runtime`,
+			},
+			Handle: func(s *Server, _ []byte) {
+				s.PublishConsoleOutputFromDaemon("The server requires a different version of Java than the one provided by the current image. Please select a different Docker image for this server.")
+			},
+		},
+	}
+)
+
+// RegisterFeatureHook registers a feature hook that eggs can enable by name. If
+// a hook with the same name is already registered it will be replaced.
+func RegisterFeatureHook(name string, hook FeatureHook) {
+	featureMu.Lock()
+	featureHooks[name] = hook
+	featureMu.Unlock()
+}
+
+// featureHook returns the registered hook with the given name.
+func featureHook(name string) (FeatureHook, bool) {
+	featureMu.RLock()
+	defer featureMu.RUnlock()
+	h, ok := featureHooks[name]
+	return h, ok
+}
+
+// processFeatureHooks checks the provided line of console output against each
+// feature enabled for the server's egg, executing the hook for any that match.
+func (s *Server) processFeatureHooks(v []byte) {
+	egg := s.Config().Egg
+	for _, name := range egg.Features {
+		hook, ok := featureHook(name)
+		if !ok {
+			continue
+		}
+
+		patterns := hook.Patterns
+		if p, ok := egg.FeaturePatterns[name]; ok && len(p) > 0 {
+			patterns = p
+		}
+		if _, ok := matchesAnyPattern(v, patterns); !ok {
+			continue
+		}
+
+		s.Log().WithField("feature", name).Debug("console output matched egg feature, executing hook")
+		s.Events().Publish(FeatureMatchedEvent, name)
+		hook.Handle(s, v)
+	}
+}
+
+// acceptEula writes an accepted eula.txt file to the root of the server so that
+// the next time it is started it will boot correctly.
+func acceptEula(s *Server, _ []byte) {
+	b := bytes.NewBufferString("# EULA accepted automatically by Wings.\neula=true\n")
+	if err := s.Filesystem().Writefile("eula.txt", b); err != nil {
+		s.Log().WithField("error", err).Error("failed to write eula.txt for server")
+		return
+	}
+	s.Log().WithField("file", "eula.txt").Info("accepted server EULA automatically based on egg feature")
+	s.PublishConsoleOutputFromDaemon("The server EULA has been accepted automatically, please start the server again.")
+}
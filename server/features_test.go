@@ -0,0 +1,76 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestFeatureHooks(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("FeatureHooks", func() {
+		var s *Server
+		var matched []string
+
+		g.BeforeEach(func() {
+			matched = []string{}
+			RegisterFeatureHook("test", FeatureHook{
+				Patterns: []string{`(?i)something went wrong`},
+				Handle: func(_ *Server, line []byte) {
+					matched = append(matched, string(line))
+				},
+			})
+
+			s = &Server{}
+			s.cfg.Uuid = "abc"
+		})
+
+		g.It("executes the hook when output matches an enabled feature", func() {
+			s.cfg.Egg.Features = []string{"test"}
+
+			s.processFeatureHooks([]byte("Something went wrong!"))
+			s.processFeatureHooks([]byte("Done (1.2s)!"))
+
+			g.Assert(matched).Equal([]string{"Something went wrong!"})
+		})
+
+		g.It("does not execute hooks for features the egg has not enabled", func() {
+			s.processFeatureHooks([]byte("Something went wrong!"))
+
+			g.Assert(len(matched)).Equal(0)
+		})
+
+		g.It("ignores features that are not registered", func() {
+			s.cfg.Egg.Features = []string{"missing", "test"}
+
+			s.processFeatureHooks([]byte("Something went wrong!"))
+
+			g.Assert(len(matched)).Equal(1)
+		})
+
+		g.It("uses the patterns defined by the egg in place of the defaults", func() {
+			s.cfg.Egg.Features = []string{"test"}
+			s.cfg.Egg.FeaturePatterns = map[string][]string{"test": {`^\[FATAL\]`}}
+
+			s.processFeatureHooks([]byte("Something went wrong!"))
+			s.processFeatureHooks([]byte("[FATAL] could not bind to port"))
+
+			g.Assert(matched).Equal([]string{"[FATAL] could not bind to port"})
+		})
+
+		g.It("matches the default patterns for the built-in features", func() {
+			hook, ok := featureHook("eula")
+			g.Assert(ok).IsTrue()
+			_, ok = matchesAnyPattern([]byte("[12:00:00] [Server thread/INFO]: You need to agree to the EULA in order to run the server. Go to eula.txt for more info."), hook.Patterns)
+			g.Assert(ok).IsTrue()
+
+			hook, ok = featureHook("oom")
+			g.Assert(ok).IsTrue()
+			_, ok = matchesAnyPattern([]byte("Exception in thread \"main\" java.lang.OutOfMemoryError: Java heap space"), hook.Patterns)
+			g.Assert(ok).IsTrue()
+			_, ok = matchesAnyPattern([]byte("Loaded 100 chunks in memory"), hook.Patterns)
+			g.Assert(ok).IsFalse()
+		})
+	})
+}
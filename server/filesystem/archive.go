@@ -2,21 +2,23 @@ package filesystem
 
 import (
 	"archive/tar"
+	"context"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"emperror.dev/errors"
 	"github.com/apex/log"
-	"github.com/juju/ratelimit"
 	"github.com/karrick/godirwalk"
 	"github.com/klauspost/pgzip"
 	ignore "github.com/sabhiram/go-gitignore"
 
 	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/throttle"
 )
 
 const memory = 4 * 1024
@@ -42,25 +44,47 @@ type Archive struct {
 	Files []string
 }
 
+type archiveProgressKey struct{}
+
+// WithArchiveProgress returns a context that causes the contents of each file
+// added to an archive created with it to also be written to w, which allows the
+// progress of creating the archive to be tracked by counting the bytes written.
+func WithArchiveProgress(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, archiveProgressKey{}, w)
+}
+
 // Create creates an archive at dst with all of the files defined in the
-// included files struct.
-func (a *Archive) Create(dst string) error {
+// included files struct. The partially written archive is removed if it cannot
+// be created, including when the context is canceled.
+func (a *Archive) Create(ctx context.Context, dst string) error {
 	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	// Select a writer based off of the WriteLimit configuration option. If there is no
-	// write limit, use the file as the writer.
-	var writer io.Writer
-	if writeLimit := int64(config.Get().System.Backups.WriteLimit * 1024 * 1024); writeLimit > 0 {
-		// Token bucket with a capacity of "writeLimit" MiB, adding "writeLimit" MiB/s
-		// and then wrap the file writer with the token bucket limiter.
-		writer = ratelimit.Writer(f, ratelimit.NewBucketWithRate(float64(writeLimit), writeLimit))
-	} else {
-		writer = f
+	err = a.Stream(ctx, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(dst)
 	}
+	return err
+}
+
+// Stream writes the archive to the given writer rather than a file on the disk,
+// which allows it to be sent somewhere else as it is created. The WriteLimit
+// configuration option is applied to the writer in the same way as when the
+// archive is written to the disk.
+//
+// Creating the archive stops with the error from the context once it is
+// canceled, even part way through writing a large file.
+func (a *Archive) Stream(ctx context.Context, w io.Writer) error {
+	// Limit the writer based off of the WriteLimit configuration option and its
+	// schedule, which is checked again while the archive is being written.
+	writer := throttle.Writer(&contextWriter{ctx: ctx, w: w}, func(now time.Time) int {
+		return config.Get().System.Backups.WriteLimitAt(now)
+	})
 
 	// Create a new gzip writer around the file.
 	gw, _ := pgzip.NewWriterLevel(writer, pgzip.BestSpeed)
@@ -75,7 +99,7 @@ func (a *Archive) Create(dst string) error {
 	options := &godirwalk.Options{
 		FollowSymbolicLinks: false,
 		Unsorted:            true,
-		Callback:            a.callback(tw),
+		Callback:            a.callback(ctx, tw),
 	}
 
 	// If we're specifically looking for only certain files, or have requested
@@ -84,7 +108,7 @@ func (a *Archive) Create(dst string) error {
 	if len(a.Files) == 0 && len(a.Ignore) > 0 {
 		i := ignore.CompileIgnoreLines(strings.Split(a.Ignore, "\n")...)
 
-		options.Callback = a.callback(tw, func(_ string, rp string) error {
+		options.Callback = a.callback(ctx, tw, func(_ string, rp string) error {
 			if i.MatchesPath(rp) {
 				return godirwalk.SkipThis
 			}
@@ -92,17 +116,46 @@ func (a *Archive) Create(dst string) error {
 			return nil
 		})
 	} else if len(a.Files) > 0 {
-		options.Callback = a.withFilesCallback(tw)
+		options.Callback = a.withFilesCallback(ctx, tw)
 	}
 
 	// Recursively walk the path we are archiving.
-	return godirwalk.Walk(a.BasePath, options)
+	if err := godirwalk.Walk(a.BasePath, options); err != nil {
+		return err
+	}
+
+	// Close the writers now rather than deferring it so that any error writing the
+	// end of the archive is returned, which matters when the writer is not a file.
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// contextWriter is a writer that returns the error from the context once it has
+// been canceled rather than writing anything more.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw *contextWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
 }
 
 // Callback function used to determine if a given file should be included in the archive
 // being generated.
-func (a *Archive) callback(tw *tar.Writer, opts ...func(path string, relative string) error) func(path string, de *godirwalk.Dirent) error {
+func (a *Archive) callback(ctx context.Context, tw *tar.Writer, opts ...func(path string, relative string) error) func(path string, de *godirwalk.Dirent) error {
 	return func(path string, de *godirwalk.Dirent) error {
+		// Stop walking the files as soon as the context is canceled, rather than
+		// only when the next file is written.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Skip directories because we walking them recursively.
 		if de.IsDir() {
 			return nil
@@ -120,13 +173,13 @@ func (a *Archive) callback(tw *tar.Writer, opts ...func(path string, relative st
 
 		// Add the file to the archive, if it is nested in a directory,
 		// the directory will be automatically "created" in the archive.
-		return a.addToArchive(path, relative, tw)
+		return a.addToArchive(ctx, path, relative, tw)
 	}
 }
 
 // Pushes only files defined in the Files key to the final archive.
-func (a *Archive) withFilesCallback(tw *tar.Writer) func(path string, de *godirwalk.Dirent) error {
-	return a.callback(tw, func(p string, rp string) error {
+func (a *Archive) withFilesCallback(ctx context.Context, tw *tar.Writer) func(path string, de *godirwalk.Dirent) error {
+	return a.callback(ctx, tw, func(p string, rp string) error {
 		for _, f := range a.Files {
 			// If the given doesn't match, or doesn't have the same prefix continue
 			// to the next item in the loop.
@@ -146,7 +199,7 @@ func (a *Archive) withFilesCallback(tw *tar.Writer) func(path string, de *godirw
 }
 
 // Adds a given file path to the final archive being created.
-func (a *Archive) addToArchive(p string, rp string, w *tar.Writer) error {
+func (a *Archive) addToArchive(ctx context.Context, p string, rp string, w *tar.Writer) error {
 	// Lstat the file, this will give us the same information as Stat except that it will not
 	// follow a symlink to it's target automatically. This is important to avoid including
 	// files that exist outside the server root unintentionally in the backup.
@@ -225,8 +278,13 @@ func (a *Archive) addToArchive(p string, rp string, w *tar.Writer) error {
 	}
 	defer f.Close()
 
-	// Copy the file's contents to the archive using our buffer.
-	if _, err := io.CopyBuffer(w, io.LimitReader(f, header.Size), buf); err != nil {
+	// Copy the file's contents to the archive using our buffer, also writing them to
+	// the progress writer if there is one.
+	var dst io.Writer = w
+	if progress, ok := ctx.Value(archiveProgressKey{}).(io.Writer); ok {
+		dst = io.MultiWriter(w, progress)
+	}
+	if _, err := io.CopyBuffer(dst, io.LimitReader(f, header.Size), buf); err != nil {
 		return errors.WrapIff(err, "failed to copy '%s' to archive", header.Name)
 	}
 
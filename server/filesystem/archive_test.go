@@ -0,0 +1,57 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestArchive_Create(t *testing.T) {
+	g := Goblin(t)
+	fs, rfs := NewFs()
+
+	g.Describe("Archive.Create", func() {
+		g.BeforeEach(func() {
+			rfs.reset()
+			if err := rfs.CreateServerFileFromString("server.properties", "motd=hello"); err != nil {
+				panic(err)
+			}
+		})
+
+		g.It("creates an archive of the files", func() {
+			dst := filepath.Join(rfs.root, "archive.tar.gz")
+			a := &Archive{BasePath: fs.Path()}
+			g.Assert(a.Create(context.Background(), dst)).IsNil()
+
+			st, err := os.Stat(dst)
+			g.Assert(err).IsNil()
+			g.Assert(st.Size() > 0).IsTrue()
+		})
+
+		g.It("writes the contents of the files to the progress writer", func() {
+			var progress bytes.Buffer
+			ctx := WithArchiveProgress(context.Background(), &progress)
+
+			a := &Archive{BasePath: fs.Path()}
+			g.Assert(a.Create(ctx, filepath.Join(rfs.root, "progress.tar.gz"))).IsNil()
+			g.Assert(progress.String()).Equal("motd=hello")
+		})
+
+		g.It("removes the partial archive when the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			dst := filepath.Join(rfs.root, "canceled.tar.gz")
+			a := &Archive{BasePath: fs.Path()}
+			err := a.Create(ctx, dst)
+			g.Assert(err).Equal(context.Canceled)
+
+			_, err = os.Stat(dst)
+			g.Assert(os.IsNotExist(err)).IsTrue()
+		})
+	})
+}
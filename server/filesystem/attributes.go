@@ -0,0 +1,37 @@
+package filesystem
+
+// FileAttributes contains the Windows specific attributes of a file, such as
+// whether it is hidden or is a reparse point.
+type FileAttributes struct {
+	Hidden   bool `json:"hidden"`
+	System   bool `json:"system"`
+	ReadOnly bool `json:"readonly"`
+	// The type of reparse point the file is, such as "junction" or "symlink",
+	// this is empty if the file is not a reparse point.
+	ReparsePoint string `json:"reparse_point,omitempty"`
+	// The number of alternate data streams attached to the file, not including
+	// the default unnamed stream.
+	AlternateStreams int `json:"alternate_streams"`
+}
+
+// AttributeChanges contains the attributes to change on a file, any attribute
+// that is nil is left as-is.
+type AttributeChanges struct {
+	Hidden   *bool `json:"hidden"`
+	System   *bool `json:"system"`
+	ReadOnly *bool `json:"readonly"`
+}
+
+// SetAttributes changes the attributes of the file at the given path. This is
+// only supported on Windows, an ErrCodeNotSupported error is returned on every
+// other system.
+func (fs *Filesystem) SetAttributes(p string, changes AttributeChanges) error {
+	if err := fs.IsIgnored(p); err != nil {
+		return err
+	}
+	cleaned, err := fs.SafePath(p)
+	if err != nil {
+		return err
+	}
+	return setAttributes(cleaned, changes)
+}
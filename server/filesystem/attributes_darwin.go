@@ -0,0 +1,15 @@
+package filesystem
+
+import (
+	"os"
+)
+
+// fileAttributes returns nil since there are no Windows attributes to report
+// on macOS.
+func fileAttributes(_ string, _ os.FileInfo) *FileAttributes {
+	return nil
+}
+
+func setAttributes(_ string, _ AttributeChanges) error {
+	return newFilesystemError(ErrCodeNotSupported, nil)
+}
@@ -0,0 +1,125 @@
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32          = windows.NewLazySystemDLL("kernel32.dll")
+	procFindFirstStreamW = modkernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = modkernel32.NewProc("FindNextStreamW")
+)
+
+// win32FindStreamData mirrors the WIN32_FIND_STREAM_DATA structure returned by
+// FindFirstStreamW and FindNextStreamW.
+type win32FindStreamData struct {
+	StreamSize int64
+	StreamName [windows.MAX_PATH + 36]uint16
+}
+
+// fileAttributes returns the attributes for the file at the given path. The
+// path is not resolved, so the attributes of a reparse point are returned
+// rather than the attributes of its target.
+func fileAttributes(p string, info os.FileInfo) *FileAttributes {
+	d, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return nil
+	}
+	a := &FileAttributes{
+		Hidden:   d.FileAttributes&windows.FILE_ATTRIBUTE_HIDDEN != 0,
+		System:   d.FileAttributes&windows.FILE_ATTRIBUTE_SYSTEM != 0,
+		ReadOnly: d.FileAttributes&windows.FILE_ATTRIBUTE_READONLY != 0,
+	}
+	if d.FileAttributes&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0 {
+		a.ReparsePoint = reparseTag(p)
+	}
+	if !info.IsDir() {
+		a.AlternateStreams = alternateStreams(p)
+	}
+	return a
+}
+
+// reparseTag returns the type of reparse point for the given path. Reparse
+// points other than junctions and symlinks, such as deduplicated or cloud
+// files, are returned as their raw tag.
+func reparseTag(p string) string {
+	p16, err := windows.UTF16PtrFromString(p)
+	if err != nil {
+		return ""
+	}
+	var fd windows.Win32finddata
+	h, err := windows.FindFirstFile(p16, &fd)
+	if err != nil {
+		return ""
+	}
+	_ = windows.FindClose(h)
+	switch fd.Reserved0 {
+	case windows.IO_REPARSE_TAG_MOUNT_POINT:
+		return "junction"
+	case windows.IO_REPARSE_TAG_SYMLINK:
+		return "symlink"
+	default:
+		return fmt.Sprintf("0x%08X", fd.Reserved0)
+	}
+}
+
+// alternateStreams returns the number of alternate data streams attached to
+// the file, which does not include the default "::$DATA" stream.
+func alternateStreams(p string) int {
+	p16, err := windows.UTF16PtrFromString(p)
+	if err != nil {
+		return 0
+	}
+	var d win32FindStreamData
+	h, _, _ := procFindFirstStreamW.Call(uintptr(unsafe.Pointer(p16)), 0, uintptr(unsafe.Pointer(&d)), 0)
+	if windows.Handle(h) == windows.InvalidHandle {
+		return 0
+	}
+	defer windows.FindClose(windows.Handle(h))
+
+	var n int
+	for {
+		if windows.UTF16ToString(d.StreamName[:]) != "::$DATA" {
+			n++
+		}
+		if r, _, _ := procFindNextStreamW.Call(h, uintptr(unsafe.Pointer(&d))); r == 0 {
+			break
+		}
+	}
+	return n
+}
+
+// setAttributes applies the changes to the attributes of the file at the given
+// path, leaving every other attribute as-is.
+func setAttributes(p string, changes AttributeChanges) error {
+	p16, err := windows.UTF16PtrFromString(p)
+	if err != nil {
+		return err
+	}
+	attrs, err := windows.GetFileAttributes(p16)
+	if err != nil {
+		return &os.PathError{Op: "getfileattributes", Path: p, Err: err}
+	}
+	set := func(v *bool, flag uint32) {
+		if v == nil {
+			return
+		}
+		if *v {
+			attrs |= flag
+		} else {
+			attrs &^= flag
+		}
+	}
+	set(changes.Hidden, windows.FILE_ATTRIBUTE_HIDDEN)
+	set(changes.System, windows.FILE_ATTRIBUTE_SYSTEM)
+	set(changes.ReadOnly, windows.FILE_ATTRIBUTE_READONLY)
+	if err := windows.SetFileAttributes(p16, attrs); err != nil {
+		return &os.PathError{Op: "setfileattributes", Path: p, Err: err}
+	}
+	return nil
+}
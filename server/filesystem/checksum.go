@@ -0,0 +1,72 @@
+package filesystem
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// The hash algorithms that can be used to compute the checksum of a file.
+const (
+	ChecksumMD5    = "md5"
+	ChecksumSHA1   = "sha1"
+	ChecksumSHA256 = "sha256"
+	ChecksumXXH64  = "xxh64"
+)
+
+var checksumHashes = map[string]func() hash.Hash{
+	ChecksumMD5:    md5.New,
+	ChecksumSHA1:   sha1.New,
+	ChecksumSHA256: sha256.New,
+	ChecksumXXH64:  func() hash.Hash { return xxhash.New() },
+}
+
+// IsChecksumAlgorithm returns true if the checksum of a file can be computed
+// using the given algorithm.
+func IsChecksumAlgorithm(alg string) bool {
+	_, ok := checksumHashes[alg]
+	return ok
+}
+
+// Checksums computes the checksum of the file for each of the given algorithms,
+// reading the file only once. The checksums are returned hex encoded, keyed by
+// the algorithm.
+func (fs *Filesystem) Checksums(p string, algorithms []string) (map[string]string, error) {
+	if err := fs.IsIgnored(p); err != nil {
+		return nil, err
+	}
+	f, _, err := fs.File(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hashes := make(map[string]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, 0, len(algorithms))
+	for _, alg := range algorithms {
+		fn, ok := checksumHashes[alg]
+		if !ok {
+			return nil, newFilesystemError(ErrCodeNotSupported, nil)
+		}
+		if _, ok := hashes[alg]; ok {
+			continue
+		}
+		hashes[alg] = fn()
+		writers = append(writers, hashes[alg])
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), bufio.NewReader(f)); err != nil {
+		return nil, wrapError(err, f.Name())
+	}
+
+	sums := make(map[string]string, len(hashes))
+	for alg, h := range hashes {
+		sums[alg] = hex.EncodeToString(h.Sum(nil))
+	}
+	return sums, nil
+}
@@ -0,0 +1,46 @@
+package filesystem
+
+import (
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestFilesystem_Checksums(t *testing.T) {
+	g := Goblin(t)
+	fs, rfs := NewFs()
+
+	g.Describe("Checksums", func() {
+		g.BeforeEach(func() {
+			rfs.reset()
+		})
+
+		g.It("computes the checksum for each algorithm", func() {
+			err := rfs.CreateServerFileFromString("test.txt", "hello world")
+			g.Assert(err).IsNil()
+
+			sums, err := fs.Checksums("test.txt", []string{ChecksumMD5, ChecksumSHA1, ChecksumSHA256, ChecksumXXH64})
+			g.Assert(err).IsNil()
+			g.Assert(sums[ChecksumMD5]).Equal("5eb63bbbe01eeed093cb22bb8f5acdc3")
+			g.Assert(sums[ChecksumSHA1]).Equal("2aae6c35c94fcfb415dbe95f408b9ce91ee846ed")
+			g.Assert(sums[ChecksumSHA256]).Equal("b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9")
+			g.Assert(sums[ChecksumXXH64]).Equal("45ab6734b21e6968")
+		})
+
+		g.It("does not compute the checksum of a directory", func() {
+			err := fs.CreateDirectory("test", "/")
+			g.Assert(err).IsNil()
+
+			_, err = fs.Checksums("test", []string{ChecksumSHA256})
+			g.Assert(IsErrorCode(err, ErrCodeIsDirectory)).IsTrue()
+		})
+
+		g.It("rejects unknown algorithms", func() {
+			err := rfs.CreateServerFileFromString("test.txt", "hello world")
+			g.Assert(err).IsNil()
+
+			_, err = fs.Checksums("test.txt", []string{"crc32"})
+			g.Assert(IsErrorCode(err, ErrCodeNotSupported)).IsTrue()
+		})
+	})
+}
@@ -0,0 +1,152 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"sync/atomic"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/karrick/godirwalk"
+	"golang.org/x/sync/errgroup"
+)
+
+// The number of files checked between each progress message logged while
+// changing the owner of a directory.
+const chownProgressInterval = 100_000
+
+// chownFunc changes the owner of the file at the given path, returning true if
+// the owner was changed or false if the file was already owned correctly.
+type chownFunc func(p string) (bool, error)
+
+// ChownOptions controls how much of a directory Chown walks through.
+type ChownOptions struct {
+	// If true only the directory and the files directly within it are changed,
+	// rather than every file within the directory.
+	TopLevel bool
+	// The number of workers used to check and change the owner of files, if zero
+	// one worker is used for every CPU core.
+	Workers int
+	// If set this is called with the number of files checked each time more files
+	// have been checked, which may be from multiple goroutines at once.
+	Progress func(n int64)
+}
+
+// Chown recursively iterates over a file or directory and sets the owner of
+// all the underlying files to the user the server runs as.
+func (fs *Filesystem) Chown(path string) error {
+	return fs.ChownWithOptions(path, ChownOptions{})
+}
+
+// ChownWithOptions sets the owner of the given file or directory to the user
+// the server runs as. If the path is a directory the files within it are also
+// changed, either recursively or only the top level depending on the options.
+func (fs *Filesystem) ChownWithOptions(path string, opts ChownOptions) error {
+	cleaned, err := fs.SafePath(path)
+	if err != nil {
+		return err
+	}
+
+	if fs.isTest {
+		return nil
+	}
+
+	chown, err := fs.chownFunc()
+	if err != nil {
+		return err
+	}
+
+	// If this is not a directory we only need to change the owner of the path
+	// that was received.
+	if st, err := os.Stat(cleaned); err != nil || !st.IsDir() {
+		if _, err := chown(cleaned); err != nil {
+			return errors.Wrap(err, "server/filesystem: chown: failed to chown path")
+		}
+		return nil
+	}
+
+	// If this was a directory, walk over its contents and ensure that all of the
+	// subfiles and directories get their permissions updated as well.
+	err = fs.chownTree(cleaned, opts, chown)
+
+	return errors.Wrap(err, "server/filesystem: chown: failed to chown during walk function")
+}
+
+// chownTree walks the given directory and calls chown on every file and
+// directory within it, including the directory itself. The walk is done in a
+// single goroutine and the files are passed off to a pool of workers since
+// checking the owner of each file is where most of the time is spent.
+//
+// Symlinks are never followed or changed, since they could point to a location
+// outside the data directory.
+func (fs *Filesystem) chownTree(root string, opts ChownOptions, chown chownFunc) error {
+	logger := log.WithField("subsystem", "filesystem").WithField("root", fs.root)
+
+	// At least two workers are always used so that the walk itself is never the
+	// bottleneck.
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers < 2 {
+		workers = 2
+	}
+
+	var checked, changed int64
+	paths := make(chan string, workers*64)
+	g, ctx := errgroup.WithContext(context.Background())
+	for i := 0; i < workers; i++ {
+		g.Go(func() error {
+			for p := range paths {
+				ok, err := chown(p)
+				if err != nil {
+					// The file was removed after it was found by the walk.
+					if errors.Is(err, os.ErrNotExist) {
+						continue
+					}
+					return err
+				}
+				if ok {
+					atomic.AddInt64(&changed, 1)
+				}
+				if opts.Progress != nil {
+					opts.Progress(1)
+				}
+				if n := atomic.AddInt64(&checked, 1); n%chownProgressInterval == 0 {
+					logger.WithField("checked", n).WithField("changed", atomic.LoadInt64(&changed)).Info("checking file ownership...")
+				}
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		defer close(paths)
+		return godirwalk.Walk(root, &godirwalk.Options{
+			Unsorted: true,
+			Callback: func(p string, e *godirwalk.Dirent) error {
+				if e.IsSymlink() {
+					if e.IsDir() {
+						return godirwalk.SkipThis
+					}
+					return nil
+				}
+				select {
+				case paths <- p:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				// Directories within the top level are changed, but not walked into.
+				if opts.TopLevel && e.IsDir() && p != root {
+					return godirwalk.SkipThis
+				}
+				return nil
+			},
+		})
+	})
+
+	err := g.Wait()
+	logger.WithField("checked", checked).WithField("changed", changed).Debug("finished checking file ownership")
+	return err
+}
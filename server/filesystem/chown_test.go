@@ -0,0 +1,77 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestFilesystem_chownTree(t *testing.T) {
+	g := Goblin(t)
+	fs, rfs := NewFs()
+
+	g.Describe("chownTree", func() {
+		g.BeforeEach(func() {
+			rfs.reset()
+
+			_ = os.MkdirAll(filepath.Join(rfs.root, "/server/foo/bar"), 0o755)
+			_ = rfs.CreateServerFileFromString("a.txt", "a")
+			_ = rfs.CreateServerFileFromString("foo/bar/b.txt", "b")
+			_ = os.Symlink(filepath.Join(rfs.root, "/server/foo"), filepath.Join(rfs.root, "/server/link"))
+		})
+
+		g.It("calls the function for every file except symlinks", func() {
+			var mu sync.Mutex
+			var seen []string
+			err := fs.chownTree(fs.Path(), ChownOptions{}, func(p string) (bool, error) {
+				mu.Lock()
+				seen = append(seen, strings.TrimPrefix(p, fs.Path()))
+				mu.Unlock()
+				return false, nil
+			})
+			g.Assert(err).IsNil()
+
+			sort.Strings(seen)
+			g.Assert(seen).Equal([]string{"", "/a.txt", "/foo", "/foo/bar", "/foo/bar/b.txt"})
+		})
+
+		g.It("only walks the top level of the directory", func() {
+			var mu sync.Mutex
+			var seen []string
+			err := fs.chownTree(fs.Path(), ChownOptions{TopLevel: true, Workers: 1}, func(p string) (bool, error) {
+				mu.Lock()
+				seen = append(seen, strings.TrimPrefix(p, fs.Path()))
+				mu.Unlock()
+				return false, nil
+			})
+			g.Assert(err).IsNil()
+
+			sort.Strings(seen)
+			g.Assert(seen).Equal([]string{"", "/a.txt", "/foo"})
+		})
+
+		g.It("ignores files removed during the walk", func() {
+			err := fs.chownTree(fs.Path(), ChownOptions{}, func(p string) (bool, error) {
+				return false, &os.PathError{Op: "lstat", Path: p, Err: os.ErrNotExist}
+			})
+			g.Assert(err).IsNil()
+		})
+
+		g.It("returns the first error encountered", func() {
+			err := fs.chownTree(fs.Path(), ChownOptions{}, func(p string) (bool, error) {
+				return false, os.ErrPermission
+			})
+			g.Assert(err).IsNotNil()
+			g.Assert(err).Equal(os.ErrPermission)
+		})
+
+		g.AfterEach(func() {
+			rfs.reset()
+		})
+	})
+}
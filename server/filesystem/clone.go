@@ -0,0 +1,296 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"emperror.dev/errors"
+	"github.com/karrick/godirwalk"
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// CloneTo copies all the files for this Filesystem instance into the root of the
+// destination Filesystem, skipping any paths that match the ignore patterns. The
+// patterns use the same format as a .gitignore file. Files are cloned using
+// copy-on-write when the underlying filesystem supports it, such as reflinks on
+// Btrfs and XFS, or block cloning on ReFS, otherwise they are copied normally.
+// The disk limit of the destination is checked before each file is written.
+//
+// The returned boolean indicates if every file was cloned using copy-on-write.
+func (fs *Filesystem) CloneTo(ctx context.Context, dst *Filesystem, ignored []string) (bool, error) {
+	cow, err := copyTree(ctx, fs.Path(), dst.Path(), ignore.CompileIgnoreLines(ignored...), dst)
+	if err != nil {
+		return false, err
+	}
+
+	return cow, dst.Chown("/")
+}
+
+// CloneDirectory copies the contents of the src directory into the dst directory
+// in the same way as CloneTo, for directories that do not belong to a server such
+// as local backup snapshots. The dst directory is created if it does not exist.
+func CloneDirectory(ctx context.Context, src string, dst string, ignored []string) (bool, error) {
+	if err := os.MkdirAll(dst, 0o700); err != nil {
+		return false, errors.Wrap(err, "server/filesystem: clone: failed to create directory")
+	}
+	return copyTree(ctx, src, dst, ignore.CompileIgnoreLines(ignored...), nil)
+}
+
+// CloneFrom copies the contents of the src directory, such as a local backup
+// snapshot, into the root of this Filesystem instance. Unlike CloneDirectory
+// every target is resolved within the root directory and existing symlinks are
+// never written through, files matching the denylist or the write denylist are
+// skipped, and the disk limit is checked before each file is written.
+//
+// The returned boolean indicates if every file was cloned using copy-on-write.
+func (fs *Filesystem) CloneFrom(ctx context.Context, src string) (bool, error) {
+	cow := true
+
+	err := godirwalk.Walk(src, &godirwalk.Options{
+		FollowSymbolicLinks: false,
+		Unsorted:            true,
+		Callback: func(p string, de *godirwalk.Dirent) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if p == src {
+				return nil
+			}
+			relative := filepath.ToSlash(strings.TrimPrefix(p, src+string(filepath.Separator)))
+			st, err := os.Lstat(p)
+			if err != nil {
+				return errors.WithStackIf(err)
+			}
+
+			// Symlinks are created in place of the entry itself, so only the directory
+			// they are created in needs to be checked.
+			isLink := st.Mode()&os.ModeSymlink != 0
+			check := relative
+			if isLink {
+				check = filepath.ToSlash(filepath.Dir(relative))
+			}
+			if _, err := fs.writeTarget(check); err != nil {
+				return err
+			}
+			target := fs.unsafeFilePath(relative)
+
+			if err := fs.IsIgnored(relative); err != nil {
+				if IsErrorCode(err, ErrCodeDenylistFile) {
+					return skipDir(st)
+				}
+				return err
+			}
+			if err := fs.isWriteDenied(relative, st.IsDir()); err != nil {
+				if IsErrorCode(err, ErrCodeWriteDenied) {
+					return skipDir(st)
+				}
+				return err
+			}
+
+			switch {
+			case st.IsDir():
+				if err := os.MkdirAll(target, st.Mode().Perm()); err != nil {
+					return errors.Wrap(err, "server/filesystem: clone: failed to create directory")
+				}
+			case isLink:
+				link, err := os.Readlink(p)
+				if err != nil {
+					return errors.WithStackIf(err)
+				}
+				if err := os.Symlink(link, target); err != nil && !os.IsExist(err) {
+					return errors.Wrap(err, "server/filesystem: clone: failed to create symlink")
+				}
+			case st.Mode().IsRegular():
+				var current int64
+				if existing, err := os.Lstat(target); err == nil {
+					if existing.IsDir() {
+						return errors.WithStack(&Error{code: ErrCodeIsDirectory, path: relative, resolved: target})
+					}
+					current = existing.Size()
+				} else if !os.IsNotExist(err) {
+					return errors.WithStackIf(err)
+				}
+				if err := fs.HasSpaceFor(st.Size() - current); err != nil {
+					return err
+				}
+				cloned, err := cloneFile(p, target, st, nil)
+				if err != nil {
+					return err
+				}
+				fs.addDisk(st.Size() - current)
+				cow = cow && cloned
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return cow, fs.Chown("/")
+}
+
+// skipDir returns godirwalk.SkipThis for directories so that nothing within
+// them is walked, and nil for everything else.
+func skipDir(st os.FileInfo) error {
+	if st.IsDir() {
+		return godirwalk.SkipThis
+	}
+	return nil
+}
+
+// cloneSupport caches the result of probing each pair of directories for
+// copy-on-write support, since this does not change while Wings is running.
+var cloneSupport sync.Map
+
+// SupportsCloning returns true if files in the src directory can be cloned into
+// the dst directory using copy-on-write, such as when both directories are on
+// the same ReFS volume or XFS filesystem with reflinks enabled. This is checked
+// by cloning a small temporary file between the directories.
+func SupportsCloning(src string, dst string) bool {
+	key := src + string(os.PathListSeparator) + dst
+	if v, ok := cloneSupport.Load(key); ok {
+		return v.(bool)
+	}
+	ok := probeCloning(src, dst)
+	cloneSupport.Store(key, ok)
+	return ok
+}
+
+func probeCloning(src string, dst string) bool {
+	in, err := os.CreateTemp(src, ".wings-clone-*")
+	if err != nil {
+		return false
+	}
+	defer os.Remove(in.Name())
+	defer in.Close()
+
+	// Block cloning on ReFS works on whole clusters, so write enough data to fill
+	// at least one of them.
+	buf := make([]byte, 64*1024)
+	if _, err := in.Write(buf); err != nil {
+		return false
+	}
+	if err := in.Sync(); err != nil {
+		return false
+	}
+
+	out, err := os.CreateTemp(dst, ".wings-clone-*")
+	if err != nil {
+		return false
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	return reflink(in, out, int64(len(buf))) == nil
+}
+
+// copyTree copies the contents of the root directory into the target directory,
+// skipping any paths matching the ignore patterns. Symlinks are copied as-is
+// rather than being followed. If a limit is provided the files are counted
+// against its disk usage, and an error is returned once it has no space left.
+// Returns true if every file was cloned using copy-on-write.
+func copyTree(ctx context.Context, root string, dst string, i *ignore.GitIgnore, limit *Filesystem) (bool, error) {
+	cow := true
+
+	err := godirwalk.Walk(root, &godirwalk.Options{
+		FollowSymbolicLinks: false,
+		Unsorted:            true,
+		Callback: func(p string, de *godirwalk.Dirent) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if p == root {
+				return nil
+			}
+			relative := filepath.ToSlash(strings.TrimPrefix(p, root+string(filepath.Separator)))
+			// Directory patterns such as "logs/" only match a path with a trailing slash.
+			if i.MatchesPath(relative) || (de.IsDir() && i.MatchesPath(relative+"/")) {
+				return godirwalk.SkipThis
+			}
+
+			target := filepath.Join(dst, filepath.FromSlash(relative))
+			st, err := os.Lstat(p)
+			if err != nil {
+				return errors.WithStackIf(err)
+			}
+
+			switch {
+			case st.IsDir():
+				if err := os.MkdirAll(target, st.Mode().Perm()); err != nil {
+					return errors.Wrap(err, "server/filesystem: clone: failed to create directory")
+				}
+			case st.Mode()&os.ModeSymlink != 0:
+				link, err := os.Readlink(p)
+				if err != nil {
+					return errors.WithStackIf(err)
+				}
+				if err := os.Symlink(link, target); err != nil && !os.IsExist(err) {
+					return errors.Wrap(err, "server/filesystem: clone: failed to create symlink")
+				}
+			case st.Mode().IsRegular():
+				if limit != nil {
+					if err := limit.HasSpaceFor(st.Size()); err != nil {
+						return err
+					}
+				}
+				cloned, err := cloneFile(p, target, st, nil)
+				if err != nil {
+					return err
+				}
+				if limit != nil {
+					limit.addDisk(st.Size())
+				}
+				cow = cow && cloned
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return cow, nil
+}
+
+// cloneFile copies a single file to the target path, attempting to use
+// copy-on-write first. Returns true if the file was cloned using copy-on-write.
+// The progress is optional and is updated as the file is copied.
+func cloneFile(src string, target string, st os.FileInfo, progress *Progress) (bool, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return false, errors.WithStackIf(err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, st.Mode().Perm())
+	if err != nil {
+		return false, errors.Wrap(err, "server/filesystem: clone: failed to create file")
+	}
+	defer out.Close()
+
+	if st.Size() > 0 && reflink(in, out, st.Size()) == nil {
+		progress.add(st.Size())
+		return true, nil
+	}
+
+	// Copy-on-write is not supported, or failed part way through, so truncate the file
+	// and copy the contents normally.
+	if err := out.Truncate(0); err != nil {
+		return false, errors.WithStackIf(err)
+	}
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		return false, errors.WithStackIf(err)
+	}
+	var r io.Reader = in
+	if progress != nil {
+		r = io.TeeReader(in, progress)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		return false, errors.Wrap(err, "server/filesystem: clone: failed to copy file")
+	}
+	return st.Size() == 0, nil
+}
@@ -0,0 +1,20 @@
+package filesystem
+
+import (
+	"os"
+
+	"emperror.dev/errors"
+	"golang.org/x/sys/unix"
+)
+
+// reflink clones the contents of the source file into the destination file using
+// the FICLONE ioctl, which is supported by filesystems such as Btrfs and XFS.
+func reflink(src *os.File, dst *os.File, _ int64) error {
+	return unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+}
+
+// isCrossDevice returns true if a rename failed because the source and target
+// are on different filesystems.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, unix.EXDEV)
+}
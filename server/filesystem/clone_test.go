@@ -0,0 +1,189 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestFilesystem_CloneTo(t *testing.T) {
+	g := Goblin(t)
+	fs, rfs := NewFs()
+
+	g.Describe("CloneTo", func() {
+		var dst *Filesystem
+
+		g.BeforeEach(func() {
+			rfs.reset()
+
+			dir, err := os.MkdirTemp(os.TempDir(), "pterodactyl-clone")
+			g.Assert(err).IsNil()
+			dst = New(dir, 0, []string{})
+			dst.isTest = true
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(dst.Path())
+		})
+
+		g.It("copies files and directories to the destination", func() {
+			err := os.MkdirAll(filepath.Join(rfs.root, "/server/world/region"), 0o755)
+			g.Assert(err).IsNil()
+			err = rfs.CreateServerFileFromString("world/region/r.0.0.mca", "region data")
+			g.Assert(err).IsNil()
+			err = rfs.CreateServerFileFromString("server.properties", "motd=test")
+			g.Assert(err).IsNil()
+
+			_, err = fs.CloneTo(context.Background(), dst, nil)
+			g.Assert(err).IsNil()
+
+			b, err := os.ReadFile(filepath.Join(dst.Path(), "world/region/r.0.0.mca"))
+			g.Assert(err).IsNil()
+			g.Assert(string(b)).Equal("region data")
+
+			b, err = os.ReadFile(filepath.Join(dst.Path(), "server.properties"))
+			g.Assert(err).IsNil()
+			g.Assert(string(b)).Equal("motd=test")
+		})
+
+		g.It("skips paths matching the ignore patterns", func() {
+			err := os.MkdirAll(filepath.Join(rfs.root, "/server/logs"), 0o755)
+			g.Assert(err).IsNil()
+			err = rfs.CreateServerFileFromString("logs/latest.log", "log")
+			g.Assert(err).IsNil()
+			err = rfs.CreateServerFileFromString("server.jar", "jar")
+			g.Assert(err).IsNil()
+			err = rfs.CreateServerFileFromString("crash.log", "crash")
+			g.Assert(err).IsNil()
+
+			_, err = fs.CloneTo(context.Background(), dst, []string{"logs/", "*.log"})
+			g.Assert(err).IsNil()
+
+			_, err = os.Stat(filepath.Join(dst.Path(), "logs"))
+			g.Assert(os.IsNotExist(err)).IsTrue()
+			_, err = os.Stat(filepath.Join(dst.Path(), "crash.log"))
+			g.Assert(os.IsNotExist(err)).IsTrue()
+			_, err = os.Stat(filepath.Join(dst.Path(), "server.jar"))
+			g.Assert(err).IsNil()
+		})
+
+		g.It("returns an error when the files do not fit within the destination disk limit", func() {
+			dst.diskLimit = 4
+			err := rfs.CreateServerFileFromString("big.dat", "more than four bytes")
+			g.Assert(err).IsNil()
+
+			_, err = fs.CloneTo(context.Background(), dst, nil)
+			g.Assert(err).IsNotNil()
+			g.Assert(IsErrorCode(err, ErrCodeDiskSpace)).IsTrue()
+
+			_, err = os.Stat(filepath.Join(dst.Path(), "big.dat"))
+			g.Assert(os.IsNotExist(err)).IsTrue()
+		})
+	})
+
+	g.Describe("CloneFrom", func() {
+		var src string
+
+		g.BeforeEach(func() {
+			rfs.reset()
+			fs.SetWriteDenylist(nil)
+			fs.diskLimit = 0
+
+			dir, err := os.MkdirTemp(os.TempDir(), "pterodactyl-snapshot")
+			g.Assert(err).IsNil()
+			src = dir
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(src)
+		})
+
+		g.It("copies files into the root directory", func() {
+			err := os.MkdirAll(filepath.Join(src, "world"), 0o755)
+			g.Assert(err).IsNil()
+			err = os.WriteFile(filepath.Join(src, "world/level.dat"), []byte("level"), 0o644)
+			g.Assert(err).IsNil()
+
+			_, err = fs.CloneFrom(context.Background(), src)
+			g.Assert(err).IsNil()
+
+			b, err := os.ReadFile(filepath.Join(rfs.root, "/server/world/level.dat"))
+			g.Assert(err).IsNil()
+			g.Assert(string(b)).Equal("level")
+		})
+
+		g.It("does not write through a symlinked directory in the root", func() {
+			outside := filepath.Join(rfs.root, "outside")
+			err := os.MkdirAll(outside, 0o755)
+			g.Assert(err).IsNil()
+			err = os.WriteFile(filepath.Join(outside, "level.dat"), []byte("original"), 0o644)
+			g.Assert(err).IsNil()
+			err = os.Symlink(outside, filepath.Join(rfs.root, "/server/world"))
+			g.Assert(err).IsNil()
+
+			err = os.MkdirAll(filepath.Join(src, "world"), 0o755)
+			g.Assert(err).IsNil()
+			err = os.WriteFile(filepath.Join(src, "world/level.dat"), []byte("level"), 0o644)
+			g.Assert(err).IsNil()
+
+			_, err = fs.CloneFrom(context.Background(), src)
+			g.Assert(err).IsNotNil()
+			g.Assert(IsErrorCode(err, ErrCodePathResolution)).IsTrue()
+
+			b, err := os.ReadFile(filepath.Join(outside, "level.dat"))
+			g.Assert(err).IsNil()
+			g.Assert(string(b)).Equal("original")
+		})
+
+		g.It("does not write through a symlinked file in the root", func() {
+			err := os.WriteFile(filepath.Join(rfs.root, "/server/config.yml"), []byte("original"), 0o644)
+			g.Assert(err).IsNil()
+			err = os.Symlink(filepath.Join(rfs.root, "/server/config.yml"), filepath.Join(rfs.root, "/server/link.yml"))
+			g.Assert(err).IsNil()
+			err = os.WriteFile(filepath.Join(src, "link.yml"), []byte("changed"), 0o644)
+			g.Assert(err).IsNil()
+
+			_, err = fs.CloneFrom(context.Background(), src)
+			g.Assert(err).IsNotNil()
+
+			b, err := os.ReadFile(filepath.Join(rfs.root, "/server/config.yml"))
+			g.Assert(err).IsNil()
+			g.Assert(string(b)).Equal("original")
+		})
+
+		g.It("skips files matching the write denylist", func() {
+			fs.SetWriteDenylist([]string{"*.jar"})
+			err := rfs.CreateServerFileFromString("server.jar", "original")
+			g.Assert(err).IsNil()
+			err = os.WriteFile(filepath.Join(src, "server.jar"), []byte("changed"), 0o644)
+			g.Assert(err).IsNil()
+			err = os.WriteFile(filepath.Join(src, "server.properties"), []byte("motd=test"), 0o644)
+			g.Assert(err).IsNil()
+
+			_, err = fs.CloneFrom(context.Background(), src)
+			g.Assert(err).IsNil()
+
+			b, err := os.ReadFile(filepath.Join(rfs.root, "/server/server.jar"))
+			g.Assert(err).IsNil()
+			g.Assert(string(b)).Equal("original")
+			_, err = rfs.StatServerFile("server.properties")
+			g.Assert(err).IsNil()
+		})
+
+		g.It("returns an error when the files do not fit within the disk limit", func() {
+			fs.diskLimit = 4
+			err := os.WriteFile(filepath.Join(src, "big.dat"), []byte("more than four bytes"), 0o644)
+			g.Assert(err).IsNil()
+
+			_, err = fs.CloneFrom(context.Background(), src)
+			g.Assert(err).IsNotNil()
+			g.Assert(IsErrorCode(err, ErrCodeDiskSpace)).IsTrue()
+
+			_, err = rfs.StatServerFile("big.dat")
+			g.Assert(os.IsNotExist(err)).IsTrue()
+		})
+	})
+}
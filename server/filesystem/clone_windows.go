@@ -0,0 +1,97 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"emperror.dev/errors"
+	"golang.org/x/sys/windows"
+)
+
+var procGetDiskFreeSpaceW = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetDiskFreeSpaceW")
+
+// duplicateExtentsData is the DUPLICATE_EXTENTS_DATA structure used for block
+// cloning on ReFS volumes.
+type duplicateExtentsData struct {
+	FileHandle       windows.Handle
+	SourceFileOffset int64
+	TargetFileOffset int64
+	ByteCount        int64
+}
+
+// The maximum number of bytes that can be cloned with a single request.
+const maxCloneChunk = 1 << 31
+
+// reflink clones the contents of the source file into the destination file using
+// block cloning, which is only supported on ReFS volumes. Both files must be on
+// the same volume.
+func reflink(src *os.File, dst *os.File, size int64) error {
+	cluster, err := clusterSize(dst.Name())
+	if err != nil {
+		return err
+	}
+	// Block cloning requires the destination to already be the correct size, and for
+	// the cloned regions to be aligned to the cluster size of the volume.
+	if err := dst.Truncate(size); err != nil {
+		return errors.WithStackIf(err)
+	}
+	aligned := (size + cluster - 1) / cluster * cluster
+	for offset := int64(0); offset < aligned; offset += maxCloneChunk {
+		n := aligned - offset
+		if n > maxCloneChunk {
+			n = maxCloneChunk
+		}
+		d := duplicateExtentsData{
+			FileHandle:       windows.Handle(src.Fd()),
+			SourceFileOffset: offset,
+			TargetFileOffset: offset,
+			ByteCount:        n,
+		}
+		var returned uint32
+		if err := windows.DeviceIoControl(
+			windows.Handle(dst.Fd()),
+			windows.FSCTL_DUPLICATE_EXTENTS_TO_FILE,
+			(*byte)(unsafe.Pointer(&d)),
+			uint32(unsafe.Sizeof(d)),
+			nil,
+			0,
+			&returned,
+			nil,
+		); err != nil {
+			return errors.WithStackIf(err)
+		}
+	}
+	return nil
+}
+
+// clusterSize returns the size in bytes of a cluster on the volume containing the
+// given path.
+func clusterSize(path string) (int64, error) {
+	p, err := windows.UTF16PtrFromString(filepath.Dir(path))
+	if err != nil {
+		return 0, err
+	}
+	buf := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(p, &buf[0], uint32(len(buf))); err != nil {
+		return 0, errors.WithStackIf(err)
+	}
+	var sectors, bytes, free, total uint32
+	r, _, err := procGetDiskFreeSpaceW.Call(
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&sectors)),
+		uintptr(unsafe.Pointer(&bytes)),
+		uintptr(unsafe.Pointer(&free)),
+		uintptr(unsafe.Pointer(&total)),
+	)
+	if r == 0 {
+		return 0, errors.WithStackIf(err)
+	}
+	return int64(sectors) * int64(bytes), nil
+}
+
+// isCrossDevice returns true if a rename failed because the source and target
+// are on different volumes.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, windows.ERROR_NOT_SAME_DEVICE)
+}
@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"os"
 	"path"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"emperror.dev/errors"
+	"github.com/apex/log"
 	"github.com/mholt/archiver/v3"
 )
 
@@ -47,7 +49,7 @@ func (fs *Filesystem) CompressFiles(dir string, paths []string) (os.FileInfo, er
 		fmt.Sprintf("archive-%s.tar.gz", strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "")),
 	)
 
-	if err := a.Create(d); err != nil {
+	if err := a.Create(context.Background(), d); err != nil {
 		return nil, err
 	}
 
@@ -70,17 +72,24 @@ func (fs *Filesystem) CompressFiles(dir string, paths []string) (os.FileInfo, er
 // SpaceAvailableForDecompression looks through a given archive and determines
 // if decompressing it would put the server over its allocated disk space limit.
 func (fs *Filesystem) SpaceAvailableForDecompression(dir string, file string) error {
+	source, err := fs.SafePath(filepath.Join(dir, file))
+	if err != nil {
+		return err
+	}
+
+	return fs.spaceAvailableForArchive(source)
+}
+
+// spaceAvailableForArchive determines if decompressing the archive at the given
+// path, which does not need to be within the server data directory, would put the
+// server over its allocated disk space limit.
+func (fs *Filesystem) spaceAvailableForArchive(source string) error {
 	// Don't waste time trying to determine this if we know the server will have the space for
 	// it since there is no limit.
 	if fs.MaxDisk() <= 0 {
 		return nil
 	}
 
-	source, err := fs.SafePath(filepath.Join(dir, file))
-	if err != nil {
-		return err
-	}
-
 	// Get the cached size in a parallel process so that if it is not cached we are not
 	// waiting an unnecessary amount of time on this call.
 	dirSize, err := fs.DiskUsage(false)
@@ -107,29 +116,48 @@ func (fs *Filesystem) SpaceAvailableForDecompression(dir string, file string) er
 // all of the files within the given archive and ensure that there is not a
 // zip-slip attack being attempted by validating that the final path is within
 // the server data directory.
-func (fs *Filesystem) DecompressFile(dir string, file string) error {
+//
+// Returns the files that were renamed or skipped because their names conflicted
+// with another file in the archive, or could not be used on this system.
+func (fs *Filesystem) DecompressFile(dir string, file string) ([]NameConflict, error) {
 	source, err := fs.SafePath(filepath.Join(dir, file))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// Ensure that the source archive actually exists on the system.
 	if _, err := os.Stat(source); err != nil {
-		return errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 
+	return fs.extractArchive(source, dir)
+}
+
+// extractArchive extracts the archive at the given source path, which does not
+// need to be within the server data directory, into a directory of the server.
+func (fs *Filesystem) extractArchive(source string, dir string) ([]NameConflict, error) {
+	names := NewNameResolver()
 	// Walk all of the files in the archiver file and write them to the disk. If any
 	// directory is encountered it will be skipped since we handle creating any missing
 	// directories automatically when writing files.
-	err = archiver.Walk(source, func(f archiver.File) error {
+	err := archiver.Walk(source, func(f archiver.File) error {
 		if f.IsDir() {
 			return nil
 		}
-		p := filepath.Join(dir, ExtractNameFromArchive(f))
+		name, ok := names.Resolve(ExtractNameFromArchive(f))
+		if !ok {
+			return nil
+		}
+		p := filepath.Join(dir, name)
 		// If it is ignored, just don't do anything with the file and skip over it.
 		if err := fs.IsIgnored(p); err != nil {
 			return nil
 		}
-		if err := fs.Writefile(p, f); err != nil {
+		if err := fs.IsWriteDenied(p); err != nil {
+			return nil
+		}
+		// Files are written as sparse files so that any preallocated regions in them,
+		// such as those in game world files, do not take up space on the disk.
+		if err := fs.writefile(p, f, true); err != nil {
 			return wrapError(err, source)
 		}
 		// Update the file permissions to the one set in the archive.
@@ -144,11 +172,25 @@ func (fs *Filesystem) DecompressFile(dir string, file string) error {
 	})
 	if err != nil {
 		if IsUnknownArchiveFormatError(err) {
-			return newFilesystemError(ErrCodeUnknownArchive, err)
+			return nil, newFilesystemError(ErrCodeUnknownArchive, err)
 		}
-		return err
+		return nil, err
+	}
+	return names.Conflicts(), nil
+}
+
+// logNameConflicts logs each of the files that were renamed or skipped when
+// extracting an archive.
+func (fs *Filesystem) logNameConflicts(conflicts []NameConflict) {
+	for _, c := range conflicts {
+		log.WithFields(log.Fields{
+			"subsystem": "filesystem",
+			"root":      fs.root,
+			"name":      c.Name,
+			"resolved":  c.Resolved,
+			"reason":    c.Reason,
+		}).Warn("renamed or skipped file with a conflicting name when extracting archive")
 	}
-	return nil
 }
 
 // ExtractNameFromArchive looks at an archive file to try and determine the name
@@ -0,0 +1,55 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// CompressionResult contains the number of files that were compressed, and the
+// space they took up on the disk before and after they were compressed. Files
+// that could not be compressed, such as those that are in use or that would not
+// get any smaller, are counted as skipped.
+type CompressionResult struct {
+	Files   int   `json:"files"`
+	Skipped int   `json:"skipped"`
+	Before  int64 `json:"before"`
+	After   int64 `json:"after"`
+}
+
+// Compress compresses every file within the server data directory using the
+// given algorithm. This should only be run while the server is offline, since
+// files that are open by the server cannot be compressed. Compression is only
+// supported on Windows, an ErrCodeNotSupported error is returned on every other
+// system.
+func (fs *Filesystem) Compress(ctx context.Context, algorithm string) (CompressionResult, error) {
+	if !config.IsVolumeCompression(algorithm) {
+		return CompressionResult{}, errors.Errorf("server/filesystem: compress: unknown algorithm \"%s\"", algorithm)
+	}
+	res, err := compressDirectory(ctx, fs.Path(), algorithm)
+	if err != nil {
+		return res, err
+	}
+	if _, err := fs.updateCachedDiskUsage(); err != nil {
+		fs.error(err).Warn("failed to update disk usage after compressing files")
+	}
+	return res, nil
+}
+
+// EnableCompression marks the server data directory so that files written to it
+// are compressed using the given algorithm. Only NTFS compression is applied to
+// new files, Compact OS style compression must be applied using Compress, so
+// this does nothing for those algorithms. Nothing is done if the data directory
+// has not been created yet.
+func (fs *Filesystem) EnableCompression(algorithm string) error {
+	if _, err := os.Stat(fs.Path()); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.WithStackIf(err)
+	}
+	return enableCompression(fs.Path(), algorithm)
+}
@@ -0,0 +1,14 @@
+package filesystem
+
+import (
+	"context"
+)
+
+func compressDirectory(_ context.Context, _ string, _ string) (CompressionResult, error) {
+	return CompressionResult{}, newFilesystemError(ErrCodeNotSupported, nil)
+}
+
+// enableCompression does nothing since volume compression is ignored on Linux.
+func enableCompression(_ string, _ string) error {
+	return nil
+}
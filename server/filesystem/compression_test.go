@@ -0,0 +1,34 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestFilesystem_Compression(t *testing.T) {
+	g := Goblin(t)
+	fs, rfs := NewFs()
+
+	g.Describe("EnableCompression", func() {
+		g.It("does nothing if the data directory has not been created", func() {
+			missing := New(filepath.Join(rfs.root, "missing"), 0, []string{})
+			g.Assert(missing.EnableCompression(config.VolumeCompressionNTFS)).IsNil()
+
+			_, err := os.Stat(missing.Path())
+			g.Assert(os.IsNotExist(err)).IsTrue()
+		})
+	})
+
+	g.Describe("Compress", func() {
+		g.It("rejects unknown algorithms", func() {
+			_, err := fs.Compress(context.Background(), "gzip")
+			g.Assert(err != nil).IsTrue()
+		})
+	})
+}
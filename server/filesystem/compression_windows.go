@@ -0,0 +1,133 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"golang.org/x/sys/windows"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+const (
+	// The COMPRESSION_FORMAT_DEFAULT format used for NTFS compression, which is
+	// currently LZNT1.
+	compressionFormatDefault = 1
+
+	fsctlSetExternalBacking    = 0x9030C
+	wofCurrentVersion          = 1
+	wofProviderFile            = 2
+	fileProviderCurrentVersion = 1
+)
+
+// The FILE_PROVIDER_COMPRESSION values for each of the Compact OS algorithms.
+var wofAlgorithms = map[string]uint32{
+	config.VolumeCompressionXpress4K:  0,
+	config.VolumeCompressionLZX:       1,
+	config.VolumeCompressionXpress8K:  2,
+	config.VolumeCompressionXpress16K: 3,
+}
+
+// wofExternalBacking is the WOF_EXTERNAL_INFO structure followed by the
+// FILE_PROVIDER_EXTERNAL_INFO_V1 structure, which together are used to compress
+// a file using the Windows Overlay Filter.
+type wofExternalBacking struct {
+	WofVersion      uint32
+	WofProvider     uint32
+	ProviderVersion uint32
+	Algorithm       uint32
+	Flags           uint32
+}
+
+// compressDirectory compresses every regular file within the directory. When
+// using NTFS compression the directories are also marked as compressed so that
+// new files written to them are compressed. Symlinks and junctions are not
+// followed.
+func compressDirectory(ctx context.Context, root string, algorithm string) (CompressionResult, error) {
+	var res CompressionResult
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// A directory that cannot be marked as compressed only means new files in it
+			// are not compressed, so the files already within it are still compressed.
+			if algorithm == config.VolumeCompressionNTFS {
+				if err := compressPath(p, algorithm, true); err != nil {
+					log.WithField("path", p).WithField("error", err).Warn("failed to mark directory as compressed, skipping...")
+				}
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		before, err := allocatedSize(p)
+		if err != nil {
+			before = info.Size()
+		}
+		if err := compressPath(p, algorithm, false); err != nil {
+			log.WithField("path", p).WithField("error", err).Debug("failed to compress file, skipping...")
+			res.Skipped++
+			return nil
+		}
+		after, err := allocatedSize(p)
+		if err != nil {
+			after = before
+		}
+		res.Files++
+		res.Before += before
+		res.After += after
+		return nil
+	})
+	return res, errors.WrapIf(err, "server/filesystem: compress: failed to walk directory")
+}
+
+// enableCompression marks the directory as compressed when using NTFS
+// compression, so that new files written to it are compressed.
+func enableCompression(root string, algorithm string) error {
+	if algorithm != config.VolumeCompressionNTFS {
+		return nil
+	}
+	return compressPath(root, algorithm, true)
+}
+
+// compressPath compresses the file or directory at the given path. Files that
+// are open for writing by another process cannot be compressed.
+func compressPath(p string, algorithm string, dir bool) error {
+	p16, err := windows.UTF16PtrFromString(p)
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+	var flags uint32 = windows.FILE_ATTRIBUTE_NORMAL
+	if dir {
+		flags = windows.FILE_FLAG_BACKUP_SEMANTICS
+	}
+	h, err := windows.CreateFile(p16, windows.GENERIC_READ|windows.GENERIC_WRITE, windows.FILE_SHARE_READ, nil, windows.OPEN_EXISTING, flags, 0)
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+	defer windows.CloseHandle(h)
+
+	var returned uint32
+	if algorithm == config.VolumeCompressionNTFS {
+		format := uint16(compressionFormatDefault)
+		err := windows.DeviceIoControl(h, windows.FSCTL_SET_COMPRESSION, (*byte)(unsafe.Pointer(&format)), uint32(unsafe.Sizeof(format)), nil, 0, &returned, nil)
+		return errors.WithStackIf(err)
+	}
+	in := wofExternalBacking{
+		WofVersion:      wofCurrentVersion,
+		WofProvider:     wofProviderFile,
+		ProviderVersion: fileProviderCurrentVersion,
+		Algorithm:       wofAlgorithms[algorithm],
+	}
+	err = windows.DeviceIoControl(h, fsctlSetExternalBacking, (*byte)(unsafe.Pointer(&in)), uint32(unsafe.Sizeof(in)), nil, 0, &returned, nil)
+	return errors.WithStackIf(err)
+}
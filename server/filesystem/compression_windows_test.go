@@ -0,0 +1,43 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/franela/goblin"
+	"golang.org/x/sys/windows"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestCompressDirectory(t *testing.T) {
+	g := Goblin(t)
+
+	g.Describe("compressDirectory", func() {
+		g.It("skips files that cannot be compressed and continues", func() {
+			dir, err := os.MkdirTemp("", "wings-compress")
+			g.Assert(err).IsNil()
+			defer os.RemoveAll(dir)
+
+			data := bytes.Repeat([]byte("compressible "), 1024)
+			g.Assert(os.MkdirAll(filepath.Join(dir, "sub"), 0o755)).IsNil()
+			g.Assert(os.WriteFile(filepath.Join(dir, "locked.txt"), data, 0o644)).IsNil()
+			g.Assert(os.WriteFile(filepath.Join(dir, "sub", "open.txt"), data, 0o644)).IsNil()
+
+			// Hold the file open without sharing it so that it cannot be compressed.
+			p16, err := windows.UTF16PtrFromString(filepath.Join(dir, "locked.txt"))
+			g.Assert(err).IsNil()
+			h, err := windows.CreateFile(p16, windows.GENERIC_READ, 0, nil, windows.OPEN_EXISTING, windows.FILE_ATTRIBUTE_NORMAL, 0)
+			g.Assert(err).IsNil()
+			defer windows.CloseHandle(h)
+
+			res, err := compressDirectory(context.Background(), dir, config.VolumeCompressionNTFS)
+			g.Assert(err).IsNil()
+			g.Assert(res.Files + res.Skipped).Equal(2)
+			g.Assert(res.Skipped >= 1).IsTrue()
+		})
+	})
+}
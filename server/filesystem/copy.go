@@ -0,0 +1,208 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"emperror.dev/errors"
+	"github.com/karrick/godirwalk"
+)
+
+// Progress tracks the number of bytes processed by a copy or move, and the
+// total number of bytes that will be processed. It is safe to read from while
+// the operation is running.
+type Progress struct {
+	done  int64
+	total int64
+}
+
+// Done returns the number of bytes that have been processed.
+func (p *Progress) Done() int64 {
+	return atomic.LoadInt64(&p.done)
+}
+
+// Total returns the number of bytes that will be processed.
+func (p *Progress) Total() int64 {
+	return atomic.LoadInt64(&p.total)
+}
+
+func (p *Progress) Write(b []byte) (int, error) {
+	p.add(int64(len(b)))
+	return len(b), nil
+}
+
+func (p *Progress) add(n int64) {
+	if p != nil {
+		atomic.AddInt64(&p.done, n)
+	}
+}
+
+func (p *Progress) grow(n int64) {
+	if p != nil {
+		atomic.AddInt64(&p.total, n)
+	}
+}
+
+// CopyTo copies the file or directory at the source path to the destination
+// path, creating any missing parent directories. The server must have enough
+// disk space available for the entire copy before it is started. Files are
+// cloned using copy-on-write when the underlying filesystem supports it, such
+// as reflinks on Btrfs and XFS, or block cloning on ReFS.
+//
+// The returned boolean indicates if every file was cloned using copy-on-write.
+// The progress is optional and is updated as each file is copied.
+func (fs *Filesystem) CopyTo(ctx context.Context, from string, to string, progress *Progress) (bool, error) {
+	src, dst, st, err := fs.prepareCopy(from, to)
+	if err != nil {
+		return false, err
+	}
+
+	size := st.Size()
+	if st.IsDir() {
+		if size, err = fs.DirectorySize(from); err != nil {
+			return false, err
+		}
+	}
+	if err := fs.HasSpaceFor(size); err != nil {
+		return false, err
+	}
+	progress.grow(size)
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return false, errors.Wrap(err, "server/filesystem: copy: failed to create directory tree")
+	}
+
+	var cow bool
+	if st.IsDir() {
+		cow, err = fs.copyDirectory(ctx, src, dst, progress)
+	} else {
+		cow, err = cloneFile(src, dst, st, progress)
+	}
+	if err != nil {
+		// Some files may have been written before the copy failed, so the disk usage
+		// is calculated again rather than guessing how much was copied.
+		if _, uerr := fs.updateCachedDiskUsage(); uerr != nil {
+			fs.error(uerr).Warn("failed to update disk usage after copying files")
+		}
+		return false, err
+	}
+	fs.addDisk(size)
+	return cow, fs.Chown(dst)
+}
+
+// MoveTo moves the file or directory at the source path to the destination
+// path, creating any missing parent directories. Moves within the same volume
+// are a rename, otherwise, such as when moving into a mount, the files are
+// copied to the destination and then removed from the source.
+func (fs *Filesystem) MoveTo(ctx context.Context, from string, to string, progress *Progress) error {
+	src, dst, st, err := fs.prepareCopy(from, to)
+	if err != nil {
+		return err
+	}
+	if err := fs.isTreeWriteDenied(src, dst); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return errors.Wrap(err, "server/filesystem: move: failed to create directory tree")
+	}
+
+	if err := os.Rename(src, dst); err == nil || !isCrossDevice(err) {
+		if err == nil && !st.IsDir() {
+			progress.grow(st.Size())
+			progress.add(st.Size())
+		}
+		return errors.WithStackIf(err)
+	}
+
+	if _, err := fs.CopyTo(ctx, from, to, progress); err != nil {
+		return err
+	}
+	return fs.Delete(from)
+}
+
+// prepareCopy resolves the source and destination of a copy or move, checking
+// that neither are on the denylist, that the destination does not already exist
+// and can be written, and that a directory is not being copied into itself.
+func (fs *Filesystem) prepareCopy(from string, to string) (string, string, os.FileInfo, error) {
+	if err := fs.IsIgnored(from, to); err != nil {
+		return "", "", nil, err
+	}
+	src, err := fs.SafePath(from)
+	if err != nil {
+		return "", "", nil, err
+	}
+	dst, err := fs.SafePath(to)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if src == fs.Path() || dst == fs.Path() {
+		return "", "", nil, NewBadPathResolution(to, dst)
+	}
+	st, err := os.Stat(src)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return "", "", nil, os.ErrExist
+	}
+	if st.IsDir() && strings.HasPrefix(dst+string(filepath.Separator), src+string(filepath.Separator)) {
+		return "", "", nil, errors.New("server/filesystem: cannot copy or move a directory into itself")
+	}
+	if err := fs.isWriteDenied(dst, st.IsDir()); err != nil {
+		return "", "", nil, err
+	}
+	return src, dst, st, nil
+}
+
+// copyDirectory copies the contents of the source directory into the target
+// directory. Any file that would be written to a path on the write denylist
+// causes the copy to fail. Symlinks are copied as-is rather than being followed.
+func (fs *Filesystem) copyDirectory(ctx context.Context, src string, dst string, progress *Progress) (bool, error) {
+	cow := true
+	err := godirwalk.Walk(src, &godirwalk.Options{
+		FollowSymbolicLinks: false,
+		Unsorted:            true,
+		Callback: func(p string, de *godirwalk.Dirent) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			target := filepath.Join(dst, strings.TrimPrefix(p, src))
+			if err := fs.isWriteDenied(target, de.IsDir()); err != nil {
+				return err
+			}
+
+			st, err := os.Lstat(p)
+			if err != nil {
+				return errors.WithStackIf(err)
+			}
+			switch {
+			case st.IsDir():
+				if err := os.MkdirAll(target, st.Mode().Perm()); err != nil {
+					return errors.Wrap(err, "server/filesystem: copy: failed to create directory")
+				}
+			case st.Mode()&os.ModeSymlink != 0:
+				link, err := os.Readlink(p)
+				if err != nil {
+					return errors.WithStackIf(err)
+				}
+				if err := os.Symlink(link, target); err != nil && !os.IsExist(err) {
+					return errors.Wrap(err, "server/filesystem: copy: failed to create symlink")
+				}
+			case st.Mode().IsRegular():
+				cloned, err := cloneFile(p, target, st, progress)
+				if err != nil {
+					return err
+				}
+				cow = cow && cloned
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return cow, nil
+}
@@ -0,0 +1,62 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestFilesystem_CopyTo(t *testing.T) {
+	g := Goblin(t)
+	fs, rfs := NewFs()
+
+	g.Describe("CopyTo", func() {
+		g.BeforeEach(func() {
+			rfs.reset()
+			atomic.StoreInt64(&fs.diskUsed, 0)
+			atomic.StoreInt64(&fs.diskLimit, 0)
+		})
+
+		g.It("copies a directory and tracks the progress", func() {
+			g.Assert(fs.Writefile("source/a.txt", bytes.NewReader([]byte("hello")))).IsNil()
+			g.Assert(fs.Writefile("source/nested/b.txt", bytes.NewReader([]byte("world")))).IsNil()
+
+			progress := &Progress{}
+			_, err := fs.CopyTo(context.Background(), "source", "target/copy", progress)
+			g.Assert(err).IsNil()
+			g.Assert(progress.Done()).Equal(int64(10))
+			g.Assert(progress.Total()).Equal(int64(10))
+
+			buf := &bytes.Buffer{}
+			g.Assert(fs.Readfile("target/copy/nested/b.txt", buf)).IsNil()
+			g.Assert(buf.String()).Equal("world")
+		})
+
+		g.It("does not copy over an existing file", func() {
+			g.Assert(fs.Writefile("a.txt", bytes.NewReader([]byte("hello")))).IsNil()
+			g.Assert(fs.Writefile("b.txt", bytes.NewReader([]byte("world")))).IsNil()
+
+			_, err := fs.CopyTo(context.Background(), "a.txt", "b.txt", nil)
+			g.Assert(err).Equal(os.ErrExist)
+		})
+
+		g.It("does not copy a directory into itself", func() {
+			g.Assert(fs.Writefile("source/a.txt", bytes.NewReader([]byte("hello")))).IsNil()
+
+			_, err := fs.CopyTo(context.Background(), "source", "source/copy", nil)
+			g.Assert(err).IsNotNil()
+		})
+
+		g.It("cannot copy more than the disk limit", func() {
+			g.Assert(fs.Writefile("a.txt", bytes.NewReader(make([]byte, 600)))).IsNil()
+			atomic.StoreInt64(&fs.diskLimit, 1024)
+
+			_, err := fs.CopyTo(context.Background(), "a.txt", "b.txt", nil)
+			g.Assert(IsErrorCode(err, ErrCodeDiskSpace)).IsTrue()
+		})
+	})
+}
@@ -121,6 +121,12 @@ func (fs *Filesystem) DiskUsage(allowStaleValue bool) (int64, error) {
 	return atomic.LoadInt64(&fs.diskUsed), nil
 }
 
+// RecalculateDiskUsage walks the data directory of the server to update the disk
+// space it uses, ignoring any cached value.
+func (fs *Filesystem) RecalculateDiskUsage() (int64, error) {
+	return fs.updateCachedDiskUsage()
+}
+
 // Updates the currently used disk space for a server.
 func (fs *Filesystem) updateCachedDiskUsage() (int64, error) {
 	// Obtain an exclusive lock on this process so that we don't unintentionally run it at the same
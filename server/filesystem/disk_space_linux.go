@@ -19,6 +19,7 @@ func (fs *Filesystem) DirectorySize(dir string) (int64, error) {
 
 	var size int64
 	var st syscall.Stat_t
+	allocated := countAllocated()
 
 	err = godirwalk.Walk(d, &godirwalk.Options{
 		Unsorted: true,
@@ -38,7 +39,13 @@ func (fs *Filesystem) DirectorySize(dir string) (int64, error) {
 
 			if !e.IsDir() {
 				syscall.Lstat(p, &st)
-				atomic.AddInt64(&size, st.Size)
+				// The number of blocks is always in 512 byte units, regardless of the
+				// block size of the filesystem.
+				if allocated {
+					atomic.AddInt64(&size, st.Blocks*512)
+				} else {
+					atomic.AddInt64(&size, st.Size)
+				}
 			}
 
 			return nil
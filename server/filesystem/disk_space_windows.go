@@ -17,14 +17,24 @@ func (fs *Filesystem) DirectorySize(dir string) (int64, error) {
 	}
 
 	var size int64
-	err = filepath.Walk(d, func(_ string, info os.FileInfo, err error) error {
+	allocated := countAllocated()
+	err = filepath.Walk(d, func(p string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() {
-			size += info.Size()
+		if info.IsDir() {
+			return nil
 		}
-		return err
+		// Fall back to the logical size if the allocated size cannot be read, so that
+		// the file is still counted towards the disk usage.
+		if allocated {
+			if n, err := allocatedSize(p); err == nil {
+				size += n
+				return nil
+			}
+		}
+		size += info.Size()
+		return nil
 	})
 
 	return size, errors.WrapIf(err, "server/filesystem: directorysize: failed to walk directory")
@@ -18,6 +18,8 @@ const (
 	ErrCodeUnknownArchive ErrorCode = "E_UNKNFMT"
 	ErrCodePathResolution ErrorCode = "E_BADPATH"
 	ErrCodeDenylistFile   ErrorCode = "E_DENYLIST"
+	ErrCodeWriteDenied    ErrorCode = "E_WRITEDENIED"
+	ErrCodeNotSupported   ErrorCode = "E_NOTSUPPORTED"
 	ErrCodeUnknownError   ErrorCode = "E_UNKNOWN"
 )
 
@@ -63,12 +65,16 @@ func (e *Error) Error() string {
 			r = "<empty>"
 		}
 		return fmt.Sprintf("filesystem: file access prohibited: [%s] is on the denylist", r)
+	case ErrCodeWriteDenied:
+		return fmt.Sprintf("filesystem: write prohibited: [%s] is on the write denylist", e.resolved)
 	case ErrCodePathResolution:
 		r := e.resolved
 		if r == "" {
 			r = "<empty>"
 		}
 		return fmt.Sprintf("filesystem: server path [%s] resolves to a location outside the server root: %s", e.path, r)
+	case ErrCodeNotSupported:
+		return "filesystem: action is not supported on this platform"
 	case ErrCodeUnknownError:
 		fallthrough
 	default:
@@ -3,7 +3,6 @@ package filesystem
 import (
 	"bufio"
 	"io"
-	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -30,12 +29,20 @@ type Filesystem struct {
 	diskCheckInterval time.Duration
 	denylist          *ignore.GitIgnore
 
+	// The files that cannot be written, or made executable, within the root
+	// directory. If nil every file can be written.
+	writeDenylist *ignore.GitIgnore
+
 	// The maximum amount of disk space (in bytes) that this Filesystem instance can use.
 	diskLimit int64
 
 	// The root data directory path for this Filesystem instance.
 	root string
 
+	// The user and group that own the files for this Filesystem instance. If nil
+	// the system user is used.
+	owner *Owner
+
 	isTest bool
 }
 
@@ -51,6 +58,38 @@ func New(root string, size int64, denylist []string) *Filesystem {
 	}
 }
 
+// Owner is the user and group that own the files of a Filesystem instance on
+// Linux, used when servers are isolated from each other with their own users.
+type Owner struct {
+	Uid int
+	Gid int
+}
+
+// SetOwner sets the user and group that the files for this Filesystem instance
+// are owned by. Passing nil will use the system user.
+func (fs *Filesystem) SetOwner(o *Owner) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.owner = o
+}
+
+// SetWriteDenylist sets the patterns for files that cannot be written or made
+// executable, in the same format as a .gitignore file.
+func (fs *Filesystem) SetWriteDenylist(lines []string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.writeDenylist = nil
+	if len(lines) > 0 {
+		folded := make([]string, len(lines))
+		for i, l := range lines {
+			folded[i] = foldCase(l)
+		}
+		fs.writeDenylist = ignore.CompileIgnoreLines(folded...)
+	}
+}
+
 // Path returns the root path for the Filesystem instance.
 func (fs *Filesystem) Path() string {
 	return fs.root
@@ -84,6 +123,11 @@ func (fs *Filesystem) Touch(p string, flag int) (*os.File, error) {
 	if err != nil {
 		return nil, err
 	}
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		if err := fs.IsWriteDenied(cleaned); err != nil {
+			return nil, err
+		}
+	}
 	f, err := os.OpenFile(cleaned, flag, 0o644)
 	if err == nil {
 		return f, nil
@@ -131,6 +175,12 @@ func (fs *Filesystem) Readfile(p string, w io.Writer) error {
 // will be created. This will also properly recalculate the disk space used by
 // the server when writing new files or modifying existing ones.
 func (fs *Filesystem) Writefile(p string, r io.Reader) error {
+	return fs.writefile(p, r, false)
+}
+
+// writefile writes the file to the system, optionally leaving any blocks of the
+// file that only contain zeros as holes so that it is written as a sparse file.
+func (fs *Filesystem) writefile(p string, r io.Reader, sparse bool) error {
 	cleaned, err := fs.SafePath(p)
 	if err != nil {
 		return err
@@ -165,8 +215,13 @@ func (fs *Filesystem) Writefile(p string, r io.Reader) error {
 	}
 	defer file.Close()
 
-	buf := make([]byte, 1024*4)
-	sz, err := io.CopyBuffer(file, r, buf)
+	var sz int64
+	if sparse {
+		sz, err = copySparse(file, r)
+	} else {
+		buf := make([]byte, 1024*4)
+		sz, err = io.CopyBuffer(file, r, buf)
+	}
 
 	// Adjust the disk usage to account for the old size and the new size of the file.
 	fs.addDisk(sz - currentSize)
@@ -180,6 +235,9 @@ func (fs *Filesystem) CreateDirectory(name string, p string) error {
 	if err != nil {
 		return err
 	}
+	if err := fs.isWriteDenied(cleaned, true); err != nil {
+		return err
+	}
 	return os.MkdirAll(cleaned, 0o755)
 }
 
@@ -195,6 +253,12 @@ func (fs *Filesystem) Rename(from string, to string) error {
 		return err
 	}
 
+	if _, err := os.Lstat(cleanedFrom); err == nil {
+		if err := fs.isTreeWriteDenied(cleanedFrom, cleanedTo); err != nil {
+			return err
+		}
+	}
+
 	// If the target file or directory already exists the rename function will fail, so just
 	// bail out now.
 	if _, err := os.Stat(cleanedTo); err == nil {
@@ -223,6 +287,13 @@ func (fs *Filesystem) Chmod(path string, mode os.FileMode) error {
 		return err
 	}
 
+	// Files on the write denylist cannot be made executable.
+	if mode&0o111 != 0 {
+		if err := fs.IsWriteDenied(cleaned); err != nil {
+			return err
+		}
+	}
+
 	if fs.isTest {
 		return nil
 	}
@@ -332,6 +403,51 @@ func (fs *Filesystem) TruncateRootDirectory() error {
 	return nil
 }
 
+// TruncateRootDirectoryExcept removes all files and directories from a server's
+// data directory except for the paths matching the preserved patterns, which use
+// the same format as a .gitignore file. Directories containing a preserved path
+// are kept, but any other files within them are removed.
+func (fs *Filesystem) TruncateRootDirectoryExcept(preserve []string) error {
+	if _, err := removeExcept(fs.Path(), fs.Path(), ignore.CompileIgnoreLines(preserve...)); err != nil {
+		return err
+	}
+	_, err := fs.updateCachedDiskUsage()
+	return err
+}
+
+// removeExcept removes everything within the directory that does not match the
+// preserved patterns, returning true if anything within the directory was kept.
+func removeExcept(root string, dir string, i *ignore.GitIgnore) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, errors.Wrap(err, "server/filesystem: failed to read directory")
+	}
+	var kept bool
+	for _, e := range entries {
+		p := filepath.Join(dir, e.Name())
+		relative := filepath.ToSlash(strings.TrimPrefix(p, root+string(filepath.Separator)))
+		// Directory patterns such as "world/" only match a path with a trailing slash.
+		if i.MatchesPath(relative) || (e.IsDir() && i.MatchesPath(relative+"/")) {
+			kept = true
+			continue
+		}
+		if e.IsDir() {
+			k, err := removeExcept(root, p, i)
+			if err != nil {
+				return false, err
+			}
+			if k {
+				kept = true
+				continue
+			}
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return false, errors.Wrap(err, "server/filesystem: failed to remove file")
+		}
+	}
+	return kept, nil
+}
+
 // Delete removes a file or folder from the system. Prevents the user from
 // accidentally (or maliciously) removing their root server data directory.
 func (fs *Filesystem) Delete(p string) error {
@@ -404,77 +520,166 @@ func (fo *fileOpener) open(path string, flags int, perm os.FileMode) (*os.File,
 	}
 }
 
+// The fields that directory listings can be sorted by.
+const (
+	SortByName     = "name"
+	SortBySize     = "size"
+	SortByModified = "modified"
+)
+
+// ListOptions controls the page of entries returned for a directory listing.
+// Directories are always listed before files.
+type ListOptions struct {
+	// The number of entries to skip, and the maximum number of entries to return.
+	// A limit of zero returns every entry.
+	Offset int
+	Limit  int
+	// The field to sort the entries by, which defaults to the name.
+	Sort       string
+	Descending bool
+}
+
+// DirectoryPage is a single page of the entries within a directory.
+type DirectoryPage struct {
+	// The total number of entries in the directory.
+	Total int
+	Files []Stat
+}
+
 // ListDirectory lists the contents of a given directory and returns stat
 // information about each file and folder within it.
 func (fs *Filesystem) ListDirectory(p string) ([]Stat, error) {
+	page, err := fs.ListDirectoryPage(p, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return page.Files, nil
+}
+
+// ListDirectoryPage returns a page of the contents of a directory. Only the
+// names of the entries are loaded for the entire directory (along with their
+// size and modification time when sorting by them), the more expensive stat
+// and mimetype lookups are only performed for the entries on the page.
+func (fs *Filesystem) ListDirectoryPage(p string, opts ListOptions) (*DirectoryPage, error) {
 	cleaned, err := fs.SafePath(p)
 	if err != nil {
 		return nil, err
 	}
 
-	files, err := ioutil.ReadDir(cleaned)
+	entries, err := os.ReadDir(cleaned)
 	if err != nil {
 		return nil, err
 	}
 
-	var wg sync.WaitGroup
+	var infos []os.FileInfo
+	if opts.Sort == SortBySize || opts.Sort == SortByModified {
+		infos = make([]os.FileInfo, 0, len(entries))
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil {
+				// The file was removed after the directory was read.
+				continue
+			}
+			infos = append(infos, info)
+		}
+		sortFileInfo(infos, opts)
+	} else {
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].IsDir() != entries[j].IsDir() {
+				return entries[i].IsDir()
+			}
+			if opts.Descending {
+				return entries[i].Name() > entries[j].Name()
+			}
+			return entries[i].Name() < entries[j].Name()
+		})
+	}
+
+	total := len(entries)
+	if infos != nil {
+		total = len(infos)
+	}
+	from, to := opts.Offset, total
+	if from < 0 {
+		from = 0
+	}
+	if from > total {
+		from = total
+	}
+	if opts.Limit > 0 && from+opts.Limit < to {
+		to = from + opts.Limit
+	}
 
 	// You must initialize the output of this directory as a non-nil value otherwise
 	// when it is marshaled into a JSON object you'll just get 'null' back, which will
 	// break the panel badly.
-	out := make([]Stat, len(files))
+	out := make([]Stat, 0, to-from)
+	for i := from; i < to; i++ {
+		if infos != nil {
+			out = append(out, Stat{FileInfo: infos[i]})
+			continue
+		}
+		info, err := entries[i].Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, Stat{FileInfo: info})
+	}
 
-	// Iterate over all of the files and directories returned and perform an async process
-	// to get the mime-type for them all.
-	for i, file := range files {
+	var wg sync.WaitGroup
+	// Iterate over all of the files and directories on the page and perform an async
+	// process to get the mime-type for them all.
+	for i := range out {
 		wg.Add(1)
 
-		go func(idx int, f os.FileInfo) {
+		go func(st *Stat) {
 			defer wg.Done()
 
-			var m *mimetype.MIME
-			d := "inode/directory"
-			if !f.IsDir() {
-				cleanedp := filepath.Join(cleaned, f.Name())
-				if f.Mode()&os.ModeSymlink != 0 {
-					cleanedp, _ = fs.SafePath(filepath.Join(cleaned, f.Name()))
-				}
-
-				if cleanedp != "" {
-					m, _ = mimetype.DetectFile(filepath.Join(cleaned, f.Name()))
-				} else {
-					// Just pass this for an unknown type because the file could not safely be resolved within
-					// the server data path.
-					d = "application/octet-stream"
-				}
+			st.Attributes = fileAttributes(filepath.Join(cleaned, st.Name()), st.FileInfo)
+			st.Mimetype = "inode/directory"
+			if st.IsDir() {
+				return
 			}
-
-			st := Stat{FileInfo: f, Mimetype: d}
-			if m != nil {
+			cleanedp := filepath.Join(cleaned, st.Name())
+			if st.Mode()&os.ModeSymlink != 0 {
+				cleanedp, _ = fs.SafePath(filepath.Join(cleaned, st.Name()))
+			}
+			if cleanedp == "" {
+				// Just pass this for an unknown type because the file could not safely be resolved within
+				// the server data path.
+				st.Mimetype = "application/octet-stream"
+				return
+			}
+			if m, _ := mimetype.DetectFile(filepath.Join(cleaned, st.Name())); m != nil {
 				st.Mimetype = m.String()
 			}
-			out[idx] = st
-		}(i, file)
+		}(&out[i])
 	}
 
 	wg.Wait()
 
-	// Sort the output alphabetically to begin with since we've run the output
-	// through an asynchronous process and the order is gonna be very random.
-	sort.SliceStable(out, func(i, j int) bool {
-		if out[i].Name() == out[j].Name() || out[i].Name() > out[j].Name() {
-			return true
-		}
-		return false
-	})
+	return &DirectoryPage{Total: total, Files: out}, nil
+}
 
-	// Then, sort it so that directories are listed first in the output. Everything
-	// will continue to be alphabetized at this point.
-	sort.SliceStable(out, func(i, j int) bool {
-		return out[i].IsDir()
+// sortFileInfo sorts the entries by their size or modification time, with the
+// name used to order entries that are otherwise equal.
+func sortFileInfo(infos []os.FileInfo, opts ListOptions) {
+	sort.Slice(infos, func(i, j int) bool {
+		a, b := infos[i], infos[j]
+		if a.IsDir() != b.IsDir() {
+			return a.IsDir()
+		}
+		if opts.Descending {
+			a, b = b, a
+		}
+		switch {
+		case opts.Sort == SortBySize && a.Size() != b.Size():
+			return a.Size() < b.Size()
+		case opts.Sort == SortByModified && !a.ModTime().Equal(b.ModTime()):
+			return a.ModTime().Before(b.ModTime())
+		}
+		return a.Name() < b.Name()
 	})
-
-	return out, nil
 }
 
 func (fs *Filesystem) Chtimes(path string, atime, mtime time.Time) error {
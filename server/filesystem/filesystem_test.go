@@ -2,6 +2,7 @@ package filesystem
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"math/rand"
 	"os"
@@ -212,6 +213,61 @@ func TestFilesystem_Writefile(t *testing.T) {
 			g.Assert(atomic.LoadInt64(&fs.diskUsed)).Equal(int64(150))
 		})*/
 
+		g.It("cannot write a file on the write denylist", func() {
+			fs.SetWriteDenylist([]string{"*.exe", "!/bin/**", ".ssh/"})
+			defer fs.SetWriteDenylist(nil)
+
+			err := fs.Writefile("setup.exe", bytes.NewReader([]byte("test")))
+			g.Assert(IsErrorCode(err, ErrCodeWriteDenied)).IsTrue()
+
+			err = fs.Writefile("home/.ssh/authorized_keys", bytes.NewReader([]byte("test")))
+			g.Assert(IsErrorCode(err, ErrCodeWriteDenied)).IsTrue()
+
+			err = fs.CreateDirectory(".ssh", "home")
+			g.Assert(IsErrorCode(err, ErrCodeWriteDenied)).IsTrue()
+
+			err = fs.Writefile("bin/setup.exe", bytes.NewReader([]byte("test")))
+			g.Assert(err).IsNil()
+		})
+
+		g.It("cannot rename a file onto the write denylist", func() {
+			fs.SetWriteDenylist([]string{"*.exe"})
+			defer fs.SetWriteDenylist(nil)
+
+			err := fs.Writefile("setup.txt", bytes.NewReader([]byte("test")))
+			g.Assert(err).IsNil()
+
+			err = fs.Rename("setup.txt", "setup.exe")
+			g.Assert(IsErrorCode(err, ErrCodeWriteDenied)).IsTrue()
+		})
+
+		g.It("cannot rename a directory containing files on the write denylist", func() {
+			fs.SetWriteDenylist([]string{"*.exe", "config/*.json"})
+			defer fs.SetWriteDenylist(nil)
+
+			err := fs.Writefile("plugins/setup.txt", bytes.NewReader([]byte("test")))
+			g.Assert(err).IsNil()
+			err = rfs.CreateServerFileFromString("plugins/run.exe", "test")
+			g.Assert(err).IsNil()
+
+			// The directory contains a denied file, so it cannot be moved anywhere.
+			err = fs.Rename("plugins", "other")
+			g.Assert(IsErrorCode(err, ErrCodeWriteDenied)).IsTrue()
+
+			err = fs.Writefile("data/settings.json", bytes.NewReader([]byte("test")))
+			g.Assert(err).IsNil()
+
+			// The file would end up on the denylist once the directory is moved.
+			err = fs.Rename("data", "config")
+			g.Assert(IsErrorCode(err, ErrCodeWriteDenied)).IsTrue()
+
+			err = fs.MoveTo(context.Background(), "data", "config", nil)
+			g.Assert(IsErrorCode(err, ErrCodeWriteDenied)).IsTrue()
+
+			err = fs.Rename("data", "other")
+			g.Assert(err).IsNil()
+		})
+
 		g.It("truncates the file when writing new contents", func() {
 			r := bytes.NewReader([]byte("original data"))
 			err := fs.Writefile("test.txt", r)
@@ -573,3 +629,106 @@ func TestFilesystem_Delete(t *testing.T) {
 		})
 	})
 }
+
+func TestFilesystem_TruncateRootDirectoryExcept(t *testing.T) {
+	g := Goblin(t)
+	fs, rfs := NewFs()
+
+	g.Describe("TruncateRootDirectoryExcept", func() {
+		g.BeforeEach(func() {
+			rfs.reset()
+
+			_ = os.MkdirAll(filepath.Join(rfs.root, "/server/world/region"), 0o755)
+			_ = os.MkdirAll(filepath.Join(rfs.root, "/server/config"), 0o755)
+			_ = os.MkdirAll(filepath.Join(rfs.root, "/server/libraries"), 0o755)
+			_ = rfs.CreateServerFileFromString("world/region/r.0.0.mca", "region")
+			_ = rfs.CreateServerFileFromString("config/server.yml", "config")
+			_ = rfs.CreateServerFileFromString("config/cache.dat", "cache")
+			_ = rfs.CreateServerFileFromString("libraries/lib.jar", "lib")
+			_ = rfs.CreateServerFileFromString("server.jar", "jar")
+		})
+
+		g.It("removes everything when nothing is preserved", func() {
+			err := fs.TruncateRootDirectoryExcept(nil)
+			g.Assert(err).IsNil()
+
+			entries, err := os.ReadDir(filepath.Join(rfs.root, "/server"))
+			g.Assert(err).IsNil()
+			g.Assert(len(entries)).Equal(0)
+		})
+
+		g.It("keeps preserved directories and files", func() {
+			err := fs.TruncateRootDirectoryExcept([]string{"world/", "config/*.yml"})
+			g.Assert(err).IsNil()
+
+			_, err = rfs.StatServerFile("world/region/r.0.0.mca")
+			g.Assert(err).IsNil()
+			_, err = rfs.StatServerFile("config/server.yml")
+			g.Assert(err).IsNil()
+
+			_, err = rfs.StatServerFile("config/cache.dat")
+			g.Assert(os.IsNotExist(err)).IsTrue()
+			_, err = rfs.StatServerFile("libraries")
+			g.Assert(os.IsNotExist(err)).IsTrue()
+			_, err = rfs.StatServerFile("server.jar")
+			g.Assert(os.IsNotExist(err)).IsTrue()
+		})
+
+		g.AfterEach(func() {
+			rfs.reset()
+		})
+	})
+}
+
+func TestFilesystem_ListDirectoryPage(t *testing.T) {
+	g := Goblin(t)
+	fs, rfs := NewFs()
+
+	g.Describe("ListDirectoryPage", func() {
+		g.BeforeEach(func() {
+			rfs.reset()
+
+			_ = os.MkdirAll(filepath.Join(rfs.root, "/server/plugins"), 0o755)
+			_ = rfs.CreateServerFileFromString("a.txt", "aaa")
+			_ = rfs.CreateServerFileFromString("b.txt", "b")
+			_ = rfs.CreateServerFileFromString("c.txt", "cc")
+		})
+
+		names := func(page *DirectoryPage) []string {
+			var out []string
+			for _, f := range page.Files {
+				out = append(out, f.Name())
+			}
+			return out
+		}
+
+		g.It("lists directories first and then files by name", func() {
+			page, err := fs.ListDirectoryPage("/", ListOptions{})
+			g.Assert(err).IsNil()
+			g.Assert(page.Total).Equal(4)
+			g.Assert(names(page)).Equal([]string{"plugins", "a.txt", "b.txt", "c.txt"})
+			g.Assert(page.Files[0].Mimetype).Equal("inode/directory")
+		})
+
+		g.It("returns a single page of entries", func() {
+			page, err := fs.ListDirectoryPage("/", ListOptions{Offset: 1, Limit: 2})
+			g.Assert(err).IsNil()
+			g.Assert(page.Total).Equal(4)
+			g.Assert(names(page)).Equal([]string{"a.txt", "b.txt"})
+
+			page, err = fs.ListDirectoryPage("/", ListOptions{Offset: 10, Limit: 2})
+			g.Assert(err).IsNil()
+			g.Assert(len(page.Files)).Equal(0)
+		})
+
+		g.It("sorts entries by size", func() {
+			page, err := fs.ListDirectoryPage("/", ListOptions{Sort: SortBySize, Descending: true})
+			g.Assert(err).IsNil()
+			g.Assert(names(page)).Equal([]string{"plugins", "a.txt", "c.txt", "b.txt"})
+		})
+
+		g.AfterEach(func() {
+			rfs.reset()
+		})
+	})
+}
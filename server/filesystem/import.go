@@ -0,0 +1,151 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+
+	"emperror.dev/errors"
+	"github.com/karrick/godirwalk"
+	"github.com/mholt/archiver/v3"
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// ImportDirectory copies the contents of a directory anywhere on the host into
+// the root of the Filesystem instance. An error is returned without copying any
+// files if the contents of the directory would not fit within the disk limit.
+func (fs *Filesystem) ImportDirectory(ctx context.Context, source string) error {
+	if fs.MaxDisk() > 0 {
+		var size int64
+		err := godirwalk.Walk(source, &godirwalk.Options{
+			FollowSymbolicLinks: false,
+			Unsorted:            true,
+			Callback: func(p string, de *godirwalk.Dirent) error {
+				if !de.IsRegular() {
+					return nil
+				}
+				st, err := os.Lstat(p)
+				if err != nil {
+					return errors.WithStackIf(err)
+				}
+				size += st.Size()
+				return nil
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if err := fs.HasSpaceFor(size); err != nil {
+			return err
+		}
+	}
+
+	if _, err := copyTree(ctx, filepath.Clean(source), fs.Path(), ignore.CompileIgnoreLines(), nil); err != nil {
+		return err
+	}
+	return fs.Chown("/")
+}
+
+// ImportArchive extracts an archive anywhere on the host into the root of the
+// Filesystem instance. An error is returned without extracting any files if the
+// contents of the archive would not fit within the disk limit.
+func (fs *Filesystem) ImportArchive(source string) error {
+	if err := fs.spaceAvailableForArchive(source); err != nil {
+		return err
+	}
+	conflicts, err := fs.extractArchive(source, "/")
+	fs.logNameConflicts(conflicts)
+	return err
+}
+
+// ReplaceWithArchive removes all the files for the Filesystem instance and then
+// extracts an archive anywhere on the host into the root. This is used to restore
+// the files that existed at the time the archive was created.
+func (fs *Filesystem) ReplaceWithArchive(source string) error {
+	entries, err := os.ReadDir(fs.Path())
+	if err != nil {
+		return errors.Wrap(err, "server/filesystem: failed to read root directory")
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(fs.Path(), e.Name())); err != nil {
+			return errors.Wrap(err, "server/filesystem: failed to remove file")
+		}
+	}
+	// Reset the cached disk usage now that the directory is empty, otherwise the files
+	// being extracted could be rejected for exceeding the disk limit.
+	if _, err := fs.updateCachedDiskUsage(); err != nil {
+		return err
+	}
+	conflicts, err := fs.extractArchive(source, "/")
+	fs.logNameConflicts(conflicts)
+	return err
+}
+
+// ExtractTransferArchive extracts a transfer archive created by another node into
+// the root of the Filesystem instance. Unlike ImportArchive the denylist and disk
+// limit are not applied, and the directories and symlinks within the archive are
+// kept, so that the server is identical to the one on the other node. Existing
+// files are never overwritten, and nothing is written through a symlink, even one
+// that was extracted from the archive. Returns the files that were renamed or
+// skipped because their names conflicted with another file in the archive, or
+// could not be used on this system.
+func (fs *Filesystem) ExtractTransferArchive(source string) ([]NameConflict, error) {
+	names := NewNameResolver()
+	err := archiver.NewTarGz().Walk(source, func(f archiver.File) error {
+		var name string
+		var ok bool
+		if f.IsDir() {
+			name, ok = names.ResolveDir(ExtractNameFromArchive(f))
+		} else {
+			name, ok = names.Resolve(ExtractNameFromArchive(f))
+		}
+		if !ok {
+			return nil
+		}
+		// Make sure the path does not escape the root directory before creating any of
+		// the directories leading up to it.
+		if _, err := fs.SafePath(name); err != nil {
+			return err
+		}
+		p := fs.unsafeFilePath(name)
+		if f.IsDir() {
+			if _, err := fs.writeTarget(name); err != nil {
+				return err
+			}
+			return errors.WithStackIf(os.MkdirAll(p, f.Mode().Perm()))
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return errors.WithStackIf(err)
+		}
+		if _, err := fs.writeTarget(name); err != nil {
+			return err
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			h, ok := f.Header.(*tar.Header)
+			if !ok {
+				return nil
+			}
+			return errors.WithStackIf(os.Symlink(h.Linkname, p))
+		}
+		if !f.Mode().IsRegular() {
+			return nil
+		}
+		out, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, f.Mode().Perm())
+		if err != nil {
+			return errors.WithStackIf(err)
+		}
+		if _, err := copySparse(out, f); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return errors.WithStackIf(err)
+		}
+		return errors.WithStackIf(os.Chtimes(p, f.ModTime(), f.ModTime()))
+	})
+	if err != nil {
+		return nil, errors.WrapIf(err, "server/filesystem: failed to extract transfer archive")
+	}
+	return names.Conflicts(), nil
+}
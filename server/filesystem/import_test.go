@@ -0,0 +1,105 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+// writeTarGz creates a transfer archive at the given path containing the given
+// tar headers, using the value in the map as the contents of regular files.
+func writeTarGz(p string, headers []*tar.Header, contents map[string]string) error {
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, h := range headers {
+		if h.Typeflag == tar.TypeReg {
+			h.Size = int64(len(contents[h.Name]))
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if h.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(contents[h.Name])); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func TestFilesystem_ExtractTransferArchive(t *testing.T) {
+	g := Goblin(t)
+	fs, rfs := NewFs()
+
+	g.Describe("ExtractTransferArchive", func() {
+		var archive string
+
+		g.BeforeEach(func() {
+			rfs.reset()
+			archive = filepath.Join(rfs.root, "transfer.tar.gz")
+		})
+
+		g.It("extracts directories, files and symlinks", func() {
+			err := writeTarGz(archive, []*tar.Header{
+				{Name: "world/", Typeflag: tar.TypeDir, Mode: 0o755},
+				{Name: "world/level.dat", Typeflag: tar.TypeReg, Mode: 0o644},
+				{Name: "level.dat", Typeflag: tar.TypeSymlink, Linkname: "world/level.dat", Mode: 0o777},
+			}, map[string]string{"world/level.dat": "level"})
+			g.Assert(err).IsNil()
+
+			_, err = fs.ExtractTransferArchive(archive)
+			g.Assert(err).IsNil()
+
+			b, err := os.ReadFile(filepath.Join(rfs.root, "/server/level.dat"))
+			g.Assert(err).IsNil()
+			g.Assert(string(b)).Equal("level")
+		})
+
+		g.It("does not write through a symlink extracted from the archive", func() {
+			outside := filepath.Join(rfs.root, "outside")
+			err := os.MkdirAll(outside, 0o755)
+			g.Assert(err).IsNil()
+
+			err = writeTarGz(archive, []*tar.Header{
+				{Name: "world", Typeflag: tar.TypeSymlink, Linkname: outside, Mode: 0o777},
+				{Name: "world/level.dat", Typeflag: tar.TypeReg, Mode: 0o644},
+			}, map[string]string{"world/level.dat": "level"})
+			g.Assert(err).IsNil()
+
+			_, err = fs.ExtractTransferArchive(archive)
+			g.Assert(err).IsNotNil()
+
+			_, err = os.Stat(filepath.Join(outside, "level.dat"))
+			g.Assert(os.IsNotExist(err)).IsTrue()
+		})
+
+		g.It("does not overwrite existing files", func() {
+			err := rfs.CreateServerFileFromString("server.properties", "original")
+			g.Assert(err).IsNil()
+
+			err = writeTarGz(archive, []*tar.Header{
+				{Name: "server.properties", Typeflag: tar.TypeReg, Mode: 0o644},
+			}, map[string]string{"server.properties": "changed"})
+			g.Assert(err).IsNil()
+
+			_, err = fs.ExtractTransferArchive(archive)
+			g.Assert(err).IsNotNil()
+
+			b, err := os.ReadFile(filepath.Join(rfs.root, "/server/server.properties"))
+			g.Assert(err).IsNil()
+			g.Assert(string(b)).Equal("original")
+		})
+	})
+}
@@ -0,0 +1,79 @@
+package filesystem
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/karrick/godirwalk"
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// The largest file that will have its line endings converted, larger files are
+// unlikely to be configuration files.
+const maxLineEndingsSize = 4 * 1024 * 1024
+
+// ConvertLineEndings converts the line endings of every text file matching the
+// patterns, which are in the same format as a .gitignore file, to the line
+// endings used by this system. Files that contain null bytes are assumed to be
+// binary and are left unchanged. Returns the number of files that were changed.
+func (fs *Filesystem) ConvertLineEndings(patterns []string) (int, error) {
+	if len(patterns) == 0 {
+		return 0, nil
+	}
+	matcher := ignore.CompileIgnoreLines(patterns...)
+
+	var n int
+	root := fs.Path()
+	err := godirwalk.Walk(root, &godirwalk.Options{
+		FollowSymbolicLinks: false,
+		Unsorted:            true,
+		Callback: func(p string, de *godirwalk.Dirent) error {
+			if !de.IsRegular() {
+				return nil
+			}
+			rel := filepath.ToSlash(strings.TrimPrefix(p, root))
+			if !matcher.MatchesPath(rel) {
+				return nil
+			}
+			changed, err := convertLineEndings(p)
+			if err != nil {
+				return err
+			}
+			if changed {
+				n++
+			}
+			return nil
+		},
+	})
+	return n, errors.WrapIf(err, "server/filesystem: failed to convert line endings")
+}
+
+// convertLineEndings rewrites the file at the given path using the line endings
+// of this system, returning true if the file was changed.
+func convertLineEndings(p string) (bool, error) {
+	st, err := os.Stat(p)
+	if err != nil {
+		return false, errors.WithStackIf(err)
+	}
+	if st.Size() > maxLineEndingsSize {
+		return false, nil
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return false, errors.WithStackIf(err)
+	}
+	if bytes.IndexByte(b, 0) != -1 {
+		return false, nil
+	}
+	out := bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+	if lineEnding != "\n" {
+		out = bytes.ReplaceAll(out, []byte("\n"), []byte(lineEnding))
+	}
+	if bytes.Equal(b, out) {
+		return false, nil
+	}
+	return true, errors.WithStackIf(os.WriteFile(p, out, st.Mode().Perm()))
+}
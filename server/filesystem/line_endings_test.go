@@ -0,0 +1,49 @@
+package filesystem
+
+import (
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestFilesystem_ConvertLineEndings(t *testing.T) {
+	g := Goblin(t)
+	fs, rfs := NewFs()
+
+	g.Describe("ConvertLineEndings", func() {
+		g.BeforeEach(func() {
+			rfs.reset()
+		})
+
+		g.It("converts files matching the patterns", func() {
+			err := rfs.CreateServerFileFromString("server.properties", "a=1\r\nb=2\r\n")
+			g.Assert(err).IsNil()
+
+			n, err := fs.ConvertLineEndings([]string{"*.properties"})
+			g.Assert(err).IsNil()
+			g.Assert(n).Equal(1)
+
+			b, err := rfs.StatServerFile("server.properties")
+			g.Assert(err).IsNil()
+			g.Assert(b.Size()).Equal(int64(len("a=1" + lineEnding + "b=2" + lineEnding)))
+		})
+
+		g.It("does not change files that do not match", func() {
+			err := rfs.CreateServerFileFromString("notes.txt", "a\r\nb\r\n")
+			g.Assert(err).IsNil()
+
+			n, err := fs.ConvertLineEndings([]string{"*.properties"})
+			g.Assert(err).IsNil()
+			g.Assert(n).Equal(0)
+		})
+
+		g.It("does not change binary files", func() {
+			err := rfs.CreateServerFileFromString("world.properties", "a\x00\r\nb\r\n")
+			g.Assert(err).IsNil()
+
+			n, err := fs.ConvertLineEndings([]string{"*.properties"})
+			g.Assert(err).IsNil()
+			g.Assert(n).Equal(0)
+		})
+	})
+}
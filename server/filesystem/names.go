@@ -0,0 +1,168 @@
+package filesystem
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// The reasons that a file could not be written using its original name.
+const (
+	NameConflictCase    = "case"
+	NameConflictInvalid = "invalid"
+)
+
+// NameConflict describes a file that could not be written using the name it had
+// in an archive, backup or transfer. Resolved is the name the file was written
+// to instead, which is empty if the file was skipped.
+type NameConflict struct {
+	Name     string `json:"name"`
+	Resolved string `json:"resolved,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+// String returns a description of what happened to the file and why.
+func (c NameConflict) String() string {
+	why := "its name only differs by case from another file"
+	if c.Reason == NameConflictInvalid {
+		why = "its name contains characters that cannot be used on this system"
+	}
+	if c.Resolved == "" {
+		return fmt.Sprintf("Skipped %s since %s.", c.Name, why)
+	}
+	return fmt.Sprintf("Renamed %s to %s since %s.", c.Name, c.Resolved, why)
+}
+
+// NameResolver tracks the names of the files written when extracting a single
+// archive, backup or transfer. Files with names that only differ by case from
+// another file, which would overwrite each other on a case-insensitive volume,
+// or that contain characters which cannot be used on this system, are renamed
+// or skipped using the name conflict policy configured for the node.
+type NameResolver struct {
+	mu        sync.Mutex
+	policy    string
+	names     map[string]struct{}
+	dirs      map[string]struct{}
+	resolved  map[string]string
+	conflicts []NameConflict
+}
+
+// NewNameResolver returns a resolver using the name conflict policy configured
+// for the node.
+func NewNameResolver() *NameResolver {
+	return &NameResolver{
+		policy:   config.Get().System.NameConflicts,
+		names:    make(map[string]struct{}),
+		dirs:     make(map[string]struct{}),
+		resolved: make(map[string]string),
+	}
+}
+
+// Resolve returns the name that the file should be written to, or false if the
+// file should be skipped. Names are slash separated paths that are relative to
+// the directory being extracted into. Resolving the same name more than once
+// returns the same result, so that files which appear in an archive multiple
+// times are overwritten as they would be normally.
+func (r *NameResolver) Resolve(name string) (string, bool) {
+	return r.resolve(name, false)
+}
+
+// ResolveDir returns the name that a directory should be created with, or false
+// if it should be skipped. Directories with names that only differ by case from
+// another directory are merged together rather than renamed, but a directory
+// with the same name as a file is renamed or skipped in the same way as a file.
+// Anything within a directory that was renamed or skipped is moved or skipped
+// along with it.
+func (r *NameResolver) ResolveDir(name string) (string, bool) {
+	return r.resolve(name, true)
+}
+
+func (r *NameResolver) resolve(name string, dir bool) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	original := name
+	// Directories are stored with a trailing slash so that they are not confused
+	// with a file of the same name.
+	key := name
+	if dir {
+		key += "/"
+	}
+	if v, ok := r.resolved[key]; ok {
+		return v, v != ""
+	}
+	// Use the resolved name of the closest parent directory, so that files within a
+	// directory that was renamed are written into the renamed directory.
+	for d := path.Dir(name); d != "." && d != "/"; d = path.Dir(d) {
+		if v, ok := r.resolved[d+"/"]; ok {
+			if v == "" {
+				r.resolved[key] = ""
+				return "", false
+			}
+			name = path.Join(v, strings.TrimPrefix(name, d+"/"))
+			break
+		}
+	}
+
+	var reason string
+	resolved := sanitizeName(name)
+	if resolved != name {
+		reason = NameConflictInvalid
+	}
+	folded := foldCase(resolved)
+	if _, ok := r.names[folded]; ok && reason == "" {
+		if _, isDir := r.dirs[folded]; !dir || !isDir {
+			reason = NameConflictCase
+		}
+	}
+	if reason == "" {
+		r.names[folded] = struct{}{}
+		if dir {
+			r.dirs[folded] = struct{}{}
+		}
+		r.resolved[key] = resolved
+		return resolved, true
+	}
+
+	if r.policy == config.NameConflictSkip {
+		r.resolved[key] = ""
+		r.conflicts = append(r.conflicts, NameConflict{Name: original, Reason: reason})
+		return "", false
+	}
+	resolved = r.unique(resolved)
+	r.names[foldCase(resolved)] = struct{}{}
+	if dir {
+		r.dirs[foldCase(resolved)] = struct{}{}
+	}
+	r.resolved[key] = resolved
+	r.conflicts = append(r.conflicts, NameConflict{Name: original, Resolved: resolved, Reason: reason})
+	return resolved, true
+}
+
+// Conflicts returns every file that has been renamed or skipped.
+func (r *NameResolver) Conflicts() []NameConflict {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]NameConflict{}, r.conflicts...)
+}
+
+// unique returns the name with a number added before its extension, such as
+// "config (1).yml", if the name is already being used by another file.
+func (r *NameResolver) unique(name string) string {
+	if _, ok := r.names[foldCase(name)]; !ok {
+		return name
+	}
+	dir, file := path.Split(name)
+	ext := path.Ext(file)
+	base := strings.TrimSuffix(file, ext)
+	for i := 1; ; i++ {
+		n := fmt.Sprintf("%s%s (%d)%s", dir, base, i, ext)
+		if _, ok := r.names[foldCase(n)]; !ok {
+			return n
+		}
+	}
+}
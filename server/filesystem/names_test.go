@@ -0,0 +1,66 @@
+package filesystem
+
+import (
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestNameResolver(t *testing.T) {
+	g := Goblin(t)
+
+	g.Describe("NameResolver", func() {
+		g.It("cleans the name of the file", func() {
+			r := NewNameResolver()
+			name, ok := r.Resolve("./config/../server.properties")
+			g.Assert(ok).IsTrue()
+			g.Assert(name).Equal("server.properties")
+		})
+
+		g.It("allows the same file to be written more than once", func() {
+			r := NewNameResolver()
+			a, _ := r.Resolve("world/level.dat")
+			b, ok := r.Resolve("world/level.dat")
+			g.Assert(ok).IsTrue()
+			g.Assert(a).Equal(b)
+			g.Assert(len(r.Conflicts())).Equal(0)
+		})
+
+		g.It("adds a number to names that are already used", func() {
+			r := NewNameResolver()
+			r.Resolve("config.yml")
+			g.Assert(r.unique("config.yml")).Equal("config (1).yml")
+			r.Resolve("config (1).yml")
+			g.Assert(r.unique("config.yml")).Equal("config (2).yml")
+			g.Assert(r.unique("plugins/config.yml")).Equal("plugins/config.yml")
+		})
+
+		g.It("merges directories with names that only differ by case", func() {
+			r := NewNameResolver()
+			r.ResolveDir("World")
+			name, ok := r.ResolveDir("world")
+			g.Assert(ok).IsTrue()
+			g.Assert(name).Equal("world")
+			g.Assert(len(r.Conflicts())).Equal(0)
+		})
+
+		g.It("renames a file with the same name as a directory", func() {
+			r := NewNameResolver()
+			r.ResolveDir("world")
+			name, ok := r.Resolve("world")
+			g.Assert(ok).IsTrue()
+			g.Assert(name).Equal("world (1)")
+			g.Assert(len(r.Conflicts())).Equal(1)
+		})
+
+		g.It("moves files into a directory that was renamed", func() {
+			r := NewNameResolver()
+			r.Resolve("world")
+			dir, _ := r.ResolveDir("world/")
+			g.Assert(dir).Equal("world (1)")
+			name, ok := r.Resolve("world/level.dat")
+			g.Assert(ok).IsTrue()
+			g.Assert(name).Equal("world (1)/level.dat")
+		})
+	})
+}
@@ -8,6 +8,8 @@ import (
 	"sync"
 
 	"emperror.dev/errors"
+	"github.com/karrick/godirwalk"
+	ignore "github.com/sabhiram/go-gitignore"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -26,6 +28,79 @@ func (fs *Filesystem) IsIgnored(paths ...string) error {
 	return nil
 }
 
+// IsWriteDenied checks if the given file or path matches the write denylist for
+// the server. If so, an Error is returned, otherwise nil is returned.
+func (fs *Filesystem) IsWriteDenied(paths ...string) error {
+	for _, p := range paths {
+		if err := fs.isWriteDenied(p, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isWriteDenied checks a single path against the write denylist. Patterns are
+// matched against the path relative to the root directory so that patterns
+// such as "/bin/**" work as expected. Directory patterns such as ".ssh/" only
+// match the directory itself when dir is true.
+func (fs *Filesystem) isWriteDenied(p string, dir bool) error {
+	fs.mu.RLock()
+	denylist := fs.writeDenylist
+	fs.mu.RUnlock()
+	if denylist == nil {
+		return nil
+	}
+	sp, err := fs.SafePath(p)
+	if err != nil {
+		return err
+	}
+	if writeDenied(denylist, fs.relative(sp), dir) {
+		return errors.WithStack(&Error{code: ErrCodeWriteDenied, path: p, resolved: sp})
+	}
+	return nil
+}
+
+// isTreeWriteDenied checks the source path and everything within it against the
+// write denylist, along with the path each of them would have once moved to the
+// destination, so that a denied file cannot be moved or replaced by moving one
+// of the directories it is in. Both paths must already be resolved within the
+// root directory.
+func (fs *Filesystem) isTreeWriteDenied(src string, dst string) error {
+	fs.mu.RLock()
+	denylist := fs.writeDenylist
+	fs.mu.RUnlock()
+	if denylist == nil {
+		return nil
+	}
+	return godirwalk.Walk(src, &godirwalk.Options{
+		AllowNonDirectory:   true,
+		FollowSymbolicLinks: false,
+		Unsorted:            true,
+		Callback: func(p string, de *godirwalk.Dirent) error {
+			for _, sp := range []string{p, filepath.Join(dst, strings.TrimPrefix(p, src))} {
+				if writeDenied(denylist, fs.relative(sp), de.IsDir()) {
+					return errors.WithStack(&Error{code: ErrCodeWriteDenied, path: fs.relative(sp), resolved: sp})
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// relative returns the path relative to the root directory, using forward
+// slashes, for a path that has already been resolved within it.
+func (fs *Filesystem) relative(sp string) string {
+	return filepath.ToSlash(strings.TrimPrefix(strings.TrimPrefix(sp, fs.Path()), string(filepath.Separator)))
+}
+
+// writeDenied returns true if the relative path matches the write denylist.
+func writeDenied(denylist *ignore.GitIgnore, rel string, dir bool) bool {
+	if dir {
+		rel += "/"
+	}
+	return denylist.MatchesPath(foldCase(rel))
+}
+
 // Normalizes a directory being passed in to ensure the user is not able to escape
 // from their data directory. After normalization if the directory is still within their home
 // path it is returned. If they managed to "escape" an error will be returned.
@@ -46,13 +121,13 @@ func (fs *Filesystem) SafePath(p string) (string, error) {
 	} else if os.IsNotExist(err) {
 		// The requested directory doesn't exist, so at this point we need to iterate up the
 		// path chain until we hit a directory that _does_ exist and can be validated.
-		parts := strings.Split(filepath.Dir(r), "/")
+		parts := strings.Split(filepath.Dir(r), string(filepath.Separator))
 
 		var try string
 		// Range over all of the path parts and form directory pathings from the end
 		// moving up until we have a valid resolution or we run out of paths to try.
 		for k := range parts {
-			try = strings.Join(parts[:(len(parts)-k)], "/")
+			try = strings.Join(parts[:(len(parts)-k)], string(filepath.Separator))
 
 			if !fs.unsafeIsInDataDirectory(try) {
 				break
@@ -72,6 +147,9 @@ func (fs *Filesystem) SafePath(p string) (string, error) {
 		if !fs.unsafeIsInDataDirectory(nonExistentPathResolution) {
 			return "", NewBadPathResolution(p, nonExistentPathResolution)
 		}
+		if err := fs.checkReparsePoints(p, nonExistentPathResolution); err != nil {
+			return "", err
+		}
 
 		// If the nonExistentPathResolution variable is not empty then the initial path requested
 		// did not exist and we looped through the pathway until we found a match. At this point
@@ -84,12 +162,36 @@ func (fs *Filesystem) SafePath(p string) (string, error) {
 	// ahead and return it. If not we'll return an error which will block any further action
 	// on the file.
 	if fs.unsafeIsInDataDirectory(ep) {
+		if err := fs.checkReparsePoints(p, ep); err != nil {
+			return "", err
+		}
 		return ep, nil
 	}
 
 	return "", NewBadPathResolution(p, r)
 }
 
+// writeTarget resolves a path within the root directory that a file is cloned
+// or extracted to, returning an error if the path, or any directory leading up
+// to it, is a symlink. Existing files are opened in place when they are written,
+// so writing through a symlink would change the file it points to instead.
+func (fs *Filesystem) writeTarget(p string) (string, error) {
+	resolved, err := fs.SafePath(p)
+	if err != nil {
+		return "", err
+	}
+	unsafe := fs.unsafeFilePath(p)
+	if resolved != unsafe {
+		return "", NewBadPathResolution(p, resolved)
+	}
+	// A path that does not exist yet is returned as-is by SafePath, so make sure
+	// that the directory it is created in was not reached through a symlink either.
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(unsafe)); err == nil && dir != filepath.Dir(unsafe) {
+		return "", NewBadPathResolution(p, dir)
+	}
+	return resolved, nil
+}
+
 // Generate a path to the file by cleaning it up and appending the root server path to it. This
 // DOES NOT guarantee that the file resolves within the server data directory. You'll want to use
 // the fs.unsafeIsInDataDirectory(p) function to confirm.
@@ -106,7 +208,13 @@ func (fs *Filesystem) unsafeFilePath(p string) string {
 // validate that the rest of the path does not end up resolving out of this directory, or that the
 // targeted file or folder is not a symlink doing the same thing.
 func (fs *Filesystem) unsafeIsInDataDirectory(p string) bool {
-	return strings.HasPrefix(strings.TrimSuffix(p, "/")+"/", strings.TrimSuffix(fs.Path(), "/")+"/")
+	return isInDirectory(p, fs.Path())
+}
+
+// isInDirectory returns true if the path is the given directory, or is within it.
+func isInDirectory(p string, dir string) bool {
+	sep := string(filepath.Separator)
+	return strings.HasPrefix(strings.TrimSuffix(p, sep)+sep, strings.TrimSuffix(dir, sep)+sep)
 }
 
 // Executes the fs.SafePath function in parallel against an array of paths. If any of the calls
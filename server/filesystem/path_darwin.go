@@ -0,0 +1,20 @@
+package filesystem
+
+// checkReparsePoints is a no-op on macOS, filepath.EvalSymlinks already
+// resolves every link within the path.
+func (fs *Filesystem) checkReparsePoints(_ string, _ string) error {
+	return nil
+}
+
+// foldCase returns the path unchanged, matching the behavior on Linux.
+func foldCase(p string) string {
+	return p
+}
+
+// sanitizeName returns the name unchanged, matching the behavior on Linux.
+func sanitizeName(name string) string {
+	return name
+}
+
+// The line ending used by text files on this system.
+const lineEnding = "\n"
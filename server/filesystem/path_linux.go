@@ -0,0 +1,21 @@
+package filesystem
+
+// checkReparsePoints is a no-op on Linux, filepath.EvalSymlinks already
+// resolves every link within the path.
+func (fs *Filesystem) checkReparsePoints(_ string, _ string) error {
+	return nil
+}
+
+// foldCase returns the path unchanged since paths on Linux are case-sensitive.
+func foldCase(p string) string {
+	return p
+}
+
+// sanitizeName returns the name unchanged since every character other than a
+// slash can be used in a file name on Linux.
+func sanitizeName(name string) string {
+	return name
+}
+
+// The line ending used by text files on this system.
+const lineEnding = "\n"
@@ -0,0 +1,121 @@
+package filesystem
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/sys/windows"
+)
+
+// checkReparsePoints ensures that the resolved path does not escape the data
+// directory through a reparse point. filepath.EvalSymlinks only follows
+// symlinks and some junctions, so a crafted junction or volume mount point
+// could otherwise point anywhere on the system. The final path of both the
+// file and the data directory is resolved by Windows itself, which follows
+// every type of reparse point, and then compared.
+func (fs *Filesystem) checkReparsePoints(p string, resolved string) error {
+	root, err := finalPathName(fs.Path())
+	if err != nil {
+		return wrapError(err, fs.Path())
+	}
+	final, err := finalPathName(resolved)
+	if err != nil {
+		// A path that cannot be resolved to a drive letter or share, such as a
+		// volume mounted without one, can never be within the data directory.
+		return NewBadPathResolution(p, resolved)
+	}
+	// Paths on Windows are case-insensitive, so a file in "C:\Servers" is
+	// also within "c:\servers".
+	if !isInDirectory(strings.ToLower(final), strings.ToLower(root)) {
+		return NewBadPathResolution(p, final)
+	}
+	return nil
+}
+
+// finalPathName returns the path to the given file after every reparse point
+// within it has been followed.
+func finalPathName(p string) (string, error) {
+	p16, err := windows.UTF16PtrFromString(p)
+	if err != nil {
+		return "", err
+	}
+	// FILE_FLAG_BACKUP_SEMANTICS is required to open a handle to a directory.
+	h, err := windows.CreateFile(p16, 0, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(h)
+
+	buf := make([]uint16, windows.MAX_PATH)
+	for {
+		// A flag of zero is FILE_NAME_NORMALIZED and VOLUME_NAME_DOS, which returns
+		// the path using a drive letter.
+		n, err := windows.GetFinalPathNameByHandle(h, &buf[0], uint32(len(buf)), 0)
+		if err != nil {
+			return "", err
+		}
+		// If the buffer is too small the returned size includes the terminating
+		// null character.
+		if int(n) < len(buf) {
+			return trimExtendedPrefix(windows.UTF16ToString(buf[:n])), nil
+		}
+		buf = make([]uint16, n)
+	}
+}
+
+// trimExtendedPrefix removes the "\\?\" prefix that is always added to the
+// path returned by GetFinalPathNameByHandle.
+func trimExtendedPrefix(p string) string {
+	if strings.HasPrefix(p, `\\?\UNC\`) {
+		return `\\` + p[len(`\\?\UNC\`):]
+	}
+	return strings.TrimPrefix(p, `\\?\`)
+}
+
+// foldCase returns the path in lowercase so that patterns such as "*.exe" also
+// match "SETUP.EXE", since paths on Windows are not case-sensitive.
+func foldCase(p string) string {
+	return strings.ToLower(p)
+}
+
+// The characters that cannot be used in a file name on Windows. A backslash is
+// included since it would otherwise be treated as a path separator.
+const invalidNameChars = `<>:"|?*\`
+
+// The names that are reserved for devices on Windows, which cannot be used as
+// the name of a file even when followed by an extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// sanitizeName returns the slash separated name with every character that
+// cannot be used on Windows replaced with an underscore. Trailing dots and
+// spaces are removed from each part of the name, since they are silently
+// dropped by Windows, and reserved device names are prefixed with an underscore.
+func sanitizeName(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		if p == "" || p == "." || p == ".." {
+			continue
+		}
+		p = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) || strings.ContainsRune(invalidNameChars, r) {
+				return '_'
+			}
+			return r
+		}, p)
+		if p = strings.TrimRight(p, ". "); p == "" {
+			p = "_"
+		}
+		if reservedNames[strings.ToUpper(strings.SplitN(p, ".", 2)[0])] {
+			p = "_" + p
+		}
+		parts[i] = p
+	}
+	return strings.Join(parts, "/")
+}
+
+// The line ending used by text files on this system.
+const lineEnding = "\r\n"
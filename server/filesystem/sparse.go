@@ -0,0 +1,69 @@
+package filesystem
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// The size of the blocks that are checked for zeros when writing a sparse file.
+// Blocks smaller than the cluster size of the filesystem cannot be left as a
+// hole, so this is kept as a multiple of the common cluster sizes.
+const sparseBlockSize = 64 * 1024
+
+var zeroBlock = make([]byte, sparseBlockSize)
+
+// countAllocated returns true if the disk usage of a server should be the space
+// allocated to its files on the disk rather than their logical size.
+func countAllocated() bool {
+	return config.Get().System.DiskUsageMode == config.DiskUsageModeAllocated
+}
+
+// copySparse copies the contents of the reader into the file, seeking over any
+// block that only contains zeros rather than writing it, so that the block is
+// left as a hole in the file and does not take up any space on the disk. If the
+// filesystem does not support sparse files the zeros are written as normal.
+// Returns the number of bytes read from the reader.
+func copySparse(f *os.File, r io.Reader) (int64, error) {
+	buf := make([]byte, sparseBlockSize)
+	var n int64
+	var holes, checked bool
+	for {
+		c, err := io.ReadFull(r, buf)
+		if c > 0 {
+			zero := bytes.Equal(buf[:c], zeroBlock[:c])
+			// The file is only marked as sparse once the first hole is found, so that
+			// files without any holes are written as normal.
+			if zero && !checked {
+				holes = markSparse(f) == nil
+				checked = true
+			}
+			if zero && holes {
+				if _, err := f.Seek(int64(c), io.SeekCurrent); err != nil {
+					return n, errors.WithStackIf(err)
+				}
+			} else if _, err := f.Write(buf[:c]); err != nil {
+				return n, errors.WithStackIf(err)
+			}
+			n += int64(c)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return n, errors.WithStackIf(err)
+		}
+	}
+	// Seeking past the end of the file does not change its size, so the file is
+	// extended to the full size in case it ends with a hole.
+	if holes {
+		if err := f.Truncate(n); err != nil {
+			return n, errors.WithStackIf(err)
+		}
+	}
+	return n, nil
+}
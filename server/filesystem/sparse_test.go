@@ -0,0 +1,54 @@
+package filesystem
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestFilesystem_CopySparse(t *testing.T) {
+	g := Goblin(t)
+	dir, err := ioutil.TempDir(os.TempDir(), "pterodactyl")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g.Describe("copySparse", func() {
+		g.It("writes the contents of a file that contains holes", func() {
+			data := make([]byte, sparseBlockSize*4+100)
+			copy(data[sparseBlockSize:], "hello")
+			copy(data[len(data)-5:], "world")
+
+			f, err := os.Create(filepath.Join(dir, "sparse.bin"))
+			g.Assert(err).IsNil()
+			n, err := copySparse(f, bytes.NewReader(data))
+			g.Assert(err).IsNil()
+			g.Assert(f.Close()).IsNil()
+			g.Assert(n).Equal(int64(len(data)))
+
+			b, err := ioutil.ReadFile(f.Name())
+			g.Assert(err).IsNil()
+			g.Assert(bytes.Equal(b, data)).IsTrue()
+		})
+
+		g.It("keeps the size of a file that ends with a hole", func() {
+			data := make([]byte, sparseBlockSize*3)
+			copy(data, "hello")
+
+			f, err := os.Create(filepath.Join(dir, "trailing.bin"))
+			g.Assert(err).IsNil()
+			_, err = copySparse(f, bytes.NewReader(data))
+			g.Assert(err).IsNil()
+			g.Assert(f.Close()).IsNil()
+
+			st, err := os.Stat(f.Name())
+			g.Assert(err).IsNil()
+			g.Assert(st.Size()).Equal(int64(len(data)))
+		})
+	})
+}
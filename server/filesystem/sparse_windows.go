@@ -0,0 +1,42 @@
+package filesystem
+
+import (
+	"os"
+	"unsafe"
+
+	"emperror.dev/errors"
+	"golang.org/x/sys/windows"
+)
+
+var procGetCompressedFileSizeW = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetCompressedFileSizeW")
+
+// The value returned by GetCompressedFileSizeW when the call fails.
+const invalidFileSize = 0xFFFFFFFF
+
+// markSparse marks the file as sparse using FSCTL_SET_SPARSE, so that regions of
+// the file that are never written to are left as holes rather than being filled
+// with zeros by NTFS. Returns an error if the volume does not support sparse
+// files, such as FAT32 and exFAT volumes.
+func markSparse(f *os.File) error {
+	var returned uint32
+	err := windows.DeviceIoControl(windows.Handle(f.Fd()), windows.FSCTL_SET_SPARSE, nil, 0, nil, 0, &returned, nil)
+	return errors.WithStackIf(err)
+}
+
+// allocatedSize returns the number of bytes allocated on the disk for the file
+// at the given path. This is smaller than the logical size of the file if it is
+// sparse or compressed.
+func allocatedSize(p string) (int64, error) {
+	name, err := windows.UTF16PtrFromString(p)
+	if err != nil {
+		return 0, errors.WithStackIf(err)
+	}
+	var high uint32
+	low, _, err := procGetCompressedFileSizeW.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&high)))
+	// INVALID_FILE_SIZE is only an error if GetLastError is also set, since it is
+	// also a valid value for the low order bits of the size.
+	if uint32(low) == invalidFileSize && err != windows.ERROR_SUCCESS {
+		return 0, errors.Wrap(err, "server/filesystem: failed to get allocated file size")
+	}
+	return int64(high)<<32 | int64(uint32(low)), nil
+}
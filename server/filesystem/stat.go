@@ -12,32 +12,37 @@ import (
 type Stat struct {
 	os.FileInfo
 	Mimetype string
+	// Attributes contains the platform specific attributes of the file, this is
+	// only present on Windows.
+	Attributes *FileAttributes
 }
 
 func (s *Stat) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		Name      string `json:"name"`
-		Created   string `json:"created"`
-		Modified  string `json:"modified"`
-		Mode      string `json:"mode"`
-		ModeBits  string `json:"mode_bits"`
-		Size      int64  `json:"size"`
-		Directory bool   `json:"directory"`
-		File      bool   `json:"file"`
-		Symlink   bool   `json:"symlink"`
-		Mime      string `json:"mime"`
+		Name       string          `json:"name"`
+		Created    string          `json:"created"`
+		Modified   string          `json:"modified"`
+		Mode       string          `json:"mode"`
+		ModeBits   string          `json:"mode_bits"`
+		Size       int64           `json:"size"`
+		Directory  bool            `json:"directory"`
+		File       bool            `json:"file"`
+		Symlink    bool            `json:"symlink"`
+		Mime       string          `json:"mime"`
+		Attributes *FileAttributes `json:"attributes,omitempty"`
 	}{
 		Name:     s.Name(),
 		Created:  s.CTime().Format(time.RFC3339),
 		Modified: s.ModTime().Format(time.RFC3339),
 		Mode:     s.Mode().String(),
 		// Using `&os.ModePerm` on the file's mode will cause the mode to only have the permission values, and nothing else.
-		ModeBits:  strconv.FormatUint(uint64(s.Mode()&os.ModePerm), 8),
-		Size:      s.Size(),
-		Directory: s.IsDir(),
-		File:      !s.IsDir(),
-		Symlink:   s.Mode().Perm()&os.ModeSymlink != 0,
-		Mime:      s.Mimetype,
+		ModeBits:   strconv.FormatUint(uint64(s.Mode()&os.ModePerm), 8),
+		Size:       s.Size(),
+		Directory:  s.IsDir(),
+		File:       !s.IsDir(),
+		Symlink:    s.Mode().Perm()&os.ModeSymlink != 0,
+		Mime:       s.Mimetype,
+		Attributes: s.Attributes,
 	})
 }
 
@@ -66,8 +71,9 @@ func (fs *Filesystem) unsafeStat(p string) (Stat, error) {
 	}
 
 	st := Stat{
-		FileInfo: s,
-		Mimetype: "inode/directory",
+		FileInfo:   s,
+		Mimetype:   "inode/directory",
+		Attributes: fileAttributes(p, s),
 	}
 	if m != nil {
 		st.Mimetype = m.String()
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/system"
+)
+
+// The time that Wings started, used to report the uptime of the node.
+var bootTime = time.Now()
+
+// Heartbeat returns the status of the node reported to the Panel for the tenant.
+// The servers and operations counted are only those belonging to the tenant, an
+// empty tenant name is the primary Panel. The headroom is that of the node, but
+// is limited by the total resources the tenant is allowed to allocate.
+func (m *Manager) Heartbeat(tenant string) remote.Heartbeat {
+	h := remote.Heartbeat{
+		Version: system.Version,
+		Uptime:  int64(time.Since(bootTime).Seconds()),
+	}
+	// The resources allocated to the servers belonging to the tenant.
+	var used NodeResources
+	for _, s := range m.Filter(func(s *Server) bool { return m.ServerTenant(s.ID()) == tenant }) {
+		h.Servers++
+		if s.Environment.State() != environment.ProcessOfflineState {
+			h.RunningServers++
+		}
+		if s.IsInstalling() {
+			h.Pending.Installs++
+		}
+		if s.IsRestoring() {
+			h.Pending.Restores++
+		}
+		if s.IsTransferring() {
+			h.Pending.Transfers++
+		}
+		h.Pending.Backups += backups.count(s.ID())
+
+		b := s.Config().Build
+		used.Memory.add(b.MemoryLimit, false)
+		used.Cpu.add(b.CpuLimit, false)
+		used.Disk.add(b.DiskSpace, false)
+	}
+
+	var limits config.TenantLimits
+	if t, ok := config.Get().Tenant(tenant); ok {
+		limits = t.Limits
+	}
+	h.Headroom = tenantHeadroom(m.Resources(), limits, used)
+	return h
+}
+
+// tenantHeadroom returns the amount of each resource that can still be allocated
+// to the servers of a tenant, which is the headroom of the node unless the tenant
+// has less left within its own limits.
+func tenantHeadroom(r NodeResources, limits config.TenantLimits, used NodeResources) remote.HeartbeatHeadroom {
+	r = tenantResources(r, limits, used)
+	return remote.HeartbeatHeadroom{
+		Memory: r.Memory.Available,
+		Cpu:    r.Cpu.Available,
+		Disk:   r.Disk.Available,
+	}
+}
+
+// StartHeartbeat reports the status of the node to the primary Panel, and the
+// Panel for every tenant, every HeartbeatInterval seconds until the context is
+// canceled. Clients that are unable to send heartbeats are skipped.
+func (m *Manager) StartHeartbeat(ctx context.Context) {
+	interval := config.Get().RemoteQuery.HeartbeatInterval
+	if interval <= 0 {
+		return
+	}
+
+	log.WithField("interval", interval).Info("starting heartbeat to the Panel")
+	ticker := time.NewTicker(time.Second * time.Duration(interval))
+	go func() {
+		defer ticker.Stop()
+		m.sendHeartbeats(ctx)
+		for {
+			select {
+			case <-ticker.C:
+				m.sendHeartbeats(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (m *Manager) sendHeartbeats(ctx context.Context) {
+	clients := map[string]remote.Client{"": m.client}
+	m.mu.RLock()
+	for name, c := range m.tenantClients {
+		clients[name] = c
+	}
+	m.mu.RUnlock()
+
+	for tenant, c := range clients {
+		hc, ok := c.(remote.HeartbeatClient)
+		if !ok {
+			continue
+		}
+		if err := hc.SendHeartbeat(ctx, m.Heartbeat(tenant)); err != nil {
+			log.WithField("tenant", tenant).WithField("error", err).Warn("failed to send heartbeat to the Panel")
+		}
+	}
+}
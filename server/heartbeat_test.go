@@ -0,0 +1,65 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/remote"
+)
+
+func TestTenantHeadroom(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("tenantHeadroom", func() {
+		node := NodeResources{
+			Memory: NodeResource{Available: 8192},
+			Cpu:    NodeResource{Available: 400},
+			Disk:   NodeResource{Available: -1024},
+		}
+
+		g.It("reports the headroom of the node when the tenant has no limits", func() {
+			h := tenantHeadroom(node, config.TenantLimits{}, NodeResources{})
+			g.Assert(h).Equal(remote.HeartbeatHeadroom{Memory: 8192, Cpu: 400, Disk: -1024})
+		})
+
+		g.It("limits the headroom to what is left within the tenant's limits", func() {
+			var used NodeResources
+			used.Memory.add(3072, false)
+			used.Cpu.add(200, true)
+			used.Disk.add(10240, false)
+
+			h := tenantHeadroom(node, config.TenantLimits{Memory: 4096, Cpu: 1000, Disk: 20480}, used)
+			g.Assert(h.Memory).Equal(int64(1024))
+			// The node has less CPU available than the tenant has left.
+			g.Assert(h.Cpu).Equal(int64(400))
+			// The node is already overcommitted on disk.
+			g.Assert(h.Disk).Equal(int64(-1024))
+		})
+
+		g.It("reports negative headroom when the tenant exceeds its limits", func() {
+			var used NodeResources
+			used.Memory.add(5120, false)
+
+			h := tenantHeadroom(node, config.TenantLimits{Memory: 4096}, used)
+			g.Assert(h.Memory).Equal(int64(-1024))
+		})
+	})
+
+	g.Describe("tenantResources", func() {
+		g.It("only counts the resources allocated to the tenant", func() {
+			node := NodeResources{
+				Memory: NodeResource{Total: 16384, Allowed: 16384, Allocated: 12288, Running: 8192, Available: 4096},
+			}
+			var used NodeResources
+			used.Memory.add(2048, true)
+
+			r := tenantResources(node, config.TenantLimits{}, used)
+			g.Assert(r.Memory).Equal(NodeResource{Total: 16384, Allowed: 16384, Allocated: 2048, Running: 2048, Available: 4096})
+
+			r = tenantResources(node, config.TenantLimits{Memory: 3072}, used)
+			g.Assert(r.Memory).Equal(NodeResource{Total: 3072, Allowed: 3072, Allocated: 2048, Running: 2048, Available: 1024})
+		})
+	})
+}
@@ -0,0 +1,24 @@
+package server
+
+import "sync"
+
+// installImages holds the image last used to install each server, keyed by the
+// server ID. The image used by an installation is only known once the script has
+// been fetched from the Panel, and the installer container is removed once it has
+// finished, so this is the only record of it.
+var installImages sync.Map
+
+// Images returns the images referenced by every server on the node, including the
+// images used to install them, whether or not the server is running. These are
+// passed to environment.PruneImages so that the images for servers that are
+// stopped, installing, or not yet installed are never removed.
+func (m *Manager) Images() []string {
+	var images []string
+	for _, s := range m.All() {
+		images = append(images, s.Config().Container.Image, s.installerConfiguration().Image)
+		if img, ok := installImages.Load(s.ID()); ok {
+			images = append(images, img.(string))
+		}
+	}
+	return images
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+var ErrImportWhileRunning = errors.Sentinel("server: cannot import files while the server is running")
+
+// IsImportPathAllowed returns true if the given path is within one of the host
+// directories that files are allowed to be imported from through the API.
+func IsImportPathAllowed(p string) bool {
+	p = filepath.Clean(p)
+	for _, allowed := range config.Get().AllowedImportPaths {
+		allowed = filepath.Clean(allowed)
+		if p == allowed || strings.HasPrefix(p, allowed+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Import populates the server with the files from a directory or archive on the
+// host, such as the data for a server being migrated from another panel. The
+// files are owned by the server user once imported, and the import is refused
+// if the files would not fit within the disk limit of the server.
+func (s *Server) Import(ctx context.Context, source string) error {
+	if s.Environment.State() != environment.ProcessOfflineState {
+		return ErrImportWhileRunning
+	}
+	st, err := os.Stat(source)
+	if err != nil {
+		return errors.Wrap(err, "server: failed to stat import source")
+	}
+	if err := s.PrepareDataDirectory(); err != nil {
+		return err
+	}
+
+	s.Log().WithField("source", source).Info("importing files for server")
+	start := time.Now()
+	if st.IsDir() {
+		err = s.Filesystem().ImportDirectory(ctx, source)
+	} else {
+		err = s.Filesystem().ImportArchive(source)
+	}
+	if err != nil {
+		return errors.WrapIf(err, "server: failed to import files")
+	}
+	s.Log().WithFields(log.Fields{
+		"source":         source,
+		"execution_time": time.Since(start),
+	}).Info("completed importing files for server")
+
+	// Update the disk usage for the server now that the files have been imported.
+	s.Filesystem().HasSpaceAvailable(false)
+
+	return nil
+}
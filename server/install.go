@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"fmt"
 	"html/template"
 	"io"
 	"os"
@@ -18,7 +19,9 @@ import (
 	"github.com/docker/docker/client"
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/events/webhook"
 	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/server/filesystem"
 	"github.com/pterodactyl/wings/system"
 )
 
@@ -29,27 +32,48 @@ import (
 // Pass true as the first argument in order to execute a server sync before the
 // process to ensure the latest information is used.
 func (s *Server) Install(sync bool) error {
+	return s.install(sync, "")
+}
+
+// install executes the installation process for the server. If the installation
+// fails the server files are rolled back to the state they were in before the
+// installation, using the backup at the given path if one was created.
+func (s *Server) install(sync bool, backup string) error {
 	if sync {
 		s.Log().Info("syncing server state with remote source before executing installation process")
 		if err := s.Sync(); err != nil {
 			return errors.WrapIf(err, "install: failed to sync server state with Panel")
 		}
+		s.SyncWriteDenylist()
 	}
 
 	var err error
+	var rolledBack bool
 	if !s.Config().SkipEggScripts {
 		// Send the start event so the Panel can automatically update. We don't send this unless the process
 		// is actually going to run, otherwise all sorts of weird rapid UI behavior happens since there isn't
 		// an actual install process being executed.
 		s.Events().Publish(InstallStartedEvent, "")
 
+		empty := s.isDataDirectoryEmpty()
+		_, _, done := s.StartOperation(s.Context(), OperationInstall, "")
 		err = s.internalInstall()
+		done()
+		if err != nil {
+			s.reportIncompatibleImage(err, "install")
+			if lerr := s.whileInstalling(func() error {
+				rolledBack = s.rollbackInstall(backup, empty)
+				return nil
+			}); lerr != nil {
+				s.Log().WithField("error", lerr).Warn("not rolling back server files, another installation has already started")
+			}
+		}
 	} else {
 		s.Log().Info("server configured to skip running installation scripts for this egg, not executing process")
 	}
 
 	s.Log().WithField("was_successful", err == nil).Debug("notifying panel of server install state")
-	if serr := s.SyncInstallState(err == nil); serr != nil {
+	if serr := s.syncInstallResult(err, rolledBack); serr != nil {
 		l := s.Log().WithField("was_successful", err == nil)
 
 		// If the request was successful but there was an error with this request, attach the
@@ -66,6 +90,18 @@ func (s *Server) Install(sync bool) error {
 	// with a blank value which is a bit confusing.
 	s.Environment.SetState(environment.ProcessOfflineState)
 
+	// Let anything listening for events know that the server files were rolled back
+	// after the failure.
+	if rolledBack {
+		data := map[string]interface{}{"error": err.Error()}
+		var serr *installScriptError
+		if errors.As(err, &serr) {
+			data["exit_code"] = serr.code
+		}
+		s.Events().Publish(InstallRolledBackEvent, data)
+		webhook.Dispatch(s.ID(), webhook.InstallRolledBackEvent, data)
+	}
+
 	// Push an event to the websocket so we can auto-refresh the information in the panel once
 	// the install is completed.
 	s.Events().Publish(InstallCompletedEvent, "")
@@ -73,9 +109,23 @@ func (s *Server) Install(sync bool) error {
 	return errors.WithStackIf(err)
 }
 
-// Reinstalls a server's software by utilizing the install script for the server egg. This
-// does not touch any existing files for the server, other than what the script modifies.
-func (s *Server) Reinstall() error {
+// ReinstallOptions controls what happens to the existing files for a server
+// when it is reinstalled.
+type ReinstallOptions struct {
+	// Clean removes all the existing files for the server before running the
+	// installation script, except for the preserved paths.
+	Clean bool `json:"clean"`
+
+	// A list of paths to keep when the existing files are removed, in the same
+	// format as a .gitignore file. These are combined with the paths the egg
+	// defines to be preserved.
+	Preserve []string `json:"preserve"`
+}
+
+// Reinstalls a server's software by utilizing the install script for the server egg. Unless
+// a clean reinstall is requested this does not touch any existing files for the server, other
+// than what the script modifies.
+func (s *Server) Reinstall(opts ReinstallOptions) error {
 	if s.Environment.State() != environment.ProcessOfflineState {
 		s.Log().Debug("waiting for server instance to enter a stopped state")
 		if err := s.Environment.WaitForStop(s.Context(), time.Second*10, true); err != nil {
@@ -83,7 +133,143 @@ func (s *Server) Reinstall() error {
 		}
 	}
 
-	return s.Install(true)
+	// Sync the server before touching any files, so that a failure to reach the Panel
+	// does not leave the files removed by a clean reinstall without being restored.
+	s.Log().Info("syncing server state with remote source before executing installation process")
+	if err := s.Sync(); err != nil {
+		return errors.WrapIf(err, "install: failed to sync server state with Panel")
+	}
+	s.SyncWriteDenylist()
+
+	// The server is marked as installing while the files are backed up and removed so
+	// that it cannot be started, or reinstalled again, part way through.
+	var backup string
+	err := s.whileInstalling(func() error {
+		// Create a backup of the server files before running the installation script, so
+		// that they can be restored if the script fails part way through.
+		if config.Get().System.Backups.ReinstallBackup && !s.Config().SkipEggScripts {
+			p, err := s.createReinstallBackup()
+			if err != nil {
+				s.PublishConsoleOutputFromDaemon("Failed to create a backup of the server files, aborting reinstall.")
+				return errors.WrapIf(err, "install: failed to create backup before reinstall")
+			}
+			backup = p
+		}
+
+		if opts.Clean && !s.isDataDirectoryEmpty() {
+			preserve := append(append([]string{}, s.Config().Egg.ReinstallPreserve...), opts.Preserve...)
+			s.Log().WithField("preserve", preserve).Info("removing server files before reinstalling")
+			s.PublishConsoleOutputFromDaemon("Removing server files before reinstalling...")
+			if err := s.Filesystem().TruncateRootDirectoryExcept(preserve); err != nil {
+				if backup != "" {
+					s.rollbackInstall(backup, false)
+				}
+				return errors.WrapIf(err, "install: failed to remove server files before reinstall")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// The Panel marks the server as installing when the reinstall is requested, so
+		// it needs to be told that the reinstall failed.
+		if serr := s.syncInstallResult(err, false); serr != nil {
+			s.Log().WithField("error", serr).Warn("failed to notify panel of server install state")
+		}
+		return err
+	}
+
+	return s.install(false, backup)
+}
+
+// whileInstalling marks the server as installing while fn runs, so that it cannot
+// be started while its files are being changed outside the installation process.
+// An error is returned without running fn if the server is already installing.
+func (s *Server) whileInstalling(fn func() error) error {
+	if !s.installing.SwapIf(true) {
+		return errors.New("install: cannot obtain installation lock")
+	}
+	defer s.installing.Store(false)
+	return fn()
+}
+
+// isDataDirectoryEmpty returns true if the server data directory does not exist
+// or does not contain any files.
+func (s *Server) isDataDirectoryEmpty() bool {
+	entries, err := os.ReadDir(s.Filesystem().Path())
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	return len(entries) == 0
+}
+
+// rollbackInstall restores the server files to the state they were in before a
+// failed installation so that a partially installed server is not left behind.
+// The files are restored from the backup if one was created, otherwise they are
+// only removed if the server did not have any files before the installation.
+// Returns true if the server files were rolled back.
+func (s *Server) rollbackInstall(backup string, empty bool) bool {
+	if backup != "" {
+		s.PublishConsoleOutputFromDaemon("Installation failed, restoring server files from the backup created before reinstalling...")
+		if err := s.Filesystem().ReplaceWithArchive(backup); err != nil {
+			s.Log().WithField("error", err).Error("failed to restore server files after failed installation")
+			s.PublishConsoleOutputFromDaemon("Failed to restore server files, the backup is available at " + backup + ".")
+			return false
+		}
+		s.PublishConsoleOutputFromDaemon("Restored server files from the backup.")
+		return true
+	}
+	if !empty {
+		return false
+	}
+	s.PublishConsoleOutputFromDaemon("Installation failed, removing the files created by the installation script...")
+	if err := s.Filesystem().TruncateRootDirectory(); err != nil {
+		s.Log().WithField("error", err).Error("failed to remove server files after failed installation")
+		return false
+	}
+	return true
+}
+
+// reinstallBackupPath returns the path to the backup created for the server
+// before it is reinstalled.
+func (s *Server) reinstallBackupPath() string {
+	return filepath.Join(config.Get().System.BackupDirectory, "reinstall", s.ID()+".tar.gz")
+}
+
+// createReinstallBackup creates a backup of all the server files, replacing any
+// backup created for a previous reinstall of the server. An empty path is returned
+// if there are no files to back up.
+func (s *Server) createReinstallBackup() (string, error) {
+	// There is nothing to back up if the server data directory was never created.
+	if _, err := os.Stat(s.Filesystem().Path()); os.IsNotExist(err) {
+		return "", nil
+	}
+
+	p := s.reinstallBackupPath()
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return "", errors.WithStackIf(err)
+	}
+
+	s.PublishConsoleOutputFromDaemon("Creating a backup of the server files before reinstalling, this could take a few minutes...")
+	// Write to a temporary file first so that the previous backup is not lost if this
+	// one fails to be created.
+	a := &filesystem.Archive{BasePath: s.Filesystem().Path()}
+	if err := a.Create(s.Context(), p+".tmp"); err != nil {
+		_ = os.Remove(p + ".tmp")
+		return "", err
+	}
+	if err := os.Rename(p+".tmp", p); err != nil {
+		return "", errors.WithStackIf(err)
+	}
+	return p, nil
+}
+
+// RemoveReinstallBackup removes the backup created before the server was last
+// reinstalled, if there is one.
+func (s *Server) RemoveReinstallBackup() error {
+	if err := os.Remove(s.reinstallBackupPath()); err != nil && !os.IsNotExist(err) {
+		return errors.WithStackIf(err)
+	}
+	return nil
 }
 
 // Internal installation function used to simplify reporting back to the Panel.
@@ -92,6 +278,11 @@ func (s *Server) internalInstall() error {
 	if err != nil {
 		return err
 	}
+	if image := s.installerConfiguration().Image; image != "" {
+		s.Log().WithField("image", image).Debug("overriding installation container image for server")
+		script.ContainerImage = image
+	}
+	installImages.Store(s.ID(), script.ContainerImage)
 	p, err := NewInstallationProcess(s, &script)
 	if err != nil {
 		return err
@@ -106,6 +297,27 @@ func (s *Server) internalInstall() error {
 	return nil
 }
 
+// installerConfiguration returns the image and resource limits to use for the
+// installation container, preferring the values defined by the egg over the
+// defaults configured for the node.
+func (s *Server) installerConfiguration() InstallerConfiguration {
+	c := s.Config().Egg.Installer
+	limits := config.Get().Docker.InstallerLimits
+	if c.Image == "" {
+		c.Image = config.Get().Docker.InstallerImage
+	}
+	if c.Memory == 0 {
+		c.Memory = limits.Memory
+	}
+	if c.Cpu == 0 {
+		c.Cpu = limits.Cpu
+	}
+	if c.Disk == 0 {
+		c.Disk = limits.Disk
+	}
+	return c
+}
+
 type InstallationProcess struct {
 	Server *Server
 	Script *remote.InstallationScript
@@ -181,12 +393,24 @@ func (ip *InstallationProcess) Run() error {
 		ip.Server.installing.Store(false)
 	}()
 
+	// Wait for other installations on the node to complete if the maximum number of them
+	// are already running. The server is marked as installing while it waits so that it
+	// cannot be started.
+	release, err := installs.acquire(ip.Server.Context(), ip.Server)
+	if err != nil {
+		return errors.WrapIf(err, "install: error while waiting for other installations to complete")
+	}
+	defer release()
+
 	if err := ip.BeforeExecute(); err != nil {
 		return err
 	}
 
+	// If the installation script itself failed the container is kept around until the
+	// logs have been written to the disk, so they can be used to determine what went wrong.
 	cID, err := ip.Execute()
-	if err != nil {
+	var serr *installScriptError
+	if err != nil && !errors.As(err, &serr) {
 		_ = ip.RemoveContainer()
 		return err
 	}
@@ -197,7 +421,41 @@ func (ip *InstallationProcess) Run() error {
 		ip.Server.Log().WithField("error", err).Warn("failed to complete after-execute step of installation process")
 	}
 
-	return nil
+	return err
+}
+
+// installScript is the file that an installation script is written to within the
+// install directory, and the command used to run it within the container.
+type installScript struct {
+	// The name of the file the script is written to.
+	Name string
+	// The command executed within the installation container to run the script.
+	Cmd []string
+	// The line ending used for every line of the script.
+	LineEnding string
+}
+
+// isPowerShell returns true if the entrypoint for an installation script is
+// Windows PowerShell or PowerShell Core.
+func isPowerShell(entrypoint string) bool {
+	name := executableName(entrypoint)
+	return name == "powershell" || name == "pwsh"
+}
+
+// isCommandPrompt returns true if the entrypoint for an installation script is
+// the Windows command prompt.
+func isCommandPrompt(entrypoint string) bool {
+	return executableName(entrypoint) == "cmd"
+}
+
+// executableName returns the lowercase name of an executable without the
+// directory or ".exe" extension, accepting both Windows and Unix style paths.
+func executableName(p string) string {
+	name := strings.ToLower(strings.TrimSpace(p))
+	if i := strings.LastIndexAny(name, "\\/"); i != -1 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, ".exe")
 }
 
 // Returns the location of the temporary data for the installation process.
@@ -214,7 +472,8 @@ func (ip *InstallationProcess) writeScriptToDisk() error {
 		return errors.WithMessage(err, "could not create temporary directory for install process")
 	}
 
-	f, err := os.OpenFile(filepath.Join(ip.tempDir(), "install.sh"), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	script := getInstallScript(ip.Script.Entrypoint)
+	f, err := os.OpenFile(filepath.Join(ip.tempDir(), script.Name), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
 	if err != nil {
 		return errors.WithMessage(err, "failed to write server installation script to disk before mount")
 	}
@@ -222,9 +481,11 @@ func (ip *InstallationProcess) writeScriptToDisk() error {
 
 	w := bufio.NewWriter(f)
 
+	// Scanning the lines strips any existing line endings, so the script is always written
+	// with the line endings expected by the shell that runs it.
 	scanner := bufio.NewScanner(bytes.NewReader([]byte(ip.Script.Script)))
 	for scanner.Scan() {
-		w.WriteString(scanner.Text() + "\n")
+		w.WriteString(scanner.Text() + script.LineEnding)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -238,6 +499,11 @@ func (ip *InstallationProcess) writeScriptToDisk() error {
 
 // Pulls the docker image to be used for the installation container.
 func (ip *InstallationProcess) pullInstallationImage() error {
+	pull, err := environment.ShouldPullImage(ip.Server.Context(), ip.client, ip.Script.ContainerImage, ip.Server.Config().Container.PullPolicy)
+	if err != nil || !pull {
+		return err
+	}
+
 	// Get a registry auth configuration from the config.
 	var registryAuth *config.RegistryConfiguration
 	for registry, c := range config.Get().Docker.Registries {
@@ -262,6 +528,14 @@ func (ip *InstallationProcess) pullInstallationImage() error {
 		imagePullOptions.RegistryAuth = b64
 	}
 
+	// Pull the manifest for the platform of the node when the image is published
+	// for several platforms.
+	platform, err := environment.SelectPlatform(ip.Server.Context(), ip.client, ip.Script.ContainerImage, imagePullOptions.RegistryAuth)
+	if err != nil {
+		return err
+	}
+	imagePullOptions.Platform = platform
+
 	r, err := ip.client.ImagePull(ip.Server.Context(), ip.Script.ContainerImage, imagePullOptions)
 	if err != nil {
 		images, ierr := ip.client.ImageList(ip.Server.Context(), types.ImageListOptions{})
@@ -318,6 +592,9 @@ func (ip *InstallationProcess) BeforeExecute() error {
 	if err := ip.pullInstallationImage(); err != nil {
 		return errors.WithMessage(err, "failed to pull updated installation container image for server")
 	}
+	if err := environment.CheckImageCompatibility(ip.Server.Context(), ip.client, ip.Script.ContainerImage); err != nil {
+		return err
+	}
 	if err := ip.RemoveContainer(); err != nil {
 		return errors.WithMessage(err, "failed to remove existing install container for server")
 	}
@@ -346,12 +623,21 @@ func (ip *InstallationProcess) AfterExecute(containerId string) error {
 		return err
 	}
 
-	f, err := os.OpenFile(ip.GetLogPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	f, err := openInstallLog(ip.GetLogPath())
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
+	// The most recent log is always written to the same path, and a copy is kept for
+	// each attempt so that the logs for previous installations can be retrieved.
+	attempt, err := ip.Server.newInstallLog()
+	if err != nil {
+		return err
+	}
+	defer attempt.Close()
+	w := io.MultiWriter(f, attempt)
+
 	// We write the contents of the container output to a more "permanent" file so that they
 	// can be referenced after this container is deleted. We'll also include the environment
 	// variables passed into the container to make debugging things a little easier.
@@ -369,7 +655,7 @@ func (ip *InstallationProcess) AfterExecute(containerId string) error {
 |
 | Environment Variables
 | ------------------------------
-{{ range $key, $value := .Server.GetEnvironmentVariables }}  {{ $value }}
+{{ range $key, $value := .EnvironmentVariables }}  {{ $value }}
 {{ end }}
 
 |
@@ -380,15 +666,21 @@ func (ip *InstallationProcess) AfterExecute(containerId string) error {
 		return err
 	}
 
-	if err := tmpl.Execute(f, ip); err != nil {
+	if err := tmpl.Execute(w, ip); err != nil {
 		return err
 	}
 
-	if _, err := io.Copy(f, reader); err != nil {
-		return err
-	}
+	return system.ScanReader(reader, func(line []byte) {
+		_, _ = w.Write(ip.Server.RedactSecrets(line))
+		_, _ = w.Write([]byte{'\n'})
+	})
+}
 
-	return nil
+// EnvironmentVariables returns the environment variables passed to the
+// installation container, with the values of any secrets redacted so that they
+// can be written to the installation log.
+func (ip *InstallationProcess) EnvironmentVariables() []string {
+	return ip.Server.redactedEnvironmentVariables()
 }
 
 // Execute executes the installation process inside a specially created docker
@@ -408,11 +700,11 @@ func (ip *InstallationProcess) Execute() (string, error) {
 	// to trigger the reinstall of the server. It is possible the directory would
 	// not exist when this runs if Wings boots with a missing directory and a user
 	// triggers a reinstall before trying to start the server.
-	if err := ip.Server.EnsureDataDirectoryExists(); err != nil {
+	if err := ip.Server.PrepareDataDirectory(); err != nil {
 		return "", err
 	}
 
-	ip.Server.Log().WithField("install_script", ip.tempDir()+"/install.sh").Info("creating install container for server process")
+	ip.Server.Log().WithField("install_script", filepath.Join(ip.tempDir(), getInstallScript(ip.Script.Entrypoint).Name)).Info("creating install container for server process")
 	// Remove the temporary directory when the installation process finishes for this server container.
 	defer func() {
 		if err := os.RemoveAll(ip.tempDir()); err != nil {
@@ -422,6 +714,15 @@ func (ip *InstallationProcess) Execute() (string, error) {
 		}
 	}()
 
+	var initial int64
+	if ip.Server.installerConfiguration().Disk > 0 {
+		size, err := ip.Server.Filesystem().DirectorySize("/")
+		if err != nil {
+			return "", errors.WrapIf(err, "install: failed to determine disk usage before installation")
+		}
+		initial = size
+	}
+
 	r, err := ip.client.ContainerCreate(ctx, conf, hostConf, nil, nil, ip.Server.ID()+"_installer")
 	if err != nil {
 		return "", err
@@ -444,6 +745,15 @@ func (ip *InstallationProcess) Execute() (string, error) {
 		}
 	}(r.ID)
 
+	// Stop the installation process if it writes more than the disk limit for the
+	// installer, since bind mounts cannot be limited by Docker itself. The limit only
+	// applies to the files written by the installation, so that reinstalling a server
+	// that already has more files than the limit does not fail straight away.
+	exceeded := make(chan struct{})
+	if limit := ip.Server.installerConfiguration().Disk; limit > 0 {
+		go ip.watchDiskUsage(ctx, r.ID, initial+limit*1024*1024, exceeded)
+	}
+
 	sChan, eChan := ip.client.ContainerWait(ctx, r.ID, container.WaitConditionNotRunning)
 	select {
 	case err := <-eChan:
@@ -453,12 +763,49 @@ func (ip *InstallationProcess) Execute() (string, error) {
 		} else {
 			return "", err
 		}
-	case <-sChan:
+	case status := <-sChan:
+		select {
+		case <-exceeded:
+			ip.Server.Events().Publish(DaemonMessageEvent, "Installation process exceeded the disk space limit for the installer and was stopped.")
+			return r.ID, &installScriptError{code: status.StatusCode, diskExceeded: true}
+		default:
+		}
+		if status.StatusCode != 0 {
+			ip.Server.Log().WithField("exit_code", status.StatusCode).Warn("installation script exited with a non-zero exit code")
+			ip.Server.Events().Publish(DaemonMessageEvent, fmt.Sprintf("Installation process failed, the installation script exited with code %d.", status.StatusCode))
+			return r.ID, &installScriptError{code: status.StatusCode}
+		}
+		ip.Server.Events().Publish(DaemonMessageEvent, "Installation process completed.")
 	}
 
 	return r.ID, nil
 }
 
+// watchDiskUsage periodically checks the size of the server files while the
+// installation container is running, and kills the container if they exceed the
+// limit in bytes. The exceeded channel is closed before the container is killed.
+func (ip *InstallationProcess) watchDiskUsage(ctx context.Context, id string, limit int64, exceeded chan struct{}) {
+	ticker := time.NewTicker(time.Second * 10)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			size, err := ip.Server.Filesystem().DirectorySize("/")
+			if err != nil || size <= limit {
+				continue
+			}
+			ip.Server.Log().WithFields(log.Fields{"size": size, "limit": limit}).Warn("installation process exceeded disk space limit, stopping container")
+			close(exceeded)
+			if err := ip.client.ContainerKill(ctx, id, "SIGKILL"); err != nil && !client.IsErrNotFound(err) {
+				ip.Server.Log().WithField("error", err).Warn("failed to kill installation container after exceeding disk space limit")
+			}
+			return
+		}
+	}
+}
+
 // StreamOutput streams the output of the installation process to a log file in
 // the server configuration directory, as well as to a websocket listener so
 // that the process can be viewed in the panel by administrators.
@@ -470,40 +817,34 @@ func (ip *InstallationProcess) StreamOutput(ctx context.Context, id string) erro
 	}
 	defer reader.Close()
 
-	err = system.ScanReader(reader, ip.Server.Sink(system.InstallSink).Push)
+	err = system.ScanReader(reader, func(line []byte) {
+		line = ip.Server.RedactSecrets(line)
+		ip.Server.Sink(system.InstallSink).Push(line)
+		ip.Server.writeConsoleLog(consoleLogSourceInstall, line)
+	})
 	if err != nil && !errors.Is(err, context.Canceled) {
 		ip.Server.Log().WithFields(log.Fields{"container_id": id, "error": err}).Warn("error processing install output lines")
 	}
 	return nil
 }
 
-// resourceLimits returns the install container specific resource limits. This
-// looks at the globally defined install container limits and attempts to use
-// the higher of the two (defined limits & server limits). This allows for servers
-// with super low limits (e.g. Discord bots with 128Mb of memory) to perform more
-// intensive installation processes if needed.
+// resourceLimits returns the install container specific resource limits. The
+// memory and CPU limits for the installer are used in place of the server's own
+// limits. This allows for servers with super low limits (e.g. Discord bots with
+// 128Mb of memory) to perform more intensive installation processes if needed.
 //
 // This also avoids a server with limits such as 4GB of memory from accidentally
 // consuming 2-5x the defined limits during the install process and causing
 // system instability.
 func (ip *InstallationProcess) resourceLimits() container.Resources {
-	limits := config.Get().Docker.InstallerLimits
+	limits := ip.Server.installerConfiguration()
 
 	// Create a copy of the configuration so we're not accidentally making changes
 	// to the underlying server build data.
 	c := *ip.Server.Config()
 	cfg := c.Build
-	if cfg.MemoryLimit < limits.Memory {
-		cfg.MemoryLimit = limits.Memory
-	}
-	// Only apply the CPU limit if neither one is currently set to unlimited. If the
-	// installer CPU limit is unlimited don't even waste time with the logic, just
-	// set the config to unlimited for this.
-	if limits.Cpu == 0 {
-		cfg.CpuLimit = 0
-	} else if cfg.CpuLimit != 0 && cfg.CpuLimit < limits.Cpu {
-		cfg.CpuLimit = limits.Cpu
-	}
+	cfg.MemoryLimit = limits.Memory
+	cfg.CpuLimit = limits.Cpu
 
 	resources := cfg.AsContainerResources()
 	// Explicitly remove the PID limits for the installation container. These scripts are
@@ -521,3 +862,20 @@ func (ip *InstallationProcess) resourceLimits() container.Resources {
 func (s *Server) SyncInstallState(successful bool) error {
 	return s.client.SetInstallationStatus(s.Context(), s.ID(), successful)
 }
+
+// syncInstallResult notifies the Panel of the result of the installation process
+// for the server, including whether the server files were rolled back and the
+// exit code of the installation script if it failed. If the client is not able
+// to send the full result only the success of the installation is sent.
+func (s *Server) syncInstallResult(err error, rolledBack bool) error {
+	c, ok := s.client.(remote.InstallationStatusClient)
+	if !ok {
+		return s.SyncInstallState(err == nil)
+	}
+	status := remote.InstallationStatus{Successful: err == nil, RolledBack: rolledBack}
+	var serr *installScriptError
+	if errors.As(err, &serr) {
+		status.ExitCode = &serr.code
+	}
+	return c.SendInstallationStatus(s.Context(), s.ID(), status)
+}
@@ -6,8 +6,19 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
 )
 
+// getInstallScript returns the file the installation script is written to and the
+// command used to run it. Scripts are always run as shell scripts on Linux.
+func getInstallScript(entrypoint string) installScript {
+	return installScript{
+		Name:       "install.sh",
+		Cmd:        []string{entrypoint, "/mnt/install/install.sh"},
+		LineEnding: "\n",
+	}
+}
+
 func getContainerConfig(ip *InstallationProcess) *container.Config {
 	return &container.Config{
 		Hostname:     "installer",
@@ -16,7 +27,7 @@ func getContainerConfig(ip *InstallationProcess) *container.Config {
 		AttachStdin:  true,
 		OpenStdin:    true,
 		Tty:          true,
-		Cmd:          []string{ip.Script.Entrypoint, "/mnt/install/install.sh"},
+		Cmd:          getInstallScript(ip.Script.Entrypoint).Cmd,
 		Image:        ip.Script.ContainerImage,
 		Env:          ip.Server.GetEnvironmentVariables(),
 		Labels: map[string]string{
@@ -48,16 +59,28 @@ func getContainerHostConfig(ip *InstallationProcess) *container.HostConfig {
 		Tmpfs: map[string]string{
 			"/tmp": "rw,exec,nosuid,size=" + tmpfsSize + "M",
 		},
-		DNS: config.Get().Docker.Network.Dns,
-		LogConfig: container.LogConfig{
-			Type: "local",
-			Config: map[string]string{
-				"max-size": "5m",
-				"max-file": "1",
-				"compress": "false",
-			},
-		},
+		DNS:         config.Get().Docker.Network.Dns,
+		LogConfig:   getInstallerLogConfig(),
 		Privileged:  true,
 		NetworkMode: container.NetworkMode(config.Get().Docker.Network.Mode),
 	}
 }
+
+// getInstallerLogConfig returns the logging configuration for the installation
+// container. Podman does not support the "local" log driver.
+func getInstallerLogConfig() container.LogConfig {
+	if environment.IsPodman() {
+		return container.LogConfig{
+			Type:   "json-file",
+			Config: map[string]string{"max-size": "5m"},
+		}
+	}
+	return container.LogConfig{
+		Type: "local",
+		Config: map[string]string{
+			"max-size": "5m",
+			"max-file": "1",
+			"compress": "false",
+		},
+	}
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/loggers/rotate"
+)
+
+// The format used for the ID of an installation log, which is the time that the
+// installation process finished and the log was written.
+const installLogFormat = "20060102T150405Z"
+
+var installLogRegex = regexp.MustCompile(`^\d{8}T\d{6}Z$`)
+
+// InstallLog is the log written for a single attempt at installing a server. The
+// size is the size of the file on the disk, which is compressed for the logs of
+// previous attempts when Wings is rotating its own logs.
+type InstallLog struct {
+	ID        string    `json:"id"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+
+	name string
+}
+
+// installLogDirectory returns the directory that the log for every installation
+// attempt of the server is written to.
+func (s *Server) installLogDirectory() string {
+	return filepath.Join(config.Get().System.LogDirectory, "install", s.ID())
+}
+
+// InstallLogs returns the logs for the previous installation attempts of the
+// server, with the most recent attempt first.
+func (s *Server) InstallLogs() ([]InstallLog, error) {
+	entries, err := os.ReadDir(s.installLogDirectory())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []InstallLog{}, nil
+		}
+		return nil, errors.WithStackIf(err)
+	}
+
+	logs := make([]InstallLog, 0, len(entries))
+	for _, e := range entries {
+		id := strings.TrimSuffix(strings.TrimSuffix(e.Name(), ".gz"), ".log")
+		if e.IsDir() || !installLogRegex.MatchString(id) || (e.Name() != id+".log" && e.Name() != id+".log.gz") {
+			continue
+		}
+		created, err := time.Parse(installLogFormat, id)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		logs = append(logs, InstallLog{ID: id, Size: info.Size(), CreatedAt: created, name: e.Name()})
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].CreatedAt.After(logs[j].CreatedAt)
+	})
+	return logs, nil
+}
+
+// InstallLogPath returns the path to the log for an installation attempt of the
+// server, which may be compressed. An error wrapping os.ErrNotExist is returned if
+// the log does not exist.
+func (s *Server) InstallLogPath(id string) (string, error) {
+	if !installLogRegex.MatchString(id) {
+		return "", errors.Wrap(os.ErrNotExist, "server: invalid install log id")
+	}
+	p := filepath.Join(s.installLogDirectory(), id+".log")
+	if _, err := os.Stat(p); err == nil {
+		return p, nil
+	} else if !os.IsNotExist(err) {
+		return "", errors.WithStackIf(err)
+	}
+	if _, err := os.Stat(p + ".gz"); err != nil {
+		return "", errors.WithStackIf(err)
+	}
+	return p + ".gz", nil
+}
+
+// OpenInstallLog opens the log for an installation attempt of the server,
+// decompressing it if it was compressed. An error wrapping os.ErrNotExist is
+// returned if the log does not exist.
+func (s *Server) OpenInstallLog(id string) (io.ReadCloser, error) {
+	p, err := s.InstallLogPath(id)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+	if filepath.Ext(p) != ".gz" {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.Wrap(err, "server: failed to decompress install log")
+	}
+	return &gzipReadCloser{Reader: gz, f: f}, nil
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (r *gzipReadCloser) Close() error {
+	_ = r.Reader.Close()
+	return r.f.Close()
+}
+
+// newInstallLog creates the log file for a new installation attempt of the
+// server, removing the logs of the oldest attempts beyond the retention limit.
+// When Wings is rotating its own logs the logs of the previous attempts are
+// compressed, if enabled.
+func (s *Server) newInstallLog() (io.WriteCloser, error) {
+	if err := os.MkdirAll(s.installLogDirectory(), 0o700); err != nil {
+		return nil, errors.WithStackIf(err)
+	}
+	if err := s.pruneInstallLogs(config.Get().System.InstallLogRetention - 1); err != nil {
+		s.Log().WithField("error", err).Warn("failed to remove old installation logs")
+	}
+	if c := config.Get().System; c.EnableLogRotate && c.LogRotation.Mode() == config.LogRotationBuiltin && c.LogRotation.Compress {
+		if err := s.compressInstallLogs(); err != nil {
+			s.Log().WithField("error", err).Warn("failed to compress old installation logs")
+		}
+	}
+	return openInstallLog(filepath.Join(s.installLogDirectory(), time.Now().UTC().Format(installLogFormat)+".log"))
+}
+
+// openInstallLog opens a log file that the output of an installation is written
+// to, replacing any existing file. When Wings is rotating its own logs the file is
+// rotated once it reaches the maximum size and only the previous part is kept, so
+// that an installation writing a large amount of output cannot fill the disk.
+func openInstallLog(p string) (io.WriteCloser, error) {
+	c := config.Get().System
+	if !c.EnableLogRotate || c.LogRotation.Mode() != config.LogRotationBuiltin || c.LogRotation.MaxSize <= 0 {
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return nil, errors.WithStackIf(err)
+		}
+		return f, nil
+	}
+	if err := removeInstallLog(p); err != nil {
+		return nil, err
+	}
+	f, err := rotate.Open(p, rotate.Options{
+		MaxSize:    int64(c.LogRotation.MaxSize) * 1024 * 1024,
+		MaxBackups: 1,
+		Compress:   c.LogRotation.Compress,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// removeInstallLog removes the log file at the path, along with any parts of it
+// that were rotated while the installation was running.
+func removeInstallLog(p string) error {
+	for _, name := range []string{p, p + ".gz"} {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return errors.WithStackIf(err)
+		}
+	}
+	rotated, _ := filepath.Glob(strings.TrimSuffix(p, ".log") + "-*.log*")
+	for _, name := range rotated {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return errors.WithStackIf(err)
+		}
+	}
+	return nil
+}
+
+// compressInstallLogs compresses the logs of the previous installation attempts
+// of the server that have not already been compressed.
+func (s *Server) compressInstallLogs() error {
+	logs, err := s.InstallLogs()
+	if err != nil {
+		return err
+	}
+	for _, l := range logs {
+		if filepath.Ext(l.name) == ".gz" {
+			continue
+		}
+		if err := rotate.Compress(filepath.Join(s.installLogDirectory(), l.name)); err != nil {
+			return errors.Wrap(err, "server: failed to compress install log")
+		}
+	}
+	return nil
+}
+
+// pruneInstallLogs removes the logs for all but the most recent installation
+// attempts of the server.
+func (s *Server) pruneInstallLogs(keep int) error {
+	logs, err := s.InstallLogs()
+	if err != nil {
+		return err
+	}
+	if keep < 0 {
+		keep = 0
+	}
+	for i := keep; i < len(logs); i++ {
+		if err := removeInstallLog(filepath.Join(s.installLogDirectory(), logs[i].ID+".log")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneExpiredInstallLogs removes the logs for installation attempts of the
+// server that are older than the maximum age. The log for the most recent
+// attempt is always kept.
+func (s *Server) PruneExpiredInstallLogs(maxAge time.Duration) error {
+	logs, err := s.InstallLogs()
+	if err != nil {
+		return err
+	}
+	for i := 1; i < len(logs); i++ {
+		if time.Since(logs[i].CreatedAt) <= maxAge {
+			continue
+		}
+		if err := removeInstallLog(filepath.Join(s.installLogDirectory(), logs[i].ID+".log")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveInstallLogs removes the logs for every installation attempt of the server.
+func (s *Server) RemoveInstallLogs() error {
+	if err := os.RemoveAll(s.installLogDirectory()); err != nil {
+		return errors.WithStackIf(err)
+	}
+	return removeInstallLog(filepath.Join(config.Get().System.LogDirectory, "install", s.ID()+".log"))
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestInstallLogs(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("InstallLogs", func() {
+		var s *Server
+
+		g.BeforeEach(func() {
+			dir, err := os.MkdirTemp("", "wings-install-logs")
+			g.Assert(err).IsNil()
+			c := &config.Configuration{AuthenticationToken: "test"}
+			c.System.LogDirectory = dir
+			c.System.InstallLogRetention = 10
+			c.System.EnableLogRotate = true
+			c.System.LogRotation = config.LogRotationConfiguration{Strategy: config.LogRotationBuiltin, MaxSize: 1, Compress: true}
+			config.Set(c)
+
+			s = &Server{}
+			s.cfg.Uuid = "abc"
+			g.Assert(os.MkdirAll(s.installLogDirectory(), 0o700)).IsNil()
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(config.Get().System.LogDirectory)
+		})
+
+		write := func(name string, data string) {
+			g.Assert(os.WriteFile(filepath.Join(s.installLogDirectory(), name), []byte(data), 0o600)).IsNil()
+		}
+
+		g.It("compresses the logs of previous attempts", func() {
+			write("20220401T120000Z.log", "first attempt")
+
+			w, err := s.newInstallLog()
+			g.Assert(err).IsNil()
+			_, err = io.WriteString(w, "second attempt")
+			g.Assert(err).IsNil()
+			g.Assert(w.Close()).IsNil()
+
+			_, err = os.Stat(filepath.Join(s.installLogDirectory(), "20220401T120000Z.log.gz"))
+			g.Assert(err).IsNil()
+
+			logs, err := s.InstallLogs()
+			g.Assert(err).IsNil()
+			g.Assert(len(logs)).Equal(2)
+			g.Assert(logs[1].ID).Equal("20220401T120000Z")
+
+			for i, want := range []string{"second attempt", "first attempt"} {
+				r, err := s.OpenInstallLog(logs[i].ID)
+				g.Assert(err).IsNil()
+				b, err := io.ReadAll(r)
+				g.Assert(err).IsNil()
+				g.Assert(r.Close()).IsNil()
+				g.Assert(string(b)).Equal(want)
+			}
+		})
+
+		g.It("does not compress logs when Wings is not rotating them", func() {
+			config.Update(func(c *config.Configuration) {
+				c.System.LogRotation.Strategy = config.LogRotationLogrotate
+			})
+			write("20220401T120000Z.log", "first attempt")
+
+			w, err := s.newInstallLog()
+			g.Assert(err).IsNil()
+			g.Assert(w.Close()).IsNil()
+
+			_, err = os.Stat(filepath.Join(s.installLogDirectory(), "20220401T120000Z.log"))
+			g.Assert(err).IsNil()
+		})
+
+		g.It("rotates a log once it reaches the maximum size", func() {
+			p := filepath.Join(s.installLogDirectory(), "20220401T120000Z.log")
+			w, err := openInstallLog(p)
+			g.Assert(err).IsNil()
+			line := make([]byte, 1024)
+			for i := 0; i < 1500; i++ {
+				_, err = w.Write(line)
+				g.Assert(err).IsNil()
+			}
+			g.Assert(w.Close()).IsNil()
+
+			st, err := os.Stat(p)
+			g.Assert(err).IsNil()
+			g.Assert(st.Size() < 1024*1024).IsTrue()
+		})
+
+		g.It("removes the rotated parts of a log along with it", func() {
+			write("20220401T120000Z.log", "")
+			write("20220401T120000Z-20220401T120010.log.gz", "")
+			write("20220402T120000Z.log", "")
+
+			g.Assert(removeInstallLog(filepath.Join(s.installLogDirectory(), "20220401T120000Z.log"))).IsNil()
+			matches, _ := filepath.Glob(filepath.Join(s.installLogDirectory(), "*"))
+			g.Assert(len(matches)).Equal(1)
+			g.Assert(filepath.Base(matches[0])).Equal("20220402T120000Z.log")
+		})
+
+		g.It("ignores files that are not installation logs", func() {
+			write("20220401T120000Z.log", "first attempt")
+			write("20220402T120000Z.txt", "")
+			write("20220403T120000Z", "")
+			write("20220404T120000Z-20220404T120001.log.gz", "")
+
+			logs, err := s.InstallLogs()
+			g.Assert(err).IsNil()
+			g.Assert(len(logs)).Equal(1)
+
+			_, err = s.OpenInstallLog("../20220401T120000Z")
+			g.Assert(errors.Is(err, os.ErrNotExist)).IsTrue()
+			_, err = s.OpenInstallLog("20220402T120000Z")
+			g.Assert(errors.Is(err, os.ErrNotExist)).IsTrue()
+		})
+	})
+}
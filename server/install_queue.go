@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// installs limits the number of installation processes that run at the same time
+// across every server on the node. Installations that cannot run straight away
+// wait in the order they were started.
+var installs = &installQueue{}
+
+type installQueue struct {
+	mu      sync.Mutex
+	running int
+	waiting []*installTicket
+}
+
+// installTicket is an installation waiting in the queue. The ready channel is
+// closed once the installation is allowed to run.
+type installTicket struct {
+	server *Server
+	ready  chan struct{}
+}
+
+// acquire blocks until the installation for the server is allowed to run,
+// returning a function that must be called once the installation has completed.
+// While waiting an InstallQueuedEvent is published for the server with its position
+// whenever the queue changes. The limit is read every time an installation
+// starts or completes so that changes made to the configuration apply without
+// restarting.
+func (q *installQueue) acquire(ctx context.Context, s *Server) (func(), error) {
+	t := &installTicket{server: s, ready: make(chan struct{})}
+	q.mu.Lock()
+	q.waiting = append(q.waiting, t)
+	queued := q.dispatch()
+	q.mu.Unlock()
+	publishQueuePositions(queued)
+
+	select {
+	case <-t.ready:
+		return q.release, nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		select {
+		case <-t.ready:
+			// The installation was allowed to run at the same time as the context was
+			// canceled, so give up the slot it was given.
+			q.running--
+		default:
+			q.remove(t)
+		}
+		queued := q.dispatch()
+		q.mu.Unlock()
+		publishQueuePositions(queued)
+		return nil, ctx.Err()
+	}
+}
+
+func (q *installQueue) release() {
+	q.mu.Lock()
+	q.running--
+	queued := q.dispatch()
+	q.mu.Unlock()
+	publishQueuePositions(queued)
+}
+
+// dispatch allows the installations at the front of the queue to run while there
+// are fewer than the maximum number running, and returns the installations that
+// are still waiting. The lock must be held by the caller.
+func (q *installQueue) dispatch() []*installTicket {
+	limit := config.Get().Docker.InstallerMaxConcurrent
+	for len(q.waiting) > 0 && (limit <= 0 || q.running < limit) {
+		close(q.waiting[0].ready)
+		q.waiting = q.waiting[1:]
+		q.running++
+	}
+	return append([]*installTicket(nil), q.waiting...)
+}
+
+// remove removes the ticket from the queue, the lock must be held by the caller.
+func (q *installQueue) remove(t *installTicket) {
+	for i, w := range q.waiting {
+		if w == t {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// publishQueuePositions publishes the position of each installation that is
+// waiting in the queue to its server.
+func publishQueuePositions(queued []*installTicket) {
+	for i, t := range queued {
+		t.server.Events().Publish(InstallQueuedEvent, map[string]interface{}{
+			"position": i + 1,
+			"queued":   len(queued),
+		})
+	}
+}
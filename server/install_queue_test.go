@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestInstallQueue(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("installQueue", func() {
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{
+				AuthenticationToken: "token",
+				Docker:              config.DockerConfiguration{InstallerMaxConcurrent: 1},
+			})
+		})
+
+		g.It("runs the waiting installations in the order they were started", func() {
+			q := &installQueue{}
+			waiting := func() int {
+				q.mu.Lock()
+				defer q.mu.Unlock()
+				return len(q.waiting)
+			}
+			release, err := q.acquire(context.Background(), &Server{})
+			g.Assert(err).IsNil()
+
+			order := make(chan int, 2)
+			for i := 1; i <= 2; i++ {
+				go func(i int) {
+					r, err := q.acquire(context.Background(), &Server{})
+					if err == nil {
+						order <- i
+						r()
+					}
+				}(i)
+				// Wait for the installation to be queued before starting the next one.
+				for waiting() < i {
+					time.Sleep(time.Millisecond)
+				}
+			}
+
+			release()
+			g.Assert(<-order).Equal(1)
+			g.Assert(<-order).Equal(2)
+		})
+
+		g.It("removes an installation from the queue when its context is canceled", func() {
+			q := &installQueue{}
+			release, err := q.acquire(context.Background(), &Server{})
+			g.Assert(err).IsNil()
+			defer release()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err = q.acquire(ctx, &Server{})
+			g.Assert(err).Equal(context.Canceled)
+			g.Assert(len(q.waiting)).Equal(0)
+			g.Assert(q.running).Equal(1)
+		})
+	})
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestInstallEntrypoint(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("isPowerShell", func() {
+		g.It("detects PowerShell entrypoints", func() {
+			g.Assert(isPowerShell("powershell")).IsTrue()
+			g.Assert(isPowerShell("pwsh")).IsTrue()
+			g.Assert(isPowerShell("PowerShell.exe")).IsTrue()
+			g.Assert(isPowerShell(`C:\Program Files\PowerShell\7\pwsh.exe`)).IsTrue()
+			g.Assert(isPowerShell("/usr/bin/pwsh")).IsTrue()
+		})
+
+		g.It("does not detect other entrypoints", func() {
+			g.Assert(isPowerShell("bash")).IsFalse()
+			g.Assert(isPowerShell("cmd.exe")).IsFalse()
+			g.Assert(isPowerShell("powershell-wrapper")).IsFalse()
+		})
+	})
+
+	g.Describe("isCommandPrompt", func() {
+		g.It("detects command prompt entrypoints", func() {
+			g.Assert(isCommandPrompt("cmd")).IsTrue()
+			g.Assert(isCommandPrompt(`C:\Windows\System32\CMD.EXE`)).IsTrue()
+			g.Assert(isCommandPrompt("pwsh")).IsFalse()
+		})
+	})
+}
+
+func TestInstallerConfiguration(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("installerConfiguration", func() {
+		g.BeforeEach(func() {
+			c := &config.Configuration{AuthenticationToken: "test"}
+			c.Docker.InstallerLimits.Memory = 1024
+			c.Docker.InstallerLimits.Cpu = 100
+			c.Docker.InstallerImage = "ghcr.io/pterodactyl/installers:debian"
+			config.Set(c)
+		})
+
+		g.It("uses the node defaults when the egg does not set a value", func() {
+			s := &Server{}
+			s.cfg.Build.MemoryLimit = 128
+			g.Assert(s.installerConfiguration()).Equal(InstallerConfiguration{
+				Image:  "ghcr.io/pterodactyl/installers:debian",
+				Memory: 1024,
+				Cpu:    100,
+			})
+		})
+
+		g.It("prefers the values set by the egg", func() {
+			s := &Server{}
+			s.cfg.Egg.Installer = InstallerConfiguration{Image: "ghcr.io/pterodactyl/installers:alpine", Memory: 4096, Disk: 2048}
+			g.Assert(s.installerConfiguration()).Equal(InstallerConfiguration{
+				Image:  "ghcr.io/pterodactyl/installers:alpine",
+				Memory: 4096,
+				Cpu:    100,
+				Disk:   2048,
+			})
+		})
+	})
+}
@@ -8,6 +8,41 @@ import (
 	"github.com/pterodactyl/wings/config"
 )
 
+// The directories that the server files and installation script are mounted to
+// within the installation container. Windows containers require the mount target
+// to be an absolute path that includes the drive letter.
+const (
+	installServerDirectory = "C:\\Pterodactyl-Server"
+	installScriptDirectory = "C:\\Pterodactyl-Install"
+)
+
+// getInstallScript returns the file the installation script is written to and the
+// command used to run it, based on the shell used as the entrypoint. PowerShell
+// scripts are run with the execution policy bypassed, since the script is written
+// to the disk by Wings and would otherwise be blocked from running.
+func getInstallScript(entrypoint string) installScript {
+	switch {
+	case isPowerShell(entrypoint):
+		return installScript{
+			Name:       "install.ps1",
+			Cmd:        []string{entrypoint, "-NoLogo", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", installScriptDirectory + "\\install.ps1"},
+			LineEnding: "\r\n",
+		}
+	case isCommandPrompt(entrypoint):
+		return installScript{
+			Name:       "install.cmd",
+			Cmd:        []string{entrypoint, "/c", installScriptDirectory + "\\install.cmd"},
+			LineEnding: "\r\n",
+		}
+	default:
+		return installScript{
+			Name:       "install.ps1",
+			Cmd:        []string{entrypoint, installScriptDirectory + "\\install.ps1"},
+			LineEnding: "\r\n",
+		}
+	}
+}
+
 func getContainerConfig(ip *InstallationProcess) *container.Config {
 	return &container.Config{
 		Hostname:     "installer",
@@ -16,7 +51,7 @@ func getContainerConfig(ip *InstallationProcess) *container.Config {
 		AttachStdin:  true,
 		OpenStdin:    true,
 		Tty:          true,
-		Cmd:          []string{ip.Script.Entrypoint, "C:\\Pterodactyl-Install\\install.ps1"},
+		Cmd:          getInstallScript(ip.Script.Entrypoint).Cmd,
 		Image:        ip.Script.ContainerImage,
 		Env:          ip.Server.GetEnvironmentVariables(),
 		Labels: map[string]string{
@@ -33,13 +68,13 @@ func getContainerHostConfig(ip *InstallationProcess) *container.HostConfig {
 	return &container.HostConfig{
 		Mounts: []mount.Mount{
 			{
-				Target:   "/Pterodactyl-Server",
+				Target:   installServerDirectory,
 				Source:   ip.Server.Filesystem().Path(),
 				Type:     mount.TypeBind,
 				ReadOnly: false,
 			},
 			{
-				Target:   "/Pterodactyl-Install",
+				Target:   installScriptDirectory,
 				Source:   ip.tempDir(),
 				Type:     mount.TypeBind,
 				ReadOnly: false,
@@ -49,7 +84,8 @@ func getContainerHostConfig(ip *InstallationProcess) *container.HostConfig {
 		Tmpfs: map[string]string{
 			"/tmp": "rw,exec,nosuid,size=" + tmpfsSize + "M",
 		},
-		DNS: config.Get().Docker.Network.Dns,
+		DNS:       config.Get().Docker.Network.Dns,
+		Isolation: container.Isolation(config.Get().Docker.Isolation),
 		LogConfig: container.LogConfig{
 			Type: "local",
 			Config: map[string]string{
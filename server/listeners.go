@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"fmt"
 	"regexp"
 	"strconv"
 	"sync"
@@ -9,8 +10,10 @@ import (
 
 	"github.com/apex/log"
 	"github.com/pterodactyl/wings/events"
-	"github.com/pterodactyl/wings/system"
+	"github.com/pterodactyl/wings/events/publisher"
+	"github.com/pterodactyl/wings/events/webhook"
 
+	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
 	"github.com/pterodactyl/wings/remote"
 )
@@ -46,6 +49,9 @@ func (dsl *diskSpaceLimiter) Reset() {
 func (dsl *diskSpaceLimiter) Trigger() {
 	dsl.o.Do(func() {
 		dsl.server.PublishConsoleOutputFromDaemon("Server is exceeding the assigned disk space limit, stopping process now.")
+		webhook.Dispatch(dsl.server.ID(), webhook.DiskLimitExceededEvent, map[string]interface{}{
+			"disk_limit": dsl.server.DiskSpace(),
+		})
 		if err := dsl.server.Environment.WaitForStop(dsl.server.Context(), time.Minute, true); err != nil {
 			dsl.server.Log().WithField("error", err).Error("failed to stop server after exceeding space limit!")
 		}
@@ -63,6 +69,11 @@ func (s *Server) processConsoleOutputEvent(v []byte) {
 	// the console sending logic.
 	go s.onConsoleOutput(v)
 
+	// Write the output to the console log before throttling it, so that the log
+	// contains everything the server output even when the console is being spammed.
+	line := s.RedactSecrets(v)
+	s.writeConsoleLog(consoleLogSourceConsole, line)
+
 	// If the console is being throttled, do nothing else with it, we don't want
 	// to waste time. This code previously terminated server instances after violating
 	// different throttle limits. That code was clunky and difficult to reason about,
@@ -74,7 +85,7 @@ func (s *Server) processConsoleOutputEvent(v []byte) {
 		return
 	}
 
-	s.Sink(system.LogSink).Push(v)
+	s.ConsoleRing().Push(line)
 }
 
 // StartEventListeners adds all the internal event listeners we want to use for
@@ -83,10 +94,16 @@ func (s *Server) processConsoleOutputEvent(v []byte) {
 func (s *Server) StartEventListeners() {
 	c := make(chan []byte, 8)
 	limit := newDiskLimiter(s)
+	startup := newStartupWatcher(s)
+	alerts := newAlertWatcher(s)
 
 	s.Log().Debug("registering event listeners: console, state, resources...")
 	s.Environment.Events().On(c)
 	s.Environment.SetLogCallback(s.processConsoleOutputEvent)
+	s.startExternalPublisher()
+	if cfg := config.Get().Docker.StatsCollector; cfg.OnDemand {
+		go s.watchDiskLimit(limit, time.Duration(cfg.Interval)*time.Second)
+	}
 
 	go func() {
 		for {
@@ -115,16 +132,28 @@ func (s *Server) StartEventListeners() {
 								limit.Trigger()
 							}
 							s.Events().Publish(StatsEvent, s.Proc())
+							alerts.Evaluate(stats.Data)
 						}
 					case environment.StateChangeEvent:
 						{
 							// Reset the throttler when the process is started.
+							if e.Data == environment.ProcessStartingState || e.Data == environment.ProcessOfflineState {
+								alerts.Reset()
+							}
 							if e.Data == environment.ProcessStartingState {
 								limit.Reset()
 								s.Throttler().Reset()
+								s.crasher.ResetOutOfMemory()
+								startup.Start()
+							} else {
+								startup.Stop()
 							}
 							s.OnStateChange()
 						}
+					case environment.OutOfMemoryEvent:
+						s.onOutOfMemory()
+					case environment.SidecarFailedEvent:
+						s.PublishConsoleOutputFromDaemon(fmt.Sprintf("Failed to start the %v sidecar container, check the Wings logs for more details.", e.Data))
 					case environment.DockerImagePullStatus:
 						s.Events().Publish(InstallOutputEvent, e.Data)
 					case environment.DockerImagePullStarted:
@@ -141,6 +170,61 @@ func (s *Server) StartEventListeners() {
 	}()
 }
 
+// watchDiskLimit checks the disk usage of the server at every interval while it
+// is running, triggering the limiter if it exceeds the limit. This is only used
+// when resource usage is collected on demand, since the check is otherwise made
+// whenever resource usage is received which stops while nobody is watching the
+// server.
+func (s *Server) watchDiskLimit(limit *diskSpaceLimiter, interval time.Duration) {
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.Context().Done():
+			return
+		case <-ticker.C:
+			if !s.IsRunning() {
+				continue
+			}
+			if !s.Filesystem().HasSpaceAvailable(true) {
+				limit.Trigger()
+			}
+		}
+	}
+}
+
+// startExternalPublisher mirrors the events emitted by the server onto the
+// external event bus, if one has been configured for this instance.
+func (s *Server) startExternalPublisher() {
+	p := publisher.Get()
+	if p == nil {
+		return
+	}
+
+	c := make(chan []byte, 8)
+	s.Events().On(c)
+	go func() {
+		for {
+			select {
+			case v, ok := <-c:
+				if !ok {
+					return
+				}
+				var e events.Event
+				if err := events.DecodeTo(v, &e); err != nil {
+					continue
+				}
+				p.Publish(s.ID(), e.Topic, e.Data)
+			case <-s.Context().Done():
+				return
+			}
+		}
+	}()
+}
+
 var stripAnsiRegex = regexp.MustCompile("[\u001B\u009B][[\\]()#;?]*(?:(?:(?:[a-zA-Z\\d]*(?:;[a-zA-Z\\d]*)*)?\u0007)|(?:(?:\\d{1,4}(?:;\\d{0,4})*)?[\\dA-PRZcf-ntqry=><~]))")
 
 // Custom listener for console output events that will check if the given line
@@ -157,6 +241,9 @@ func (s *Server) onConsoleOutput(data []byte) {
 	v := make([]byte, len(data))
 	copy(v, data)
 
+	// Run any feature hooks enabled by the egg against the output.
+	s.processFeatureHooks(v)
+
 	// Check if the server is currently starting.
 	if s.Environment.State() == environment.ProcessStartingState {
 		// Check if we should strip ansi color codes.
@@ -164,6 +251,20 @@ func (s *Server) onConsoleOutput(data []byte) {
 			v = stripAnsiRegex.ReplaceAll(v, []byte(""))
 		}
 
+		startup := s.Config().Startup
+
+		// Check the failure patterns first, a line indicating that the server failed to
+		// start should never be able to mark the server as running.
+		if p, ok := matchesAnyPattern(v, startup.Failed); ok {
+			s.Log().WithFields(log.Fields{
+				"match":   p,
+				"against": strconv.QuoteToASCII(string(v)),
+			}).Debug("detected server failed to start based on console line output")
+
+			s.markStartupFailed("matched failure output")
+			return
+		}
+
 		// Iterate over all the done lines.
 		for _, l := range processConfiguration.Startup.Done {
 			if !l.Matches(v) {
@@ -181,6 +282,19 @@ func (s *Server) onConsoleOutput(data []byte) {
 			s.Environment.SetState(environment.ProcessRunningState)
 			break
 		}
+
+		// Check any additional patterns defined for the server if none of the egg's done
+		// lines matched the output.
+		if s.Environment.State() == environment.ProcessStartingState {
+			if p, ok := matchesAnyPattern(v, startup.Done); ok {
+				s.Log().WithFields(log.Fields{
+					"match":   p,
+					"against": strconv.QuoteToASCII(string(v)),
+				}).Debug("detected server in running state based on console line output")
+
+				s.Environment.SetState(environment.ProcessRunningState)
+			}
+		}
 	}
 
 	// If the command sent to the server is one that should stop the server we will need to
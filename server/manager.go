@@ -17,6 +17,7 @@ import (
 
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/environment/containerd"
 	"github.com/pterodactyl/wings/environment/docker"
 	"github.com/pterodactyl/wings/remote"
 	"github.com/pterodactyl/wings/server/filesystem"
@@ -26,16 +27,58 @@ type Manager struct {
 	mu      sync.RWMutex
 	client  remote.Client
 	servers []*Server
+
+	// The API clients for each of the additional Panels this node is registered
+	// with, and the name of the tenant that each server belongs to. Servers that
+	// belong to the primary Panel are not tracked.
+	tenantClients map[string]remote.Client
+	tenants       map[string]string
+
+	// The clients for the Panels that were unreachable when booting, keyed by the
+	// name of the tenant, whose servers were loaded from the offline cache.
+	offline map[string]remote.Client
+
+	// The tenants that some or all servers could not be loaded for when booting,
+	// which are retried in the background.
+	incomplete map[string]bool
+
+	// The UUIDs of every server returned by a Panel, including those that could
+	// not be loaded, so that their containers are never treated as orphaned.
+	listed map[string]bool
+
+	// The tasks scheduled on the node, which are run by the manager since most of
+	// them act on the servers it holds.
+	tasks *taskScheduler
+}
+
+// ManagerOption is a functional option for configuring the server manager.
+type ManagerOption func(m *Manager)
+
+// WithTenant registers the API client for an additional Panel that this node
+// is registered with, so that the servers belonging to it are loaded as well.
+func WithTenant(name string, client remote.Client) ManagerOption {
+	return func(m *Manager) {
+		m.tenantClients[name] = client
+	}
 }
 
 // NewManager returns a new server manager instance. This will boot up all the
 // servers that are currently present on the filesystem and set them into the
 // manager.
-func NewManager(ctx context.Context, client remote.Client) (*Manager, error) {
+func NewManager(ctx context.Context, client remote.Client, opts ...ManagerOption) (*Manager, error) {
 	m := NewEmptyManager(client)
+	for _, opt := range opts {
+		opt(m)
+	}
 	if err := m.init(ctx); err != nil {
 		return nil, err
 	}
+	if len(m.offline) > 0 {
+		go m.syncOffline(ctx)
+	}
+	nodeMu.Lock()
+	nodeManager = m
+	nodeMu.Unlock()
 	return m, nil
 }
 
@@ -43,7 +86,15 @@ func NewManager(ctx context.Context, client remote.Client) (*Manager, error) {
 // loading any of the servers from the disk. This allows the caller to set their
 // own servers into the collection as needed.
 func NewEmptyManager(client remote.Client) *Manager {
-	return &Manager{client: client}
+	return &Manager{
+		client:        client,
+		tenantClients: make(map[string]remote.Client),
+		tenants:       make(map[string]string),
+		offline:       make(map[string]remote.Client),
+		incomplete:    make(map[string]bool),
+		listed:        make(map[string]bool),
+		tasks:         newTaskScheduler(),
+	}
 }
 
 // Client returns the HTTP client interface that allows interaction with the
@@ -52,6 +103,32 @@ func (m *Manager) Client() remote.Client {
 	return m.client
 }
 
+// TenantClient returns the HTTP client interface for the Panel that the tenant
+// belongs to. The client for the primary Panel is returned for an empty or
+// unknown tenant name.
+func (m *Manager) TenantClient(tenant string) remote.Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if c, ok := m.tenantClients[tenant]; ok {
+		return c
+	}
+	return m.client
+}
+
+// ServerTenant returns the name of the tenant that the server belongs to, which
+// is an empty string for servers belonging to the primary Panel.
+func (m *Manager) ServerTenant(id string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tenants[id]
+}
+
+// ServerClient returns the HTTP client interface for the Panel that the server
+// belongs to.
+func (m *Manager) ServerClient(id string) remote.Client {
+	return m.TenantClient(m.ServerTenant(id))
+}
+
 // Put replaces all the current values in the collection with the value that
 // is passed through.
 func (m *Manager) Put(s []*Server) {
@@ -117,6 +194,8 @@ func (m *Manager) Remove(filter func(match *Server) bool) {
 	for _, v := range m.servers {
 		if !filter(v) {
 			r = append(r, v)
+		} else {
+			delete(m.tenants, v.ID())
 		}
 	}
 	m.servers = r
@@ -167,7 +246,14 @@ func (m *Manager) ReadStates() (map[string]string, error) {
 // marshaled into the given struct using a YAML marshaler. This will also
 // configure the given environment for a server.
 func (m *Manager) InitServer(data remote.ServerConfigurationResponse) (*Server, error) {
-	s, err := New(m.client)
+	return m.InitTenantServer("", data)
+}
+
+// InitTenantServer initializes a server belonging to the given tenant, which
+// uses the tenant's API client and stores its files within the tenant's data
+// directory. An empty tenant name is the primary Panel.
+func (m *Manager) InitTenantServer(tenant string, data remote.ServerConfigurationResponse) (*Server, error) {
+	s, err := New(m.TenantClient(tenant))
 	if err != nil {
 		return nil, err
 	}
@@ -178,11 +264,9 @@ func (m *Manager) InitServer(data remote.ServerConfigurationResponse) (*Server,
 		return nil, errors.WithStackIf(err)
 	}
 
-	s.fs = filesystem.New(filepath.Join(config.Get().System.Data, s.ID()), s.DiskSpace(), s.Config().Egg.FileDenylist)
+	s.fs = filesystem.New(filepath.Join(config.Get().System.TenantDataDirectory(tenant), s.ID()), s.DiskSpace(), s.Config().Egg.FileDenylist)
+	s.SyncWriteDenylist()
 
-	// Right now we only support a Docker based environment, so I'm going to hard code
-	// this logic in. When we're ready to support other environment we'll need to make
-	// some modifications here, obviously.
 	settings := environment.Settings{
 		Mounts:      s.Mounts(),
 		Allocations: s.cfg.Allocations,
@@ -190,71 +274,196 @@ func (m *Manager) InitServer(data remote.ServerConfigurationResponse) (*Server,
 	}
 
 	envCfg := environment.NewConfiguration(settings, s.GetEnvironmentVariables())
-	meta := docker.Metadata{
-		Image: s.Config().Container.Image,
+	if transferred, err := isTransferred(s.ID()); err != nil {
+		s.Log().WithField("error", err).Warn("failed to check if server was transferred to this node")
+	} else {
+		envCfg.SetTranslatePaths(transferred)
 	}
-
-	if env, err := docker.New(s.ID(), &meta, envCfg); err != nil {
+	if env, err := newEnvironment(s, envCfg); err != nil {
 		return nil, err
 	} else {
 		s.Environment = env
 		s.StartEventListeners()
 	}
 
+	if err := s.configureUserIsolation(); err != nil {
+		return nil, err
+	}
+
 	// If the server's data directory exists, force disk usage calculation.
 	if _, err := os.Stat(s.Filesystem().Path()); err == nil {
 		s.Filesystem().HasSpaceAvailable(true)
 	}
 
+	if tenant != "" {
+		m.mu.Lock()
+		m.tenants[s.ID()] = tenant
+		m.mu.Unlock()
+	}
+
 	return s, nil
 }
 
+// newEnvironment returns the environment that the server process runs in. This
+// is a Docker environment unless the node has been configured to run server
+// processes using containerd.
+func newEnvironment(s *Server, envCfg *environment.Configuration) (environment.ProcessEnvironment, error) {
+	if config.Get().Containerd.Enabled {
+		return containerd.New(s.ID(), &containerd.Metadata{
+			Image: s.Config().Container.Image,
+		}, envCfg)
+	}
+	return docker.New(s.ID(), &docker.Metadata{
+		Image:    s.Config().Container.Image,
+		Sidecars: s.Config().Egg.Sidecars,
+	}, envCfg)
+}
+
+// parseServerData returns the configuration for the server from the data
+// returned by the Panel when listing servers.
+func parseServerData(data remote.RawServerData) (remote.ServerConfigurationResponse, error) {
+	d := remote.ServerConfigurationResponse{
+		Settings: data.Settings,
+	}
+	if err := json.Unmarshal(data.ProcessConfiguration, &d.ProcessConfiguration); err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
 // initializeFromRemoteSource iterates over a given directory and loads all
 // the servers listed before returning them to the calling function.
 func (m *Manager) init(ctx context.Context) error {
-	log.Info("fetching list of servers from API")
-	servers, err := m.client.GetServers(ctx, config.Get().RemoteQuery.BootServersPerPage)
-	if err != nil {
-		if !remote.IsRequestError(err) {
-			return errors.WithStackIf(err)
+	if err := m.initTenant(ctx, "", m.client); err != nil {
+		return err
+	}
+	// A Panel for a tenant being unreachable should not prevent the servers for every
+	// other Panel from being loaded.
+	for name, client := range m.tenantClients {
+		if err := m.initTenant(ctx, name, client); err != nil {
+			log.WithField("tenant", name).WithField("error", err).Error("failed to load servers for tenant, skipping...")
+			m.setIncomplete(name, true)
 		}
-		return errors.WrapIf(err, "manager: failed to retrieve server configurations")
 	}
+	return nil
+}
+
+// setIncomplete marks if the servers for the tenant have not all been loaded.
+func (m *Manager) setIncomplete(tenant string, incomplete bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if incomplete {
+		m.incomplete[tenant] = true
+	} else {
+		delete(m.incomplete, tenant)
+	}
+}
+
+// setListed marks the server as having been returned by a Panel.
+func (m *Manager) setListed(uuid string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listed[uuid] = true
+}
+
+// Listed returns true if the server was returned by a Panel, even if it could
+// not be loaded.
+func (m *Manager) Listed(uuid string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.listed[uuid]
+}
+
+// FullyLoaded returns true if the servers for every Panel were loaded from the
+// Panel when booting. This is false while any servers are still being fetched
+// in the background, or while servers are being run from the offline cache.
+func (m *Manager) FullyLoaded() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.incomplete) == 0 && len(m.offline) == 0
+}
 
+// initTenant loads all the servers belonging to the Panel for the tenant. An
+// empty tenant name is the primary Panel.
+func (m *Manager) initTenant(ctx context.Context, tenant string, client remote.Client) error {
+	log.WithField("tenant", tenant).Info("fetching list of servers from API")
 	start := time.Now()
+
+	// When the client is able to fetch a single page of servers at a time, the servers
+	// are loaded as each page arrives rather than waiting for all of them.
+	if pager, ok := client.(serverPager); ok {
+		err := m.bootTenant(ctx, tenant, pager)
+		if err == nil {
+			log.WithField("duration", fmt.Sprintf("%s", time.Now().Sub(start))).Info("finished processing server configurations")
+			return nil
+		}
+		servers, err := m.serversFromCache(tenant, client, err)
+		if err != nil {
+			return err
+		}
+		m.loadServers(tenant, servers)
+		return nil
+	}
+
+	servers, err := m.fetchServers(ctx, tenant, client)
+	if err != nil {
+		return err
+	}
+
 	log.WithField("total_configs", len(servers)).Info("processing servers returned by the API")
+	m.loadServers(tenant, servers)
+
+	diff := time.Now().Sub(start)
+	log.WithField("duration", fmt.Sprintf("%s", diff)).Info("finished processing server configurations")
+
+	return nil
+}
 
+// loadServers initializes each of the servers returned by the Panel for the
+// tenant and adds them to the manager.
+func (m *Manager) loadServers(tenant string, servers []remote.RawServerData) {
 	pool := workerpool.New(runtime.NumCPU())
 	log.Debugf("using %d workerpools to instantiate server instances", runtime.NumCPU())
-	for _, data := range servers {
-		data := data
-		pool.Submit(func() {
-			// Parse the json.RawMessage into an expected struct value. We do this here so that a single broken
-			// server does not cause the entire boot process to hang, and allows us to show more useful error
-			// messaging in the output.
-			d := remote.ServerConfigurationResponse{
-				Settings: data.Settings,
-			}
-			log.WithField("server", data.Uuid).Info("creating new server object from API response")
-			if err := json.Unmarshal(data.ProcessConfiguration, &d.ProcessConfiguration); err != nil {
-				log.WithField("server", data.Uuid).WithField("error", err).Error("failed to parse server configuration from API response, skipping...")
-				return
-			}
-			s, err := m.InitServer(d)
-			if err != nil {
-				log.WithField("server", data.Uuid).WithField("error", err).Error("failed to load server, skipping...")
-				return
-			}
-			m.Add(s)
-		})
-	}
+	m.submitServers(pool, tenant, servers)
 
 	// Wait until we've processed all the configuration files in the directory
 	// before continuing.
 	pool.StopWait()
+}
 
-	diff := time.Now().Sub(start)
-	log.WithField("duration", fmt.Sprintf("%s", diff)).Info("finished processing server configurations")
+// submitServers submits each of the servers to the pool to be initialized and
+// added to the manager.
+func (m *Manager) submitServers(pool *workerpool.WorkerPool, tenant string, servers []remote.RawServerData) {
+	for _, data := range servers {
+		data := data
+		pool.Submit(func() {
+			m.loadServer(tenant, data)
+		})
+	}
+}
 
-	return nil
+// loadServer initializes a single server returned by the Panel and adds it to
+// the manager, logging any errors rather than returning them so that a single
+// broken server does not prevent the rest from booting.
+func (m *Manager) loadServer(tenant string, data remote.RawServerData) {
+	// Parse the json.RawMessage into an expected struct value. We do this here so that a single broken
+	// server does not cause the entire boot process to hang, and allows us to show more useful error
+	// messaging in the output.
+	log.WithField("server", data.Uuid).Info("creating new server object from API response")
+	m.setListed(data.Uuid)
+	d, err := parseServerData(data)
+	if err != nil {
+		log.WithField("server", data.Uuid).WithField("error", err).Error("failed to parse server configuration from API response, skipping...")
+		return
+	}
+	if _, ok := m.Get(data.Uuid); ok {
+		log.WithField("server", data.Uuid).WithField("tenant", tenant).Error("server has already been loaded for another panel, skipping...")
+		return
+	}
+	s, err := m.InitTenantServer(tenant, d)
+	if err != nil {
+		log.WithField("server", data.Uuid).WithField("error", err).Error("failed to load server, skipping...")
+		return
+	}
+	m.Add(s)
 }
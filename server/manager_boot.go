@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/gammazero/workerpool"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/remote/transport"
+)
+
+// serverPager is implemented by Panel API clients that are able to return a
+// single page of the servers belonging to this node. Clients that do not
+// implement it are booted by fetching every server before any are loaded.
+type serverPager interface {
+	GetServersPaged(ctx context.Context, page, limit int) ([]remote.RawServerData, remote.Pagination, error)
+}
+
+// bootCheckpoint tracks the pages of servers that have been fetched from the
+// Panel for a tenant, so that pages which failed to load can be fetched again
+// later without requesting the pages that were already loaded.
+type bootCheckpoint struct {
+	mu    sync.Mutex
+	last  int
+	pages map[int][]remote.RawServerData
+}
+
+func newBootCheckpoint(last int) *bootCheckpoint {
+	if last < 1 {
+		last = 1
+	}
+	return &bootCheckpoint{last: last, pages: make(map[int][]remote.RawServerData, last)}
+}
+
+// complete marks the page as having been fetched.
+func (c *bootCheckpoint) complete(page int, servers []remote.RawServerData) {
+	c.mu.Lock()
+	c.pages[page] = servers
+	c.mu.Unlock()
+}
+
+// missing returns the pages that have not been fetched yet, in order.
+func (c *bootCheckpoint) missing() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var pages []int
+	for p := 1; p <= c.last; p++ {
+		if _, ok := c.pages[p]; !ok {
+			pages = append(pages, p)
+		}
+	}
+	return pages
+}
+
+// servers returns the servers from every page that has been fetched, in the
+// order that the Panel returned them.
+func (c *bootCheckpoint) servers() []remote.RawServerData {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pages := make([]int, 0, len(c.pages))
+	for p := range c.pages {
+		pages = append(pages, p)
+	}
+	sort.Ints(pages)
+	var out []remote.RawServerData
+	for _, p := range pages {
+		out = append(out, c.pages[p]...)
+	}
+	return out
+}
+
+// bootTenant fetches the servers for the tenant a page at a time, loading the
+// servers on each page as soon as it arrives. An error is only returned if the
+// first page could not be fetched, pages after it that fail to load are fetched
+// again in the background until every server has been loaded.
+func (m *Manager) bootTenant(ctx context.Context, tenant string, client serverPager) error {
+	servers, meta, err := fetchServerPage(ctx, client, 1)
+	if err != nil {
+		return err
+	}
+	cp := newBootCheckpoint(int(meta.LastPage))
+	cp.complete(1, servers)
+
+	pool := workerpool.New(runtime.NumCPU())
+	log.Debugf("using %d workerpools to instantiate server instances", runtime.NumCPU())
+	log.WithField("tenant", tenant).WithField("total_pages", cp.last).Info("processing servers returned by the API")
+	m.submitServers(pool, tenant, servers)
+	m.fetchServerPages(ctx, tenant, client, cp, pool)
+	pool.StopWait()
+
+	if missing := cp.missing(); len(missing) > 0 {
+		log.WithField("tenant", tenant).WithField("pages", missing).Error("failed to fetch some pages of servers from the Panel, they will be loaded once the Panel responds")
+		m.setIncomplete(tenant, true)
+		go m.resumeBoot(ctx, tenant, client, cp)
+		return nil
+	}
+	cacheServers(tenant, cp.servers())
+	return nil
+}
+
+// resumeBoot fetches the pages of servers that could not be loaded when booting
+// every OfflineCacheRetry seconds, until all of them have been loaded.
+func (m *Manager) resumeBoot(ctx context.Context, tenant string, client serverPager, cp *bootCheckpoint) {
+	ticker := time.NewTicker(time.Duration(config.Get().RemoteQuery.OfflineCacheRetry) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pool := workerpool.New(runtime.NumCPU())
+		m.fetchServerPages(ctx, tenant, client, cp, pool)
+		pool.StopWait()
+		if len(cp.missing()) == 0 {
+			log.WithField("tenant", tenant).Info("finished loading the servers that could not be fetched when booting")
+			cacheServers(tenant, cp.servers())
+			m.setIncomplete(tenant, false)
+			return
+		}
+	}
+}
+
+// fetchServerPages fetches each of the pages missing from the checkpoint using
+// BootConcurrency requests at a time, submitting the servers on each page to
+// the pool to be loaded as soon as it arrives.
+func (m *Manager) fetchServerPages(ctx context.Context, tenant string, client serverPager, cp *bootCheckpoint, pool *workerpool.WorkerPool) {
+	workers := config.Get().RemoteQuery.BootConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	pages := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range pages {
+				servers, _, err := fetchServerPage(ctx, client, page)
+				if err != nil {
+					log.WithField("tenant", tenant).WithField("page", page).WithField("error", err).Error("failed to fetch page of servers from the Panel")
+					continue
+				}
+				cp.complete(page, servers)
+				m.submitServers(pool, tenant, servers)
+			}
+		}()
+	}
+	for _, page := range cp.missing() {
+		pages <- page
+	}
+	close(pages)
+	wg.Wait()
+}
+
+// fetchServerPage returns a single page of servers from the Panel, retrying the
+// request up to BootPageRetries times with an exponential backoff if the Panel
+// could not be reached. Requests are not retried while the circuit breaker for
+// the Panel is open since they would fail without being sent.
+func fetchServerPage(ctx context.Context, client serverPager, page int) ([]remote.RawServerData, remote.Pagination, error) {
+	cfg := config.Get().RemoteQuery
+	backoff := time.Duration(cfg.RetryBackoff) * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		servers, meta, err := client.GetServersPaged(ctx, page, cfg.BootServersPerPage)
+		if err == nil || attempt >= cfg.BootPageRetries || !isPanelOutage(err) || errors.Is(err, transport.ErrCircuitOpen) {
+			return servers, meta, err
+		}
+		log.WithField("page", page).WithField("error", err).Warn("failed to fetch page of servers from the Panel, retrying...")
+		select {
+		case <-ctx.Done():
+			return nil, meta, ctx.Err()
+		case <-time.After(backoff << attempt):
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/remote/transport"
+)
+
+// fakePager is a Panel client that returns a single server on each page, and
+// fails to return any of the pages in fail with err, if it is set.
+type fakePager struct {
+	remote.Client
+
+	mu        sync.Mutex
+	pages     int
+	fail      map[int]bool
+	err       error
+	requested []int
+}
+
+func (f *fakePager) GetServersPaged(_ context.Context, page, _ int) ([]remote.RawServerData, remote.Pagination, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requested = append(f.requested, page)
+	if f.fail[page] {
+		if f.err != nil {
+			return nil, remote.Pagination{}, f.err
+		}
+		return nil, remote.Pagination{}, errors.New("panel is unavailable")
+	}
+	return []remote.RawServerData{{Uuid: fmt.Sprintf("server-%d", page)}}, remote.Pagination{LastPage: uint(f.pages)}, nil
+}
+
+func (f *fakePager) setFail(page int, fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail[page] = fail
+}
+
+func (f *fakePager) pagesRequested() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := append([]int(nil), f.requested...)
+	sort.Ints(out)
+	return out
+}
+
+func TestBootCheckpoint(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("BootCheckpoint", func() {
+		g.It("returns the pages that have not been fetched", func() {
+			cp := newBootCheckpoint(4)
+			cp.complete(1, nil)
+			cp.complete(3, nil)
+			g.Assert(cp.missing()).Equal([]int{2, 4})
+
+			cp.complete(2, nil)
+			cp.complete(4, nil)
+			g.Assert(len(cp.missing())).Equal(0)
+		})
+
+		g.It("returns the servers in page order", func() {
+			cp := newBootCheckpoint(3)
+			cp.complete(3, []remote.RawServerData{{Uuid: "c"}})
+			cp.complete(1, []remote.RawServerData{{Uuid: "a"}})
+			cp.complete(2, []remote.RawServerData{{Uuid: "b"}})
+
+			servers := cp.servers()
+			g.Assert(len(servers)).Equal(3)
+			g.Assert(servers[0].Uuid).Equal("a")
+			g.Assert(servers[1].Uuid).Equal("b")
+			g.Assert(servers[2].Uuid).Equal("c")
+		})
+
+		g.It("always has at least one page", func() {
+			cp := newBootCheckpoint(0)
+			g.Assert(cp.missing()).Equal([]int{1})
+		})
+	})
+
+	g.Describe("Manager boot", func() {
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{
+				AuthenticationToken: "token",
+				RemoteQuery: config.RemoteQueryConfiguration{
+					BootServersPerPage: 1,
+					BootConcurrency:    2,
+					OfflineCacheRetry:  1,
+				},
+			})
+		})
+
+		g.It("fetches every page using a client that supports paging", func() {
+			pager := &fakePager{pages: 3, fail: map[int]bool{}}
+			m := NewEmptyManager(pager)
+
+			g.Assert(m.initTenant(context.Background(), "", pager)).IsNil()
+			g.Assert(pager.pagesRequested()).Equal([]int{1, 2, 3})
+			g.Assert(m.FullyLoaded()).IsTrue()
+		})
+
+		g.It("fetches pages that failed to load in the background", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			pager := &fakePager{pages: 3, fail: map[int]bool{2: true}}
+			m := NewEmptyManager(pager)
+
+			g.Assert(m.initTenant(ctx, "", pager)).IsNil()
+			g.Assert(m.FullyLoaded()).IsFalse()
+
+			pager.setFail(2, false)
+			deadline := time.Now().Add(time.Second * 5)
+			for !m.FullyLoaded() && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond * 50)
+			}
+			g.Assert(m.FullyLoaded()).IsTrue()
+			g.Assert(pager.pagesRequested()).Equal([]int{1, 2, 2, 3})
+		})
+
+		g.It("returns an error if the first page cannot be fetched", func() {
+			pager := &fakePager{pages: 3, fail: map[int]bool{1: true}}
+			m := NewEmptyManager(pager)
+
+			g.Assert(m.initTenant(context.Background(), "", pager) != nil).IsTrue()
+			g.Assert(pager.pagesRequested()).Equal([]int{1})
+		})
+
+		g.It("retries a page that could not be fetched", func() {
+			config.Update(func(c *config.Configuration) {
+				c.RemoteQuery.BootPageRetries = 2
+				c.RemoteQuery.RetryBackoff = 1
+			})
+			pager := &fakePager{pages: 1, fail: map[int]bool{1: true}}
+
+			_, _, err := fetchServerPage(context.Background(), pager, 1)
+			g.Assert(err != nil).IsTrue()
+			g.Assert(pager.pagesRequested()).Equal([]int{1, 1, 1})
+		})
+
+		g.It("does not retry a page while the circuit breaker is open", func() {
+			config.Update(func(c *config.Configuration) {
+				c.RemoteQuery.BootPageRetries = 2
+				c.RemoteQuery.RetryBackoff = 1
+			})
+			pager := &fakePager{pages: 1, fail: map[int]bool{1: true}, err: transport.ErrCircuitOpen}
+
+			_, _, err := fetchServerPage(context.Background(), pager, 1)
+			g.Assert(errors.Is(err, transport.ErrCircuitOpen)).IsTrue()
+			g.Assert(pager.pagesRequested()).Equal([]int{1})
+		})
+	})
+}
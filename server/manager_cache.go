@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/remote"
+)
+
+// fetchServers returns the servers belonging to the Panel for the tenant. When
+// the offline cache is enabled the servers are stored on the disk, and if the
+// Panel cannot be reached the servers from the last successful boot are returned
+// instead so that they can keep running until the Panel recovers.
+func (m *Manager) fetchServers(ctx context.Context, tenant string, client remote.Client) ([]remote.RawServerData, error) {
+	servers, err := client.GetServers(ctx, config.Get().RemoteQuery.BootServersPerPage)
+	if err != nil {
+		return m.serversFromCache(tenant, client, err)
+	}
+	cacheServers(tenant, servers)
+	return servers, nil
+}
+
+// cacheServers stores the servers returned by the Panel for the tenant in the
+// offline cache if it is enabled.
+func cacheServers(tenant string, servers []remote.RawServerData) {
+	if !config.Get().RemoteQuery.OfflineCache {
+		return
+	}
+	if err := writeServerCache(tenant, servers); err != nil {
+		log.WithField("tenant", tenant).WithField("error", err).Warn("failed to write servers to the offline cache")
+	}
+}
+
+// serversFromCache returns the servers for the tenant from the offline cache
+// after the servers could not be fetched from the Panel. The original error is
+// returned if the cache is disabled, or the Panel rejected the request rather
+// than being unreachable.
+func (m *Manager) serversFromCache(tenant string, client remote.Client, err error) ([]remote.RawServerData, error) {
+	if !remote.IsRequestError(err) {
+		err = errors.WithStackIf(err)
+	} else {
+		err = errors.WrapIf(err, "manager: failed to retrieve server configurations")
+	}
+	if !config.Get().RemoteQuery.OfflineCache || !isPanelOutage(err) {
+		return nil, err
+	}
+	cached, cerr := readServerCache(tenant)
+	if cerr != nil {
+		if !errors.Is(cerr, os.ErrNotExist) {
+			log.WithField("tenant", tenant).WithField("error", cerr).Error("failed to read servers from the offline cache")
+		}
+		return nil, err
+	}
+
+	log.WithField("tenant", tenant).WithField("error", err).Warn("unable to reach the Panel, loading servers from the offline cache")
+	m.mu.Lock()
+	m.offline[tenant] = client
+	m.mu.Unlock()
+	return cached, nil
+}
+
+// isPanelOutage returns true if the error is caused by the Panel being down
+// rather than the request being rejected, which would not be fixed by booting
+// from the cache.
+func isPanelOutage(err error) bool {
+	var re *remote.RequestError
+	if errors.As(err, &re) {
+		return len(re.Status) == 0 || re.Status[0] != '4'
+	}
+	return true
+}
+
+// syncOffline tries to fetch the servers for each of the Panels that were
+// unreachable when booting, syncing the servers loaded from the offline cache
+// with the Panel once it responds.
+func (m *Manager) syncOffline(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(config.Get().RemoteQuery.OfflineCacheRetry) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		m.mu.RLock()
+		offline := make(map[string]remote.Client, len(m.offline))
+		for tenant, client := range m.offline {
+			offline[tenant] = client
+		}
+		m.mu.RUnlock()
+		if len(offline) == 0 {
+			return
+		}
+
+		for tenant, client := range offline {
+			servers, err := client.GetServers(ctx, config.Get().RemoteQuery.BootServersPerPage)
+			if err != nil {
+				log.WithField("tenant", tenant).WithField("error", err).Debug("Panel is still unreachable, running servers from the offline cache")
+				continue
+			}
+			log.WithField("tenant", tenant).Info("Panel is reachable again, syncing servers loaded from the offline cache")
+			if err := writeServerCache(tenant, servers); err != nil {
+				log.WithField("tenant", tenant).WithField("error", err).Warn("failed to write servers to the offline cache")
+			}
+			m.resyncTenant(tenant, servers)
+			m.mu.Lock()
+			delete(m.offline, tenant)
+			m.mu.Unlock()
+		}
+	}
+}
+
+// resyncTenant updates the servers belonging to the tenant with the servers that
+// were returned by the Panel, loading any servers that were not in the cache.
+func (m *Manager) resyncTenant(tenant string, servers []remote.RawServerData) {
+	var missing []remote.RawServerData
+	seen := make(map[string]bool, len(servers))
+	for _, data := range servers {
+		seen[data.Uuid] = true
+		s, ok := m.Get(data.Uuid)
+		if !ok {
+			missing = append(missing, data)
+			continue
+		}
+		if m.ServerTenant(s.ID()) != tenant {
+			continue
+		}
+		d, err := parseServerData(data)
+		if err != nil {
+			s.Log().WithField("error", err).Error("failed to parse server configuration from API response")
+			continue
+		}
+		if err := s.SyncWithConfiguration(d); err != nil {
+			s.Log().WithField("error", err).Error("failed to sync server configuration with the Panel")
+			continue
+		}
+		s.Filesystem().SetDiskLimit(s.DiskSpace())
+		s.SyncWriteDenylist()
+		s.enableVolumeCompression()
+		s.SyncWithEnvironment()
+	}
+	if len(missing) > 0 {
+		m.loadServers(tenant, missing)
+	}
+
+	// Servers that were deleted from the Panel while it was unreachable must not keep
+	// running, so they are stopped and removed from the manager. Their files are left
+	// on the disk since the Panel has not asked for them to be deleted.
+	stale := m.Filter(func(s *Server) bool {
+		return !seen[s.ID()] && m.ServerTenant(s.ID()) == tenant
+	})
+	if len(stale) == 0 {
+		return
+	}
+	ids := make(map[string]bool, len(stale))
+	for _, s := range stale {
+		ids[s.ID()] = true
+	}
+	m.Remove(func(s *Server) bool {
+		return ids[s.ID()]
+	})
+	for _, s := range stale {
+		s.Log().Warn("server loaded from the offline cache no longer exists on the Panel, stopping and unloading it")
+		go func(s *Server) {
+			if err := s.Environment.WaitForStop(context.Background(), time.Minute, true); err != nil {
+				s.Log().WithField("error", err).Error("failed to stop server that no longer exists on the Panel")
+			}
+		}(s)
+	}
+}
+
+// writeServerCache stores the servers returned by the Panel for the tenant. The
+// server configurations contain secrets, so the file is only readable by Wings.
+func writeServerCache(tenant string, servers []remote.RawServerData) error {
+	b, err := json.Marshal(servers)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	p := config.Get().System.GetServerCachePath(tenant)
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return errors.WithStack(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	// Replace the cache in a single step so that a crash while writing it does not
+	// leave a partial cache behind to boot from.
+	return errors.WithStack(os.Rename(tmp.Name(), p))
+}
+
+// readServerCache returns the servers for the tenant from the offline cache.
+func readServerCache(tenant string) ([]remote.RawServerData, error) {
+	b, err := os.ReadFile(config.Get().System.GetServerCachePath(tenant))
+	if err != nil {
+		return nil, err
+	}
+	var servers []remote.RawServerData
+	if err := json.Unmarshal(b, &servers); err != nil {
+		return nil, errors.Wrap(err, "manager: offline cache is corrupted")
+	}
+	return servers, nil
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"os"
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/remote"
+)
+
+func TestServerCache(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("ServerCache", func() {
+		var dir string
+
+		g.BeforeEach(func() {
+			dir, _ = os.MkdirTemp(os.TempDir(), "pterodactyl-cache")
+			config.Set(&config.Configuration{
+				AuthenticationToken: "token",
+				System:              config.SystemConfiguration{RootDirectory: dir},
+			})
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		g.It("reads back the servers that were written for the tenant", func() {
+			servers := []remote.RawServerData{{Uuid: "a", Settings: []byte(`{"uuid":"a"}`), ProcessConfiguration: []byte(`{}`)}}
+			g.Assert(writeServerCache("reseller", servers)).IsNil()
+
+			cached, err := readServerCache("reseller")
+			g.Assert(err).IsNil()
+			g.Assert(len(cached)).Equal(1)
+			g.Assert(cached[0].Uuid).Equal("a")
+			g.Assert(string(cached[0].Settings)).Equal(`{"uuid":"a"}`)
+
+			_, err = readServerCache("")
+			g.Assert(errors.Is(err, os.ErrNotExist)).IsTrue()
+		})
+
+		g.It("does not boot from the cache unless it is enabled", func() {
+			servers := []remote.RawServerData{{Uuid: "a", ProcessConfiguration: []byte(`{}`)}}
+			g.Assert(writeServerCache("", servers)).IsNil()
+
+			m := NewEmptyManager(nil)
+			_, err := m.serversFromCache("", nil, errors.New("dial tcp: connection refused"))
+			g.Assert(err != nil).IsTrue()
+
+			config.Update(func(c *config.Configuration) {
+				c.RemoteQuery.OfflineCache = true
+			})
+			cached, err := m.serversFromCache("", nil, errors.New("dial tcp: connection refused"))
+			g.Assert(err).IsNil()
+			g.Assert(len(cached)).Equal(1)
+		})
+
+		g.It("only boots from the cache when the Panel is down", func() {
+			g.Assert(isPanelOutage(errors.New("dial tcp: connection refused"))).IsTrue()
+			g.Assert(isPanelOutage(errors.WithStack(&remote.RequestError{Status: "502"}))).IsTrue()
+			g.Assert(isPanelOutage(errors.WithStack(&remote.RequestError{Status: "403"}))).IsFalse()
+		})
+	})
+}
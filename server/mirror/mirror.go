@@ -0,0 +1,286 @@
+// Package mirror keeps a directory of a server in sync with a remote endpoint,
+// either pushing the local files to the endpoint or pulling the files from it.
+// Files are compared using their size and modification time, and only files
+// that have changed are copied.
+package mirror
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	ignore "github.com/sabhiram/go-gitignore"
+
+	"github.com/pterodactyl/wings/server/filesystem"
+	"github.com/pterodactyl/wings/throttle"
+)
+
+// The directions that files can be synced in.
+const (
+	DirectionPush = "push"
+	DirectionPull = "pull"
+)
+
+// Job defines a directory of a server that is synced with a remote endpoint
+// every Interval seconds.
+type Job struct {
+	// The name of the job, which must be unique for the server.
+	Name string `json:"name"`
+
+	// Either "push" to copy the files of the server to the remote endpoint, or "pull"
+	// to copy the files from the remote endpoint into the server.
+	Direction string `json:"direction"`
+
+	// The directory within the server that is synced, defaults to the root directory.
+	Path string `json:"path"`
+
+	// The remote endpoint, which is one of:
+	//  - "s3://bucket/prefix", with the "region" and "endpoint" query parameters being
+	//    used for buckets outside of us-east-1 or that are not hosted by AWS.
+	//  - "sftp://host:port/path", the "host_key" must be the SHA256 fingerprint of the
+	//    public key of the host.
+	//  - "http://host/path" or "https://host/path" for a WebDAV server.
+	Remote string `json:"remote"`
+
+	// The credentials used to access the remote endpoint. For S3 these are the access
+	// key ID and the secret access key.
+	Username string `json:"username"`
+	Password string `json:"password"`
+	HostKey  string `json:"host_key"`
+
+	// The number of seconds between each sync.
+	Interval int `json:"interval"`
+
+	// The maximum rate in MiB/s that files are copied at, zero is unlimited.
+	BandwidthLimit int `json:"bandwidth_limit"`
+
+	// Whether files that no longer exist at the source are removed from the
+	// destination, making it an exact mirror of the source.
+	Delete bool `json:"delete"`
+
+	// Files that are not synced, in the same format as a .gitignore file.
+	Ignore []string `json:"ignore"`
+}
+
+// Validate checks that the job can be run.
+func (j Job) Validate() error {
+	if j.Name == "" {
+		return errors.New("mirror: job name must not be empty")
+	}
+	if j.Direction != DirectionPush && j.Direction != DirectionPull {
+		return errors.Errorf("mirror: invalid direction \"%s\"", j.Direction)
+	}
+	if j.Interval <= 0 {
+		return errors.New("mirror: interval must be greater than 0")
+	}
+	u, err := url.Parse(j.Remote)
+	if err != nil {
+		return errors.Wrap(err, "mirror: invalid remote")
+	}
+	switch u.Scheme {
+	case "s3", "http", "https":
+	case "sftp":
+		if j.HostKey == "" {
+			return errors.New("mirror: host_key is required for sftp remotes")
+		}
+	default:
+		return errors.Errorf("mirror: unsupported remote \"%s\"", u.Scheme)
+	}
+	return nil
+}
+
+// Entry is a file at the source or destination of a sync.
+type Entry struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Remote is an endpoint that files are synced with. Names are slash separated
+// and relative to the path of the remote.
+type Remote interface {
+	// List returns every file within the remote, including those in directories.
+	List(ctx context.Context) (map[string]Entry, error)
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	Delete(ctx context.Context, name string) error
+	Close() error
+}
+
+// Open connects to the remote endpoint of the job.
+func Open(ctx context.Context, j Job) (Remote, error) {
+	u, err := url.Parse(j.Remote)
+	if err != nil {
+		return nil, errors.Wrap(err, "mirror: invalid remote")
+	}
+	switch u.Scheme {
+	case "s3":
+		return newS3Remote(u, j.Username, j.Password)
+	case "sftp":
+		return newSftpRemote(ctx, u, j.Username, j.Password, j.HostKey)
+	case "http", "https":
+		return newHttpRemote(u, j.Username, j.Password), nil
+	}
+	return nil, errors.Errorf("mirror: unsupported remote \"%s\"", u.Scheme)
+}
+
+// Result is the outcome of a sync.
+type Result struct {
+	Copied  int
+	Deleted int
+	Bytes   int64
+}
+
+// Sync copies every file that has changed between the directory of the server
+// and the remote in the direction of the job.
+func Sync(ctx context.Context, j Job, fs *filesystem.Filesystem, r Remote) (Result, error) {
+	var res Result
+	root, err := fs.SafePath(j.Path)
+	if err != nil {
+		return res, err
+	}
+	local, err := listLocal(root)
+	if err != nil {
+		return res, err
+	}
+	remote, err := r.List(ctx)
+	if err != nil {
+		return res, errors.WrapIf(err, "mirror: failed to list remote files")
+	}
+	if len(j.Ignore) > 0 {
+		i := ignore.CompileIgnoreLines(j.Ignore...)
+		for _, m := range []map[string]Entry{local, remote} {
+			for name := range m {
+				if i.MatchesPath(name) {
+					delete(m, name)
+				}
+			}
+		}
+	}
+
+	src, dst := local, remote
+	if j.Direction == DirectionPull {
+		src, dst = remote, local
+	}
+	copies, deletes := plan(src, dst, j.Delete)
+	limit := func(time.Time) int { return j.BandwidthLimit }
+
+	for _, name := range copies {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		e := src[name]
+		if j.Direction == DirectionPush {
+			err = push(ctx, r, filepath.Join(root, filepath.FromSlash(name)), name, e.Size, limit)
+		} else {
+			err = pull(ctx, r, fs, path.Join(j.Path, name), name, e.ModTime, limit)
+		}
+		if err != nil {
+			return res, errors.WrapIf(err, "mirror: failed to copy "+name)
+		}
+		res.Copied++
+		res.Bytes += e.Size
+	}
+	for _, name := range deletes {
+		if j.Direction == DirectionPush {
+			err = r.Delete(ctx, name)
+		} else {
+			err = fs.Delete(path.Join(j.Path, name))
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return res, errors.WrapIf(err, "mirror: failed to delete "+name)
+		}
+		res.Deleted++
+	}
+	return res, nil
+}
+
+// plan returns the files that must be copied from the source to the destination
+// because they are missing or have changed, and the files that must be removed
+// from the destination because they no longer exist at the source.
+func plan(src, dst map[string]Entry, remove bool) (copies []string, deletes []string) {
+	for name, s := range src {
+		d, ok := dst[name]
+		if !ok || s.Size != d.Size || s.ModTime.Truncate(time.Second).After(d.ModTime) {
+			copies = append(copies, name)
+		}
+	}
+	if remove {
+		for name := range dst {
+			if _, ok := src[name]; !ok {
+				deletes = append(deletes, name)
+			}
+		}
+	}
+	sort.Strings(copies)
+	sort.Strings(deletes)
+	return copies, deletes
+}
+
+// listLocal returns every regular file within the directory. Symlinks are not
+// followed so that nothing outside of the server can be synced.
+func listLocal(root string) (map[string]Entry, error) {
+	out := make(map[string]Entry)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root && errors.Is(err, os.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		out[filepath.ToSlash(rel)] = Entry{Size: info.Size(), ModTime: info.ModTime()}
+		return nil
+	})
+	return out, errors.WrapIf(err, "mirror: failed to list local files")
+}
+
+// push uploads a local file to the remote.
+func push(ctx context.Context, r Remote, p, name string, size int64, limit throttle.LimitFunc) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.Put(ctx, name, throttle.Reader(f, limit), size)
+}
+
+// pull downloads a file from the remote into the server, keeping the time it was
+// modified at the remote so that it is not copied again by the next sync.
+func pull(ctx context.Context, r Remote, fs *filesystem.Filesystem, p, name string, modTime time.Time, limit throttle.LimitFunc) error {
+	rc, err := r.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := fs.Writefile(p, throttle.Reader(rc, limit)); err != nil {
+		return err
+	}
+	return fs.Chtimes(p, modTime, modTime)
+}
+
+// joinKey joins the prefix of a remote with the name of a file.
+func joinKey(prefix, name string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
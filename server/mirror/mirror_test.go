@@ -0,0 +1,110 @@
+package mirror
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/netguard"
+)
+
+func TestMirror(t *testing.T) {
+	g := goblin.Goblin(t)
+	now := time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	g.Describe("plan", func() {
+		src := map[string]Entry{
+			"same.txt":     {Size: 10, ModTime: now},
+			"resized.txt":  {Size: 20, ModTime: now},
+			"newer.txt":    {Size: 30, ModTime: now.Add(time.Minute)},
+			"maps/new.bsp": {Size: 40, ModTime: now},
+		}
+		dst := map[string]Entry{
+			"same.txt":    {Size: 10, ModTime: now.Add(time.Minute)},
+			"resized.txt": {Size: 10, ModTime: now.Add(time.Minute)},
+			"newer.txt":   {Size: 30, ModTime: now},
+			"old.txt":     {Size: 50, ModTime: now},
+		}
+
+		g.It("copies files that are missing or have changed", func() {
+			copies, deletes := plan(src, dst, false)
+			g.Assert(copies).Equal([]string{"maps/new.bsp", "newer.txt", "resized.txt"})
+			g.Assert(len(deletes)).Equal(0)
+		})
+
+		g.It("removes files that are no longer at the source", func() {
+			_, deletes := plan(src, dst, true)
+			g.Assert(deletes).Equal([]string{"old.txt"})
+		})
+	})
+
+	g.Describe("Job", func() {
+		g.It("validates the remote", func() {
+			j := Job{Name: "maps", Direction: DirectionPull, Interval: 60, Remote: "s3://bucket/maps"}
+			g.Assert(j.Validate()).IsNil()
+
+			j.Remote = "ftp://example.com/maps"
+			g.Assert(j.Validate() != nil).IsTrue()
+
+			j.Remote = "sftp://example.com/maps"
+			g.Assert(j.Validate() != nil).IsTrue()
+			j.HostKey = "SHA256:abc"
+			g.Assert(j.Validate()).IsNil()
+		})
+	})
+
+	g.Describe("s3Remote", func() {
+		g.It("signs requests using path style addressing", func() {
+			u, _ := url.Parse("s3://examplebucket/logs?region=eu-west-1")
+			s, err := newS3Remote(u, "AKID", "secret")
+			g.Assert(err).IsNil()
+
+			req, _ := http.NewRequest(http.MethodGet, "https://s3.eu-west-1.amazonaws.com/examplebucket/logs/a%20b.txt", nil)
+			s.sign(req, now)
+			g.Assert(req.Header.Get("x-amz-date")).Equal("20220401T120000Z")
+			g.Assert(req.Header.Get("Authorization")).Equal("AWS4-HMAC-SHA256 Credential=AKID/20220401/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=3c16c472909e4832f537616b27d2bdf36c7af82a815d5d2fb3247ee113d66842")
+		})
+
+		g.It("escapes reserved characters", func() {
+			g.Assert(s3Escape("a b/c+d", true)).Equal("a%20b/c%2Bd")
+			g.Assert(s3Escape("a/b", false)).Equal("a%2Fb")
+		})
+	})
+
+	g.Describe("httpRemote", func() {
+		var srv *httptest.Server
+
+		g.BeforeEach(func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token"})
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			}))
+		})
+
+		g.AfterEach(func() {
+			srv.Close()
+		})
+
+		g.It("refuses remotes within the local network", func() {
+			u, _ := url.Parse(srv.URL + "/mirror")
+			_, err := newHttpRemote(u, "", "").List(context.Background())
+			g.Assert(err).IsNotNil()
+			g.Assert(errors.Is(err, netguard.ErrInternalAddress)).IsTrue()
+		})
+
+		g.It("connects to remotes in an allowed network", func() {
+			config.Set(&config.Configuration{AuthenticationToken: "token", AllowedInternalNetworks: []string{"127.0.0.0/8"}})
+			u, _ := url.Parse(srv.URL + "/mirror")
+			entries, err := newHttpRemote(u, "", "").List(context.Background())
+			g.Assert(err).IsNil()
+			g.Assert(len(entries)).Equal(0)
+		})
+	})
+}
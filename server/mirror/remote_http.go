@@ -0,0 +1,216 @@
+package mirror
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+)
+
+// The properties requested when listing the contents of a WebDAV collection.
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:"><D:prop><D:resourcetype/><D:getcontentlength/><D:getlastmodified/></D:prop></D:propfind>`
+
+// httpRemote is a collection on a WebDAV server.
+type httpRemote struct {
+	base     *url.URL
+	username string
+	password string
+}
+
+func newHttpRemote(u *url.URL, username, password string) *httpRemote {
+	base := *u
+	base.Path = strings.TrimSuffix(base.Path, "/") + "/"
+	return &httpRemote{base: &base, username: username, password: password}
+}
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ContentLength int64  `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+			} `xml:"prop"`
+			Status string `xml:"status"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// List walks every collection below the base URL. Each collection is listed
+// separately since many servers do not allow a depth of infinity.
+func (h *httpRemote) List(ctx context.Context) (map[string]Entry, error) {
+	out := make(map[string]Entry)
+	pending := []string{""}
+	for len(pending) > 0 {
+		dir := pending[0]
+		pending = pending[1:]
+
+		res, err := h.do(ctx, "PROPFIND", dir, strings.NewReader(propfindBody), map[string]string{
+			"Depth":        "1",
+			"Content-Type": "application/xml",
+		})
+		if err != nil {
+			if dir == "" && isNotFound(err) {
+				return out, nil
+			}
+			return nil, err
+		}
+		var ms multistatus
+		err = xml.NewDecoder(res.Body).Decode(&ms)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "mirror: failed to decode webdav listing")
+		}
+
+		for _, r := range ms.Responses {
+			name, ok := h.relative(r.Href)
+			if !ok || strings.TrimSuffix(name, "/") == strings.TrimSuffix(dir, "/") {
+				continue
+			}
+			for _, ps := range r.Propstat {
+				if !strings.Contains(ps.Status, " 200 ") {
+					continue
+				}
+				if ps.Prop.ResourceType.Collection != nil {
+					pending = append(pending, strings.TrimSuffix(name, "/")+"/")
+					continue
+				}
+				t, _ := time.Parse(http.TimeFormat, ps.Prop.LastModified)
+				out[name] = Entry{Size: ps.Prop.ContentLength, ModTime: t}
+			}
+		}
+	}
+	return out, nil
+}
+
+func (h *httpRemote) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	res, err := h.do(ctx, http.MethodGet, name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// Put uploads the file, creating the base collection and any collections leading
+// up to the file. The parent of the base collection must already exist.
+func (h *httpRemote) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	dirs := []string{""}
+	if d := path.Dir(name); d != "." {
+		for _, part := range strings.Split(d, "/") {
+			dirs = append(dirs, dirs[len(dirs)-1]+part+"/")
+		}
+	}
+	for _, dir := range dirs {
+		res, err := h.do(ctx, "MKCOL", dir, nil, nil)
+		if err == nil {
+			_ = res.Body.Close()
+		} else if !isStatus(err, http.StatusMethodNotAllowed) {
+			return err
+		}
+	}
+	if size == 0 {
+		r = http.NoBody
+	}
+	res, err := h.doWithLength(ctx, http.MethodPut, name, r, size)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (h *httpRemote) Delete(ctx context.Context, name string) error {
+	res, err := h.do(ctx, http.MethodDelete, name, nil, nil)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (h *httpRemote) Close() error {
+	return nil
+}
+
+// relative returns the name of the href relative to the base URL, and false if
+// the href is outside of it.
+func (h *httpRemote) relative(href string) (string, bool) {
+	u, err := url.Parse(href)
+	if err != nil || !strings.HasPrefix(u.Path, h.base.Path) {
+		return "", false
+	}
+	return strings.TrimPrefix(u.Path, h.base.Path), true
+}
+
+func (h *httpRemote) do(ctx context.Context, method, name string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	req, err := h.newRequest(ctx, method, name, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return h.send(req)
+}
+
+func (h *httpRemote) doWithLength(ctx context.Context, method, name string, body io.Reader, size int64) (*http.Response, error) {
+	req, err := h.newRequest(ctx, method, name, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	return h.send(req)
+}
+
+func (h *httpRemote) newRequest(ctx context.Context, method, name string, body io.Reader) (*http.Request, error) {
+	u := *h.base
+	u.Path += name
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if h.username != "" || h.password != "" {
+		req.SetBasicAuth(h.username, h.password)
+	}
+	return req, nil
+}
+
+func (h *httpRemote) send(req *http.Request) (*http.Response, error) {
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "mirror: webdav request failed")
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		_ = res.Body.Close()
+		return nil, errors.WithStack(&statusError{method: req.Method, path: req.URL.Path, status: res.StatusCode})
+	}
+	return res, nil
+}
+
+// statusError is returned when a request to a remote is not successful.
+type statusError struct {
+	method string
+	path   string
+	status int
+}
+
+func (e *statusError) Error() string {
+	return "mirror: " + e.method + " " + e.path + " returned status " + http.StatusText(e.status)
+}
+
+func isStatus(err error, status int) bool {
+	var se *statusError
+	return errors.As(err, &se) && se.status == status
+}
+
+func isNotFound(err error) bool {
+	return isStatus(err, http.StatusNotFound)
+}
@@ -0,0 +1,238 @@
+package mirror
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/netguard"
+)
+
+// The payload hash sent for requests whose body is not signed, which allows the
+// files to be streamed to the bucket without reading them twice.
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// httpClient is used for requests to S3 and WebDAV remotes. The remotes are set
+// by the Panel, so addresses within the local network of the node are refused
+// unless they are in the allowed_internal_networks configuration.
+var httpClient = netguard.Client(time.Hour)
+
+// s3Remote is a prefix within an S3 bucket. Requests are signed using version 4
+// signatures and use path style addressing so that buckets hosted by providers
+// other than AWS can be used.
+type s3Remote struct {
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	region   string
+	key      string
+	secret   string
+}
+
+func newS3Remote(u *url.URL, key, secret string) (*s3Remote, error) {
+	region := u.Query().Get("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := u.Query().Get("endpoint")
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	e, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "mirror: invalid s3 endpoint")
+	}
+	return &s3Remote{
+		endpoint: e,
+		bucket:   u.Host,
+		prefix:   strings.Trim(u.Path, "/"),
+		region:   region,
+		key:      key,
+		secret:   secret,
+	}, nil
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns every object below the prefix, following continuation tokens
+// until every page has been read.
+func (s *s3Remote) List(ctx context.Context) (map[string]Entry, error) {
+	out := make(map[string]Entry)
+	prefix := s.prefix
+	if prefix != "" {
+		prefix += "/"
+	}
+	var token string
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		res, err := s.do(ctx, http.MethodGet, "", q, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		var page s3ListResult
+		err = xml.NewDecoder(res.Body).Decode(&page)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "mirror: failed to decode s3 listing")
+		}
+		for _, o := range page.Contents {
+			if strings.HasSuffix(o.Key, "/") {
+				continue
+			}
+			out[strings.TrimPrefix(o.Key, prefix)] = Entry{Size: o.Size, ModTime: o.LastModified}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return out, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (s *s3Remote) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	res, err := s.do(ctx, http.MethodGet, joinKey(s.prefix, name), nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *s3Remote) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	res, err := s.do(ctx, http.MethodPut, joinKey(s.prefix, name), nil, r, size)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (s *s3Remote) Delete(ctx context.Context, name string) error {
+	res, err := s.do(ctx, http.MethodDelete, joinKey(s.prefix, name), nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (s *s3Remote) Close() error {
+	return nil
+}
+
+// do sends a signed request for the key, returning an error if the response is
+// not successful.
+func (s *s3Remote) do(ctx context.Context, method, key string, q url.Values, body io.Reader, size int64) (*http.Response, error) {
+	p := "/" + s.bucket
+	if key != "" {
+		p += "/" + key
+	}
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + p
+	u.RawPath = s3Escape(u.Path, true)
+	u.RawQuery = s3CanonicalQuery(q)
+
+	// A request with a body of an unknown length is sent using chunked encoding, which
+	// S3 does not accept, so empty files must be sent without a body.
+	if body != nil && size == 0 {
+		body = http.NoBody
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, time.Now().UTC())
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "mirror: s3 request failed")
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		_ = res.Body.Close()
+		return nil, errors.Errorf("mirror: s3 request to %s returned status %d: %s", key, res.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return res, nil
+}
+
+// sign adds a version 4 signature to the request.
+func (s *s3Remote) sign(req *http.Request, now time.Time) {
+	date := now.Format("20060102T150405Z")
+	req.Header.Set("x-amz-date", date)
+	req.Header.Set("x-amz-content-sha256", s3UnsignedPayload)
+
+	headers := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", req.URL.Host, s3UnsignedPayload, date)
+	signed := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{req.Method, req.URL.EscapedPath(), req.URL.RawQuery, headers, signed, s3UnsignedPayload}, "\n")
+
+	scope := date[:8] + "/" + s.region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + date + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	k := hmacSha256([]byte("AWS4"+s.secret), date[:8])
+	k = hmacSha256(k, s.region)
+	k = hmacSha256(k, "s3")
+	k = hmacSha256(k, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(k, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.key, scope, signed, signature))
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3CanonicalQuery encodes the query with the keys sorted, as required for the
+// canonical request that is signed.
+func s3CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, s3Escape(k, false)+"="+s3Escape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent encodes every byte other than the unreserved characters, and
+// slashes when encoding a path.
+func s3Escape(s string, path bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || (path && c == '/') {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,121 @@
+package mirror
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/pterodactyl/wings/netguard"
+)
+
+// sftpRemote is a directory on an SFTP server.
+type sftpRemote struct {
+	conn   *ssh.Client
+	client *sftp.Client
+	root   string
+}
+
+// newSftpRemote connects to the SFTP server, which must present the host key
+// with the given SHA256 fingerprint.
+func newSftpRemote(ctx context.Context, u *url.URL, username, password, hostKey string) (*sftpRemote, error) {
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "22")
+	}
+	cfg := &ssh.ClientConfig{
+		User: username,
+		Auth: []ssh.AuthMethod{ssh.Password(password)},
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if ssh.FingerprintSHA256(key) != hostKey {
+				return errors.Errorf("mirror: host key for %s does not match, got %s", hostname, ssh.FingerprintSHA256(key))
+			}
+			return nil
+		},
+		Timeout: time.Second * 30,
+	}
+
+	// Addresses within the local network of the node are refused in the same way as
+	// for S3 and WebDAV remotes.
+	c, err := netguard.Dialer().DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, errors.Wrap(err, "mirror: failed to connect to sftp server")
+	}
+	sc, chans, reqs, err := ssh.NewClientConn(c, host, cfg)
+	if err != nil {
+		_ = c.Close()
+		return nil, errors.Wrap(err, "mirror: failed to authenticate with sftp server")
+	}
+	conn := ssh.NewClient(sc, chans, reqs)
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrap(err, "mirror: failed to start sftp session")
+	}
+	root := u.Path
+	if root == "" {
+		root = "."
+	}
+	return &sftpRemote{conn: conn, client: client, root: root}, nil
+}
+
+func (s *sftpRemote) List(ctx context.Context) (map[string]Entry, error) {
+	out := make(map[string]Entry)
+	w := s.client.Walk(s.root)
+	for w.Step() {
+		if err := w.Err(); err != nil {
+			if w.Path() == s.root && errors.Is(err, os.ErrNotExist) {
+				return out, nil
+			}
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		st := w.Stat()
+		if !st.Mode().IsRegular() {
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(w.Path(), s.root), "/")
+		out[name] = Entry{Size: st.Size(), ModTime: st.ModTime()}
+	}
+	return out, nil
+}
+
+func (s *sftpRemote) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.client.Open(path.Join(s.root, name))
+}
+
+// Put uploads the file, creating any directories leading up to it.
+func (s *sftpRemote) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	p := path.Join(s.root, name)
+	if err := s.client.MkdirAll(path.Dir(p)); err != nil {
+		return err
+	}
+	f, err := s.client.Create(p)
+	if err != nil {
+		return err
+	}
+	if _, err := f.ReadFrom(r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *sftpRemote) Delete(ctx context.Context, name string) error {
+	return s.client.Remove(path.Join(s.root, name))
+}
+
+func (s *sftpRemote) Close() error {
+	_ = s.client.Close()
+	return s.conn.Close()
+}
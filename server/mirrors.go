@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/events/webhook"
+	"github.com/pterodactyl/wings/server/mirror"
+)
+
+// How often the mirror jobs of every server are checked to see if they are due.
+const mirrorCheckInterval = time.Second * 30
+
+// mirrorScheduler tracks when each mirror job was last run, and which jobs are
+// currently running so that a slow job is never started again while running.
+type mirrorScheduler struct {
+	mu      sync.Mutex
+	last    map[string]time.Time
+	running map[string]bool
+	slots   chan struct{}
+}
+
+// StartMirrors runs the mirror jobs of every server once they are due until the
+// context is canceled.
+func (m *Manager) StartMirrors(ctx context.Context) {
+	cfg := config.Get().Mirrors
+	if !cfg.Enabled {
+		return
+	}
+	max := cfg.MaxConcurrent
+	if max < 1 {
+		max = 1
+	}
+	ms := &mirrorScheduler{
+		last:    make(map[string]time.Time),
+		running: make(map[string]bool),
+		slots:   make(chan struct{}, max),
+	}
+
+	ticker := time.NewTicker(mirrorCheckInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				for _, s := range m.All() {
+					ms.schedule(s, time.Now())
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// schedule starts every mirror job for the server that is due and not already
+// running. Jobs are not run while the server is suspended or its files are being
+// replaced by an installation, restore or transfer.
+func (ms *mirrorScheduler) schedule(s *Server, now time.Time) {
+	if s.IsSuspended() || s.IsInstalling() || s.IsRestoring() || s.IsTransferring() {
+		return
+	}
+	for _, j := range s.Config().Mirrors {
+		if err := j.Validate(); err != nil {
+			continue
+		}
+		key := s.ID() + "/" + j.Name
+		ms.mu.Lock()
+		due := !ms.running[key] && now.Sub(ms.last[key]) >= time.Duration(j.Interval)*time.Second
+		if due {
+			ms.running[key] = true
+		}
+		ms.mu.Unlock()
+		if !due {
+			continue
+		}
+		go func(j mirror.Job, key string) {
+			select {
+			case ms.slots <- struct{}{}:
+			case <-s.Context().Done():
+				ms.done(key, time.Time{})
+				return
+			}
+			s.RunMirror(s.Context(), j)
+			<-ms.slots
+			ms.done(key, time.Now())
+		}(j, key)
+	}
+}
+
+// done marks the job as no longer running. The time it was last run is only
+// updated when it is not zero so that a job that never ran is retried.
+func (ms *mirrorScheduler) done(key string, t time.Time) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.running, key)
+	if !t.IsZero() {
+		ms.last[key] = t
+	}
+}
+
+// RunMirror syncs the files of the server for the mirror job, emitting an event
+// with the outcome once it has completed.
+func (s *Server) RunMirror(ctx context.Context, j mirror.Job) {
+	logger := s.Log().WithFields(log.Fields{"mirror": j.Name, "direction": j.Direction})
+	logger.Debug("running mirror job for server")
+
+	start := time.Now()
+	res, err := s.runMirror(ctx, j)
+	data := map[string]interface{}{
+		"name":        j.Name,
+		"direction":   j.Direction,
+		"status":      "completed",
+		"copied":      res.Copied,
+		"deleted":     res.Deleted,
+		"bytes":       res.Bytes,
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		logger.WithField("error", err).Warn("failed to run mirror job for server")
+		data["status"] = "failed"
+		data["error"] = err.Error()
+		webhook.Dispatch(s.ID(), webhook.MirrorFailedEvent, data)
+	} else {
+		logger.WithFields(log.Fields{"copied": res.Copied, "deleted": res.Deleted}).Info("completed mirror job for server")
+	}
+	s.Events().Publish(MirrorSyncEvent, data)
+}
+
+func (s *Server) runMirror(ctx context.Context, j mirror.Job) (mirror.Result, error) {
+	r, err := mirror.Open(ctx, j)
+	if err != nil {
+		return mirror.Result{}, err
+	}
+	defer r.Close()
+	return mirror.Sync(ctx, j, s.Filesystem(), r)
+}
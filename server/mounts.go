@@ -23,7 +23,7 @@ func (s *Server) customMounts() []environment.Mount {
 	// TODO: probably need to handle things trying to mount directories that do not exist.
 	for _, m := range s.Config().Mounts {
 		source := filepath.Clean(m.Source)
-		target := filepath.Clean(m.Target)
+		target := filepath.Clean(translateMountTarget(m.Target))
 
 		logger := s.Log().WithFields(log.Fields{
 			"source_path": source,
@@ -56,3 +56,16 @@ func (s *Server) customMounts() []environment.Mount {
 
 	return mounts
 }
+
+// translateMountTarget converts a mount target written for a node running on a
+// different operating system, such as a server that has been transferred from an
+// upstream Linux node, into a target that can be used on this node. Any drive
+// letter is removed, backslashes are converted to slashes, and references to the
+// upstream data directory are replaced with the data directory of this node.
+func translateMountTarget(target string) string {
+	t := strings.ReplaceAll(target, "\\", "/")
+	if len(t) >= 2 && t[1] == ':' {
+		t = t[2:]
+	}
+	return environment.TranslateDataDirectory(t)
+}
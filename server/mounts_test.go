@@ -0,0 +1,25 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+func TestTranslateMountTarget(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("translateMountTarget", func() {
+		g.It("removes drive letters and converts backslashes", func() {
+			g.Assert(translateMountTarget(`C:\Maps\shared`)).Equal("/Maps/shared")
+			g.Assert(translateMountTarget("/maps")).Equal("/maps")
+		})
+
+		g.It("replaces the upstream data directory", func() {
+			g.Assert(translateMountTarget("/home/container/maps")).Equal(environment.ContainerDataDirectory + "/maps")
+			g.Assert(translateMountTarget("/home/containers")).Equal("/home/containers")
+		})
+	})
+}
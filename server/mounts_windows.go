@@ -12,7 +12,7 @@ func (s *Server) Mounts() []environment.Mount {
 	m := []environment.Mount{
 		{
 			Default:  true,
-			Target:   "/Container",
+			Target:   environment.ContainerDataDirectory,
 			Source:   s.Filesystem().Path(),
 			ReadOnly: false,
 		},
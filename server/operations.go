@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+)
+
+// The long-running operations that are tracked for a server. Backups, transfer
+// archives and transfers can also be canceled.
+const (
+	OperationInstall  = "install"
+	OperationBackup   = "backup"
+	OperationArchive  = "archive"
+	OperationTransfer = "transfer"
+	OperationChown    = "chown"
+)
+
+// The units that the progress of each type of operation is measured in. The
+// progress of an install is not known.
+var operationUnits = map[string]string{
+	OperationBackup:   "bytes",
+	OperationArchive:  "bytes",
+	OperationTransfer: "bytes",
+	OperationChown:    "files",
+}
+
+// The types of operation that can be canceled.
+var cancelableOperations = map[string]bool{
+	OperationBackup:   true,
+	OperationArchive:  true,
+	OperationTransfer: true,
+}
+
+var ErrOperationNotFound = errors.Sentinel("operation is not in progress")
+
+// Operation is a long-running operation in progress for a server, such as a
+// backup being generated.
+type Operation struct {
+	// The progress of the operation, these must be the first fields in the struct
+	// so that they are aligned for atomic operations on 32-bit platforms.
+	current    int64
+	total      int64
+	cancelable int32
+
+	ID     string
+	Type   string
+	Server string
+	// The thing that the operation is for, such as the UUID of a backup. This is
+	// empty for operations that can only be running once for a server at a time.
+	Reference string
+	StartedAt time.Time
+	// The unit that the progress is measured in, this is empty if the progress of
+	// the operation is not known.
+	Unit string
+
+	cancel context.CancelFunc
+}
+
+// SetTotal sets the total amount of work for the operation, which can be zero if
+// it is not known.
+func (op *Operation) SetTotal(n int64) {
+	atomic.StoreInt64(&op.total, n)
+}
+
+// Add adds to the amount of work done for the operation.
+func (op *Operation) Add(n int64) {
+	atomic.AddInt64(&op.current, n)
+}
+
+// Set sets the amount of work done for the operation.
+func (op *Operation) Set(n int64) {
+	atomic.StoreInt64(&op.current, n)
+}
+
+// Write adds the length of p to the amount of work done, which allows the
+// operation to count the bytes written to it by an io.MultiWriter or
+// io.TeeReader.
+func (op *Operation) Write(p []byte) (int, error) {
+	op.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// Cancelable returns true if the operation can currently be canceled.
+func (op *Operation) Cancelable() bool {
+	return atomic.LoadInt32(&op.cancelable) == 1
+}
+
+// DisableCancel prevents the operation from being canceled from this point on,
+// for when it has reached a stage that cannot be safely stopped part way through.
+func (op *Operation) DisableCancel() {
+	atomic.StoreInt32(&op.cancelable, 0)
+}
+
+// Progress returns the amount of work done and the total amount of work for
+// the operation.
+func (op *Operation) Progress() (current int64, total int64) {
+	return atomic.LoadInt64(&op.current), atomic.LoadInt64(&op.total)
+}
+
+type operationProgress struct {
+	Unit    string `json:"unit"`
+	Current int64  `json:"current"`
+	Total   int64  `json:"total,omitempty"`
+}
+
+// MarshalJSON returns the operation along with its progress, if it is known.
+func (op *Operation) MarshalJSON() ([]byte, error) {
+	var progress *operationProgress
+	if op.Unit != "" {
+		current, total := op.Progress()
+		progress = &operationProgress{Unit: op.Unit, Current: current, Total: total}
+	}
+	return json.Marshal(struct {
+		ID         string             `json:"id"`
+		Type       string             `json:"type"`
+		Server     string             `json:"server"`
+		Reference  string             `json:"reference,omitempty"`
+		StartedAt  time.Time          `json:"started_at"`
+		Progress   *operationProgress `json:"progress"`
+		Cancelable bool               `json:"cancelable"`
+	}{op.ID, op.Type, op.Server, op.Reference, op.StartedAt, progress, op.Cancelable()})
+}
+
+// operations holds every operation in progress, keyed by the ID of the operation.
+var operations = struct {
+	sync.Mutex
+	running map[string]*Operation
+}{running: make(map[string]*Operation)}
+
+// StartOperation tracks an operation for the server until the function returned
+// is called, which must be done once the operation is done. The context returned
+// is canceled when CancelOperation is called for the operation, or when ctx is
+// canceled.
+func (s *Server) StartOperation(ctx context.Context, kind, ref string) (context.Context, *Operation, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	op := &Operation{
+		ID:        uuid.New().String(),
+		Type:      kind,
+		Server:    s.ID(),
+		Reference: ref,
+		StartedAt: time.Now().UTC(),
+		Unit:      operationUnits[kind],
+		cancel:    cancel,
+	}
+	if cancelableOperations[kind] {
+		op.cancelable = 1
+	}
+	operations.Lock()
+	operations.running[op.ID] = op
+	operations.Unlock()
+
+	return ctx, op, func() {
+		operations.Lock()
+		delete(operations.running, op.ID)
+		operations.Unlock()
+		cancel()
+	}
+}
+
+// CancelOperation cancels the operation on the server, returning an
+// ErrOperationNotFound error if it is not in progress. The context of the
+// operation is canceled straight away, but the operation may take a moment to
+// stop and clean up after itself, and is still returned by Operations until it
+// has.
+func (s *Server) CancelOperation(kind, ref string) error {
+	operations.Lock()
+	var found []*Operation
+	for _, op := range operations.running {
+		if op.Server == s.ID() && op.Type == kind && op.Reference == ref && op.Cancelable() {
+			found = append(found, op)
+		}
+	}
+	operations.Unlock()
+	if len(found) == 0 {
+		return ErrOperationNotFound
+	}
+	s.Log().WithField("operation", kind).WithField("reference", ref).Info("canceling operation for server")
+	for _, op := range found {
+		op.cancel()
+	}
+	return nil
+}
+
+// Operations returns every operation in progress on the node, with the oldest
+// operation first.
+func Operations() []*Operation {
+	operations.Lock()
+	out := make([]*Operation, 0, len(operations.running))
+	for _, op := range operations.running {
+		out = append(out, op)
+	}
+	operations.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].StartedAt.Equal(out[j].StartedAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].StartedAt.Before(out[j].StartedAt)
+	})
+	return out
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/goccy/go-json"
+)
+
+func TestOperation(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("Operation", func() {
+		g.It("includes the progress when the unit is known", func() {
+			op := &Operation{ID: "op", Type: OperationBackup, Server: "srv", Unit: operationUnits[OperationBackup], cancelable: 1}
+			op.SetTotal(100)
+			op.Add(25)
+			_, _ = op.Write(make([]byte, 10))
+
+			var out map[string]interface{}
+			b, err := json.Marshal(op)
+			g.Assert(err).IsNil()
+			g.Assert(json.Unmarshal(b, &out)).IsNil()
+			g.Assert(out["type"]).Equal("backup")
+			g.Assert(out["cancelable"]).Equal(true)
+			g.Assert(out["progress"]).Equal(map[string]interface{}{"unit": "bytes", "current": float64(35), "total": float64(100)})
+		})
+
+		g.It("does not include the progress when it is not known", func() {
+			op := &Operation{ID: "op", Type: OperationInstall, Server: "srv"}
+			op.DisableCancel()
+
+			var out map[string]interface{}
+			b, err := json.Marshal(op)
+			g.Assert(err).IsNil()
+			g.Assert(json.Unmarshal(b, &out)).IsNil()
+			g.Assert(out["progress"]).IsNil()
+			g.Assert(out["cancelable"]).Equal(false)
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/events/webhook"
+)
+
+// The amount of time an orphaned container is given to stop before it is killed.
+const orphanStopTimeout = time.Second * 30
+
+// ReconcileContainers compares the containers created by Wings against the
+// servers on the node. Containers belonging to a server are left untouched so
+// that running servers are attached to again without being restarted, while
+// containers that do not belong to any server are handled according to the
+// orphan policy. The orphaned containers that were found are returned.
+//
+// If the servers for every Panel could not be loaded the orphans are only
+// reported, since a container may belong to a server that was not loaded.
+func (m *Manager) ReconcileContainers(ctx context.Context) ([]environment.ManagedContainer, error) {
+	containers, err := environment.ManagedContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := config.Get().Docker.OrphanPolicy
+	if !m.FullyLoaded() && policy != config.OrphanPolicyFlag {
+		log.WithField("policy", policy).Warn("not every server could be loaded from the Panel, orphaned containers will only be reported")
+		policy = config.OrphanPolicyFlag
+	}
+
+	orphans := m.orphanedContainers(containers)
+	for _, c := range orphans {
+		handleOrphanedContainer(ctx, c, policy)
+	}
+	return orphans, nil
+}
+
+// orphanedContainers returns the containers that do not belong to any server
+// returned by a Panel. Containers for servers that were returned by a Panel but
+// failed to load are not orphaned, since the server still exists.
+func (m *Manager) orphanedContainers(containers []environment.ManagedContainer) []environment.ManagedContainer {
+	var adopted int
+	var orphans []environment.ManagedContainer
+	for _, c := range containers {
+		if _, ok := m.Get(c.Server); ok {
+			if c.Running && c.Type == environment.ContainerTypeServer {
+				adopted++
+			}
+			continue
+		}
+		if m.Listed(c.Server) {
+			log.WithFields(log.Fields{"container_id": c.ID, "server": c.Server}).Warn("found container for a server that failed to load, leaving it untouched")
+			continue
+		}
+		orphans = append(orphans, c)
+	}
+	if adopted > 0 {
+		log.WithField("containers", adopted).Info("found running containers for servers on the node, these will be re-attached to without being restarted")
+	}
+	return orphans
+}
+
+// handleOrphanedContainer applies the orphan policy to the container and sends
+// the orphaned container event to any webhook endpoints subscribed to it.
+func handleOrphanedContainer(ctx context.Context, c environment.ManagedContainer, policy string) {
+	logger := log.WithFields(log.Fields{"container_id": c.ID, "name": c.Name, "type": c.Type, "running": c.Running})
+
+	action := config.OrphanPolicyFlag
+	var err error
+	switch policy {
+	case config.OrphanPolicyStop:
+		if c.Running {
+			action = config.OrphanPolicyStop
+			err = environment.StopContainer(ctx, c.ID, orphanStopTimeout)
+		}
+	case config.OrphanPolicyRemove:
+		action = config.OrphanPolicyRemove
+		err = environment.RemoveContainer(ctx, c.ID)
+	}
+
+	data := map[string]interface{}{
+		"container_id": c.ID,
+		"name":         c.Name,
+		"type":         c.Type,
+		"image":        c.Image,
+		"running":      c.Running,
+		"action":       action,
+	}
+	if err != nil {
+		logger.WithField("error", err).Error("failed to handle orphaned container")
+		data["error"] = err.Error()
+	} else {
+		logger.WithField("action", action).Warn("found container that does not belong to any server on the node")
+	}
+	webhook.Dispatch(c.Server, webhook.OrphanedContainerEvent, data)
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/remote"
+)
+
+func TestOrphanedContainers(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("orphanedContainers", func() {
+		g.It("does not treat containers for servers that failed to load as orphaned", func() {
+			m := NewEmptyManager(nil)
+			// The server has no process configuration so it fails to load.
+			m.loadServer("", remote.RawServerData{Uuid: "broken"})
+			_, ok := m.Get("broken")
+			g.Assert(ok).IsFalse()
+			g.Assert(m.Listed("broken")).IsTrue()
+
+			orphans := m.orphanedContainers([]environment.ManagedContainer{
+				{ID: "a", Server: "broken", Type: environment.ContainerTypeServer, Running: true},
+				{ID: "b", Server: "unknown", Type: environment.ContainerTypeServer, Running: true},
+			})
+			g.Assert(len(orphans)).Equal(1)
+			g.Assert(orphans[0].ID).Equal("b")
+		})
+	})
+}
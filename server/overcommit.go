@@ -0,0 +1,226 @@
+package server
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+var ErrOvercommitted = errors.Sentinel("server: node does not have enough resources available")
+
+// The manager containing every server on the node, used to check the resources
+// allocated on the node when a server is started.
+var (
+	nodeMu      sync.RWMutex
+	nodeManager *Manager
+)
+
+// NodeResource is the amount of a resource on the node and the amount allocated
+// to servers. Memory and disk are in megabytes, and CPU is a percentage where 100
+// is a single core.
+type NodeResource struct {
+	// The amount of the resource the node has, or 0 if it could not be determined.
+	Total int64 `json:"total"`
+
+	// The amount that can be allocated to servers based on the overcommit ratio.
+	Allowed int64 `json:"allowed"`
+
+	// The amount allocated to all servers, and to the servers that are running.
+	Allocated int64 `json:"allocated"`
+	Running   int64 `json:"running"`
+
+	// The amount that can still be allocated to servers. This is negative if the
+	// node is already overcommitted.
+	Available int64 `json:"available"`
+}
+
+// NodeResources is the memory, CPU and disk allocated to the servers on the node.
+type NodeResources struct {
+	Memory NodeResource `json:"memory"`
+	Cpu    NodeResource `json:"cpu"`
+	Disk   NodeResource `json:"disk"`
+}
+
+func (r *NodeResource) add(v int64, running bool) {
+	if v <= 0 {
+		return
+	}
+	r.Allocated += v
+	if running {
+		r.Running += v
+	}
+}
+
+func (r *NodeResource) allow(ratio float64) {
+	r.Allowed = int64(float64(r.Total) * ratio)
+	r.Available = r.Allowed - r.Allocated
+}
+
+// exceeds returns true if allocating v on top of the current amount would exceed
+// the amount allowed. Resources that could not be determined are never exceeded.
+func (r NodeResource) exceeds(current, v int64) bool {
+	return r.Total > 0 && v > 0 && current+v > r.Allowed
+}
+
+// Resources returns the resources of the node and the amount of them allocated
+// to servers, allowing the Panel to determine where new servers can be placed.
+func (m *Manager) Resources() NodeResources {
+	return m.resources("")
+}
+
+// TenantResources returns the resources of the node as seen by the Panel for the
+// tenant, so that a tenant cannot see the servers of any other tenant. Only the
+// servers of the tenant are counted as allocated, and the amount available is
+// limited to what is left within the limits of the tenant. An empty tenant name
+// is the primary Panel, which receives the resources of the entire node.
+func (m *Manager) TenantResources(tenant string) NodeResources {
+	node := m.Resources()
+	if tenant == "" {
+		return node
+	}
+
+	var used NodeResources
+	for _, s := range m.Filter(func(s *Server) bool { return m.ServerTenant(s.ID()) == tenant }) {
+		memory, cpu := s.allocated()
+		running := s.Environment.State() != environment.ProcessOfflineState
+		used.Memory.add(memory, running)
+		used.Cpu.add(cpu, running)
+		used.Disk.add(s.Config().Build.DiskSpace, running)
+	}
+	var limits config.TenantLimits
+	if t, ok := config.Get().Tenant(tenant); ok {
+		limits = t.Limits
+	}
+	return tenantResources(node, limits, used)
+}
+
+// tenantResources returns the resources of the node limited to those used by a
+// tenant. Where the tenant has a limit for a resource it is reported as the total
+// and allowed amount, and the amount available is the smaller of what the node and
+// the tenant have left.
+func tenantResources(node NodeResources, limits config.TenantLimits, used NodeResources) NodeResources {
+	scope := func(n NodeResource, limit int64, u NodeResource) NodeResource {
+		r := NodeResource{Total: n.Total, Allowed: n.Allowed, Allocated: u.Allocated, Running: u.Running, Available: n.Available}
+		if limit > 0 {
+			r.Total, r.Allowed = limit, limit
+			if limit-u.Allocated < r.Available {
+				r.Available = limit - u.Allocated
+			}
+		}
+		return r
+	}
+	return NodeResources{
+		Memory: scope(node.Memory, limits.Memory, used.Memory),
+		Cpu:    scope(node.Cpu, limits.Cpu, used.Cpu),
+		Disk:   scope(node.Disk, limits.Disk, used.Disk),
+	}
+}
+
+func (m *Manager) resources(exclude string) NodeResources {
+	r := NodeResources{
+		Memory: NodeResource{Total: environment.Capabilities().MemoryTotal / 1_000_000},
+		Cpu:    NodeResource{Total: int64(runtime.NumCPU()) * 100},
+		Disk:   NodeResource{Total: diskCapacity(config.Get().System.Data) / 1024 / 1024},
+	}
+	for _, s := range m.All() {
+		if s.ID() == exclude {
+			continue
+		}
+		memory, cpu := s.allocated()
+		running := s.Environment.State() != environment.ProcessOfflineState
+		r.Memory.add(memory, running)
+		r.Cpu.add(cpu, running)
+		r.Disk.add(s.Config().Build.DiskSpace, running)
+	}
+
+	c := config.Get().Overcommit
+	r.Memory.allow(c.Memory)
+	r.Cpu.allow(c.Cpu)
+	r.Disk.allow(c.Disk)
+
+	return r
+}
+
+// CheckOvercommit checks that the node has enough resources available for a new
+// server. Depending on the overcommit mode for the node an error is returned, or
+// a warning is logged, if creating the server would overcommit the node.
+func (m *Manager) CheckOvercommit(s *Server) error {
+	if config.Get().Overcommit.Mode == config.OvercommitModeOff {
+		return nil
+	}
+	r := m.resources(s.ID())
+	memory, cpu := s.allocated()
+
+	var exceeded []string
+	if r.Memory.exceeds(r.Memory.Allocated, memory) {
+		exceeded = append(exceeded, "memory")
+	}
+	if r.Cpu.exceeds(r.Cpu.Allocated, cpu) {
+		exceeded = append(exceeded, "cpu")
+	}
+	if r.Disk.exceeds(r.Disk.Allocated, s.Config().Build.DiskSpace) {
+		exceeded = append(exceeded, "disk")
+	}
+	return s.enforceOvercommit("create", exceeded)
+}
+
+// checkStartOvercommit checks that the node has enough memory and CPU available
+// for the server to be started, based on the servers that are already running.
+func (s *Server) checkStartOvercommit() error {
+	nodeMu.RLock()
+	m := nodeManager
+	nodeMu.RUnlock()
+	if m == nil || config.Get().Overcommit.Mode == config.OvercommitModeOff {
+		return nil
+	}
+	r := m.resources(s.ID())
+	memory, cpu := s.allocated()
+
+	var exceeded []string
+	if r.Memory.exceeds(r.Memory.Running, memory) {
+		exceeded = append(exceeded, "memory")
+	}
+	if r.Cpu.exceeds(r.Cpu.Running, cpu) {
+		exceeded = append(exceeded, "cpu")
+	}
+	if err := s.enforceOvercommit("start", exceeded); err != nil {
+		s.PublishConsoleOutputFromDaemon("The node does not have enough " + strings.Join(exceeded, " and ") + " available to start this server.")
+		return err
+	}
+	return nil
+}
+
+// allocated returns the memory and CPU allocated to the server, including the
+// limits of any sidecars defined by the egg. Sidecars are only counted towards
+// a resource when the server itself is limited.
+func (s *Server) allocated() (memory int64, cpu int64) {
+	c := s.Config()
+	memory, cpu = c.Build.MemoryLimit, c.Build.CpuLimit
+	for _, sc := range c.Egg.Sidecars {
+		if memory > 0 && sc.MemoryLimit > 0 {
+			memory += sc.MemoryLimit
+		}
+		if cpu > 0 && sc.CpuLimit > 0 {
+			cpu += sc.CpuLimit
+		}
+	}
+	return memory, cpu
+}
+
+func (s *Server) enforceOvercommit(action string, exceeded []string) error {
+	if len(exceeded) == 0 {
+		return nil
+	}
+	l := s.Log().WithField("action", action).WithField("resources", strings.Join(exceeded, ","))
+	if config.Get().Overcommit.Mode == config.OvercommitModeRefuse {
+		l.Warn("refusing server action that would overcommit the node")
+		return errors.Wrap(ErrOvercommitted, strings.Join(exceeded, ", "))
+	}
+	l.Warn("server action will overcommit the node")
+	return nil
+}
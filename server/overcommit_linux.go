@@ -0,0 +1,15 @@
+package server
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// diskCapacity returns the size in bytes of the filesystem containing the given
+// path, or 0 if it cannot be determined.
+func diskCapacity(path string) int64 {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0
+	}
+	return int64(st.Blocks) * int64(st.Bsize)
+}
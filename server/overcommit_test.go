@@ -0,0 +1,65 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+func TestNodeResource(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("NodeResource", func() {
+		g.It("calculates the allowed and available amount using the ratio", func() {
+			r := NodeResource{Total: 1000}
+			r.add(400, true)
+			r.add(300, false)
+			r.allow(1.5)
+
+			g.Assert(r.Allocated).Equal(int64(700))
+			g.Assert(r.Running).Equal(int64(400))
+			g.Assert(r.Allowed).Equal(int64(1500))
+			g.Assert(r.Available).Equal(int64(800))
+		})
+
+		g.It("does not count servers without a limit", func() {
+			r := NodeResource{Total: 1000}
+			r.add(0, true)
+			g.Assert(r.Allocated).Equal(int64(0))
+			g.Assert(r.exceeds(r.Allocated, 0)).IsFalse()
+		})
+
+		g.It("detects when an allocation exceeds the allowed amount", func() {
+			r := NodeResource{Total: 1000}
+			r.add(800, true)
+			r.allow(1)
+
+			g.Assert(r.exceeds(r.Allocated, 200)).IsFalse()
+			g.Assert(r.exceeds(r.Allocated, 201)).IsTrue()
+		})
+
+		g.It("never exceeds a resource that could not be determined", func() {
+			r := NodeResource{}
+			r.allow(1)
+			g.Assert(r.exceeds(0, 100)).IsFalse()
+		})
+	})
+
+	g.Describe("Server.allocated", func() {
+		g.It("includes the limits of the sidecars for limited servers", func() {
+			s := &Server{}
+			s.cfg.Build = environment.Limits{MemoryLimit: 1024, CpuLimit: 100}
+			s.cfg.Egg.Sidecars = []environment.Sidecar{{Name: "proxy", MemoryLimit: 256, CpuLimit: 50}, {Name: "exporter"}}
+			memory, cpu := s.allocated()
+			g.Assert(memory).Equal(int64(1280))
+			g.Assert(cpu).Equal(int64(150))
+
+			s.cfg.Build = environment.Limits{MemoryLimit: 1024}
+			memory, cpu = s.allocated()
+			g.Assert(memory).Equal(int64(1280))
+			g.Assert(cpu).Equal(int64(0))
+		})
+	})
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// diskCapacity returns the size in bytes of the volume containing the given path,
+// or 0 if it cannot be determined.
+func diskCapacity(path string) int64 {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree); err != nil {
+		return 0
+	}
+	return int64(total)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/server/filesystem"
 )
 
 type PowerAction string
@@ -131,7 +132,7 @@ func (s *Server) HandlePowerAction(action PowerAction, waitSeconds ...int) error
 			return err
 		}
 
-		return s.Environment.Start(s.Context())
+		return s.startEnvironment()
 	case PowerActionStop:
 		fallthrough
 	case PowerActionRestart:
@@ -158,7 +159,7 @@ func (s *Server) HandlePowerAction(action PowerAction, waitSeconds ...int) error
 			return err
 		}
 
-		return s.Environment.Start(s.Context())
+		return s.startEnvironment()
 	case PowerActionTerminate:
 		return s.Environment.Terminate(s.Context(), os.Kill)
 	}
@@ -166,6 +167,16 @@ func (s *Server) HandlePowerAction(action PowerAction, waitSeconds ...int) error
 	return errors.New("attempting to handle unknown power action")
 }
 
+// startEnvironment starts the environment for the server, explaining why it could
+// not be started if the image cannot run on the node.
+func (s *Server) startEnvironment() error {
+	err := s.Environment.Start(s.Context())
+	if err != nil {
+		s.reportIncompatibleImage(err, "start")
+	}
+	return err
+}
+
 // Execute a few functions before actually calling the environment start commands. This ensures
 // that everything is ready to go for environment booting, and that the server can even be started.
 func (s *Server) onBeforeStart() error {
@@ -173,6 +184,7 @@ func (s *Server) onBeforeStart() error {
 	if err := s.Sync(); err != nil {
 		return errors.WithMessage(err, "unable to sync server data from Panel instance")
 	}
+	s.SyncWriteDenylist()
 
 	// Disallow start & restart if the server is suspended. Do this check after performing a sync
 	// action with the Panel to ensure that we have the most up-to-date information for that server.
@@ -184,6 +196,55 @@ func (s *Server) onBeforeStart() error {
 	// and process resource limits are correctly applied.
 	s.SyncWithEnvironment()
 
+	// If the node is configured to select images based on the server runtime, swap the
+	// image out now that the environment has been synced with the server configuration.
+	s.applyRuntimeImage()
+
+	// Check that the node has the resources available to run the server now that the
+	// latest limits for it have been synced from the Panel.
+	if err := s.checkStartOvercommit(); err != nil {
+		return err
+	}
+
+	// Apply the pull policy for the server so that the image is only pulled when the
+	// container is recreated if the policy allows for it.
+	if env, ok := s.Environment.(interface{ SetPullPolicy(string) }); ok {
+		env.SetPullPolicy(s.Config().Container.PullPolicy)
+	}
+
+	// Apply the sidecars defined by the egg, these are created once the server
+	// container has been started.
+	if env, ok := s.Environment.(interface{ SetSidecars([]environment.Sidecar) }); ok {
+		env.SetSidecars(s.Config().Egg.Sidecars)
+	}
+
+	// Apply the security profiles requested by the egg, these are validated against
+	// the profiles the node allows when the container is created.
+	if env, ok := s.Environment.(interface {
+		SetSecurityProfile(environment.SecurityProfile)
+	}); ok {
+		env.SetSecurityProfile(s.Config().Egg.Security)
+	}
+
+	// Apply the root filesystem settings for the server, these are applied when the
+	// container is next created.
+	if env, ok := s.Environment.(interface{ SetRootFilesystem(*bool, []string) }); ok {
+		c := s.Config().Container
+		env.SetRootFilesystem(c.ReadOnlyRoot, c.WritablePaths)
+	}
+
+	// Apply the entrypoint wrapper requested by the egg, this is validated against the
+	// wrappers configured for the node when the container is created.
+	if env, ok := s.Environment.(interface{ SetEntrypointWrapper(string) }); ok {
+		env.SetEntrypointWrapper(s.Config().Egg.EntrypointWrapper)
+	}
+
+	// Apply the chain of steps used to stop the server, resolving the address and
+	// password for any RCON steps from the current configuration of the server.
+	if env, ok := s.Environment.(interface{ SetStopChain([]environment.StopStep) }); ok {
+		env.SetStopChain(s.stopChain())
+	}
+
 	// If a server has unlimited disk space, we don't care enough to block the startup to check remaining.
 	// However, we should trigger a size anyway, as it'd be good to kick it off for other processes.
 	if s.DiskSpace() <= 0 {
@@ -205,15 +266,43 @@ func (s *Server) onBeforeStart() error {
 	s.UpdateConfigurationFiles()
 	s.Log().Debug("updated server configuration files")
 
-	if config.Get().System.CheckPermissionsOnBoot {
-		s.PublishConsoleOutputFromDaemon("Ensuring file permissions are set correctly, this could take a few seconds...")
-		// Ensure all the server file permissions are set correctly before booting the process.
-		s.Log().Debug("chowning server root directory...")
-		if err := s.Filesystem().Chown("/"); err != nil {
-			return errors.WithMessage(err, "failed to chown root server directory during pre-boot process")
+	if err := s.configureUserIsolation(); err != nil {
+		return errors.WithMessage(err, "failed to configure server user during pre-boot process")
+	}
+
+	if cfg := config.Get().System; cfg.CheckPermissionsOnBoot {
+		opts := filesystem.ChownOptions{
+			TopLevel: cfg.CheckPermissionsScope == config.CheckPermissionsScopeTopLevel,
+			Workers:  cfg.CheckPermissionsWorkers,
+		}
+		if cfg.CheckPermissionsAsync {
+			// The permissions are checked while the server boots, any files that the
+			// process cannot access before they are reached will be fixed on the next boot.
+			go func() {
+				s.Log().Debug("chowning server root directory in the background...")
+				if err := s.chownRoot(opts); err != nil {
+					s.Log().WithField("error", err).Warn("failed to chown root server directory after pre-boot process")
+				}
+			}()
+		} else {
+			s.PublishConsoleOutputFromDaemon("Ensuring file permissions are set correctly, this could take a few seconds...")
+			// Ensure all the server file permissions are set correctly before booting the process.
+			s.Log().Debug("chowning server root directory...")
+			if err := s.chownRoot(opts); err != nil {
+				return errors.WithMessage(err, "failed to chown root server directory during pre-boot process")
+			}
 		}
 	}
 
 	s.Log().Info("completed server preflight, starting boot process...")
 	return nil
 }
+
+// chownRoot changes the owner of every file in the data directory of the server,
+// tracking it as an operation while it is running.
+func (s *Server) chownRoot(opts filesystem.ChownOptions) error {
+	_, op, done := s.StartOperation(s.Context(), OperationChown, "")
+	defer done()
+	opts.Progress = op.Add
+	return s.Filesystem().ChownWithOptions("/", opts)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/gammazero/workerpool"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+// interruptible is implemented by environments that can be detached from their
+// process when the connection to the container engine is lost.
+type interruptible interface {
+	Interrupted() bool
+}
+
+// ReconcileEnvironments returns each server using the Docker environment to the
+// state it was in before the connection to the Docker daemon was lost. Wings
+// attaches to any container that is still running, and servers whose containers
+// were stopped while the daemon was unavailable are started again.
+func (m *Manager) ReconcileEnvironments(ctx context.Context) {
+	pool := workerpool.New(4)
+	for _, s := range m.All() {
+		if _, ok := s.Environment.(interruptible); !ok {
+			continue
+		}
+		s := s
+		pool.Submit(func() {
+			s.reconcileEnvironment(ctx)
+		})
+	}
+	pool.StopWait()
+}
+
+func (s *Server) reconcileEnvironment(ctx context.Context) {
+	interrupted := s.Environment.(interruptible).Interrupted()
+	if s.IsInstalling() || s.IsTransferring() || s.IsRestoring() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	running, err := s.Environment.IsRunning(ctx)
+	if err != nil && !client.IsErrNotFound(err) {
+		s.Log().WithField("error", err).Warn("failed to check server environment status after reconnecting to docker")
+		return
+	}
+
+	if running {
+		// The container kept running, or was restarted by the daemon itself, so all
+		// that needs to happen is attaching to it again.
+		if s.Environment.State() == environment.ProcessOfflineState {
+			s.Log().Info("detected server is still running after reconnecting to docker, re-attaching to process...")
+			s.Environment.SetState(environment.ProcessRunningState)
+		}
+		if err := s.Environment.Attach(ctx); err != nil {
+			s.Log().WithField("error", err).Warn("failed to attach to running server environment")
+		}
+		return
+	}
+
+	if !interrupted {
+		return
+	}
+	if !config.Get().Docker.Reconnect.RestartServers || s.IsSuspended() {
+		s.PublishConsoleOutputFromDaemon("Server process was stopped while the connection to Docker was lost.")
+		return
+	}
+	s.Log().Info("starting server that was stopped while the connection to docker was lost")
+	s.PublishConsoleOutputFromDaemon("Connection to Docker restored, starting server process that was stopped while it was lost...")
+	if err := s.HandlePowerAction(PowerActionStart); err != nil {
+		s.Log().WithField("error", err).Warn("failed to return server to running state after reconnecting to docker")
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"sync"
+)
+
+// watchers tracks the number of websocket connections open for every server so
+// that resource usage is only collected while somebody is watching, when it is
+// configured to be collected on demand.
+var watchers = &watcherTracker{count: make(map[string]int)}
+
+type watcherTracker struct {
+	mu    sync.Mutex
+	count map[string]int
+}
+
+// WatchResources registers something watching the resource usage of the server,
+// such as a websocket connection. The function returned must be called once it
+// is no longer watching.
+func (s *Server) WatchResources() func() {
+	watchers.mu.Lock()
+	watchers.count[s.ID()]++
+	if watchers.count[s.ID()] == 1 {
+		s.setResourceDemand(true)
+	}
+	watchers.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			watchers.mu.Lock()
+			defer watchers.mu.Unlock()
+			watchers.count[s.ID()]--
+			if watchers.count[s.ID()] <= 0 {
+				delete(watchers.count, s.ID())
+				s.setResourceDemand(false)
+			}
+		})
+	}
+}
+
+// setResourceDemand tells the environment if the resource usage of the server is
+// currently wanted. Environments that always collect resource usage are left as
+// they are.
+func (s *Server) setResourceDemand(wanted bool) {
+	if env, ok := s.Environment.(interface{ SetResourceDemand(bool) }); ok {
+		env.SetResourceDemand(wanted)
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"archive/zip"
+	"io"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// The environment variables checked when attempting to determine the version of
+// Minecraft that a server is running.
+var minecraftVersionVariables = []string{"MINECRAFT_VERSION", "MC_VERSION", "VANILLA_VERSION"}
+
+// DetectRuntime determines the runtime required by the server in the format of
+// "runtime:version". The runtime defined on the egg always takes priority, after
+// which the server jar and finally the configured Minecraft version are checked.
+// An empty string is returned if no runtime could be determined.
+func (s *Server) DetectRuntime() string {
+	cfg := s.Config()
+	if cfg.Egg.Runtime != "" {
+		return cfg.Egg.Runtime
+	}
+
+	if jar := cfg.EnvVars.Get("SERVER_JARFILE"); jar != "" {
+		v, err := s.javaVersionFromJar(jar)
+		if err != nil {
+			s.Log().WithField("jar", jar).WithField("error", err).Debug("could not determine java version from server jar")
+		} else if v > 0 {
+			return "java:" + strconv.Itoa(v)
+		}
+	}
+
+	for _, k := range minecraftVersionVariables {
+		if v := javaVersionForMinecraft(cfg.EnvVars.Get(k)); v > 0 {
+			return "java:" + strconv.Itoa(v)
+		}
+	}
+
+	return ""
+}
+
+// javaVersionFromJar reads the "version.json" file that is bundled with modern
+// Minecraft server jars and returns the major Java version that it requires.
+func (s *Server) javaVersionFromJar(name string) (int, error) {
+	f, st, err := s.Filesystem().File(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r, err := zip.NewReader(f, st.Size())
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	for _, zf := range r.File {
+		if zf.Name != "version.json" {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		defer rc.Close()
+
+		var v struct {
+			JavaVersion int `json:"java_version"`
+		}
+		if err := json.NewDecoder(io.LimitReader(rc, 1024*64)).Decode(&v); err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return v.JavaVersion, nil
+	}
+	return 0, nil
+}
+
+// javaVersionForMinecraft returns the minimum major Java version required to run
+// the given version of Minecraft, or 0 if the version cannot be parsed.
+func javaVersionForMinecraft(version string) int {
+	parts := strings.Split(strings.TrimSpace(version), ".")
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0
+	}
+	var patch int
+	if len(parts) > 2 {
+		patch, _ = strconv.Atoi(parts[2])
+	}
+
+	switch {
+	case minor > 20 || (minor == 20 && patch >= 5):
+		return 21
+	case minor >= 18:
+		return 17
+	case minor == 17:
+		return 16
+	default:
+		return 8
+	}
+}
+
+// applyRuntimeImage replaces the image used by the server environment with the
+// image mapped to the server's detected runtime, if runtime selection is enabled
+// and a mapping exists for it.
+func (s *Server) applyRuntimeImage() {
+	images := config.Get().Docker.RuntimeImages
+	if !images.Enabled {
+		return
+	}
+
+	runtime := s.DetectRuntime()
+	img, ok := images.ImageFor(runtime)
+	if !ok {
+		return
+	}
+
+	env, ok := s.Environment.(interface{ SetImage(string) })
+	if !ok {
+		return
+	}
+
+	s.Log().WithFields(log.Fields{"runtime": runtime, "image": img}).Info("using container image selected for detected server runtime")
+	s.PublishConsoleOutputFromDaemon("Detected " + runtime + " runtime, using container image " + img)
+	env.SetImage(img)
+}
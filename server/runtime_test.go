@@ -0,0 +1,29 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestJavaVersionForMinecraft(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("javaVersionForMinecraft", func() {
+		g.It("maps Minecraft versions to the required Java version", func() {
+			g.Assert(javaVersionForMinecraft("1.12.2")).Equal(8)
+			g.Assert(javaVersionForMinecraft("1.16.5")).Equal(8)
+			g.Assert(javaVersionForMinecraft("1.17.1")).Equal(16)
+			g.Assert(javaVersionForMinecraft("1.18")).Equal(17)
+			g.Assert(javaVersionForMinecraft("1.20.4")).Equal(17)
+			g.Assert(javaVersionForMinecraft("1.20.5")).Equal(21)
+			g.Assert(javaVersionForMinecraft("1.21")).Equal(21)
+		})
+
+		g.It("returns zero for unknown versions", func() {
+			g.Assert(javaVersionForMinecraft("")).Equal(0)
+			g.Assert(javaVersionForMinecraft("latest")).Equal(0)
+			g.Assert(javaVersionForMinecraft("1.x")).Equal(0)
+		})
+	})
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"strings"
+)
+
+// The value that secret variables are replaced with when they are redacted.
+const redactedSecret = "********"
+
+// The minimum length of a secret value for it to be redacted from output. Shorter
+// values are too likely to appear within unrelated output, so are left as-is.
+const minSecretLength = 4
+
+// isSecretVariable returns true if the Panel has marked the environment variable
+// as a secret. Variable names are compared without regard to case since they are
+// passed to the container in uppercase.
+func (s *Server) isSecretVariable(name string) bool {
+	for _, v := range s.Config().Secrets {
+		if strings.EqualFold(v, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretValues returns the values of every environment variable for the server
+// that has been marked as a secret.
+func (s *Server) secretValues() []string {
+	c := s.Config()
+	if len(c.Secrets) == 0 {
+		return nil
+	}
+	var out []string
+	for k := range c.EnvVars {
+		if !s.isSecretVariable(k) {
+			continue
+		}
+		if v := c.EnvVars.Get(k); len(v) >= minSecretLength {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// RedactSecrets replaces the value of every secret environment variable within
+// the output with a placeholder, so that they are not exposed to anyone viewing
+// the console or logs for the server.
+func (s *Server) RedactSecrets(b []byte) []byte {
+	for _, v := range s.secretValues() {
+		b = bytes.ReplaceAll(b, []byte(v), []byte(redactedSecret))
+	}
+	return b
+}
+
+// RedactSecretLines replaces the value of every secret environment variable
+// within each of the lines with a placeholder.
+func (s *Server) RedactSecretLines(lines []string) []string {
+	values := s.secretValues()
+	if len(values) == 0 {
+		return lines
+	}
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		for _, v := range values {
+			l = strings.ReplaceAll(l, v, redactedSecret)
+		}
+		out[i] = l
+	}
+	return out
+}
+
+// redactedEnvironmentVariables returns the environment variables for the server
+// with the value of every secret variable replaced with a placeholder.
+func (s *Server) redactedEnvironmentVariables() []string {
+	evs := s.GetEnvironmentVariables()
+	out := make([]string, len(evs))
+	for i, v := range evs {
+		out[i] = v
+		if k := strings.SplitN(v, "=", 2); len(k) == 2 && s.isSecretVariable(k[0]) {
+			out[i] = k[0] + "=" + redactedSecret
+		}
+	}
+	// Secrets can also be referenced within the values of other variables, such as the
+	// startup command for the server.
+	return s.RedactSecretLines(out)
+}
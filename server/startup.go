@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/events/webhook"
+	"github.com/pterodactyl/wings/remote"
+)
+
+// The actions that can be taken when a server does not reach the running state
+// within the configured startup timeout.
+const (
+	StartupTimeoutActionKill    = "kill"
+	StartupTimeoutActionRestart = "restart"
+	StartupTimeoutActionFail    = "fail"
+)
+
+// The maximum number of startup detection expressions that are cached. The
+// patterns are defined by the Panel, so the cache is cleared once it is full
+// rather than growing without limit.
+const maxStartupPatterns = 512
+
+// startupPatterns caches compiled startup detection expressions so that we are
+// not compiling the same expression for every line of console output. Patterns
+// that failed to compile are stored as nil so that they are only reported once.
+var startupPatterns = struct {
+	sync.RWMutex
+	m map[string]*regexp.Regexp
+}{m: make(map[string]*regexp.Regexp)}
+
+// compileStartupPattern returns the compiled regular expression for the given
+// pattern, or nil if the pattern is not a valid expression.
+func compileStartupPattern(p string) *regexp.Regexp {
+	startupPatterns.RLock()
+	r, ok := startupPatterns.m[p]
+	startupPatterns.RUnlock()
+	if ok {
+		return r
+	}
+
+	r, err := regexp.Compile(p)
+	if err != nil {
+		log.WithFields(log.Fields{"pattern": p, "error": err}).Warn("failed to compile startup detection pattern, ignoring")
+		r = nil
+	}
+	startupPatterns.Lock()
+	defer startupPatterns.Unlock()
+	if _, ok := startupPatterns.m[p]; !ok && len(startupPatterns.m) >= maxStartupPatterns {
+		startupPatterns.m = make(map[string]*regexp.Regexp)
+	}
+	startupPatterns.m[p] = r
+	return r
+}
+
+// matchesAnyPattern determines if the given line of output matches any of the
+// provided regular expressions, returning the first pattern that matched.
+func matchesAnyPattern(v []byte, patterns []string) (string, bool) {
+	for _, p := range patterns {
+		if r := compileStartupPattern(p); r != nil && r.Match(v) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// startupTimeout returns the timeout and action to apply for the server when it
+// does not finish starting, falling back to the node defaults for any values not
+// defined on the server itself.
+func (s *Server) startupTimeout() (time.Duration, string) {
+	c := s.Config().Startup
+	d := config.Get().System.StartupDetection
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = d.Timeout
+	}
+	action := c.TimeoutAction
+	if action == "" {
+		action = d.TimeoutAction
+	}
+	return time.Duration(timeout) * time.Second, action
+}
+
+type startupWatcher struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	server *Server
+}
+
+func newStartupWatcher(s *Server) *startupWatcher {
+	return &startupWatcher{server: s}
+}
+
+// Start begins tracking the amount of time the server has spent in the starting
+// state. If the server is still starting once the timeout has passed the timeout
+// action is applied. Calling this function again will reset the timer.
+func (sw *startupWatcher) Start() {
+	timeout, action := sw.server.startupTimeout()
+
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.cancel != nil {
+		sw.cancel()
+		sw.cancel = nil
+	}
+	if timeout <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(sw.server.Context(), timeout)
+	sw.cancel = cancel
+	go func() {
+		<-ctx.Done()
+		if ctx.Err() != context.DeadlineExceeded {
+			return
+		}
+		if sw.server.Environment.State() != environment.ProcessStartingState {
+			return
+		}
+		sw.server.onStartupTimeout(timeout, action)
+	}()
+}
+
+// Stop cancels any running startup timer for the server.
+func (sw *startupWatcher) Stop() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.cancel != nil {
+		sw.cancel()
+		sw.cancel = nil
+	}
+}
+
+// onStartupTimeout applies the configured timeout action to a server that did
+// not reach the running state in time.
+func (s *Server) onStartupTimeout(timeout time.Duration, action string) {
+	s.Log().WithFields(log.Fields{"timeout": timeout, "action": action}).Warn("server did not reach running state before startup timeout")
+	s.PublishConsoleOutputFromDaemon(fmt.Sprintf("Server did not finish starting within %s.", timeout))
+
+	switch action {
+	case StartupTimeoutActionRestart:
+		s.PublishConsoleOutputFromDaemon("Restarting server process...")
+		if err := s.HandlePowerAction(PowerActionRestart); err != nil {
+			s.Log().WithField("error", err).Error("failed to restart server after startup timeout")
+		}
+	case StartupTimeoutActionFail:
+		s.markStartupFailed("startup timeout exceeded")
+	default:
+		s.PublishConsoleOutputFromDaemon("Terminating server process...")
+		if err := s.HandlePowerAction(PowerActionTerminate); err != nil {
+			s.Log().WithField("error", err).Error("failed to terminate server after startup timeout")
+		}
+	}
+}
+
+// markStartupFailed terminates the server process and notifies any listeners
+// that the server failed to start. Terminating the process marks the server as
+// stopping first, so crash detection will not attempt to restart it.
+func (s *Server) markStartupFailed(reason string) {
+	s.PublishConsoleOutputFromDaemon("Server has been marked as failed to start: " + reason)
+	s.Events().Publish(StartupFailedEvent, reason)
+	if err := s.Environment.Terminate(s.Context(), os.Kill); err != nil {
+		s.Log().WithField("error", err).Error("failed to terminate server after it failed to start")
+	}
+}
+
+// reportIncompatibleImage tells the user, the Panel and any webhook endpoints why
+// the image for the server cannot be run on the node if the error is because of
+// that. Any other error is ignored.
+func (s *Server) reportIncompatibleImage(err error, action string) {
+	var ierr *environment.ImageCompatibilityError
+	if !errors.As(err, &ierr) {
+		return
+	}
+	s.Log().WithField("image", ierr.Image).WithField("reason", ierr.Reason).Error("image for server cannot run on this node")
+	s.PublishConsoleOutputFromDaemon(fmt.Sprintf("The image %s cannot be used on this node: %s.", ierr.Image, ierr.Reason))
+	if action == "start" {
+		s.Events().Publish(StartupFailedEvent, "incompatible image")
+	}
+	webhook.Dispatch(s.ID(), webhook.ImageIncompatibleEvent, map[string]interface{}{
+		"image":  ierr.Image,
+		"reason": ierr.Reason,
+		"action": action,
+	})
+
+	if c, ok := s.client.(remote.IncompatibleImageClient); ok {
+		r := remote.IncompatibleImageReport{Image: ierr.Image, Reason: ierr.Reason, Action: action}
+		if err := c.ReportIncompatibleImage(s.Context(), s.ID(), r); err != nil {
+			s.Log().WithField("error", err).Warn("failed to notify panel of incompatible image")
+		}
+	}
+}
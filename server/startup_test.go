@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestMatchesAnyPattern(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("matchesAnyPattern", func() {
+		g.It("returns the first matching pattern", func() {
+			p, ok := matchesAnyPattern([]byte("[12:00:00] Done (3.21s)! For help, type \"help\""), []string{
+				`^Listening on port \d+`,
+				`Done \([0-9.]+s\)!`,
+			})
+			g.Assert(ok).IsTrue()
+			g.Assert(p).Equal(`Done \([0-9.]+s\)!`)
+		})
+
+		g.It("does not match when no patterns are defined", func() {
+			_, ok := matchesAnyPattern([]byte("Done!"), nil)
+			g.Assert(ok).IsFalse()
+		})
+
+		g.It("ignores invalid expressions", func() {
+			_, ok := matchesAnyPattern([]byte("Done ("), []string{"Done ("})
+			g.Assert(ok).IsFalse()
+		})
+
+		g.It("caches expressions that fail to compile", func() {
+			g.Assert(compileStartupPattern("Invalid (") == nil).IsTrue()
+
+			startupPatterns.RLock()
+			r, ok := startupPatterns.m["Invalid ("]
+			startupPatterns.RUnlock()
+			g.Assert(ok).IsTrue()
+			g.Assert(r == nil).IsTrue()
+		})
+
+		g.It("clears the cache once it is full", func() {
+			for i := 0; i <= maxStartupPatterns; i++ {
+				compileStartupPattern(fmt.Sprintf("pattern %d", i))
+			}
+
+			startupPatterns.RLock()
+			defer startupPatterns.RUnlock()
+			g.Assert(len(startupPatterns.m) <= maxStartupPatterns).IsTrue()
+		})
+	})
+}
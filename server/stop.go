@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+// stopChain returns the steps defined by the egg to stop the server, with the
+// address and password of the RCON server resolved for any RCON steps. The RCON
+// port must be one of the server's allocations, otherwise the address is left
+// empty and the step is skipped, so that an egg cannot use the stop chain to
+// connect to services on the node that do not belong to the server.
+func (s *Server) stopChain() []environment.StopStep {
+	cfg := s.Config()
+	chain := make([]environment.StopStep, len(cfg.Egg.StopChain))
+	for i, step := range cfg.Egg.StopChain {
+		if step.Type == environment.StopStepRcon {
+			if addr, ok := rconAddress(cfg.Allocations, cfg.EnvVars.Get(step.RconPortVariable)); ok {
+				step.RconAddress = addr
+			} else {
+				s.Log().WithField("port", cfg.EnvVars.Get(step.RconPortVariable)).Warn("rcon port for stop step is not one of the server's allocations")
+			}
+			if step.RconPasswordVariable != "" {
+				step.RconPassword = cfg.EnvVars.Get(step.RconPasswordVariable)
+			}
+		}
+		chain[i] = step
+	}
+	return chain
+}
+
+// rconAddress returns the address of the RCON server listening on the given
+// port, which must be allocated to the server. The IP of the default allocation
+// is used if the port is allocated on it, otherwise the IP the port is allocated
+// on. Returns false if the port is not allocated to the server.
+func rconAddress(a environment.Allocations, port string) (string, bool) {
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return "", false
+	}
+	var ip string
+	var found bool
+	for mip, ports := range a.Mappings {
+		for _, v := range ports {
+			if v == p && (!found || mip == a.DefaultMapping.Ip) {
+				ip, found = mip, true
+			}
+		}
+	}
+	if !found {
+		return "", false
+	}
+	if ip == "" || ip == "0.0.0.0" {
+		ip = "127.0.0.1"
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(p)), true
+}
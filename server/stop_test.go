@@ -0,0 +1,43 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+func TestRconAddress(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("rconAddress", func() {
+		a := environment.Allocations{
+			Mappings: map[string][]int{
+				"0.0.0.0":  {25565, 25575},
+				"10.0.0.5": {25580},
+			},
+		}
+		a.DefaultMapping.Ip = "0.0.0.0"
+		a.DefaultMapping.Port = 25565
+
+		g.It("uses the loopback address for ports on the default allocation", func() {
+			addr, ok := rconAddress(a, "25575")
+			g.Assert(ok).IsTrue()
+			g.Assert(addr).Equal("127.0.0.1:25575")
+		})
+
+		g.It("uses the IP the port is allocated on", func() {
+			addr, ok := rconAddress(a, "25580")
+			g.Assert(ok).IsTrue()
+			g.Assert(addr).Equal("10.0.0.5:25580")
+		})
+
+		g.It("refuses ports that are not allocated to the server", func() {
+			_, ok := rconAddress(a, "22")
+			g.Assert(ok).IsFalse()
+			_, ok = rconAddress(a, "")
+			g.Assert(ok).IsFalse()
+		})
+	})
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"emperror.dev/errors"
+)
+
+// sessions tracks the open file sessions, such as SFTP and WebDAV, for every
+// server so that they can be closed when the server is suspended.
+var sessions = &sessionTracker{open: make(map[string]map[*fileSession]struct{})}
+
+type fileSession struct {
+	cancel func()
+}
+
+type sessionTracker struct {
+	mu   sync.Mutex
+	open map[string]map[*fileSession]struct{}
+}
+
+// TrackFileSession registers a session accessing the files of the server, such
+// as an SFTP or WebDAV session, the cancel function is called if the server is
+// suspended while the session is open. The function returned must be called once
+// the session has ended. An error is returned if the server is already
+// suspended, in which case the session must be refused.
+func (s *Server) TrackFileSession(cancel func()) (func(), error) {
+	if s.IsSuspended() {
+		return nil, ErrSuspended
+	}
+	sess := &fileSession{cancel: cancel}
+	sessions.mu.Lock()
+	if sessions.open[s.ID()] == nil {
+		sessions.open[s.ID()] = make(map[*fileSession]struct{})
+	}
+	sessions.open[s.ID()][sess] = struct{}{}
+	sessions.mu.Unlock()
+
+	return func() {
+		sessions.mu.Lock()
+		delete(sessions.open[s.ID()], sess)
+		if len(sessions.open[s.ID()]) == 0 {
+			delete(sessions.open, s.ID())
+		}
+		sessions.mu.Unlock()
+	}, nil
+}
+
+// closeFileSessions closes every open file session for the server, returning the
+// number of sessions that were closed.
+func (s *Server) closeFileSessions() int {
+	sessions.mu.Lock()
+	open := sessions.open[s.ID()]
+	delete(sessions.open, s.ID())
+	sessions.mu.Unlock()
+
+	for sess := range open {
+		sess.cancel()
+	}
+	return len(open)
+}
+
+// EnforceSuspension applies the suspension state of the server to anything that
+// is still running for it. Refusing power actions is not enough to stop a server
+// that was already running when it was suspended from serving players, so the
+// server's container is disconnected from the network and any open SFTP and
+// WebDAV sessions are closed. Once the server is unsuspended the container is reconnected.
+func (s *Server) EnforceSuspension(ctx context.Context) error {
+	suspended := s.IsSuspended()
+	if suspended {
+		if n := s.closeFileSessions(); n > 0 {
+			s.Log().WithField("sessions", n).Info("closed file sessions for suspended server")
+		}
+	}
+
+	env, ok := s.Environment.(interface {
+		SetNetworkIsolated(context.Context, bool) error
+	})
+	if !ok {
+		if suspended {
+			s.Log().WithField("environment", s.Environment.Type()).Debug("environment does not support network isolation, skipping")
+		}
+		return nil
+	}
+	if err := env.SetNetworkIsolated(ctx, suspended); err != nil {
+		return errors.WrapIf(err, "server: failed to enforce suspension")
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/google/uuid"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/cron"
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/events/webhook"
+	"github.com/pterodactyl/wings/server/backup"
+)
+
+// How often the tasks scheduled on the node are checked to see if they are due.
+const taskCheckInterval = time.Second * 15
+
+// The maximum amount of output from a hook script that is logged.
+const maxHookOutput = 4 * 1024
+
+var (
+	ErrTaskNotFound = errors.Sentinel("task not found")
+	ErrTaskRunning  = errors.Sentinel("task is already running")
+)
+
+// TaskStatus is a task scheduled on the node along with when it next runs and
+// the outcome of the last time it was run.
+type TaskStatus struct {
+	config.ScheduledTask
+	NextRun *time.Time `json:"next_run"`
+	Running bool       `json:"running"`
+	LastRun *TaskRun   `json:"last_run"`
+}
+
+// TaskRun is the outcome of a single run of a task. The errors are for each of
+// the servers the task failed for, or the task itself.
+type TaskRun struct {
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	Successful  bool      `json:"successful"`
+	Errors      []string  `json:"errors"`
+}
+
+// taskScheduler tracks when each task next runs, which tasks are currently
+// running so that a slow task is never started again while running, and the
+// outcome of the last run of each task.
+type taskScheduler struct {
+	mu        sync.Mutex
+	ctx       context.Context
+	next      map[string]time.Time
+	schedules map[string]string
+	running   map[string]bool
+	last      map[string]TaskRun
+}
+
+func newTaskScheduler() *taskScheduler {
+	return &taskScheduler{
+		ctx:       context.Background(),
+		next:      make(map[string]time.Time),
+		schedules: make(map[string]string),
+		running:   make(map[string]bool),
+		last:      make(map[string]TaskRun),
+	}
+}
+
+// StartTasks runs the tasks scheduled on the node once they are due until the
+// context is canceled. Tasks are read from the configuration every time they
+// are checked, so changes made through the API apply without restarting.
+func (m *Manager) StartTasks(ctx context.Context) {
+	if !config.Get().Tasks.Enabled {
+		return
+	}
+	m.tasks.mu.Lock()
+	m.tasks.ctx = ctx
+	m.tasks.mu.Unlock()
+
+	ticker := time.NewTicker(taskCheckInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.scheduleTasks(time.Now())
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// scheduleTasks starts every task that is due and not already running. A task
+// that is still running when it is next due is skipped until the time after.
+func (m *Manager) scheduleTasks(now time.Time) {
+	cfg := config.Get().Tasks
+	for _, t := range cfg.Tasks {
+		if t.Disabled || t.Validate(cfg.HookDirectory) != nil {
+			continue
+		}
+		s, _ := cron.Parse(t.Schedule)
+
+		m.tasks.mu.Lock()
+		next, ok := m.tasks.next[t.Name]
+		if !ok || m.tasks.schedules[t.Name] != t.Schedule {
+			next = s.Next(now)
+			m.tasks.next[t.Name] = next
+			m.tasks.schedules[t.Name] = t.Schedule
+		}
+		due := !next.IsZero() && !now.Before(next)
+		if due {
+			m.tasks.next[t.Name] = s.Next(now)
+			due = !m.tasks.running[t.Name]
+			m.tasks.running[t.Name] = true
+		}
+		ctx := m.tasks.ctx
+		m.tasks.mu.Unlock()
+
+		if due {
+			go m.runTask(ctx, t)
+		}
+	}
+}
+
+// Tasks returns the status of every task scheduled on the node.
+func (m *Manager) Tasks() []TaskStatus {
+	tasks := config.Get().Tasks.Tasks
+	out := make([]TaskStatus, 0, len(tasks))
+
+	m.tasks.mu.Lock()
+	defer m.tasks.mu.Unlock()
+	for _, t := range tasks {
+		st := TaskStatus{ScheduledTask: t, Running: m.tasks.running[t.Name]}
+		if next, ok := m.tasks.next[t.Name]; ok && !next.IsZero() && !t.Disabled && m.tasks.schedules[t.Name] == t.Schedule {
+			st.NextRun = &next
+		}
+		if last, ok := m.tasks.last[t.Name]; ok {
+			st.LastRun = &last
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// RunTask runs the task in the background straight away, regardless of its
+// schedule or whether it is disabled.
+func (m *Manager) RunTask(name string) error {
+	t, ok := config.Get().Task(name)
+	if !ok {
+		return ErrTaskNotFound
+	}
+
+	m.tasks.mu.Lock()
+	if m.tasks.running[name] {
+		m.tasks.mu.Unlock()
+		return ErrTaskRunning
+	}
+	m.tasks.running[name] = true
+	ctx := m.tasks.ctx
+	m.tasks.mu.Unlock()
+
+	go m.runTask(ctx, t)
+	return nil
+}
+
+// ForgetTask removes everything tracked for the task once it is removed from the
+// configuration.
+func (m *Manager) ForgetTask(name string) {
+	m.tasks.mu.Lock()
+	defer m.tasks.mu.Unlock()
+	delete(m.tasks.next, name)
+	delete(m.tasks.schedules, name)
+	delete(m.tasks.last, name)
+}
+
+// runTask runs the action for the task, storing the outcome and sending the
+// task failed event to any webhook endpoints subscribed to it if it fails. The
+// task must already be marked as running.
+func (m *Manager) runTask(ctx context.Context, t config.ScheduledTask) {
+	logger := log.WithFields(log.Fields{"task": t.Name, "action": t.Action})
+	logger.Info("running scheduled task for node")
+
+	run := TaskRun{StartedAt: time.Now(), Errors: []string{}}
+	for _, err := range m.runTaskAction(ctx, t, logger) {
+		run.Errors = append(run.Errors, err.Error())
+	}
+	run.CompletedAt = time.Now()
+	run.Successful = len(run.Errors) == 0
+
+	m.tasks.mu.Lock()
+	delete(m.tasks.running, t.Name)
+	m.tasks.last[t.Name] = run
+	m.tasks.mu.Unlock()
+
+	if !run.Successful {
+		logger.WithField("errors", run.Errors).Warn("failed to run scheduled task for node")
+		webhook.Dispatch("", webhook.TaskFailedEvent, map[string]interface{}{
+			"name":        t.Name,
+			"action":      t.Action,
+			"errors":      run.Errors,
+			"duration_ms": run.CompletedAt.Sub(run.StartedAt).Milliseconds(),
+		})
+		return
+	}
+	logger.WithField("execution_time", run.CompletedAt.Sub(run.StartedAt)).Info("completed scheduled task for node")
+}
+
+func (m *Manager) runTaskAction(ctx context.Context, t config.ScheduledTask, logger *log.Entry) []error {
+	switch t.Action {
+	case config.TaskActionPruneImages:
+		r, err := environment.PruneImages(ctx, m.Images())
+		if err != nil {
+			return []error{err}
+		}
+		logger.WithFields(log.Fields{"removed": len(r.Removed), "space_reclaimed": r.SpaceReclaimed}).Info("removed unused docker images")
+		return nil
+	case config.TaskActionHook:
+		if err := runHook(ctx, t, logger); err != nil {
+			return []error{err}
+		}
+		return nil
+	}
+
+	servers, errs := m.taskServers(t)
+	for _, s := range servers {
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
+		var err error
+		switch t.Action {
+		case config.TaskActionBackup:
+			_, err = s.CreateLocalBackup(t.Keep)
+		case config.TaskActionRestart:
+			// Only servers that are already running are restarted, a scheduled restart
+			// should never start a server that was stopped on purpose.
+			if s.Environment.State() == environment.ProcessRunningState {
+				err = s.HandlePowerAction(PowerActionRestart, 30)
+			}
+		case config.TaskActionRecalculateDisk:
+			_, err = s.Filesystem().RecalculateDiskUsage()
+		}
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, s.ID()))
+		}
+	}
+	return errs
+}
+
+// taskServers returns the servers that the task applies to, which is every
+// server on the node if the task does not list any.
+func (m *Manager) taskServers(t config.ScheduledTask) ([]*Server, []error) {
+	if len(t.Servers) == 0 {
+		return m.All(), nil
+	}
+	var errs []error
+	servers := make([]*Server, 0, len(t.Servers))
+	for _, id := range t.Servers {
+		s, ok := m.Get(id)
+		if !ok {
+			errs = append(errs, errors.Errorf("%s: server does not exist on this node", id))
+			continue
+		}
+		servers = append(servers, s)
+	}
+	return servers, errs
+}
+
+// runHook runs the script for a hook task from within the hook directory. The
+// name of the task is passed to the script in the WINGS_TASK variable.
+func runHook(ctx context.Context, t config.ScheduledTask, logger *log.Entry) error {
+	dir := config.Get().Tasks.HookDirectory
+	if dir == "" || len(t.Command) == 0 || !config.WithinHookDirectory(dir, t.Command[0]) {
+		return errors.New("server/tasks: hook script is not within the hook directory")
+	}
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(t.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, filepath.Clean(t.Command[0]), t.Command[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "WINGS_TASK="+t.Name)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+
+	output := strings.TrimSpace(out.String())
+	if len(output) > maxHookOutput {
+		output = output[len(output)-maxHookOutput:]
+	}
+	if err != nil {
+		if output != "" {
+			return errors.Errorf("server/tasks: hook script failed: %s: %s", err, output)
+		}
+		return errors.Wrap(err, "server/tasks: hook script failed")
+	}
+	logger.WithField("output", output).Debug("hook script completed")
+	return nil
+}
+
+// CreateLocalBackup creates a local backup of the server that is not reported to
+// the Panel, in the same way as the "wings backup create" command. Once created,
+// the oldest of these backups are removed so that only the most recent are kept,
+// unless keep is zero.
+func (s *Server) CreateLocalBackup(keep int) (*backup.ArchiveDetails, error) {
+	if s.IsInstalling() || s.IsTransferring() || s.IsRestoring() {
+		return nil, errors.New("server/tasks: cannot create a backup while the server files are being replaced")
+	}
+	ignored, err := s.getServerwideIgnoredFiles()
+	if err != nil {
+		s.Log().WithField("error", err).Warn("failed to get server-wide ignored files")
+	}
+
+	release, err := backups.acquire(s.Context(), s.ID())
+	if err != nil {
+		return nil, errors.WrapIf(err, "backup: error while waiting for other backups to complete")
+	}
+	b := backup.NewLocal(s.client, uuid.New().String(), ignored)
+	ad, err := b.Generate(s.Context(), s.Filesystem().Path(), ignored)
+	release()
+	if err != nil {
+		_ = b.Remove()
+		return nil, errors.WrapIf(err, "backup: error while generating server backup")
+	}
+	err = b.WriteMetadata(backup.LocalMetadata{
+		Server:    s.ID(),
+		CreatedAt: time.Now(),
+		Checksum:  ad.Checksum,
+		Size:      ad.Size,
+	})
+	if err != nil {
+		_ = b.Remove()
+		return nil, errors.WrapIf(err, "backup: failed to store the details of the backup")
+	}
+	s.Log().WithField("backup", b.Identifier()).Info("created local backup for server")
+
+	if keep > 0 {
+		s.pruneLocalBackups(keep)
+	}
+	return ad, nil
+}
+
+// pruneLocalBackups removes all but the most recent local backups of the server.
+func (s *Server) pruneLocalBackups(keep int) {
+	existing, err := backup.ListLocalMetadata(s.ID())
+	if err != nil {
+		s.Log().WithField("error", err).Warn("failed to list local backups for server")
+		return
+	}
+	if len(existing) <= keep {
+		return
+	}
+	for _, m := range existing[keep:] {
+		b, _, err := backup.LocateLocal(nil, m.Uuid)
+		if err == nil {
+			err = b.Remove()
+		}
+		if err != nil {
+			s.Log().WithFields(log.Fields{"backup": m.Uuid, "error": err}).Warn("failed to remove old local backup for server")
+			continue
+		}
+		s.Log().WithField("backup", m.Uuid).Debug("removed old local backup for server")
+	}
+}
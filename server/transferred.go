@@ -0,0 +1,107 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"emperror.dev/errors"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// Tracks the servers that were transferred to this node from another node. The
+// startup variables of these servers may reference the data directory used by
+// upstream Linux nodes, so those references are translated for them but left
+// alone for every other server. These are persisted to the disk so that they
+// are still translated after Wings restarts.
+var transferredServers struct {
+	sync.Mutex
+	ids map[string]bool
+}
+
+func transferredServersPath() string {
+	return filepath.Join(config.Get().System.RootDirectory, "transferred_servers.json")
+}
+
+// loadTransferredServers reads the transferred servers from the disk if they have
+// not already been loaded. The lock must be held when calling this function.
+func loadTransferredServers() error {
+	if transferredServers.ids != nil {
+		return nil
+	}
+	transferredServers.ids = make(map[string]bool)
+	b, err := os.ReadFile(transferredServersPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "server: failed to read transferred servers")
+	}
+	if err := json.Unmarshal(b, &transferredServers.ids); err != nil {
+		return errors.Wrap(err, "server: failed to parse transferred servers")
+	}
+	return nil
+}
+
+// saveTransferredServers writes the transferred servers to the disk. The lock
+// must be held when calling this function.
+func saveTransferredServers() error {
+	b, err := json.Marshal(transferredServers.ids)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.WriteFile(transferredServersPath(), b, 0o600); err != nil {
+		return errors.Wrap(err, "server: failed to write transferred servers")
+	}
+	return nil
+}
+
+// isTransferred returns true if the server was transferred to this node.
+func isTransferred(uuid string) (bool, error) {
+	transferredServers.Lock()
+	defer transferredServers.Unlock()
+
+	if err := loadTransferredServers(); err != nil {
+		return false, err
+	}
+	return transferredServers.ids[uuid], nil
+}
+
+// MarkTransferred records that the server was transferred to this node from
+// another node, so that references to the data directory used by upstream Linux
+// nodes are translated in its startup variables. This must be called before the
+// environment for the server is created.
+func (s *Server) MarkTransferred() error {
+	transferredServers.Lock()
+	defer transferredServers.Unlock()
+
+	if err := loadTransferredServers(); err != nil {
+		return err
+	}
+	s.Environment.Config().SetTranslatePaths(true)
+	if transferredServers.ids[s.ID()] {
+		return nil
+	}
+	transferredServers.ids[s.ID()] = true
+
+	return saveTransferredServers()
+}
+
+// ReleaseTransferred removes the record of a server being transferred to this
+// node, this should be called once the server has been deleted.
+func ReleaseTransferred(uuid string) error {
+	transferredServers.Lock()
+	defer transferredServers.Unlock()
+
+	if err := loadTransferredServers(); err != nil {
+		return err
+	}
+	if !transferredServers.ids[uuid] {
+		return nil
+	}
+	delete(transferredServers.ids, uuid)
+
+	return saveTransferredServers()
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"emperror.dev/errors"
+	"github.com/goccy/go-json"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// Tracks the IDs assigned to each server when servers are isolated with their own
+// users. These are persisted to the disk so that a server keeps the same ID, and
+// therefore ownership of its files, between restarts of Wings.
+var serverUsers struct {
+	sync.Mutex
+	ids map[string]int
+}
+
+func serverUsersPath() string {
+	return filepath.Join(config.Get().System.RootDirectory, "server_users.json")
+}
+
+// loadServerUsers reads the assigned IDs from the disk if they have not already
+// been loaded. The lock must be held when calling this function.
+func loadServerUsers() error {
+	if serverUsers.ids != nil {
+		return nil
+	}
+	serverUsers.ids = make(map[string]int)
+	b, err := os.ReadFile(serverUsersPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "server: failed to read assigned server users")
+	}
+	if err := json.Unmarshal(b, &serverUsers.ids); err != nil {
+		return errors.Wrap(err, "server: failed to parse assigned server users")
+	}
+	return nil
+}
+
+// saveServerUsers writes the assigned IDs to the disk. The lock must be held when
+// calling this function.
+func saveServerUsers() error {
+	b, err := json.Marshal(serverUsers.ids)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.WriteFile(serverUsersPath(), b, 0o600); err != nil {
+		return errors.Wrap(err, "server: failed to write assigned server users")
+	}
+	return nil
+}
+
+// assignServerUser returns the ID used for both the user and group of a server,
+// assigning the next unused ID after base if the server does not yet have one.
+func assignServerUser(uuid string, base int) (int, error) {
+	serverUsers.Lock()
+	defer serverUsers.Unlock()
+
+	if err := loadServerUsers(); err != nil {
+		return 0, err
+	}
+	if id, ok := serverUsers.ids[uuid]; ok {
+		return id, nil
+	}
+
+	used := make(map[int]bool, len(serverUsers.ids))
+	for _, id := range serverUsers.ids {
+		used[id] = true
+	}
+	id := base
+	for used[id] {
+		id++
+	}
+	serverUsers.ids[uuid] = id
+
+	return id, saveServerUsers()
+}
+
+// ReleaseServerUser removes the ID assigned to a server so that it can be used by
+// another server. This should only be called once the files for the server have
+// been deleted.
+func ReleaseServerUser(uuid string) error {
+	serverUsers.Lock()
+	defer serverUsers.Unlock()
+
+	if err := loadServerUsers(); err != nil {
+		return err
+	}
+	if _, ok := serverUsers.ids[uuid]; !ok {
+		return nil
+	}
+	delete(serverUsers.ids, uuid)
+
+	return saveServerUsers()
+}
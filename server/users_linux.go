@@ -0,0 +1,53 @@
+package server
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/server/filesystem"
+)
+
+// configureUserIsolation assigns the server its own user and group when servers
+// are isolated from each other. The files for the server are owned by the user,
+// and the server process runs as it within the container.
+func (s *Server) configureUserIsolation() error {
+	u := config.Get().System.User
+	if !u.PerServer.Enabled || u.Rootless.Enabled {
+		return nil
+	}
+
+	id, err := assignServerUser(s.ID(), u.PerServer.BaseID)
+	if err != nil {
+		return err
+	}
+	s.Filesystem().SetOwner(&filesystem.Owner{Uid: id, Gid: id})
+	if env, ok := s.Environment.(interface{ SetUser(string) }); ok {
+		env.SetUser(strconv.Itoa(id) + ":" + strconv.Itoa(id))
+	}
+
+	st, err := os.Stat(s.Filesystem().Path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "server: failed to stat data directory")
+	}
+	// If the data directory is not yet owned by the server user, such as when
+	// isolation has just been enabled, the ownership of all the files must be
+	// changed for the server to continue working.
+	if sys, ok := st.Sys().(*syscall.Stat_t); ok && int(sys.Uid) != id {
+		if err := s.chownRoot(filesystem.ChownOptions{}); err != nil {
+			return errors.WithMessage(err, "server: failed to change owner of server files")
+		}
+	}
+	// Only the server user is able to access the data directory, so that a server
+	// process is unable to read the files of any other server.
+	if err := os.Chmod(s.Filesystem().Path(), 0o700); err != nil {
+		return errors.Wrap(err, "server: failed to set permissions on data directory")
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"os"
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestAssignServerUser(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("assignServerUser", func() {
+		g.BeforeEach(func() {
+			dir, err := os.MkdirTemp("", "wings-users")
+			g.Assert(err).IsNil()
+			c := &config.Configuration{AuthenticationToken: "test"}
+			c.System.RootDirectory = dir
+			config.Set(c)
+			serverUsers.ids = nil
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(config.Get().System.RootDirectory)
+		})
+
+		g.It("assigns the next unused ID to each server", func() {
+			a, err := assignServerUser("a", 1000)
+			g.Assert(err).IsNil()
+			b, err := assignServerUser("b", 1000)
+			g.Assert(err).IsNil()
+			g.Assert(a).Equal(1000)
+			g.Assert(b).Equal(1001)
+
+			again, err := assignServerUser("a", 1000)
+			g.Assert(err).IsNil()
+			g.Assert(again).Equal(1000)
+		})
+
+		g.It("persists assigned IDs between loads", func() {
+			_, _ = assignServerUser("a", 1000)
+			_, _ = assignServerUser("b", 1000)
+			serverUsers.ids = nil
+
+			b, err := assignServerUser("b", 1000)
+			g.Assert(err).IsNil()
+			g.Assert(b).Equal(1001)
+		})
+
+		g.It("reuses IDs released by deleted servers", func() {
+			_, _ = assignServerUser("a", 1000)
+			_, _ = assignServerUser("b", 1000)
+			g.Assert(ReleaseServerUser("a")).IsNil()
+
+			c, err := assignServerUser("c", 1000)
+			g.Assert(err).IsNil()
+			g.Assert(c).Equal(1000)
+		})
+	})
+}
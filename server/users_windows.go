@@ -0,0 +1,7 @@
+package server
+
+// configureUserIsolation is a no-op on Windows, all servers run as the system
+// user.
+func (s *Server) configureUserIsolation() error {
+	return nil
+}
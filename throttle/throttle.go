@@ -0,0 +1,98 @@
+// Package throttle limits the rate at which data is read or written using a limit
+// that is re-evaluated while the data is being copied, allowing the limit to be
+// changed by a schedule part way through a long running backup or transfer.
+package throttle
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/juju/ratelimit"
+)
+
+// How often the limit is re-evaluated.
+var interval = time.Minute
+
+// LimitFunc returns the limit in MiB/s that should be applied at the given time.
+// A value less than 1 means the rate is unlimited.
+type LimitFunc func(now time.Time) int
+
+type limiter struct {
+	mu      sync.Mutex
+	limit   LimitFunc
+	current int
+	bucket  *ratelimit.Bucket
+	checked time.Time
+}
+
+func newLimiter(fn LimitFunc) *limiter {
+	l := &limiter{limit: fn, current: -1}
+	l.update(time.Now())
+	return l
+}
+
+// update creates a new bucket if the limit has changed since it was last checked.
+func (l *limiter) update(now time.Time) {
+	l.checked = now
+	limit := l.limit(now)
+	if limit < 1 {
+		limit = 0
+	}
+	if limit == l.current {
+		return
+	}
+	l.current = limit
+	l.bucket = nil
+	if limit > 0 {
+		rate := int64(limit) * 1024 * 1024
+		l.bucket = ratelimit.NewBucketWithRate(float64(rate), rate)
+	}
+}
+
+// wait blocks until n bytes are allowed to be read or written.
+func (l *limiter) wait(n int) {
+	if n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	if now := time.Now(); now.Sub(l.checked) >= interval {
+		l.update(now)
+	}
+	bucket := l.bucket
+	l.mu.Unlock()
+	if bucket != nil {
+		bucket.Wait(int64(n))
+	}
+}
+
+type reader struct {
+	r io.Reader
+	l *limiter
+}
+
+// Reader returns a reader that is limited to the rate returned by the function.
+func Reader(r io.Reader, fn LimitFunc) io.Reader {
+	return &reader{r: r, l: newLimiter(fn)}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.l.wait(n)
+	return n, err
+}
+
+type writer struct {
+	w io.Writer
+	l *limiter
+}
+
+// Writer returns a writer that is limited to the rate returned by the function.
+func Writer(w io.Writer, fn LimitFunc) io.Writer {
+	return &writer{w: w, l: newLimiter(fn)}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.l.wait(len(p))
+	return w.w.Write(p)
+}
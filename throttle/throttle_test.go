@@ -0,0 +1,61 @@
+package throttle
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+)
+
+func TestThrottle(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("limiter", func() {
+		g.It("does not create a bucket when unlimited", func() {
+			l := newLimiter(func(time.Time) int { return 0 })
+			g.Assert(l.bucket == nil).IsTrue()
+			l.wait(1024 * 1024 * 100)
+		})
+
+		g.It("replaces the bucket when the limit changes", func() {
+			limit := 10
+			l := newLimiter(func(time.Time) int { return limit })
+			g.Assert(l.current).Equal(10)
+			g.Assert(l.bucket == nil).IsFalse()
+
+			b := l.bucket
+			l.update(time.Now())
+			g.Assert(l.bucket == b).IsTrue()
+
+			limit = 0
+			l.update(time.Now())
+			g.Assert(l.bucket == nil).IsTrue()
+
+			limit = 20
+			l.update(time.Now())
+			g.Assert(l.current).Equal(20)
+			g.Assert(l.bucket == nil).IsFalse()
+		})
+
+		g.It("re-evaluates the limit while data is copied", func() {
+			defer func(v time.Duration) { interval = v }(interval)
+			interval = 0
+
+			calls := 0
+			r := Reader(bytes.NewReader(make([]byte, 4096)), func(time.Time) int {
+				calls++
+				return 0
+			})
+			for i := 0; i < 4; i++ {
+				n, err := r.Read(make([]byte, 1024))
+				g.Assert(err).IsNil()
+				g.Assert(n).Equal(1024)
+			}
+			_, err := r.Read(make([]byte, 1024))
+			g.Assert(err).Equal(io.EOF)
+			g.Assert(calls).Equal(5)
+		})
+	})
+}